@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"example-api/internal/app"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"example-api/internal/stream"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// consumeKafka stores messages from the configured Kafka topics as events
+// until SIGINT or SIGTERM
+func consumeKafka(args []string) error {
+	flags := flag.NewFlagSet("consume-kafka", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-kafka")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if len(cfg.Kafka.Brokers) == 0 || len(cfg.Kafka.Topics) == 0 {
+		return fmt.Errorf("kafka.brokers and kafka.topics must be configured")
+	}
+
+	// Offsets are committed explicitly, once a batch is stored
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Kafka.Brokers,
+		GroupID:     cfg.Kafka.GroupID,
+		GroupTopics: cfg.Kafka.Topics,
+		StartOffset: kafka.FirstOffset,
+	})
+	defer reader.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := &consumer{
+		db:           db,
+		reader:       reader,
+		batchSize:    cfg.Kafka.BatchSize,
+		batchTimeout: time.Duration(cfg.Kafka.BatchTimeoutMS) * time.Millisecond,
+	}
+	log.Printf("Consuming %v from %v as group %s", cfg.Kafka.Topics, cfg.Kafka.Brokers, cfg.Kafka.GroupID)
+	c.run(ctx)
+	app.WaitAlerts(cfg)
+	log.Println("Kafka consumer stopped")
+	return nil
+}
+
+// consumer stores batches of Kafka messages as events
+type consumer struct {
+	db           *database.Database
+	reader       *kafka.Reader
+	batchSize    int
+	batchTimeout time.Duration
+}
+
+// run consumes until ctx is cancelled. A batch's offsets are committed only
+// after its events are stored, so messages fetched before a crash or shutdown
+// are delivered again.
+func (c *consumer) run(ctx context.Context) {
+	for {
+		batch, err := c.fetchBatch(ctx)
+		if len(batch) == 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to fetch messages: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if !c.store(ctx, batch) {
+			return
+		}
+		// Commit even while shutting down: the batch is stored
+		if err := c.reader.CommitMessages(context.Background(), batch...); err != nil {
+			log.Printf("Failed to commit offsets: %v", err)
+		}
+	}
+}
+
+// fetchBatch waits for a message, then collects more until the batch is full
+// or the batch timeout passes
+func (c *consumer) fetchBatch(ctx context.Context) ([]kafka.Message, error) {
+	first, err := c.reader.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch := []kafka.Message{first}
+
+	fillCtx, cancel := context.WithTimeout(ctx, c.batchTimeout)
+	defer cancel()
+	for len(batch) < c.batchSize {
+		msg, err := c.reader.FetchMessage(fillCtx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+				break
+			}
+			return batch, err
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
+
+// store decodes the batch and stores its events, retrying until they are
+// stored. Messages that aren't valid events are logged and skipped. It
+// reports false if ctx was cancelled before the batch was stored.
+func (c *consumer) store(ctx context.Context, batch []kafka.Message) bool {
+	events := make([]*models.EventRequest, 0, len(batch))
+	for _, msg := range batch {
+		event, err := stream.DecodeEvent(msg.Value, msg.Topic)
+		if err != nil {
+			_ = c.db.LogEventStatus(0, "error", fmt.Sprintf("kafka %s/%d offset %d: %v", msg.Topic, msg.Partition, msg.Offset, err))
+			continue
+		}
+		events = append(events, event)
+	}
+
+	stored, err := stream.StoreBatch(ctx, c.db, events)
+	if err != nil {
+		return false
+	}
+	last := batch[len(batch)-1]
+	log.Printf("Stored %d event(s) from %d message(s), up to %s/%d offset %d", stored, len(batch), last.Topic, last.Partition, last.Offset)
+	return true
+}
@@ -0,0 +1,925 @@
+// Command eventdb runs the event database's servers and maintenance tasks
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"example-api/internal/alert"
+	"example-api/internal/anomaly"
+	"example-api/internal/app"
+	"example-api/internal/auth"
+	"example-api/internal/bench"
+	"example-api/internal/config"
+	"example-api/internal/database"
+	"example-api/internal/digest"
+	"example-api/internal/ingest"
+	"example-api/internal/logging"
+	"example-api/internal/mailer"
+	"example-api/internal/models"
+	"example-api/internal/monitor"
+	"example-api/internal/reporting"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const usage = `Usage: eventdb <command> [flags]
+
+Commands:
+  serve-api     Run the JSON API and ingestion webhooks (server.port)
+  serve-web     Run the web interface (port 8082)
+  serve-all     Run the API and the web interface in one process
+  serve-smtp    Receive events as email (inbound_smtp.listen)
+  consume-kafka Store messages from the configured Kafka topics as events
+  consume-nats  Store messages on the configured NATS subjects as events
+  poll          Fetch the configured URLs and store new items as events
+  migrate       Apply the SQL migrations
+  create-user   Create a web interface user
+  export        Write all events to an NDJSON backup
+  import        Load events from an NDJSON export
+  purge         Delete old events, for running from cron
+  digest        Email the daily and weekly digests that are due, for running from cron
+  remind        Notify alert channels of events due soon or overdue, for running from cron
+  expect        Notify the channels of expectations missed by silent sources, for running from cron
+  anomalies     Flag unusual spikes and drops in the number of events, for running from cron
+  archive       Upload new events to S3-compatible storage on an interval, or once
+  monitor       Send the heartbeats of the configured monitors, or show their status
+  bench         Load-test the ingestion API of a running server
+
+Run "eventdb <command> -h" for the command's flags.
+`
+
+func main() {
+	log.SetFlags(log.Ldate | log.Ltime | log.LUTC)
+	log.SetPrefix("[eventdb] ")
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch command {
+	case "serve-api", "serve-web", "serve-all":
+		err = serve(command, args)
+	case "serve-smtp":
+		err = serveSMTP(args)
+	case "consume-kafka":
+		err = consumeKafka(args)
+	case "consume-nats":
+		err = consumeNATS(args)
+	case "poll":
+		err = poll(args)
+	case "migrate":
+		err = migrate(args)
+	case "create-user":
+		err = createUser(args)
+	case "export":
+		err = export(args)
+	case "import":
+		err = importEvents(args)
+	case "purge":
+		err = purge(args)
+	case "digest":
+		err = sendDigests(args)
+	case "remind":
+		err = sendReminders(args)
+	case "expect":
+		err = checkExpectations(args)
+	case "anomalies":
+		err = detectAnomalies(args)
+	case "archive":
+		err = uploadArchives(args)
+	case "monitor":
+		err = runMonitors(args)
+	case "bench":
+		err = benchmark(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+	reporting.Flush(2 * time.Second)
+	if err != nil {
+		log.Fatalf("%s: %v", command, err)
+	}
+}
+
+// setup loads the configuration, configures logging and connects to the database
+func setup(service string) (*config.Config, *database.Database, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := logging.Setup(service, cfg.Logging.Level, cfg.Logging.Format); err != nil {
+		return nil, nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	if err := reporting.Setup(service, cfg.ErrorReporting.SentryDSN, cfg.ErrorReporting.SampleRate, cfg.ErrorReporting.Environment); err != nil {
+		return nil, nil, fmt.Errorf("invalid error reporting configuration: %w", err)
+	}
+	db, err := app.OpenDatabase(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, db, nil
+}
+
+// serve runs the API, the web interface or both until SIGINT or SIGTERM
+func serve(command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-" + strings.TrimPrefix(command, "serve-"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	app.WarnMissingIndexes(context.Background(), db)
+
+	servers := make(map[string]*http.Server)
+	if command != "serve-web" {
+		server, err := app.NewAPIServer(cfg, db)
+		if err != nil {
+			return err
+		}
+		servers[app.ListenerAPI] = server
+	}
+	if command != "serve-api" {
+		server, err := app.NewWebServer(cfg, db)
+		if err != nil {
+			return err
+		}
+		servers[app.ListenerWeb] = server
+	}
+
+	https, err := app.NewHTTPS(cfg)
+	if err != nil {
+		return err
+	}
+	if https != nil {
+		for _, server := range servers {
+			server.TLSConfig = https.Config
+		}
+		// Browsers arriving over plain HTTP are sent to the web interface,
+		// unless only the API runs
+		if addr := https.RedirectAddr(cfg); addr != "" {
+			target := servers[app.ListenerWeb]
+			if target == nil {
+				target = servers[app.ListenerAPI]
+			}
+			servers[app.ListenerRedirect] = https.RedirectServer(addr, target)
+		}
+	} else if cfg.Server.RedirectHTTP != "" {
+		log.Println("Warning: server.redirect_http is ignored without server.tls_cert or server.acme_domains")
+	}
+
+	// A second signal stops the process immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	// SIGHUP reloads the settings that don't need a restart
+	app.WatchReload(ctx, cfg)
+	if err := app.Serve(ctx, cfg, servers); err != nil {
+		return err
+	}
+	log.Println("Stopped")
+	return nil
+}
+
+// migrate applies the migrations in the migrations directory
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dir := flags.String("dir", "migrations", "directory holding the .sql migration files")
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-migrate")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	log.Printf("Running migrations on database: %s", cfg.Database.Name)
+	if err := db.Migrate(*dir); err != nil {
+		return err
+	}
+	log.Println("All migrations completed successfully")
+	return nil
+}
+
+// createUser adds a web interface user. The password is read from standard
+// input unless given with -password, which leaves it in the shell history.
+func createUser(args []string) error {
+	flags := flag.NewFlagSet("create-user", flag.ExitOnError)
+	username := flags.String("username", "", "name to log in with (required)")
+	password := flags.String("password", "", "password; read from standard input when not set")
+	role := flags.String("role", "user", "admin or user")
+	email := flags.String("email", "", "email address")
+	flags.Parse(args)
+
+	if *username == "" {
+		return fmt.Errorf("-username is required")
+	}
+	if *role != "admin" && *role != "user" {
+		return fmt.Errorf("invalid role %q, want admin or user", *role)
+	}
+	if *password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+	if len(*password) < 8 {
+		return fmt.Errorf("password must be at least 8 characters")
+	}
+
+	_, db, err := setup("eventdb-create-user")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	authSystem := auth.New()
+	authSystem.SetAuditor(db)
+	if err := authSystem.SetUserStore(db); err != nil {
+		return err
+	}
+	user, err := authSystem.CreateUser(*username, *password, *role)
+	if err != nil {
+		return err
+	}
+	if *email != "" {
+		if err := authSystem.SetUserEmail(user.Username, *email); err != nil {
+			return err
+		}
+	}
+	log.Printf("Created %s user %s (ID %d)", user.Role, user.Username, user.ID)
+	return nil
+}
+
+// export writes every event with its logs and attachments to a file or
+// standard output, one JSON object per line. A file is written under a
+// temporary name and only renamed into place once the export is complete.
+func export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "ndjson", "output format; only ndjson is supported")
+	out := flags.String("out", "-", `file to write, or "-" for standard output`)
+	withData := flags.Bool("attachment-data", false, "include attachment contents, base64 encoded")
+	flags.Parse(args)
+
+	if *format != "ndjson" {
+		return fmt.Errorf("unsupported format %q, want ndjson", *format)
+	}
+
+	_, db, err := setup("eventdb-export")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var file *os.File
+	if *out == "-" {
+		file = os.Stdout
+	} else {
+		if file, err = os.Create(*out + ".tmp"); err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+	}
+
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	count := 0
+	err = db.ExportEvents(*withData, func(event *models.ExportedEvent) error {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to write event %d: %w", event.ID, err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if file != os.Stdout {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		if err := os.Rename(file.Name(), *out); err != nil {
+			return fmt.Errorf("failed to move export into place: %w", err)
+		}
+	}
+	log.Printf("Exported %d events", count)
+	return nil
+}
+
+// importEvents loads an NDJSON export, committing every -batch-size events
+// in one transaction so a failure only loses the batch in progress
+func importEvents(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	onConflict := flags.String("on-conflict", models.ImportSkip, "what to do with events whose ID is already stored: skip, overwrite or new-id")
+	batchSize := flags.Int("batch-size", 500, "events per transaction")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), `Usage: eventdb import [flags] FILE ("-" for standard input)`)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	// Allow the flags after the file name too
+	path := flags.Arg(0)
+	flags.Parse(flags.Args()[min(1, flags.NArg()):])
+	if path == "" || flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	switch *onConflict {
+	case models.ImportSkip, models.ImportOverwrite, models.ImportNewID:
+	default:
+		return fmt.Errorf("invalid -on-conflict %q, want skip, overwrite or new-id", *onConflict)
+	}
+	if *batchSize < 1 {
+		return fmt.Errorf("-batch-size must be at least 1")
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	_, db, err := setup("eventdb-import")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var total models.ImportResult
+	read := 0
+	flush := func(batch []models.ExportedEvent) error {
+		result, err := db.ImportEvents(batch, *onConflict)
+		if err != nil {
+			return fmt.Errorf("batch ending at record %d: %w", read, err)
+		}
+		total.Imported += result.Imported
+		total.Overwritten += result.Overwritten
+		total.Skipped += result.Skipped
+		total.MissingAttachmentData += result.MissingAttachmentData
+		log.Printf("Read %d events: %d imported, %d overwritten, %d skipped",
+			read, total.Imported, total.Overwritten, total.Skipped)
+		return nil
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(in))
+	batch := make([]models.ExportedEvent, 0, *batchSize)
+	for {
+		var event models.ExportedEvent
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read record %d: %w", read+1, err)
+		}
+		read++
+		batch = append(batch, event)
+		if len(batch) == *batchSize {
+			if err := flush(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(batch); err != nil {
+			return err
+		}
+	}
+
+	if total.MissingAttachmentData > 0 {
+		log.Printf("%d attachments were left out because the export has no attachment data (export with -attachment-data)",
+			total.MissingAttachmentData)
+	}
+	log.Printf("Import finished: %d events read, %d imported, %d overwritten, %d skipped",
+		read, total.Imported, total.Overwritten, total.Skipped)
+	return nil
+}
+
+// tagList collects a repeatable -tag flag
+type tagList []string
+
+func (t *tagList) String() string { return strings.Join(*t, ",") }
+
+func (t *tagList) Set(value string) error {
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+// parseAge parses a duration, also accepting whole days such as "90d"
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return age, nil
+}
+
+// purge deletes events older than -older-than in batches, optionally
+// appending each batch to an NDJSON archive before it is deleted
+func purge(args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	olderThan := flags.String("older-than", "", `delete events created longer ago than this, e.g. "90d" or "36h" (required)`)
+	var tags tagList
+	flags.Var(&tags, "tag", "only delete events with this tag; repeat or separate with commas for several")
+	dryRun := flags.Bool("dry-run", false, "only print what would be deleted")
+	batchSize := flags.Int("batch-size", 1000, "events deleted per transaction")
+	archive := flags.String("archive", "", "append the events to this NDJSON file before deleting them")
+	withData := flags.Bool("attachment-data", false, "include attachment contents in the archive")
+	flags.Parse(args)
+
+	if *olderThan == "" {
+		return fmt.Errorf("-older-than is required")
+	}
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		return err
+	}
+	if *batchSize < 1 {
+		return fmt.Errorf("-batch-size must be at least 1")
+	}
+	cutoff := time.Now().UTC().Add(-age)
+
+	_, db, err := setup("eventdb-purge")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	events, logs, attachments, err := db.CountPurgeCandidates(cutoff, tags)
+	if err != nil {
+		return err
+	}
+	scope := ""
+	if len(tags) > 0 {
+		scope = " tagged " + tags.String()
+	}
+	log.Printf("%d events%s created before %s, with %d logs and %d attachments",
+		events, scope, cutoff.Format(time.RFC3339), logs, attachments)
+	if *dryRun || events == 0 {
+		return nil
+	}
+
+	var archiveFile *os.File
+	if *archive != "" {
+		if archiveFile, err = os.OpenFile(*archive, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600); err != nil {
+			return fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer archiveFile.Close()
+	}
+
+	var deleted int64
+	for {
+		ids, err := db.PurgeCandidates(cutoff, tags, *batchSize)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		if archiveFile != nil {
+			if err := archiveEvents(db, archiveFile, ids, *withData); err != nil {
+				return err
+			}
+		}
+		n, err := db.DeleteEvents(ids)
+		if err != nil {
+			return err
+		}
+		deleted += n
+		log.Printf("Deleted %d of %d events", deleted, events)
+	}
+	incidents, err := db.DeleteEmptyIncidents()
+	if err != nil {
+		return err
+	}
+	log.Printf("Purge finished: %d events deleted, %d incidents left empty deleted", deleted, incidents)
+	return nil
+}
+
+// archiveEvents appends events to an NDJSON archive and syncs it, so they are
+// on disk before being deleted
+func archiveEvents(db *database.Database, file *os.File, ids []int64, withData bool) error {
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	err := db.ExportEventsByID(ids, withData, func(event *models.ExportedEvent) error {
+		return encoder.Encode(event)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive events: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// sendDigests emails each subscriber the digest of their last whole day or
+// week, if they haven't had it yet. Run hourly, users get their digest soon
+// after midnight in their time zone.
+func sendDigests(args []string) error {
+	flags := flag.NewFlagSet("digest", flag.ExitOnError)
+	period := flags.String("period", "", `send only the "daily" or "weekly" digests`)
+	dryRun := flags.Bool("dry-run", false, "print the digests instead of sending them")
+	flags.Parse(args)
+
+	periods := digest.Periods
+	if *period != "" {
+		periods = []string{*period}
+	}
+
+	cfg, db, err := setup("eventdb-digest")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m := mailer.New(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	sender := digest.New(db, m, cfg.Server.BaseURL)
+	sender.DryRun, sender.Out = *dryRun, os.Stdout
+	now := time.Now()
+	for _, p := range periods {
+		sent, err := sender.Send(p, now)
+		log.Printf("Sent %d %s digests", sent, p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendReminders notifies the channels of the alert rules matching each
+// unresolved event that became due soon or overdue since the last run. Each
+// event gets one reminder of each kind per due date.
+func sendReminders(args []string) error {
+	flags := flag.NewFlagSet("remind", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list the reminders instead of sending them")
+	timeout := flags.Duration("timeout", time.Minute, "how long to wait for notifications to be delivered")
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-remind")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	reminders, err := db.DueReminders(time.Now())
+	if err != nil {
+		return err
+	}
+	notifier := app.Notifier(cfg, db)
+	sent := 0
+	for _, reminder := range reminders {
+		event := reminder.Event
+		if *dryRun {
+			fmt.Printf("#%d %s, due %s\n", event.ID, reminder.Stage, event.DueAt.Format(time.RFC3339))
+			continue
+		}
+		rules, err := notifier.Remind(&event, reminder.Stage)
+		if err != nil {
+			return err
+		}
+		if err := db.MarkDueReminded(event.ID, reminder.Stage); err != nil {
+			return err
+		}
+		sent += rules
+	}
+	if *dryRun {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := alert.Wait(ctx); err != nil {
+		return fmt.Errorf("notifications were still being sent after %s: %w", *timeout, err)
+	}
+	log.Printf("Sent %d reminders for %d events", sent, len(reminders))
+	return nil
+}
+
+// checkExpectations checks every enabled expectation and notifies its
+// channel when it was missed since the last run, and when it is met again
+func checkExpectations(args []string) error {
+	flags := flag.NewFlagSet("expect", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print the state of each expectation instead of notifying")
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-expect")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	expectations, err := db.EnabledExpectations()
+	if err != nil {
+		return err
+	}
+	notifier := app.Notifier(cfg, db)
+	now := time.Now()
+	sent, failed := 0, 0
+	for i := range expectations {
+		exp := &expectations[i]
+		if *dryRun {
+			check, err := notifier.CheckExpectation(exp, now)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s: %s, %d of %d event(s) in %s\n", exp.Name, check.State, check.Events, exp.MinEvents, exp.WindowText())
+			continue
+		}
+		check, notified, err := notifier.Expect(exp, now)
+		if err != nil {
+			log.Printf("Expectation %q: %v", exp.Name, err)
+			failed++
+			continue
+		}
+		if notified {
+			log.Printf("Expectation %q is %s", exp.Name, check.State)
+			sent++
+		}
+	}
+	if *dryRun {
+		return nil
+	}
+	log.Printf("Checked %d expectations, sent %d notifications", len(expectations), sent)
+	if failed > 0 {
+		return fmt.Errorf("%d expectations could not be checked or notified", failed)
+	}
+	return nil
+}
+
+// detectAnomalies compares the number of events per source, per tag and
+// overall in the last complete hour with the hours before it, recording the
+// spikes and drops it finds
+func detectAnomalies(args []string) error {
+	flags := flag.NewFlagSet("anomalies", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "print the anomalies of the last hour instead of recording them")
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-anomalies")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	detector, err := app.AnomalyDetector(cfg, db)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if *dryRun {
+		hour := now.UTC().Truncate(time.Hour).Add(-time.Hour)
+		anomalies, err := detector.Detect(hour)
+		if err != nil {
+			return err
+		}
+		for i := range anomalies {
+			a := &anomalies[i]
+			fmt.Printf("%s: %d event(s), %.1f expected\n", anomaly.Summary(a), a.Count, a.Expected)
+		}
+		fmt.Printf("%d anomalies between %s and %s UTC\n", len(anomalies), hour.Format("15:04"), hour.Add(time.Hour).Format("15:04"))
+		return nil
+	}
+
+	anomalies, err := detector.Run(now)
+	for i := range anomalies {
+		log.Printf("%s: %d event(s), %.1f expected", anomaly.Summary(&anomalies[i]), anomalies[i].Count, anomalies[i].Expected)
+	}
+	// Alert rules on the anomaly tag notify in the background
+	app.WaitAlerts(cfg)
+	if err != nil {
+		return err
+	}
+	log.Printf("Recorded %d anomalies", len(anomalies))
+	return nil
+}
+
+// uploadArchives uploads the events not archived yet to the bucket
+// configured under archive as compressed NDJSON, on archive.interval until
+// SIGINT or SIGTERM, or once with -once. -list prints the latest uploads
+// instead.
+func uploadArchives(args []string) error {
+	flags := flag.NewFlagSet("archive", flag.ExitOnError)
+	once := flags.Bool("once", false, "upload the new events and exit, for running from cron")
+	list := flags.Int("list", 0, "print the latest `n` uploads instead of uploading")
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-archive")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	archiver, err := app.Archiver(cfg, db)
+	if err != nil {
+		return err
+	}
+
+	if *list > 0 {
+		archives, err := db.ListArchives(cfg.Archive.Bucket, *list)
+		if err != nil {
+			return err
+		}
+		for _, a := range archives {
+			fmt.Printf("%s  events %d-%d  %d events  %d bytes  %s\n",
+				a.CreatedAt.UTC().Format(time.RFC3339), a.FirstEventID, a.LastEventID, a.Events, a.Bytes, a.Key)
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *once {
+		archives, err := archiver.Archive(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		log.Printf("Uploaded %d archives", len(archives))
+		return nil
+	}
+
+	log.Printf("Archiving new events to %s every %s", cfg.Archive.Bucket, cfg.Archive.Interval)
+	archiver.Run(ctx)
+	log.Println("Archiving stopped")
+	return nil
+}
+
+// runMonitors sends the heartbeat of each configured monitor through the
+// ingestion pipeline of its source on the monitor's interval until SIGINT or
+// SIGTERM, or once with -once. -status prints whether heartbeats and other
+// events are arriving instead, failing when a monitor is late.
+func runMonitors(args []string) error {
+	flags := flag.NewFlagSet("monitor", flag.ExitOnError)
+	once := flags.Bool("once", false, "send one heartbeat per monitor and exit, for running from cron")
+	status := flags.Bool("status", false, "print the status of the monitors instead of sending heartbeats")
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-monitor")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	monitors, err := app.Monitors(cfg)
+	if err != nil {
+		return err
+	}
+	if len(monitors) == 0 {
+		return fmt.Errorf("no monitors are configured")
+	}
+
+	if *status {
+		statuses, err := monitor.Statuses(db, monitors, time.Now())
+		if err != nil {
+			return err
+		}
+		late := 0
+		for _, s := range statuses {
+			lastHeartbeat, lastEvent := "never", "never"
+			if s.LastHeartbeat != nil {
+				lastHeartbeat = s.LastHeartbeat.UTC().Format(time.RFC3339)
+			}
+			if s.LastEvent != nil {
+				lastEvent = s.LastEvent.UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%-20s %-8s source %s, every %s, last heartbeat %s, last event %s\n",
+				s.Name, s.State, s.Source, s.Interval, lastHeartbeat, lastEvent)
+			if s.State == models.MonitorLate {
+				late++
+			}
+		}
+		if late > 0 {
+			return fmt.Errorf("%d of %d monitors are late", late, len(statuses))
+		}
+		return nil
+	}
+
+	if err := app.ConfigureFilters(cfg); err != nil {
+		return err
+	}
+	if _, err := app.ConfigureGeocoding(cfg); err != nil {
+		return err
+	}
+	ingester := ingest.New(db)
+	if err := ingester.SetPipelines(cfg.Pipeline.Default, cfg.Pipeline.Sources); err != nil {
+		return fmt.Errorf("invalid ingestion pipeline: %w", err)
+	}
+	runner := monitor.NewRunner(ingester, monitors)
+
+	if *once {
+		failed := 0
+		for _, m := range monitors {
+			if _, err := runner.Beat(m, time.Now()); err != nil {
+				log.Printf("Monitor %s: %v", m.Name, err)
+				failed++
+			}
+		}
+		app.WaitAlerts(cfg)
+		if failed > 0 {
+			return fmt.Errorf("%d of %d heartbeats failed", failed, len(monitors))
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for _, m := range monitors {
+		log.Printf("Sending the heartbeat of %s from %s every %s", m.Name, m.Source, m.Interval)
+	}
+	runner.Run(ctx)
+	app.WaitAlerts(cfg)
+	log.Println("Monitors stopped")
+	return nil
+}
+
+// benchmark posts synthetic events to a running API server at -rate for
+// -duration and reports the throughput and latency percentiles. The events
+// are stored like any others, so point it at a test instance.
+func benchmark(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	url := flags.String("url", "", "base URL of the API server; http://localhost:<server.port> when not set")
+	token := flags.String("token", "", "API token; server.api_token when not set")
+	rate := flags.Float64("rate", 100, "events per second to send; 0 sends as fast as the workers can")
+	duration := flags.Duration("duration", 30*time.Second, "how long to send events for")
+	concurrency := flags.Int("concurrency", 10, "requests in flight at most")
+	source := flags.String("source", "bench", "source to send the events with")
+	bodySize := flags.Int("body-size", 1024, "bytes of text in each event's body")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	flags.Parse(args)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if *url == "" {
+		*url = fmt.Sprintf("http://localhost:%d", cfg.Server.Port)
+	}
+	if *token == "" {
+		*token = cfg.Server.APIToken
+	}
+
+	// Ctrl-C stops early and still reports what was measured
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Sending events to %s at %g/s for %s", *url, *rate, *duration)
+	result, err := bench.Run(ctx, bench.Options{
+		URL:         *url,
+		Token:       *token,
+		Source:      *source,
+		Rate:        *rate,
+		Duration:    *duration,
+		Concurrency: *concurrency,
+		BodySize:    *bodySize,
+	})
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	result.WriteText(os.Stdout)
+	return nil
+}
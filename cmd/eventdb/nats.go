@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"example-api/internal/app"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"example-api/internal/stream"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// fetchWait is how long a JetStream pull waits for messages before trying again
+const fetchWait = 5 * time.Second
+
+// consumeNATS stores messages on the configured NATS subjects as events until
+// SIGINT or SIGTERM
+func consumeNATS(args []string) error {
+	flags := flag.NewFlagSet("consume-nats", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-nats")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if len(cfg.NATS.Subjects) == 0 {
+		return fmt.Errorf("nats.subjects must be configured")
+	}
+
+	options := []nats.Option{nats.Name("event-db"), nats.MaxReconnects(-1)}
+	if cfg.NATS.CredsFile != "" {
+		options = append(options, nats.UserCredentials(cfg.NATS.CredsFile))
+	}
+	nc, err := nats.Connect(cfg.NATS.URL, options...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	defer nc.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := &subscriber{db: db, batchSize: cfg.NATS.BatchSize}
+	if cfg.NATS.Stream != "" {
+		js, err := nc.JetStream()
+		if err != nil {
+			return fmt.Errorf("failed to open JetStream context: %w", err)
+		}
+		var subs []*nats.Subscription
+		for _, subject := range cfg.NATS.Subjects {
+			durable := durableName(cfg.NATS.Durable, subject)
+			sub, err := js.PullSubscribe(subject, durable, nats.BindStream(cfg.NATS.Stream), nats.ManualAck())
+			if err != nil {
+				return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+			}
+			log.Printf("Consuming %s from stream %s as durable consumer %s", subject, cfg.NATS.Stream, durable)
+			subs = append(subs, sub)
+		}
+		var wg sync.WaitGroup
+		for i, sub := range subs {
+			wg.Add(1)
+			go func(sub *nats.Subscription, subject string) {
+				defer wg.Done()
+				s.pull(ctx, sub, subject)
+			}(sub, cfg.NATS.Subjects[i])
+		}
+		wg.Wait()
+	} else {
+		// Core NATS doesn't keep messages, so anything published while no
+		// subscriber is running is lost; use a stream for durability
+		for _, subject := range cfg.NATS.Subjects {
+			subject := subject
+			_, err := nc.QueueSubscribe(subject, cfg.NATS.Queue, func(msg *nats.Msg) {
+				s.storeMessages(ctx, subject, []*nats.Msg{msg})
+			})
+			if err != nil {
+				return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+			}
+			log.Printf("Subscribed to %s in queue group %s", subject, cfg.NATS.Queue)
+		}
+		<-ctx.Done()
+	}
+
+	if err := nc.Drain(); err != nil {
+		log.Printf("Failed to drain NATS connection: %v", err)
+	}
+	app.WaitAlerts(cfg)
+	log.Println("NATS subscriber stopped")
+	return nil
+}
+
+// subscriber stores NATS messages as events
+type subscriber struct {
+	db        *database.Database
+	batchSize int
+}
+
+// pull fetches batches from a durable JetStream consumer until ctx is
+// cancelled. Messages are acknowledged only after they are stored; the rest
+// are redelivered once their ack wait expires, including after a restart.
+func (s *subscriber) pull(ctx context.Context, sub *nats.Subscription, subject string) {
+	for ctx.Err() == nil {
+		msgs, err := sub.Fetch(s.batchSize, nats.MaxWait(fetchWait))
+		if err != nil {
+			if !errors.Is(err, nats.ErrTimeout) {
+				log.Printf("Failed to fetch from %s: %v", subject, err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		if !s.storeMessages(ctx, subject, msgs) {
+			return
+		}
+		for _, msg := range msgs {
+			if err := msg.Ack(); err != nil {
+				log.Printf("Failed to acknowledge message on %s: %v", msg.Subject, err)
+			}
+		}
+	}
+}
+
+// storeMessages stores the messages received on a subscription to pattern,
+// retrying until they are stored. Messages that aren't valid events are logged
+// and skipped, and acknowledged with the rest so they aren't redelivered. It
+// reports false if ctx was cancelled before the messages were stored.
+func (s *subscriber) storeMessages(ctx context.Context, pattern string, msgs []*nats.Msg) bool {
+	events := make([]*models.EventRequest, 0, len(msgs))
+	for _, msg := range msgs {
+		event, err := decodeMessage(pattern, msg)
+		if err != nil {
+			_ = s.db.LogEventStatus(0, "error", fmt.Sprintf("nats %s: %v", msg.Subject, err))
+			continue
+		}
+		events = append(events, event)
+	}
+
+	stored, err := stream.StoreBatch(ctx, s.db, events)
+	if err != nil {
+		return false
+	}
+	log.Printf("Stored %d event(s) from %d message(s) on %s", stored, len(msgs), pattern)
+	return true
+}
+
+// decodeMessage turns a message into an event. The subject tokens matched by
+// the pattern's wildcards become tags. JSON objects are decoded like Kafka
+// messages; any other payload is stored as the event's text.
+func decodeMessage(pattern string, msg *nats.Msg) (*models.EventRequest, error) {
+	tags := stream.SubjectTags(pattern, msg.Subject)
+	if bytes.HasPrefix(bytes.TrimSpace(msg.Data), []byte("{")) {
+		return stream.DecodeEvent(msg.Data, "nats", tags...)
+	}
+
+	text := strings.TrimSpace(string(msg.Data))
+	if text == "" {
+		return nil, stream.ErrEmptyEvent
+	}
+	if len(tags) == 0 {
+		tags = []string{"untagged"}
+	}
+	for i, tag := range tags {
+		tags[i] = strings.ToLower(tag)
+	}
+	return &models.EventRequest{Tags: tags, Data: text, Source: "nats"}, nil
+}
+
+// durableName derives a consumer name for each subject, since consumer names
+// can't contain the subject's dots and wildcards
+func durableName(prefix, subject string) string {
+	name := strings.NewReplacer(".", "_", "*", "any", ">", "all").Replace(subject)
+	return prefix + "_" + name
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"example-api/internal/app"
+	"example-api/internal/poller"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// poll fetches the configured URLs on their intervals and stores new items as
+// events until SIGINT or SIGTERM
+func poll(args []string) error {
+	flags := flag.NewFlagSet("poll", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-poller")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if len(cfg.Pollers) == 0 {
+		return fmt.Errorf("no pollers are configured")
+	}
+
+	var pollers []*poller.Poller
+	names := make(map[string]bool)
+	for _, c := range cfg.Pollers {
+		p := &poller.Poller{
+			Name:     c.Name,
+			URL:      c.URL,
+			Interval: c.Interval,
+			Format:   c.Format,
+			Selector: c.Selector,
+			Headers:  c.Headers,
+			Tags:     c.Tags,
+			Source:   c.Source,
+			Backfill: c.Backfill,
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("invalid poller configuration: %w", err)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("invalid poller configuration: duplicate poller name %s", p.Name)
+		}
+		names[p.Name] = true
+		pollers = append(pollers, p)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, p := range pollers {
+		log.Printf("Polling %s (%s) every %s as %s", p.URL, p.Format, p.Interval, p.Name)
+	}
+	poller.NewRunner(db, pollers).Run(ctx)
+	app.WaitAlerts(cfg)
+	log.Println("URL poller stopped")
+	return nil
+}
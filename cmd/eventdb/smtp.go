@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"example-api/internal/alert"
+	"example-api/internal/app"
+	"example-api/internal/config"
+	"example-api/internal/ingest"
+	"example-api/internal/smtpd"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// serveSMTP accepts mail on inbound_smtp.listen and stores each message as an
+// event until SIGINT or SIGTERM
+func serveSMTP(args []string) error {
+	flags := flag.NewFlagSet("serve-smtp", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, db, err := setup("eventdb-smtp")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	inbound := cfg.InboundSMTP
+	hostname := inbound.Hostname
+	if hostname == "" {
+		if hostname, err = os.Hostname(); err != nil {
+			hostname = "localhost"
+		}
+	}
+
+	if err := app.ConfigureFilters(cfg); err != nil {
+		return err
+	}
+	if _, err := app.ConfigureGeocoding(cfg); err != nil {
+		return err
+	}
+	ingester := ingest.New(db)
+	if err := ingester.SetPipelines(cfg.Pipeline.Default, cfg.Pipeline.Sources); err != nil {
+		return fmt.Errorf("invalid ingestion pipeline: %w", err)
+	}
+	app.OnReload("SMTP ingestion", func(cfg *config.Config) error {
+		if err := app.ConfigureFilters(cfg); err != nil {
+			return err
+		}
+		return ingester.SetPipelines(cfg.Pipeline.Default, cfg.Pipeline.Sources)
+	})
+	server := &smtpd.Server{
+		Hostname:       hostname,
+		MaxMessageSize: inbound.MaxMessageSize,
+		AllowedDomains: inbound.AllowedDomains,
+		Handler: func(env *smtpd.Envelope) error {
+			email, err := ingest.ParseMessage(env.Data)
+			if err != nil {
+				return err
+			}
+			email.ReceivedFrom = fmt.Sprintf("%s (%s)", env.Helo, env.RemoteAddr)
+			email.AuthenticatedAs = env.AuthenticatedAs
+			email.Recipients = env.To
+			email.Helo, email.MailFrom = env.Helo, env.From
+			// SPF says nothing about mail relayed locally or submitted with credentials
+			if host, _, err := net.SplitHostPort(env.RemoteAddr); err == nil && env.AuthenticatedAs == "" {
+				if ip := net.ParseIP(host); ip != nil && !ip.IsLoopback() {
+					email.ClientIP = ip
+				}
+			}
+			if email.From == "" {
+				email.From = env.From
+			}
+			if email.To == "" {
+				email.To = strings.Join(env.To, ", ")
+			}
+
+			event, created, err := ingester.Ingest(email, inbound.Source)
+			if errors.Is(err, ingest.ErrRejected) {
+				return &smtpd.Error{Code: 550, Message: "Message rejected"}
+			}
+			if errors.Is(err, ingest.ErrQuarantined) {
+				// Accepted for review; the sender needn't know
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if created {
+				log.Printf("Stored message from %s as event %d", env.From, event.ID)
+			}
+			return nil
+		},
+	}
+
+	if inbound.CertFile != "" && inbound.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(inbound.CertFile, inbound.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+	if inbound.RequireAuth {
+		if cfg.Server.APIToken == "" {
+			return fmt.Errorf("inbound_smtp.require_auth is set but no API token is configured")
+		}
+		server.AuthPassword = cfg.Server.APIToken
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	app.WatchReload(ctx, cfg)
+	serveErr := make(chan error, 1)
+	go func() {
+		var err error
+		if l, ok := app.ActivatedListener(app.ListenerSMTP, inbound.Listen); ok {
+			log.Printf("SMTP receiver ready. Listening on %s as %s", l.Addr(), hostname)
+			err = server.Serve(l)
+		} else {
+			log.Printf("SMTP receiver ready. Listening on %s as %s", inbound.Listen, hostname)
+			err = server.ListenAndServe(inbound.Listen)
+		}
+		if err != nil && err != smtpd.ErrServerClosed {
+			serveErr <- fmt.Errorf("failed to start SMTP receiver: %w", err)
+		}
+	}()
+
+	// Idle clients are told to come back later; messages being received are
+	// stored first. A second signal stops the receiver immediately.
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+	log.Printf("Shutting down, waiting up to %s for messages in progress", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Messages were still in progress at the shutdown timeout: %v", err)
+	}
+	if err := ingest.WaitForwards(shutdownCtx); err != nil {
+		log.Printf("Events were still being forwarded at the shutdown timeout: %v", err)
+	}
+	if err := alert.Wait(shutdownCtx); err != nil {
+		log.Printf("Alert notifications were still being sent at the shutdown timeout: %v", err)
+	}
+	log.Println("SMTP receiver stopped")
+	return nil
+}
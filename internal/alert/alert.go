@@ -0,0 +1,392 @@
+// Package alert notifies webhooks, email addresses and Slack channels of
+// received events matching alert rules, of matching events that are due
+// soon or overdue, and of sources that went silent
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"example-api/internal/database"
+	"example-api/internal/mailer"
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// deliveryAttempts is how many times a notification is tried
+	deliveryAttempts = 3
+	// deliveryBackoff is the wait before the first retry; it doubles after each
+	deliveryBackoff = 2 * time.Second
+	// deliveryTimeout bounds each webhook or Slack attempt
+	deliveryTimeout = 10 * time.Second
+	// excerptLength is how much of the event's data notifications quote
+	excerptLength = 500
+)
+
+// Channels lists the ways a rule can notify
+var Channels = []string{"webhook", "email", "slack"}
+
+// client delivers webhook and Slack notifications
+var client = &http.Client{Timeout: deliveryTimeout}
+
+var (
+	// deliveries tracks notifications being sent in the background
+	deliveries        sync.WaitGroup
+	pendingDeliveries atomic.Int64
+)
+
+// Wait waits for notifications being sent to be delivered or given up on.
+// It returns ctx's error if ctx is done first.
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		deliveries.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		slog.Warn("Stopped waiting for alert notifications", "pending", pendingDeliveries.Load())
+		return ctx.Err()
+	}
+}
+
+// Notifier sends the notifications of the rules events match
+type Notifier struct {
+	db      *database.Database
+	mailer  *mailer.Mailer
+	baseURL string // Where the web interface is reached, for links to events
+}
+
+// New creates a Notifier sending email through m. Notifications link to
+// events under baseURL when it is set.
+func New(db *database.Database, m *mailer.Mailer, baseURL string) *Notifier {
+	return &Notifier{db: db, mailer: m, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Watch checks every event stored through the Notifier's database against
+// the alert rules
+func (n *Notifier) Watch() {
+	n.db.OnEventStored(n.Check)
+}
+
+// Check sends the notifications of the enabled rules event matches in the
+// background
+func (n *Notifier) Check(event *models.Event) {
+	rules, err := n.db.EnabledAlertRules()
+	if err != nil {
+		slog.Error("Failed to load alert rules", "event_id", event.ID, "error", err)
+		return
+	}
+	for i := range rules {
+		if !Matches(&rules[i], event) {
+			continue
+		}
+		rule := rules[i]
+		deliveries.Add(1)
+		pendingDeliveries.Add(1)
+		go func() {
+			defer deliveries.Done()
+			defer pendingDeliveries.Add(-1)
+			n.notify(&rule, event, "")
+		}()
+	}
+}
+
+// Remind sends the notifications of the enabled rules event matches in the
+// background, saying that it is due soon or overdue (stage is
+// models.DueSoon or models.DueOverdue). It returns how many rules matched.
+func (n *Notifier) Remind(event *models.Event, stage string) (int, error) {
+	rules, err := n.db.EnabledAlertRules()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load alert rules: %w", err)
+	}
+	matched := 0
+	for i := range rules {
+		if !Matches(&rules[i], event) {
+			continue
+		}
+		matched++
+		rule := rules[i]
+		deliveries.Add(1)
+		pendingDeliveries.Add(1)
+		go func() {
+			defer deliveries.Done()
+			defer pendingDeliveries.Add(-1)
+			n.notify(&rule, event, stage)
+		}()
+	}
+	return matched, nil
+}
+
+// Matches reports whether event meets every condition the rule sets
+func Matches(rule *models.AlertRule, event *models.Event) bool {
+	if len(rule.Tags) > 0 && !hasAnyTag(event.Tags, rule.Tags) {
+		return false
+	}
+	if rule.Source != "" && !strings.EqualFold(rule.Source, event.Source) {
+		return false
+	}
+	if rule.Keyword != "" && !strings.Contains(strings.ToLower(event.Data), strings.ToLower(rule.Keyword)) {
+		return false
+	}
+	return true
+}
+
+// Validate checks that a rule has a name, a condition and a usable target
+// for its channel
+func Validate(rule *models.AlertRule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("the rule needs a name")
+	}
+	if len(rule.Tags) == 0 && rule.Source == "" && rule.Keyword == "" {
+		return fmt.Errorf("the rule needs a tag, source or keyword to match")
+	}
+	return validateTarget(rule.Channel, rule.Target)
+}
+
+// validateTarget checks that target is usable for channel
+func validateTarget(channel, target string) error {
+	switch channel {
+	case "webhook", "slack":
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			return fmt.Errorf("the %s URL must start with http:// or https://", channel)
+		}
+	case "email":
+		if _, err := mail.ParseAddress(target); err != nil {
+			return fmt.Errorf("invalid email address %q", target)
+		}
+	default:
+		return fmt.Errorf("unknown channel %q, want one of %s", channel, strings.Join(Channels, ", "))
+	}
+	return nil
+}
+
+// Test sends the rule's notification for a made-up event, once, so the
+// channel can be checked without waiting for a matching event
+func (n *Notifier) Test(rule *models.AlertRule) error {
+	event := &models.Event{
+		Tags:      rule.Tags,
+		Source:    rule.Source,
+		Data:      fmt.Sprintf("Test notification for the alert rule %q", rule.Name),
+		CreatedAt: time.Now(),
+	}
+	if event.Source == "" {
+		event.Source = "eventdb"
+	}
+	_, err := n.send(rule, event, "")
+	return err
+}
+
+// notify sends the rule's notification for event, or its reminder that the
+// event is due soon or overdue when reminder is set, retrying failed
+// attempts, and records the outcome in the event's ingestion log
+func (n *Notifier) notify(rule *models.AlertRule, event *models.Event, reminder string) {
+	backoff := deliveryBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.send(rule, event, reminder)
+		if err == nil {
+			slog.Info("Sent alert", "rule", rule.Name, "event_id", event.ID, "channel", rule.Channel, "reminder", reminder)
+			message := fmt.Sprintf("rule %q notified %s %s", rule.Name, rule.Channel, rule.Target)
+			if reminder != "" {
+				message += fmt.Sprintf(" that the event is %s", dueState(reminder))
+			}
+			if err := n.db.LogEventStatus(event.ID, "alerted", message); err != nil {
+				slog.Warn("Failed to log alert", "event_id", event.ID, "error", err)
+			}
+			if err := n.db.TouchAlertRule(rule.ID); err != nil {
+				slog.Warn("Failed to update alert rule", "rule_id", rule.ID, "error", err)
+			}
+			return
+		}
+
+		if !retry || attempt == deliveryAttempts {
+			slog.Error("Giving up sending alert", "rule", rule.Name, "event_id", event.ID, "channel", rule.Channel, "error", err)
+			message := fmt.Sprintf("rule %q failed to notify %s %s after %d attempt(s): %v", rule.Name, rule.Channel, rule.Target, attempt, err)
+			if err := n.db.LogEventStatus(event.ID, "alert_failed", message); err != nil {
+				slog.Warn("Failed to log alert", "event_id", event.ID, "error", err)
+			}
+			return
+		}
+
+		slog.Warn("Sending alert failed, retrying", "rule", rule.Name, "event_id", event.ID, "attempt", attempt, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// send makes one attempt at the rule's notification, or its reminder,
+// reporting whether a failure is worth retrying
+func (n *Notifier) send(rule *models.AlertRule, event *models.Event, reminder string) (retry bool, err error) {
+	switch rule.Channel {
+	case "webhook":
+		body, err := json.Marshal(struct {
+			Rule     ruleRef       `json:"rule"`
+			Event    *models.Event `json:"event"`
+			URL      string        `json:"url,omitempty"`
+			Reminder string        `json:"reminder,omitempty"`
+		}{ruleRef{rule.ID, rule.Name}, event, n.eventURL(event), reminder})
+		if err != nil {
+			return false, fmt.Errorf("failed to encode notification: %w", err)
+		}
+		return post(rule.Target, event.ID, body)
+	case "slack":
+		body, err := json.Marshal(map[string]string{"text": n.slackText(rule, event, reminder)})
+		if err != nil {
+			return false, fmt.Errorf("failed to encode notification: %w", err)
+		}
+		return post(rule.Target, event.ID, body)
+	case "email":
+		// The relay may be down for a moment, so failures are retried
+		return true, n.mailer.Send(rule.Target, n.subject(rule, event, reminder), n.emailBody(rule, event, reminder))
+	default:
+		return false, fmt.Errorf("unknown channel %q", rule.Channel)
+	}
+}
+
+// ruleRef identifies the rule in webhook payloads
+type ruleRef struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// post makes one webhook or Slack delivery attempt. Network errors, 429 and
+// 5xx responses are worth retrying, other statuses aren't.
+func post(endpoint string, eventID int64, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "event-db-alerts/1.0")
+	if eventID != 0 {
+		req.Header.Set("X-Event-ID", strconv.FormatInt(eventID, 10))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("endpoint responded %s", resp.Status)
+}
+
+// eventURL links to the event in the web interface, if its address is known
+func (n *Notifier) eventURL(event *models.Event) string {
+	if n.baseURL == "" || event.ID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/events/%d", n.baseURL, event.ID)
+}
+
+// subject is the email subject of a notification
+func (n *Notifier) subject(rule *models.AlertRule, event *models.Event, reminder string) string {
+	switch reminder {
+	case models.DueSoon:
+		return fmt.Sprintf("[%s] Due soon: %s", rule.Name, firstLine(event.Data))
+	case models.DueOverdue:
+		return fmt.Sprintf("[%s] Overdue: %s", rule.Name, firstLine(event.Data))
+	}
+	return fmt.Sprintf("[%s] %s", rule.Name, firstLine(event.Data))
+}
+
+// emailBody describes the event in plain text
+func (n *Notifier) emailBody(rule *models.AlertRule, event *models.Event, reminder string) string {
+	var b strings.Builder
+	if reminder != "" {
+		fmt.Fprintf(&b, "An event matching the alert rule %q is %s.\n\n", rule.Name, dueState(reminder))
+	} else {
+		fmt.Fprintf(&b, "The alert rule %q matched an event.\n\n", rule.Name)
+	}
+	if event.ID != 0 {
+		fmt.Fprintf(&b, "Event:  #%d\n", event.ID)
+	}
+	fmt.Fprintf(&b, "Source: %s\n", event.Source)
+	fmt.Fprintf(&b, "Tags:   %s\n", strings.Join(event.Tags, ", "))
+	fmt.Fprintf(&b, "Time:   %s\n", event.CreatedAt.Format(time.RFC1123))
+	if event.DueAt != nil {
+		fmt.Fprintf(&b, "Due:    %s\n", event.DueAt.Format(time.RFC1123))
+	}
+	if url := n.eventURL(event); url != "" {
+		fmt.Fprintf(&b, "Link:   %s\n", url)
+	}
+	fmt.Fprintf(&b, "\n%s\n", excerpt(event.Data))
+	return b.String()
+}
+
+// slackText describes the event in Slack's message formatting
+func (n *Notifier) slackText(rule *models.AlertRule, event *models.Event, reminder string) string {
+	title := fmt.Sprintf("*%s*: %s", slackEscape(rule.Name), slackEscape(firstLine(event.Data)))
+	if url := n.eventURL(event); url != "" {
+		title = fmt.Sprintf("*%s*: <%s|%s>", slackEscape(rule.Name), url, slackEscape(firstLine(event.Data)))
+	}
+	if reminder != "" && event.DueAt != nil {
+		title += fmt.Sprintf(" is %s, due <!date^%d^{date_short_pretty} {time}|%s>",
+			dueState(reminder), event.DueAt.Unix(), event.DueAt.UTC().Format(time.RFC1123))
+	}
+	return fmt.Sprintf("%s\nSource: %s · Tags: %s\n```%s```",
+		title, slackEscape(event.Source), slackEscape(strings.Join(event.Tags, ", ")), slackEscape(excerpt(event.Data)))
+}
+
+// slackEscape escapes the characters Slack treats as markup
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// firstLine returns the first non-empty line of s, shortened to fit a subject
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if runes := []rune(line); len(runes) > 80 {
+				return string(runes[:80]) + "…"
+			}
+			return line
+		}
+	}
+	return "(no data)"
+}
+
+// excerpt returns the start of the event's data
+func excerpt(s string) string {
+	if runes := []rune(s); len(runes) > excerptLength {
+		return string(runes[:excerptLength]) + "…"
+	}
+	return s
+}
+
+// hasAnyTag reports whether tags contains any of wanted, ignoring case
+func hasAnyTag(tags, wanted []string) bool {
+	for _, want := range wanted {
+		for _, tag := range tags {
+			if strings.EqualFold(tag, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dueState describes a reminder's stage, such as "overdue"
+func dueState(reminder string) string {
+	if reminder == models.DueSoon {
+		return "due soon"
+	}
+	return reminder
+}
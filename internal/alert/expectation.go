@@ -0,0 +1,230 @@
+package alert
+
+import (
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// minExpectationWindow keeps expectations from being checked against
+// windows shorter than the cron interval they are checked on
+const minExpectationWindow = 5 * time.Minute
+
+// ValidateExpectation checks that an expectation has a name, a condition, a
+// usable window and a usable target for its channel
+func ValidateExpectation(exp *models.Expectation) error {
+	if strings.TrimSpace(exp.Name) == "" {
+		return fmt.Errorf("the expectation needs a name")
+	}
+	if exp.Source == "" && len(exp.Tags) == 0 {
+		return fmt.Errorf("the expectation needs a source or tags to match")
+	}
+	if exp.MinEvents < 1 {
+		return fmt.Errorf("at least one event must be expected")
+	}
+	if exp.Window < minExpectationWindow {
+		return fmt.Errorf("the window must be at least 5m")
+	}
+	return validateTarget(exp.Channel, exp.Target)
+}
+
+// CheckExpectation counts the events matching exp in the window ending at
+// now, without recording anything. The check is missed or recovered when
+// the expectation's state changed since it was last checked.
+func (n *Notifier) CheckExpectation(exp *models.Expectation, now time.Time) (*models.ExpectationCheck, error) {
+	count, last, err := n.db.ExpectationActivity(exp, now.Add(-exp.Window))
+	if err != nil {
+		return nil, err
+	}
+	check := &models.ExpectationCheck{Expectation: *exp, Events: count, LastEvent: last, CheckedAt: now}
+	switch {
+	case count >= exp.MinEvents && exp.MissedSince != nil:
+		check.State = models.ExpectationRecovered
+	case count >= exp.MinEvents:
+		check.State = models.ExpectationMet
+	case exp.MissedSince == nil && now.Sub(exp.CreatedAt) < exp.Window:
+		check.State = models.ExpectationPending
+	default:
+		check.State = models.ExpectationMissed
+	}
+	return check, nil
+}
+
+// Expect checks exp at now and notifies its channel when it became missed,
+// or met again, since the last check. It reports whether a notification was
+// sent; checks running at the same time notify once.
+func (n *Notifier) Expect(exp *models.Expectation, now time.Time) (*models.ExpectationCheck, bool, error) {
+	check, err := n.CheckExpectation(exp, now)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := n.db.MarkExpectationChecked(exp.ID, now); err != nil {
+		return nil, false, err
+	}
+
+	changed := false
+	switch {
+	case check.State == models.ExpectationMissed && exp.MissedSince == nil:
+		changed, err = n.db.MarkExpectationMissed(exp.ID, now)
+	case check.State == models.ExpectationRecovered:
+		changed, err = n.db.MarkExpectationMet(exp.ID)
+	}
+	if err != nil || !changed {
+		return check, false, err
+	}
+	if err := n.notifyExpectation(check); err != nil {
+		// Forget the miss so the next check notifies again
+		if check.State == models.ExpectationMissed {
+			if _, err := n.db.MarkExpectationMet(exp.ID); err != nil {
+				slog.Warn("Failed to reset expectation", "expectation_id", exp.ID, "error", err)
+			}
+		}
+		return check, false, err
+	}
+	return check, true, nil
+}
+
+// TestExpectation sends the notification of exp being missed, once, with
+// "(test)" after its name, so the channel can be checked without waiting
+// for the source to go silent
+func (n *Notifier) TestExpectation(exp *models.Expectation) error {
+	check := &models.ExpectationCheck{Expectation: *exp, State: models.ExpectationMissed, CheckedAt: time.Now()}
+	check.Expectation.Name += " (test)"
+	_, err := n.sendExpectation(check)
+	return err
+}
+
+// notifyExpectation sends the notification of a missed or recovered
+// expectation, retrying failed attempts
+func (n *Notifier) notifyExpectation(check *models.ExpectationCheck) error {
+	exp := &check.Expectation
+	backoff := deliveryBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.sendExpectation(check)
+		if err == nil {
+			slog.Info("Sent expectation notification", "expectation", exp.Name, "state", check.State, "channel", exp.Channel)
+			return nil
+		}
+		if !retry || attempt == deliveryAttempts {
+			return fmt.Errorf("expectation %q failed to notify %s %s after %d attempt(s): %w", exp.Name, exp.Channel, exp.Target, attempt, err)
+		}
+		slog.Warn("Sending expectation notification failed, retrying", "expectation", exp.Name, "attempt", attempt, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// sendExpectation makes one attempt at an expectation's notification,
+// reporting whether a failure is worth retrying
+func (n *Notifier) sendExpectation(check *models.ExpectationCheck) (retry bool, err error) {
+	exp := &check.Expectation
+	switch exp.Channel {
+	case "webhook":
+		body, err := json.Marshal(struct {
+			Expectation ruleRef    `json:"expectation"`
+			State       string     `json:"state"`
+			Source      string     `json:"source,omitempty"`
+			Tags        []string   `json:"tags,omitempty"`
+			Events      int        `json:"events"`
+			MinEvents   int        `json:"min_events"`
+			Window      string     `json:"window"`
+			LastEvent   *time.Time `json:"last_event"`
+			URL         string     `json:"url,omitempty"`
+		}{ruleRef{exp.ID, exp.Name}, check.State, exp.Source, exp.Tags, check.Events, exp.MinEvents,
+			exp.WindowText(), check.LastEvent, n.expectationURL(exp)})
+		if err != nil {
+			return false, fmt.Errorf("failed to encode notification: %w", err)
+		}
+		return post(exp.Target, 0, body)
+	case "slack":
+		body, err := json.Marshal(map[string]string{"text": n.expectationSlackText(check)})
+		if err != nil {
+			return false, fmt.Errorf("failed to encode notification: %w", err)
+		}
+		return post(exp.Target, 0, body)
+	case "email":
+		return true, n.mailer.Send(exp.Target, expectationSummary(check), n.expectationEmailBody(check))
+	default:
+		return false, fmt.Errorf("unknown channel %q", exp.Channel)
+	}
+}
+
+// expectationURL links to the expectation's events in the web interface, if
+// its address is known
+func (n *Notifier) expectationURL(exp *models.Expectation) string {
+	if n.baseURL == "" {
+		return ""
+	}
+	query := url.Values{}
+	if exp.Source != "" {
+		query.Set("source", exp.Source)
+	}
+	for _, tag := range exp.Tags {
+		query.Add("tag", tag)
+	}
+	return n.baseURL + "/?" + query.Encode()
+}
+
+// expectationSummary says what happened, such as "[Backups] No events from
+// backups in 1d"
+func expectationSummary(check *models.ExpectationCheck) string {
+	exp := &check.Expectation
+	if check.State == models.ExpectationRecovered {
+		return fmt.Sprintf("[%s] Events from %s arrive again", exp.Name, expectationSubject(exp))
+	}
+	if check.Events == 0 {
+		return fmt.Sprintf("[%s] No events from %s in %s", exp.Name, expectationSubject(exp), exp.WindowText())
+	}
+	return fmt.Sprintf("[%s] Only %d of %d events from %s in %s", exp.Name, check.Events, exp.MinEvents, expectationSubject(exp), exp.WindowText())
+}
+
+// expectationSubject describes the events an expectation matches
+func expectationSubject(exp *models.Expectation) string {
+	var parts []string
+	if exp.Source != "" {
+		parts = append(parts, exp.Source)
+	}
+	if len(exp.Tags) > 0 {
+		parts = append(parts, "tagged "+strings.Join(exp.Tags, " or "))
+	}
+	return strings.Join(parts, " ")
+}
+
+// expectationEmailBody describes the check in plain text
+func (n *Notifier) expectationEmailBody(check *models.ExpectationCheck) string {
+	exp := &check.Expectation
+	var b strings.Builder
+	if check.State == models.ExpectationRecovered {
+		fmt.Fprintf(&b, "The expectation %q is met again.\n\n", exp.Name)
+	} else {
+		fmt.Fprintf(&b, "The expectation %q was missed.\n\n", exp.Name)
+	}
+	fmt.Fprintf(&b, "Expected:   at least %d event(s) from %s every %s\n", exp.MinEvents, expectationSubject(exp), exp.WindowText())
+	fmt.Fprintf(&b, "Received:   %d in the last %s\n", check.Events, exp.WindowText())
+	if check.LastEvent != nil {
+		fmt.Fprintf(&b, "Last event: %s\n", check.LastEvent.Format(time.RFC1123))
+	} else {
+		fmt.Fprintf(&b, "Last event: never\n")
+	}
+	if link := n.expectationURL(exp); link != "" {
+		fmt.Fprintf(&b, "Link:       %s\n", link)
+	}
+	return b.String()
+}
+
+// expectationSlackText describes the check in Slack's message formatting
+func (n *Notifier) expectationSlackText(check *models.ExpectationCheck) string {
+	text := slackEscape(expectationSummary(check))
+	if link := n.expectationURL(&check.Expectation); link != "" {
+		text = fmt.Sprintf("<%s|%s>", link, text)
+	}
+	if check.LastEvent == nil {
+		return text + "\nLast event: never"
+	}
+	return fmt.Sprintf("%s\nLast event: <!date^%d^{date_short_pretty} {time}|%s>",
+		text, check.LastEvent.Unix(), check.LastEvent.UTC().Format(time.RFC1123))
+}
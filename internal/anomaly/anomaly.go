@@ -0,0 +1,212 @@
+// Package anomaly flags hours in which a source, a tag or all events together
+// saw far more or far fewer events than in the hours before. A sudden
+// silence from a busy source is often the real incident.
+package anomaly
+
+import (
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseline    = 7 * 24 * time.Hour
+	minBaseline        = 24 * time.Hour
+	defaultSensitivity = 3
+	defaultMinEvents   = 10
+)
+
+// Settings configure the detection
+type Settings struct {
+	Baseline    time.Duration // Hours each hour is compared with
+	Sensitivity float64       // Standard deviations from the mean that make an hour unusual
+	MinEvents   int           // Fewest events in a spike, and in an average hour of a series that can drop
+	Events      bool          // Store an event reporting each anomaly
+}
+
+// Validate checks the settings and fills in defaults
+func (s *Settings) Validate() error {
+	if s.Baseline == 0 {
+		s.Baseline = defaultBaseline
+	}
+	if s.Baseline < minBaseline {
+		return fmt.Errorf("anomalies: baseline must be at least %s", minBaseline)
+	}
+	if s.Sensitivity == 0 {
+		s.Sensitivity = defaultSensitivity
+	}
+	if s.Sensitivity < 0 {
+		return fmt.Errorf("anomalies: sensitivity must be positive")
+	}
+	if s.MinEvents == 0 {
+		s.MinEvents = defaultMinEvents
+	}
+	if s.MinEvents < 1 {
+		return fmt.Errorf("anomalies: min_events must be at least 1")
+	}
+	return nil
+}
+
+// Detector finds anomalies in the hourly event counts
+type Detector struct {
+	db       *database.Database
+	settings Settings
+}
+
+// NewDetector creates a detector. Settings must be valid.
+func NewDetector(db *database.Database, settings Settings) *Detector {
+	return &Detector{db: db, settings: settings}
+}
+
+// Detect compares the hour starting at hour with the baseline before it,
+// without recording anything
+func (d *Detector) Detect(hour time.Time) ([]models.Anomaly, error) {
+	hour = hour.UTC().Truncate(time.Hour)
+	baseline := int(d.settings.Baseline / time.Hour)
+	series, err := d.db.HourlyCounts(hour.Add(-time.Duration(baseline)*time.Hour), hour.Add(time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	var anomalies []models.Anomaly
+	for _, s := range series {
+		if ignored(s) {
+			continue
+		}
+		count := s.Counts[baseline]
+		direction, mean, stddev := Evaluate(s.Counts[:baseline], count, d.settings.Sensitivity, d.settings.MinEvents)
+		if direction == "" {
+			continue
+		}
+		anomalies = append(anomalies, models.Anomaly{
+			Kind:      s.Kind,
+			Name:      s.Name,
+			Hour:      hour,
+			Direction: direction,
+			Count:     count,
+			Expected:  mean,
+			StdDev:    stddev,
+		})
+	}
+	return anomalies, nil
+}
+
+// Run detects the anomalies of the last complete hour before now and
+// records the new ones, storing an event reporting each when the settings
+// ask for it. It returns the anomalies it recorded. Hourly counts older
+// than the baseline are pruned.
+func (d *Detector) Run(now time.Time) ([]models.Anomaly, error) {
+	hour := now.UTC().Truncate(time.Hour).Add(-time.Hour)
+	anomalies, err := d.Detect(hour)
+	if err != nil {
+		return nil, err
+	}
+
+	var recorded []models.Anomaly
+	for i := range anomalies {
+		anomaly := &anomalies[i]
+		ok, err := d.db.RecordAnomaly(anomaly)
+		if err != nil {
+			return recorded, err
+		}
+		if !ok {
+			continue
+		}
+		if d.settings.Events {
+			event, err := d.db.StoreEvent(Event(anomaly))
+			if err != nil {
+				return recorded, fmt.Errorf("failed to store anomaly event: %w", err)
+			}
+			if err := d.db.SetAnomalyEvent(anomaly.ID, event.ID); err != nil {
+				return recorded, err
+			}
+			anomaly.EventID = event.ID
+		}
+		recorded = append(recorded, *anomaly)
+	}
+
+	pruned, err := d.db.PruneHourlyCounts(hour.Add(-d.settings.Baseline))
+	if err != nil {
+		log.Printf("Failed to prune hourly counts: %v", err)
+	} else if pruned > 0 {
+		log.Printf("Pruned %d hourly counts older than the baseline", pruned)
+	}
+	return recorded, nil
+}
+
+// Evaluate compares the count of an hour with the counts of the hours
+// before it. It returns the anomaly's direction, or "" when the count is
+// usual, along with the baseline's mean and standard deviation.
+//
+// A spike has at least minEvents events and more than sensitivity standard
+// deviations above the mean. A drop has more than sensitivity standard
+// deviations below a mean of at least minEvents. The standard deviation is
+// taken to be at least the square root of the mean, the noise expected of
+// events arriving independently, so a very steady series doesn't flag
+// every small change.
+func Evaluate(baseline []int, count int, sensitivity float64, minEvents int) (direction string, mean, stddev float64) {
+	if len(baseline) == 0 {
+		return "", 0, 0
+	}
+	for _, n := range baseline {
+		mean += float64(n)
+	}
+	mean /= float64(len(baseline))
+	for _, n := range baseline {
+		stddev += (float64(n) - mean) * (float64(n) - mean)
+	}
+	stddev = math.Sqrt(stddev / float64(len(baseline)))
+
+	spread := math.Max(stddev, math.Max(math.Sqrt(mean), 1))
+	switch {
+	case count >= minEvents && float64(count) > mean+sensitivity*spread:
+		return models.AnomalySpike, mean, stddev
+	case mean >= float64(minEvents) && float64(count) < mean-sensitivity*spread:
+		return models.AnomalyDrop, mean, stddev
+	}
+	return "", mean, stddev
+}
+
+// Event builds the event reporting an anomaly. It is tagged "anomaly", so
+// alert rules can notify of it.
+func Event(anomaly *models.Anomaly) *models.EventRequest {
+	return &models.EventRequest{
+		Tags:   []string{models.AnomalyTag},
+		Source: models.AnomalySource,
+		Data: fmt.Sprintf("%s\n\n%d event(s) between %s and %s UTC on %s, where about %.1f per hour (standard deviation %.1f) were expected.",
+			Summary(anomaly), anomaly.Count, anomaly.Hour.Format("15:04"), anomaly.Hour.Add(time.Hour).Format("15:04"),
+			anomaly.Hour.Format("Jan 02, 2006"), anomaly.Expected, anomaly.StdDev),
+	}
+}
+
+// Summary says what happened, such as "Drop in events from billing"
+func Summary(anomaly *models.Anomaly) string {
+	what := "Drop in events"
+	if anomaly.Direction == models.AnomalySpike {
+		what = "Spike in events"
+	}
+	switch anomaly.Kind {
+	case "source":
+		return what + " from " + anomaly.Name
+	case "tag":
+		return what + " tagged " + anomaly.Name
+	default:
+		return what
+	}
+}
+
+// ignored reports whether a series is left out of detection: the events
+// reporting anomalies, and heartbeats, which arrive on a schedule
+func ignored(s models.HourlySeries) bool {
+	switch s.Kind {
+	case "source":
+		return strings.EqualFold(s.Name, models.AnomalySource)
+	case "tag":
+		return s.Name == models.AnomalyTag || s.Name == models.HeartbeatTag
+	}
+	return false
+}
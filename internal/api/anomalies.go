@@ -0,0 +1,53 @@
+package api
+
+import (
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListAnomalies handles GET /api/anomalies, listing the anomalies of
+// the last hours hours, most recent first. Anomalies count the events of
+// every project, so only callers who can see every project may list them.
+func (h *Handler) HandleListAnomalies(c *gin.Context) {
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+	if !access.All {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access to every project required"})
+		return
+	}
+
+	hours := 24
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		parsed, err := strconv.Atoi(hoursStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hours parameter"})
+			return
+		}
+		hours = parsed
+	}
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+			return
+		}
+		limit = parsed
+	}
+
+	anomalies, err := h.db.RecentAnomalies(time.Now().Add(-time.Duration(hours)*time.Hour), limit)
+	if err != nil {
+		log.Printf("Failed to list anomalies: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve anomalies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AnomalyResponse{Anomalies: anomalies, Total: len(anomalies)})
+}
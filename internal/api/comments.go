@@ -0,0 +1,127 @@
+package api
+
+import (
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetComments returns the comments on an event, oldest first
+func (h *Handler) HandleGetComments(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok {
+		return
+	}
+
+	comments, err := h.db.GetComments(event.ID)
+	if err != nil {
+		log.Printf("Failed to get comments on event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+		return
+	}
+	if comments == nil {
+		comments = []models.EventComment{}
+	}
+
+	c.JSON(http.StatusOK, models.EventCommentsResponse{Comments: comments, Total: len(comments)})
+}
+
+// HandleCreateComment adds a comment by the caller to an event
+func (h *Handler) HandleCreateComment(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	switch {
+	case body == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment body is empty"})
+		return
+	case len([]rune(body)) > models.MaxCommentLength:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Comments can be at most %d characters", models.MaxCommentLength)})
+		return
+	}
+
+	comment := &models.EventComment{EventID: event.ID, Author: c.GetString(authUserKey), Body: body}
+	if err := h.db.AddComment(comment); err != nil {
+		log.Printf("Failed to add comment to event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// HandleDeleteComment removes a comment. Only its author and admins can, and
+// only while they can see the event's project.
+func (h *Handler) HandleDeleteComment(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("commentID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID format"})
+		return
+	}
+
+	comment, err := h.db.GetComment(event.ID, id)
+	if err != nil {
+		log.Printf("Failed to get comment %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comment"})
+		return
+	}
+	if comment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if comment.Author != c.GetString(authUserKey) && c.GetString(authRoleKey) != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the comment's author or an admin can delete it"})
+		return
+	}
+
+	if err := h.db.DeleteComment(id); err != nil {
+		log.Printf("Failed to delete comment %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// requestedEvent loads the event named in the URL, responding with an error
+// if it doesn't exist or is in a project the caller can't see
+func (h *Handler) requestedEvent(c *gin.Context) (*models.Event, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	event, err := h.db.GetEventByID(id)
+	if err != nil {
+		log.Printf("Failed to get event: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		return nil, false
+	}
+	access, ok := h.access(c)
+	if !ok {
+		return nil, false
+	}
+	if event == nil || !access.CanView(event.ProjectID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return nil, false
+	}
+	return event, true
+}
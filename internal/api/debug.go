@@ -0,0 +1,111 @@
+package api
+
+import (
+	"example-api/internal/logging"
+	"example-api/internal/models"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// debugCapture is the set of sources whose POST /api/events request bodies
+// are captured to the debug_payloads table
+type debugCapture struct {
+	mu      sync.RWMutex // Guards the settings, which can be reloaded
+	sources map[string]bool
+	all     bool
+	keep    int
+}
+
+// SetDebugSources captures the raw request bodies POST /api/events receives
+// for sources, keeping the newest keep of each. "*" captures every request,
+// including those that don't decode. No sources turns capturing off.
+func (h *Handler) SetDebugSources(sources []string, keep int) {
+	set := make(map[string]bool, len(sources))
+	all := false
+	for _, source := range sources {
+		if source == "*" {
+			all = true
+		}
+		set[source] = true
+	}
+
+	h.debug.mu.Lock()
+	defer h.debug.mu.Unlock()
+	h.debug.sources, h.debug.all, h.debug.keep = set, all, keep
+	if len(sources) > 0 {
+		slog.Warn("Capturing raw event payloads", "sources", sources)
+	}
+}
+
+// debugging reports whether requests for source are captured, and how many
+// payloads of a source to keep
+func (h *Handler) debugging(source string) (bool, int) {
+	h.debug.mu.RLock()
+	defer h.debug.mu.RUnlock()
+	return h.debug.all || h.debug.sources[source], h.debug.keep
+}
+
+// captureDebugPayload stores the body of a request for source once it has
+// been answered, if source is being debugged. failure is why the event
+// wasn't stored, if it wasn't.
+func (h *Handler) captureDebugPayload(c *gin.Context, source string, body []byte, failure error) {
+	capture, keep := h.debugging(source)
+	if !capture {
+		return
+	}
+	payload := &models.DebugPayload{
+		Source:      source,
+		ContentType: c.GetHeader("Content-Type"),
+		RemoteAddr:  c.ClientIP(),
+		RequestID:   logging.RequestID(c.Request.Context()),
+		Status:      c.Writer.Status(),
+		Payload:     string(body),
+	}
+	if failure != nil {
+		payload.Error = failure.Error()
+	}
+	if err := h.db.SaveDebugPayload(payload, keep); err != nil {
+		log.Printf("Failed to capture debug payload: %v", err)
+	}
+}
+
+// HandleListDebugPayloads lists captured request bodies, newest first,
+// optionally of one source
+func (h *Handler) HandleListDebugPayloads(c *gin.Context) {
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+			return
+		}
+		limit = parsed
+	}
+
+	items, err := h.db.ListDebugPayloads(c.Query("source"), limit)
+	if err != nil {
+		log.Printf("Failed to list debug payloads: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve debug payloads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DebugPayloadsResponse{Items: items, Total: len(items)})
+}
+
+// HandleDeleteDebugPayloads discards captured request bodies, optionally only
+// those of one source
+func (h *Handler) HandleDeleteDebugPayloads(c *gin.Context) {
+	deleted, err := h.db.DeleteDebugPayloads(c.Query("source"))
+	if err != nil {
+		log.Printf("Failed to delete debug payloads: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete debug payloads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
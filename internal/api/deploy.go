@@ -0,0 +1,101 @@
+package api
+
+import (
+	"example-api/internal/inbound"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetDeploySecrets configures POST /ingest/deploy: the secret simple deploy
+// payloads are signed with and the tokens GitLab and Jenkins send. GitHub
+// Actions deliveries are checked with the GitHub webhook secret.
+func (h *Handler) SetDeploySecrets(secret, gitlabToken, jenkinsToken string) {
+	h.deploySecret = secret
+	h.gitlabToken = gitlabToken
+	h.jenkinsToken = jenkinsToken
+}
+
+// HandleDeploy records a deploy reported by GitHub Actions, GitLab CI,
+// Jenkins or anything posting the simple deploy schema. The provider is
+// told by the headers and the payload, and each is checked with its own
+// secret. The service and environment query parameters fill in what the
+// payload doesn't say.
+func (h *Handler) HandleDeploy(c *gin.Context) {
+	if h.githubSecret == "" && h.gitlabToken == "" && h.jenkinsToken == "" && h.deploySecret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Deploy ingestion is not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	var provider, secret string
+	var verify func() error
+	var deploy *inbound.Deploy
+	switch {
+	case c.GetHeader(inbound.GitHubEventHeader) != "":
+		provider, secret = inbound.DeployGitHub, h.githubSecret
+		verify = func() error { return inbound.VerifyGitHub(secret, c.GetHeader(inbound.GitHubSignatureHeader), body) }
+	case c.GetHeader(inbound.GitLabEventHeader) != "":
+		provider, secret = inbound.DeployGitLab, h.gitlabToken
+		verify = func() error { return inbound.VerifyToken(secret, c.GetHeader(inbound.GitLabTokenHeader)) }
+	case c.GetHeader(inbound.JenkinsTokenHeader) != "" || c.Query("token") != "" || inbound.IsJenkins(body):
+		// The Notification plugin can't set headers, so the token may be in
+		// the URL
+		token := c.GetHeader(inbound.JenkinsTokenHeader)
+		if token == "" {
+			token = c.Query("token")
+		}
+		provider, secret = inbound.DeployJenkins, h.jenkinsToken
+		verify = func() error { return inbound.VerifyToken(secret, token) }
+	default:
+		provider, secret = inbound.DeployGeneric, h.deploySecret
+		verify = func() error { return inbound.VerifyGitHub(secret, c.GetHeader(inbound.DeploySignatureHeader), body) }
+	}
+	if secret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Deploys from " + provider + " are not configured"})
+		return
+	}
+	if err := verify(); err != nil {
+		log.Printf("Rejected %s deploy webhook from %s: %v", provider, c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	switch provider {
+	case inbound.DeployGitHub:
+		if c.GetHeader(inbound.GitHubEventHeader) == "ping" {
+			c.JSON(http.StatusOK, gin.H{"message": "pong"})
+			return
+		}
+		deploy, err = inbound.ParseGitHubDeploy(c.GetHeader(inbound.GitHubEventHeader), body)
+	case inbound.DeployGitLab:
+		deploy, err = inbound.ParseGitLabDeploy(body)
+	case inbound.DeployJenkins:
+		deploy, err = inbound.ParseJenkinsDeploy(body)
+	default:
+		deploy, err = inbound.ParseDeploy(body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if deploy == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Not a deploy, ignored"})
+		return
+	}
+	if deploy.Service == "" {
+		deploy.Service = c.Query("service")
+	}
+	if deploy.Environment == "" {
+		deploy.Environment = c.Query("environment")
+	}
+
+	h.ingestWebhook(c, inbound.DeployEventRequest(provider, deploy))
+}
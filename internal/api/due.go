@@ -0,0 +1,53 @@
+package api
+
+import (
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleSetEventDue sets when an event is due, or clears it with
+// {"due_at": null}, and returns the event. The change is recorded in the
+// event's audit trail.
+func (h *Handler) HandleSetEventDue(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok || !h.canEdit(c, event.ProjectID) {
+		return
+	}
+
+	var req models.SetDueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format, due_at must be an RFC 3339 time or null"})
+		return
+	}
+
+	found, err := h.db.SetEventDue(event.ID, req.DueAt)
+	if err != nil {
+		log.Printf("Failed to set due date of event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set due date"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	updated := *event
+	updated.DueAt = req.DueAt
+	h.recordEventAudit(c, models.EventEdited, event.ID, event, &updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// dueFilter reads the due query parameter, responding with 400 unless it is
+// empty, soon or overdue
+func dueFilter(c *gin.Context) (string, bool) {
+	due := c.Query("due")
+	if due != "" && !slices.Contains(models.DueFilters, due) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid due parameter, expected soon or overdue"})
+		return "", false
+	}
+	return due, true
+}
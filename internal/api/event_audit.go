@@ -0,0 +1,113 @@
+package api
+
+import (
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordEventAudit adds a change made through the API to an event's audit
+// trail. A failure is only logged; the change itself has already been made.
+func (h *Handler) recordEventAudit(c *gin.Context, action string, id int64, before, after *models.Event) {
+	entry := &models.EventAuditEntry{EventID: id, Action: action, Actor: c.GetString(authUserKey), Old: before, New: after}
+	if err := h.db.RecordEventAudit(entry); err != nil {
+		log.Printf("Failed to record %s of event %d in the audit trail: %v", action, id, err)
+	}
+}
+
+// HandleGetEventAudit returns who changed an event and how, newest first
+func (h *Handler) HandleGetEventAudit(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	entries, err := h.db.GetEventAudit(id)
+	if err != nil {
+		log.Printf("Failed to get audit trail of event %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit trail"})
+		return
+	}
+	if entries == nil {
+		entries = []models.EventAuditEntry{}
+	}
+
+	// Entries outlive the event, so its project is taken from the newest
+	// snapshot
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+	if len(entries) > 0 {
+		snapshot := entries[0].New
+		if snapshot == nil {
+			snapshot = entries[0].Old
+		}
+		if snapshot != nil && !access.CanView(snapshot.ProjectID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.EventAuditResponse{Entries: entries, Total: len(entries)})
+}
+
+// HandleListEventAudit returns the most recent changes to any event,
+// optionally filtered by action
+func (h *Handler) HandleListEventAudit(c *gin.Context) {
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := h.db.ListEventAudit(c.Query("action"), limit)
+	if err != nil {
+		log.Printf("Failed to list event audit trail: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve audit trail"})
+		return
+	}
+	if entries == nil {
+		entries = []models.EventAuditEntry{}
+	}
+
+	c.JSON(http.StatusOK, models.EventAuditResponse{Entries: entries, Total: len(entries)})
+}
+
+// HandleRestoreEvent brings back a deleted event with its original ID
+func (h *Handler) HandleRestoreEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	event, err := h.db.RestoreEvent(id, c.GetString(authUserKey))
+	switch {
+	case errors.Is(err, database.ErrEventExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Event has not been deleted"})
+		return
+	case errors.Is(err, database.ErrDuplicateMessageID):
+		c.JSON(http.StatusConflict, gin.H{"error": "The event's email has been stored again since it was deleted"})
+		return
+	case err != nil:
+		log.Printf("Failed to restore event %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore event"})
+		return
+	case event == nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted event not found"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
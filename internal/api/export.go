@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"example-api/internal/export"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleExportEvents streams the events matching the query as CSV, a JSON
+// array or newline-delimited JSON, without loading them all into memory
+func (h *Handler) HandleExportEvents(c *gin.Context) {
+	format := c.DefaultQuery("format", "ndjson")
+
+	var tags []string
+	for _, tag := range c.QueryArray("tag") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	filter := models.EventFilter{
+		Tags:     tags,
+		MatchAll: c.Query("match") == "all",
+		DateFrom: c.Query("from"),
+		DateTo:   c.Query("to"),
+		Source:   c.Query("source"),
+		Query:    strings.TrimSpace(c.Query("q")),
+		SortBy:   "created_at",
+		SortDesc: c.Query("order") != "asc",
+	}
+	if c.Query("starred") == "true" {
+		filter.StarredBy = c.GetString(authUserKey)
+	}
+	metadata, ok := h.fieldFilters(c)
+	if !ok {
+		return
+	}
+	if filter.Status, ok = statusFilter(c); !ok {
+		return
+	}
+	if filter.Due, ok = dueFilter(c); !ok {
+		return
+	}
+	if filter.Labels, ok = h.labelFilter(c); !ok {
+		return
+	}
+	if filter.BBox, ok = bboxFilter(c); !ok {
+		return
+	}
+	if filter.Location, ok = h.timezoneFilter(c); !ok {
+		return
+	}
+	// Map tools have no use for events that can't be placed
+	filter.Located = format == "geojson"
+	filter.Metadata = metadata
+	if filter.Projects, ok = h.projectFilter(c); !ok {
+		return
+	}
+
+	count, err := export.Stream(c.Writer, format, func(fn func(models.Event) error) error {
+		return h.db.StreamEvents(filter, fn)
+	})
+	if errors.Is(err, export.ErrUnsupportedFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if count == 0 && err != nil {
+		log.Printf("Failed to export events: %v", err)
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export events"})
+		return
+	}
+	if err != nil {
+		// Headers are already sent, so the best we can do is log and truncate
+		log.Printf("Error exporting events after %d rows: %v", count, err)
+		return
+	}
+	log.Printf("Exported %d events as %s", count, format)
+}
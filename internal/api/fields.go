@@ -0,0 +1,168 @@
+package api
+
+import (
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldParamPrefix starts the query parameters filtering on custom fields,
+// such as field.severity=high
+const fieldParamPrefix = "field."
+
+// HandleListFields returns the custom fields events can have
+func (h *Handler) HandleListFields(c *gin.Context) {
+	fields, err := h.db.ListCustomFields()
+	if err != nil {
+		log.Printf("Failed to list custom fields: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve fields"})
+		return
+	}
+	if fields == nil {
+		fields = []models.CustomField{}
+	}
+
+	c.JSON(http.StatusOK, models.ListFieldsResponse{Fields: fields, Total: len(fields)})
+}
+
+// HandleCreateField defines a custom field
+func (h *Handler) HandleCreateField(c *gin.Context) {
+	var req models.CreateFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	field := &models.CustomField{Name: strings.TrimSpace(req.Name), Label: req.Label, Type: req.Type, Options: req.Options}
+	if err := field.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.CreateCustomField(field)
+	if errors.Is(err, database.ErrFieldExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A field with that name already exists"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create custom field: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create field"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, field)
+}
+
+// HandleDeleteField removes a custom field and its values from every event
+func (h *Handler) HandleDeleteField(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	field, err := h.db.DeleteCustomField(id)
+	if err != nil {
+		log.Printf("Failed to delete custom field %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete field"})
+		return
+	}
+	if field == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Field not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleSetEventFields sets an event's custom field values. Fields missing
+// from the body are left alone and null values remove a field's value.
+func (h *Handler) HandleSetEventFields(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok || !h.canEdit(c, event.ProjectID) {
+		return
+	}
+
+	var values models.Metadata
+	if err := c.ShouldBindJSON(&values); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	fields, err := h.db.ListCustomFields()
+	if err != nil {
+		log.Printf("Failed to list custom fields: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve fields"})
+		return
+	}
+	checked, err := models.CheckMetadata(fields, values)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	old := *event
+	metadata := models.Metadata{}
+	for name, value := range event.Metadata {
+		metadata[name] = value
+	}
+	for name := range values {
+		if value, ok := checked[name]; ok {
+			metadata[name] = value
+		} else {
+			delete(metadata, name)
+		}
+	}
+	event.Metadata = metadata
+
+	if err := h.db.UpdateEvent(event); err != nil {
+		log.Printf("Failed to set fields of event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+		return
+	}
+	h.recordEventAudit(c, models.EventEdited, event.ID, &old, event)
+
+	c.JSON(http.StatusOK, event)
+}
+
+// fieldFilters reads the field.<name> query parameters into the values events
+// must have, responding with an error for unknown fields or invalid values
+func (h *Handler) fieldFilters(c *gin.Context) (models.Metadata, bool) {
+	var filters models.Metadata
+	var fields []models.CustomField
+	for key, values := range c.Request.URL.Query() {
+		name, ok := strings.CutPrefix(key, fieldParamPrefix)
+		if !ok || len(values) == 0 || values[0] == "" {
+			continue
+		}
+		if fields == nil {
+			var err error
+			if fields, err = h.db.ListCustomFields(); err != nil {
+				log.Printf("Failed to list custom fields: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve fields"})
+				return nil, false
+			}
+		}
+
+		field := models.FieldByName(fields, name)
+		if field == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown field " + strconv.Quote(name)})
+			return nil, false
+		}
+		value, err := field.Parse(values[0])
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		if filters == nil {
+			filters = models.Metadata{}
+		}
+		filters[name] = value
+	}
+	return filters, true
+}
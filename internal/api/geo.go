@@ -0,0 +1,88 @@
+package api
+
+import (
+	"example-api/internal/geocode"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetGeocoder sets the geocoder for locations given without coordinates;
+// nil leaves them without
+func (h *Handler) SetGeocoder(geocoder geocode.Geocoder) {
+	h.geocoder = geocoder
+}
+
+// HandleSetEventLocation sets where an event happened and returns the event.
+// A location given without coordinates is geocoded; when that fails the
+// event keeps the location without coordinates. The change is recorded in
+// the event's audit trail.
+func (h *Handler) HandleSetEventLocation(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok || !h.canEdit(c, event.ProjectID) {
+		return
+	}
+
+	var req models.SetLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Latitude == nil {
+		var err error
+		if req.Latitude, req.Longitude, err = geocode.Locate(h.geocoder, req.Location); err != nil {
+			log.Printf("Failed to geocode %q for event %d: %v", req.Location, event.ID, err)
+		}
+	}
+
+	found, err := h.db.SetEventLocation(event.ID, req.Location, req.Latitude, req.Longitude)
+	if err != nil {
+		log.Printf("Failed to set location of event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set location"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	updated := *event
+	updated.Location, updated.Latitude, updated.Longitude = req.Location, req.Latitude, req.Longitude
+	h.recordEventAudit(c, models.EventEdited, event.ID, event, &updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// bboxFilter reads the bbox query parameter, west,south,east,north in
+// degrees, responding with 400 when it is malformed
+func bboxFilter(c *gin.Context) (*models.BoundingBox, bool) {
+	bbox := c.Query("bbox")
+	if bbox == "" {
+		return nil, true
+	}
+	box, err := models.ParseBoundingBox(bbox)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bbox parameter: " + err.Error()})
+		return nil, false
+	}
+	return box, true
+}
+
+// withinBox keeps the events located inside the box, if there is one
+func withinBox(events []models.Event, box *models.BoundingBox) []models.Event {
+	if box == nil {
+		return events
+	}
+	kept := []models.Event{}
+	for _, event := range events {
+		if box.Contains(event) {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
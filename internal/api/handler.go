@@ -2,50 +2,63 @@ package api
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"example-api/internal/auth"
 	"example-api/internal/database"
+	"example-api/internal/geocode"
+	"example-api/internal/inbound"
+	"example-api/internal/ingest"
+	"example-api/internal/logging"
 	"example-api/internal/models"
-	"example-api/internal/utils"
+	"example-api/internal/monitor"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
-	"reflect"
+	"slices"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	db *database.Database
+	db            *database.Database
+	ingester      *ingest.Ingester
+	queue         *ingest.Queue // Set when POST /api/events is ingested in the background
+	mappingDomain string
+	mappingLength int
+	mailgunKey    string
+	sendgridKey   *ecdsa.PublicKey
+	snsTopics     []string
+	sns           *inbound.SNSVerifier
+	slackSecret   string
+	slackKeywords []string
+	baseURL       string // Where the web interface is reached, for links in Slack command replies
+	githubSecret  string
+	deploySecret  string // Signs simple payloads to /ingest/deploy
+	gitlabToken   string
+	jenkinsToken  string
+	geocoder      geocode.Geocoder // Locates events given a location without coordinates
+	monitors      []*monitor.Monitor
+	debug         debugCapture
 }
 
 func New(db *database.Database) *Handler {
-	return &Handler{db: db}
+	return &Handler{db: db, ingester: ingest.New(db)}
 }
 
-// For debugging - prints struct field names and their json tags
-func init() {
-	t := reflect.TypeOf(struct {
-		Data struct {
-			From                    string              `json:"from"`
-			To                      string              `json:"to"`
-			Subject                 string              `json:"subject"`
-			Data                    string              `json:"body"`
-			Cc                      []string            `json:"cc,omitempty"`
-			Bcc                     []string            `json:"bcc,omitempty"`
-		} `json:"data"`
-	}{})
-	
-	field, _ := t.FieldByName("Data")
-	log.Printf("DEBUG STRUCT: Outer field 'Data' has json tag: %q", field.Tag.Get("json"))
-	
-	inner := field.Type
-	for i := 0; i < inner.NumField(); i++ {
-		f := inner.Field(i)
-		log.Printf("DEBUG STRUCT: Inner field %q has json tag: %q", f.Name, f.Tag.Get("json"))
-	}
+// SetPipelines configures the ingestion pipelines emails are run through
+func (h *Handler) SetPipelines(defaultNames []string, sources map[string][]string) error {
+	return h.ingester.SetPipelines(defaultNames, sources)
+}
+
+// SetQueue makes POST /api/events queue emails for workers to ingest,
+// answering 202 without waiting for them to be stored
+func (h *Handler) SetQueue(size, workers, batchSize int, batchWait time.Duration) {
+	h.queue = h.ingester.NewQueue(size, workers, batchSize, batchWait)
 }
 
 // AuthMiddleware checks for a valid token in the Authorization header
@@ -71,15 +84,27 @@ func AuthMiddleware(validToken string) gin.HandlerFunc {
 			return
 		}
 
-		log.Printf("Auth successful for %s %s", c.Request.Method, c.Request.URL.Path)
+		logging.SetUser(c.Request.Context(), "api-token")
+		c.Next()
+	}
+}
+
+// AllowlistMiddleware rejects requests from addresses outside the allowlist
+func AllowlistMiddleware(list *auth.IPAllowlist) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.RemoteIP()
+		if !list.Allows(ip) {
+			log.Printf("Rejected %s %s from %s: not in admin allowlist", c.Request.Method, c.Request.URL.Path, ip)
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
 // HandleEventReceive processes incoming event data
 func (h *Handler) HandleEventReceive(c *gin.Context) {
-	log.Printf("Received event request with Content-Type: %s", c.GetHeader("Content-Type"))
-
 	var incoming struct {
 		Data struct {
 			From                    string              `json:"from"`
@@ -101,109 +126,119 @@ func (h *Handler) HandleEventReceive(c *gin.Context) {
 			AuthenticatedAs         string              `json:"authenticated_as,omitempty"`
 			Headers                 map[string][]string `json:"headers,omitempty"`
 		} `json:"data"`
-		Source string `json:"source"`
+		Tags      []string `json:"tags,omitempty"` // Added to the tags the pipeline gives the event
+		Source    string   `json:"source"`
+		Project   string   `json:"project,omitempty"`  // ID or slug
+		Location  string   `json:"location,omitempty"` // Geocoded unless latitude and longitude are given
+		Latitude  *float64 `json:"latitude,omitempty"`
+		Longitude *float64 `json:"longitude,omitempty"`
 	}
 
-	// Capture raw JSON for debugging
+	// Keep the raw body so it can be captured when its source is debugged
 	var rawBody []byte
 	if c.Request.Body != nil {
 		rawBody, _ = io.ReadAll(c.Request.Body)
 		// Restore body for binding
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(rawBody))
-		log.Printf("DEBUG: Raw JSON body: %s", string(rawBody))
 	}
+	var failure error
+	defer func() { h.captureDebugPayload(c, incoming.Source, rawBody, failure) }()
 
 	if err := c.ShouldBindJSON(&incoming); err != nil {
-		log.Printf("Failed to decode JSON: %v", err)
-		log.Printf("Raw request body: %s", string(rawBody))
+		failure = err
+		slog.Warn("Failed to decode event request", "error", err, "content_type", c.GetHeader("Content-Type"), "bytes", len(rawBody))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
 	}
+	if err := models.ValidateCoordinates(incoming.Latitude, incoming.Longitude); err != nil {
+		failure = err
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slog.Debug("Decoded event request", "from", incoming.Data.From, "subject", incoming.Data.Subject, "message_id", incoming.Data.MessageID, "source", incoming.Source)
 
-	log.Printf("Decoded incoming data: %+v", incoming)
-	log.Printf("DEBUG: Body field from JSON: %q", incoming.Data.Data)
-
-	// Extract tags from subject
-	tags := strings.Fields(incoming.Data.Subject)
-	if len(tags) == 0 {
-		tags = []string{"untagged"}
+	project, ok := h.ingestProject(c, incoming.Project)
+	if !ok {
+		failure = fmt.Errorf("not allowed to add events to project %q", incoming.Project)
+		return
 	}
-	// Convert all tags to lowercase
-	for i, tag := range tags {
-		tags[i] = strings.ToLower(tag)
+
+	email := &ingest.Email{
+		Project:         project,
+		From:            incoming.Data.From,
+		To:              incoming.Data.To,
+		Cc:              incoming.Data.Cc,
+		Subject:         incoming.Data.Subject,
+		MessageID:       incoming.Data.MessageID,
+		InReplyTo:       incoming.Data.InReplyTo,
+		References:      incoming.Data.References,
+		Date:            incoming.Data.Date,
+		Body:            incoming.Data.Data,
+		PlainBody:       incoming.Data.PlainBody,
+		HTMLBody:        incoming.Data.HTMLBody,
+		ReceivedFrom:    incoming.Data.ReceivedFrom,
+		AuthenticatedAs: incoming.Data.AuthenticatedAs,
+		Headers:         incoming.Data.Headers,
+		Tags:            incoming.Tags,
+		Location:        incoming.Location,
+		Latitude:        incoming.Latitude,
+		Longitude:       incoming.Longitude,
 	}
-	log.Printf("Extracted tags: %v", tags)
 
-	// --- Begin: Extract only the inline MIME part if present ---
-	var contentToProcess string
-	// Check if Data field is empty, use PlainBody as fallback
-	if incoming.Data.Data == "" && incoming.Data.PlainBody != "" {
-		log.Printf("DEBUG: Data field (mapped from 'body' in JSON) is empty, using PlainBody instead")
-		log.Printf("DEBUG: PlainBody content: %q", incoming.Data.PlainBody)
-		contentToProcess = incoming.Data.PlainBody
-	} else {
-		log.Printf("DEBUG: Using Data field (mapped from 'body' in JSON): %q", incoming.Data.Data)
-		contentToProcess = incoming.Data.Data
-	}
-	
-	// Try a simple content extraction first - look for content after blank line
-	// This works for simple MIME messages that follow the standard format
-	actualContent := extractSimpleContent(contentToProcess)
-	if actualContent != "" {
-		log.Printf("Successfully extracted simple content: %q", actualContent)
-		dataToStore := actualContent
-		// Store in database
-		event := &models.EventRequest{
-			Tags:   tags,
-			Data:   dataToStore,
-			Source: incoming.Source,
-		}
-		log.Printf("Storing event with simple extraction: %+v", event)
-		storedEvent, err := h.db.StoreEvent(event)
-		if err != nil {
-			log.Printf("Failed to store event: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store event"})
+	if h.queue != nil {
+		if err := h.queue.Enqueue(email, incoming.Source); err != nil {
+			failure = err
+			log.Printf("Failed to queue event: %v", err)
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
 			return
 		}
-		log.Printf("Successfully stored event with ID: %d", storedEvent.ID)
-		c.JSON(http.StatusCreated, storedEvent)
+		c.JSON(http.StatusAccepted, gin.H{"message": "queued"})
 		return
 	}
-	
-	// If simple extraction didn't work, try the more complex MIME parsing
-	log.Printf("Simple extraction failed, trying MIME parsing for content: %q", contentToProcess)
-	plainData, err := utils.ExtractPlain([]byte(contentToProcess))
-	if err != nil {
-		log.Printf("Failed to extract plain data: %v", err)
-		plainData = contentToProcess // fallback to original
-	}
-	log.Printf("Result after MIME extraction: %q", plainData)
-	dataToStore := plainData
-	// --- End: Extract only the inline MIME part if present ---
 
-	// Store in database
-	event := &models.EventRequest{
-		Tags:   tags,
-		Data:   dataToStore,
-		Source: incoming.Source,
+	// A forwarder retrying a delivery gets the existing event back with 200
+	storedEvent, created, err := h.ingester.Ingest(email, incoming.Source)
+	failure = err
+	if errors.Is(err, ingest.ErrRejected) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ingest.ErrQuarantined) {
+		c.JSON(http.StatusAccepted, gin.H{"message": err.Error()})
+		return
 	}
-
-	log.Printf("Storing event: %+v", event)
-	log.Printf("DEBUG: EventRequest struct: tags=%v, data=%q (length=%d), source=%s", 
-		event.Tags, event.Data, len(event.Data), event.Source)
-	storedEvent, err := h.db.StoreEvent(event)
 	if err != nil {
 		log.Printf("Failed to store event: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store event"})
 		return
 	}
-
-	log.Printf("Successfully stored event with ID: %d", storedEvent.ID)
+	if !created {
+		c.JSON(http.StatusOK, storedEvent)
+		return
+	}
 	c.JSON(http.StatusCreated, storedEvent)
 }
 
 // HandleGetEventByID handles GET requests to retrieve an event by ID
 func (h *Handler) HandleGetEventByID(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok {
+		return
+	}
+	links, ok := h.eventLinks(c, event.ID)
+	if !ok {
+		return
+	}
+
+	// The event may be cached, so the links go on a copy
+	response := *event
+	response.Links = links
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleGetThread returns the email conversation an event belongs to, oldest first
+func (h *Handler) HandleGetThread(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -211,37 +246,97 @@ func (h *Handler) HandleGetEventByID(c *gin.Context) {
 		return
 	}
 
-	event, err := h.db.GetEventByID(id)
+	thread, err := h.db.GetThread(id)
 	if err != nil {
-		log.Printf("Failed to get event: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		log.Printf("Failed to get thread: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve thread"})
 		return
 	}
 
-	if event == nil {
+	// Messages of the thread in other projects are left out
+	visible, ok := h.visibleEvents(c, thread)
+	if !ok {
+		return
+	}
+	if !slices.ContainsFunc(visible, func(event models.Event) bool { return event.ID == id }) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, event)
+	c.JSON(http.StatusOK, visible)
+}
+
+// HandleCloneEvent creates a new event with the tags, data and source of an
+// existing one, in the same project
+func (h *Handler) HandleCloneEvent(c *gin.Context) {
+	original, ok := h.requestedEvent(c)
+	if !ok || !h.canEdit(c, original.ProjectID) {
+		return
+	}
+	id := original.ID
+
+	clone := &models.Event{
+		Tags:      original.Tags,
+		Data:      original.Data,
+		Source:    original.Source,
+		Metadata:  original.Metadata,
+		ProjectID: original.ProjectID,
+		CreatedAt: time.Now(),
+	}
+	if err := h.db.SaveEvent(clone); err != nil {
+		log.Printf("Failed to clone event %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone event"})
+		return
+	}
+
+	h.recordEventAudit(c, models.EventCreated, clone.ID, nil, clone)
+	log.Printf("Cloned event %d as %d", id, clone.ID)
+	c.JSON(http.StatusCreated, clone)
 }
 
-// HandleGetEventsByTag handles GET requests to retrieve events by tag
+// HandleGetEventsByTag handles GET requests to retrieve events by tag, by
+// label, inside a bounding box, or a combination of those
 func (h *Handler) HandleGetEventsByTag(c *gin.Context) {
 	tag := c.Query("tag")
-	if tag == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag parameter is required"})
+	labels, ok := h.labelFilter(c)
+	if !ok {
+		return
+	}
+	box, ok := bboxFilter(c)
+	if !ok {
+		return
+	}
+	if tag == "" && len(labels) == 0 && box == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag, label or bbox parameter is required"})
+		return
+	}
+	status, ok := statusFilter(c)
+	if !ok {
 		return
 	}
 
-	log.Printf("Searching for events with tag: %s", tag)
-	events, err := h.db.GetEventsByTag(tag)
+	var events []models.Event
+	var err error
+	if tag == "" {
+		events, _, err = h.db.ListEvents(models.EventFilter{Labels: labels, BBox: box, SortBy: "created_at", SortDesc: true})
+	} else {
+		log.Printf("Searching for events with tag: %s", tag)
+		events, err = h.db.GetEventsByTag(tag)
+	}
 	if err != nil {
 		log.Printf("Failed to get events by tag %q: %+v", tag, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to retrieve events: %v", err)})
 		return
 	}
 
+	events, ok = h.visibleEvents(c, events)
+	if !ok {
+		return
+	}
+	events = withStatus(events, status)
+	events = withLabels(events, labels)
+	events = withinBox(events, box)
+
 	log.Printf("Found %d events with tag %q", len(events), tag)
 	response := models.EventResponse{
 		Events: events,
@@ -251,41 +346,32 @@ func (h *Handler) HandleGetEventsByTag(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// extractSimpleContent tries to extract content from MIME messages by looking for content after headers
-func extractSimpleContent(content string) string {
-	// Split by lines
-	lines := strings.Split(content, "\n")
-	
-	// Find a blank line (which typically separates headers from content)
-	inContent := false
-	var contentLines []string
-	
-	for _, line := range lines {
-		trimmedLine := strings.TrimSpace(line)
-		
-		// When we find a blank line, we're transitioning into content
-		if trimmedLine == "" && !inContent {
-			inContent = true
-			continue
-		}
-		
-		// If we're in the content section and hit a boundary line, we're done
-		if inContent && strings.HasPrefix(trimmedLine, "--") {
-			break
-		}
-		
-		// If we're in content, collect the line
-		if inContent {
-			contentLines = append(contentLines, line)
+// HandleGetAuditLog handles GET requests to retrieve the audit log
+func (h *Handler) HandleGetAuditLog(c *gin.Context) {
+	action := c.Query("action")
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+			return
 		}
+		limit = parsed
 	}
-	
-	// Join the content lines
-	if len(contentLines) > 0 {
-		return strings.TrimSpace(strings.Join(contentLines, "\n"))
+
+	entries, err := h.db.GetAuditLog(action, limit)
+	if err != nil {
+		log.Printf("Failed to get audit log: %+v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to retrieve audit log: %v", err)})
+		return
+	}
+
+	response := models.AuditLogResponse{
+		Entries: entries,
+		Total:   len(entries),
 	}
-	
-	return ""
+
+	c.JSON(http.StatusOK, response)
 }
 
 // HandleGetEventsByDate handles GET requests to retrieve events by date (YYYY-MM-DD)
@@ -299,14 +385,38 @@ func (h *Handler) HandleGetEventsByDate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD."})
 		return
 	}
+	status, ok := statusFilter(c)
+	if !ok {
+		return
+	}
+	labels, ok := h.labelFilter(c)
+	if !ok {
+		return
+	}
+	box, ok := bboxFilter(c)
+	if !ok {
+		return
+	}
+	loc, ok := h.timezoneFilter(c)
+	if !ok {
+		return
+	}
 
-	events, err := h.db.GetEventsByDate(date)
+	events, err := h.db.GetEventsByDate(date, loc)
 	if err != nil {
 		log.Printf("Failed to get events by date %q: %+v", date, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to retrieve events: %v", err)})
 		return
 	}
 
+	events, ok = h.visibleEvents(c, events)
+	if !ok {
+		return
+	}
+	events = withStatus(events, status)
+	events = withLabels(events, labels)
+	events = withinBox(events, box)
+
 	log.Printf("Found %d events for date %q", len(events), date)
 	response := models.EventResponse{
 		Events: events,
@@ -315,3 +425,29 @@ func (h *Handler) HandleGetEventsByDate(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// timezoneFilter reads the tz query parameter, the IANA time zone dates are
+// days in, responding with 400 when it is unknown. Without it, callers signed
+// in to the web interface get the time zone of their settings and everyone
+// else UTC.
+func (h *Handler) timezoneFilter(c *gin.Context) (*time.Location, bool) {
+	name := c.Query("tz")
+	if name == "" {
+		if username := c.GetString(authUserKey); username != "" {
+			prefs, err := h.db.GetPreferences(username)
+			if err != nil {
+				log.Printf("Failed to load preferences of %s: %v", username, err)
+			}
+			if prefs != nil {
+				return prefs.Location(), true
+			}
+		}
+		return time.UTC, true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown time zone %q, expected a name such as Europe/Amsterdam", name)})
+		return nil, false
+	}
+	return loc, true
+}
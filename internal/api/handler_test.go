@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"example-api/internal/auth"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testDatabase connects to the database named by EVENTDB_TEST_DATABASE_URL
+// and migrates it, skipping the test when it isn't set
+func testDatabase(t *testing.T) *database.Database {
+	t.Helper()
+	connStr := os.Getenv("EVENTDB_TEST_DATABASE_URL")
+	if connStr == "" {
+		t.Skip("EVENTDB_TEST_DATABASE_URL is not set")
+	}
+	db, err := database.NewPostgres(connStr)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate("../../migrations"); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// A Message-ID stored in one project must not return that project's event to
+// a caller posting the same Message-ID to another project
+func TestEventReceiveDuplicateStaysInProject(t *testing.T) {
+	db := testDatabase(t)
+	gin.SetMode(gin.TestMode)
+
+	suffix := time.Now().UnixNano()
+	projectA := &models.Project{Slug: fmt.Sprintf("dedupe-a-%d", suffix), Name: "Dedupe A"}
+	projectB := &models.Project{Slug: fmt.Sprintf("dedupe-b-%d", suffix), Name: "Dedupe B"}
+	for _, project := range []*models.Project{projectA, projectB} {
+		if err := db.CreateProject(project); err != nil {
+			t.Fatalf("failed to create project: %v", err)
+		}
+	}
+
+	messageID := fmt.Sprintf("deploy-%d@ci", suffix)
+	secret, err := db.StoreEvent(&models.EventRequest{
+		Tags:      []string{"secret"},
+		Data:      "Project A only",
+		Source:    "test",
+		Email:     &models.EmailMetadata{MessageID: messageID},
+		ProjectID: projectA.ID,
+	})
+	if err != nil {
+		t.Fatalf("failed to store event: %v", err)
+	}
+
+	token, hash, err := auth.NewAPIToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.CreateAPIToken(&models.APIToken{
+		Name:           "dedupe",
+		TokenHash:      hash,
+		OrganizationID: models.DefaultOrganizationID,
+		ProjectID:      projectB.ID,
+		Role:           models.ProjectEditor,
+		CreatedBy:      "test",
+	})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/events", SessionAuthMiddleware("server-token", db, db), New(db).HandleEventReceive)
+	post := func() (int, models.Event) {
+		body := fmt.Sprintf(`{"data": {"subject": "Deployed", "body": "Project B", "message_id": %q}, "source": "test"}`, messageID)
+		req := httptest.NewRequest(http.MethodPost, "/api/events", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var event models.Event
+		json.Unmarshal(w.Body.Bytes(), &event)
+		return w.Code, event
+	}
+
+	code, event := post()
+	if code != http.StatusCreated {
+		t.Fatalf("first post to project B = %d, want %d", code, http.StatusCreated)
+	}
+	if event.ID == secret.ID || event.ProjectID != projectB.ID {
+		t.Fatalf("got event %d of project %d, want a new event in project %d", event.ID, event.ProjectID, projectB.ID)
+	}
+
+	// Redelivering to project B still returns project B's event
+	code, again := post()
+	if code != http.StatusOK || again.ID != event.ID {
+		t.Fatalf("second post = %d with event %d, want %d with event %d", code, again.ID, http.StatusOK, event.ID)
+	}
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"example-api/internal/inbound"
+	"example-api/internal/ingest"
+	"example-api/internal/models"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookSize caps provider webhook bodies, which carry whole messages
+const maxWebhookSize = 32 << 20
+
+// SetInboundProviders configures the provider webhooks. A provider without a
+// key, or SES without allowed topics, answers 503.
+func (h *Handler) SetInboundProviders(mailgunKey string, sendgridKey *ecdsa.PublicKey, snsTopics []string) {
+	h.mailgunKey = mailgunKey
+	h.sendgridKey = sendgridKey
+	h.snsTopics = snsTopics
+	h.sns = inbound.NewSNSVerifier()
+}
+
+// HandleMailgun receives messages from a Mailgun inbound route
+func (h *Handler) HandleMailgun(c *gin.Context) {
+	if h.mailgunKey == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Mailgun ingestion is not configured"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize)
+	form, err := webhookForm(c)
+	if err != nil {
+		log.Printf("Failed to parse Mailgun webhook: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	defer form.RemoveAll()
+
+	if err := inbound.VerifyMailgun(h.mailgunKey, c.Request.FormValue("timestamp"),
+		c.Request.FormValue("token"), c.Request.FormValue("signature")); err != nil {
+		log.Printf("Rejected Mailgun webhook from %s: %v", c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	email, err := inbound.ParseMailgun(form)
+	if err != nil {
+		log.Printf("Failed to parse Mailgun message: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message"})
+		return
+	}
+	h.ingestInbound(c, email, "mailgun")
+}
+
+// HandleSendGrid receives messages from SendGrid Inbound Parse
+func (h *Handler) HandleSendGrid(c *gin.Context) {
+	if h.sendgridKey == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SendGrid ingestion is not configured"})
+		return
+	}
+
+	// The signature covers the raw body, so read it before parsing the form
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if err := inbound.VerifySendGrid(h.sendgridKey, c.GetHeader(inbound.SendGridSignatureHeader),
+		c.GetHeader(inbound.SendGridTimestampHeader), body); err != nil {
+		log.Printf("Rejected SendGrid webhook from %s: %v", c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	form, err := webhookForm(c)
+	if err != nil {
+		log.Printf("Failed to parse SendGrid webhook: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	defer form.RemoveAll()
+
+	email, err := inbound.ParseSendGrid(form)
+	if err != nil {
+		log.Printf("Failed to parse SendGrid message: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message"})
+		return
+	}
+	h.ingestInbound(c, email, "sendgrid")
+}
+
+// HandleSESNotification receives SES receipt notifications delivered by an
+// SNS topic subscription, confirming the subscription when SNS asks
+func (h *Handler) HandleSESNotification(c *gin.Context) {
+	if len(h.snsTopics) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SES ingestion is not configured"})
+		return
+	}
+
+	// SNS sends JSON with a text/plain content type, so decode it directly
+	var msg inbound.SNSMessage
+	if err := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize)).Decode(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if !h.snsTopicAllowed(msg.TopicArn) {
+		log.Printf("Rejected SNS message from %s for topic %q", c.ClientIP(), msg.TopicArn)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Topic not allowed"})
+		return
+	}
+	if err := h.sns.Verify(&msg); err != nil {
+		log.Printf("Rejected SNS message from %s: %v", c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	switch msg.Type {
+	case inbound.SNSSubscriptionConfirmation:
+		if err := h.sns.Confirm(&msg); err != nil {
+			log.Printf("Failed to confirm SNS subscription to %s: %v", msg.TopicArn, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to confirm subscription"})
+			return
+		}
+		log.Printf("Confirmed SNS subscription to %s", msg.TopicArn)
+		c.JSON(http.StatusOK, gin.H{"message": "Subscription confirmed"})
+	case inbound.SNSUnsubscribeConfirmation:
+		log.Printf("Unsubscribed from SNS topic %s", msg.TopicArn)
+		c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed"})
+	case inbound.SNSNotification:
+		email, err := inbound.ParseSES(msg.Message)
+		if err != nil {
+			// Answer 200 so SNS doesn't retry a message that will never parse
+			log.Printf("Ignoring SNS notification %s: %v", msg.MessageID, err)
+			c.JSON(http.StatusOK, gin.H{"message": "Notification ignored"})
+			return
+		}
+		h.ingestInbound(c, email, "ses")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown SNS message type"})
+	}
+}
+
+// SetSlack configures the Slack webhook with the app's signing secret and the
+// keywords that become tags when a message mentions them
+func (h *Handler) SetSlack(signingSecret string, keywords []string) {
+	h.slackSecret = signingSecret
+	h.slackKeywords = keywords
+}
+
+// HandleSlack receives messages from the Slack Events API and workflow
+// webhook steps
+func (h *Handler) HandleSlack(c *gin.Context) {
+	if h.slackSecret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Slack ingestion is not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if err := inbound.VerifySlack(h.slackSecret, c.GetHeader(inbound.SlackSignatureHeader),
+		c.GetHeader(inbound.SlackTimestampHeader), body); err != nil {
+		log.Printf("Rejected Slack webhook from %s: %v", c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	payload, err := inbound.ParseSlack(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if payload.Type == "url_verification" {
+		c.JSON(http.StatusOK, gin.H{"challenge": payload.Challenge})
+		return
+	}
+	// Slack retries when it doesn't get an answer within three seconds; the
+	// first attempt was still being stored, so don't store the event twice
+	if c.GetHeader("X-Slack-Retry-Reason") == "http_timeout" {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	event := inbound.SlackEventRequest(payload, h.slackKeywords)
+	if event == nil {
+		c.Status(http.StatusOK)
+		return
+	}
+	h.ingestWebhook(c, event)
+}
+
+// SetGitHubSecret sets the secret GitHub webhooks are signed with
+func (h *Handler) SetGitHubSecret(secret string) {
+	h.githubSecret = secret
+}
+
+// HandleGitHub receives repository webhooks from GitHub
+func (h *Handler) HandleGitHub(c *gin.Context) {
+	if h.githubSecret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "GitHub ingestion is not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if err := inbound.VerifyGitHub(h.githubSecret, c.GetHeader(inbound.GitHubSignatureHeader), body); err != nil {
+		log.Printf("Rejected GitHub webhook from %s: %v", c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	eventType := c.GetHeader(inbound.GitHubEventHeader)
+	if eventType == "ping" {
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+		return
+	}
+	event, err := inbound.GitHubEventRequest(eventType, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if event == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Event type ignored"})
+		return
+	}
+	h.ingestWebhook(c, event)
+}
+
+// ingestInbound stores a provider's email, answering like HandleEventReceive
+func (h *Handler) ingestInbound(c *gin.Context, email *ingest.Email, source string) {
+	storedEvent, created, err := h.ingester.Ingest(email, source)
+	respondIngested(c, source, storedEvent, created, err)
+}
+
+// ingestWebhook stores an event built from a webhook through its source's
+// pipeline, answering like ingestInbound
+func (h *Handler) ingestWebhook(c *gin.Context, event *models.EventRequest) {
+	storedEvent, created, err := h.ingester.IngestEvent(event)
+	respondIngested(c, event.Source, storedEvent, created, err)
+}
+
+// respondIngested answers a provider with the outcome of an ingestion
+func respondIngested(c *gin.Context, source string, storedEvent *models.Event, created bool, err error) {
+	if errors.Is(err, ingest.ErrRejected) || errors.Is(err, ingest.ErrQuarantined) {
+		// Answer 200 so the provider doesn't retry a decision that won't change
+		c.JSON(http.StatusOK, gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to store %s event: %v", source, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store event"})
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, storedEvent)
+		return
+	}
+	c.JSON(http.StatusCreated, storedEvent)
+}
+
+// snsTopicAllowed reports whether arn is one of the configured topics
+func (h *Handler) snsTopicAllowed(arn string) bool {
+	for _, topic := range h.snsTopics {
+		if strings.EqualFold(topic, arn) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookForm parses a multipart or URL-encoded webhook body into one form
+func webhookForm(c *gin.Context) (*multipart.Form, error) {
+	form, err := c.MultipartForm()
+	if err == nil {
+		return form, nil
+	}
+	if !errors.Is(err, http.ErrNotMultipart) {
+		return nil, err
+	}
+	if err := c.Request.ParseForm(); err != nil {
+		return nil, err
+	}
+	return &multipart.Form{Value: c.Request.PostForm}, nil
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListIncidents lists the incidents of the projects the caller can
+// see, most recently active first, paged by limit and offset
+func (h *Handler) HandleListIncidents(c *gin.Context) {
+	limit, offset := 100, 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+			return
+		}
+		limit = parsed
+	}
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		parsed, err := strconv.Atoi(offsetStr)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+			return
+		}
+		offset = parsed
+	}
+	projects, ok := h.projectFilter(c)
+	if !ok {
+		return
+	}
+
+	incidents, total, err := h.db.ListIncidents(projects, limit, offset)
+	if err != nil {
+		log.Printf("Failed to list incidents: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve incidents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.IncidentResponse{Incidents: incidents, Total: total})
+}
+
+// HandleGetIncident returns an incident
+func (h *Handler) HandleGetIncident(c *gin.Context) {
+	incident, ok := h.requestedIncident(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, incident)
+}
+
+// HandleGetIncidentEvents returns the events grouped in an incident, newest
+// first
+func (h *Handler) HandleGetIncidentEvents(c *gin.Context) {
+	incident, ok := h.requestedIncident(c)
+	if !ok {
+		return
+	}
+
+	events, _, err := h.db.ListEvents(models.EventFilter{Incident: incident.ID, SortBy: "created_at", SortDesc: true})
+	if err == nil {
+		err = h.db.MarkIncidents(events)
+	}
+	if err != nil {
+		log.Printf("Failed to get events of incident %d: %v", incident.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.EventResponse{Events: events, Total: len(events)})
+}
+
+// requestedIncident loads the incident named in the URL, responding with an
+// error if it doesn't exist or is in a project the caller can't see
+func (h *Handler) requestedIncident(c *gin.Context) (*models.Incident, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	incident, err := h.db.GetIncident(id)
+	if err != nil {
+		log.Printf("Failed to get incident: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve incident"})
+		return nil, false
+	}
+	access, ok := h.access(c)
+	if !ok {
+		return nil, false
+	}
+	if incident == nil || !access.CanView(incident.ProjectID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return nil, false
+	}
+	return incident, true
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListLabels returns the labels events can have
+func (h *Handler) HandleListLabels(c *gin.Context) {
+	labels, err := h.db.ListLabels()
+	if err != nil {
+		log.Printf("Failed to list labels: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve labels"})
+		return
+	}
+	if labels == nil {
+		labels = []models.Label{}
+	}
+
+	c.JSON(http.StatusOK, models.ListLabelsResponse{Labels: labels, Total: len(labels)})
+}
+
+// HandleCreateLabel defines a label
+func (h *Handler) HandleCreateLabel(c *gin.Context) {
+	var req models.LabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	label := &models.Label{Name: req.Name, Color: req.Color, Description: req.Description}
+	if err := label.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.CreateLabel(label)
+	if errors.Is(err, database.ErrLabelExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A label with that name already exists"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create label: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create label"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, label)
+}
+
+// HandleUpdateLabel changes the color and description of a label
+func (h *Handler) HandleUpdateLabel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var req models.LabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	label := &models.Label{ID: id, Color: req.Color, Description: req.Description}
+	if err := label.ValidateStyle(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	found, err := h.db.UpdateLabel(label)
+	if err != nil {
+		log.Printf("Failed to update label %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update label"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+// HandleDeleteLabel removes a label and takes it off every event
+func (h *Handler) HandleDeleteLabel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	label, err := h.db.DeleteLabel(id)
+	if err != nil {
+		log.Printf("Failed to delete label %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete label"})
+		return
+	}
+	if label == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleSetEventLabels replaces an event's labels with the named ones and
+// returns the event. The change is recorded in the event's audit trail.
+func (h *Handler) HandleSetEventLabels(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok || !h.canEdit(c, event.ProjectID) {
+		return
+	}
+
+	var req models.SetLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	defined, err := h.db.ListLabels()
+	if err != nil {
+		log.Printf("Failed to list labels: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve labels"})
+		return
+	}
+	labels, err := models.CheckLabels(defined, req.Labels)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	found, err := h.db.SetEventLabels(event.ID, labels)
+	if err != nil {
+		log.Printf("Failed to set labels of event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set labels"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	updated := *event
+	updated.Labels = labels
+	h.recordEventAudit(c, models.EventEdited, event.ID, event, &updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// labelFilter reads the label query parameters, which can be repeated, into
+// the labels events must all have, responding with 400 for undefined labels
+func (h *Handler) labelFilter(c *gin.Context) ([]string, bool) {
+	var names []string
+	for _, name := range c.QueryArray("label") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, true
+	}
+
+	defined, err := h.db.ListLabels()
+	if err != nil {
+		log.Printf("Failed to list labels: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve labels"})
+		return nil, false
+	}
+	labels, err := models.CheckLabels(defined, names)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return labels, true
+}
+
+// withLabels keeps the events with all of the labels
+func withLabels(events []models.Event, labels []string) []models.Event {
+	if len(labels) == 0 {
+		return events
+	}
+	kept := []models.Event{}
+	for _, event := range events {
+		if !slices.ContainsFunc(labels, func(label string) bool { return !slices.Contains(event.Labels, label) }) {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetEventLinks returns the links from and to an event, oldest first,
+// leaving out those to events the caller can't see
+func (h *Handler) HandleGetEventLinks(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok {
+		return
+	}
+	links, ok := h.eventLinks(c, event.ID)
+	if !ok {
+		return
+	}
+	if links == nil {
+		links = []models.EventLink{}
+	}
+
+	c.JSON(http.StatusOK, models.EventLinksResponse{Links: links, Total: len(links)})
+}
+
+// HandleCreateEventLink links an event to another, such as an incident to the
+// deploy that caused it
+func (h *Handler) HandleCreateEventLink(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok || !h.canEdit(c, event.ProjectID) {
+		return
+	}
+
+	var req models.CreateLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format, a target_id and a type of caused-by, duplicate-of or follow-up are required"})
+		return
+	}
+	if req.TargetID == event.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An event can't be linked to itself"})
+		return
+	}
+	target, err := h.db.GetEventByID(req.TargetID)
+	if err != nil {
+		log.Printf("Failed to get event %d: %v", req.TargetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		return
+	}
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+	if target == nil || !access.CanView(target.ProjectID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown target event"})
+		return
+	}
+
+	link := &models.EventLink{EventID: event.ID, TargetID: target.ID, Type: req.Type, CreatedBy: c.GetString(authUserKey)}
+	if err := h.db.AddEventLink(link); err != nil {
+		if errors.Is(err, database.ErrLinkExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The events are already linked this way"})
+			return
+		}
+		log.Printf("Failed to link event %d to %d: %v", event.ID, target.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link events"})
+		return
+	}
+	link.Event = target
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// HandleDeleteEventLink removes a link from or to an event
+func (h *Handler) HandleDeleteEventLink(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok || !h.canEdit(c, event.ProjectID) {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("linkID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID format"})
+		return
+	}
+
+	deleted, err := h.db.DeleteEventLink(event.ID, id)
+	if err != nil {
+		log.Printf("Failed to delete link %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete link"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// eventLinks loads the links from and to an event that the caller can see
+// both ends of, responding with an error if they can't be loaded
+func (h *Handler) eventLinks(c *gin.Context, eventID int64) ([]models.EventLink, bool) {
+	links, err := h.db.GetEventLinks(eventID)
+	if err != nil {
+		log.Printf("Failed to get links of event %d: %v", eventID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve links"})
+		return nil, false
+	}
+	access, ok := h.access(c)
+	if !ok {
+		return nil, false
+	}
+
+	var visible []models.EventLink
+	for _, link := range links {
+		if access.CanView(link.Event.ProjectID) {
+			visible = append(visible, link)
+		}
+	}
+	return visible, true
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"example-api/internal/models"
+	"example-api/internal/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAddressLength is the length of the random part of generated
+// addresses when none is configured
+const defaultAddressLength = 12
+
+// SetMappingAddresses sets the domain and random part length of generated
+// mapping addresses
+func (h *Handler) SetMappingAddresses(domain string, length int) {
+	h.mappingDomain = domain
+	h.mappingLength = length
+}
+
+// HandleListMappings lists the caller's email mappings; admins see all of them
+func (h *Handler) HandleListMappings(c *gin.Context) {
+	owner := c.GetString(authUserKey)
+	if c.GetString(authRoleKey) == "admin" {
+		owner = ""
+	}
+
+	mappings, err := h.db.ListEmailMappings(owner)
+	if err != nil {
+		log.Printf("Failed to list mappings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve mappings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ListMappingsResponse{Mappings: mappings, Total: len(mappings)})
+}
+
+// HandleCreateMapping generates a new address routing mail to the requested
+// tags, source and endpoint
+func (h *Handler) HandleCreateMapping(c *gin.Context) {
+	if h.mappingDomain == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No mail domain configured (server.domain)"})
+		return
+	}
+
+	var req models.CreateMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if req.ProjectID == 0 {
+		req.ProjectID = tokenProject(c)
+	}
+	if !h.canEdit(c, req.ProjectID) {
+		return
+	}
+
+	length := h.mappingLength
+	if length <= 0 {
+		length = defaultAddressLength
+	}
+	address, err := utils.GenerateEmailAddress(length, h.mappingDomain)
+	if err != nil {
+		log.Printf("Failed to generate address: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate address"})
+		return
+	}
+
+	mapping := &models.EmailMapping{
+		Owner:          c.GetString(authUserKey),
+		GeneratedEmail: address,
+		Tags:           normalizeTags(req.Tags),
+		Source:         strings.TrimSpace(req.Source),
+		EndpointURL:    req.EndpointURL,
+		Description:    req.Description,
+		IsActive:       true,
+		ProjectID:      req.ProjectID,
+	}
+	if err := h.db.CreateEmailMapping(mapping); err != nil {
+		log.Printf("Failed to create mapping: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create mapping"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, mapping)
+}
+
+// HandleGetMapping returns one of the caller's mappings
+func (h *Handler) HandleGetMapping(c *gin.Context) {
+	mapping, ok := h.ownedMapping(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, mapping)
+}
+
+// HandleUpdateMapping replaces a mapping's tags, source, endpoint and
+// description, enables or disables it, and can move it to another project
+func (h *Handler) HandleUpdateMapping(c *gin.Context) {
+	mapping, ok := h.ownedMapping(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if req.ProjectID != 0 && req.ProjectID != mapping.ProjectID {
+		if !h.canEdit(c, req.ProjectID) {
+			return
+		}
+		mapping.ProjectID = req.ProjectID
+	}
+
+	mapping.Tags = normalizeTags(req.Tags)
+	mapping.Source = strings.TrimSpace(req.Source)
+	mapping.EndpointURL = req.EndpointURL
+	mapping.Description = req.Description
+	if req.IsActive != nil {
+		mapping.IsActive = *req.IsActive
+	}
+	if err := h.db.UpdateEmailMapping(mapping); err != nil {
+		log.Printf("Failed to update mapping %d: %v", mapping.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update mapping"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mapping)
+}
+
+// HandleDeleteMapping removes a mapping; mail to its address is no longer routed
+func (h *Handler) HandleDeleteMapping(c *gin.Context) {
+	mapping, ok := h.ownedMapping(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteEmailMapping(mapping.ID); err != nil {
+		log.Printf("Failed to delete mapping %d: %v", mapping.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete mapping"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ownedMapping loads the mapping named in the URL, answering 404 when it
+// doesn't exist or belongs to someone else and the caller isn't an admin
+func (h *Handler) ownedMapping(c *gin.Context) (*models.EmailMapping, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+
+	mapping, err := h.db.GetEmailMapping(id)
+	if err != nil {
+		log.Printf("Failed to get mapping %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve mapping"})
+		return nil, false
+	}
+	if mapping == nil || (mapping.Owner != c.GetString(authUserKey) && c.GetString(authRoleKey) != "admin") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mapping not found"})
+		return nil, false
+	}
+
+	return mapping, true
+}
+
+// normalizeTags lowercases and trims tags, dropping empty ones and duplicates
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"example-api/internal/models"
+	"example-api/internal/monitor"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetMonitors sets the heartbeat monitors whose status GET /api/monitors
+// reports
+func (h *Handler) SetMonitors(monitors []*monitor.Monitor) {
+	h.monitors = monitors
+}
+
+// HandleListMonitors handles GET /api/monitors, telling for each monitor
+// whether its heartbeats, and other events from its source, are arriving
+func (h *Handler) HandleListMonitors(c *gin.Context) {
+	statuses, err := monitor.Statuses(h.db, h.monitors, time.Now())
+	if err != nil {
+		log.Printf("Failed to get monitor statuses: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get monitor statuses"})
+		return
+	}
+	c.JSON(http.StatusOK, models.MonitorStatusResponse{Monitors: statuses, Total: len(statuses)})
+}
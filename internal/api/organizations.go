@@ -0,0 +1,294 @@
+package api
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListOrganizations returns the organizations the caller belongs to,
+// or every organization for admins
+func (h *Handler) HandleListOrganizations(c *gin.Context) {
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+	orgs, err := h.db.ListOrganizations()
+	if err != nil {
+		log.Printf("Failed to list organizations: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organizations"})
+		return
+	}
+
+	visible := []models.Organization{}
+	for _, org := range orgs {
+		if access.All || access.Organizations[org.ID] != "" {
+			visible = append(visible, org)
+		}
+	}
+	c.JSON(http.StatusOK, models.ListOrganizationsResponse{Organizations: visible, Total: len(visible)})
+}
+
+// HandleCreateOrganization creates an organization
+func (h *Handler) HandleCreateOrganization(c *gin.Context) {
+	var req models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	org := &models.Organization{Slug: req.Slug, Name: req.Name}
+	if err := org.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.CreateOrganization(org); err != nil {
+		if errors.Is(err, database.ErrOrganizationExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "An organization with this slug already exists"})
+			return
+		}
+		log.Printf("Failed to create organization: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, org)
+}
+
+// HandleDeleteOrganization deletes an organization without projects
+func (h *Handler) HandleDeleteOrganization(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	deleted, err := h.db.DeleteOrganization(id)
+	switch {
+	case errors.Is(err, database.ErrOrganizationInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "The default organization and organizations with projects can't be deleted"})
+	case err != nil:
+		log.Printf("Failed to delete organization %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+	case !deleted:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// HandleListOrganizationMembers returns the members of an organization and
+// their roles
+func (h *Handler) HandleListOrganizationMembers(c *gin.Context) {
+	org, ok := h.managedOrganization(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.db.OrganizationMembers(org.ID)
+	if err != nil {
+		log.Printf("Failed to list members of organization %d: %v", org.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve members"})
+		return
+	}
+	if members == nil {
+		members = []models.OrganizationMember{}
+	}
+
+	c.JSON(http.StatusOK, models.OrganizationMembersResponse{Members: members, Total: len(members)})
+}
+
+// HandleSetOrganizationMember gives a user a role in an organization
+func (h *Handler) HandleSetOrganizationMember(c *gin.Context) {
+	org, ok := h.managedOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format, role must be member or admin"})
+		return
+	}
+	user, err := h.db.GetUser(c.Param("username"))
+	if err != nil {
+		log.Printf("Failed to get user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	member := &models.OrganizationMember{OrganizationID: org.ID, Username: user.Username, Role: req.Role}
+	if err := h.db.SetOrganizationMember(member); err != nil {
+		log.Printf("Failed to set member of organization %d: %v", org.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// HandleRemoveOrganizationMember takes a user's role in an organization away
+func (h *Handler) HandleRemoveOrganizationMember(c *gin.Context) {
+	org, ok := h.managedOrganization(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.RemoveOrganizationMember(org.ID, c.Param("username")); err != nil {
+		log.Printf("Failed to remove member of organization %d: %v", org.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleListOrganizationTokens returns the API tokens of an organization
+// that reach all of its projects, without the tokens themselves
+func (h *Handler) HandleListOrganizationTokens(c *gin.Context) {
+	org, ok := h.managedOrganization(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.db.ListOrganizationTokens(org.ID)
+	if err != nil {
+		log.Printf("Failed to list API tokens of organization %d: %v", org.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tokens"})
+		return
+	}
+	if tokens == nil {
+		tokens = []models.APIToken{}
+	}
+
+	c.JSON(http.StatusOK, models.ListAPITokensResponse{Tokens: tokens, Total: len(tokens)})
+}
+
+// HandleCreateOrganizationToken creates an API token for every project of
+// an organization. The response is the only time the token is shown.
+func (h *Handler) HandleCreateOrganizationToken(c *gin.Context) {
+	org, ok := h.managedOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format, a name and a role of viewer or editor are required"})
+		return
+	}
+
+	secret, hash, err := auth.NewAPIToken()
+	if err != nil {
+		log.Printf("Failed to generate API token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+	token := models.APIToken{
+		Name:           strings.TrimSpace(req.Name),
+		TokenHash:      hash,
+		OrganizationID: org.ID,
+		Role:           req.Role,
+		CreatedBy:      c.GetString(authUserKey),
+	}
+	if err := h.db.CreateAPIToken(&token); err != nil {
+		log.Printf("Failed to create API token for organization %d: %v", org.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreateAPITokenResponse{APIToken: token, Token: secret})
+}
+
+// HandleDeleteOrganizationToken revokes an API token of an organization
+func (h *Handler) HandleDeleteOrganizationToken(c *gin.Context) {
+	org, ok := h.managedOrganization(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("tokenID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID format"})
+		return
+	}
+
+	deleted, err := h.db.DeleteOrganizationToken(org.ID, id)
+	if err != nil {
+		log.Printf("Failed to delete API token %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete token"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleOrganizationUsage returns how many events an organization's projects
+// store, events per day over the last days days (30 by default, at most
+// 366), and its number of members and API tokens
+func (h *Handler) HandleOrganizationUsage(c *gin.Context) {
+	org, ok := h.managedOrganization(c)
+	if !ok {
+		return
+	}
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 || parsed > 366 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days parameter"})
+			return
+		}
+		days = parsed
+	}
+
+	usage, err := h.db.OrganizationUsage(org.ID, days)
+	if err != nil {
+		log.Printf("Failed to get usage of organization %d: %v", org.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve usage"})
+		return
+	}
+	if usage.Projects == nil {
+		usage.Projects = []models.ProjectUsage{}
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// managedOrganization loads the organization named in the URL, responding
+// with an error if it doesn't exist or the caller can't manage it
+func (h *Handler) managedOrganization(c *gin.Context) (*models.Organization, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return nil, false
+	}
+	if !h.canManage(c, id) {
+		return nil, false
+	}
+
+	org, err := h.db.GetOrganization(id)
+	if err != nil {
+		log.Printf("Failed to get organization %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		return nil, false
+	}
+	if org == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return nil, false
+	}
+	return org, true
+}
@@ -0,0 +1,437 @@
+package api
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenTouchInterval limits how often the last use of a project API token
+// is written
+const tokenTouchInterval = time.Minute
+
+// projectAccessKey caches the caller's project access in the request context
+const projectAccessKey = "project_access"
+
+// access returns what the caller can do in each project, responding with an
+// error if it can't be determined. Project API tokens have their role in
+// their project only, organization API tokens in the projects of their
+// organization, and anonymous callers can read the default project.
+func (h *Handler) access(c *gin.Context) (*models.ProjectAccess, bool) {
+	if value, ok := c.Get(projectAccessKey); ok {
+		return value.(*models.ProjectAccess), true
+	}
+
+	var access *models.ProjectAccess
+	switch user := c.GetString(authUserKey); {
+	case apiToken(c) != nil:
+		var err error
+		if access, err = h.db.TokenAccess(apiToken(c)); err != nil {
+			log.Printf("Failed to get project access of %s: %v", user, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve project access"})
+			return nil, false
+		}
+	case user == "":
+		access = &models.ProjectAccess{Roles: map[int64]string{models.DefaultProjectID: models.ProjectViewer}}
+	default:
+		var err error
+		if access, err = h.db.ProjectAccess(user, c.GetString(authRoleKey)); err != nil {
+			log.Printf("Failed to get project access of %s: %v", user, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve project access"})
+			return nil, false
+		}
+	}
+	c.Set(projectAccessKey, access)
+	return access, true
+}
+
+// apiToken returns the project or organization API token the caller
+// authenticated with, if any
+func apiToken(c *gin.Context) *models.APIToken {
+	value, _ := c.Get(authTokenKey)
+	token, _ := value.(*models.APIToken)
+	return token
+}
+
+// tokenProject returns the project of a project API token, or zero
+func tokenProject(c *gin.Context) int64 {
+	if token := apiToken(c); token != nil {
+		return token.ProjectID
+	}
+	return 0
+}
+
+// canEdit reports whether the caller can edit events of the project,
+// responding with 403 if not
+func (h *Handler) canEdit(c *gin.Context, projectID int64) bool {
+	access, ok := h.access(c)
+	if !ok {
+		return false
+	}
+	if !access.CanEdit(projectID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Editor role in the event's project required"})
+		return false
+	}
+	return true
+}
+
+// canManage reports whether the caller can manage the organization's
+// members, projects and API tokens, responding with 403 if not
+func (h *Handler) canManage(c *gin.Context, orgID int64) bool {
+	access, ok := h.access(c)
+	if !ok {
+		return false
+	}
+	if !access.CanManage(orgID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Organization admin role required"})
+		return false
+	}
+	return true
+}
+
+// visibleEvents drops the events of projects the caller can't see
+func (h *Handler) visibleEvents(c *gin.Context, events []models.Event) ([]models.Event, bool) {
+	access, ok := h.access(c)
+	if !ok {
+		return nil, false
+	}
+	visible := []models.Event{}
+	for _, event := range events {
+		if access.CanView(event.ProjectID) {
+			visible = append(visible, event)
+		}
+	}
+	return visible, true
+}
+
+// projectFilter returns the projects events are listed from: the one named
+// by the project query parameter, by ID or slug, or every project the
+// caller can see. It responds with an error for unknown projects.
+func (h *Handler) projectFilter(c *gin.Context) ([]int64, bool) {
+	access, ok := h.access(c)
+	if !ok {
+		return nil, false
+	}
+	name := c.Query("project")
+	if name == "" {
+		return access.Viewable(), true
+	}
+
+	project, ok := h.namedProject(c, name)
+	if !ok {
+		return nil, false
+	}
+	if project == nil || !access.CanView(project.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown project " + strconv.Quote(name)})
+		return nil, false
+	}
+	return []int64{project.ID}, true
+}
+
+// namedProject looks up a project by ID or slug, responding with an error
+// if the lookup fails. It returns nil if there is no such project.
+func (h *Handler) namedProject(c *gin.Context, name string) (*models.Project, bool) {
+	var project *models.Project
+	var err error
+	if id, parseErr := strconv.ParseInt(name, 10, 64); parseErr == nil {
+		project, err = h.db.GetProject(id)
+	} else {
+		project, err = h.db.GetProjectBySlug(strings.ToLower(name))
+	}
+	if err != nil {
+		log.Printf("Failed to get project %q: %v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve project"})
+		return nil, false
+	}
+	return project, true
+}
+
+// ingestProject returns the project events posted by the caller are stored
+// in: the one named by ID or slug, the project of a project API token, or
+// zero to leave it to the email's mapping or the default project. It
+// responds with an error unless the caller can edit events there.
+func (h *Handler) ingestProject(c *gin.Context, name string) (int64, bool) {
+	access, ok := h.access(c)
+	if !ok {
+		return 0, false
+	}
+
+	var id int64
+	switch {
+	case name != "":
+		project, ok := h.namedProject(c, name)
+		if !ok {
+			return 0, false
+		}
+		if project == nil || !access.CanView(project.ID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown project " + strconv.Quote(name)})
+			return 0, false
+		}
+		id = project.ID
+	case tokenProject(c) != 0:
+		id = tokenProject(c)
+	}
+
+	if !access.CanEdit(id) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Editor role in the project required"})
+		return 0, false
+	}
+	return id, true
+}
+
+// HandleListProjects returns the projects the caller can see
+func (h *Handler) HandleListProjects(c *gin.Context) {
+	access, ok := h.access(c)
+	if !ok {
+		return
+	}
+	projects, err := h.db.ListProjects()
+	if err != nil {
+		log.Printf("Failed to list projects: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve projects"})
+		return
+	}
+
+	visible := access.Visible(projects)
+	if visible == nil {
+		visible = []models.Project{}
+	}
+	c.JSON(http.StatusOK, models.ListProjectsResponse{Projects: visible, Total: len(visible)})
+}
+
+// HandleCreateProject creates a project in an organization the caller
+// manages
+func (h *Handler) HandleCreateProject(c *gin.Context) {
+	var req models.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	if req.OrganizationID == 0 {
+		req.OrganizationID = models.DefaultOrganizationID
+	}
+	if !h.canManage(c, req.OrganizationID) {
+		return
+	}
+
+	project := &models.Project{Slug: req.Slug, Name: req.Name, OrganizationID: req.OrganizationID}
+	if err := project.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.db.CreateProject(project); err != nil {
+		if errors.Is(err, database.ErrProjectExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A project with this slug already exists"})
+			return
+		}
+		if errors.Is(err, database.ErrOrganizationNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Organization not found"})
+			return
+		}
+		log.Printf("Failed to create project: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// HandleDeleteProject deletes a project without events or email mappings
+func (h *Handler) HandleDeleteProject(c *gin.Context) {
+	project, ok := h.managedProject(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.db.DeleteProject(project.ID)
+	switch {
+	case errors.Is(err, database.ErrProjectInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "The default project and projects with events or email mappings can't be deleted"})
+	case err != nil:
+		log.Printf("Failed to delete project %d: %v", project.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+	case !deleted:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// HandleListProjectMembers returns the members of a project and their roles
+func (h *Handler) HandleListProjectMembers(c *gin.Context) {
+	project, ok := h.managedProject(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.db.ProjectMembers(project.ID)
+	if err != nil {
+		log.Printf("Failed to list members of project %d: %v", project.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve members"})
+		return
+	}
+	if members == nil {
+		members = []models.ProjectMember{}
+	}
+
+	c.JSON(http.StatusOK, models.ProjectMembersResponse{Members: members, Total: len(members)})
+}
+
+// HandleSetProjectMember gives a user a role in a project
+func (h *Handler) HandleSetProjectMember(c *gin.Context) {
+	project, ok := h.managedProject(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetProjectMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format, role must be viewer or editor"})
+		return
+	}
+	user, err := h.db.GetUser(c.Param("username"))
+	if err != nil {
+		log.Printf("Failed to get user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	member := &models.ProjectMember{ProjectID: project.ID, Username: user.Username, Role: req.Role}
+	if err := h.db.SetProjectMember(member); err != nil {
+		log.Printf("Failed to set member of project %d: %v", project.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// HandleRemoveProjectMember takes a user's role in a project away
+func (h *Handler) HandleRemoveProjectMember(c *gin.Context) {
+	project, ok := h.managedProject(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.RemoveProjectMember(project.ID, c.Param("username")); err != nil {
+		log.Printf("Failed to remove member of project %d: %v", project.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleListAPITokens returns the API tokens of a project, without the
+// tokens themselves
+func (h *Handler) HandleListAPITokens(c *gin.Context) {
+	project, ok := h.managedProject(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.db.ListAPITokens(project.ID)
+	if err != nil {
+		log.Printf("Failed to list API tokens of project %d: %v", project.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tokens"})
+		return
+	}
+	if tokens == nil {
+		tokens = []models.APIToken{}
+	}
+
+	c.JSON(http.StatusOK, models.ListAPITokensResponse{Tokens: tokens, Total: len(tokens)})
+}
+
+// HandleCreateAPIToken creates an API token for a project. The response is
+// the only time the token is shown.
+func (h *Handler) HandleCreateAPIToken(c *gin.Context) {
+	project, ok := h.managedProject(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format, a name and a role of viewer or editor are required"})
+		return
+	}
+
+	secret, hash, err := auth.NewAPIToken()
+	if err != nil {
+		log.Printf("Failed to generate API token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+	token := models.APIToken{
+		Name:           strings.TrimSpace(req.Name),
+		TokenHash:      hash,
+		OrganizationID: project.OrganizationID,
+		ProjectID:      project.ID,
+		Role:           req.Role,
+		CreatedBy:      c.GetString(authUserKey),
+	}
+	if err := h.db.CreateAPIToken(&token); err != nil {
+		log.Printf("Failed to create API token for project %d: %v", project.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.CreateAPITokenResponse{APIToken: token, Token: secret})
+}
+
+// HandleDeleteAPIToken revokes an API token of a project
+func (h *Handler) HandleDeleteAPIToken(c *gin.Context) {
+	project, ok := h.managedProject(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("tokenID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID format"})
+		return
+	}
+
+	deleted, err := h.db.DeleteAPIToken(project.ID, id)
+	if err != nil {
+		log.Printf("Failed to delete API token %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete token"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// managedProject loads the project named in the URL by ID or slug,
+// responding with an error if it doesn't exist or the caller can't manage
+// its organization
+func (h *Handler) managedProject(c *gin.Context) (*models.Project, bool) {
+	project, ok := h.namedProject(c, c.Param("id"))
+	if !ok {
+		return nil, false
+	}
+	if project == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+	if !h.canManage(c, project.OrganizationID) {
+		return nil, false
+	}
+	return project, true
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListQuarantine lists quarantined events, newest first
+func (h *Handler) HandleListQuarantine(c *gin.Context) {
+	limit := 100
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+			return
+		}
+		limit = parsed
+	}
+
+	items, err := h.db.ListQuarantine(limit)
+	if err != nil {
+		log.Printf("Failed to list quarantine: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve quarantine"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.QuarantineResponse{Items: items, Total: len(items)})
+}
+
+// HandleReleaseQuarantined stores a quarantined event and returns it
+func (h *Handler) HandleReleaseQuarantined(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	event, err := h.db.ReleaseQuarantined(id)
+	if err != nil {
+		log.Printf("Failed to release quarantined event %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release event"})
+		return
+	}
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quarantined event not found"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+// HandleDeleteQuarantined discards a quarantined event
+func (h *Handler) HandleDeleteQuarantined(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	deleted, err := h.db.DeleteQuarantined(id)
+	if err != nil {
+		log.Printf("Failed to delete quarantined event %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Quarantined event not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
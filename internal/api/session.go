@@ -0,0 +1,154 @@
+package api
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/logging"
+	"example-api/internal/models"
+	"example-api/internal/reporting"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys set by SessionAuthMiddleware
+const (
+	authUserKey  = "auth_user"
+	authRoleKey  = "auth_role"
+	authTokenKey = "auth_token" // The *models.APIToken of project and organization API tokens
+)
+
+// TokenStore looks up project and organization API tokens by the hash of
+// the token
+type TokenStore interface {
+	GetAPIToken(tokenHash string) (*models.APIToken, error)
+	TouchAPIToken(id int64) error
+}
+
+// SessionAuthMiddleware accepts the configured API token or a project or
+// organization API token in the Authorization header, or a web interface session cookie
+// validated against the shared session store
+func SessionAuthMiddleware(validToken string, sessions auth.SessionStore, tokens TokenStore) gin.HandlerFunc {
+	tokenAuth := AuthMiddleware(validToken)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if cookie, err := c.Cookie("session"); err == nil && cookie != "" {
+				session, err := sessions.GetSession(auth.HashSessionToken(cookie))
+				if err != nil {
+					log.Printf("Session lookup failed for %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate session"})
+					c.Abort()
+					return
+				}
+				if session == nil {
+					log.Printf("Auth failed: Invalid session cookie for %s %s", c.Request.Method, c.Request.URL.Path)
+					c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired session"})
+					c.Abort()
+					return
+				}
+
+				logging.SetUser(c.Request.Context(), session.Username)
+				c.Set(authUserKey, session.Username)
+				c.Set(authRoleKey, session.Role)
+				c.Next()
+				return
+			}
+		}
+
+		if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); token != "" && token != validToken {
+			stored, err := tokens.GetAPIToken(auth.HashAPIToken(token))
+			if err != nil {
+				log.Printf("API token lookup failed for %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+				c.Abort()
+				return
+			}
+			if stored != nil {
+				if time.Since(stored.LastUsedAt) > tokenTouchInterval {
+					if err := tokens.TouchAPIToken(stored.ID); err != nil {
+						log.Printf("Warning: %v", err)
+					}
+				}
+				name := "token:" + stored.Name
+				logging.SetUser(c.Request.Context(), name)
+				c.Set(authUserKey, name)
+				c.Set(authRoleKey, "user")
+				c.Set(authTokenKey, stored)
+				c.Next()
+				return
+			}
+		}
+
+		// Fall back to the API token, which grants full access
+		c.Set(authUserKey, "api-token")
+		c.Set(authRoleKey, "admin")
+		tokenAuth(c)
+	}
+}
+
+// OptionalAuthMiddleware authenticates requests that carry an Authorization
+// header or a session cookie with authenticate, and lets anonymous requests
+// through. Anonymous callers only see the default project.
+func OptionalAuthMiddleware(authenticate gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cookie, _ := c.Cookie("session"); c.GetHeader("Authorization") == "" && cookie == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+// RequireAdminRole rejects session-authenticated requests from non-admin users
+func RequireAdminRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(authRoleKey) != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin role required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CORSMiddleware allows credentialed requests from the given origins, such as
+// the web interface calling the API with its session cookie
+func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowed := make(map[string]bool)
+	for _, origin := range allowedOrigins {
+		allowed[strings.TrimRight(strings.TrimSpace(origin), "/")] = true
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || !allowed[origin] {
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Vary", "Origin")
+		c.Header("Access-Control-Expose-Headers", logging.RequestIDHeader)
+
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, "+logging.RequestIDHeader)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RecoveryMiddleware is gin's recovery, which logs panics and answers them
+// with a 500, that also reports them
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		reporting.CapturePanic(c.Request, recovered)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"example-api/internal/inbound"
+	"example-api/internal/models"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slackCommandLimit is how many events a slash command reply lists
+const slackCommandLimit = 10
+
+// SetBaseURL sets where the web interface is reached, for links in Slack
+// command replies
+func (h *Handler) SetBaseURL(baseURL string) {
+	h.baseURL = baseURL
+}
+
+// HandleSlackCommand answers a Slack slash command, such as
+// "/events tag:outage last:24h", with a summary of the matching events. It
+// is signed like the Slack webhook, and sees only the default project, as
+// requests without credentials do.
+func (h *Handler) HandleSlackCommand(c *gin.Context) {
+	if h.slackSecret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Slack is not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if err := inbound.VerifySlack(h.slackSecret, c.GetHeader(inbound.SlackSignatureHeader),
+		c.GetHeader(inbound.SlackTimestampHeader), body); err != nil {
+		log.Printf("Rejected Slack command from %s: %v", c.ClientIP(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	command, err := inbound.ParseSlackCommand(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+	// Slack shows replies to the user whatever their status, but only those
+	// answered with 200
+	if command.Text == "" || strings.EqualFold(command.Text, "help") {
+		c.JSON(http.StatusOK, inbound.SlackUsage(command.Command))
+		return
+	}
+	query, err := inbound.ParseSlackQuery(command.Text)
+	if err != nil {
+		c.JSON(http.StatusOK, inbound.SlackMessage("Sorry, "+err.Error()+"."))
+		return
+	}
+
+	now := time.Now()
+	filter := query.Filter(now)
+	filter.Projects = []int64{models.DefaultProjectID}
+	filter.Limit = slackCommandLimit
+	events, total, err := h.db.ListEvents(filter)
+	if err != nil {
+		log.Printf("Failed to query events for Slack command of %s: %v", command.UserName, err)
+		c.JSON(http.StatusOK, inbound.SlackMessage("Sorry, the events couldn't be retrieved. Try again later."))
+		return
+	}
+	c.JSON(http.StatusOK, inbound.SlackQueryReply(query, events, total, h.baseURL, now))
+}
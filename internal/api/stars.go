@@ -0,0 +1,61 @@
+package api
+
+import (
+	"example-api/internal/models"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListStarred returns the events the caller starred, most recently
+// starred first
+func (h *Handler) HandleListStarred(c *gin.Context) {
+	events, err := h.db.StarredEvents(c.GetString(authUserKey))
+	if err != nil {
+		log.Printf("Failed to get starred events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		return
+	}
+	// Stars stay on events of projects the caller has left, hidden
+	events, ok := h.visibleEvents(c, events)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.EventResponse{Events: events, Total: len(events)})
+}
+
+// HandleStarEvent stars an event for the caller and returns it
+func (h *Handler) HandleStarEvent(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.StarEvent(c.GetString(authUserKey), event.ID); err != nil {
+		log.Printf("Failed to star event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to star event"})
+		return
+	}
+
+	event.Starred = true
+	c.JSON(http.StatusOK, event)
+}
+
+// HandleUnstarEvent removes the caller's star from an event
+func (h *Handler) HandleUnstarEvent(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.UnstarEvent(c.GetString(authUserKey), event.ID); err != nil {
+		log.Printf("Failed to unstar event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unstar event"})
+		return
+	}
+
+	event.Starred = false
+	c.JSON(http.StatusOK, event)
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetStats returns database size and ingestion statistics for
+// dashboards and capacity planning
+func (h *Handler) HandleGetStats(c *gin.Context) {
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 || parsed > 366 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days parameter"})
+			return
+		}
+		days = parsed
+	}
+
+	stats, err := h.db.GetDatabaseStats(days)
+	if err != nil {
+		log.Printf("Failed to get database statistics: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleSetEventStatus moves an event to another status, such as
+// acknowledged or resolved, and returns it. The change is recorded in the
+// event's audit trail.
+func (h *Handler) HandleSetEventStatus(c *gin.Context) {
+	event, ok := h.requestedEvent(c)
+	if !ok || !h.canEdit(c, event.ProjectID) {
+		return
+	}
+
+	var req models.SetStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format, status must be open, acknowledged, resolved or ignored"})
+		return
+	}
+	if req.Status == event.Status {
+		c.JSON(http.StatusOK, event)
+		return
+	}
+
+	found, err := h.db.SetEventStatus(event.ID, req.Status)
+	if err != nil {
+		log.Printf("Failed to set status of event %d: %v", event.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set status"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	updated := *event
+	updated.Status = req.Status
+	h.recordEventAudit(c, models.EventStatusChanged, event.ID, event, &updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// statusFilter reads the status query parameter, responding with 400 unless
+// it is empty or a known status
+func statusFilter(c *gin.Context) (string, bool) {
+	status := c.Query("status")
+	if status != "" && !slices.Contains(models.EventStatuses, status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status parameter, expected open, acknowledged, resolved or ignored"})
+		return "", false
+	}
+	return status, true
+}
+
+// withStatus keeps the events with the status, or all of them if it is empty
+func withStatus(events []models.Event, status string) []models.Event {
+	if status == "" {
+		return events
+	}
+	kept := []models.Event{}
+	for _, event := range events {
+		if event.Status == status {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
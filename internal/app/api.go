@@ -0,0 +1,229 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"example-api/internal/api"
+	"example-api/internal/auth"
+	"example-api/internal/config"
+	"example-api/internal/database"
+	"example-api/internal/geocode"
+	"example-api/internal/health"
+	"example-api/internal/inbound"
+	"example-api/internal/ingest"
+	"example-api/internal/logging"
+	"example-api/internal/metrics"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigureFilters sets up the sender rules and spam scoring run by the
+// "filter" and "spam" ingestion processors. Pipelines must be set again for
+// a change to take effect.
+func ConfigureFilters(cfg *config.Config) error {
+	var spamChecker ingest.SpamChecker
+	switch spam := cfg.Filter.Spam; {
+	case spam.RspamdURL != "":
+		spamChecker = ingest.NewRspamd(spam.RspamdURL, spam.RspamdPassword)
+	case spam.SpamdAddr != "":
+		spamChecker = &ingest.SpamAssassin{Addr: spam.SpamdAddr}
+	}
+	rules := ingest.FilterRules{
+		AllowSenders:  cfg.Filter.AllowSenders,
+		DenySenders:   cfg.Filter.DenySenders,
+		AllowSources:  cfg.Filter.AllowSources,
+		DenySources:   cfg.Filter.DenySources,
+		UnknownAction: cfg.Filter.UnknownAction,
+	}
+	if err := ingest.ConfigureFilters(rules, spamChecker, cfg.Filter.Spam.QuarantineScore, cfg.Filter.Spam.RejectScore); err != nil {
+		return fmt.Errorf("invalid filter configuration: %w", err)
+	}
+	return nil
+}
+
+// ConfigureGeocoding sets up the geocoder of the "geocode" ingestion
+// processor and returns it, or nil when geocoding isn't configured
+func ConfigureGeocoding(cfg *config.Config) (geocode.Geocoder, error) {
+	geocoder, err := Geocoder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ingest.ConfigureGeocoder(geocoder)
+	return geocoder, nil
+}
+
+// Geocoder creates the geocoder configured under geocoding, or returns nil
+// when there is none
+func Geocoder(cfg *config.Config) (geocode.Geocoder, error) {
+	geocoder, err := geocode.New(cfg.Geocoding.Places, cfg.Geocoding.URL, cfg.Geocoding.UserAgent)
+	if err != nil {
+		return nil, fmt.Errorf("invalid geocoding configuration: %w", err)
+	}
+	return geocoder, nil
+}
+
+// NewAPIServer creates the JSON API and ingestion webhook server, listening
+// on server.port
+func NewAPIServer(cfg *config.Config, db *database.Database) (*http.Server, error) {
+	// Initialize router and handler
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New() // Requests are logged by logging.Middleware around the router
+	router.Use(api.RecoveryMiddleware())
+	router.Use(api.CORSMiddleware(cfg.Server.AllowedOrigins))
+
+	handler := api.New(db)
+	handler.SetMappingAddresses(cfg.Server.Domain, cfg.Security.RandomEmailLen)
+	if err := ConfigureFilters(cfg); err != nil {
+		return nil, err
+	}
+	geocoder, err := ConfigureGeocoding(cfg)
+	if err != nil {
+		return nil, err
+	}
+	handler.SetGeocoder(geocoder)
+	monitors, err := Monitors(cfg)
+	if err != nil {
+		return nil, err
+	}
+	handler.SetMonitors(monitors)
+	if err := handler.SetPipelines(cfg.Pipeline.Default, cfg.Pipeline.Sources); err != nil {
+		return nil, fmt.Errorf("invalid ingestion pipeline: %w", err)
+	}
+	if queue := cfg.Pipeline.Queue; queue.Enabled {
+		handler.SetQueue(queue.Size, queue.Workers, queue.BatchSize, queue.BatchWait)
+	}
+	handler.SetDebugSources(cfg.Pipeline.Debug.Sources, cfg.Pipeline.Debug.Keep)
+	OnReload("API ingestion", func(cfg *config.Config) error {
+		if err := ConfigureFilters(cfg); err != nil {
+			return err
+		}
+		handler.SetDebugSources(cfg.Pipeline.Debug.Sources, cfg.Pipeline.Debug.Keep)
+		return handler.SetPipelines(cfg.Pipeline.Default, cfg.Pipeline.Sources)
+	})
+
+	var sendgridKey *ecdsa.PublicKey
+	if cfg.Inbound.SendGridPublicKey != "" {
+		var err error
+		sendgridKey, err = inbound.ParseSendGridKey(cfg.Inbound.SendGridPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SendGrid public key: %w", err)
+		}
+	}
+	handler.SetInboundProviders(cfg.Inbound.MailgunSigningKey, sendgridKey, cfg.Inbound.SNSTopicARNs)
+	handler.SetSlack(cfg.Inbound.SlackSigningSecret, cfg.Inbound.SlackKeywords)
+	handler.SetBaseURL(cfg.Server.BaseURL)
+	handler.SetGitHubSecret(cfg.Inbound.GitHubSecret)
+	handler.SetDeploySecrets(cfg.Inbound.DeploySecret, cfg.Inbound.GitLabToken, cfg.Inbound.JenkinsToken)
+
+	adminAllowlist, err := auth.ParseIPAllowlist(cfg.Security.AdminAllowlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin allowlist: %w", err)
+	}
+	OnReload("API admin allowlist", func(cfg *config.Config) error {
+		return adminAllowlist.Update(cfg.Security.AdminAllowlist)
+	})
+
+	// Probes for orchestrators; /readyz fails while the database is down or
+	// migrations are pending
+	checker := health.New()
+	checker.Add("database", health.Database(db))
+	if check, err := health.Migrations(db, "migrations"); err != nil {
+		log.Printf("Readiness won't check migrations: %v", err)
+	} else {
+		checker.Add("migrations", check)
+	}
+	router.GET("/livez", gin.WrapF(checker.Livez))
+	router.GET("/readyz", gin.WrapF(checker.Readyz))
+	router.GET("/metrics", gin.WrapF(metrics.Handler(db)))
+
+	// Set up routes
+	requireAuth := api.SessionAuthMiddleware(cfg.Server.APIToken, sessionStore(db), db)
+	// Reading events doesn't need credentials, but without them only the
+	// default project is visible
+	optionalAuth := api.OptionalAuthMiddleware(requireAuth)
+	router.POST("/api/events", requireAuth, handler.HandleEventReceive)
+	router.GET("/api/events/:id", optionalAuth, handler.HandleGetEventByID)
+	router.GET("/api/events/:id/thread", optionalAuth, handler.HandleGetThread)
+	router.GET("/api/events/:id/audit", requireAuth, handler.HandleGetEventAudit)
+	router.GET("/api/events/:id/comments", requireAuth, handler.HandleGetComments)
+	router.POST("/api/events/:id/comments", requireAuth, handler.HandleCreateComment)
+	router.DELETE("/api/events/:id/comments/:commentID", requireAuth, handler.HandleDeleteComment)
+	router.GET("/api/events/:id/links", requireAuth, handler.HandleGetEventLinks)
+	router.POST("/api/events/:id/links", requireAuth, handler.HandleCreateEventLink)
+	router.DELETE("/api/events/:id/links/:linkID", requireAuth, handler.HandleDeleteEventLink)
+	router.PUT("/api/events/:id/star", requireAuth, handler.HandleStarEvent)
+	router.DELETE("/api/events/:id/star", requireAuth, handler.HandleUnstarEvent)
+	router.GET("/api/events/starred", requireAuth, handler.HandleListStarred)
+	router.POST("/api/events/:id/clone", requireAuth, handler.HandleCloneEvent)
+	router.PUT("/api/events/:id/fields", requireAuth, handler.HandleSetEventFields)
+	router.PUT("/api/events/:id/status", requireAuth, handler.HandleSetEventStatus)
+	router.PUT("/api/events/:id/due", requireAuth, handler.HandleSetEventDue)
+	router.GET("/api/fields", requireAuth, handler.HandleListFields)
+	router.GET("/api/labels", requireAuth, handler.HandleListLabels)
+	router.GET("/api/monitors", requireAuth, handler.HandleListMonitors)
+	router.GET("/api/anomalies", requireAuth, handler.HandleListAnomalies)
+	router.GET("/api/incidents", requireAuth, handler.HandleListIncidents)
+	router.GET("/api/incidents/:id", requireAuth, handler.HandleGetIncident)
+	router.GET("/api/incidents/:id/events", requireAuth, handler.HandleGetIncidentEvents)
+	router.PUT("/api/events/:id/labels", requireAuth, handler.HandleSetEventLabels)
+	router.PUT("/api/events/:id/location", requireAuth, handler.HandleSetEventLocation)
+	router.GET("/api/events", optionalAuth, handler.HandleGetEventsByTag)
+	router.GET("/api/events/by-date", optionalAuth, handler.HandleGetEventsByDate)
+	router.GET("/api/events/export", requireAuth, handler.HandleExportEvents)
+	// Projects and organizations are managed by admins and organization admins
+	projects := router.Group("/api/projects", requireAuth)
+	projects.GET("", handler.HandleListProjects)
+	projects.POST("", handler.HandleCreateProject)
+	projects.DELETE("/:id", handler.HandleDeleteProject)
+	projects.GET("/:id/members", handler.HandleListProjectMembers)
+	projects.PUT("/:id/members/:username", handler.HandleSetProjectMember)
+	projects.DELETE("/:id/members/:username", handler.HandleRemoveProjectMember)
+	projects.GET("/:id/tokens", handler.HandleListAPITokens)
+	projects.POST("/:id/tokens", handler.HandleCreateAPIToken)
+	projects.DELETE("/:id/tokens/:tokenID", handler.HandleDeleteAPIToken)
+	organizations := router.Group("/api/organizations", requireAuth)
+	organizations.GET("", handler.HandleListOrganizations)
+	organizations.GET("/:id/usage", handler.HandleOrganizationUsage)
+	organizations.GET("/:id/members", handler.HandleListOrganizationMembers)
+	organizations.PUT("/:id/members/:username", handler.HandleSetOrganizationMember)
+	organizations.DELETE("/:id/members/:username", handler.HandleRemoveOrganizationMember)
+	organizations.GET("/:id/tokens", handler.HandleListOrganizationTokens)
+	organizations.POST("/:id/tokens", handler.HandleCreateOrganizationToken)
+	organizations.DELETE("/:id/tokens/:tokenID", handler.HandleDeleteOrganizationToken)
+	mappings := router.Group("/api/mappings", requireAuth)
+	mappings.GET("", handler.HandleListMappings)
+	mappings.POST("", handler.HandleCreateMapping)
+	mappings.GET("/:id", handler.HandleGetMapping)
+	mappings.PUT("/:id", handler.HandleUpdateMapping)
+	mappings.DELETE("/:id", handler.HandleDeleteMapping)
+	// Provider webhooks are authenticated by their signatures
+	router.POST("/ingest/mailgun", handler.HandleMailgun)
+	router.POST("/ingest/sendgrid", handler.HandleSendGrid)
+	router.POST("/ingest/ses-sns", handler.HandleSESNotification)
+	router.POST("/ingest/slack", handler.HandleSlack)
+	router.POST("/ingest/github", handler.HandleGitHub)
+	router.POST("/ingest/deploy", handler.HandleDeploy)
+	router.POST("/slack/command", handler.HandleSlackCommand)
+	admin := router.Group("/api/admin", api.AllowlistMiddleware(adminAllowlist), requireAuth, api.RequireAdminRole())
+	admin.GET("/audit", handler.HandleGetAuditLog)
+	admin.GET("/event-audit", handler.HandleListEventAudit)
+	admin.GET("/stats", handler.HandleGetStats)
+	admin.POST("/events/:id/restore", handler.HandleRestoreEvent)
+	admin.POST("/fields", handler.HandleCreateField)
+	admin.DELETE("/fields/:id", handler.HandleDeleteField)
+	admin.POST("/labels", handler.HandleCreateLabel)
+	admin.PUT("/labels/:id", handler.HandleUpdateLabel)
+	admin.DELETE("/labels/:id", handler.HandleDeleteLabel)
+	admin.POST("/organizations", handler.HandleCreateOrganization)
+	admin.DELETE("/organizations/:id", handler.HandleDeleteOrganization)
+	admin.GET("/quarantine", handler.HandleListQuarantine)
+	admin.POST("/quarantine/:id/release", handler.HandleReleaseQuarantined)
+	admin.DELETE("/quarantine/:id", handler.HandleDeleteQuarantined)
+	admin.GET("/debug-payloads", handler.HandleListDebugPayloads)
+	admin.DELETE("/debug-payloads", handler.HandleDeleteDebugPayloads)
+
+	address := fmt.Sprintf(":%d", cfg.Server.Port)
+	return newServer(cfg, address, logging.Middleware(router), cfg.HTTP.API), nil
+}
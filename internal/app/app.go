@@ -0,0 +1,254 @@
+// Package app builds the servers run by the eventdb command and the setup
+// they share
+package app
+
+import (
+	"context"
+	"errors"
+	"example-api/internal/alert"
+	"example-api/internal/anomaly"
+	"example-api/internal/archive"
+	"example-api/internal/auth"
+	"example-api/internal/cache"
+	"example-api/internal/config"
+	"example-api/internal/database"
+	"example-api/internal/ingest"
+	"example-api/internal/mailer"
+	"example-api/internal/monitor"
+	"example-api/internal/s3"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Bounds of the wait between attempts to connect to the database at startup
+const (
+	connectRetryMin = 500 * time.Millisecond
+	connectRetryMax = 10 * time.Second
+)
+
+// OpenDatabase connects to the configured PostgreSQL database
+func OpenDatabase(cfg *config.Config) (*database.Database, error) {
+	connStr := fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Database.Host,
+		cfg.Database.Port,
+		cfg.Database.User,
+		cfg.Database.Password,
+		cfg.Database.Name,
+		cfg.Database.SSLMode,
+	)
+	if cfg.Database.TransactionPooling {
+		// Without this, lib/pq prepares each parameterized query and runs it
+		// in a second round trip, which the pooler may send to another server
+		connStr += " binary_parameters=yes"
+	}
+	// The database may still be starting, as when the containers of a
+	// deployment come up together, so keep trying for a while
+	deadline := time.Now().Add(cfg.Database.ConnectTimeout)
+	wait := connectRetryMin
+	var db *database.Database
+	var err error
+	for {
+		db, err = database.NewPostgres(connStr)
+		if err == nil || !time.Now().Add(wait).Before(deadline) {
+			break
+		}
+		log.Printf("Database not ready, retrying in %s: %v", wait, err)
+		time.Sleep(wait)
+		wait = min(2*wait, connectRetryMax)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database %s: %w", cfg.Database.Name, err)
+	}
+	c, err := openCache(cfg)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	db.SetCache(c)
+	db.SetNameCacheTTL(cfg.Database.NameCacheTTL)
+	db.SetEventCache(cfg.Database.EventCacheSize, cfg.Database.EventCacheTTL)
+	db.SetTransactionPooling(cfg.Database.TransactionPooling)
+	if cfg.Correlation.Window > 0 {
+		db.GroupIncidents(cfg.Correlation.Window)
+	}
+	Notifier(cfg, db).Watch()
+	return db, nil
+}
+
+// WaitAlerts waits up to server.shutdown_timeout for alert notifications
+// still being sent, for the consumers to call before exiting
+func WaitAlerts(cfg *config.Config) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := alert.Wait(ctx); err != nil {
+		log.Printf("Alert notifications were still being sent at the shutdown timeout: %v", err)
+	}
+}
+
+// Notifier creates the notifier sending alerts for events stored in db
+func Notifier(cfg *config.Config, db *database.Database) *alert.Notifier {
+	m := mailer.New(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	return alert.New(db, m, cfg.Server.BaseURL)
+}
+
+// Monitors returns the heartbeat monitors configured under monitors
+func Monitors(cfg *config.Config) ([]*monitor.Monitor, error) {
+	var monitors []*monitor.Monitor
+	names := make(map[string]bool)
+	for _, c := range cfg.Monitors {
+		m := &monitor.Monitor{Name: c.Name, Source: c.Source, Interval: c.Interval, Tags: c.Tags}
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid monitor configuration: %w", err)
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("invalid monitor configuration: duplicate monitor name %s", m.Name)
+		}
+		names[m.Name] = true
+		monitors = append(monitors, m)
+	}
+	return monitors, nil
+}
+
+// AnomalyDetector returns the anomaly detector configured under anomalies
+func AnomalyDetector(cfg *config.Config, db *database.Database) (*anomaly.Detector, error) {
+	settings := anomaly.Settings{
+		Baseline:    cfg.Anomalies.Baseline,
+		Sensitivity: cfg.Anomalies.Sensitivity,
+		MinEvents:   cfg.Anomalies.MinEvents,
+		Events:      cfg.Anomalies.Events,
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid anomaly configuration: %w", err)
+	}
+	return anomaly.NewDetector(db, settings), nil
+}
+
+// Archiver returns the archiver configured under archive. The credentials
+// fall back to the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN environment variables.
+func Archiver(cfg *config.Config, db *database.Database) (*archive.Archiver, error) {
+	c := cfg.Archive
+	store := &s3.Client{
+		Endpoint:     c.Endpoint,
+		Region:       c.Region,
+		Bucket:       c.Bucket,
+		AccessKey:    c.AccessKeyID,
+		SecretKey:    c.SecretAccessKey,
+		SessionToken: c.SessionToken,
+		PathStyle:    c.PathStyle,
+	}
+	if store.AccessKey == "" && store.SecretKey == "" {
+		store.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		store.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		store.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if err := store.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid archive configuration: %w", err)
+	}
+	settings := archive.Settings{
+		Prefix:         c.Prefix,
+		Interval:       c.Interval,
+		BatchSize:      c.BatchSize,
+		AttachmentData: c.AttachmentData,
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid archive configuration: %w", err)
+	}
+	return archive.New(db, store, settings), nil
+}
+
+// openCache creates the cache configured under cache. An unreachable Redis
+// server is only logged, since reads and writes fall back to the database.
+func openCache(cfg *config.Config) (cache.Cache, error) {
+	c, err := cache.New(cfg.Cache.Backend, cfg.Cache.RedisURL, cfg.Cache.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := c.(*cache.Redis); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := r.Ping(ctx); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	return c, nil
+}
+
+// sessionStore returns where web sessions are looked up. With a Redis cache,
+// sessions are cached there in front of the database; an in-memory cache
+// isn't used, since a session revoked by another process would stay cached.
+func sessionStore(db *database.Database) auth.SessionStore {
+	if c, ok := db.Cache().(*cache.Redis); ok {
+		return cache.NewSessionStore(db, c)
+	}
+	return db
+}
+
+// WarnMissingIndexes logs the indexes the event queries rely on that the
+// database lacks, which leaves listing events to scan the whole table
+func WarnMissingIndexes(ctx context.Context, db *database.Database) {
+	missing, err := db.MissingIndexes(ctx)
+	if err != nil {
+		log.Printf("Could not check the database indexes: %v", err)
+		return
+	}
+	if len(missing) > 0 {
+		log.Printf("Warning: missing database indexes %s; run \"eventdb migrate\" to create them", strings.Join(missing, ", "))
+	}
+}
+
+// Serve runs the servers, keyed by the name of the systemd socket each may
+// be passed (ListenerAPI and friends), until ctx is done or one of them
+// fails. It then stops accepting connections and waits up to
+// server.shutdown_timeout for requests in progress, events being forwarded
+// and alert notifications.
+func Serve(ctx context.Context, cfg *config.Config, servers map[string]*http.Server) error {
+	// Take the sockets first, so each server's Addr is where it is reached
+	listeners := make(map[*http.Server]net.Listener)
+	for name, server := range servers {
+		if l, ok := ActivatedListener(name, server.Addr); ok {
+			listeners[server] = l
+			server.Addr = l.Addr().String()
+		}
+	}
+
+	failed := make(chan error, len(servers))
+	for _, server := range servers {
+		go func(server *http.Server) {
+			if err := listen(server, listeners[server]); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				failed <- fmt.Errorf("server on %s failed: %w", server.Addr, err)
+			}
+		}(server)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-failed:
+	}
+
+	log.Printf("Shutting down, waiting up to %s for requests in progress", cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Requests to %s were still in progress at the shutdown timeout: %v", server.Addr, err)
+		}
+	}
+	if err := ingest.CloseQueues(shutdownCtx); err != nil {
+		log.Printf("Emails were still queued for ingestion at the shutdown timeout: %v", err)
+	}
+	if err := ingest.WaitForwards(shutdownCtx); err != nil {
+		log.Printf("Events were still being forwarded at the shutdown timeout: %v", err)
+	}
+	if err := alert.Wait(shutdownCtx); err != nil {
+		log.Printf("Alert notifications were still being sent at the shutdown timeout: %v", err)
+	}
+	return err
+}
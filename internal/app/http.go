@@ -0,0 +1,34 @@
+package app
+
+import (
+	"crypto/tls"
+	"example-api/internal/config"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// newServer creates a server on addr with the timeouts in settings and the
+// HTTP/2 support set under http. HTTPS connections negotiate HTTP/2 when
+// the server is given a TLS config, unless http.http2 is off; http.h2c also
+// accepts HTTP/2 over plain HTTP.
+func newServer(cfg *config.Config, addr string, handler http.Handler, settings config.HTTPSettings) *http.Server {
+	if cfg.HTTP.H2C {
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: settings.IdleTimeout})
+	}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       settings.ReadTimeout,
+		ReadHeaderTimeout: settings.ReadHeaderTimeout,
+		WriteTimeout:      settings.WriteTimeout,
+		IdleTimeout:       settings.IdleTimeout,
+		MaxHeaderBytes:    settings.MaxHeaderBytes,
+	}
+	if !cfg.HTTP.HTTP2 {
+		// A non-nil map stops net/http from setting up HTTP/2 over TLS
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	return server
+}
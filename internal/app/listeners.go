@@ -0,0 +1,116 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Names of the sockets systemd can pass, set with FileDescriptorName= in
+// the .socket unit
+const (
+	ListenerAPI      = "api"
+	ListenerWeb      = "web"
+	ListenerRedirect = "redirect"
+	ListenerSMTP     = "smtp"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd
+const listenFDsStart = 3
+
+var (
+	activatedOnce sync.Once
+	activatedMu   sync.Mutex
+	activated     []activatedListener
+)
+
+type activatedListener struct {
+	name string
+	net.Listener
+}
+
+// ActivatedListener returns the socket systemd passed for a server, if it
+// was started by socket activation. A socket is matched by its name, or,
+// when systemd passed it unnamed, by the port of addr. Each socket is
+// returned once.
+func ActivatedListener(name, addr string) (net.Listener, bool) {
+	activatedOnce.Do(func() {
+		var err error
+		if activated, err = listenFDs(); err != nil {
+			log.Printf("Ignoring sockets passed by systemd: %v", err)
+		}
+	})
+
+	activatedMu.Lock()
+	defer activatedMu.Unlock()
+	match := -1
+	for i, l := range activated {
+		if l.name == name {
+			match = i
+			break
+		}
+		if match < 0 && (l.name == "" || l.name == "unknown") && samePort(l.Addr(), addr) {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, false
+	}
+	l := activated[match]
+	activated = append(activated[:match], activated[match+1:]...)
+	log.Printf("Using socket %s from systemd for %s", l.Addr(), name)
+	return l.Listener, true
+}
+
+// listenFDs reads the sockets passed under the sd_listen_fds(3) protocol
+// and unsets its variables, so child processes don't inherit them
+func listenFDs() ([]activatedListener, error) {
+	pid, fds, names := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS"), os.Getenv("LISTEN_FDNAMES")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	if pid == "" || fds == "" {
+		return nil, nil
+	}
+	if pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+
+	var fdNames []string
+	if names != "" {
+		fdNames = strings.Split(names, ":")
+	}
+	listeners := make([]activatedListener, 0, n)
+	for i := 0; i < n; i++ {
+		name := ""
+		if i < len(fdNames) {
+			name = fdNames[i]
+		}
+		file := os.NewFile(uintptr(listenFDsStart+i), name)
+		l, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("socket %d (%s) is not a stream socket: %w", listenFDsStart+i, name, err)
+		}
+		listeners = append(listeners, activatedListener{name: name, Listener: l})
+	}
+	return listeners, nil
+}
+
+// samePort reports whether a listening address has the port of addr
+func samePort(listening net.Addr, addr string) bool {
+	tcp, ok := listening.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	_, port, err := net.SplitHostPort(addr)
+	return err == nil && port == strconv.Itoa(tcp.Port)
+}
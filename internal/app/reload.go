@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"example-api/internal/config"
+	"example-api/internal/logging"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadHook applies the settings of one component that can change without
+// a restart
+type reloadHook struct {
+	name  string
+	apply func(cfg *config.Config) error
+}
+
+var (
+	reloadMu    sync.Mutex // Serializes reloads and guards reloadHooks
+	reloadHooks []reloadHook
+)
+
+// OnReload registers fn to apply name's settings when the configuration is
+// reloaded. fn must leave the settings it can't apply unchanged.
+func OnReload(name string, fn func(cfg *config.Config) error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, reloadHook{name: name, apply: fn})
+}
+
+// Reload reads the configuration again and applies the log level and the
+// settings of every registered component. Settings such as ports, the
+// database connection and secrets still need a restart. A component whose
+// new settings are invalid keeps its old ones.
+func Reload() {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("Configuration not reloaded: %v", err)
+		return
+	}
+	if err := logging.SetLevel(cfg.Logging.Level); err != nil {
+		log.Printf("Log level not reloaded: %v", err)
+	}
+	for _, hook := range reloadHooks {
+		if err := hook.apply(cfg); err != nil {
+			log.Printf("Settings of %s not reloaded: %v", hook.name, err)
+		}
+	}
+	log.Printf("Reloaded configuration")
+}
+
+// WatchReload reloads the configuration on SIGHUP until ctx is done. With
+// server.watch_config set, it also reloads whenever the config file changes.
+func WatchReload(ctx context.Context, cfg *config.Config) {
+	if cfg.Server.WatchConfig {
+		if file := viper.ConfigFileUsed(); file != "" {
+			viper.OnConfigChange(func(event fsnotify.Event) {
+				log.Printf("%s changed, reloading", event.Name)
+				Reload()
+			})
+			viper.WatchConfig()
+		} else {
+			log.Printf("server.watch_config is set but no config file was found to watch")
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Printf("SIGHUP received, reloading")
+				Reload()
+			}
+		}
+	}()
+}
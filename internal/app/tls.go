@@ -0,0 +1,191 @@
+package app
+
+import (
+	"crypto/tls"
+	"example-api/internal/config"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsVersions are the accepted values of server.tls_min_version
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// HTTPS holds the TLS settings of the API and web servers
+type HTTPS struct {
+	Config *tls.Config
+	acme   *autocert.Manager // Set when certificates are obtained over ACME
+}
+
+// NewHTTPS builds the TLS settings from server.tls_* and server.acme_*. It
+// returns nil when neither a certificate nor ACME domains are configured.
+// A configured certificate is read again when the configuration is reloaded,
+// so renewed certificates are picked up without a restart.
+func NewHTTPS(cfg *config.Config) (*HTTPS, error) {
+	server := cfg.Server
+	if server.TLSCert == "" && server.TLSKey == "" && len(server.ACMEDomains) == 0 {
+		return nil, nil
+	}
+
+	minVersion, ok := tlsVersions[server.TLSMinVersion]
+	if !ok {
+		return nil, fmt.Errorf("invalid server.tls_min_version %q, want 1.0, 1.1, 1.2 or 1.3", server.TLSMinVersion)
+	}
+	cipherSuites, err := parseCipherSuites(server.TLSCipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(server.ACMEDomains) > 0 {
+		if server.TLSCert != "" || server.TLSKey != "" {
+			return nil, fmt.Errorf("server.acme_domains can't be used with server.tls_cert and server.tls_key")
+		}
+		if server.ACMECacheDir == "" {
+			return nil, fmt.Errorf("server.acme_cache_dir must be set to keep certificates across restarts")
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(server.ACMEDomains...),
+			Cache:      autocert.DirCache(server.ACMECacheDir),
+			Email:      server.ACMEEmail,
+		}
+		if server.ACMEDirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: server.ACMEDirectoryURL}
+		}
+		config := manager.TLSConfig()
+		config.MinVersion = minVersion
+		config.CipherSuites = cipherSuites
+		if !cfg.HTTP.HTTP2 {
+			config.NextProtos = slices.DeleteFunc(config.NextProtos, func(proto string) bool { return proto == "h2" })
+		}
+		log.Printf("Obtaining certificates over ACME for %s", strings.Join(server.ACMEDomains, ", "))
+		return &HTTPS{Config: config, acme: manager}, nil
+	}
+
+	if server.TLSCert == "" || server.TLSKey == "" {
+		return nil, fmt.Errorf("server.tls_cert and server.tls_key must be set together")
+	}
+	cert := &certificate{}
+	if err := cert.load(server.TLSCert, server.TLSKey); err != nil {
+		return nil, err
+	}
+	OnReload("TLS certificate", func(cfg *config.Config) error {
+		if cfg.Server.TLSCert == "" || cfg.Server.TLSKey == "" {
+			return fmt.Errorf("the certificate can't be removed without a restart")
+		}
+		return cert.load(cfg.Server.TLSCert, cfg.Server.TLSKey)
+	})
+
+	return &HTTPS{Config: &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: cert.get,
+	}}, nil
+}
+
+// RedirectAddr is the address of the plain HTTP listener: server.redirect_http,
+// or ":80" with ACME, which answers HTTP challenges there
+func (h *HTTPS) RedirectAddr(cfg *config.Config) string {
+	if cfg.Server.RedirectHTTP == "" && h.acme != nil {
+		return ":80"
+	}
+	return cfg.Server.RedirectHTTP
+}
+
+// parseCipherSuites looks up cipher suites by their Go names, such as
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. Only suites Go considers secure are
+// accepted. No names leaves Go's defaults.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	byName := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		byName[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q in server.tls_cipher_suites", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// certificate holds a key pair that can be replaced while serving
+type certificate struct {
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func (c *certificate) load(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	return nil
+}
+
+func (c *certificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// RedirectServer creates a server on addr redirecting every request to the
+// same host and path over HTTPS, on the port target listens on. With ACME it
+// also answers the certificate authority's HTTP challenges.
+func (h *HTTPS) RedirectServer(addr string, target *http.Server) *http.Server {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		// Read on every request: Serve changes Addr to a socket from systemd
+		if _, port, _ := net.SplitHostPort(target.Addr); port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+	if h.acme != nil {
+		handler = h.acme.HTTPHandler(handler)
+	}
+	return &http.Server{Addr: addr, Handler: handler}
+}
+
+// listen serves on l, or on a new listener on server.Addr when l is nil,
+// over TLS when the server has a TLS configuration
+func listen(server *http.Server, l net.Listener) error {
+	scheme := "HTTP"
+	if server.TLSConfig != nil {
+		scheme = "HTTPS"
+	}
+	log.Printf("Listening on %s (%s)", server.Addr, scheme)
+	switch {
+	case l == nil && server.TLSConfig == nil:
+		return server.ListenAndServe()
+	case l == nil:
+		return server.ListenAndServeTLS("", "")
+	case server.TLSConfig == nil:
+		return server.Serve(l)
+	default:
+		return server.ServeTLS(l, "", "")
+	}
+}
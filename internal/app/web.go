@@ -0,0 +1,135 @@
+package app
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/config"
+	"example-api/internal/database"
+	"example-api/internal/health"
+	"example-api/internal/logging"
+	"example-api/internal/mailer"
+	"example-api/internal/metrics"
+	"example-api/internal/reporting"
+	"example-api/internal/signing"
+	"example-api/internal/sso"
+	"example-api/internal/web"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// NewWebServer creates the web interface server, listening on port 8082
+func NewWebServer(cfg *config.Config, db *database.Database) (*http.Server, error) {
+	// Initialize authentication system
+	authSystem := auth.New()
+	authSystem.SetAuditor(db)
+	authSystem.SetSessionStore(sessionStore(db))
+	if err := authSystem.SetUserStore(db); err != nil {
+		return nil, err
+	}
+	authSystem.InitializeDefaultUsers()
+	log.Println("Authentication system initialized")
+
+	// Create web handler
+	webHandler, err := web.NewWebHandler(db, authSystem, cfg.Server.APIToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create web handler: %w", err)
+	}
+
+	adminAllowlist, err := auth.ParseIPAllowlist(cfg.Security.AdminAllowlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin allowlist: %w", err)
+	}
+	webHandler.SetAdminAllowlist(adminAllowlist)
+	webHandler.SetMarkdownSources(cfg.Display.MarkdownSources)
+	webHandler.SetTemplateReload(cfg.Display.ReloadTemplates)
+	webHandler.SetStatsCache(db.Cache(), cfg.Cache.DashboardTTL)
+	webHandler.SetAlertNotifier(Notifier(cfg, db))
+	geocoder, err := Geocoder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	webHandler.SetGeocoder(geocoder)
+	webHandler.SetMap(cfg.Display.MapTiles, cfg.Display.MapAttribution)
+	monitors, err := Monitors(cfg)
+	if err != nil {
+		return nil, err
+	}
+	webHandler.SetMonitors(monitors)
+	OnReload("web interface", func(cfg *config.Config) error {
+		webHandler.SetMarkdownSources(cfg.Display.MarkdownSources)
+		return adminAllowlist.Update(cfg.Security.AdminAllowlist)
+	})
+	webHandler.SetMappingAddresses(cfg.Server.Domain, cfg.Security.RandomEmailLen)
+
+	if cfg.Security.LinkSecret != "" {
+		webHandler.SetLinkSigner(signing.New([]byte(cfg.Security.LinkSecret)))
+	} else {
+		signer, err := signing.NewRandom()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create link signer: %w", err)
+		}
+		webHandler.SetLinkSigner(signer)
+		log.Println("Warning: No link secret set (security.link_secret); share links will stop working when the server restarts")
+	}
+
+	if cfg.Security.AllowRegistration {
+		m := mailer.New(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+		webHandler.EnableRegistration(m, time.Duration(cfg.Security.InviteExpiry)*time.Hour)
+		log.Println("Self-service registration enabled")
+	}
+
+	if cfg.SAML.Enabled {
+		samlProvider, err := sso.NewSAMLProvider(sso.SAMLConfig{
+			RootURL:           cfg.SAML.RootURL,
+			EntityID:          cfg.SAML.EntityID,
+			CertFile:          cfg.SAML.CertFile,
+			KeyFile:           cfg.SAML.KeyFile,
+			IDPMetadataURL:    cfg.SAML.IDPMetadataURL,
+			IDPMetadataFile:   cfg.SAML.IDPMetadataFile,
+			UsernameAttribute: cfg.SAML.UsernameAttribute,
+			RoleAttribute:     cfg.SAML.RoleAttribute,
+			AdminValues:       cfg.SAML.AdminValues,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize SAML: %w", err)
+		}
+		webHandler.SetSAML(samlProvider)
+		log.Println("SAML single sign-on enabled")
+	}
+
+	// Initialize router
+	router := mux.NewRouter()
+
+	// Set up static file server for CSS, JS, and images
+	router.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", http.FileServer(http.Dir("public/assets"))))
+
+	// Probes for orchestrators; /readyz fails while the database is down,
+	// migrations are pending or the templates are broken
+	checker := health.New()
+	checker.Add("database", health.Database(db))
+	if check, err := health.Migrations(db, "migrations"); err != nil {
+		log.Printf("Readiness won't check migrations: %v", err)
+	} else {
+		checker.Add("migrations", check)
+	}
+	checker.Add("templates", webHandler.CheckTemplates)
+	router.HandleFunc("/livez", checker.Livez).Methods("GET")
+	router.HandleFunc("/readyz", checker.Readyz).Methods("GET")
+	router.HandleFunc("/metrics", metrics.Handler(db)).Methods("GET")
+
+	// Configure routes
+	webHandler.SetupRoutes(router)
+
+	// Add a catch-all route for 404s
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, "404 - Page not found")
+	})
+
+	// Create HTTP server
+	webAddr := ":8082"
+	return newServer(cfg, webAddr, logging.Middleware(reporting.Middleware(router)), cfg.HTTP.Web), nil
+}
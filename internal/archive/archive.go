@@ -0,0 +1,177 @@
+// Package archive uploads new events to S3-compatible storage as compressed
+// NDJSON on a schedule, for long-term archival and downstream analytics.
+// Objects hold the events in the format of eventdb export, so eventdb import
+// can load them back after gunzip.
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"example-api/internal/s3"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInterval  = time.Hour
+	minInterval      = time.Minute
+	defaultBatchSize = 10000
+	// settleDelay leaves out the newest events, since transactions still in
+	// flight may commit events with lower IDs than ones already committed
+	settleDelay = time.Minute
+)
+
+// Settings configure what is archived and how often
+type Settings struct {
+	Prefix         string // Prepended to object keys, such as "eventdb/"
+	Interval       time.Duration
+	BatchSize      int  // Most events in one object
+	AttachmentData bool // Include attachment contents, base64 encoded
+}
+
+// Validate checks the settings and fills in defaults
+func (s *Settings) Validate() error {
+	if s.Interval == 0 {
+		s.Interval = defaultInterval
+	}
+	if s.Interval < minInterval {
+		return fmt.Errorf("archive: interval must be at least %s", minInterval)
+	}
+	if s.BatchSize == 0 {
+		s.BatchSize = defaultBatchSize
+	}
+	if s.BatchSize < 1 {
+		return fmt.Errorf("archive: batch_size must be at least 1")
+	}
+	s.Prefix = strings.TrimPrefix(s.Prefix, "/")
+	if s.Prefix != "" && !strings.HasSuffix(s.Prefix, "/") {
+		s.Prefix += "/"
+	}
+	return nil
+}
+
+// Archiver uploads the events not archived yet to a bucket
+type Archiver struct {
+	db       *database.Database
+	store    *s3.Client
+	settings Settings
+}
+
+// New creates an archiver uploading with store. The store and the settings
+// must be valid.
+func New(db *database.Database, store *s3.Client, settings Settings) *Archiver {
+	return &Archiver{db: db, store: store, settings: settings}
+}
+
+// Run archives new events immediately and then on the interval until ctx is
+// done
+func (a *Archiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.settings.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := a.Archive(ctx, time.Now()); err != nil {
+			log.Printf("Archive: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Archive uploads the events created before now that weren't archived yet,
+// BatchSize events per object, and returns the archives it uploaded. It
+// stops at the first failure; the next run starts over from there.
+func (a *Archiver) Archive(ctx context.Context, now time.Time) ([]models.Archive, error) {
+	after, err := a.db.LastArchivedEvent(a.store.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []models.Archive
+	for ctx.Err() == nil {
+		archive, err := a.upload(ctx, after, now.Add(-settleDelay))
+		if err != nil {
+			return archives, err
+		}
+		if archive == nil {
+			break
+		}
+		log.Printf("Archived events %d to %d (%d events, %d bytes) to %s",
+			archive.FirstEventID, archive.LastEventID, archive.Events, archive.Bytes, a.store.URL(archive.Key))
+		archives = append(archives, *archive)
+		if archive.Events < a.settings.BatchSize {
+			break
+		}
+		after = archive.LastEventID
+	}
+	return archives, nil
+}
+
+// upload writes the next batch of events after afterID to a temporary file,
+// uploads it and records it. It returns nil when there are no new events.
+func (a *Archiver) upload(ctx context.Context, afterID int64, before time.Time) (*models.Archive, error) {
+	file, err := os.CreateTemp("", "eventdb-archive-*.ndjson.gz")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	compressed := gzip.NewWriter(io.MultiWriter(file, hash))
+	encoder := json.NewEncoder(compressed)
+	archive := &models.Archive{Bucket: a.store.Bucket}
+	err = a.db.ExportEventsAfter(afterID, before, a.settings.BatchSize, a.settings.AttachmentData, func(event *models.ExportedEvent) error {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to write event %d: %w", event.ID, err)
+		}
+		if archive.Events == 0 {
+			archive.FirstEventID = event.ID
+		}
+		archive.LastEventID = event.ID
+		archive.Events++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if archive.Events == 0 {
+		return nil, nil
+	}
+	if err := compressed.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress events: %w", err)
+	}
+
+	if archive.Bytes, err = file.Seek(0, io.SeekCurrent); err != nil {
+		return nil, fmt.Errorf("failed to read temporary file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read temporary file: %w", err)
+	}
+	archive.Key = Key(a.settings.Prefix, archive.FirstEventID, archive.LastEventID, time.Now())
+	if err := a.store.Put(ctx, archive.Key, file, archive.Bytes, hex.EncodeToString(hash.Sum(nil)), "application/gzip"); err != nil {
+		return nil, err
+	}
+	if err := a.db.RecordArchive(archive); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// Key names the object of the events from first to last uploaded at at,
+// such as "eventdb/2024/05/01/events-0000001001-0000002000.ndjson.gz". Keys
+// sort by date and then by event ID.
+func Key(prefix string, first, last int64, at time.Time) string {
+	return fmt.Sprintf("%s%s/events-%010d-%010d.ndjson.gz", prefix, at.UTC().Format("2006/01/02"), first, last)
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// IPAllowlist restricts access to a set of CIDR ranges
+type IPAllowlist struct {
+	mu   sync.RWMutex
+	nets []*net.IPNet
+}
+
+// ParseIPAllowlist parses CIDR ranges or bare IP addresses.
+// An empty list allows every address.
+func ParseIPAllowlist(entries []string) (*IPAllowlist, error) {
+	list := &IPAllowlist{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address in allowlist: %q", entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR in allowlist: %q: %w", entry, err)
+		}
+		list.nets = append(list.nets, ipNet)
+	}
+	return list, nil
+}
+
+// Update replaces the ranges with entries, which are parsed as by
+// ParseIPAllowlist. The list is left unchanged if an entry is invalid.
+func (l *IPAllowlist) Update(entries []string) error {
+	parsed, err := ParseIPAllowlist(entries)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.nets = parsed.nets
+	return nil
+}
+
+// Allows reports whether the given IP address is permitted
+func (l *IPAllowlist) Allows(ipStr string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if len(l.nets) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range l.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware rejects requests from addresses outside the allowlist with 403
+func (l *IPAllowlist) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIP(r)
+		if !l.Allows(ip) {
+			log.Printf("Rejected %s %s from %s: not in admin allowlist", r.Method, r.URL.Path, ip)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"example-api/internal/models"
+	"log"
+	"net"
+	"net/http"
+)
+
+// Audit actions recorded by the authentication system
+const (
+	AuditLogin           = "login"
+	AuditLoginFailed     = "login_failed"
+	AuditLogout          = "logout"
+	AuditUserCreated     = "user_created"
+	AuditRoleChanged     = "role_changed"
+	AuditTokenIssued     = "token_issued"
+	AuditSessionsRevoked = "sessions_revoked"
+)
+
+// Auditor stores audit entries
+type Auditor interface {
+	LogAudit(entry *models.AuditEntry) error
+}
+
+// SetAuditor configures where audit entries are recorded
+func (a *Auth) SetAuditor(auditor Auditor) {
+	a.auditor = auditor
+}
+
+// RecordAudit records an action in the audit log, if an auditor is configured
+func (a *Auth) RecordAudit(action, actor, target, ipAddress, details string) {
+	if a.auditor == nil {
+		return
+	}
+
+	entry := &models.AuditEntry{
+		Action:    action,
+		Actor:     actor,
+		Target:    target,
+		IPAddress: ipAddress,
+		Details:   details,
+	}
+	if err := a.auditor.LogAudit(entry); err != nil {
+		log.Printf("Warning: failed to record audit entry %q for %s: %v", action, actor, err)
+	}
+}
+
+// ClientIP returns the remote IP address of a request without the port
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
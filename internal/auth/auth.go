@@ -4,7 +4,9 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
@@ -18,38 +20,64 @@ type User struct {
 	Username     string
 	PasswordHash string
 	Role         string // "admin" or "user"
+	Email        string
+	Pending      bool   // True until a self-registered user verifies their email
+	Provider     string // Identity provider that created the user, empty for local users
 	CreatedAt    time.Time
 }
 
 // Session represents a user session
 type Session struct {
 	ID        string
+	Handle    string // Non-secret identifier used to reference the session in forms
 	UserID    int
 	CreatedAt time.Time
+	LastSeen  time.Time
 	ExpiresAt time.Time
+	IPAddress string
+	UserAgent string
 }
 
 // Auth handles authentication for the application
 type Auth struct {
-	users    map[string]*User
-	sessions map[string]*Session
-	mu       sync.RWMutex
+	users         map[string]*User
+	sessions      map[string]*Session
+	verifications map[string]*verification
+	auditor       Auditor
+	store         SessionStore
+	userStore     UserStore
+	mu            sync.RWMutex
 }
 
 // New creates a new Auth instance
 func New() *Auth {
 	return &Auth{
-		users:    make(map[string]*User),
-		sessions: make(map[string]*Session),
+		users:         make(map[string]*User),
+		sessions:      make(map[string]*Session),
+		verifications: make(map[string]*verification),
 	}
 }
 
 // CreateUser creates a new user
 func (a *Auth) CreateUser(username, password, role string) (*User, error) {
+	user, err := a.createUser(username, password, role)
+	if err != nil {
+		return nil, err
+	}
+
+	a.RecordAudit(AuditUserCreated, "system", username, "", fmt.Sprintf("role=%s", role))
+	return user, nil
+}
+
+// createUser adds a user to the store without recording an audit entry
+func (a *Auth) createUser(username, password, role string) (*User, error) {
+	// Check if user already exists, including users created by other processes
+	if a.lookupUser(username) != nil {
+		return nil, errors.New("user already exists")
+	}
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
-
-	// Check if user already exists
 	if _, exists := a.users[username]; exists {
 		return nil, errors.New("user already exists")
 	}
@@ -69,76 +97,148 @@ func (a *Auth) CreateUser(username, password, role string) (*User, error) {
 		CreatedAt:    time.Now(),
 	}
 
+	// The store assigns the ID of persisted users
+	if err := a.persistUser(user); err != nil {
+		return nil, err
+	}
 	a.users[username] = user
 	return user, nil
 }
 
+// UpdateUserRole changes the role of an existing user. A changed role ends
+// the user's sessions, so copies of them cached with the old role, such as
+// in Redis, aren't used any more.
+func (a *Auth) UpdateUserRole(actor, username, role string) error {
+	if role != "admin" && role != "user" {
+		return fmt.Errorf("invalid role: %s", role)
+	}
+
+	if a.lookupUser(username) == nil {
+		return errors.New("user not found")
+	}
+
+	a.mu.Lock()
+	user := a.users[username]
+	oldRole := user.Role
+	user.Role = role
+	a.mu.Unlock()
+	a.syncUser(username)
+	if oldRole != role {
+		a.RevokeUserSessions(user.ID)
+	}
+
+	a.RecordAudit(AuditRoleChanged, actor, username, "", fmt.Sprintf("role %s -> %s", oldRole, role))
+	return nil
+}
+
 // Authenticate checks if the username and password are valid
 func (a *Auth) Authenticate(username, password string) (*User, error) {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-
-	user, exists := a.users[username]
-	if !exists {
+	user := a.lookupUser(username)
+	if user == nil {
 		return nil, errors.New("invalid username or password")
 	}
 
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
 		return nil, errors.New("invalid username or password")
 	}
 
+	if user.Pending {
+		return nil, errors.New("email address has not been verified")
+	}
+
 	return user, nil
 }
 
 // CreateSession creates a new session for a user
-func (a *Auth) CreateSession(userID int) (*Session, error) {
+func (a *Auth) CreateSession(userID int, ipAddress, userAgent string) (*Session, error) {
 	a.mu.Lock()
-	defer a.mu.Unlock()
 
 	// Generate random session ID
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
+		a.mu.Unlock()
 		return nil, err
 	}
 	sessionID := base64.StdEncoding.EncodeToString(b)
 
 	// Create session
+	now := time.Now()
 	session := &Session{
 		ID:        sessionID,
+		Handle:    sessionHandle(sessionID),
 		UserID:    userID,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(24 * time.Hour), // 24 hour sessions
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(24 * time.Hour), // 24 hour sessions
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
 	}
 
 	a.sessions[sessionID] = session
+	a.mu.Unlock()
+
+	a.persistSession(session)
+
+	a.RecordAudit(AuditTokenIssued, "system", fmt.Sprintf("user:%d", userID), ipAddress, "session token issued")
 	return session, nil
 }
 
-// GetSession retrieves a session by ID
+// GetSession retrieves a session by ID. With a session store, the store is
+// checked on every call, so a session ended by another replica or process
+// ends here too; the copy in memory is only used while the store can't be
+// read.
 func (a *Auth) GetSession(sessionID string) (*Session, error) {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
+	if a.store != nil {
+		stored, err := a.loadSession(sessionID)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		} else if stored == nil {
+			a.mu.Lock()
+			delete(a.sessions, sessionID)
+			a.mu.Unlock()
+			return nil, errors.New("session not found")
+		}
+	}
 
+	a.mu.RLock()
 	session, exists := a.sessions[sessionID]
+	a.mu.RUnlock()
 	if !exists {
 		return nil, errors.New("session not found")
 	}
 
 	// Check if session has expired
 	if time.Now().After(session.ExpiresAt) {
-		delete(a.sessions, sessionID)
+		a.DeleteSession(sessionID)
 		return nil, errors.New("session expired")
 	}
 
 	return session, nil
 }
 
-// GetUserByID retrieves a user by ID
+// GetUserByID retrieves a user by ID. With a user store, the user is read
+// from the store, so a role change or deletion made by another replica or
+// process is seen at once; the copy in memory is only used while the store
+// can't be read.
 func (a *Auth) GetUserByID(userID int) (*User, error) {
 	a.mu.RLock()
-	defer a.mu.RUnlock()
+	store := a.userStore
+	a.mu.RUnlock()
+	if store != nil {
+		stored, err := store.GetUserByID(userID)
+		if err != nil {
+			log.Printf("Warning: failed to load user %d from store: %v", userID, err)
+		} else {
+			return a.refreshUser(userID, stored)
+		}
+	}
 
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	for _, user := range a.users {
 		if user.ID == userID {
 			return user, nil
@@ -151,19 +251,21 @@ func (a *Auth) GetUserByID(userID int) (*User, error) {
 // DeleteSession removes a session
 func (a *Auth) DeleteSession(sessionID string) {
 	a.mu.Lock()
-	defer a.mu.Unlock()
-
 	delete(a.sessions, sessionID)
+	a.mu.Unlock()
+
+	a.unpersistSessions([]string{sessionID})
 }
 
-// SetSessionCookie sets a session cookie on the response
-func SetSessionCookie(w http.ResponseWriter, session *Session) {
+// SetSessionCookie sets a session cookie on the response to r. The cookie
+// is only sent back over HTTPS when r arrived over HTTPS.
+func SetSessionCookie(w http.ResponseWriter, r *http.Request, session *Session) {
 	cookie := &http.Cookie{
 		Name:     "session",
 		Value:    session.ID,
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   false, // Allow HTTP access
+		Secure:   r.TLS != nil, // Plain HTTP servers still get a usable cookie
 		SameSite: http.SameSiteLaxMode,
 		Expires:  session.ExpiresAt,
 	}
@@ -188,11 +290,7 @@ func ClearSessionCookie(w http.ResponseWriter) {
 // InitializeDefaultUsers creates default users for the system
 func (a *Auth) InitializeDefaultUsers() {
 	// Check if admin user exists already
-	a.mu.RLock()
-	_, adminExists := a.users["admin"]
-	a.mu.RUnlock()
-
-	if !adminExists {
+	if a.lookupUser("admin") == nil {
 		a.CreateUser("admin", "admin123", "admin")
 	}
 }
@@ -200,33 +298,29 @@ func (a *Auth) InitializeDefaultUsers() {
 // RequireAuth is middleware that checks if a user is authenticated
 func (a *Auth) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("Auth middleware checking request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
-		
 		cookie, err := r.Cookie("session")
 		if err != nil {
-			log.Printf("No session cookie found: %v - redirecting to login", err)
+			slog.Debug("No session cookie, redirecting to login", "path", r.URL.Path)
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
-		log.Printf("Found session cookie: %s", cookie.Value)
 
 		session, err := a.GetSession(cookie.Value)
 		if err != nil {
-			log.Printf("Invalid session: %v - clearing cookie and redirecting", err)
+			slog.Info("Invalid session, clearing cookie and redirecting to login", "error", err)
 			ClearSessionCookie(w)
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
-		log.Printf("Valid session found for user ID: %d", session.UserID)
+		a.TouchSession(session.ID)
 
 		user, err := a.GetUserByID(session.UserID)
 		if err != nil {
-			log.Printf("User not found for session: %v - clearing cookie and redirecting", err)
+			slog.Warn("User not found for session, clearing cookie and redirecting to login", "user_id", session.UserID, "error", err)
 			ClearSessionCookie(w)
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
-		log.Printf("User authenticated: %s (ID: %d), proceeding to handler", user.Username, user.ID)
 
 		// Store user in request context
 		ctx := SetUserInContext(r.Context(), user)
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"example-api/internal/logging"
 )
 
 // contextKey is a custom type for context keys to prevent collisions
@@ -10,8 +11,10 @@ type contextKey string
 // userKey is the key used to store the user in the context
 const userKey = contextKey("user")
 
-// SetUserInContext stores the user in the context
+// SetUserInContext stores the user in the context and records them as the
+// identity in the request log
 func SetUserInContext(ctx context.Context, user *User) context.Context {
+	logging.SetUser(ctx, user.Username)
 	return context.WithValue(ctx, userKey, user)
 }
 
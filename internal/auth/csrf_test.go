@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// CSRF tokens carry no expiry of their own: they are derived from the
+// session, so they stop working when it ends and the cookie changes
+func TestRequireCSRF(t *testing.T) {
+	const session = "session-a"
+	token := CSRFToken(session)
+	tampered := token[:len(token)-1] + "0"
+	if tampered == token {
+		tampered = token[:len(token)-1] + "1"
+	}
+	handler := RequireCSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		cookie string
+		header string
+		form   string
+		want   int
+	}{
+		{name: "header", method: http.MethodPost, cookie: session, header: token, want: http.StatusNoContent},
+		{name: "form field", method: http.MethodPost, cookie: session, form: token, want: http.StatusNoContent},
+		{name: "reads pass", method: http.MethodGet, want: http.StatusNoContent},
+		{name: "missing token", method: http.MethodPost, cookie: session, want: http.StatusForbidden},
+		{name: "tampered token", method: http.MethodPost, cookie: session, header: tampered, want: http.StatusForbidden},
+		{name: "token of another session", method: http.MethodPost, cookie: "session-b", header: token, want: http.StatusForbidden},
+		{name: "token of an ended session", method: http.MethodDelete, cookie: "session-renewed", form: token, want: http.StatusForbidden},
+		{name: "no session", method: http.MethodPost, header: token, want: http.StatusForbidden},
+		{name: "empty token without session", method: http.MethodPost, header: "", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.form != "" {
+				form.Set(CSRFFormField, tt.form)
+			}
+			req := httptest.NewRequest(tt.method, "/events/1", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set("X-CSRF-Token", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFTokenPerSession(t *testing.T) {
+	if CSRFToken("session-a") != CSRFToken("session-a") {
+		t.Error("CSRF token of a session changes")
+	}
+	if CSRFToken("session-a") == CSRFToken("session-b") {
+		t.Error("two sessions share a CSRF token")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := RequestCSRFToken(req); got != "" {
+		t.Errorf("RequestCSRFToken() without a session = %q, want none", got)
+	}
+	req.AddCookie(&http.Cookie{Name: "session", Value: "session-a"})
+	if got := RequestCSRFToken(req); got != CSRFToken("session-a") {
+		t.Errorf("RequestCSRFToken() = %q, want the session's token", got)
+	}
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// verificationTTL is how long a verification link can be used
+const verificationTTL = 24 * time.Hour
+
+// verification is a pending email verification for a self-registered user,
+// kept in memory when there is no user store
+type verification struct {
+	Username  string
+	ExpiresAt time.Time
+}
+
+// hashVerificationToken returns the SHA-256 of a verification token, which
+// is what gets stored
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RegisterUser creates a user that cannot log in until their email is verified.
+// It returns the verification token that must be passed to VerifyEmail.
+func (a *Auth) RegisterUser(username, email, password, role string) (*User, string, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, "", errors.New("username is required")
+	}
+	if len(password) < 8 {
+		return nil, "", errors.New("password must be at least 8 characters")
+	}
+
+	user, err := a.createUser(username, password, role)
+	if err != nil {
+		return nil, "", err
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(b)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	user.Email = strings.ToLower(strings.TrimSpace(email))
+	user.Pending = true
+	if err := a.persistUser(user); err != nil {
+		log.Printf("Warning: failed to persist user %s: %v", username, err)
+	}
+	expiresAt := time.Now().Add(verificationTTL)
+	if a.userStore != nil {
+		// The link may be opened on any server, so it only works once stored
+		err := a.userStore.SaveVerification(&models.EmailVerification{
+			TokenHash: hashVerificationToken(token),
+			UserID:    user.ID,
+			Username:  username,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	} else {
+		a.verifications[hashVerificationToken(token)] = &verification{
+			Username:  username,
+			ExpiresAt: expiresAt,
+		}
+	}
+
+	a.RecordAudit(AuditUserCreated, username, username, "", fmt.Sprintf("self-registered, role=%s", role))
+	return user, token, nil
+}
+
+// VerifyEmail activates the user associated with a verification token
+func (a *Auth) VerifyEmail(token string) (*User, error) {
+	v, err := a.takeVerification(hashVerificationToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, errors.New("invalid verification token")
+	}
+	if time.Now().After(v.ExpiresAt) {
+		return nil, errors.New("verification token expired")
+	}
+
+	user := a.lookupUser(v.Username)
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	user.Pending = false
+	if err := a.persistUser(user); err != nil {
+		log.Printf("Warning: failed to persist user %s: %v", user.Username, err)
+	}
+
+	return user, nil
+}
+
+// takeVerification removes and returns the pending verification with the
+// given token hash, from the user store if there is one. It returns nil if
+// there is no such verification.
+func (a *Auth) takeVerification(tokenHash string) (*verification, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.userStore == nil {
+		v := a.verifications[tokenHash]
+		delete(a.verifications, tokenHash)
+		return v, nil
+	}
+
+	stored, err := a.userStore.TakeVerification(tokenHash)
+	if err != nil || stored == nil {
+		return nil, err
+	}
+	return &verification{Username: stored.Username, ExpiresAt: stored.ExpiresAt}, nil
+}
+
+// DeleteUser removes a user and all of their sessions
+func (a *Auth) DeleteUser(username string) error {
+	user := a.lookupUser(username)
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	a.RevokeUserSessions(user.ID)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for token, v := range a.verifications {
+		if v.Username == username {
+			delete(a.verifications, token)
+		}
+	}
+	delete(a.users, username)
+	if a.userStore != nil {
+		if err := a.userStore.DeleteUser(username); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnsureExternalUser returns the user for an identity asserted by an external
+// identity provider, creating it or updating its role as needed. Only users
+// the provider created are returned: a local account or one of another
+// provider with the same username is refused rather than taken over.
+func (a *Auth) EnsureExternalUser(username, email, role, provider string) (*User, error) {
+	user := a.lookupUser(username)
+	if user == nil {
+		// External users never log in with a password, so use an unguessable one
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		created, err := a.createUser(username, hex.EncodeToString(b), role)
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		created.Email = strings.ToLower(strings.TrimSpace(email))
+		created.Provider = provider
+		if err := a.persistUser(created); err != nil {
+			log.Printf("Warning: failed to persist user %s: %v", username, err)
+		}
+		a.mu.Unlock()
+
+		a.RecordAudit(AuditUserCreated, provider, username, "", fmt.Sprintf("provisioned by %s, role=%s", provider, role))
+		return created, nil
+	}
+
+	if user.Provider != provider {
+		return nil, fmt.Errorf("user %s was not created by %s", username, provider)
+	}
+	if user.Role != role {
+		if err := a.UpdateUserRole(provider, username, role); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
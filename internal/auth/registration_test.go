@@ -0,0 +1,67 @@
+package auth
+
+import "testing"
+
+// Single sign-on must not log in as, or change the role of, a local account
+// that happens to share the asserted username
+func TestEnsureExternalUserRefusesLocalAccounts(t *testing.T) {
+	a := New()
+	if _, err := a.CreateUser("admin", "admin123", "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	if user, err := a.EnsureExternalUser("admin", "admin@example.com", "user", "saml"); err == nil {
+		t.Fatalf("got user %s, want an error", user.Username)
+	}
+	if user := a.lookupUser("admin"); user.Role != "admin" || user.Provider != "" {
+		t.Errorf("local admin became role %q of provider %q", user.Role, user.Provider)
+	}
+}
+
+func TestEnsureExternalUserSyncsRole(t *testing.T) {
+	a := New()
+	created, err := a.EnsureExternalUser("alice", "Alice@Example.com", "user", "saml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Provider != "saml" || created.Email != "alice@example.com" {
+		t.Fatalf("created user of provider %q with email %q", created.Provider, created.Email)
+	}
+
+	user, err := a.EnsureExternalUser("alice", "alice@example.com", "admin", "saml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != created.ID || user.Role != "admin" {
+		t.Errorf("got user %d with role %q, want user %d with role admin", user.ID, user.Role, created.ID)
+	}
+
+	if _, err := a.EnsureExternalUser("alice", "alice@example.com", "admin", "oidc"); err == nil {
+		t.Error("another provider logged in as alice")
+	}
+}
+
+func TestVerifyEmailOnce(t *testing.T) {
+	a := New()
+	user, token, err := a.RegisterUser("bob", "bob@example.com", "password123", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.Pending {
+		t.Fatal("registered user isn't pending")
+	}
+	if _, exists := a.verifications[token]; exists {
+		t.Error("verification token is kept in the clear")
+	}
+
+	verified, err := a.VerifyEmail(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Username != "bob" || verified.Pending {
+		t.Errorf("got user %s, pending %v", verified.Username, verified.Pending)
+	}
+	if _, err := a.VerifyEmail(token); err == nil {
+		t.Error("verification token was accepted twice")
+	}
+}
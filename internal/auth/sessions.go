@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// touchInterval limits how often last seen times are written to the session store
+const touchInterval = time.Minute
+
+// SessionStore persists sessions so they survive restarts and can be
+// validated by other processes such as the API server
+type SessionStore interface {
+	SaveSession(session *models.WebSession) error
+	GetSession(tokenHash string) (*models.WebSession, error)
+	TouchSession(tokenHash string, lastSeen time.Time) error
+	DeleteSessions(tokenHashes []string) error
+	DeleteUserSessions(userID int) error
+}
+
+// SetSessionStore configures where sessions are persisted
+func (a *Auth) SetSessionStore(store SessionStore) {
+	a.store = store
+}
+
+// HashSessionToken returns the value used to look up a session cookie in the store
+func HashSessionToken(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+// persistSession writes a session to the store, if one is configured
+func (a *Auth) persistSession(session *Session) {
+	if a.store == nil {
+		return
+	}
+
+	user, err := a.GetUserByID(session.UserID)
+	if err != nil {
+		log.Printf("Warning: not persisting session for unknown user ID %d", session.UserID)
+		return
+	}
+
+	err = a.store.SaveSession(&models.WebSession{
+		TokenHash: HashSessionToken(session.ID),
+		Handle:    session.Handle,
+		UserID:    session.UserID,
+		Username:  user.Username,
+		Role:      user.Role,
+		IPAddress: session.IPAddress,
+		UserAgent: session.UserAgent,
+		CreatedAt: session.CreatedAt,
+		LastSeen:  session.LastSeen,
+		ExpiresAt: session.ExpiresAt,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to persist session for user %s: %v", user.Username, err)
+	}
+}
+
+// loadSession reads a session from the store into memory, keeping the last
+// seen time of the copy in memory when it is newer. It returns nil if the
+// store doesn't have the session.
+func (a *Auth) loadSession(sessionID string) (*Session, error) {
+	stored, err := a.store.GetSession(HashSessionToken(sessionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session from store: %w", err)
+	}
+	if stored == nil {
+		return nil, nil
+	}
+
+	session := &Session{
+		ID:        sessionID,
+		Handle:    stored.Handle,
+		UserID:    stored.UserID,
+		CreatedAt: stored.CreatedAt,
+		LastSeen:  stored.LastSeen,
+		ExpiresAt: stored.ExpiresAt,
+		IPAddress: stored.IPAddress,
+		UserAgent: stored.UserAgent,
+	}
+
+	a.mu.Lock()
+	if cached, exists := a.sessions[sessionID]; exists && cached.LastSeen.After(session.LastSeen) {
+		session.LastSeen = cached.LastSeen
+	}
+	a.sessions[sessionID] = session
+	a.mu.Unlock()
+
+	return session, nil
+}
+
+// unpersistSessions removes sessions from the store, if one is configured
+func (a *Auth) unpersistSessions(sessionIDs []string) {
+	if a.store == nil || len(sessionIDs) == 0 {
+		return
+	}
+
+	hashes := make([]string, len(sessionIDs))
+	for i, id := range sessionIDs {
+		hashes[i] = HashSessionToken(id)
+	}
+	if err := a.store.DeleteSessions(hashes); err != nil {
+		log.Printf("Warning: failed to delete persisted sessions: %v", err)
+	}
+}
+
+// sessionHandle derives a non-secret identifier for a session ID
+func sessionHandle(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// TouchSession updates the last seen time of a session
+func (a *Auth) TouchSession(sessionID string) {
+	now := time.Now()
+	persist := false
+
+	a.mu.Lock()
+	if session, exists := a.sessions[sessionID]; exists {
+		persist = now.Sub(session.LastSeen) >= touchInterval
+		session.LastSeen = now
+	}
+	a.mu.Unlock()
+
+	if persist && a.store != nil {
+		if err := a.store.TouchSession(HashSessionToken(sessionID), now); err != nil {
+			log.Printf("Warning: failed to update session last seen time: %v", err)
+		}
+	}
+}
+
+// ListUserSessions returns the active sessions of a user, most recently seen first
+func (a *Auth) ListUserSessions(userID int) []Session {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	now := time.Now()
+	var sessions []Session
+	for _, session := range a.sessions {
+		if session.UserID == userID && now.Before(session.ExpiresAt) {
+			sessions = append(sessions, *session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+
+	return sessions
+}
+
+// RevokeSession removes a single session of a user by its handle
+func (a *Auth) RevokeSession(userID int, handle string) error {
+	a.mu.Lock()
+	var revoked string
+	for id, session := range a.sessions {
+		if session.UserID == userID && session.Handle == handle {
+			delete(a.sessions, id)
+			revoked = id
+			break
+		}
+	}
+	a.mu.Unlock()
+
+	if revoked == "" {
+		return errors.New("session not found")
+	}
+
+	a.unpersistSessions([]string{revoked})
+	return nil
+}
+
+// RevokeUserSessions removes all sessions of a user and returns how many were removed
+func (a *Auth) RevokeUserSessions(userID int) int {
+	a.mu.Lock()
+	var revoked []string
+	for id, session := range a.sessions {
+		if session.UserID == userID {
+			delete(a.sessions, id)
+			revoked = append(revoked, id)
+		}
+	}
+	a.mu.Unlock()
+
+	// Also remove persisted sessions that have not been loaded since a restart
+	if a.store != nil {
+		if err := a.store.DeleteUserSessions(userID); err != nil {
+			log.Printf("Warning: failed to delete persisted sessions for user ID %d: %v", userID, err)
+		}
+	}
+	return len(revoked)
+}
+
+// ListUsers returns all users ordered by ID
+func (a *Auth) ListUsers() []User {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	users := make([]User, 0, len(a.users))
+	for _, user := range a.users {
+		users = append(users, *user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	return users
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// UserStore persists users so they survive restarts and can be created by
+// other processes, such as "eventdb create-user". Pending email
+// verifications are kept with them; deleting a user deletes theirs.
+type UserStore interface {
+	SaveUser(user *models.WebUser) error
+	GetUser(username string) (*models.WebUser, error)
+	GetUserByID(id int) (*models.WebUser, error)
+	ListUsers() ([]models.WebUser, error)
+	DeleteUser(username string) error
+	SaveVerification(v *models.EmailVerification) error
+	TakeVerification(tokenHash string) (*models.EmailVerification, error)
+}
+
+// SetUserStore configures where users are persisted and loads the stored
+// users. It should be called before InitializeDefaultUsers so a stored admin
+// account isn't created again.
+func (a *Auth) SetUserStore(store UserStore) error {
+	stored, err := store.ListUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.userStore = store
+	for i := range stored {
+		a.users[stored[i].Username] = userFromStored(&stored[i])
+	}
+	return nil
+}
+
+// lookupUser returns a user from memory or, failing that, from the store.
+// a.mu must not be held.
+func (a *Auth) lookupUser(username string) *User {
+	a.mu.RLock()
+	user, exists := a.users[username]
+	store := a.userStore
+	a.mu.RUnlock()
+	if exists || store == nil {
+		return user
+	}
+
+	stored, err := store.GetUser(username)
+	if err != nil {
+		log.Printf("Warning: failed to load user %s from store: %v", username, err)
+		return nil
+	}
+	if stored == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if user, exists := a.users[username]; exists {
+		return user
+	}
+	user = userFromStored(stored)
+	a.users[username] = user
+	return user
+}
+
+// refreshUser replaces the copy in memory of the user with the given ID by
+// the stored one, which is nil if the store no longer has the user
+func (a *Auth) refreshUser(userID int, stored *models.WebUser) (*User, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for username, user := range a.users {
+		if user.ID == userID && (stored == nil || username != stored.Username) {
+			delete(a.users, username)
+		}
+	}
+	if stored == nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Callers may hold the user, so it is updated in place
+	user, exists := a.users[stored.Username]
+	if !exists {
+		user = &User{}
+		a.users[stored.Username] = user
+	}
+	*user = *userFromStored(stored)
+	return user, nil
+}
+
+// persistUser writes a user to the store, if one is configured. a.mu must be
+// held.
+func (a *Auth) persistUser(user *User) error {
+	if a.userStore == nil {
+		return nil
+	}
+	stored := &models.WebUser{
+		ID:           user.ID,
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+		Role:         user.Role,
+		Email:        user.Email,
+		Pending:      user.Pending,
+		Provider:     user.Provider,
+		CreatedAt:    user.CreatedAt,
+	}
+	if err := a.userStore.SaveUser(stored); err != nil {
+		return err
+	}
+	user.ID = stored.ID
+	return nil
+}
+
+// syncUser persists a user for callers not holding a.mu. Failures are only
+// logged; the change has already been made in memory.
+func (a *Auth) syncUser(username string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	user, exists := a.users[username]
+	if !exists {
+		return
+	}
+	if err := a.persistUser(user); err != nil {
+		log.Printf("Warning: failed to persist user %s: %v", username, err)
+	}
+}
+
+func userFromStored(stored *models.WebUser) *User {
+	return &User{
+		ID:           stored.ID,
+		Username:     stored.Username,
+		PasswordHash: stored.PasswordHash,
+		Role:         stored.Role,
+		Email:        stored.Email,
+		Pending:      stored.Pending,
+		Provider:     stored.Provider,
+		CreatedAt:    stored.CreatedAt,
+	}
+}
+
+// SetUserEmail changes the email address of a user
+func (a *Auth) SetUserEmail(username, email string) error {
+	if a.lookupUser(username) == nil {
+		return errors.New("user not found")
+	}
+	a.mu.Lock()
+	a.users[username].Email = strings.ToLower(strings.TrimSpace(email))
+	a.mu.Unlock()
+	a.syncUser(username)
+	return nil
+}
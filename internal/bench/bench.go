@@ -0,0 +1,266 @@
+// Package bench load-tests the ingestion path of a running server by posting
+// synthetic events to POST /api/events and measuring how fast they are
+// answered.
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures a benchmark run
+type Options struct {
+	URL         string        // Base URL of the API server
+	Token       string        // API token
+	Source      string        // Source the events are sent with
+	Rate        float64       // Events per second to send; 0 sends as fast as the workers can
+	Duration    time.Duration // How long to send for
+	Concurrency int           // Requests in flight at most
+	BodySize    int           // Bytes of text in each event's body
+	Client      *http.Client
+}
+
+// Result is what a benchmark run measured
+type Result struct {
+	Sent       int         `json:"sent"`
+	Succeeded  int         `json:"succeeded"` // Answered with a 2xx status
+	Failed     int         `json:"failed"`    // Answered with another status or not at all
+	Skipped    int         `json:"skipped"`   // Not sent because every worker was busy, so the rate wasn't reached
+	Statuses   map[int]int `json:"statuses"`  // Responses by status; 0 counts requests that got none
+	Elapsed    Duration    `json:"elapsed"`
+	Throughput float64     `json:"throughput"` // Succeeded events per second
+	Latency    Latency     `json:"latency"`    // Of the requests that got a response
+}
+
+// Latency summarizes request latencies
+type Latency struct {
+	Min  Duration `json:"min"`
+	Mean Duration `json:"mean"`
+	P50  Duration `json:"p50"`
+	P90  Duration `json:"p90"`
+	P95  Duration `json:"p95"`
+	P99  Duration `json:"p99"`
+	Max  Duration `json:"max"`
+}
+
+// Duration is a time.Duration written to JSON in milliseconds
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(float64(d) / float64(time.Millisecond))
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).Round(10 * time.Microsecond).String()
+}
+
+// sample is the outcome of one request
+type sample struct {
+	status  int
+	latency time.Duration
+}
+
+// Run sends events for opts.Duration, or until ctx is done, and returns what
+// was measured. Requests still in flight at the end are waited for.
+func Run(ctx context.Context, opts Options) (*Result, error) {
+	if opts.Concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1")
+	}
+	if opts.Rate < 0 {
+		return nil, fmt.Errorf("rate can't be negative")
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{MaxIdleConnsPerHost: opts.Concurrency},
+		}
+	}
+	endpoint := strings.TrimRight(opts.URL, "/") + "/api/events"
+	body := strings.Repeat("Synthetic benchmark event. ", opts.BodySize/27+1)[:max(opts.BodySize, 0)]
+	run := time.Now().UTC().Format("20060102T150405")
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	jobs := make(chan int)
+	samples := make(chan sample, opts.Concurrency)
+	var workers sync.WaitGroup
+	for w := 0; w < opts.Concurrency; w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for n := range jobs {
+				samples <- send(client, endpoint, opts, event(run, n, opts.Source, body))
+			}
+		}()
+	}
+
+	result := &Result{Statuses: make(map[int]int)}
+	var latencies []time.Duration
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for s := range samples {
+			result.Statuses[s.status]++
+			if s.status >= 200 && s.status < 300 {
+				result.Succeeded++
+			} else {
+				result.Failed++
+			}
+			if s.status != 0 {
+				latencies = append(latencies, s.latency)
+			}
+		}
+	}()
+
+	start := time.Now()
+	if opts.Rate == 0 {
+	send:
+		for n := 1; ; n++ {
+			select {
+			case jobs <- n:
+				result.Sent++
+			case <-ctx.Done():
+				break send
+			}
+		}
+	} else {
+		// Events are scheduled at the target rate whether or not earlier ones
+		// have been answered, so a slow server shows up as skipped events
+		// rather than as a lower rate with good latencies
+		interval := time.Duration(float64(time.Second) / opts.Rate)
+		ticker := time.NewTicker(max(interval, time.Microsecond))
+		defer ticker.Stop()
+		for n := 1; ctx.Err() == nil; n++ {
+			select {
+			case jobs <- n:
+				result.Sent++
+			default:
+				result.Skipped++
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+			}
+		}
+	}
+	close(jobs)
+	workers.Wait()
+	close(samples)
+	<-collected
+
+	elapsed := time.Since(start)
+	result.Elapsed = Duration(elapsed)
+	result.Throughput = float64(result.Succeeded) / elapsed.Seconds()
+	result.Latency = summarize(latencies)
+	return result, nil
+}
+
+// event returns the request body of the nth event of a run. Each event has
+// its own message ID so none is dropped as a duplicate.
+func event(run string, n int, source, body string) []byte {
+	var req struct {
+		Data struct {
+			From      string    `json:"from"`
+			To        string    `json:"to"`
+			Subject   string    `json:"subject"`
+			Body      string    `json:"body"`
+			MessageID string    `json:"message_id"`
+			Date      time.Time `json:"date"`
+		} `json:"data"`
+		Source string `json:"source"`
+	}
+	req.Data.From = "bench@example.com"
+	req.Data.To = "events@example.com"
+	req.Data.Subject = fmt.Sprintf("Benchmark %s event %d", run, n)
+	req.Data.Body = body
+	req.Data.MessageID = fmt.Sprintf("<bench-%s-%d@eventdb.invalid>", run, n)
+	req.Data.Date = time.Now().UTC()
+	req.Source = source
+	b, _ := json.Marshal(req)
+	return b
+}
+
+// send posts one event, returning status 0 if no response was received
+func send(client *http.Client, endpoint string, opts Options, body []byte) sample {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return sample{}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.Token)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return sample{}
+	}
+	// Read the whole response so the connection is reused
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return sample{status: resp.StatusCode, latency: time.Since(start)}
+}
+
+// summarize computes the latency percentiles, using the nearest rank
+func summarize(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	slices.Sort(latencies)
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	percentile := func(p float64) Duration {
+		rank := int(math.Ceil(p/100*float64(len(latencies)))) - 1
+		return Duration(latencies[min(max(rank, 0), len(latencies)-1)])
+	}
+	return Latency{
+		Min:  Duration(latencies[0]),
+		Mean: Duration(total / time.Duration(len(latencies))),
+		P50:  percentile(50),
+		P90:  percentile(90),
+		P95:  percentile(95),
+		P99:  percentile(99),
+		Max:  Duration(latencies[len(latencies)-1]),
+	}
+}
+
+// WriteText writes the result as a human-readable report
+func (r *Result) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "Sent %d events in %s: %d succeeded, %d failed", r.Sent, r.Elapsed, r.Succeeded, r.Failed)
+	if r.Skipped > 0 {
+		fmt.Fprintf(w, ", %d skipped because every worker was busy", r.Skipped)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Throughput: %.1f events/s\n", r.Throughput)
+
+	statuses := make([]int, 0, len(r.Statuses))
+	for status := range r.Statuses {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+	for _, status := range statuses {
+		label := fmt.Sprintf("%d %s", status, http.StatusText(status))
+		if status == 0 {
+			label = "no response"
+		}
+		fmt.Fprintf(w, "  %-28s %d\n", label, r.Statuses[status])
+	}
+
+	l := r.Latency
+	fmt.Fprintf(w, "Latency: min %s, mean %s, p50 %s, p90 %s, p95 %s, p99 %s, max %s\n",
+		l.Min, l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
+}
@@ -0,0 +1,64 @@
+// Package cache stores values that are expensive to compute, in memory or in
+// Redis, where every server of a deployment shares them
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMiss is returned by Cache.Get when the key isn't cached
+var ErrMiss = errors.New("cache miss")
+
+// Cache stores values for a limited time. Implementations are safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the value stored under key, or ErrMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys, ignoring those that aren't cached
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// New creates the cache named by backend: "memory", kept by this process,
+// or "redis", shared through the server at redisURL. Redis keys start with
+// prefix so several deployments can share a server.
+func New(backend, redisURL, prefix string) (Cache, error) {
+	switch backend {
+	case "", "memory":
+		return NewMemory(), nil
+	case "redis":
+		return NewRedis(redisURL, prefix)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q, want memory or redis", backend)
+	}
+}
+
+// GetJSON decodes the JSON value stored under key into v, reporting whether
+// it was cached
+func GetJSON(ctx context.Context, c Cache, key string, v interface{}) (bool, error) {
+	b, err := c.Get(ctx, key)
+	if errors.Is(err, ErrMiss) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, fmt.Errorf("failed to decode cached %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// SetJSON stores v under key as JSON for ttl
+func SetJSON(ctx context.Context, c Cache, key string, v interface{}, ttl time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s for the cache: %w", key, err)
+	}
+	return c.Set(ctx, key, b, ttl)
+}
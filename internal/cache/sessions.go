@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"time"
+)
+
+// SessionBackend is where sessions are kept for good
+type SessionBackend interface {
+	auth.SessionStore
+	UserSessionTokenHashes(userID int) ([]string, error)
+}
+
+// SessionStore keeps web sessions in a cache in front of the database, so
+// servers that haven't seen a session yet, such as after a restart or on
+// another replica, find it without a query. Every write goes to the database
+// first; the cache only ever holds copies.
+type SessionStore struct {
+	backend SessionBackend
+	cache   Cache
+}
+
+// NewSessionStore caches the sessions stored in backend in c
+func NewSessionStore(backend SessionBackend, c Cache) *SessionStore {
+	return &SessionStore{backend: backend, cache: c}
+}
+
+func sessionKey(tokenHash string) string {
+	return "session:" + tokenHash
+}
+
+func (s *SessionStore) SaveSession(session *models.WebSession) error {
+	if err := s.backend.SaveSession(session); err != nil {
+		return err
+	}
+	s.store(session)
+	return nil
+}
+
+func (s *SessionStore) GetSession(tokenHash string) (*models.WebSession, error) {
+	var session models.WebSession
+	ok, err := GetJSON(context.Background(), s.cache, sessionKey(tokenHash), &session)
+	if err != nil {
+		log.Printf("Warning: failed to read session from the cache: %v", err)
+	}
+	if ok && time.Now().Before(session.ExpiresAt) {
+		session.TokenHash = tokenHash
+		return &session, nil
+	}
+
+	stored, err := s.backend.GetSession(tokenHash)
+	if err != nil || stored == nil {
+		return stored, err
+	}
+	s.store(stored)
+	return stored, nil
+}
+
+func (s *SessionStore) TouchSession(tokenHash string, lastSeen time.Time) error {
+	if err := s.backend.TouchSession(tokenHash, lastSeen); err != nil {
+		return err
+	}
+	// A cached copy only has an older last seen time, so failing to drop it
+	// is harmless
+	if err := s.drop(tokenHash); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return nil
+}
+
+// DeleteSessions removes sessions from the cache and the database. They are
+// dropped from the cache first, so a session that could stay cached is not
+// deleted either, and again afterwards in case one was read back meanwhile.
+func (s *SessionStore) DeleteSessions(tokenHashes []string) error {
+	if err := s.drop(tokenHashes...); err != nil {
+		return err
+	}
+	if err := s.backend.DeleteSessions(tokenHashes); err != nil {
+		return err
+	}
+	return s.drop(tokenHashes...)
+}
+
+// DeleteUserSessions removes a user's sessions like DeleteSessions
+func (s *SessionStore) DeleteUserSessions(userID int) error {
+	hashes, err := s.backend.UserSessionTokenHashes(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.drop(hashes...); err != nil {
+		return err
+	}
+	if err := s.backend.DeleteUserSessions(userID); err != nil {
+		return err
+	}
+	return s.drop(hashes...)
+}
+
+// store caches a session until it expires
+func (s *SessionStore) store(session *models.WebSession) {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return
+	}
+	if err := SetJSON(context.Background(), s.cache, sessionKey(session.TokenHash), session, ttl); err != nil {
+		log.Printf("Warning: failed to cache session: %v", err)
+	}
+}
+
+// drop removes sessions from the cache
+func (s *SessionStore) drop(tokenHashes ...string) error {
+	keys := make([]string, len(tokenHashes))
+	for i, hash := range tokenHashes {
+		keys[i] = sessionKey(hash)
+	}
+	if err := s.cache.Delete(context.Background(), keys...); err != nil {
+		return fmt.Errorf("failed to drop sessions from the cache: %w", err)
+	}
+	return nil
+}
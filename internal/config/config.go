@@ -4,16 +4,46 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// HTTPSettings are the connection limits of one of the HTTP servers. A zero
+// timeout means none.
+type HTTPSettings struct {
+	ReadTimeout       time.Duration `mapstructure:"read_timeout"`        // For the whole request, body included
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"` // For the request headers
+	WriteTimeout      time.Duration `mapstructure:"write_timeout"`       // From the end of the request headers to the end of the response
+	IdleTimeout       time.Duration `mapstructure:"idle_timeout"`        // How long a keep-alive connection waits for the next request
+	MaxHeaderBytes    int           `mapstructure:"max_header_bytes"`
+}
+
 type Config struct {
 	Server struct {
-		Port     int
-		Domain   string
-		APIToken string `mapstructure:"api_token"`
+		Port             int
+		Domain           string
+		BaseURL          string        `mapstructure:"base_url"` // Where the web interface is reached, for links in alerts and digests
+		APIToken         string        `mapstructure:"api_token"`
+		AllowedOrigins   []string      `mapstructure:"allowed_origins"`
+		ShutdownTimeout  time.Duration `mapstructure:"shutdown_timeout"` // How long requests in progress may take to finish on shutdown
+		WatchConfig      bool          `mapstructure:"watch_config"`     // Reload settings when the config file changes, as on SIGHUP
+		TLSCert          string        `mapstructure:"tls_cert"`         // Certificate (PEM, with the chain) to serve HTTPS with
+		TLSKey           string        `mapstructure:"tls_key"`
+		TLSMinVersion    string        `mapstructure:"tls_min_version"`    // 1.0, 1.1, 1.2 or 1.3
+		TLSCipherSuites  []string      `mapstructure:"tls_cipher_suites"`  // Go names of the suites allowed below TLS 1.3; empty for Go's defaults
+		RedirectHTTP     string        `mapstructure:"redirect_http"`      // Address redirecting plain HTTP to HTTPS, such as ":80"
+		ACMEDomains      []string      `mapstructure:"acme_domains"`       // Domains to obtain certificates for over ACME instead of tls_cert
+		ACMECacheDir     string        `mapstructure:"acme_cache_dir"`     // Where ACME account keys and certificates are kept
+		ACMEEmail        string        `mapstructure:"acme_email"`         // Contact for expiry notices from the certificate authority
+		ACMEDirectoryURL string        `mapstructure:"acme_directory_url"` // Let's Encrypt when empty
 	} `mapstructure:"server"`
+	HTTP struct {
+		HTTP2 bool         `mapstructure:"http2"` // Offer HTTP/2 to HTTPS clients
+		H2C   bool         `mapstructure:"h2c"`   // Accept HTTP/2 without TLS, for proxies on a private network
+		API   HTTPSettings `mapstructure:"api"`
+		Web   HTTPSettings `mapstructure:"web"`
+	} `mapstructure:"http"`
 	Database struct {
 		Host     string
 		Port     int
@@ -21,13 +51,192 @@ type Config struct {
 		User     string
 		Password string
 		SSLMode  string `mapstructure:"sslmode"`
+		// How long the lists of tags and sources are cached; 0 turns the cache off
+		NameCacheTTL time.Duration `mapstructure:"name_cache_ttl"`
+		// How many events looked up by ID are cached, and for how long; 0
+		// for either turns the cache off
+		EventCacheSize int           `mapstructure:"event_cache_size"`
+		EventCacheTTL  time.Duration `mapstructure:"event_cache_ttl"`
+		// Set when connecting through PgBouncer or another pooler in
+		// transaction pooling mode
+		TransactionPooling bool `mapstructure:"transaction_pooling"`
+		// How long to keep retrying when the database isn't up at startup;
+		// 0 tries once
+		ConnectTimeout time.Duration `mapstructure:"connect_timeout"`
 	} `mapstructure:"database"`
+	Cache struct {
+		Backend      string        // memory or redis
+		RedisURL     string        `mapstructure:"redis_url"`
+		Prefix       string        // Starts every Redis key
+		DashboardTTL time.Duration `mapstructure:"dashboard_ttl"` // How long the dashboard's aggregates are cached; 0 turns it off
+	} `mapstructure:"cache"`
 	Security struct {
-		JWTSecret      string `mapstructure:"jwt_secret"`
-		AdminPassword  string `mapstructure:"admin_password"`
-		TokenExpiry    int    `mapstructure:"token_expiry"`
-		RandomEmailLen int    `mapstructure:"random_email_length"`
+		JWTSecret         string   `mapstructure:"jwt_secret"`
+		AdminPassword     string   `mapstructure:"admin_password"`
+		TokenExpiry       int      `mapstructure:"token_expiry"`
+		RandomEmailLen    int      `mapstructure:"random_email_length"`
+		AllowRegistration bool     `mapstructure:"allow_registration"`
+		InviteExpiry      int      `mapstructure:"invite_expiry"`
+		AdminAllowlist    []string `mapstructure:"admin_allowlist"`
+		LinkSecret        string   `mapstructure:"link_secret"`
 	} `mapstructure:"security"`
+	SMTP struct {
+		Host     string
+		Port     int
+		Username string
+		Password string
+		From     string
+	} `mapstructure:"smtp"`
+	InboundSMTP struct {
+		Listen         string
+		Hostname       string
+		Source         string
+		MaxMessageSize int64    `mapstructure:"max_message_size"`
+		AllowedDomains []string `mapstructure:"allowed_domains"`
+		CertFile       string   `mapstructure:"cert_file"`
+		KeyFile        string   `mapstructure:"key_file"`
+		RequireAuth    bool     `mapstructure:"require_auth"`
+	} `mapstructure:"inbound_smtp"`
+	Pipeline struct {
+		Default []string
+		Sources map[string][]string
+		// Queue makes POST /api/events answer before the event is stored
+		Queue struct {
+			Enabled   bool
+			Size      int
+			Workers   int
+			BatchSize int           `mapstructure:"batch_size"`
+			BatchWait time.Duration `mapstructure:"batch_wait"`
+		}
+		// Debug captures the raw POST /api/events bodies of some sources
+		Debug struct {
+			Sources []string // "*" for every request
+			Keep    int      // Payloads kept per source; 0 keeps them all
+		}
+	} `mapstructure:"pipeline"`
+	Filter struct {
+		AllowSenders  []string `mapstructure:"allow_senders"`
+		DenySenders   []string `mapstructure:"deny_senders"`
+		AllowSources  []string `mapstructure:"allow_sources"`
+		DenySources   []string `mapstructure:"deny_sources"`
+		UnknownAction string   `mapstructure:"unknown_action"`
+		Spam          struct {
+			RspamdURL       string  `mapstructure:"rspamd_url"`
+			RspamdPassword  string  `mapstructure:"rspamd_password"`
+			SpamdAddr       string  `mapstructure:"spamd_addr"`
+			QuarantineScore float64 `mapstructure:"quarantine_score"`
+			RejectScore     float64 `mapstructure:"reject_score"`
+		} `mapstructure:"spam"`
+	} `mapstructure:"filter"`
+	// Geocoding finds the coordinates of event locations, from Places and
+	// then from the Nominatim server at URL
+	Geocoding struct {
+		URL       string            // Such as https://nominatim.openstreetmap.org; off when empty
+		UserAgent string            `mapstructure:"user_agent"`
+		Places    map[string]string // "latitude,longitude" by location name
+	} `mapstructure:"geocoding"`
+	// Correlation groups bursts of similar events into incidents
+	Correlation struct {
+		Window time.Duration // Longest gap between the events of an incident; 0 turns grouping off
+	} `mapstructure:"correlation"`
+	// Anomalies flags hours with far more or far fewer events than usual
+	// from a source, with a tag or overall
+	Anomalies struct {
+		Baseline    time.Duration // Hours each hour is compared with
+		Sensitivity float64       // Standard deviations from the mean that make an hour unusual
+		MinEvents   int           `mapstructure:"min_events"`
+		Events      bool          // Store an event tagged "anomaly" for each anomaly
+	} `mapstructure:"anomalies"`
+	// Archive uploads new events as compressed NDJSON to S3-compatible
+	// storage on an interval
+	Archive struct {
+		Endpoint        string // Such as http://minio:9000; the AWS endpoint of the region when empty
+		Region          string
+		Bucket          string
+		Prefix          string
+		AccessKeyID     string `mapstructure:"access_key_id"`     // AWS_ACCESS_KEY_ID when empty
+		SecretAccessKey string `mapstructure:"secret_access_key"` // AWS_SECRET_ACCESS_KEY when empty
+		SessionToken    string `mapstructure:"session_token"`
+		PathStyle       bool   `mapstructure:"path_style"` // Needed by MinIO and most other S3-compatible servers
+		Interval        time.Duration
+		BatchSize       int  `mapstructure:"batch_size"`
+		AttachmentData  bool `mapstructure:"attachment_data"`
+	} `mapstructure:"archive"`
+	Inbound struct {
+		MailgunSigningKey  string   `mapstructure:"mailgun_signing_key"`
+		SendGridPublicKey  string   `mapstructure:"sendgrid_public_key"`
+		SNSTopicARNs       []string `mapstructure:"sns_topic_arns"`
+		SlackSigningSecret string   `mapstructure:"slack_signing_secret"`
+		SlackKeywords      []string `mapstructure:"slack_keywords"`
+		GitHubSecret       string   `mapstructure:"github_secret"`
+		DeploySecret       string   `mapstructure:"deploy_secret"` // HMAC secret of simple payloads to /ingest/deploy
+		GitLabToken        string   `mapstructure:"gitlab_token"`  // Secret token of GitLab deployment hooks
+		JenkinsToken       string   `mapstructure:"jenkins_token"` // Token Jenkins notifications carry
+	} `mapstructure:"inbound"`
+	Kafka struct {
+		Brokers        []string
+		Topics         []string
+		GroupID        string `mapstructure:"group_id"`
+		BatchSize      int    `mapstructure:"batch_size"`
+		BatchTimeoutMS int    `mapstructure:"batch_timeout_ms"`
+	} `mapstructure:"kafka"`
+	NATS struct {
+		URL       string
+		Subjects  []string
+		Stream    string
+		Durable   string
+		Queue     string
+		CredsFile string `mapstructure:"creds_file"`
+		BatchSize int    `mapstructure:"batch_size"`
+	} `mapstructure:"nats"`
+	Pollers []struct {
+		Name     string
+		URL      string
+		Interval time.Duration
+		Format   string
+		Selector string
+		Headers  map[string]string
+		Tags     []string
+		Source   string
+		Backfill bool
+	} `mapstructure:"pollers"`
+	// Monitors send heartbeat events through the ingestion pipeline of
+	// their source on an interval
+	Monitors []struct {
+		Name     string
+		Source   string
+		Interval time.Duration
+		Tags     []string
+	} `mapstructure:"monitors"`
+	SAML struct {
+		Enabled           bool
+		RootURL           string   `mapstructure:"root_url"`
+		EntityID          string   `mapstructure:"entity_id"`
+		CertFile          string   `mapstructure:"cert_file"`
+		KeyFile           string   `mapstructure:"key_file"`
+		IDPMetadataURL    string   `mapstructure:"idp_metadata_url"`
+		IDPMetadataFile   string   `mapstructure:"idp_metadata_file"`
+		UsernameAttribute string   `mapstructure:"username_attribute"`
+		RoleAttribute     string   `mapstructure:"role_attribute"`
+		AdminValues       []string `mapstructure:"admin_values"`
+	} `mapstructure:"saml"`
+	Display struct {
+		MarkdownSources []string `mapstructure:"markdown_sources"`
+		// Parse the templates again for every page, for working on them
+		ReloadTemplates bool `mapstructure:"reload_templates"`
+		// Map tiles of the event map, with {z}, {x} and {y} placeholders
+		MapTiles       string `mapstructure:"map_tiles"`
+		MapAttribution string `mapstructure:"map_attribution"`
+	} `mapstructure:"display"`
+	Logging struct {
+		Level  string // debug, info, warn or error
+		Format string // text or json
+	} `mapstructure:"logging"`
+	ErrorReporting struct {
+		SentryDSN   string  `mapstructure:"sentry_dsn"`  // Reporting is off when empty
+		SampleRate  float64 `mapstructure:"sample_rate"` // Share of errors sent, 0 to 1
+		Environment string
+	} `mapstructure:"error_reporting"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -47,9 +256,87 @@ func LoadConfig() (*Config, error) {
 
 	// Set defaults
 	viper.SetDefault("server.port", 8081)
+	viper.SetDefault("server.base_url", "")
+	viper.SetDefault("server.shutdown_timeout", "30s")
+	viper.SetDefault("server.watch_config", false)
+	viper.SetDefault("server.tls_cert", "")
+	viper.SetDefault("server.tls_key", "")
+	viper.SetDefault("server.tls_min_version", "1.2")
+	viper.SetDefault("server.redirect_http", "")
+	viper.SetDefault("server.acme_domains", []string{})
+	viper.SetDefault("server.acme_cache_dir", "acme-cache")
+	viper.SetDefault("server.acme_email", "")
+	viper.SetDefault("server.acme_directory_url", "")
+	viper.SetDefault("http.http2", true)
+	viper.SetDefault("http.h2c", false)
+	// The API has no read or write timeout by default, since webhooks may
+	// upload large emails and exports are streamed for as long as they take
+	viper.SetDefault("http.api.read_timeout", "0s")
+	viper.SetDefault("http.api.read_header_timeout", "10s")
+	viper.SetDefault("http.api.write_timeout", "0s")
+	viper.SetDefault("http.api.idle_timeout", "60s")
+	viper.SetDefault("http.api.max_header_bytes", 1<<20)
+	viper.SetDefault("http.web.read_timeout", "15s")
+	viper.SetDefault("http.web.read_header_timeout", "10s")
+	viper.SetDefault("http.web.write_timeout", "15s")
+	viper.SetDefault("http.web.idle_timeout", "60s")
+	viper.SetDefault("http.web.max_header_bytes", 1<<20)
 	viper.SetDefault("database.port", 5432)
+	viper.SetDefault("cache.backend", "memory")
+	viper.SetDefault("cache.redis_url", "")
+	viper.SetDefault("cache.prefix", "eventdb:")
+	viper.SetDefault("cache.dashboard_ttl", "15s")
+	viper.SetDefault("database.name_cache_ttl", "1m")
+	viper.SetDefault("database.event_cache_size", 1000)
+	viper.SetDefault("database.event_cache_ttl", "30s")
+	viper.SetDefault("database.transaction_pooling", false)
+	viper.SetDefault("database.connect_timeout", "1m")
 	viper.SetDefault("security.token_expiry", 24)
 	viper.SetDefault("security.random_email_length", 12)
+	viper.SetDefault("security.allow_registration", false)
+	viper.SetDefault("security.invite_expiry", 168)
+	viper.SetDefault("smtp.port", 587)
+	viper.SetDefault("inbound_smtp.listen", ":25")
+	viper.SetDefault("inbound_smtp.source", "email")
+	viper.SetDefault("inbound_smtp.max_message_size", 26214400)
+	viper.SetDefault("pipeline.queue.enabled", false)
+	viper.SetDefault("pipeline.queue.size", 10000)
+	viper.SetDefault("pipeline.queue.workers", 4)
+	viper.SetDefault("pipeline.queue.batch_size", 100)
+	viper.SetDefault("pipeline.queue.batch_wait", "100ms")
+	viper.SetDefault("pipeline.debug.sources", []string{})
+	viper.SetDefault("pipeline.debug.keep", 100)
+	viper.SetDefault("filter.unknown_action", "quarantine")
+	viper.SetDefault("filter.spam.quarantine_score", 5)
+	viper.SetDefault("filter.spam.reject_score", 15)
+	viper.SetDefault("kafka.group_id", "event-db")
+	viper.SetDefault("kafka.batch_size", 100)
+	viper.SetDefault("kafka.batch_timeout_ms", 1000)
+	viper.SetDefault("nats.url", "nats://127.0.0.1:4222")
+	viper.SetDefault("nats.durable", "event-db")
+	viper.SetDefault("nats.queue", "event-db")
+	viper.SetDefault("nats.batch_size", 100)
+	viper.SetDefault("display.reload_templates", false)
+	viper.SetDefault("display.map_tiles", "https://tile.openstreetmap.org/{z}/{x}/{y}.png")
+	viper.SetDefault("display.map_attribution", "© OpenStreetMap contributors")
+	viper.SetDefault("geocoding.url", "")
+	viper.SetDefault("geocoding.user_agent", "eventdb")
+	viper.SetDefault("correlation.window", "10m")
+	viper.SetDefault("anomalies.baseline", "168h")
+	viper.SetDefault("anomalies.sensitivity", 3)
+	viper.SetDefault("anomalies.min_events", 10)
+	viper.SetDefault("anomalies.events", true)
+	viper.SetDefault("archive.region", "us-east-1")
+	viper.SetDefault("archive.prefix", "eventdb/")
+	viper.SetDefault("archive.interval", "1h")
+	viper.SetDefault("archive.batch_size", 10000)
+	viper.SetDefault("archive.path_style", false)
+	viper.SetDefault("archive.attachment_data", false)
+	viper.SetDefault("logging.level", "info")
+	viper.SetDefault("logging.format", "text")
+	viper.SetDefault("error_reporting.sentry_dsn", "")
+	viper.SetDefault("error_reporting.sample_rate", 1.0)
+	viper.SetDefault("error_reporting.environment", "")
 
 	if err := viper.ReadInConfig(); err != nil {
 		// Only error if config file is missing and not overridden by env
@@ -75,9 +362,36 @@ func LoadConfig() (*Config, error) {
 	if v := viper.GetString("SECURITY_JWT_SECRET"); v != "" {
 		cfg.Security.JWTSecret = v
 	}
+	if v := viper.GetString("SECURITY_LINK_SECRET"); v != "" {
+		cfg.Security.LinkSecret = v
+	}
 	if v := viper.GetString("SERVER_API_TOKEN"); v != "" {
 		cfg.Server.APIToken = v
 	}
+	if v := viper.GetString("SMTP_PASSWORD"); v != "" {
+		cfg.SMTP.Password = v
+	}
+	if v := viper.GetString("INBOUND_MAILGUN_SIGNING_KEY"); v != "" {
+		cfg.Inbound.MailgunSigningKey = v
+	}
+	if v := viper.GetString("INBOUND_SENDGRID_PUBLIC_KEY"); v != "" {
+		cfg.Inbound.SendGridPublicKey = v
+	}
+	if v := viper.GetString("INBOUND_SLACK_SIGNING_SECRET"); v != "" {
+		cfg.Inbound.SlackSigningSecret = v
+	}
+	if v := viper.GetString("INBOUND_GITHUB_SECRET"); v != "" {
+		cfg.Inbound.GitHubSecret = v
+	}
+	if v := viper.GetString("INBOUND_DEPLOY_SECRET"); v != "" {
+		cfg.Inbound.DeploySecret = v
+	}
+	if v := viper.GetString("INBOUND_GITLAB_TOKEN"); v != "" {
+		cfg.Inbound.GitLabToken = v
+	}
+	if v := viper.GetString("INBOUND_JENKINS_TOKEN"); v != "" {
+		cfg.Inbound.JenkinsToken = v
+	}
 	
 	// If API token is not set after loading config and checking env vars, log a warning
 	if cfg.Server.APIToken == "" {
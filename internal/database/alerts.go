@@ -0,0 +1,170 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// alertRuleCacheTTL bounds how long rules changed by other processes can go
+// unnoticed by the ones receiving events
+const alertRuleCacheTTL = 30 * time.Second
+
+const alertRuleColumns = `id, name, tags, source, keyword, channel, target, enabled, created_by, created_at, last_fired_at`
+
+// alertRuleCache keeps the enabled alert rules, which are checked for every
+// stored event. Changes through this Database invalidate it.
+type alertRuleCache struct {
+	mu         sync.Mutex
+	rules      []models.AlertRule
+	loaded     time.Time
+	generation uint64 // Bumped by invalidate
+}
+
+// invalidate drops the cached rules after they change
+func (c *alertRuleCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules, c.loaded = nil, time.Time{}
+	c.generation++
+}
+
+// CreateAlertRule stores a new alert rule
+func (d *Database) CreateAlertRule(rule *models.AlertRule) error {
+	defer d.alerts.invalidate()
+	rule.Tags = normalizeTags(rule.Tags)
+	tagsJSON, err := json.Marshal(nonNilTags(rule.Tags))
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	err = d.db.QueryRow(
+		`INSERT INTO alert_rules (name, tags, source, keyword, channel, target, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		rule.Name,
+		string(tagsJSON),
+		rule.Source,
+		rule.Keyword,
+		rule.Channel,
+		rule.Target,
+		rule.Enabled,
+		rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert alert rule: %w", err)
+	}
+	return nil
+}
+
+// GetAlertRule retrieves an alert rule by ID, or nil if there is none
+func (d *Database) GetAlertRule(id int64) (*models.AlertRule, error) {
+	rule, err := scanAlertRule(d.db.QueryRow("SELECT "+alertRuleColumns+" FROM alert_rules WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rule: %w", err)
+	}
+	return rule, nil
+}
+
+// ListAlertRules retrieves every alert rule by name
+func (d *Database) ListAlertRules() ([]models.AlertRule, error) {
+	return d.queryAlertRules("SELECT " + alertRuleColumns + " FROM alert_rules ORDER BY lower(name), id")
+}
+
+// EnabledAlertRules returns the rules stored events are checked against.
+// They are cached for up to 30 seconds; the caller must not modify them.
+func (d *Database) EnabledAlertRules() ([]models.AlertRule, error) {
+	c := &d.alerts
+	c.mu.Lock()
+	if !c.loaded.IsZero() && time.Since(c.loaded) < alertRuleCacheTTL {
+		defer c.mu.Unlock()
+		return c.rules, nil
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	rules, err := d.queryAlertRules("SELECT " + alertRuleColumns + " FROM alert_rules WHERE enabled ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	// A change during the load may have made the result stale already
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.rules, c.loaded = rules, time.Now()
+	}
+	return rules, nil
+}
+
+// SetAlertRuleEnabled turns an alert rule on or off
+func (d *Database) SetAlertRuleEnabled(id int64, enabled bool) error {
+	defer d.alerts.invalidate()
+	if _, err := d.db.Exec("UPDATE alert_rules SET enabled = $1 WHERE id = $2", enabled, id); err != nil {
+		return fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteAlertRule removes an alert rule
+func (d *Database) DeleteAlertRule(id int64) error {
+	defer d.alerts.invalidate()
+	if _, err := d.db.Exec("DELETE FROM alert_rules WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	return nil
+}
+
+// TouchAlertRule records that an alert rule sent a notification
+func (d *Database) TouchAlertRule(id int64) error {
+	if _, err := d.db.Exec("UPDATE alert_rules SET last_fired_at = $1 WHERE id = $2", time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) queryAlertRules(query string) ([]models.AlertRule, error) {
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.AlertRule
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule row: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return rules, nil
+}
+
+func scanAlertRule(row rowScanner) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	var tagsJSON string
+	var lastFiredAt sql.NullTime
+
+	if err := row.Scan(&rule.ID, &rule.Name, &tagsJSON, &rule.Source, &rule.Keyword, &rule.Channel,
+		&rule.Target, &rule.Enabled, &rule.CreatedBy, &rule.CreatedAt, &lastFiredAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tagsJSON), &rule.Tags); err != nil {
+		return nil, fmt.Errorf("failed to parse tags: %w", err)
+	}
+	rule.LastFiredAt = lastFiredAt.Time
+
+	return &rule, nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+	"time"
+)
+
+const anomalyColumns = `id, kind, name, hour, direction, count, expected, stddev, event_id, created_at`
+
+// HourlyCounts returns the number of events per hour from each source, with
+// each tag and of all events together, for every hour from the start of the
+// hour of from up to to. Hours without events count zero; series without
+// any event in those hours are left out.
+func (d *Database) HourlyCounts(from, to time.Time) ([]models.HourlySeries, error) {
+	from = from.UTC().Truncate(time.Hour)
+	hours := int(to.UTC().Sub(from) / time.Hour)
+	if hours <= 0 {
+		return nil, nil
+	}
+
+	rows, err := d.db.Query(
+		"SELECT kind, name, hour, count FROM hourly_stats WHERE hour >= $1 AND hour < $2 ORDER BY kind, name",
+		from, from.Add(time.Duration(hours)*time.Hour),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query hourly counts: %w", err)
+	}
+	defer rows.Close()
+
+	var series []models.HourlySeries
+	for rows.Next() {
+		var kind, name string
+		var hour time.Time
+		var count int
+		if err := rows.Scan(&kind, &name, &hour, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan hourly count: %w", err)
+		}
+		if n := len(series); n == 0 || series[n-1].Kind != kind || series[n-1].Name != name {
+			series = append(series, models.HourlySeries{Kind: kind, Name: name, Counts: make([]int, hours)})
+		}
+		series[len(series)-1].Counts[int(hour.Sub(from)/time.Hour)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return series, nil
+}
+
+// PruneHourlyCounts deletes the hourly counts of the hours before before,
+// returning how many rows there were
+func (d *Database) PruneHourlyCounts(before time.Time) (int64, error) {
+	result, err := d.db.Exec("DELETE FROM hourly_stats WHERE hour < $1", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune hourly counts: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n, nil
+}
+
+// RecordAnomaly stores an anomaly, filling in its ID. It reports false,
+// storing nothing, when the anomaly of the same series and hour was
+// already recorded.
+func (d *Database) RecordAnomaly(anomaly *models.Anomaly) (bool, error) {
+	err := d.db.QueryRow(
+		`INSERT INTO anomalies (kind, name, hour, direction, count, expected, stddev)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (kind, name, hour) DO NOTHING
+		RETURNING id, created_at`,
+		anomaly.Kind, anomaly.Name, anomaly.Hour.UTC(), anomaly.Direction, anomaly.Count, anomaly.Expected, anomaly.StdDev,
+	).Scan(&anomaly.ID, &anomaly.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to insert anomaly: %w", err)
+	}
+	return true, nil
+}
+
+// SetAnomalyEvent records the event stored to report an anomaly
+func (d *Database) SetAnomalyEvent(id, eventID int64) error {
+	if _, err := d.db.Exec("UPDATE anomalies SET event_id = $1 WHERE id = $2", eventID, id); err != nil {
+		return fmt.Errorf("failed to update anomaly: %w", err)
+	}
+	return nil
+}
+
+// RecentAnomalies retrieves the anomalies of the hours since since, most
+// recent hour first, at most limit of them
+func (d *Database) RecentAnomalies(since time.Time, limit int) ([]models.Anomaly, error) {
+	rows, err := d.db.Query(
+		"SELECT "+anomalyColumns+" FROM anomalies WHERE hour >= $1 ORDER BY hour DESC, kind, name LIMIT $2",
+		since.UTC().Truncate(time.Hour), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	anomalies := []models.Anomaly{}
+	for rows.Next() {
+		var anomaly models.Anomaly
+		var eventID sql.NullInt64
+		if err := rows.Scan(&anomaly.ID, &anomaly.Kind, &anomaly.Name, &anomaly.Hour, &anomaly.Direction, &anomaly.Count,
+			&anomaly.Expected, &anomaly.StdDev, &eventID, &anomaly.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan anomaly row: %w", err)
+		}
+		anomaly.EventID = eventID.Int64
+		anomalies = append(anomalies, anomaly)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return anomalies, nil
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"fmt"
+)
+
+// LastArchivedEvent returns the highest event ID archived to bucket, or 0
+// if nothing was
+func (d *Database) LastArchivedEvent(bucket string) (int64, error) {
+	var id int64
+	if err := d.db.QueryRow("SELECT COALESCE(MAX(last_event_id), 0) FROM archives WHERE bucket = $1", bucket).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to query archives: %w", err)
+	}
+	return id, nil
+}
+
+// RecordArchive stores an uploaded archive, filling in its ID
+func (d *Database) RecordArchive(archive *models.Archive) error {
+	err := d.db.QueryRow(
+		`INSERT INTO archives (bucket, object_key, first_event_id, last_event_id, events, bytes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		archive.Bucket, archive.Key, archive.FirstEventID, archive.LastEventID, archive.Events, archive.Bytes,
+	).Scan(&archive.ID, &archive.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert archive: %w", err)
+	}
+	return nil
+}
+
+// ListArchives retrieves the archives uploaded to bucket, newest first, at
+// most limit of them
+func (d *Database) ListArchives(bucket string, limit int) ([]models.Archive, error) {
+	rows, err := d.db.Query(
+		`SELECT id, bucket, object_key, first_event_id, last_event_id, events, bytes, created_at
+		FROM archives WHERE bucket = $1 ORDER BY last_event_id DESC LIMIT $2`,
+		bucket, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query archives: %w", err)
+	}
+	defer rows.Close()
+
+	archives := []models.Archive{}
+	for rows.Next() {
+		var archive models.Archive
+		if err := rows.Scan(&archive.ID, &archive.Bucket, &archive.Key, &archive.FirstEventID, &archive.LastEventID,
+			&archive.Events, &archive.Bytes, &archive.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan archive row: %w", err)
+		}
+		archives = append(archives, archive)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return archives, nil
+}
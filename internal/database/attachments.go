@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// insertAttachments stores an event's attachments within the event's transaction
+func insertAttachments(tx *sql.Tx, eventID int64, attachments []models.Attachment) error {
+	for i := range attachments {
+		a := &attachments[i]
+		a.EventID = eventID
+		a.Size = len(a.Data)
+		err := tx.QueryRow(
+			"INSERT INTO attachments (event_id, filename, content_type, size, data) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+			eventID, a.Filename, a.ContentType, a.Size, a.Data,
+		).Scan(&a.ID, &a.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert attachment %q: %w", a.Filename, err)
+		}
+	}
+	return nil
+}
+
+// GetAttachments lists an event's attachments without their contents
+func (d *Database) GetAttachments(eventID int64) ([]models.Attachment, error) {
+	rows, err := d.db.Query(
+		"SELECT id, event_id, filename, content_type, size, created_at FROM attachments WHERE event_id = $1 ORDER BY id",
+		eventID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		var a models.Attachment
+		if err := rows.Scan(&a.ID, &a.EventID, &a.Filename, &a.ContentType, &a.Size, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return attachments, nil
+}
+
+// GetAttachment retrieves an attachment with its contents, or nil if the
+// event has no attachment with that ID
+func (d *Database) GetAttachment(eventID, id int64) (*models.Attachment, error) {
+	var a models.Attachment
+	err := d.db.QueryRow(
+		"SELECT id, event_id, filename, content_type, size, data, created_at FROM attachments WHERE event_id = $1 AND id = $2",
+		eventID, id,
+	).Scan(&a.ID, &a.EventID, &a.Filename, &a.ContentType, &a.Size, &a.Data, &a.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+
+	return &a, nil
+}
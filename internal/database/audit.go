@@ -0,0 +1,67 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// LogAudit stores an audit entry in the database
+func (d *Database) LogAudit(entry *models.AuditEntry) error {
+	err := d.db.QueryRow(
+		`INSERT INTO audit_log (action, actor, target, ip_address, details)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		entry.Action,
+		entry.Actor,
+		entry.Target,
+		entry.IPAddress,
+		entry.Details,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// GetAuditLog retrieves the most recent audit entries, optionally filtered by action
+func (d *Database) GetAuditLog(action string, limit int) ([]models.AuditEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := d.db.Query(
+		`SELECT id, action, actor, target, ip_address, details, created_at
+		FROM audit_log
+		WHERE $1 = '' OR action = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`,
+		action,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		var target, ipAddress, details sql.NullString
+
+		if err := rows.Scan(&entry.ID, &entry.Action, &entry.Actor, &target, &ipAddress, &details, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit row: %w", err)
+		}
+		entry.Target = target.String
+		entry.IPAddress = ipAddress.String
+		entry.Details = details.String
+
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,298 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DeleteEvents removes several events and their logs in a single transaction,
+// returning the number of events deleted
+func (d *Database) DeleteEvents(ids []int64) (int64, error) {
+	defer d.names.invalidate()
+	defer d.events.invalidate(ids...)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM event_logs WHERE event_id = ANY($1)", pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("failed to delete event logs: %w", err)
+	}
+
+	result, err := tx.Exec("DELETE FROM events WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete events: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error checking rows affected: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Bulk deleted %d of %d events", deleted, len(ids))
+	return deleted, nil
+}
+
+// AddTagsToEvents adds tags to several events, skipping tags an event already has.
+// It returns the number of events that changed.
+func (d *Database) AddTagsToEvents(ids []int64, tags []string) (int64, error) {
+	tags = normalizeTags(tags)
+	return d.updateEventTags(ids, func(existing []string) []string {
+		seen := make(map[string]bool, len(existing))
+		for _, tag := range existing {
+			seen[tag] = true
+		}
+		for _, tag := range tags {
+			if !seen[tag] {
+				existing = append(existing, tag)
+				seen[tag] = true
+			}
+		}
+		return existing
+	})
+}
+
+// RemoveTagsFromEvents removes tags from several events, returning the number
+// of events that changed
+func (d *Database) RemoveTagsFromEvents(ids []int64, tags []string) (int64, error) {
+	remove := make(map[string]bool, len(tags))
+	for _, tag := range normalizeTags(tags) {
+		remove[tag] = true
+	}
+	return d.updateEventTags(ids, func(existing []string) []string {
+		kept := []string{}
+		for _, tag := range existing {
+			if !remove[tag] {
+				kept = append(kept, tag)
+			}
+		}
+		return kept
+	})
+}
+
+// updateEventTags rewrites the tags of each event with update inside one
+// transaction, logging an "updated" status for every event that changed
+func (d *Database) updateEventTags(ids []int64, update func([]string) []string) (int64, error) {
+	defer d.names.invalidate()
+	defer d.events.invalidate(ids...)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, tags FROM events WHERE id = ANY($1) FOR UPDATE", pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query events: %w", err)
+	}
+
+	current := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var tagsJSON string
+		if err := rows.Scan(&id, &tagsJSON); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan event row: %w", err)
+		}
+		var tags []string
+		if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to parse tags for event %d: %w", id, err)
+		}
+		current[id] = tags
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	var changed int64
+	for id, tags := range current {
+		updated := normalizeTags(update(append([]string(nil), tags...)))
+		if equalTags(tags, updated) {
+			continue
+		}
+		if err := setEventTags(tx, id, updated); err != nil {
+			return 0, err
+		}
+		changed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Bulk updated tags on %d of %d events", changed, len(ids))
+	return changed, nil
+}
+
+// setEventTags stores the tags of one event and logs the update
+func setEventTags(tx *sql.Tx, id int64, tags []string) error {
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE events SET tags = $1 WHERE id = $2", string(tagsJSON), id); err != nil {
+		return fmt.Errorf("failed to update tags for event %d: %w", id, err)
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO event_logs (event_id, status, error_message) VALUES ($1, $2, $3)",
+		id, "updated", "",
+	); err != nil {
+		return fmt.Errorf("failed to log event status: %w", err)
+	}
+	return nil
+}
+
+// equalTags reports whether two tag lists are identical
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// StoreEvents inserts several events in a single transaction, so either all
+// of them are stored or none are. Emails whose Message-ID is already stored
+// are skipped. It returns the number of events inserted.
+func (d *Database) StoreEvents(events []*models.EventRequest) (int, error) {
+	stored, err := d.InsertEvents(events)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, event := range stored {
+		if event != nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// InsertEvents stores events like StoreEvents, returning the stored event
+// for each request, or nil where it was skipped as a duplicate
+func (d *Database) InsertEvents(events []*models.EventRequest) ([]*models.Event, error) {
+	defer d.names.invalidate()
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const insert = "INSERT INTO events (tags, data, source, html_body, message_id, email, created_at, metadata, project_id, location, latitude, longitude) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) ON CONFLICT (project_id, message_id) DO NOTHING RETURNING id"
+	insertRow := func(args ...interface{}) *sql.Row {
+		return tx.QueryRow(insert, args...)
+	}
+	if !d.pooled {
+		// Preparing the insert once saves parsing it for every event. The
+		// statement is closed after the transaction commits, which a
+		// transaction pooler may have moved on from, so it isn't used there.
+		stmt, err := tx.Prepare(insert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare insert: %w", err)
+		}
+		defer stmt.Close()
+		insertRow = stmt.QueryRow
+	}
+
+	now := time.Now()
+	var ids []int64
+	stored := make([]*models.Event, len(events))
+	for i, event := range events {
+		event.Tags = normalizeTags(event.Tags)
+		tagsJSON, err := json.Marshal(event.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tags: %w", err)
+		}
+
+		var messageID sql.NullString
+		var emailJSON []byte
+		if event.Email != nil {
+			if emailJSON, err = json.Marshal(event.Email); err != nil {
+				return nil, fmt.Errorf("failed to marshal email metadata: %w", err)
+			}
+			messageID = sql.NullString{String: event.Email.MessageID, Valid: event.Email.MessageID != ""}
+		}
+
+		metadataJSON, err := metadataParam(event.Metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		data := strings.TrimRight(event.Data, "\r\n")
+		var id int64
+		err = insertRow(string(tagsJSON), data, event.Source, event.HTMLBody, messageID, jsonParam(emailJSON), now, metadataJSON, projectID(event.ProjectID), event.Location, event.Latitude, event.Longitude).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert event: %w", err)
+		}
+		if err := insertAttachments(tx, id, event.Attachments); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		stored[i] = &models.Event{
+			ID:              id,
+			Tags:            event.Tags,
+			Data:            data,
+			Source:          event.Source,
+			HTMLBody:        event.HTMLBody,
+			Email:           event.Email,
+			AttachmentCount: len(event.Attachments),
+			Metadata:        event.Metadata,
+			ProjectID:       projectID(event.ProjectID),
+			Location:        event.Location,
+			Latitude:        event.Latitude,
+			Longitude:       event.Longitude,
+			CreatedAt:       now,
+		}
+	}
+
+	if len(ids) > 0 {
+		if _, err := tx.Exec("INSERT INTO event_logs (event_id, status, error_message) SELECT unnest($1::bigint[]), 'success', ''", pq.Array(ids)); err != nil {
+			return nil, fmt.Errorf("failed to log event status: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Bulk stored %d of %d events", len(ids), len(events))
+	for _, event := range stored {
+		if event != nil {
+			d.hooks.stored(event)
+		}
+	}
+	return stored, nil
+}
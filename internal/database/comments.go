@@ -0,0 +1,71 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// AddComment attaches a comment to an event, setting its ID and creation time
+func (d *Database) AddComment(comment *models.EventComment) error {
+	err := d.db.QueryRow(
+		"INSERT INTO event_comments (event_id, author, body) VALUES ($1, $2, $3) RETURNING id, created_at",
+		comment.EventID, comment.Author, comment.Body,
+	).Scan(&comment.ID, &comment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add comment: %w", err)
+	}
+	return nil
+}
+
+// GetComments retrieves the comments on an event, oldest first
+func (d *Database) GetComments(eventID int64) ([]models.EventComment, error) {
+	rows, err := d.db.Query(
+		"SELECT id, event_id, author, body, created_at FROM event_comments WHERE event_id = $1 ORDER BY created_at, id",
+		eventID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []models.EventComment
+	for rows.Next() {
+		var comment models.EventComment
+		if err := rows.Scan(&comment.ID, &comment.EventID, &comment.Author, &comment.Body, &comment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return comments, nil
+}
+
+// GetComment retrieves a comment on an event, or nil if the event has no
+// comment with that ID
+func (d *Database) GetComment(eventID, id int64) (*models.EventComment, error) {
+	var comment models.EventComment
+	err := d.db.QueryRow(
+		"SELECT id, event_id, author, body, created_at FROM event_comments WHERE id = $1 AND event_id = $2",
+		id, eventID,
+	).Scan(&comment.ID, &comment.EventID, &comment.Author, &comment.Body, &comment.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	return &comment, nil
+}
+
+// DeleteComment removes a comment
+func (d *Database) DeleteComment(id int64) error {
+	if _, err := d.db.Exec("DELETE FROM event_comments WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	return nil
+}
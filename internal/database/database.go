@@ -3,18 +3,30 @@ package database
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"example-api/internal/logging"
 	"example-api/internal/models"
 	"fmt"
 	"log"
+	"log/slog"
 	"sort"
 	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// ErrDuplicateMessageID is returned by StoreEvent when an email with the same
+// Message-ID has already been stored in the event's project
+var ErrDuplicateMessageID = errors.New("duplicate message ID")
+
 type Database struct {
-	db *sql.DB
+	db     *sql.DB
+	names  nameCache      // Distinct tags and sources
+	events eventCache     // Events looked up by ID
+	alerts alertRuleCache // Enabled alert rules
+	hooks  eventHooks     // Called with stored events
+	pooled bool           // Connected through a transaction pooler
 }
 
 // NewPostgres creates a new Database instance using PostgreSQL connection info.
@@ -25,29 +37,52 @@ func NewPostgres(connStr string) (*Database, error) {
 	}
 	// Optionally: ping to check connection
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
-	return &Database{db: db}, nil
+	return &Database{
+		db:     db,
+		names:  nameCache{ttl: defaultNameCacheTTL},
+		events: eventCache{size: defaultEventCacheSize, ttl: defaultEventCacheTTL},
+	}, nil
+}
+
+// SetTransactionPooling tells the database layer it is connected through a
+// pooler in transaction pooling mode, such as PgBouncer, where consecutive
+// transactions may run on different server connections. Named prepared
+// statements are then not used, since they would be left behind on the
+// server connection.
+func (d *Database) SetTransactionPooling(pooled bool) {
+	d.pooled = pooled
 }
 
 func (d *Database) Close() error {
 	return d.db.Close()
 }
 
+// jsonParam passes encoded JSON as a query argument for a JSONB column. It
+// is sent as text, since lib/pq sends []byte in binary format when
+// connecting with binary_parameters, which JSONB doesn't accept. nil stays NULL.
+func jsonParam(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
+
 // LogEventStatus logs the status of an event operation
-// If eventID is 0, this logs to stdout instead of the database (avoids foreign key constraint violation)
+// If eventID is 0, the event hasn't been created yet, so the entry is stored without an event
 func (d *Database) LogEventStatus(eventID int64, status string, errorMessage string) error {
-	// If eventID is 0, it means the event hasn't been created yet
-	// Just log to stdout to avoid foreign key constraint violation
+	var eventRef sql.NullInt64
 	if eventID == 0 {
 		log.Printf("Event log (pre-insert): status=%s, error=%s", status, errorMessage)
-		return nil
+	} else {
+		eventRef = sql.NullInt64{Int64: eventID, Valid: true}
 	}
 	
-	// For valid event IDs, log to the database
 	_, err := d.db.Exec(
 		"INSERT INTO event_logs (event_id, status, error_message) VALUES ($1, $2, $3)",
-		eventID,
+		eventRef,
 		status,
 		errorMessage,
 	)
@@ -57,7 +92,34 @@ func (d *Database) LogEventStatus(eventID int64, status string, errorMessage str
 	return nil
 }
 
-func (d *Database) StoreEvent(event *models.EventRequest) (*models.Event, error) {
+// insertEventWithLogs inserts an event together with its event_logs entries
+// in a single statement, so storing an event takes one round trip. $1 to $12
+// are the event's columns, $13 and $14 the statuses and messages of the log
+// entries. No row is returned if the Message-ID is already stored in the
+// project.
+//
+// This stands in for the prepared statements in a pgx batch that synth-1676
+// asked for. Behind PgBouncer in transaction pooling mode (synth-1668), named
+// prepared statements are left on whichever server connection prepared them,
+// so the next use on another connection fails. One unprepared statement gets
+// the same single round trip in both modes, on the lib/pq driver the rest of
+// this package uses.
+const insertEventWithLogs = `WITH event AS (
+		INSERT INTO events (tags, data, source, html_body, message_id, email, created_at, metadata, project_id, location, latitude, longitude)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (project_id, message_id) DO NOTHING RETURNING id
+	), logged AS (
+		INSERT INTO event_logs (event_id, status, error_message)
+		SELECT event.id, l.status, l.message FROM event, unnest($13::text[], $14::text[]) AS l(status, message)
+	)
+	SELECT id FROM event`
+
+// StoreEvent stores an event with its attachments and logs its success,
+// followed by a warning entry for each of warnings. Without attachments this
+// is a single statement; with them, one transaction.
+func (d *Database) StoreEvent(event *models.EventRequest, warnings ...string) (*models.Event, error) {
+	defer d.names.invalidate()
+	event.Tags = normalizeTags(event.Tags)
 	tagsJSON, err := json.Marshal(event.Tags)
 	if err != nil {
 		// Log pre-insert error (will be logged to stdout since event ID is 0)
@@ -66,50 +128,113 @@ func (d *Database) StoreEvent(event *models.EventRequest) (*models.Event, error)
 	}
 
 	cleanData := strings.TrimRight(event.Data, "\r\n")
-	log.Printf("DEBUG database: Original Data: %q, CleanData: %q (length=%d)", event.Data, cleanData, len(cleanData))
+
+	var messageID sql.NullString
+	var emailJSON []byte
+	if event.Email != nil {
+		if emailJSON, err = json.Marshal(event.Email); err != nil {
+			return nil, fmt.Errorf("failed to marshal email metadata: %w", err)
+		}
+		messageID = sql.NullString{String: event.Email.MessageID, Valid: event.Email.MessageID != ""}
+	}
+	metadataJSON, err := metadataParam(event.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, messages := []string{"success"}, []string{""}
+	for _, warning := range warnings {
+		statuses, messages = append(statuses, "warning"), append(messages, warning)
+	}
 
 	var id int64
-	log.Printf("DEBUG database: Executing SQL with params: tags=%s, data=%q, source=%s", 
-		string(tagsJSON), cleanData, event.Source)
-	err = d.db.QueryRow(
-		"INSERT INTO events (tags, data, source, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+	slog.Debug("Inserting event", "tags", string(tagsJSON), "source", event.Source, "data", logging.Payload(cleanData))
+	// Attachments are stored with the event or not at all
+	var q rowQuerier = d.db
+	var tx *sql.Tx
+	if len(event.Attachments) > 0 {
+		if tx, err = d.db.Begin(); err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		q = tx
+	}
+
+	err = q.QueryRow(
+		insertEventWithLogs,
 		string(tagsJSON),
 		cleanData,
 		event.Source,
+		event.HTMLBody,
+		messageID,
+		jsonParam(emailJSON),
 		time.Now(),
+		metadataJSON,
+		projectID(event.ProjectID),
+		event.Location,
+		event.Latitude,
+		event.Longitude,
+		pq.Array(statuses),
+		pq.Array(messages),
 	).Scan(&id)
-	log.Printf("DEBUG database: Insert result: id=%d, err=%v", id, err)
+	if err == sql.ErrNoRows {
+		// Nothing was inserted because the Message-ID is already stored
+		return nil, ErrDuplicateMessageID
+	}
 	if err != nil {
 		// Log pre-insert error (will be logged to stdout since event ID is 0)
 		_ = d.LogEventStatus(0, "error", fmt.Sprintf("failed to insert event: %v", err))
 		return nil, fmt.Errorf("failed to insert event: %w", err)
 	}
 
-	// Now that we have a valid event ID, log the success in the database
-	if err := d.LogEventStatus(id, "success", ""); err != nil {
-		log.Printf("Warning: Event was stored but failed to log success: %v", err)
+	if tx != nil {
+		if err := insertAttachments(tx, id, event.Attachments); err != nil {
+			_ = d.LogEventStatus(0, "error", err.Error())
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit event: %w", err)
+		}
 	}
 
 	result := &models.Event{
-		ID:        id,
-		Tags:      event.Tags,
-		Data:      cleanData,
-		Source:    event.Source,
-		CreatedAt: time.Now(),
-	}
-	log.Printf("DEBUG database: Returning event result: %+v", result)
+		ID:              id,
+		Tags:            event.Tags,
+		Data:            cleanData,
+		Source:          event.Source,
+		HTMLBody:        event.HTMLBody,
+		Email:           event.Email,
+		AttachmentCount: len(event.Attachments),
+		Metadata:        event.Metadata,
+		ProjectID:       projectID(event.ProjectID),
+		Status:          models.StatusOpen,
+		Location:        event.Location,
+		Latitude:        event.Latitude,
+		Longitude:       event.Longitude,
+		CreatedAt:       time.Now(),
+	}
+	d.hooks.stored(result)
 	return result, nil
 }
 
+// GetEventByID returns the event with id, or nil if there is none. Recently
+// used events are cached (see SetEventCache).
 func (d *Database) GetEventByID(id int64) (*models.Event, error) {
+	return d.events.get(id, func() (*models.Event, error) {
+		return d.getEventByID(id)
+	})
+}
+
+func (d *Database) getEventByID(id int64) (*models.Event, error) {
 	var event models.Event
 	var tagsJSON string
+	var emailJSON, metadataJSON, labelsJSON []byte
 	var createdAt time.Time
 
 	err := d.db.QueryRow(
-		"SELECT id, tags, data, source, created_at FROM events WHERE id = $1",
+		"SELECT id, tags, data, source, html_body, email, metadata, project_id, status, due_at, labels, location, latitude, longitude, (SELECT COUNT(*) FROM attachments WHERE event_id = events.id), created_at FROM events WHERE id = $1",
 		id,
-	).Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &createdAt)
+	).Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &event.HTMLBody, &emailJSON, &metadataJSON, &event.ProjectID, &event.Status, &event.DueAt, &labelsJSON, &event.Location, &event.Latitude, &event.Longitude, &event.AttachmentCount, &createdAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -121,17 +246,43 @@ func (d *Database) GetEventByID(id int64) (*models.Event, error) {
 	if err := json.Unmarshal([]byte(tagsJSON), &event.Tags); err != nil {
 		return nil, fmt.Errorf("failed to parse tags: %w", err)
 	}
+	if emailJSON != nil {
+		event.Email = &models.EmailMetadata{}
+		if err := json.Unmarshal(emailJSON, event.Email); err != nil {
+			return nil, fmt.Errorf("failed to parse email metadata: %w", err)
+		}
+	}
+	if event.Metadata, err = parseMetadata(metadataJSON); err != nil {
+		return nil, err
+	}
+	if event.Labels, err = parseLabels(labelsJSON); err != nil {
+		return nil, err
+	}
 
 	return &event, nil
 }
 
+// GetEventByMessageID retrieves the event stored for an email in a project,
+// or nil if there is none. Zero is the default project.
+func (d *Database) GetEventByMessageID(project int64, messageID string) (*models.Event, error) {
+	var id int64
+	err := d.db.QueryRow("SELECT id FROM events WHERE project_id = $1 AND message_id = $2", projectID(project), messageID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up message ID: %w", err)
+	}
+	return d.GetEventByID(id)
+}
+
 func (d *Database) GetEventsByTag(tag string) ([]models.Event, error) {
 	rows, err := d.db.Query(
-		`SELECT id, tags, data, source, created_at 
+		`SELECT id, tags, data, source, created_at, project_id, status, due_at, labels, location, latitude, longitude
 		FROM events 
-		WHERE tags::text LIKE $1 
+		WHERE tags ? $1
 		ORDER BY created_at DESC`,
-		fmt.Sprintf("%%\"%s\"%%", tag),
+		normalizeTag(tag),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query events: %w", err)
@@ -142,9 +293,10 @@ func (d *Database) GetEventsByTag(tag string) ([]models.Event, error) {
 	for rows.Next() {
 		var event models.Event
 		var tagsJSON string
+		var labelsJSON []byte
 		var createdAt time.Time
 
-		if err := rows.Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &createdAt); err != nil {
+		if err := rows.Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &createdAt, &event.ProjectID, &event.Status, &event.DueAt, &labelsJSON, &event.Location, &event.Latitude, &event.Longitude); err != nil {
 			return nil, fmt.Errorf("failed to scan event row: %w", err)
 		}
 
@@ -152,6 +304,9 @@ func (d *Database) GetEventsByTag(tag string) ([]models.Event, error) {
 		if err := json.Unmarshal([]byte(tagsJSON), &event.Tags); err != nil {
 			return nil, fmt.Errorf("failed to parse tags: %w", err)
 		}
+		if event.Labels, err = parseLabels(labelsJSON); err != nil {
+			return nil, err
+		}
 
 		events = append(events, event)
 	}
@@ -164,30 +319,28 @@ func (d *Database) GetEventsByTag(tag string) ([]models.Event, error) {
 }
 
 // GetEventsByDate retrieves all events created on a specific date (YYYY-MM-DD)
-func (d *Database) GetEventsByDate(date string) ([]models.Event, error) {
+// in the time zone loc, or UTC when loc is nil
+func (d *Database) GetEventsByDate(date string, loc *time.Location) ([]models.Event, error) {
 	// Handle empty date parameter
 	if date == "" {
 		return d.GetEventsByTag("")
 	}
 	
-	// Validate date format (YYYY-MM-DD)
-	_, err := time.Parse("2006-01-02", date)
+	// Validate date format (YYYY-MM-DD) and find when the day starts and
+	// ends in UTC, which created_at is stored in
+	start, end, err := models.LocalDay(date, loc)
 	if err != nil {
 		return nil, fmt.Errorf("invalid date format, expected YYYY-MM-DD: %w", err)
 	}
 	
-	// Create time bounds for the given date
-	start := date + " 00:00:00"
-	end := date + " 23:59:59.999999"
-	
-	log.Printf("Querying events between %s and %s", start, end)
+	log.Printf("Querying events between %s and %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
 	
 	rows, err := d.db.Query(
-		`SELECT id, tags, data, source, created_at 
+		`SELECT id, tags, data, source, created_at, project_id, status, due_at, labels, location, latitude, longitude
 		FROM events 
-		WHERE created_at::date = $1::date
+		WHERE created_at >= $1 AND created_at < $2
 		ORDER BY created_at DESC`,
-		date,
+		start, end,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("database query failed: %w", err)
@@ -198,14 +351,18 @@ func (d *Database) GetEventsByDate(date string) ([]models.Event, error) {
 	for rows.Next() {
 		var event models.Event
 		var tagsJSON string
+		var labelsJSON []byte
 		var createdAt time.Time
-		if err := rows.Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &createdAt); err != nil {
+		if err := rows.Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &createdAt, &event.ProjectID, &event.Status, &event.DueAt, &labelsJSON, &event.Location, &event.Latitude, &event.Longitude); err != nil {
 			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
 		event.CreatedAt = createdAt
 		if err := json.Unmarshal([]byte(tagsJSON), &event.Tags); err != nil {
 			return nil, fmt.Errorf("error unmarshaling tags: %w", err)
 		}
+		if event.Labels, err = parseLabels(labelsJSON); err != nil {
+			return nil, err
+		}
 		events = append(events, event)
 	}
 	if err = rows.Err(); err != nil {
@@ -218,86 +375,40 @@ func (d *Database) GetEventsByDate(date string) ([]models.Event, error) {
 
 // GetAllTags retrieves all unique tags used in events
 func (d *Database) GetAllTags() ([]string, error) {
-	rows, err := d.db.Query("SELECT tags FROM events")
-	if err != nil {
-		return nil, fmt.Errorf("failed to query events for tags: %w", err)
-	}
-	defer rows.Close()
-	
-	// Use a map to ensure uniqueness of tags
-	uniqueTags := make(map[string]bool)
-	
-	for rows.Next() {
-		var tagsJSON string
-		if err := rows.Scan(&tagsJSON); err != nil {
-			return nil, fmt.Errorf("failed to scan tags row: %w", err)
-		}
-		
-		var tags []string
-		if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
-			// If one event has invalid tags, we just skip it
-			continue
-		}
-		
-		// Add all tags to the uniqueTags map
-		for _, tag := range tags {
-			if tag != "" {
-				uniqueTags[tag] = true
-			}
-		}
-	}
-	
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
-	}
-	
-	// Convert map keys to slice
-	result := make([]string, 0, len(uniqueTags))
-	for tag := range uniqueTags {
-		result = append(result, tag)
-	}
-	
-	// Sort tags alphabetically for consistency
-	sort.Strings(result)
-	
-	return result, nil
+	return d.names.get("names:tags", func() ([]string, error) {
+		return d.queryNames("SELECT DISTINCT tag FROM events, jsonb_array_elements_text(tags) AS tag WHERE tag != ''")
+	})
 }
 
 // GetAllSources retrieves all unique sources used in events
 func (d *Database) GetAllSources() ([]string, error) {
-	rows, err := d.db.Query("SELECT DISTINCT source FROM events WHERE source != '' ORDER BY source")
+	return d.names.get("names:sources", func() ([]string, error) {
+		return d.queryNames("SELECT DISTINCT source FROM events WHERE source != ''")
+	})
+}
+
+// queryNames runs a query returning one name per row and sorts the names
+func (d *Database) queryNames(query string) ([]string, error) {
+	rows, err := d.db.Query(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query events for sources: %w", err)
+		return nil, fmt.Errorf("failed to query names: %w", err)
 	}
 	defer rows.Close()
-	
-	// Use a map to ensure uniqueness of sources
-	uniqueSources := make(map[string]bool)
-	
+
+	result := []string{}
 	for rows.Next() {
-		var source string
-		if err := rows.Scan(&source); err != nil {
-			return nil, fmt.Errorf("failed to scan source row: %w", err)
-		}
-		
-		if source != "" {
-			uniqueSources[source] = true
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan name row: %w", err)
 		}
+		result = append(result, name)
 	}
-	
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
-	
-	// Convert map keys to slice
-	result := make([]string, 0, len(uniqueSources))
-	for source := range uniqueSources {
-		result = append(result, source)
-	}
-	
-	// Sort sources alphabetically for consistency
+
+	// Sort alphabetically for consistency
 	sort.Strings(result)
-	
 	return result, nil
 }
 
@@ -311,9 +422,9 @@ func (d *Database) GetEventsBySource(source string) ([]models.Event, error) {
 	log.Printf("Querying events with source: %s", source)
 	
 	rows, err := d.db.Query(
-		`SELECT id, tags, data, source, created_at 
+		`SELECT id, tags, data, source, created_at, project_id, status, due_at, labels, location, latitude, longitude
 		FROM events 
-		WHERE source ILIKE $1 
+		WHERE lower(source) = lower($1)
 		ORDER BY created_at DESC`,
 		source,
 	)
@@ -326,9 +437,10 @@ func (d *Database) GetEventsBySource(source string) ([]models.Event, error) {
 	for rows.Next() {
 		var event models.Event
 		var tagsJSON string
+		var labelsJSON []byte
 		var createdAt time.Time
 
-		if err := rows.Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &createdAt); err != nil {
+		if err := rows.Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &createdAt, &event.ProjectID, &event.Status, &event.DueAt, &labelsJSON, &event.Location, &event.Latitude, &event.Longitude); err != nil {
 			return nil, fmt.Errorf("failed to scan event row: %w", err)
 		}
 
@@ -336,6 +448,9 @@ func (d *Database) GetEventsBySource(source string) ([]models.Event, error) {
 		if err := json.Unmarshal([]byte(tagsJSON), &event.Tags); err != nil {
 			return nil, fmt.Errorf("failed to parse tags: %w", err)
 		}
+		if event.Labels, err = parseLabels(labelsJSON); err != nil {
+			return nil, err
+		}
 
 		events = append(events, event)
 	}
@@ -350,6 +465,8 @@ func (d *Database) GetEventsBySource(source string) ([]models.Event, error) {
 
 // SaveEvent stores an Event in the database
 func (d *Database) SaveEvent(event *models.Event) error {
+	defer d.names.invalidate()
+	event.Tags = normalizeTags(event.Tags)
 	tagsJSON, err := json.Marshal(event.Tags)
 	if err != nil {
 		// Log pre-insert error (will be logged to stdout since event ID is 0)
@@ -358,14 +475,28 @@ func (d *Database) SaveEvent(event *models.Event) error {
 	}
 
 	cleanData := strings.TrimRight(event.Data, "\r\n")
+	metadataJSON, err := metadataParam(event.Metadata)
+	if err != nil {
+		return err
+	}
 	
 	var id int64
 	err = d.db.QueryRow(
-		"INSERT INTO events (tags, data, source, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		insertEventWithLogs,
 		string(tagsJSON),
 		cleanData,
 		event.Source,
+		"",
+		nil,
+		nil,
 		event.CreatedAt,
+		metadataJSON,
+		projectID(event.ProjectID),
+		event.Location,
+		event.Latitude,
+		event.Longitude,
+		pq.Array([]string{"success"}),
+		pq.Array([]string{""}),
 	).Scan(&id)
 	
 	if err != nil {
@@ -374,23 +505,19 @@ func (d *Database) SaveEvent(event *models.Event) error {
 		return fmt.Errorf("failed to insert event: %w", err)
 	}
 
-	// Now that we have a valid event ID, log the success in the database
-	if err := d.LogEventStatus(id, "success", ""); err != nil {
-		log.Printf("Warning: Event was stored but failed to log success: %v", err)
-	}
-
 	// Update the ID of the passed event
 	event.ID = id
+	event.ProjectID = projectID(event.ProjectID)
+	event.Status = models.StatusOpen
 	
 	return nil
 }
 
 // UpdateEvent updates an existing Event in the database
 func (d *Database) UpdateEvent(event *models.Event) error {
-	// Ensure we have a valid tags array
-	if event.Tags == nil {
-		event.Tags = []string{}
-	}
+	defer d.names.invalidate()
+	// Ensure we have a valid, normalized tags array
+	event.Tags = normalizeTags(event.Tags)
 	
 	// Log the tags for debugging
 	log.Printf("Updating event %d with tags: %v", event.ID, event.Tags)
@@ -405,13 +532,19 @@ func (d *Database) UpdateEvent(event *models.Event) error {
 
 	// Clean data by removing trailing whitespace
 	cleanData := strings.TrimRight(event.Data, "\r\n")
+	metadataJSON, err := metadataParam(event.Metadata)
+	if err != nil {
+		return err
+	}
 
 	// Execute update query
 	result, err := d.db.Exec(
-		"UPDATE events SET tags = $1, data = $2, source = $3 WHERE id = $4",
+		"UPDATE events SET tags = $1, data = $2, source = $3, metadata = $4, project_id = $5 WHERE id = $6",
 		string(tagsJSON),
 		cleanData,
 		event.Source,
+		metadataJSON,
+		projectID(event.ProjectID),
 		event.ID,
 	)
 	
@@ -419,6 +552,7 @@ func (d *Database) UpdateEvent(event *models.Event) error {
 		log.Printf("Error in SQL update for event %d: %v", event.ID, err)
 		return fmt.Errorf("failed to update event: %w", err)
 	}
+	d.events.invalidate(event.ID)
 
 	// Check if any rows were affected
 	rowsAffected, err := result.RowsAffected()
@@ -446,6 +580,8 @@ func (d *Database) UpdateEvent(event *models.Event) error {
 
 // DeleteEvent removes an event from the database by ID
 func (d *Database) DeleteEvent(id int64) error {
+	defer d.names.invalidate()
+	defer d.events.invalidate(id)
 	// Start a transaction
 	tx, err := d.db.Begin()
 	if err != nil {
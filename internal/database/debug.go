@@ -0,0 +1,77 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"fmt"
+)
+
+// SaveDebugPayload stores a captured request body, setting p.ID and
+// p.CreatedAt. Only the newest keep payloads of p's source are kept; keep 0
+// keeps them all.
+func (d *Database) SaveDebugPayload(p *models.DebugPayload, keep int) error {
+	err := d.db.QueryRow(
+		`INSERT INTO debug_payloads (source, content_type, remote_addr, request_id, status, error, payload)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		p.Source, p.ContentType, p.RemoteAddr, p.RequestID, p.Status, p.Error, []byte(p.Payload),
+	).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save debug payload: %w", err)
+	}
+	if keep <= 0 {
+		return nil
+	}
+
+	_, err = d.db.Exec(
+		`DELETE FROM debug_payloads WHERE source = $1 AND id < (
+			SELECT MIN(id) FROM (SELECT id FROM debug_payloads WHERE source = $1 ORDER BY id DESC LIMIT $2) newest
+		)`,
+		p.Source, keep,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to prune debug payloads: %w", err)
+	}
+	return nil
+}
+
+// ListDebugPayloads returns captured payloads, newest first. An empty source
+// lists those of every source.
+func (d *Database) ListDebugPayloads(source string, limit int) ([]models.DebugPayload, error) {
+	rows, err := d.db.Query(
+		`SELECT id, source, content_type, remote_addr, request_id, status, error, payload, created_at
+		FROM debug_payloads WHERE ($1 = '' OR source = $1) ORDER BY id DESC LIMIT $2`,
+		source, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query debug payloads: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.DebugPayload
+	for rows.Next() {
+		var p models.DebugPayload
+		var payload []byte
+		if err := rows.Scan(&p.ID, &p.Source, &p.ContentType, &p.RemoteAddr, &p.RequestID, &p.Status, &p.Error, &payload, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan debug payload: %w", err)
+		}
+		p.Payload = string(payload)
+		items = append(items, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating debug payloads: %w", err)
+	}
+	return items, nil
+}
+
+// DeleteDebugPayloads discards the captured payloads of source, or of every
+// source when it is empty, returning how many were deleted
+func (d *Database) DeleteDebugPayloads(source string) (int64, error) {
+	result, err := d.db.Exec("DELETE FROM debug_payloads WHERE ($1 = '' OR source = $1)", source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete debug payloads: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DigestSubscribers returns the users receiving digests of the given
+// period ("daily" or "weekly"). Users awaiting email verification are left
+// out; those without any address are returned with an empty Email.
+func (d *Database) DigestSubscribers(period string) ([]models.DigestSubscriber, error) {
+	rows, err := d.db.Query(
+		`SELECT p.username, COALESCE(u.role, ''), COALESCE(NULLIF(p.digest_email, ''), u.email, ''), p.timezone, p.digest_sent_at
+		FROM user_preferences p
+		LEFT JOIN web_users u ON u.username = p.username
+		WHERE p.digest = $1 AND NOT COALESCE(u.pending, FALSE)
+		ORDER BY p.username`,
+		period,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	var subscribers []models.DigestSubscriber
+	for rows.Next() {
+		var s models.DigestSubscriber
+		var sentAt sql.NullTime
+		if err := rows.Scan(&s.Username, &s.Role, &s.Email, &s.Timezone, &sentAt); err != nil {
+			return nil, fmt.Errorf("failed to scan digest subscriber row: %w", err)
+		}
+		s.SentAt = sentAt.Time
+		subscribers = append(subscribers, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return subscribers, nil
+}
+
+// MarkDigestSent records the end of the period of the last digest sent to a user
+func (d *Database) MarkDigestSent(username string, periodEnd time.Time) error {
+	_, err := d.db.Exec("UPDATE user_preferences SET digest_sent_at = $1 WHERE username = $2", periodEnd.UTC(), username)
+	if err != nil {
+		return fmt.Errorf("failed to record digest: %w", err)
+	}
+	return nil
+}
+
+// GetDigest summarizes the events of the projects created from from until
+// to: their number, the top tags and sources, and the newest events, limit
+// of each. A nil projects covers every project.
+func (d *Database) GetDigest(from, to time.Time, limit int, projects []int64) (*models.Digest, error) {
+	digest := &models.Digest{From: from, To: to}
+	from, to = from.UTC(), to.UTC()
+	inProjects := pq.Array(projects)
+
+	err := d.db.QueryRow(
+		"SELECT COUNT(*) FROM events WHERE created_at >= $1 AND created_at < $2 AND ($3::integer[] IS NULL OR project_id = ANY($3))",
+		from, to, inProjects,
+	).Scan(&digest.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count events: %w", err)
+	}
+	if digest.Total == 0 {
+		return digest, nil
+	}
+
+	if digest.Tags, err = d.queryNameCounts(
+		`SELECT tag, COUNT(*) AS n
+		FROM events, jsonb_array_elements_text(tags) AS tag
+		WHERE created_at >= $1 AND created_at < $2 AND ($4::integer[] IS NULL OR project_id = ANY($4))
+		GROUP BY tag
+		ORDER BY n DESC, tag
+		LIMIT $3`,
+		from, to, limit, inProjects,
+	); err != nil {
+		return nil, err
+	}
+
+	if digest.Sources, err = d.queryNameCounts(
+		`SELECT source, COUNT(*) AS n
+		FROM events
+		WHERE created_at >= $1 AND created_at < $2 AND ($4::integer[] IS NULL OR project_id = ANY($4))
+		GROUP BY source
+		ORDER BY n DESC, source
+		LIMIT $3`,
+		from, to, limit, inProjects,
+	); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(
+		`SELECT `+eventColumns+` FROM events
+		WHERE created_at >= $1 AND created_at < $2 AND ($4::integer[] IS NULL OR project_id = ANY($4))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`,
+		from, to, limit, inProjects,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+	if digest.Latest, err = scanEvents(rows); err != nil {
+		return nil, err
+	}
+
+	return digest, nil
+}
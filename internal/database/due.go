@@ -0,0 +1,86 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"fmt"
+	"time"
+)
+
+// SetEventDue sets when an event is due, or clears it when due is nil,
+// returning false if there is no such event. Reminders about the previous
+// due date are forgotten, so the new one gets its own.
+func (d *Database) SetEventDue(id int64, due *time.Time) (bool, error) {
+	defer d.events.invalidate(id)
+	result, err := d.db.Exec("UPDATE events SET due_at = $1, due_reminder = '' WHERE id = $2", dueParam(due), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to set event due date: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DueReminders returns the unresolved events that became overdue, or due
+// within models.DueSoonWindow, since they were last reminded about, soonest
+// due first
+func (d *Database) DueReminders(now time.Time) ([]models.DueReminder, error) {
+	rows, err := d.db.Query(
+		`SELECT `+eventColumns+`
+		FROM events
+		WHERE status IN ($1, $2) AND due_at IS NOT NULL
+			AND ((due_at < $3 AND due_reminder <> $5) OR (due_at < $4 AND due_reminder = ''))
+		ORDER BY due_at, id`,
+		models.StatusOpen, models.StatusAcknowledged, now.UTC(), now.Add(models.DueSoonWindow).UTC(), models.DueOverdue,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due events: %w", err)
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	reminders := make([]models.DueReminder, len(events))
+	for i, event := range events {
+		reminders[i] = models.DueReminder{Event: event, Stage: models.DueSoon}
+		if event.DueAt.Before(now) {
+			reminders[i].Stage = models.DueOverdue
+		}
+	}
+	return reminders, nil
+}
+
+// MarkDueReminded records that an event's reminder for stage was sent
+func (d *Database) MarkDueReminded(id int64, stage string) error {
+	if _, err := d.db.Exec("UPDATE events SET due_reminder = $1 WHERE id = $2", stage, id); err != nil {
+		return fmt.Errorf("failed to record due reminder: %w", err)
+	}
+	return nil
+}
+
+// dueCondition builds the filter condition for unresolved events due soon
+// or overdue at now, appending its arguments to args
+func dueCondition(due string, now time.Time, args *[]interface{}) (string, error) {
+	*args = append(*args, models.StatusOpen, models.StatusAcknowledged, now.UTC())
+	unresolved := fmt.Sprintf("status IN ($%d, $%d)", len(*args)-2, len(*args)-1)
+	switch due {
+	case models.DueOverdue:
+		return fmt.Sprintf("(%s AND due_at < $%d)", unresolved, len(*args)), nil
+	case models.DueSoon:
+		*args = append(*args, now.Add(models.DueSoonWindow).UTC())
+		return fmt.Sprintf("(%s AND due_at >= $%d AND due_at < $%d)", unresolved, len(*args)-1, len(*args)), nil
+	default:
+		return "", fmt.Errorf("invalid due filter %q, expected soon or overdue", due)
+	}
+}
+
+// dueParam passes a due date to a TIMESTAMP column, which stores UTC times
+func dueParam(due *time.Time) interface{} {
+	if due == nil {
+		return nil
+	}
+	return due.UTC()
+}
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+	"strings"
+)
+
+// ErrEventExists is returned by RestoreEvent when the event has not been deleted
+var ErrEventExists = errors.New("event exists")
+
+// RecordEventAudit stores an entry in an event's audit trail, setting its ID
+// and CreatedAt
+func (d *Database) RecordEventAudit(entry *models.EventAuditEntry) error {
+	return recordEventAudit(d.db, entry)
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func recordEventAudit(q rowQuerier, entry *models.EventAuditEntry) error {
+	oldJSON, err := marshalSnapshot(entry.Old)
+	if err != nil {
+		return err
+	}
+	newJSON, err := marshalSnapshot(entry.New)
+	if err != nil {
+		return err
+	}
+
+	err = q.QueryRow(
+		"INSERT INTO event_audit (event_id, action, actor, old_values, new_values) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+		entry.EventID, entry.Action, entry.Actor, jsonParam(oldJSON), jsonParam(newJSON),
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert event audit entry: %w", err)
+	}
+	return nil
+}
+
+// marshalSnapshot encodes an event for the audit trail; nil stays NULL
+func marshalSnapshot(event *models.Event) ([]byte, error) {
+	if event == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// GetEventAudit returns the audit trail of an event, newest first
+func (d *Database) GetEventAudit(eventID int64) ([]models.EventAuditEntry, error) {
+	return d.queryEventAudit(
+		`SELECT id, event_id, action, actor, old_values, new_values, created_at
+		FROM event_audit
+		WHERE event_id = $1
+		ORDER BY created_at DESC, id DESC`,
+		eventID,
+	)
+}
+
+// ListEventAudit returns the most recent changes to any event, optionally
+// filtered by action
+func (d *Database) ListEventAudit(action string, limit int) ([]models.EventAuditEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	return d.queryEventAudit(
+		`SELECT id, event_id, action, actor, old_values, new_values, created_at
+		FROM event_audit
+		WHERE $1 = '' OR action = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`,
+		action, limit,
+	)
+}
+
+func (d *Database) queryEventAudit(query string, args ...interface{}) ([]models.EventAuditEntry, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event audit: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.EventAuditEntry
+	for rows.Next() {
+		entry, err := scanEventAudit(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return entries, nil
+}
+
+func scanEventAudit(row rowScanner) (*models.EventAuditEntry, error) {
+	var entry models.EventAuditEntry
+	var oldJSON, newJSON []byte
+	if err := row.Scan(&entry.ID, &entry.EventID, &entry.Action, &entry.Actor, &oldJSON, &newJSON, &entry.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan event audit row: %w", err)
+	}
+	if oldJSON != nil {
+		entry.Old = &models.Event{}
+		if err := json.Unmarshal(oldJSON, entry.Old); err != nil {
+			return nil, fmt.Errorf("failed to decode audit entry %d: %w", entry.ID, err)
+		}
+	}
+	if newJSON != nil {
+		entry.New = &models.Event{}
+		if err := json.Unmarshal(newJSON, entry.New); err != nil {
+			return nil, fmt.Errorf("failed to decode audit entry %d: %w", entry.ID, err)
+		}
+	}
+	return &entry, nil
+}
+
+// RestoreEvent brings back a deleted event with its original ID from the
+// snapshot taken when it was deleted, recording the restore for actor. It
+// returns nil if the event was never deleted, ErrEventExists if it is still
+// stored and ErrDuplicateMessageID if its email has been stored again in its
+// project since.
+// Attachments are not restored.
+func (d *Database) RestoreEvent(id int64, actor string) (*models.Event, error) {
+	defer d.names.invalidate()
+	defer d.events.invalidate(id)
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM events WHERE id = $1)", id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up event: %w", err)
+	}
+	if exists {
+		return nil, ErrEventExists
+	}
+
+	deleted, err := scanEventAudit(tx.QueryRow(
+		`SELECT id, event_id, action, actor, old_values, new_values, created_at
+		FROM event_audit
+		WHERE event_id = $1 AND action = $2 AND old_values IS NOT NULL
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`,
+		id, models.EventDeleted,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	event := deleted.Old
+	event.ID = id
+	event.Status = eventStatus(event.Status)
+	event.AttachmentCount = 0
+	event.Tags = normalizeTags(event.Tags)
+	tagsJSON, err := json.Marshal(event.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	var messageID sql.NullString
+	var emailJSON []byte
+	if event.Email != nil {
+		if emailJSON, err = json.Marshal(event.Email); err != nil {
+			return nil, fmt.Errorf("failed to marshal email metadata: %w", err)
+		}
+		messageID = sql.NullString{String: event.Email.MessageID, Valid: event.Email.MessageID != ""}
+	}
+	metadataJSON, err := metadataParam(event.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	labelsJSON, err := labelsParam(event.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO events (id, tags, data, source, html_body, message_id, email, created_at, metadata, project_id, status, due_at, labels, location, latitude, longitude) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, "+knownLabels(13)+", $14, $15, $16) ON CONFLICT (project_id, message_id) DO NOTHING",
+		id, string(tagsJSON), strings.TrimRight(event.Data, "\r\n"), event.Source, event.HTMLBody, messageID, jsonParam(emailJSON), event.CreatedAt, metadataJSON, projectID(event.ProjectID), eventStatus(event.Status), dueParam(event.DueAt), labelsJSON, event.Location, event.Latitude, event.Longitude,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore event: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("error checking rows affected: %w", err)
+	} else if n == 0 {
+		return nil, ErrDuplicateMessageID
+	}
+
+	if err := recordEventAudit(tx, &models.EventAuditEntry{EventID: id, Action: models.EventRestored, Actor: actor, New: event}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return event, nil
+}
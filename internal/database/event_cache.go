@@ -0,0 +1,131 @@
+package database
+
+import (
+	"container/list"
+	"example-api/internal/models"
+	"maps"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultEventCacheSize is how many events GetEventByID keeps by default
+	defaultEventCacheSize = 1000
+	// defaultEventCacheTTL bounds how long a cached event can miss changes
+	// made by other processes
+	defaultEventCacheTTL = 30 * time.Second
+)
+
+// eventCache keeps the most recently used events fetched by GetEventByID.
+// Writes through this Database invalidate the events they change; changes
+// made by other processes show up once the entries expire.
+type eventCache struct {
+	mu         sync.Mutex
+	size       int           // Zero disables caching
+	ttl        time.Duration // Zero disables caching
+	generation uint64        // Bumped by invalidate and clear
+	order      *list.List    // Of *eventCacheEntry, most recently used first
+	entries    map[int64]*list.Element
+}
+
+type eventCacheEntry struct {
+	event   *models.Event
+	expires time.Time
+}
+
+// SetEventCache sets how many events GetEventByID caches and for how long.
+// Zero for either turns the cache off.
+func (d *Database) SetEventCache(size int, ttl time.Duration) {
+	d.events.mu.Lock()
+	defer d.events.mu.Unlock()
+	d.events.size, d.events.ttl = size, ttl
+	d.events.generation++
+	d.events.order, d.events.entries = nil, nil
+}
+
+// get returns the cached event with id, calling load when it is missing or
+// expired. Events that don't exist aren't cached. The caller gets its own
+// copy.
+func (c *eventCache) get(id int64, load func() (*models.Event, error)) (*models.Event, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[id]; ok {
+		entry := elem.Value.(*eventCacheEntry)
+		if time.Now().Before(entry.expires) {
+			c.order.MoveToFront(elem)
+			c.mu.Unlock()
+			return cloneEvent(entry.event), nil
+		}
+		c.order.Remove(elem)
+		delete(c.entries, id)
+	}
+	size, ttl, generation := c.size, c.ttl, c.generation
+	c.mu.Unlock()
+
+	event, err := load()
+	if err != nil || event == nil || size <= 0 || ttl <= 0 {
+		return event, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// A write during the load may have made the result stale already
+	if c.generation == generation {
+		if c.entries == nil {
+			c.order, c.entries = list.New(), make(map[int64]*list.Element)
+		}
+		if elem, ok := c.entries[id]; ok {
+			c.order.Remove(elem)
+		}
+		c.entries[id] = c.order.PushFront(&eventCacheEntry{event: cloneEvent(event), expires: time.Now().Add(ttl)})
+		for c.order.Len() > size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*eventCacheEntry).event.ID)
+		}
+	}
+	return event, nil
+}
+
+// invalidate drops the cached copies of events after they are changed or
+// deleted
+func (c *eventCache) invalidate(ids ...int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, id := range ids {
+		if elem, ok := c.entries[id]; ok {
+			c.order.Remove(elem)
+			delete(c.entries, id)
+		}
+	}
+}
+
+// clear drops every cached event, after writes that may change any of them
+func (c *eventCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.order, c.entries = nil, nil
+}
+
+// cloneEvent copies an event so callers can't change the cached one. The
+// email metadata's own slices and maps are shared.
+func cloneEvent(event *models.Event) *models.Event {
+	clone := *event
+	clone.Tags = append(event.Tags[:0:0], event.Tags...)
+	clone.Labels = append(event.Labels[:0:0], event.Labels...)
+	clone.Metadata = maps.Clone(event.Metadata)
+	if event.Email != nil {
+		email := *event.Email
+		clone.Email = &email
+	}
+	if event.DueAt != nil {
+		due := *event.DueAt
+		clone.DueAt = &due
+	}
+	if event.Located() {
+		latitude, longitude := *event.Latitude, *event.Longitude
+		clone.Latitude, clone.Longitude = &latitude, &longitude
+	}
+	return &clone
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// GetEventLogs retrieves the most recent event log entries, optionally filtered by status
+func (d *Database) GetEventLogs(status string, limit int) ([]models.EventLog, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := d.db.Query(
+		`SELECT id, event_id, status, error_message, created_at
+		FROM event_logs
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`,
+		status,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []models.EventLog
+	for rows.Next() {
+		var entry models.EventLog
+		var eventID sql.NullInt64
+		var errorMessage sql.NullString
+
+		if err := rows.Scan(&entry.ID, &eventID, &entry.Status, &errorMessage, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event log row: %w", err)
+		}
+		entry.EventID = eventID.Int64
+		entry.ErrorMessage = errorMessage.String
+
+		logs = append(logs, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return logs, nil
+}
+
+// GetEventLogStatusCounts returns the number of log entries for each status
+func (d *Database) GetEventLogStatusCounts() ([]models.NameCount, error) {
+	return d.queryNameCounts("SELECT status, COUNT(*) AS n FROM event_logs GROUP BY status ORDER BY status")
+}
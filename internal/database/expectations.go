@@ -0,0 +1,190 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const expectationColumns = `id, name, source, tags, min_events, window_seconds, channel, target, enabled, created_by, created_at, checked_at, missed_since`
+
+// CreateExpectation stores a new expectation
+func (d *Database) CreateExpectation(exp *models.Expectation) error {
+	exp.Tags = normalizeTags(exp.Tags)
+	tagsJSON, err := json.Marshal(nonNilTags(exp.Tags))
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	err = d.db.QueryRow(
+		`INSERT INTO expectations (name, source, tags, min_events, window_seconds, channel, target, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`,
+		exp.Name,
+		exp.Source,
+		string(tagsJSON),
+		exp.MinEvents,
+		int64(exp.Window/time.Second),
+		exp.Channel,
+		exp.Target,
+		exp.Enabled,
+		exp.CreatedBy,
+	).Scan(&exp.ID, &exp.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert expectation: %w", err)
+	}
+	return nil
+}
+
+// GetExpectation retrieves an expectation by ID, or nil if there is none
+func (d *Database) GetExpectation(id int64) (*models.Expectation, error) {
+	exp, err := scanExpectation(d.db.QueryRow("SELECT "+expectationColumns+" FROM expectations WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expectation: %w", err)
+	}
+	return exp, nil
+}
+
+// ListExpectations retrieves every expectation by name
+func (d *Database) ListExpectations() ([]models.Expectation, error) {
+	return d.queryExpectations("SELECT " + expectationColumns + " FROM expectations ORDER BY lower(name), id")
+}
+
+// EnabledExpectations retrieves the expectations eventdb expect checks
+func (d *Database) EnabledExpectations() ([]models.Expectation, error) {
+	return d.queryExpectations("SELECT " + expectationColumns + " FROM expectations WHERE enabled ORDER BY id")
+}
+
+// SetExpectationEnabled turns an expectation on or off. Turning it off
+// forgets that it was missed, so it starts over when turned back on.
+func (d *Database) SetExpectationEnabled(id int64, enabled bool) error {
+	if _, err := d.db.Exec("UPDATE expectations SET enabled = $1, missed_since = NULL WHERE id = $2", enabled, id); err != nil {
+		return fmt.Errorf("failed to update expectation: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpectation removes an expectation
+func (d *Database) DeleteExpectation(id int64) error {
+	if _, err := d.db.Exec("DELETE FROM expectations WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete expectation: %w", err)
+	}
+	return nil
+}
+
+// ExpectationActivity counts the events matching the expectation stored
+// since, up to its MinEvents, and returns when the newest matching event was
+// stored. Heartbeats from monitors don't count.
+func (d *Database) ExpectationActivity(exp *models.Expectation, since time.Time) (int, *time.Time, error) {
+	args := []interface{}{models.HeartbeatTag}
+	conditions := []string{"NOT tags ? $1"}
+	if exp.Source != "" {
+		args = append(args, exp.Source)
+		conditions = append(conditions, fmt.Sprintf("lower(source) = lower($%d)", len(args)))
+	}
+	if len(exp.Tags) > 0 {
+		args = append(args, pq.Array(exp.Tags))
+		conditions = append(conditions, fmt.Sprintf("tags ?| $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var last *time.Time
+	if err := d.db.QueryRow("SELECT max(created_at) FROM events WHERE "+where, args...).Scan(&last); err != nil {
+		return 0, nil, fmt.Errorf("failed to query events of expectation %d: %w", exp.ID, err)
+	}
+
+	var count int
+	err := d.db.QueryRow(
+		fmt.Sprintf("SELECT count(*) FROM (SELECT 1 FROM events WHERE %s AND created_at >= $%d LIMIT $%d) matched",
+			where, len(args)+1, len(args)+2),
+		append(args, since.UTC(), exp.MinEvents)...,
+	).Scan(&count)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to count events of expectation %d: %w", exp.ID, err)
+	}
+	return count, last, nil
+}
+
+// MarkExpectationChecked records when an expectation was last checked
+func (d *Database) MarkExpectationChecked(id int64, now time.Time) error {
+	if _, err := d.db.Exec("UPDATE expectations SET checked_at = $1 WHERE id = $2", now.UTC(), id); err != nil {
+		return fmt.Errorf("failed to update expectation: %w", err)
+	}
+	return nil
+}
+
+// MarkExpectationMissed records that an expectation is missed since since,
+// returning false if it already was, so only one check notifies about it
+func (d *Database) MarkExpectationMissed(id int64, since time.Time) (bool, error) {
+	result, err := d.db.Exec("UPDATE expectations SET missed_since = $1 WHERE id = $2 AND missed_since IS NULL", since.UTC(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to update expectation: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// MarkExpectationMet records that an expectation is met again, returning
+// false if it wasn't missed
+func (d *Database) MarkExpectationMet(id int64) (bool, error) {
+	result, err := d.db.Exec("UPDATE expectations SET missed_since = NULL WHERE id = $1 AND missed_since IS NOT NULL", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to update expectation: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (d *Database) queryExpectations(query string) ([]models.Expectation, error) {
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expectations: %w", err)
+	}
+	defer rows.Close()
+
+	var expectations []models.Expectation
+	for rows.Next() {
+		exp, err := scanExpectation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan expectation row: %w", err)
+		}
+		expectations = append(expectations, *exp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return expectations, nil
+}
+
+func scanExpectation(row rowScanner) (*models.Expectation, error) {
+	var exp models.Expectation
+	var tagsJSON string
+	var windowSeconds int64
+
+	if err := row.Scan(&exp.ID, &exp.Name, &exp.Source, &tagsJSON, &exp.MinEvents, &windowSeconds, &exp.Channel,
+		&exp.Target, &exp.Enabled, &exp.CreatedBy, &exp.CreatedAt, &exp.CheckedAt, &exp.MissedSince); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tagsJSON), &exp.Tags); err != nil {
+		return nil, fmt.Errorf("failed to parse tags: %w", err)
+	}
+	exp.Window = time.Duration(windowSeconds) * time.Second
+
+	return &exp, nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ExportEvents calls fn for every event in ID order, with its ingestion logs
+// and attachments, without loading them all into memory. Attachment contents
+// are only read when withData is set.
+func (d *Database) ExportEvents(withData bool, fn func(event *models.ExportedEvent) error) error {
+	return d.exportEvents(withData, fn, "")
+}
+
+// ExportEventsByID is ExportEvents for the given events only
+func (d *Database) ExportEventsByID(ids []int64, withData bool, fn func(event *models.ExportedEvent) error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return d.exportEvents(withData, fn, "WHERE e.id = ANY($1)", pq.Array(ids))
+}
+
+// ExportEventsAfter is ExportEvents for at most limit events with IDs above
+// afterID, created before before
+func (d *Database) ExportEventsAfter(afterID int64, before time.Time, limit int, withData bool, fn func(event *models.ExportedEvent) error) error {
+	return d.exportEvents(withData, fn,
+		"WHERE e.id IN (SELECT id FROM events WHERE id > $1 AND created_at < $2 ORDER BY id LIMIT $3)",
+		afterID, before.UTC(), limit)
+}
+
+func (d *Database) exportEvents(withData bool, fn func(event *models.ExportedEvent) error, where string, args ...interface{}) error {
+	attachmentData := "NULL"
+	if withData {
+		attachmentData = "translate(encode(a.data, 'base64'), E'\\n', '')"
+	}
+
+	rows, err := d.db.Query(`SELECT e.id, e.tags, e.data, e.source, e.html_body, e.email, e.metadata, e.project_id, e.status, e.due_at, e.labels, e.location, e.latitude, e.longitude, e.created_at,
+		(SELECT json_agg(json_build_object(
+			'id', l.id, 'event_id', l.event_id, 'status', l.status,
+			'error_message', COALESCE(l.error_message, ''), 'created_at', l.created_at AT TIME ZONE 'UTC'
+		) ORDER BY l.id) FROM event_logs l WHERE l.event_id = e.id),
+		(SELECT json_agg(json_build_object(
+			'id', a.id, 'event_id', a.event_id, 'filename', a.filename, 'content_type', a.content_type,
+			'size', a.size, 'created_at', a.created_at AT TIME ZONE 'UTC', 'data', `+attachmentData+`
+		) ORDER BY a.id) FROM attachments a WHERE a.event_id = e.id)
+		FROM events e
+		`+where+`
+		ORDER BY e.id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.ExportedEvent
+		var tagsJSON string
+		var emailJSON, metadataJSON, labelsJSON, logsJSON, attachmentsJSON []byte
+		if err := rows.Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &event.HTMLBody, &emailJSON, &metadataJSON,
+			&event.ProjectID, &event.Status, &event.DueAt, &labelsJSON, &event.Location, &event.Latitude, &event.Longitude, &event.CreatedAt, &logsJSON, &attachmentsJSON); err != nil {
+			return fmt.Errorf("failed to scan event row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(tagsJSON), &event.Tags); err != nil {
+			return fmt.Errorf("failed to parse tags of event %d: %w", event.ID, err)
+		}
+		if emailJSON != nil {
+			event.Email = &models.EmailMetadata{}
+			if err := json.Unmarshal(emailJSON, event.Email); err != nil {
+				return fmt.Errorf("failed to parse email metadata of event %d: %w", event.ID, err)
+			}
+		}
+		metadata, err := parseMetadata(metadataJSON)
+		if err != nil {
+			return fmt.Errorf("failed to parse metadata of event %d: %w", event.ID, err)
+		}
+		event.Metadata = metadata
+		if event.Labels, err = parseLabels(labelsJSON); err != nil {
+			return fmt.Errorf("failed to parse labels of event %d: %w", event.ID, err)
+		}
+		if logsJSON != nil {
+			if err := json.Unmarshal(logsJSON, &event.Logs); err != nil {
+				return fmt.Errorf("failed to parse logs of event %d: %w", event.ID, err)
+			}
+		}
+		if attachmentsJSON != nil {
+			if err := json.Unmarshal(attachmentsJSON, &event.Attachments); err != nil {
+				return fmt.Errorf("failed to parse attachments of event %d: %w", event.ID, err)
+			}
+		}
+		event.AttachmentCount = len(event.Attachments)
+
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	return nil
+}
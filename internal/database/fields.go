@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// ErrFieldExists is returned by CreateCustomField when a field already has
+// the name
+var ErrFieldExists = errors.New("custom field exists")
+
+// CreateCustomField defines a custom field, setting its ID and creation time
+func (d *Database) CreateCustomField(field *models.CustomField) error {
+	optionsJSON, err := json.Marshal(field.Options)
+	if err != nil {
+		return fmt.Errorf("failed to marshal options: %w", err)
+	}
+	err = d.db.QueryRow(
+		`INSERT INTO custom_fields (name, label, type, options) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO NOTHING RETURNING id, created_at`,
+		field.Name, field.Label, field.Type, string(optionsJSON),
+	).Scan(&field.ID, &field.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrFieldExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create custom field: %w", err)
+	}
+	return nil
+}
+
+// ListCustomFields retrieves the custom fields in the order they were defined
+func (d *Database) ListCustomFields() ([]models.CustomField, error) {
+	rows, err := d.db.Query("SELECT id, name, label, type, options, created_at FROM custom_fields ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	var fields []models.CustomField
+	for rows.Next() {
+		var field models.CustomField
+		var optionsJSON []byte
+		if err := rows.Scan(&field.ID, &field.Name, &field.Label, &field.Type, &optionsJSON, &field.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan custom field row: %w", err)
+		}
+		if optionsJSON != nil {
+			if err := json.Unmarshal(optionsJSON, &field.Options); err != nil {
+				return nil, fmt.Errorf("failed to parse options of field %s: %w", field.Name, err)
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return fields, nil
+}
+
+// DeleteCustomField removes a custom field and its values from every event.
+// It returns the removed field, or nil if there was none with the ID.
+func (d *Database) DeleteCustomField(id int64) (*models.CustomField, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var field models.CustomField
+	err = tx.QueryRow("DELETE FROM custom_fields WHERE id = $1 RETURNING name, label, type", id).Scan(&field.Name, &field.Label, &field.Type)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete custom field: %w", err)
+	}
+	field.ID = id
+
+	if _, err := tx.Exec("UPDATE events SET metadata = metadata - $1 WHERE metadata ? $1", field.Name); err != nil {
+		return nil, fmt.Errorf("failed to remove field values: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	d.events.clear()
+	return &field, nil
+}
+
+// metadataParam encodes custom field values for the metadata column
+func metadataParam(metadata models.Metadata) (string, error) {
+	if len(metadata) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+// parseMetadata decodes the metadata column, leaving events without custom
+// field values with nil Metadata
+func parseMetadata(data []byte) (models.Metadata, error) {
+	var metadata models.Metadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	return metadata, nil
+}
@@ -0,0 +1,255 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ListEvents retrieves a page of events matching the filter, along with the
+// total number of matching events
+func (d *Database) ListEvents(filter models.EventFilter) ([]models.Event, int, error) {
+	where, args, err := eventFilterClause(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	from := "events " + where
+	if filter.Collapse {
+		// Each incident is represented by its newest event matching the filter
+		from = `(SELECT *, row_number() OVER (PARTITION BY COALESCE(incident_id, -id) ORDER BY created_at DESC, id DESC) AS incident_rank
+			FROM events ` + where + `) events WHERE incident_rank = 1`
+	}
+
+	var total int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM "+from, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	query := "SELECT " + eventColumns + " FROM " + from + " ORDER BY " + orderBy(filter.SortBy, filter.SortDesc)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// StreamEvents calls fn for every event matching the filter without loading
+// the whole result set into memory. Limit, Offset and Collapse are ignored.
+func (d *Database) StreamEvents(filter models.EventFilter, fn func(event models.Event) error) error {
+	where, args, err := eventFilterClause(filter)
+	if err != nil {
+		return err
+	}
+
+	rows, err := d.db.Query(
+		"SELECT "+eventColumns+" FROM events "+where+" ORDER BY "+orderBy(filter.SortBy, filter.SortDesc),
+		args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
+
+// eventFilterClause builds the WHERE clause and arguments for an event filter
+func eventFilterClause(filter models.EventFilter) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+
+	// Tags are stored normalized, so matching whole tags with the JSONB
+	// operators is case-insensitive and can use the GIN index
+	if tags := normalizeTags(filter.Tags); len(tags) > 0 {
+		args = append(args, pq.Array(tags))
+		if filter.MatchAll {
+			conditions = append(conditions, fmt.Sprintf("tags ?& $%d", len(args)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("tags ?| $%d", len(args)))
+		}
+	}
+	// Dates are days in the filter's time zone, and created_at is UTC
+	if filter.DateFrom != "" {
+		start, _, err := models.LocalDay(filter.DateFrom, filter.Location)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid from date format, expected YYYY-MM-DD: %w", err)
+		}
+		args = append(args, start)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.DateTo != "" {
+		_, end, err := models.LocalDay(filter.DateTo, filter.Location)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid to date format, expected YYYY-MM-DD: %w", err)
+		}
+		args = append(args, end)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.Source != "" {
+		args = append(args, filter.Source)
+		conditions = append(conditions, fmt.Sprintf("lower(source) = lower($%d)", len(args)))
+	}
+	if filter.Query != "" {
+		// Full-text match on the data, with a substring fallback for partial words and sources
+		args = append(args, filter.Query, "%"+escapeLike(filter.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(to_tsvector('english', data) @@ plainto_tsquery('english', $%d) OR data ILIKE $%d OR source ILIKE $%d)",
+			len(args)-1, len(args), len(args)))
+	}
+
+	if len(filter.Metadata) > 0 {
+		metadataJSON, err := metadataParam(filter.Metadata)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, metadataJSON)
+		conditions = append(conditions, fmt.Sprintf("metadata @> $%d::jsonb", len(args)))
+	}
+	if filter.Projects != nil {
+		args = append(args, pq.Array(filter.Projects))
+		conditions = append(conditions, fmt.Sprintf("project_id = ANY($%d)", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if len(filter.Labels) > 0 {
+		labelsJSON, err := labelsParam(filter.Labels)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, labelsJSON)
+		conditions = append(conditions, fmt.Sprintf("labels @> $%d::jsonb", len(args)))
+	}
+	if filter.Due != "" {
+		due, err := dueCondition(filter.Due, time.Now(), &args)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, due)
+	}
+	if filter.BBox != nil {
+		conditions = append(conditions, bboxCondition(*filter.BBox, &args))
+	} else if filter.Located {
+		conditions = append(conditions, "latitude IS NOT NULL")
+	}
+	if filter.StarredBy != "" {
+		args = append(args, filter.StarredBy)
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT event_id FROM event_stars WHERE username = $%d)", len(args)))
+	}
+	if filter.Incident != 0 {
+		args = append(args, filter.Incident)
+		conditions = append(conditions, fmt.Sprintf("incident_id = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return where, args, nil
+}
+
+// sortColumns maps sort keys to the columns they order by
+var sortColumns = map[string]string{
+	"created_at": "created_at",
+	"source":     "source",
+	"id":         "id",
+}
+
+// orderBy builds an ORDER BY clause from a whitelisted sort key, breaking ties by ID
+func orderBy(sortBy string, desc bool) string {
+	column, ok := sortColumns[sortBy]
+	if !ok {
+		column = "created_at"
+	}
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	if column == "id" {
+		return "id " + direction
+	}
+	return column + " " + direction + ", id " + direction
+}
+
+// escapeLike escapes the LIKE wildcard characters in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// eventColumns are the columns of events read by scanEvent
+const eventColumns = "id, tags, data, source, created_at, project_id, status, due_at, labels, location, latitude, longitude"
+
+// scanEvents reads eventColumns rows into events
+func scanEvents(rows *sql.Rows) ([]models.Event, error) {
+	var events []models.Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return events, nil
+}
+
+// scanEvent reads the current eventColumns row
+func scanEvent(rows *sql.Rows) (models.Event, error) {
+	var event models.Event
+	var tagsJSON string
+	var labelsJSON []byte
+
+	if err := rows.Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &event.CreatedAt, &event.ProjectID, &event.Status, &event.DueAt, &labelsJSON, &event.Location, &event.Latitude, &event.Longitude); err != nil {
+		return event, fmt.Errorf("failed to scan event row: %w", err)
+	}
+	if err := json.Unmarshal([]byte(tagsJSON), &event.Tags); err != nil {
+		return event, fmt.Errorf("failed to parse tags: %w", err)
+	}
+	var err error
+	if event.Labels, err = parseLabels(labelsJSON); err != nil {
+		return event, err
+	}
+
+	return event, nil
+}
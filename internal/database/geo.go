@@ -0,0 +1,38 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"fmt"
+)
+
+// SetEventLocation sets where an event happened, returning false if there is
+// no such event. Coordinates are nil for a location that couldn't be
+// geocoded, and an empty location with nil coordinates clears both.
+func (d *Database) SetEventLocation(id int64, location string, latitude, longitude *float64) (bool, error) {
+	defer d.events.invalidate(id)
+	result, err := d.db.Exec(
+		"UPDATE events SET location = $1, latitude = $2, longitude = $3 WHERE id = $4",
+		location, latitude, longitude, id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to set event location: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// bboxCondition builds the filter condition for events located inside box,
+// appending its arguments to args. A box whose west edge is east of its east
+// edge crosses the antimeridian, and takes the longitudes outside the two.
+func bboxCondition(box models.BoundingBox, args *[]interface{}) string {
+	*args = append(*args, box.South, box.North, box.West, box.East)
+	n := len(*args)
+	join := "AND"
+	if box.West > box.East {
+		join = "OR"
+	}
+	return fmt.Sprintf("(latitude BETWEEN $%d AND $%d AND (longitude >= $%d %s longitude <= $%d))", n-3, n-2, n-1, join, n)
+}
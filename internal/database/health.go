@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Ping checks that the database answers
+func (d *Database) Ping(ctx context.Context) error {
+	if err := d.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("database unreachable: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the statistics of the connection pool
+func (d *Database) Stats() sql.DBStats {
+	return d.db.Stats()
+}
+
+// requiredIndexes are the indexes the common event queries rely on, with a
+// part of the definition PostgreSQL reports for each
+var requiredIndexes = map[string]string{
+	"idx_events_created_at":        "(created_at)",
+	"idx_events_source_created_at": "(lower(source), created_at)",
+	"idx_events_tags":              "USING gin (tags)",
+}
+
+// MissingIndexes returns the names of the indexes on events that the common
+// queries rely on but which don't exist, or are defined differently
+func (d *Database) MissingIndexes(ctx context.Context) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT indexname, indexdef FROM pg_indexes WHERE tablename = 'events'")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool)
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+		if want, ok := requiredIndexes[name]; ok && strings.Contains(definition, want) {
+			found[name] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating indexes: %w", err)
+	}
+
+	var missing []string
+	for name := range requiredIndexes {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// AppliedMigrations returns the names of the migration files recorded by the
+// migrate command. Before it first records any, none are applied.
+func (d *Database) AppliedMigrations(ctx context.Context) (map[string]bool, error) {
+	rows, err := d.db.QueryContext(ctx, "SELECT name FROM schema_migrations")
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "42P01" { // undefined_table
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating migrations: %w", err)
+	}
+	return applied, nil
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"sync"
+)
+
+// eventHooks are the functions called with each received event once it is
+// stored
+type eventHooks struct {
+	mu        sync.RWMutex
+	storedFns []func(event *models.Event)
+}
+
+// OnEventStored registers fn to be called with every event received through
+// StoreEvent, StoreEvents or InsertEvents once it is stored. Events created or
+// cloned in the web interface and imported events aren't passed. fn runs on
+// the goroutine storing the event, so it should hand slow work off.
+func (d *Database) OnEventStored(fn func(event *models.Event)) {
+	d.hooks.mu.Lock()
+	defer d.hooks.mu.Unlock()
+	d.hooks.storedFns = append(d.hooks.storedFns, fn)
+}
+
+// stored calls the OnEventStored functions with event
+func (h *eventHooks) stored(event *models.Event) {
+	h.mu.RLock()
+	fns := h.storedFns
+	h.mu.RUnlock()
+	for _, fn := range fns {
+		fn(event)
+	}
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"strings"
+)
+
+// ImportEvents stores a batch of exported events, with their logs and
+// attachments, in one transaction. strategy decides what happens to an event
+// whose ID is already stored (see models.ImportSkip and friends); events
+// whose email Message-ID belongs to another stored event are always skipped.
+func (d *Database) ImportEvents(events []models.ExportedEvent, strategy string) (models.ImportResult, error) {
+	defer d.names.invalidate()
+	defer d.events.clear()
+	var result models.ImportResult
+	switch strategy {
+	case models.ImportSkip, models.ImportOverwrite, models.ImportNewID:
+	default:
+		return result, fmt.Errorf("unknown conflict strategy %q", strategy)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i := range events {
+		event := &events[i]
+		stored, err := importEvent(tx, event, strategy)
+		if err != nil {
+			return result, fmt.Errorf("event %d: %w", event.ID, err)
+		}
+		switch stored {
+		case "":
+			result.Skipped++
+			continue
+		case models.ImportOverwrite:
+			result.Overwritten++
+		default:
+			result.Imported++
+		}
+
+		for _, l := range event.Logs {
+			if _, err := tx.Exec(
+				"INSERT INTO event_logs (event_id, status, error_message, created_at) VALUES ($1, $2, $3, $4)",
+				event.ID, l.Status, l.ErrorMessage, l.CreatedAt,
+			); err != nil {
+				return result, fmt.Errorf("event %d: failed to insert event log: %w", event.ID, err)
+			}
+		}
+		for _, a := range event.Attachments {
+			if len(a.Data) == 0 && a.Size > 0 {
+				result.MissingAttachmentData++
+				continue
+			}
+			if _, err := tx.Exec(
+				"INSERT INTO attachments (event_id, filename, content_type, size, data, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+				event.ID, a.Filename, a.ContentType, len(a.Data), a.Data, a.CreatedAt,
+			); err != nil {
+				return result, fmt.Errorf("event %d: failed to insert attachment %q: %w", event.ID, a.Filename, err)
+			}
+		}
+	}
+
+	// Events inserted with their exported IDs don't advance the sequence
+	if strategy != models.ImportNewID {
+		if _, err := tx.Exec("SELECT setval(pg_get_serial_sequence('events', 'id'), GREATEST((SELECT MAX(id) FROM events), 1))"); err != nil {
+			return result, fmt.Errorf("failed to advance event ID sequence: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// importEvent stores one event and sets its ID to the one it was stored
+// under. It returns "" if the event was skipped, models.ImportOverwrite if it
+// replaced a stored event and models.ImportNewID otherwise.
+func importEvent(tx *sql.Tx, event *models.ExportedEvent, strategy string) (string, error) {
+	event.Tags = normalizeTags(event.Tags)
+	tagsJSON, err := json.Marshal(event.Tags)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	var messageID sql.NullString
+	var emailJSON []byte
+	if event.Email != nil {
+		if emailJSON, err = json.Marshal(event.Email); err != nil {
+			return "", fmt.Errorf("failed to marshal email metadata: %w", err)
+		}
+		messageID = sql.NullString{String: event.Email.MessageID, Valid: event.Email.MessageID != ""}
+	}
+	data := strings.TrimRight(event.Data, "\r\n")
+	metadataJSON, err := metadataParam(event.Metadata)
+	if err != nil {
+		return "", err
+	}
+	// Labels this database doesn't define are dropped
+	labelsJSON, err := labelsParam(event.Labels)
+	if err != nil {
+		return "", err
+	}
+	// Events of projects this database doesn't have go to the default one
+	project := projectID(event.ProjectID)
+	status := eventStatus(event.Status)
+
+	if strategy == models.ImportNewID {
+		err := tx.QueryRow(
+			"INSERT INTO events (tags, data, source, html_body, message_id, email, created_at, metadata, project_id, status, due_at, labels, location, latitude, longitude) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE((SELECT id FROM projects WHERE id = $9), 1), $10, $11, "+knownLabels(12)+", $13, $14, $15) ON CONFLICT (project_id, message_id) DO NOTHING RETURNING id",
+			string(tagsJSON), data, event.Source, event.HTMLBody, messageID, jsonParam(emailJSON), event.CreatedAt, metadataJSON, project, status, dueParam(event.DueAt), labelsJSON, event.Location, event.Latitude, event.Longitude,
+		).Scan(&event.ID)
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to insert event: %w", err)
+		}
+		return models.ImportNewID, nil
+	}
+
+	var exists, messageIDTaken bool
+	err = tx.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM events WHERE id = $1), EXISTS (SELECT 1 FROM events WHERE message_id = $2 AND project_id = COALESCE((SELECT id FROM projects WHERE id = $3), 1) AND id <> $1)",
+		event.ID, messageID, project,
+	).Scan(&exists, &messageIDTaken)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up event: %w", err)
+	}
+	if messageIDTaken || (exists && strategy == models.ImportSkip) {
+		return "", nil
+	}
+
+	if !exists {
+		if _, err := tx.Exec(
+			"INSERT INTO events (id, tags, data, source, html_body, message_id, email, created_at, metadata, project_id, status, due_at, labels, location, latitude, longitude) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE((SELECT id FROM projects WHERE id = $10), 1), $11, $12, "+knownLabels(13)+", $14, $15, $16)",
+			event.ID, string(tagsJSON), data, event.Source, event.HTMLBody, messageID, jsonParam(emailJSON), event.CreatedAt, metadataJSON, project, status, dueParam(event.DueAt), labelsJSON, event.Location, event.Latitude, event.Longitude,
+		); err != nil {
+			return "", fmt.Errorf("failed to insert event: %w", err)
+		}
+		return models.ImportNewID, nil
+	}
+
+	// Overwrite: the export's logs and attachments replace the stored ones
+	if _, err := tx.Exec("DELETE FROM event_logs WHERE event_id = $1", event.ID); err != nil {
+		return "", fmt.Errorf("failed to delete event logs: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM attachments WHERE event_id = $1", event.ID); err != nil {
+		return "", fmt.Errorf("failed to delete attachments: %w", err)
+	}
+	if _, err := tx.Exec(
+		"UPDATE events SET tags = $2, data = $3, source = $4, html_body = $5, message_id = $6, email = $7, created_at = $8, metadata = $9, project_id = COALESCE((SELECT id FROM projects WHERE id = $10), 1), status = $11, due_at = $12, due_reminder = '', labels = "+knownLabels(13)+", location = $14, latitude = $15, longitude = $16 WHERE id = $1",
+		event.ID, string(tagsJSON), data, event.Source, event.HTMLBody, messageID, jsonParam(emailJSON), event.CreatedAt, metadataJSON, project, status, dueParam(event.DueAt), labelsJSON, event.Location, event.Latitude, event.Longitude,
+	); err != nil {
+		return "", fmt.Errorf("failed to update event: %w", err)
+	}
+	return models.ImportOverwrite, nil
+}
@@ -0,0 +1,272 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// incidentSummaryLength caps the length of incident summaries, in characters
+const incidentSummaryLength = 200
+
+const incidentColumns = `id, project_id, source, tags, summary, first_seen, last_seen,
+	(SELECT COUNT(*) FROM events WHERE incident_id = incidents.id)`
+
+// GroupIncidents groups every event stored through StoreEvent, StoreEvents
+// or InsertEvents into an incident with the similar events stored within
+// window before it
+func (d *Database) GroupIncidents(window time.Duration) {
+	d.OnEventStored(func(event *models.Event) {
+		if _, err := d.GroupEvent(event, window); err != nil {
+			slog.Error("Failed to group event into an incident", "event_id", event.ID, "error", err)
+		}
+	})
+}
+
+// GroupEvent adds a stored event to the incident of the events with the
+// same project, source and tags, if the last of them was stored within
+// window before it. Without such an incident, one is started when an
+// ungrouped similar event was stored within window. It returns the
+// incident's ID, or 0 when the event stays alone.
+func (d *Database) GroupEvent(event *models.Event, window time.Duration) (int64, error) {
+	defer d.events.invalidate(event.ID)
+	fingerprint := incidentFingerprint(event)
+	tagsJSON, err := json.Marshal(normalizeTags(event.Tags))
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	seen := event.CreatedAt.UTC()
+	since := seen.Add(-window)
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Similar events stored at the same time must not start two incidents
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", fingerprint); err != nil {
+		return 0, fmt.Errorf("failed to lock incident: %w", err)
+	}
+
+	var incidentID int64
+	err = tx.QueryRow(
+		"SELECT id FROM incidents WHERE fingerprint = $1 AND last_seen >= $2 ORDER BY last_seen DESC LIMIT 1",
+		fingerprint, since,
+	).Scan(&incidentID)
+	switch {
+	case err == nil:
+		if _, err := tx.Exec("UPDATE incidents SET last_seen = GREATEST(last_seen, $1) WHERE id = $2", seen, incidentID); err != nil {
+			return 0, fmt.Errorf("failed to update incident: %w", err)
+		}
+	case err == sql.ErrNoRows:
+		var previousID int64
+		var data string
+		var firstSeen time.Time
+		err = tx.QueryRow(
+			`SELECT id, data, created_at FROM events
+			WHERE project_id = $1 AND lower(source) = lower($2) AND tags @> $3::jsonb AND tags <@ $3::jsonb
+				AND incident_id IS NULL AND created_at >= $4 AND id <> $5
+			ORDER BY created_at DESC, id DESC LIMIT 1`,
+			projectID(event.ProjectID), event.Source, string(tagsJSON), since, event.ID,
+		).Scan(&previousID, &data, &firstSeen)
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to find similar events: %w", err)
+		}
+		err = tx.QueryRow(
+			`INSERT INTO incidents (fingerprint, project_id, source, tags, summary, first_seen, last_seen)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			fingerprint, projectID(event.ProjectID), event.Source, string(tagsJSON), incidentSummary(data), firstSeen, seen,
+		).Scan(&incidentID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert incident: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE events SET incident_id = $1 WHERE id = $2", incidentID, previousID); err != nil {
+			return 0, fmt.Errorf("failed to group event: %w", err)
+		}
+		d.events.invalidate(previousID)
+	default:
+		return 0, fmt.Errorf("failed to find incident: %w", err)
+	}
+
+	if _, err := tx.Exec("UPDATE events SET incident_id = $1 WHERE id = $2", incidentID, event.ID); err != nil {
+		return 0, fmt.Errorf("failed to group event: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return incidentID, nil
+}
+
+// GetIncident retrieves an incident by ID, or nil if there is none
+func (d *Database) GetIncident(id int64) (*models.Incident, error) {
+	incident, err := scanIncident(d.db.QueryRow("SELECT "+incidentColumns+" FROM incidents WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	return incident, nil
+}
+
+// EventIncident retrieves the incident an event is grouped in, or nil if it
+// isn't grouped
+func (d *Database) EventIncident(eventID int64) (*models.Incident, error) {
+	incident, err := scanIncident(d.db.QueryRow(
+		"SELECT "+incidentColumns+" FROM incidents WHERE id = (SELECT incident_id FROM events WHERE id = $1)", eventID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident of event: %w", err)
+	}
+	return incident, nil
+}
+
+// ListIncidents retrieves a page of the incidents that still have events,
+// most recently active first, along with their total. Only incidents in
+// projects are listed, unless projects is nil.
+func (d *Database) ListIncidents(projects []int64, limit, offset int) ([]models.Incident, int, error) {
+	where := "WHERE EXISTS (SELECT 1 FROM events WHERE incident_id = incidents.id)"
+	var args []interface{}
+	if projects != nil {
+		args = append(args, pq.Array(projects))
+		where += " AND project_id = ANY($1)"
+	}
+
+	var total int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM incidents "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count incidents: %w", err)
+	}
+
+	args = append(args, limit, offset)
+	rows, err := d.db.Query(
+		fmt.Sprintf("SELECT %s FROM incidents %s ORDER BY last_seen DESC, id DESC LIMIT $%d OFFSET $%d",
+			incidentColumns, where, len(args)-1, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := []models.Incident{}
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan incident row: %w", err)
+		}
+		incidents = append(incidents, *incident)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return incidents, total, nil
+}
+
+// MarkIncidents sets the incident of each event grouped in one, and how many
+// events the incident holds
+func (d *Database) MarkIncidents(events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	rows, err := d.db.Query(
+		`SELECT e.id, e.incident_id, (SELECT COUNT(*) FROM events WHERE incident_id = e.incident_id)
+		FROM events e WHERE e.id = ANY($1) AND e.incident_id IS NOT NULL`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query incidents of events: %w", err)
+	}
+	defer rows.Close()
+
+	type membership struct {
+		incident int64
+		size     int
+	}
+	grouped := make(map[int64]membership)
+	for rows.Next() {
+		var id int64
+		var m membership
+		if err := rows.Scan(&id, &m.incident, &m.size); err != nil {
+			return fmt.Errorf("failed to scan incident row: %w", err)
+		}
+		grouped[id] = m
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	for i := range events {
+		m := grouped[events[i].ID]
+		events[i].IncidentID, events[i].IncidentSize = m.incident, m.size
+	}
+	return nil
+}
+
+// DeleteEmptyIncidents removes the incidents whose events were all deleted,
+// returning how many there were
+func (d *Database) DeleteEmptyIncidents() (int64, error) {
+	result, err := d.db.Exec("DELETE FROM incidents WHERE NOT EXISTS (SELECT 1 FROM events WHERE incident_id = incidents.id)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete empty incidents: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n, nil
+}
+
+func scanIncident(row rowScanner) (*models.Incident, error) {
+	var incident models.Incident
+	var tagsJSON string
+
+	if err := row.Scan(&incident.ID, &incident.ProjectID, &incident.Source, &tagsJSON, &incident.Summary,
+		&incident.FirstSeen, &incident.LastSeen, &incident.EventCount); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tagsJSON), &incident.Tags); err != nil {
+		return nil, fmt.Errorf("failed to parse tags: %w", err)
+	}
+	return &incident, nil
+}
+
+// incidentFingerprint identifies the events an event is similar to: those of
+// its project with the same source, ignoring case, and the same tags in any
+// order
+func incidentFingerprint(event *models.Event) string {
+	tags := normalizeTags(event.Tags)
+	sort.Strings(tags)
+	return fmt.Sprintf("%d|%s|%s", projectID(event.ProjectID), strings.ToLower(event.Source), strings.Join(tags, ","))
+}
+
+// incidentSummary returns the first non-empty line of an event's data,
+// shortened to incidentSummaryLength
+func incidentSummary(data string) string {
+	for _, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if runes := []rune(line); len(runes) > incidentSummaryLength {
+				return string(runes[:incidentSummaryLength]) + "…"
+			}
+			return line
+		}
+	}
+	return "(no data)"
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+	"time"
+)
+
+// CreateInviteCode stores a new invite code
+func (d *Database) CreateInviteCode(invite *models.InviteCode) error {
+	err := d.db.QueryRow(
+		`INSERT INTO invite_codes (code, created_by, role, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		invite.Code,
+		invite.CreatedBy,
+		invite.Role,
+		invite.ExpiresAt,
+	).Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert invite code: %w", err)
+	}
+	return nil
+}
+
+// GetInviteCode retrieves an invite code by its code value
+func (d *Database) GetInviteCode(code string) (*models.InviteCode, error) {
+	invite, err := scanInviteCode(d.db.QueryRow(
+		`SELECT id, code, created_by, role, expires_at, used_at, used_by, created_at
+		FROM invite_codes
+		WHERE code = $1`,
+		code,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite code: %w", err)
+	}
+	return invite, nil
+}
+
+// RedeemInviteCode marks an invite code as used, failing if it was already used or expired
+func (d *Database) RedeemInviteCode(code, username string) error {
+	result, err := d.db.Exec(
+		`UPDATE invite_codes SET used_at = $1, used_by = $2
+		WHERE code = $3 AND used_at IS NULL AND expires_at > $1`,
+		time.Now(),
+		username,
+		code,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to redeem invite code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("invite code is invalid, expired or already used")
+	}
+
+	return nil
+}
+
+// ListInviteCodes retrieves all invite codes, newest first
+func (d *Database) ListInviteCodes() ([]models.InviteCode, error) {
+	rows, err := d.db.Query(
+		`SELECT id, code, created_by, role, expires_at, used_at, used_by, created_at
+		FROM invite_codes
+		ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invite codes: %w", err)
+	}
+	defer rows.Close()
+
+	var invites []models.InviteCode
+	for rows.Next() {
+		invite, err := scanInviteCode(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan invite code row: %w", err)
+		}
+		invites = append(invites, *invite)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return invites, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInviteCode(row rowScanner) (*models.InviteCode, error) {
+	var invite models.InviteCode
+	var usedAt sql.NullTime
+	var usedBy sql.NullString
+
+	if err := row.Scan(&invite.ID, &invite.Code, &invite.CreatedBy, &invite.Role, &invite.ExpiresAt, &usedAt, &usedBy, &invite.CreatedAt); err != nil {
+		return nil, err
+	}
+	invite.UsedAt = usedAt.Time
+	invite.UsedBy = usedBy.String
+
+	return &invite, nil
+}
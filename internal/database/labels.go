@@ -0,0 +1,154 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// ErrLabelExists is returned by CreateLabel when a label already has the name
+var ErrLabelExists = errors.New("label exists")
+
+const labelColumns = `id, name, color, description, created_at`
+
+// CreateLabel defines a label, setting its ID and creation time
+func (d *Database) CreateLabel(label *models.Label) error {
+	err := d.db.QueryRow(
+		`INSERT INTO labels (name, color, description) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO NOTHING RETURNING id, created_at`,
+		label.Name, label.Color, label.Description,
+	).Scan(&label.ID, &label.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrLabelExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create label: %w", err)
+	}
+	return nil
+}
+
+// ListLabels retrieves the labels by name
+func (d *Database) ListLabels() ([]models.Label, error) {
+	rows, err := d.db.Query("SELECT " + labelColumns + " FROM labels ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query labels: %w", err)
+	}
+	defer rows.Close()
+
+	var labels []models.Label
+	for rows.Next() {
+		var label models.Label
+		if err := rows.Scan(&label.ID, &label.Name, &label.Color, &label.Description, &label.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan label row: %w", err)
+		}
+		labels = append(labels, label)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return labels, nil
+}
+
+// UpdateLabel changes the color and description of a label, returning false
+// if there is no label with its ID. Names can't be changed, since events
+// keep them.
+func (d *Database) UpdateLabel(label *models.Label) (bool, error) {
+	err := d.db.QueryRow(
+		"UPDATE labels SET color = $1, description = $2 WHERE id = $3 RETURNING name, created_at",
+		label.Color, label.Description, label.ID,
+	).Scan(&label.Name, &label.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update label: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteLabel removes a label and takes it off every event. It returns the
+// removed label, or nil if there was none with the ID.
+func (d *Database) DeleteLabel(id int64) (*models.Label, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var label models.Label
+	err = tx.QueryRow("DELETE FROM labels WHERE id = $1 RETURNING "+labelColumns, id).
+		Scan(&label.ID, &label.Name, &label.Color, &label.Description, &label.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete label: %w", err)
+	}
+
+	if _, err := tx.Exec("UPDATE events SET labels = labels - $1 WHERE labels ? $1", label.Name); err != nil {
+		return nil, fmt.Errorf("failed to remove label from events: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	d.events.clear()
+	return &label, nil
+}
+
+// SetEventLabels replaces the labels of an event with the named ones, which
+// must be defined, returning false if there is no such event
+func (d *Database) SetEventLabels(id int64, labels []string) (bool, error) {
+	labelsJSON, err := labelsParam(labels)
+	if err != nil {
+		return false, err
+	}
+	defer d.events.invalidate(id)
+	result, err := d.db.Exec("UPDATE events SET labels = $1::jsonb WHERE id = $2", labelsJSON, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to set event labels: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// knownLabels is the SQL expression keeping the labels of the JSON array
+// parameter $n that are defined, for events imported or restored after
+// their labels may have been deleted
+func knownLabels(n int) string {
+	return fmt.Sprintf("(SELECT COALESCE(jsonb_agg(name ORDER BY name), '[]') FROM labels WHERE $%d::jsonb ? name)", n)
+}
+
+// labelsParam encodes label names for the labels column
+func labelsParam(labels []string) (string, error) {
+	if len(labels) == 0 {
+		return "[]", nil
+	}
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal labels: %w", err)
+	}
+	return string(data), nil
+}
+
+// parseLabels decodes the labels column, leaving events without labels with
+// nil Labels
+func parseLabels(data []byte) ([]string, error) {
+	var labels []string
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("failed to parse labels: %w", err)
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// ErrLinkExists is returned when linking two events the same way twice
+var ErrLinkExists = errors.New("link already exists")
+
+// AddEventLink links an event to another, setting the link's ID and creation
+// time. It returns ErrLinkExists if the events are already linked that way.
+func (d *Database) AddEventLink(link *models.EventLink) error {
+	err := d.db.QueryRow(
+		`INSERT INTO event_links (event_id, target_id, type, created_by) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id, target_id, type) DO NOTHING
+		RETURNING id, created_at`,
+		link.EventID, link.TargetID, link.Type, link.CreatedBy,
+	).Scan(&link.ID, &link.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrLinkExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add event link: %w", err)
+	}
+	return nil
+}
+
+// GetEventLinks retrieves the links from and to an event, oldest first, each
+// with the event at its other end
+func (d *Database) GetEventLinks(eventID int64) ([]models.EventLink, error) {
+	rows, err := d.db.Query(
+		`SELECT l.id, l.event_id, l.target_id, l.type, l.created_by, l.created_at, l.event_id <> $1,
+			e.id, e.tags, e.data, e.source, e.created_at, e.project_id, e.status
+		FROM event_links l
+		JOIN events e ON e.id = CASE WHEN l.event_id = $1 THEN l.target_id ELSE l.event_id END
+		WHERE l.event_id = $1 OR l.target_id = $1
+		ORDER BY l.created_at, l.id`,
+		eventID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query event links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []models.EventLink
+	for rows.Next() {
+		var link models.EventLink
+		var other models.Event
+		var tagsJSON string
+		if err := rows.Scan(&link.ID, &link.EventID, &link.TargetID, &link.Type, &link.CreatedBy, &link.CreatedAt, &link.Incoming,
+			&other.ID, &tagsJSON, &other.Data, &other.Source, &other.CreatedAt, &other.ProjectID, &other.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan event link row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(tagsJSON), &other.Tags); err != nil {
+			return nil, fmt.Errorf("failed to parse tags: %w", err)
+		}
+		link.Event = &other
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return links, nil
+}
+
+// DeleteEventLink removes a link from or to an event, returning false if the
+// event has no such link
+func (d *Database) DeleteEventLink(eventID, id int64) (bool, error) {
+	result, err := d.db.Exec("DELETE FROM event_links WHERE id = $1 AND (event_id = $2 OR target_id = $2)", id, eventID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete event link: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n > 0, nil
+}
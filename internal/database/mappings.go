@@ -0,0 +1,164 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const mappingColumns = `id, owner, generated_email, tags, source, endpoint_url, description, is_active, project_id, created_at, last_used_at`
+
+// CreateEmailMapping stores a new email mapping
+func (d *Database) CreateEmailMapping(mapping *models.EmailMapping) error {
+	tagsJSON, err := json.Marshal(nonNilTags(mapping.Tags))
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	err = d.db.QueryRow(
+		`INSERT INTO email_mappings (owner, generated_email, tags, source, endpoint_url, description, is_active, project_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		mapping.Owner,
+		strings.ToLower(mapping.GeneratedEmail),
+		string(tagsJSON),
+		mapping.Source,
+		mapping.EndpointURL,
+		mapping.Description,
+		mapping.IsActive,
+		projectID(mapping.ProjectID),
+	).Scan(&mapping.ID, &mapping.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert email mapping: %w", err)
+	}
+	return nil
+}
+
+// GetEmailMapping retrieves a mapping by ID, or nil if there is none
+func (d *Database) GetEmailMapping(id int64) (*models.EmailMapping, error) {
+	mapping, err := scanEmailMapping(d.db.QueryRow(
+		"SELECT "+mappingColumns+" FROM email_mappings WHERE id = $1",
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// GetEmailMappingByAddress retrieves the mapping for a generated address, or
+// nil if there is none
+func (d *Database) GetEmailMappingByAddress(address string) (*models.EmailMapping, error) {
+	mapping, err := scanEmailMapping(d.db.QueryRow(
+		"SELECT "+mappingColumns+" FROM email_mappings WHERE generated_email = $1",
+		strings.ToLower(strings.TrimSpace(address)),
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get email mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// ListEmailMappings retrieves the mappings owned by owner, or every mapping
+// when owner is empty, newest first
+func (d *Database) ListEmailMappings(owner string) ([]models.EmailMapping, error) {
+	rows, err := d.db.Query(
+		"SELECT "+mappingColumns+" FROM email_mappings WHERE $1 = '' OR owner = $1 ORDER BY created_at DESC",
+		owner,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email mappings: %w", err)
+	}
+	defer rows.Close()
+
+	var mappings []models.EmailMapping
+	for rows.Next() {
+		mapping, err := scanEmailMapping(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan email mapping row: %w", err)
+		}
+		mappings = append(mappings, *mapping)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return mappings, nil
+}
+
+// UpdateEmailMapping saves the tags, source, endpoint, description, active
+// flag and project of a mapping
+func (d *Database) UpdateEmailMapping(mapping *models.EmailMapping) error {
+	tagsJSON, err := json.Marshal(nonNilTags(mapping.Tags))
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	_, err = d.db.Exec(
+		`UPDATE email_mappings
+		SET tags = $1, source = $2, endpoint_url = $3, description = $4, is_active = $5, project_id = $6
+		WHERE id = $7`,
+		string(tagsJSON),
+		mapping.Source,
+		mapping.EndpointURL,
+		mapping.Description,
+		mapping.IsActive,
+		projectID(mapping.ProjectID),
+		mapping.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update email mapping: %w", err)
+	}
+	return nil
+}
+
+// DeleteEmailMapping removes a mapping
+func (d *Database) DeleteEmailMapping(id int64) error {
+	if _, err := d.db.Exec("DELETE FROM email_mappings WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete email mapping: %w", err)
+	}
+	return nil
+}
+
+// TouchEmailMapping records that mail was received for a mapping
+func (d *Database) TouchEmailMapping(id int64) error {
+	if _, err := d.db.Exec("UPDATE email_mappings SET last_used_at = $1 WHERE id = $2", time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update email mapping: %w", err)
+	}
+	return nil
+}
+
+func scanEmailMapping(row rowScanner) (*models.EmailMapping, error) {
+	var mapping models.EmailMapping
+	var tagsJSON string
+	var lastUsedAt sql.NullTime
+
+	if err := row.Scan(&mapping.ID, &mapping.Owner, &mapping.GeneratedEmail, &tagsJSON, &mapping.Source,
+		&mapping.EndpointURL, &mapping.Description, &mapping.IsActive, &mapping.ProjectID, &mapping.CreatedAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tagsJSON), &mapping.Tags); err != nil {
+		return nil, fmt.Errorf("failed to parse tags: %w", err)
+	}
+	mapping.LastUsedAt = lastUsedAt.Time
+
+	return &mapping, nil
+}
+
+// nonNilTags returns tags, or an empty list so it is stored as [] rather than null
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
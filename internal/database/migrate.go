@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Migrate runs the .sql files in dir that haven't run yet, in name order,
+// and records each in schema_migrations, so the servers' /readyz can tell
+// whether the schema is current. Files already recorded are skipped: a later
+// migration may have changed what an earlier one set up, such as 039 making
+// Message-IDs unique per project, and running the earlier one again would
+// undo it.
+func (d *Database) Migrate(dir string) error {
+	if _, err := d.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		name TEXT PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("failed to list migration files: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations found in %s", dir)
+	}
+	sort.Strings(files)
+
+	applied, err := d.AppliedMigrations(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if applied[filepath.Base(file)] {
+			continue
+		}
+		log.Printf("Running migration: %s", file)
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration file %s: %w", file, err)
+		}
+		if _, err := d.db.Exec(string(migration)); err != nil {
+			return fmt.Errorf("failed to execute migration %s: %w", file, err)
+		}
+		if _, err := d.db.Exec("INSERT INTO schema_migrations (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", filepath.Base(file)); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", file, err)
+		}
+	}
+	return nil
+}
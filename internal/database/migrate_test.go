@@ -0,0 +1,67 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDatabase connects to the database named by EVENTDB_TEST_DATABASE_URL
+// and migrates it, skipping the test when it isn't set
+func testDatabase(t *testing.T) *Database {
+	t.Helper()
+	connStr := os.Getenv("EVENTDB_TEST_DATABASE_URL")
+	if connStr == "" {
+		t.Skip("EVENTDB_TEST_DATABASE_URL is not set")
+	}
+	db, err := NewPostgres(connStr)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate("../../migrations"); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// Running the migrations again must not undo later ones, such as 012
+// clearing the Message-IDs that 039 allows to repeat across projects
+func TestMigrateAgainKeepsMessageIDsPerProject(t *testing.T) {
+	db := testDatabase(t)
+
+	project := &models.Project{Slug: fmt.Sprintf("migrate-%d", time.Now().UnixNano()), Name: "Migrate"}
+	if err := db.CreateProject(project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	messageID := fmt.Sprintf("migrate-%d@test", time.Now().UnixNano())
+	var ids []int64
+	for _, projectID := range []int64{models.DefaultProjectID, project.ID} {
+		event, err := db.StoreEvent(&models.EventRequest{
+			Data:      "Same email, two projects",
+			Source:    "test",
+			Email:     &models.EmailMetadata{MessageID: messageID},
+			ProjectID: projectID,
+		})
+		if err != nil {
+			t.Fatalf("failed to store event in project %d: %v", projectID, err)
+		}
+		ids = append(ids, event.ID)
+	}
+
+	if err := db.Migrate("../../migrations"); err != nil {
+		t.Fatalf("failed to migrate again: %v", err)
+	}
+
+	for i, projectID := range []int64{models.DefaultProjectID, project.ID} {
+		event, err := db.GetEventByMessageID(projectID, messageID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event == nil || event.ID != ids[i] {
+			t.Errorf("project %d lost the event %d of message %s", projectID, ids[i], messageID)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// MonitorActivity returns when the monitor last had a heartbeat stored from
+// source, and when the newest other event from source was stored
+func (d *Database) MonitorActivity(monitor, source string) (*models.MonitorActivity, error) {
+	var activity models.MonitorActivity
+	err := d.db.QueryRow(
+		`SELECT id, created_at FROM events
+		WHERE lower(source) = lower($1) AND tags ?& $2
+		ORDER BY created_at DESC LIMIT 1`,
+		source, pq.Array([]string{models.HeartbeatTag, monitor}),
+	).Scan(&activity.HeartbeatID, &activity.LastHeartbeat)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query heartbeats of monitor %s: %w", monitor, err)
+	}
+
+	err = d.db.QueryRow(
+		`SELECT created_at FROM events
+		WHERE lower(source) = lower($1) AND NOT tags ? $2
+		ORDER BY created_at DESC LIMIT 1`,
+		source, models.HeartbeatTag,
+	).Scan(&activity.LastEvent)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query events from %s: %w", source, err)
+	}
+	return &activity, nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"example-api/internal/cache"
+	"log"
+	"sync"
+	"time"
+)
+
+// defaultNameCacheTTL bounds how long the tag and source lists can miss
+// changes made by other processes
+const defaultNameCacheTTL = time.Minute
+
+// nameCacheKeys are the cache keys of the lists, dropped by invalidate
+var nameCacheKeys = []string{"names:tags", "names:sources"}
+
+// nameCache keeps the distinct tags and sources shown in filter dropdowns.
+// Writes through this Database invalidate it. Changes made by other
+// processes show up once the entries expire, or straight away when the
+// processes share a Redis cache.
+type nameCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration // Zero disables caching
+	generation uint64        // Bumped by invalidate
+	store      cache.Cache
+}
+
+// SetNameCacheTTL sets how long the lists of tags and sources are cached.
+// Zero turns the cache off.
+func (d *Database) SetNameCacheTTL(ttl time.Duration) {
+	d.names.mu.Lock()
+	d.names.ttl = ttl
+	d.names.mu.Unlock()
+	d.names.invalidate()
+}
+
+// SetCache sets where the lists of tags and sources are cached; by default
+// each Database keeps its own in memory
+func (d *Database) SetCache(c cache.Cache) {
+	d.names.mu.Lock()
+	defer d.names.mu.Unlock()
+	d.names.store = c
+	d.names.generation++
+}
+
+// Cache returns where the lists of tags and sources are cached, for other
+// values to share it
+func (d *Database) Cache() cache.Cache {
+	d.names.mu.Lock()
+	defer d.names.mu.Unlock()
+	if d.names.store == nil {
+		d.names.store = cache.NewMemory()
+	}
+	return d.names.store
+}
+
+// get returns the cached list under key ("names:tags" or "names:sources"),
+// calling load when it is missing or expired. The caller gets its own copy.
+func (c *nameCache) get(key string, load func() ([]string, error)) ([]string, error) {
+	c.mu.Lock()
+	if c.store == nil {
+		c.store = cache.NewMemory()
+	}
+	store, ttl, generation := c.store, c.ttl, c.generation
+	c.mu.Unlock()
+	if ttl <= 0 {
+		return load()
+	}
+
+	ctx := context.Background()
+	var names []string
+	if ok, err := cache.GetJSON(ctx, store, key, &names); err != nil {
+		log.Printf("Warning: failed to read %s from the cache: %v", key, err)
+	} else if ok {
+		return names, nil
+	}
+
+	names, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	// A write during the load may have made the result stale already
+	c.mu.Lock()
+	stale := c.generation != generation
+	c.mu.Unlock()
+	if !stale {
+		if err := cache.SetJSON(ctx, store, key, names, ttl); err != nil {
+			log.Printf("Warning: failed to cache %s: %v", key, err)
+		}
+	}
+	return names, nil
+}
+
+// invalidate drops the cached lists after events are written
+func (c *nameCache) invalidate() {
+	c.mu.Lock()
+	c.generation++
+	store := c.store
+	c.mu.Unlock()
+	if store == nil {
+		return
+	}
+	if err := store.Delete(context.Background(), nameCacheKeys...); err != nil {
+		log.Printf("Warning: failed to drop the cached tags and sources: %v", err)
+	}
+}
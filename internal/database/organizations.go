@@ -0,0 +1,309 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+	"time"
+)
+
+// ErrOrganizationExists is returned when creating an organization whose
+// slug is taken
+var ErrOrganizationExists = errors.New("organization already exists")
+
+// ErrOrganizationInUse is returned when deleting the default organization,
+// or one that still has projects
+var ErrOrganizationInUse = errors.New("organization is in use")
+
+const organizationColumns = `id, slug, name, created_at`
+
+// CreateOrganization stores a new organization, returning
+// ErrOrganizationExists if its slug is taken
+func (d *Database) CreateOrganization(org *models.Organization) error {
+	err := d.db.QueryRow(
+		`INSERT INTO organizations (slug, name) VALUES ($1, $2)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING id, created_at`,
+		org.Slug, org.Name,
+	).Scan(&org.ID, &org.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrOrganizationExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	return nil
+}
+
+// GetOrganization retrieves an organization by ID, or nil if there is none
+func (d *Database) GetOrganization(id int64) (*models.Organization, error) {
+	org, err := scanOrganization(d.db.QueryRow("SELECT "+organizationColumns+" FROM organizations WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return org, nil
+}
+
+// ListOrganizations retrieves every organization, the default one first and
+// the others by name
+func (d *Database) ListOrganizations() ([]models.Organization, error) {
+	rows, err := d.db.Query("SELECT "+organizationColumns+" FROM organizations ORDER BY id <> $1, lower(name), id", models.DefaultOrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization row: %w", err)
+		}
+		orgs = append(orgs, *org)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// DeleteOrganization removes an organization without projects, with its
+// members and API tokens. It returns ErrOrganizationInUse for the default
+// organization and organizations that still have projects, and false if
+// there is no such organization.
+func (d *Database) DeleteOrganization(id int64) (bool, error) {
+	if id == models.DefaultOrganizationID {
+		return false, ErrOrganizationInUse
+	}
+	result, err := d.db.Exec(
+		`DELETE FROM organizations WHERE id = $1
+		AND NOT EXISTS (SELECT 1 FROM projects WHERE organization_id = $1)`,
+		id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete organization: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+
+	org, err := d.GetOrganization(id)
+	if err != nil || org == nil {
+		return false, err
+	}
+	return false, ErrOrganizationInUse
+}
+
+// SetOrganizationMember gives a user a role in an organization, replacing
+// any role they had there
+func (d *Database) SetOrganizationMember(member *models.OrganizationMember) error {
+	err := d.db.QueryRow(
+		`INSERT INTO organization_members (organization_id, username, role) VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, username) DO UPDATE SET role = EXCLUDED.role
+		RETURNING created_at`,
+		member.OrganizationID, member.Username, member.Role,
+	).Scan(&member.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set organization member: %w", err)
+	}
+	return nil
+}
+
+// RemoveOrganizationMember takes a user's role in an organization away
+func (d *Database) RemoveOrganizationMember(orgID int64, username string) error {
+	if _, err := d.db.Exec("DELETE FROM organization_members WHERE organization_id = $1 AND username = $2", orgID, username); err != nil {
+		return fmt.Errorf("failed to remove organization member: %w", err)
+	}
+	return nil
+}
+
+// OrganizationMembers retrieves the members of an organization by username
+func (d *Database) OrganizationMembers(orgID int64) ([]models.OrganizationMember, error) {
+	rows, err := d.db.Query(
+		"SELECT organization_id, username, role, created_at FROM organization_members WHERE organization_id = $1 ORDER BY username",
+		orgID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organization members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []models.OrganizationMember
+	for rows.Next() {
+		var member models.OrganizationMember
+		if err := rows.Scan(&member.OrganizationID, &member.Username, &member.Role, &member.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization member row: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return members, nil
+}
+
+// OrganizationProjectIDs returns the IDs of the projects of an organization
+func (d *Database) OrganizationProjectIDs(orgID int64) ([]int64, error) {
+	rows, err := d.db.Query("SELECT id FROM projects WHERE organization_id = $1", orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organization projects: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan project row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return ids, nil
+}
+
+// TokenAccess returns what a project or organization API token can do: its
+// role in its project, or in every project of its organization
+func (d *Database) TokenAccess(token *models.APIToken) (*models.ProjectAccess, error) {
+	access := &models.ProjectAccess{Roles: map[int64]string{}}
+	if token.ProjectID != 0 {
+		access.Roles[token.ProjectID] = token.Role
+		return access, nil
+	}
+
+	ids, err := d.OrganizationProjectIDs(token.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		access.Roles[id] = token.Role
+	}
+	return access, nil
+}
+
+// organizationAccess adds a user's organization roles to their access, and
+// the project roles that come with them. Roles from organizations only ever
+// raise the user's role in a project.
+func (d *Database) organizationAccess(username string, access *models.ProjectAccess) error {
+	rows, err := d.db.Query(
+		`SELECT m.organization_id, m.role, p.id
+		FROM organization_members m
+		LEFT JOIN projects p ON p.organization_id = m.organization_id
+		WHERE m.username = $1`,
+		username,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query organization roles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orgID int64
+		var role string
+		var projectID sql.NullInt64
+		if err := rows.Scan(&orgID, &role, &projectID); err != nil {
+			return fmt.Errorf("failed to scan organization role row: %w", err)
+		}
+		access.Organizations[orgID] = role
+		if !projectID.Valid {
+			continue
+		}
+		switch {
+		case role == models.OrganizationRoleAdmin:
+			access.Roles[projectID.Int64] = models.ProjectEditor
+		case access.Roles[projectID.Int64] == "":
+			access.Roles[projectID.Int64] = models.ProjectViewer
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return nil
+}
+
+// OrganizationUsage returns how many events the projects of an organization
+// store and how large they are, the number of events created per day over
+// the last days days, and how many members and API tokens it has
+func (d *Database) OrganizationUsage(orgID int64, days int) (*models.OrganizationUsage, error) {
+	usage := &models.OrganizationUsage{OrganizationID: orgID}
+	dayAgo := time.Now().Add(-24 * time.Hour)
+
+	rows, err := d.db.Query(
+		`SELECT p.id, p.slug, p.name, COUNT(e.id), COUNT(e.id) FILTER (WHERE e.created_at >= $2),
+			COALESCE(SUM(pg_column_size(e.*)), 0), MAX(e.created_at)
+		FROM projects p
+		LEFT JOIN events e ON e.project_id = p.id
+		WHERE p.organization_id = $1
+		GROUP BY p.id
+		ORDER BY p.id <> $3, lower(p.name), p.id`,
+		orgID, dayAgo, models.DefaultProjectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var project models.ProjectUsage
+		if err := rows.Scan(&project.ProjectID, &project.Slug, &project.Name, &project.Events, &project.LastDay,
+			&project.Size, &project.NewestEvent); err != nil {
+			return nil, fmt.Errorf("failed to scan project usage: %w", err)
+		}
+		usage.Projects = append(usage.Projects, project)
+		usage.Events += project.Events
+		usage.LastDay += project.LastDay
+		usage.Size += project.Size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	err = d.db.QueryRow(
+		`SELECT (SELECT COUNT(*) FROM organization_members WHERE organization_id = $1),
+			(SELECT COUNT(*) FROM api_tokens WHERE organization_id = $1),
+			(SELECT COUNT(*) FROM api_tokens WHERE organization_id = $1 AND last_used_at >= $2)`,
+		orgID, dayAgo,
+	).Scan(&usage.Members, &usage.Tokens, &usage.TokensUsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count organization members and tokens: %w", err)
+	}
+
+	start, today := lastDays(days)
+	dayRows, err := d.db.Query(
+		`SELECT e.created_at::date, COUNT(*)
+		FROM events e
+		JOIN projects p ON p.id = e.project_id
+		WHERE p.organization_id = $1 AND e.created_at >= $2::date
+		GROUP BY 1`,
+		orgID, start.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query organization events per day: %w", err)
+	}
+	if usage.EventsPerDay, err = scanDailyCounts(dayRows, start, today); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func scanOrganization(row rowScanner) (*models.Organization, error) {
+	var org models.Organization
+	if err := row.Scan(&org.ID, &org.Slug, &org.Name, &org.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
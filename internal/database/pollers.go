@@ -0,0 +1,58 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PollerItems returns when each item a poller has seen was last seen
+func (d *Database) PollerItems(poller string) (map[string]time.Time, error) {
+	rows, err := d.db.Query("SELECT item_key, last_seen FROM poller_items WHERE poller = $1", poller)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query poller items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[string]time.Time)
+	for rows.Next() {
+		var key string
+		var lastSeen time.Time
+		if err := rows.Scan(&key, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan poller item: %w", err)
+		}
+		items[key] = lastSeen
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating poller items: %w", err)
+	}
+	return items, nil
+}
+
+// TouchPollerItems records that a poller saw the items at seenAt
+func (d *Database) TouchPollerItems(poller string, keys []string, seenAt time.Time) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := d.db.Exec(
+		`INSERT INTO poller_items (poller, item_key, first_seen, last_seen)
+		SELECT $1, key, $3, $3 FROM unnest($2::text[]) AS key
+		ON CONFLICT (poller, item_key) DO UPDATE SET last_seen = EXCLUDED.last_seen`,
+		poller, pq.Array(keys), seenAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record poller items: %w", err)
+	}
+	return nil
+}
+
+// PrunePollerItems forgets items a poller hasn't seen since before, returning
+// how many were removed
+func (d *Database) PrunePollerItems(poller string, before time.Time) (int64, error) {
+	result, err := d.db.Exec("DELETE FROM poller_items WHERE poller = $1 AND last_seen < $2", poller, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune poller items: %w", err)
+	}
+	return result.RowsAffected()
+}
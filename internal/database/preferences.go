@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+	"time"
+)
+
+// GetPreferences retrieves a user's preferences, or nil if they have none saved
+func (d *Database) GetPreferences(username string) (*models.UserPreferences, error) {
+	var prefs models.UserPreferences
+	err := d.db.QueryRow(
+		"SELECT username, page_size, default_filter, timezone, theme, digest, digest_email, updated_at FROM user_preferences WHERE username = $1",
+		username,
+	).Scan(&prefs.Username, &prefs.PageSize, &prefs.DefaultFilter, &prefs.Timezone, &prefs.Theme, &prefs.Digest, &prefs.DigestEmail, &prefs.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	return &prefs, nil
+}
+
+// SavePreferences creates or replaces a user's preferences
+func (d *Database) SavePreferences(prefs *models.UserPreferences) error {
+	prefs.UpdatedAt = time.Now()
+	_, err := d.db.Exec(
+		`INSERT INTO user_preferences (username, page_size, default_filter, timezone, theme, digest, digest_email, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (username) DO UPDATE SET
+			page_size = EXCLUDED.page_size,
+			default_filter = EXCLUDED.default_filter,
+			timezone = EXCLUDED.timezone,
+			theme = EXCLUDED.theme,
+			digest = EXCLUDED.digest,
+			digest_email = EXCLUDED.digest_email,
+			updated_at = EXCLUDED.updated_at`,
+		prefs.Username,
+		prefs.PageSize,
+		prefs.DefaultFilter,
+		prefs.Timezone,
+		prefs.Theme,
+		prefs.Digest,
+		prefs.DigestEmail,
+		prefs.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// ErrProjectExists is returned when creating a project whose slug is taken
+var ErrProjectExists = errors.New("project already exists")
+
+// ErrOrganizationNotFound is returned when creating a project in an
+// organization that doesn't exist
+var ErrOrganizationNotFound = errors.New("organization not found")
+
+// ErrProjectInUse is returned when deleting the default project, or one that
+// still has events or email mappings
+var ErrProjectInUse = errors.New("project is in use")
+
+const projectColumns = `id, slug, name, organization_id, created_at`
+
+// projectID returns id, or the default project when it is zero
+func projectID(id int64) int64 {
+	if id == 0 {
+		return models.DefaultProjectID
+	}
+	return id
+}
+
+// CreateProject stores a new project, in the default organization unless
+// another one is set. It returns ErrProjectExists if its slug is taken and
+// ErrOrganizationNotFound if the organization doesn't exist.
+func (d *Database) CreateProject(project *models.Project) error {
+	if project.OrganizationID == 0 {
+		project.OrganizationID = models.DefaultOrganizationID
+	}
+	err := d.db.QueryRow(
+		`INSERT INTO projects (slug, name, organization_id) VALUES ($1, $2, $3)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING id, created_at`,
+		project.Slug, project.Name, project.OrganizationID,
+	).Scan(&project.ID, &project.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrProjectExists
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" { // foreign_key_violation
+		return ErrOrganizationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create project: %w", err)
+	}
+	return nil
+}
+
+// GetProject retrieves a project by ID, or nil if there is none
+func (d *Database) GetProject(id int64) (*models.Project, error) {
+	return d.getProject("id = $1", id)
+}
+
+// GetProjectBySlug retrieves a project by slug, or nil if there is none
+func (d *Database) GetProjectBySlug(slug string) (*models.Project, error) {
+	return d.getProject("slug = $1", slug)
+}
+
+func (d *Database) getProject(where string, arg interface{}) (*models.Project, error) {
+	project, err := scanProject(d.db.QueryRow("SELECT "+projectColumns+" FROM projects WHERE "+where, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	return project, nil
+}
+
+// ListProjects retrieves every project, the default one first and the
+// others by name
+func (d *Database) ListProjects() ([]models.Project, error) {
+	rows, err := d.db.Query("SELECT "+projectColumns+" FROM projects ORDER BY id <> $1, lower(name), id", models.DefaultProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []models.Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project row: %w", err)
+		}
+		projects = append(projects, *project)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return projects, nil
+}
+
+// DeleteProject removes an empty project with its members and API tokens.
+// It returns ErrProjectInUse for the default project and projects that
+// still have events or email mappings, and false if there is no such
+// project.
+func (d *Database) DeleteProject(id int64) (bool, error) {
+	if id == models.DefaultProjectID {
+		return false, ErrProjectInUse
+	}
+	result, err := d.db.Exec(
+		`DELETE FROM projects WHERE id = $1
+		AND NOT EXISTS (SELECT 1 FROM events WHERE project_id = $1)
+		AND NOT EXISTS (SELECT 1 FROM email_mappings WHERE project_id = $1)`,
+		id,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete project: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return n > 0, err
+	}
+
+	project, err := d.GetProject(id)
+	if err != nil || project == nil {
+		return false, err
+	}
+	return false, ErrProjectInUse
+}
+
+// SetProjectMember gives a user a role in a project, replacing any role
+// they had there
+func (d *Database) SetProjectMember(member *models.ProjectMember) error {
+	err := d.db.QueryRow(
+		`INSERT INTO project_members (project_id, username, role) VALUES ($1, $2, $3)
+		ON CONFLICT (project_id, username) DO UPDATE SET role = EXCLUDED.role
+		RETURNING created_at`,
+		member.ProjectID, member.Username, member.Role,
+	).Scan(&member.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set project member: %w", err)
+	}
+	return nil
+}
+
+// RemoveProjectMember takes a user's role in a project away
+func (d *Database) RemoveProjectMember(projectID int64, username string) error {
+	if _, err := d.db.Exec("DELETE FROM project_members WHERE project_id = $1 AND username = $2", projectID, username); err != nil {
+		return fmt.Errorf("failed to remove project member: %w", err)
+	}
+	return nil
+}
+
+// ProjectMembers retrieves the members of a project by username
+func (d *Database) ProjectMembers(projectID int64) ([]models.ProjectMember, error) {
+	rows, err := d.db.Query(
+		"SELECT project_id, username, role, created_at FROM project_members WHERE project_id = $1 ORDER BY username",
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []models.ProjectMember
+	for rows.Next() {
+		var member models.ProjectMember
+		if err := rows.Scan(&member.ProjectID, &member.Username, &member.Role, &member.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan project member row: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return members, nil
+}
+
+// ProjectAccess returns what a web user can do in each project. Admins can
+// do anything; other users are editors of the default project unless given
+// another role there, and have their member role in other projects.
+// Organization admins are editors of every project of the organization and
+// organization members at least viewers.
+func (d *Database) ProjectAccess(username, role string) (*models.ProjectAccess, error) {
+	if role == "admin" {
+		return &models.ProjectAccess{All: true}, nil
+	}
+
+	access := &models.ProjectAccess{
+		Roles:         map[int64]string{models.DefaultProjectID: models.ProjectEditor},
+		Organizations: map[int64]string{},
+	}
+	if username == "" {
+		return access, nil
+	}
+	rows, err := d.db.Query("SELECT project_id, role FROM project_members WHERE username = $1", username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project roles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var role string
+		if err := rows.Scan(&id, &role); err != nil {
+			return nil, fmt.Errorf("failed to scan project role row: %w", err)
+		}
+		access.Roles[id] = role
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if err := d.organizationAccess(username, access); err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+func scanProject(row rowScanner) (*models.Project, error) {
+	var project models.Project
+	if err := row.Scan(&project.ID, &project.Slug, &project.Name, &project.OrganizationID, &project.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
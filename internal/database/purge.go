@@ -0,0 +1,54 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// purgeCondition matches events created before $1 carrying any of the tags in
+// $2, or any events when $2 is empty
+const purgeCondition = "created_at < $1 AND (cardinality($2::text[]) = 0 OR tags ?| $2)"
+
+// PurgeCandidates returns the IDs of up to limit of the oldest events created
+// before cutoff, restricted to events carrying any of tags when given
+func (d *Database) PurgeCandidates(cutoff time.Time, tags []string, limit int) ([]int64, error) {
+	rows, err := d.db.Query(
+		"SELECT id FROM events WHERE "+purgeCondition+" ORDER BY created_at, id LIMIT $3",
+		cutoff, pq.Array(tags), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events to purge: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan event id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return ids, nil
+}
+
+// CountPurgeCandidates counts the events PurgeCandidates would return without
+// a limit, and their logs and attachments
+func (d *Database) CountPurgeCandidates(cutoff time.Time, tags []string) (events, logs, attachments int64, err error) {
+	err = d.db.QueryRow(
+		`WITH matched AS (SELECT id FROM events WHERE `+purgeCondition+`)
+		SELECT (SELECT COUNT(*) FROM matched),
+			(SELECT COUNT(*) FROM event_logs WHERE event_id IN (SELECT id FROM matched)),
+			(SELECT COUNT(*) FROM attachments WHERE event_id IN (SELECT id FROM matched))`,
+		cutoff, pq.Array(tags),
+	).Scan(&events, &logs, &attachments)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to count events to purge: %w", err)
+	}
+	return events, logs, attachments, nil
+}
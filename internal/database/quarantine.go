@@ -0,0 +1,150 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+)
+
+// quarantinedEvent is the stored form of a quarantined event, keeping the
+// attachment contents the event's own JSON leaves out
+type quarantinedEvent struct {
+	models.EventRequest
+	Attachments []quarantinedAttachment `json:"attachments,omitempty"`
+}
+
+type quarantinedAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// QuarantineEvent holds an event back for review, setting q.ID and q.CreatedAt
+func (d *Database) QuarantineEvent(q *models.QuarantinedEvent) error {
+	stored := quarantinedEvent{EventRequest: q.Event}
+	for _, a := range q.Event.Attachments {
+		stored.Attachments = append(stored.Attachments, quarantinedAttachment{Filename: a.Filename, ContentType: a.ContentType, Data: a.Data})
+	}
+	eventJSON, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantined event: %w", err)
+	}
+
+	err = d.db.QueryRow(
+		"INSERT INTO quarantine (sender, source, subject, reason, score, event) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+		q.Sender, q.Source, q.Subject, q.Reason, q.Score, jsonParam(eventJSON),
+	).Scan(&q.ID, &q.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to quarantine event: %w", err)
+	}
+	return nil
+}
+
+// ListQuarantine returns quarantined events, newest first, without their
+// attachments
+func (d *Database) ListQuarantine(limit int) ([]models.QuarantinedEvent, error) {
+	rows, err := d.db.Query(
+		"SELECT id, sender, source, subject, reason, score, event - 'attachments', created_at FROM quarantine ORDER BY created_at DESC, id DESC LIMIT $1",
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quarantine: %w", err)
+	}
+	defer rows.Close()
+
+	var items []models.QuarantinedEvent
+	for rows.Next() {
+		q, err := scanQuarantined(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating quarantine: %w", err)
+	}
+	return items, nil
+}
+
+// GetQuarantined returns a quarantined event with its attachments
+func (d *Database) GetQuarantined(id int64) (*models.QuarantinedEvent, error) {
+	row := d.db.QueryRow(
+		"SELECT id, sender, source, subject, reason, score, event, created_at FROM quarantine WHERE id = $1",
+		id,
+	)
+	q, err := scanQuarantined(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return q, err
+}
+
+// DeleteQuarantined discards a quarantined event, reporting whether it existed
+func (d *Database) DeleteQuarantined(id int64) (bool, error) {
+	result, err := d.db.Exec("DELETE FROM quarantine WHERE id = $1", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete quarantined event: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ReleaseQuarantined stores a quarantined event and removes it from the
+// quarantine. It returns nil if there is no such quarantined event. An email
+// stored in the meantime is not stored again; the existing event is returned.
+func (d *Database) ReleaseQuarantined(id int64) (*models.Event, error) {
+	q, err := d.GetQuarantined(id)
+	if err != nil || q == nil {
+		return nil, err
+	}
+
+	event, err := d.StoreEvent(&q.Event)
+	if errors.Is(err, ErrDuplicateMessageID) {
+		event, err = d.GetEventByMessageID(q.Event.ProjectID, q.Event.Email.MessageID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, fmt.Errorf("event for message %s not found", q.Event.Email.MessageID)
+	}
+	if _, err := d.DeleteQuarantined(id); err != nil {
+		return nil, err
+	}
+
+	if err := d.LogEventStatus(event.ID, "released", fmt.Sprintf("released from quarantine (%s)", q.Reason)); err != nil {
+		log.Printf("Failed to log release of event %d: %v", event.ID, err)
+	}
+	return event, nil
+}
+
+func scanQuarantined(row rowScanner) (*models.QuarantinedEvent, error) {
+	var q models.QuarantinedEvent
+	var score sql.NullFloat64
+	var eventJSON []byte
+	if err := row.Scan(&q.ID, &q.Sender, &q.Source, &q.Subject, &q.Reason, &score, &eventJSON, &q.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan quarantined event: %w", err)
+	}
+	if score.Valid {
+		q.Score = &score.Float64
+	}
+
+	var stored quarantinedEvent
+	if err := json.Unmarshal(eventJSON, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode quarantined event %d: %w", q.ID, err)
+	}
+	q.Event = stored.EventRequest
+	for _, a := range stored.Attachments {
+		q.Event.Attachments = append(q.Event.Attachments, models.Attachment{Filename: a.Filename, ContentType: a.ContentType, Data: a.Data})
+	}
+	return &q, nil
+}
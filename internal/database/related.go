@@ -0,0 +1,35 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// GetRelatedEvents returns other events of the event's project sharing tags
+// with it, those with the most tags in common first
+func (d *Database) GetRelatedEvents(event *models.Event, limit int) ([]models.Event, error) {
+	if len(event.Tags) == 0 {
+		return nil, nil
+	}
+
+	rows, err := d.db.Query(
+		`SELECT `+eventColumns+`
+		FROM events
+		WHERE id != $1 AND tags ?| $2 AND project_id = $4
+		ORDER BY (SELECT COUNT(*) FROM jsonb_array_elements_text(tags) AS tag WHERE tag = ANY($2)) DESC,
+			created_at DESC
+		LIMIT $3`,
+		event.ID,
+		pq.Array(event.Tags),
+		limit,
+		projectID(event.ProjectID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query related events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// SaveSearch stores a user's search, replacing the filter of any search they
+// already saved under the same name
+func (d *Database) SaveSearch(search *models.SavedSearch) error {
+	err := d.db.QueryRow(
+		`INSERT INTO saved_searches (username, name, query)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (username, name) DO UPDATE SET query = EXCLUDED.query
+		RETURNING id, created_at`,
+		search.Username,
+		search.Name,
+		search.Query,
+	).Scan(&search.ID, &search.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save search: %w", err)
+	}
+	return nil
+}
+
+// GetSavedSearch retrieves one of a user's searches, or nil if they have no
+// search with that ID
+func (d *Database) GetSavedSearch(username string, id int64) (*models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := d.db.QueryRow(
+		"SELECT id, username, name, query, created_at FROM saved_searches WHERE id = $1 AND username = $2",
+		id, username,
+	).Scan(&search.ID, &search.Username, &search.Name, &search.Query, &search.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+	return &search, nil
+}
+
+// ListSavedSearches retrieves a user's searches by name
+func (d *Database) ListSavedSearches(username string) ([]models.SavedSearch, error) {
+	rows, err := d.db.Query(
+		"SELECT id, username, name, query, created_at FROM saved_searches WHERE username = $1 ORDER BY lower(name), id",
+		username,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	var searches []models.SavedSearch
+	for rows.Next() {
+		var search models.SavedSearch
+		if err := rows.Scan(&search.ID, &search.Username, &search.Name, &search.Query, &search.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search row: %w", err)
+		}
+		searches = append(searches, search)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return searches, nil
+}
+
+// DeleteSavedSearch removes one of a user's searches
+func (d *Database) DeleteSavedSearch(username string, id int64) error {
+	if _, err := d.db.Exec("DELETE FROM saved_searches WHERE id = $1 AND username = $2", id, username); err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SaveSession stores a web session
+func (d *Database) SaveSession(session *models.WebSession) error {
+	_, err := d.db.Exec(
+		`INSERT INTO web_sessions (token_hash, handle, user_id, username, role, ip_address, user_agent, created_at, last_seen, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (token_hash) DO UPDATE SET last_seen = EXCLUDED.last_seen, expires_at = EXCLUDED.expires_at`,
+		session.TokenHash,
+		session.Handle,
+		session.UserID,
+		session.Username,
+		session.Role,
+		session.IPAddress,
+		session.UserAgent,
+		session.CreatedAt,
+		session.LastSeen,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// GetSession retrieves an unexpired web session by token hash. The role is
+// the user's current one rather than the copy taken at login, so a demoted
+// admin loses access straight away.
+func (d *Database) GetSession(tokenHash string) (*models.WebSession, error) {
+	var session models.WebSession
+	var ipAddress, userAgent sql.NullString
+
+	err := d.db.QueryRow(
+		`SELECT s.token_hash, s.handle, s.user_id, s.username, u.role, s.ip_address, s.user_agent, s.created_at, s.last_seen, s.expires_at
+		FROM web_sessions s JOIN web_users u ON u.id = s.user_id
+		WHERE s.token_hash = $1 AND s.expires_at > $2`,
+		tokenHash,
+		time.Now(),
+	).Scan(&session.TokenHash, &session.Handle, &session.UserID, &session.Username, &session.Role,
+		&ipAddress, &userAgent, &session.CreatedAt, &session.LastSeen, &session.ExpiresAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	session.IPAddress = ipAddress.String
+	session.UserAgent = userAgent.String
+
+	return &session, nil
+}
+
+// TouchSession updates the last seen time of a web session
+func (d *Database) TouchSession(tokenHash string, lastSeen time.Time) error {
+	_, err := d.db.Exec("UPDATE web_sessions SET last_seen = $1 WHERE token_hash = $2", lastSeen, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	return nil
+}
+
+// DeleteSessions removes web sessions by token hash, along with any expired sessions
+func (d *Database) DeleteSessions(tokenHashes []string) error {
+	_, err := d.db.Exec(
+		"DELETE FROM web_sessions WHERE token_hash = ANY($1) OR expires_at <= $2",
+		pq.Array(tokenHashes),
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserSessions removes all web sessions of a user
+func (d *Database) DeleteUserSessions(userID int) error {
+	_, err := d.db.Exec("DELETE FROM web_sessions WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+	return nil
+}
+
+// UserSessionTokenHashes returns the token hashes of a user's web sessions
+func (d *Database) UserSessionTokenHashes(userID int) ([]string, error) {
+	rows, err := d.db.Query("SELECT token_hash FROM web_sessions WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// StarEvent stars an event for a user; starring it again does nothing
+func (d *Database) StarEvent(username string, eventID int64) error {
+	_, err := d.db.Exec(
+		"INSERT INTO event_stars (username, event_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		username, eventID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to star event: %w", err)
+	}
+	return nil
+}
+
+// UnstarEvent removes a user's star from an event
+func (d *Database) UnstarEvent(username string, eventID int64) error {
+	if _, err := d.db.Exec("DELETE FROM event_stars WHERE username = $1 AND event_id = $2", username, eventID); err != nil {
+		return fmt.Errorf("failed to unstar event: %w", err)
+	}
+	return nil
+}
+
+// IsStarred reports whether the user starred an event
+func (d *Database) IsStarred(username string, eventID int64) (bool, error) {
+	var starred bool
+	err := d.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM event_stars WHERE username = $1 AND event_id = $2)",
+		username, eventID,
+	).Scan(&starred)
+	if err != nil {
+		return false, fmt.Errorf("failed to check star: %w", err)
+	}
+	return starred, nil
+}
+
+// MarkStarred sets Starred on the events the user starred
+func (d *Database) MarkStarred(username string, events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+
+	rows, err := d.db.Query(
+		"SELECT event_id FROM event_stars WHERE username = $1 AND event_id = ANY($2)",
+		username, pq.Array(ids),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query stars: %w", err)
+	}
+	defer rows.Close()
+
+	starred := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan star row: %w", err)
+		}
+		starred[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	for i := range events {
+		events[i].Starred = starred[events[i].ID]
+	}
+	return nil
+}
+
+// StarredEvents retrieves the events a user starred, most recently starred
+// first
+func (d *Database) StarredEvents(username string) ([]models.Event, error) {
+	rows, err := d.db.Query(
+		`SELECT e.id, e.tags, e.data, e.source, e.created_at, e.project_id, e.status, e.due_at, e.labels, e.location, e.latitude, e.longitude
+		FROM events e JOIN event_stars s ON s.event_id = e.id
+		WHERE s.username = $1
+		ORDER BY s.created_at DESC, e.id DESC`,
+		username,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query starred events: %w", err)
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].Starred = true
+	}
+	return events, nil
+}
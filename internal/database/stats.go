@@ -0,0 +1,172 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+	"time"
+)
+
+// CountEvents returns the total number of events and the number created on
+// or after the day of the given time
+func (d *Database) CountEvents(since time.Time) (total int, recent int, err error) {
+	err = d.db.QueryRow(
+		`SELECT COALESCE(SUM(count), 0), COALESCE(SUM(count) FILTER (WHERE day >= $1::date), 0)
+		FROM daily_stats
+		WHERE kind = 'events'`,
+		since.Format("2006-01-02"),
+	).Scan(&total, &recent)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+	return total, recent, nil
+}
+
+// CountUniqueTags returns the number of distinct tags across all events
+func (d *Database) CountUniqueTags() (int, error) {
+	var count int
+	err := d.db.QueryRow(
+		"SELECT COUNT(DISTINCT name) FROM daily_stats WHERE kind = 'tag'",
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tags: %w", err)
+	}
+	return count, nil
+}
+
+// GetEventsPerDay returns the number of events created on each of the last
+// days days, including today. Days without events are reported with a zero count.
+func (d *Database) GetEventsPerDay(days int) ([]models.DailyCount, error) {
+	start, today := lastDays(days)
+	rows, err := d.db.Query(
+		"SELECT day, count FROM daily_stats WHERE kind = 'events' AND day >= $1::date",
+		start.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events per day: %w", err)
+	}
+	return scanDailyCounts(rows, start, today)
+}
+
+// lastDays returns the first day and today of the last days days, in UTC
+func lastDays(days int) (start, today time.Time) {
+	now := time.Now()
+	today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return today.AddDate(0, 0, -(days - 1)), today
+}
+
+// scanDailyCounts reads day, count rows and closes them, returning a count
+// for every day from start to today. Days without a row count zero.
+func scanDailyCounts(rows *sql.Rows, start, today time.Time) ([]models.DailyCount, error) {
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var day time.Time
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan daily count: %w", err)
+		}
+		counts[day.Format("2006-01-02")] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	result := make([]models.DailyCount, 0, int(today.Sub(start).Hours()/24)+1)
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		result = append(result, models.DailyCount{Day: day, Count: counts[day.Format("2006-01-02")]})
+	}
+	return result, nil
+}
+
+// GetTopTags returns the most used tags with their event counts
+func (d *Database) GetTopTags(limit int) ([]models.NameCount, error) {
+	return d.queryNameCounts(
+		`SELECT name, SUM(count) AS n
+		FROM daily_stats
+		WHERE kind = 'tag'
+		GROUP BY name
+		ORDER BY n DESC, name
+		LIMIT $1`,
+		limit,
+	)
+}
+
+// GetTopSources returns the sources with the most events
+func (d *Database) GetTopSources(limit int) ([]models.NameCount, error) {
+	return d.queryNameCounts(
+		`SELECT name, SUM(count) AS n
+		FROM daily_stats
+		WHERE kind = 'source'
+		GROUP BY name
+		ORDER BY n DESC, name
+		LIMIT $1`,
+		limit,
+	)
+}
+
+// queryNameCounts runs a query returning name, count rows
+func (d *Database) queryNameCounts(query string, args ...interface{}) ([]models.NameCount, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query counts: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.NameCount
+	for rows.Next() {
+		var nc models.NameCount
+		if err := rows.Scan(&nc.Name, &nc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan count row: %w", err)
+		}
+		result = append(result, nc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return result, nil
+}
+
+// GetDatabaseStats returns the database and table sizes, the range of event
+// timestamps and the number of events created per day over the last days days
+func (d *Database) GetDatabaseStats(days int) (*models.DatabaseStats, error) {
+	var stats models.DatabaseStats
+	now := time.Now()
+	err := d.db.QueryRow(
+		`SELECT pg_database_size(current_database()),
+			(SELECT COALESCE(SUM(count), 0) FROM daily_stats WHERE kind = 'events'),
+			(SELECT MIN(created_at) FROM events), (SELECT MAX(created_at) FROM events),
+			(SELECT COUNT(*) FROM events WHERE created_at >= $1),
+			(SELECT COUNT(*) FROM events WHERE created_at >= $2)`,
+		now.Add(-time.Hour), now.Add(-24*time.Hour),
+	).Scan(&stats.Size, &stats.TotalEvents, &stats.OldestEvent, &stats.NewestEvent, &stats.LastHour, &stats.LastDay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database statistics: %w", err)
+	}
+
+	rows, err := d.db.Query(
+		`SELECT relname, n_live_tup, pg_total_relation_size(relid)
+		FROM pg_stat_user_tables
+		ORDER BY pg_total_relation_size(relid) DESC, relname`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table statistics: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table models.TableStats
+		if err := rows.Scan(&table.Name, &table.Rows, &table.Size); err != nil {
+			return nil, fmt.Errorf("failed to scan table statistics: %w", err)
+		}
+		stats.Tables = append(stats.Tables, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if stats.EventsPerDay, err = d.GetEventsPerDay(days); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
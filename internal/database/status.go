@@ -0,0 +1,31 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"fmt"
+	"slices"
+)
+
+// SetEventStatus changes the status of an event, returning false if there
+// is no such event
+func (d *Database) SetEventStatus(id int64, status string) (bool, error) {
+	defer d.events.invalidate(id)
+	result, err := d.db.Exec("UPDATE events SET status = $1 WHERE id = $2", status, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to set event status: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// eventStatus returns status, or open for events exported or deleted before
+// events had statuses
+func eventStatus(status string) string {
+	if !slices.Contains(models.EventStatuses, status) {
+		return models.StatusOpen
+	}
+	return status
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"example-api/internal/models"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// GetTagCounts returns every tag with the number of events using it, most used first
+func (d *Database) GetTagCounts() ([]models.NameCount, error) {
+	return d.queryNameCounts(
+		`SELECT name, SUM(count) AS n
+		FROM daily_stats
+		WHERE kind = 'tag'
+		GROUP BY name
+		ORDER BY n DESC, name`,
+	)
+}
+
+// RenameTag replaces a tag with a new name on every event. If an event
+// already has the new tag, the two are merged.
+func (d *Database) RenameTag(oldTag, newTag string) (int64, error) {
+	return d.MergeTags([]string{oldTag}, newTag)
+}
+
+// MergeTags replaces each of the source tags with the target tag on every
+// event, returning the number of events changed
+func (d *Database) MergeTags(sources []string, target string) (int64, error) {
+	sources = normalizeTags(sources)
+	target = normalizeTag(target)
+	merge := make(map[string]bool, len(sources))
+	for _, tag := range sources {
+		merge[tag] = true
+	}
+
+	ids, err := d.EventIDsWithTags(sources)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.updateEventTags(ids, func(existing []string) []string {
+		result := []string{}
+		seen := make(map[string]bool)
+		for _, tag := range existing {
+			if merge[tag] {
+				tag = target
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				result = append(result, tag)
+			}
+		}
+		return result
+	})
+}
+
+// DeleteTag removes a tag from every event, returning the number of events changed
+func (d *Database) DeleteTag(tag string) (int64, error) {
+	tag = normalizeTag(tag)
+	ids, err := d.EventIDsWithTags([]string{tag})
+	if err != nil {
+		return 0, err
+	}
+	return d.RemoveTagsFromEvents(ids, []string{tag})
+}
+
+// EventIDsWithTags returns the IDs of events carrying any of the given tags
+func (d *Database) EventIDsWithTags(tags []string) ([]int64, error) {
+	rows, err := d.db.Query("SELECT id FROM events WHERE tags ?| $1", pq.Array(normalizeTags(tags)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events by tag: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan event id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return ids, nil
+}
+
+// normalizeTag lowercases and trims a tag, the form every tag is stored in
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// normalizeTags normalizes each tag, dropping empty and repeated ones. The
+// result is never nil so it always marshals to a JSON array.
+func normalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// threadLimit caps the number of messages returned for one thread
+	threadLimit = 200
+	// threadRounds caps how many times a thread is widened by the Message-IDs
+	// of the messages found so far
+	threadRounds = 5
+)
+
+// GetThread returns the email conversation an event belongs to, oldest first.
+// Messages are linked through their Message-ID, In-Reply-To and References
+// headers, so replies are found even when the original alert is not the one
+// they name directly. Events that did not arrive by email are returned alone;
+// nil is returned when the event does not exist.
+func (d *Database) GetThread(eventID int64) ([]models.Event, error) {
+	event, err := d.GetEventByID(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, nil
+	}
+
+	ids := make(map[string]bool)
+	if !addThreadIDs(ids, event.Email) {
+		return []models.Event{*event}, nil
+	}
+
+	var thread []models.Event
+	for round := 0; round < threadRounds; round++ {
+		thread, err = d.threadMessages(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		grew := false
+		for i := range thread {
+			if addThreadIDs(ids, thread[i].Email) {
+				grew = true
+			}
+		}
+		if !grew {
+			break
+		}
+	}
+
+	return thread, nil
+}
+
+// addThreadIDs adds the Message-IDs an email names to ids, reporting whether
+// any were new
+func addThreadIDs(ids map[string]bool, email *models.EmailMetadata) bool {
+	if email == nil {
+		return false
+	}
+	added := false
+	for _, id := range append([]string{email.MessageID, email.InReplyTo}, email.References...) {
+		if id != "" && !ids[id] {
+			ids[id] = true
+			added = true
+		}
+	}
+	return added
+}
+
+// threadMessages returns the events that have, or reply to, any of the
+// Message-IDs
+func (d *Database) threadMessages(ids map[string]bool) ([]models.Event, error) {
+	list := make([]string, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+
+	rows, err := d.db.Query(
+		`SELECT id, tags, data, source, email, created_at, project_id, status
+		FROM events
+		WHERE message_id = ANY($1)
+			OR email->>'in_reply_to' = ANY($1)
+			OR email->'references' ?| $1
+		ORDER BY created_at, id
+		LIMIT $2`,
+		pq.Array(list),
+		threadLimit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query thread: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var event models.Event
+		var tagsJSON string
+		var emailJSON []byte
+		if err := rows.Scan(&event.ID, &tagsJSON, &event.Data, &event.Source, &emailJSON, &event.CreatedAt, &event.ProjectID, &event.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan event row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(tagsJSON), &event.Tags); err != nil {
+			return nil, fmt.Errorf("failed to parse tags: %w", err)
+		}
+		if emailJSON != nil {
+			event.Email = &models.EmailMetadata{}
+			if err := json.Unmarshal(emailJSON, event.Email); err != nil {
+				return nil, fmt.Errorf("failed to parse email metadata: %w", err)
+			}
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return events, nil
+}
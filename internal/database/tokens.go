@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+	"time"
+)
+
+const apiTokenColumns = `id, name, token_hash, organization_id, project_id, role, created_by, created_at, last_used_at`
+
+// CreateAPIToken stores a project or organization API token. Only its hash
+// is kept.
+func (d *Database) CreateAPIToken(token *models.APIToken) error {
+	err := d.db.QueryRow(
+		`INSERT INTO api_tokens (name, token_hash, organization_id, project_id, role, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		token.Name, token.TokenHash, token.OrganizationID, sql.NullInt64{Int64: token.ProjectID, Valid: token.ProjectID != 0},
+		token.Role, token.CreatedBy,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API token: %w", err)
+	}
+	return nil
+}
+
+// GetAPIToken retrieves the token with the given hash, or nil if there is
+// none
+func (d *Database) GetAPIToken(tokenHash string) (*models.APIToken, error) {
+	token, err := scanAPIToken(d.db.QueryRow("SELECT "+apiTokenColumns+" FROM api_tokens WHERE token_hash = $1", tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+	return token, nil
+}
+
+// ListAPITokens retrieves the tokens of a project, newest first
+func (d *Database) ListAPITokens(projectID int64) ([]models.APIToken, error) {
+	return d.queryAPITokens("project_id = $1", projectID)
+}
+
+// ListOrganizationTokens retrieves the tokens of an organization that
+// aren't limited to one project, newest first
+func (d *Database) ListOrganizationTokens(orgID int64) ([]models.APIToken, error) {
+	return d.queryAPITokens("organization_id = $1 AND project_id IS NULL", orgID)
+}
+
+func (d *Database) queryAPITokens(where string, arg int64) ([]models.APIToken, error) {
+	rows, err := d.db.Query(
+		"SELECT "+apiTokenColumns+" FROM api_tokens WHERE "+where+" ORDER BY created_at DESC, id DESC",
+		arg,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token row: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// DeleteAPIToken revokes a token of a project, reporting whether it existed
+func (d *Database) DeleteAPIToken(projectID, id int64) (bool, error) {
+	result, err := d.db.Exec("DELETE FROM api_tokens WHERE id = $1 AND project_id = $2", id, projectID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API token: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteOrganizationToken revokes a token of an organization that isn't
+// limited to one project, reporting whether it existed
+func (d *Database) DeleteOrganizationToken(orgID, id int64) (bool, error) {
+	result, err := d.db.Exec("DELETE FROM api_tokens WHERE id = $1 AND organization_id = $2 AND project_id IS NULL", id, orgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API token: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// TouchAPIToken records that a token was used
+func (d *Database) TouchAPIToken(id int64) error {
+	if _, err := d.db.Exec("UPDATE api_tokens SET last_used_at = $1 WHERE id = $2", time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update API token: %w", err)
+	}
+	return nil
+}
+
+func scanAPIToken(row rowScanner) (*models.APIToken, error) {
+	var token models.APIToken
+	var projectID sql.NullInt64
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(&token.ID, &token.Name, &token.TokenHash, &token.OrganizationID, &projectID, &token.Role,
+		&token.CreatedBy, &token.CreatedAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	token.ProjectID = projectID.Int64
+	token.LastUsedAt = lastUsedAt.Time
+	return &token, nil
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// SaveUser stores a web user, replacing the stored user with the same
+// username. New users are given the next ID, which is set on user.
+func (d *Database) SaveUser(user *models.WebUser) error {
+	err := d.db.QueryRow(
+		`INSERT INTO web_users (username, password_hash, role, email, pending, provider, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (username) DO UPDATE SET password_hash = EXCLUDED.password_hash, role = EXCLUDED.role,
+			email = EXCLUDED.email, pending = EXCLUDED.pending, provider = EXCLUDED.provider
+		RETURNING id`,
+		user.Username,
+		user.PasswordHash,
+		user.Role,
+		user.Email,
+		user.Pending,
+		user.Provider,
+		user.CreatedAt,
+	).Scan(&user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save user: %w", err)
+	}
+	return nil
+}
+
+// GetUser retrieves a web user by username, or nil if there is none
+func (d *Database) GetUser(username string) (*models.WebUser, error) {
+	row := d.db.QueryRow(
+		"SELECT id, username, password_hash, role, email, pending, provider, created_at FROM web_users WHERE username = $1",
+		username,
+	)
+	user, err := scanWebUser(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+// GetUserByID returns the web user with the given ID, or nil if there is none
+func (d *Database) GetUserByID(id int) (*models.WebUser, error) {
+	row := d.db.QueryRow(
+		"SELECT id, username, password_hash, role, email, pending, provider, created_at FROM web_users WHERE id = $1",
+		id,
+	)
+	user, err := scanWebUser(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+// ListUsers returns every web user, in ID order
+func (d *Database) ListUsers() ([]models.WebUser, error) {
+	rows, err := d.db.Query("SELECT id, username, password_hash, role, email, pending, provider, created_at FROM web_users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.WebUser
+	for rows.Next() {
+		user, err := scanWebUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return users, nil
+}
+
+// DeleteUser removes a web user
+func (d *Database) DeleteUser(username string) error {
+	// Their project and organization roles go with them, so a new user of
+	// the same name doesn't inherit them
+	_, err := d.db.Exec(
+		`WITH members AS (DELETE FROM project_members WHERE username = $1),
+		org_members AS (DELETE FROM organization_members WHERE username = $1)
+		DELETE FROM web_users WHERE username = $1`,
+		username,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
+
+func scanWebUser(row rowScanner) (*models.WebUser, error) {
+	var user models.WebUser
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.Email, &user.Pending, &user.Provider, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"database/sql"
+	"example-api/internal/models"
+	"fmt"
+)
+
+// SaveVerification stores a pending email verification
+func (d *Database) SaveVerification(v *models.EmailVerification) error {
+	_, err := d.db.Exec(
+		"INSERT INTO email_verifications (token_hash, user_id, expires_at) VALUES ($1, $2, $3)",
+		v.TokenHash,
+		v.UserID,
+		v.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save verification: %w", err)
+	}
+	return nil
+}
+
+// TakeVerification removes and returns the email verification with the given
+// token hash, or nil if there is none, so each token is used once
+func (d *Database) TakeVerification(tokenHash string) (*models.EmailVerification, error) {
+	var v models.EmailVerification
+	err := d.db.QueryRow(
+		`DELETE FROM email_verifications v USING web_users u
+		WHERE v.token_hash = $1 AND u.id = v.user_id
+		RETURNING v.token_hash, v.user_id, u.username, v.expires_at`,
+		tokenHash,
+	).Scan(&v.TokenHash, &v.UserID, &v.Username, &v.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take verification: %w", err)
+	}
+	return &v, nil
+}
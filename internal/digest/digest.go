@@ -0,0 +1,208 @@
+// Package digest emails users a summary of the events created each day or
+// week
+package digest
+
+import (
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/mailer"
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Periods lists the digests users can subscribe to
+var Periods = []string{"daily", "weekly"}
+
+// topItems is how many tags, sources and events a digest lists
+const topItems = 10
+
+// Sender sends the digests that are due
+type Sender struct {
+	db      *database.Database
+	mailer  *mailer.Mailer
+	baseURL string // Where the web interface is reached, for links to events
+
+	// DryRun writes the digests to Out instead of sending them, and doesn't
+	// record them as sent
+	DryRun bool
+	Out    io.Writer
+}
+
+// New creates a Sender mailing digests through m. Digests link to the events
+// under baseURL when it is set.
+func New(db *database.Database, m *mailer.Mailer, baseURL string) *Sender {
+	return &Sender{db: db, mailer: m, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Period returns the last whole day ("daily") or Monday-to-Sunday week
+// ("weekly") before now in loc
+func Period(period string, now time.Time, loc *time.Location) (start, end time.Time, err error) {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch period {
+	case "daily":
+		return today.AddDate(0, 0, -1), today, nil
+	case "weekly":
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return monday.AddDate(0, 0, -7), monday, nil
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown digest period %q, want daily or weekly", period)
+	}
+}
+
+// Send sends the digest of the last whole period to each subscriber who
+// hasn't had it yet. Periods end at midnight in the subscriber's time zone,
+// so running it every hour delivers each digest soon after. Digests of
+// periods without events aren't sent. It returns how many were sent; failing
+// to send one doesn't stop the others.
+func (s *Sender) Send(period string, now time.Time) (int, error) {
+	if _, _, err := Period(period, now, time.UTC); err != nil {
+		return 0, err
+	}
+	subscribers, err := s.db.DigestSubscribers(period)
+	if err != nil {
+		return 0, err
+	}
+
+	// Subscribers in the same time zone who see the same projects share a
+	// summary
+	summaries := make(map[summaryKey]*models.Digest)
+	sent := 0
+	var errs []error
+	for _, sub := range subscribers {
+		loc, err := time.LoadLocation(sub.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		start, end, _ := Period(period, now, loc)
+		if !sub.SentAt.Before(end) {
+			continue
+		}
+		if sub.Email == "" {
+			log.Printf("Not sending the %s digest to %s, who has no email address", period, sub.Username)
+			continue
+		}
+
+		access, err := s.db.ProjectAccess(sub.Username, sub.Role)
+		if err != nil {
+			return sent, err
+		}
+		projects := access.Viewable()
+		key := summaryKey{start: start.Unix(), end: end.Unix(), projects: "all"}
+		if projects != nil {
+			slices.Sort(projects)
+			key.projects = fmt.Sprint(projects)
+		}
+		summary, ok := summaries[key]
+		if !ok {
+			if summary, err = s.db.GetDigest(start, end, topItems, projects); err != nil {
+				return sent, err
+			}
+			summaries[key] = summary
+		}
+
+		if summary.Total > 0 {
+			subject, body := s.subject(period, summary, loc), s.body(period, summary, loc)
+			if s.DryRun {
+				fmt.Fprintf(s.Out, "To: %s\nSubject: %s\n\n%s\n", sub.Email, subject, body)
+				continue
+			}
+			if err := s.mailer.Send(sub.Email, subject, body); err != nil {
+				log.Printf("Failed to send the %s digest to %s: %v", period, sub.Username, err)
+				errs = append(errs, err)
+				continue
+			}
+			sent++
+		}
+		if !s.DryRun {
+			if err := s.db.MarkDigestSent(sub.Username, end); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// summaryKey identifies the summary of a period for a set of projects
+type summaryKey struct {
+	start, end int64
+	projects   string
+}
+
+// subject names the period and the number of events
+func (s *Sender) subject(period string, d *models.Digest, loc *time.Location) string {
+	if period == "daily" {
+		return fmt.Sprintf("Daily digest: %d new events on %s", d.Total, d.From.In(loc).Format("Mon Jan 2"))
+	}
+	return fmt.Sprintf("Weekly digest: %d new events, %s to %s", d.Total,
+		d.From.In(loc).Format("Jan 2"), d.To.In(loc).AddDate(0, 0, -1).Format("Jan 2"))
+}
+
+// body lists the events' top tags and sources and the newest events in
+// plain text, with times in loc
+func (s *Sender) body(period string, d *models.Digest, loc *time.Location) string {
+	var b strings.Builder
+	last := d.To.In(loc).AddDate(0, 0, -1)
+	if period == "daily" {
+		fmt.Fprintf(&b, "%d new events on %s (%s).\n", d.Total, d.From.In(loc).Format("Monday, January 2, 2006"), loc)
+	} else {
+		fmt.Fprintf(&b, "%d new events from %s to %s (%s).\n", d.Total,
+			d.From.In(loc).Format("Monday, January 2"), last.Format("Monday, January 2, 2006"), loc)
+	}
+
+	writeCounts(&b, "By tag", d.Tags)
+	writeCounts(&b, "By source", d.Sources)
+
+	if len(d.Latest) > 0 {
+		b.WriteString("\nLatest:\n")
+		for _, event := range d.Latest {
+			fmt.Fprintf(&b, "  #%d  %s  %s  [%s]\n      %s\n", event.ID, event.CreatedAt.In(loc).Format("Jan 02 15:04"),
+				event.Source, strings.Join(event.Tags, ", "), firstLine(event.Data))
+		}
+	}
+
+	if s.baseURL != "" {
+		// The events list filters whole UTC days, so this may take in a few more
+		query := url.Values{
+			"from": {d.From.UTC().Format("2006-01-02")},
+			"to":   {d.To.Add(-time.Nanosecond).UTC().Format("2006-01-02")},
+		}
+		fmt.Fprintf(&b, "\nSee them all: %s/?%s\n", s.baseURL, query.Encode())
+	}
+	b.WriteString("\nChoose how often you get digests, or stop them, on the settings page.\n")
+	return b.String()
+}
+
+// writeCounts writes a titled list of names and counts
+func writeCounts(b *strings.Builder, title string, counts []models.NameCount) {
+	if len(counts) == 0 {
+		return
+	}
+	width := 0
+	for _, c := range counts {
+		width = max(width, len(c.Name))
+	}
+	fmt.Fprintf(b, "\n%s:\n", title)
+	for _, c := range counts {
+		fmt.Fprintf(b, "  %-*s  %d\n", width, c.Name, c.Count)
+	}
+}
+
+// firstLine returns the first non-empty line of s, shortened to fit a line
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if runes := []rune(line); len(runes) > 100 {
+				return string(runes[:100]) + "…"
+			}
+			return line
+		}
+	}
+	return "(no data)"
+}
@@ -0,0 +1,217 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedFormat is returned for export formats without a writer
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Writer encodes events one at a time so exports can be streamed
+type Writer interface {
+	WriteEvent(event models.Event) error
+	// Flush writes out anything the writer buffers
+	Flush() error
+	Close() error
+}
+
+// ContentType returns the MIME type for an export format
+func ContentType(format string) string {
+	switch format {
+	case "csv":
+		return "text/csv; charset=utf-8"
+	case "ndjson":
+		return "application/x-ndjson"
+	case "geojson":
+		return "application/geo+json"
+	}
+	return "application/json"
+}
+
+// NewWriter returns a streaming writer for "csv", "json", "ndjson" or
+// "geojson"
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case "csv":
+		return newCSVWriter(w)
+	case "json":
+		return newJSONWriter(w), nil
+	case "ndjson":
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case "geojson":
+		return newGeoJSONWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// Filename returns a download filename for an export taken at the given time
+func Filename(format string, at time.Time) string {
+	return fmt.Sprintf("events-%s.%s", at.Format("20060102-150405"), format)
+}
+
+// csvWriter writes one row per event with tags joined by commas
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write([]string{"id", "tags", "data", "source", "created_at", "status", "due_at", "labels", "location", "latitude", "longitude"}); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (c *csvWriter) WriteEvent(event models.Event) error {
+	var dueAt, latitude, longitude string
+	if event.DueAt != nil {
+		dueAt = event.DueAt.Format(time.RFC3339)
+	}
+	if event.Located() {
+		latitude = strconv.FormatFloat(*event.Latitude, 'f', -1, 64)
+		longitude = strconv.FormatFloat(*event.Longitude, 'f', -1, 64)
+	}
+	return c.w.Write([]string{
+		strconv.FormatInt(event.ID, 10),
+		strings.Join(event.Tags, ","),
+		event.Data,
+		event.Source,
+		event.CreatedAt.Format(time.RFC3339),
+		event.Status,
+		dueAt,
+		strings.Join(event.Labels, ","),
+		event.Location,
+		latitude,
+		longitude,
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+// jsonWriter writes a JSON array, one element per event
+type jsonWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newJSONWriter(w io.Writer) *jsonWriter {
+	return &jsonWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (j *jsonWriter) WriteEvent(event models.Event) error {
+	sep := ","
+	if j.count == 0 {
+		sep = "["
+	}
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	j.count++
+	return j.enc.Encode(event)
+}
+
+// Flush does nothing; events are written as they are encoded
+func (j *jsonWriter) Flush() error {
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	closing := "]\n"
+	if j.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+// ndjsonWriter writes one JSON object per line
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) WriteEvent(event models.Event) error {
+	return n.enc.Encode(event)
+}
+
+// Flush does nothing; events are written as they are encoded
+func (n *ndjsonWriter) Flush() error {
+	return nil
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// geoJSONWriter writes a GeoJSON FeatureCollection with a Point feature per
+// event, whose properties are the event. Events without coordinates get a
+// null geometry.
+type geoJSONWriter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+// geoJSONFeature is an event as a GeoJSON feature
+type geoJSONFeature struct {
+	Type       string        `json:"type"`
+	ID         int64         `json:"id"`
+	Geometry   *geoJSONPoint `json:"geometry"`
+	Properties models.Event  `json:"properties"`
+}
+
+// geoJSONPoint is a GeoJSON Point, longitude first
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+func newGeoJSONWriter(w io.Writer) *geoJSONWriter {
+	return &geoJSONWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (g *geoJSONWriter) WriteEvent(event models.Event) error {
+	sep := ","
+	if g.count == 0 {
+		sep = `{"type":"FeatureCollection","features":[`
+	}
+	if _, err := io.WriteString(g.w, sep); err != nil {
+		return err
+	}
+	g.count++
+	feature := geoJSONFeature{Type: "Feature", ID: event.ID, Properties: event}
+	if event.Located() {
+		feature.Geometry = &geoJSONPoint{Type: "Point", Coordinates: [2]float64{*event.Longitude, *event.Latitude}}
+	}
+	return g.enc.Encode(feature)
+}
+
+// Flush does nothing; events are written as they are encoded
+func (g *geoJSONWriter) Flush() error {
+	return nil
+}
+
+func (g *geoJSONWriter) Close() error {
+	closing := "]}\n"
+	if g.count == 0 {
+		closing = `{"type":"FeatureCollection","features":[]}` + "\n"
+	}
+	_, err := io.WriteString(g.w, closing)
+	return err
+}
@@ -0,0 +1,358 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldColumnPrefix starts the mapping target of a column holding a custom
+// field, such as "field.severity"
+const FieldColumnPrefix = "field."
+
+// defaultImportSource is the source of imported events that have none
+const defaultImportSource = "import"
+
+// ImportColumns are the event fields a CSV column can be mapped to, besides
+// custom fields. The columns of a CSV export map to them by name.
+var ImportColumns = []string{"data", "tags", "source", "created_at", "status", "due_at", "labels", "location", "latitude", "longitude"}
+
+// columnAliases are other common names of the import columns
+var columnAliases = map[string]string{
+	"message":     "data",
+	"description": "data",
+	"text":        "data",
+	"body":        "data",
+	"tag":         "tags",
+	"timestamp":   "created_at",
+	"time":        "created_at",
+	"date":        "created_at",
+	"created":     "created_at",
+	"due":         "due_at",
+	"label":       "labels",
+	"lat":         "latitude",
+	"lon":         "longitude",
+	"lng":         "longitude",
+	"long":        "longitude",
+}
+
+// timeLayouts are the formats accepted for times in CSV imports; times
+// without an offset are in Mapping.Location
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// Mapping says how the columns of a CSV import become events
+type Mapping struct {
+	Columns   []string // Target of each column by position; "" skips the column
+	Fields    []models.CustomField
+	Tags      []string       // Added to every event
+	Source    string         // Source of events without one
+	ProjectID int64          // Project of every CSV event; the default project when zero
+	Location  *time.Location // Of times without an offset; UTC when nil
+}
+
+// Validate checks that every target is known and mapped once, and that a
+// column holds the events' data
+func (m *Mapping) Validate() error {
+	seen := make(map[string]bool)
+	for _, target := range m.Columns {
+		if target == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(target, FieldColumnPrefix); ok {
+			if models.FieldByName(m.Fields, name) == nil {
+				return fmt.Errorf("unknown custom field %q", name)
+			}
+		} else if !slices.Contains(ImportColumns, target) {
+			return fmt.Errorf("unknown column target %q", target)
+		}
+		if seen[target] {
+			return fmt.Errorf("more than one column is mapped to %s", target)
+		}
+		seen[target] = true
+	}
+	if !seen["data"] {
+		return fmt.Errorf("a column must be mapped to data")
+	}
+	return nil
+}
+
+// GuessColumn returns the target a CSV column is likely meant for, judging
+// by its header, or "" if there is none
+func GuessColumn(header string, fields []models.CustomField) string {
+	name := strings.ToLower(strings.TrimSpace(header))
+	name = strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+	if slices.Contains(ImportColumns, name) {
+		return name
+	}
+	if target, ok := columnAliases[name]; ok {
+		return target
+	}
+	name = strings.TrimPrefix(name, FieldColumnPrefix)
+	if models.FieldByName(fields, name) != nil {
+		return FieldColumnPrefix + name
+	}
+	return ""
+}
+
+// RecordError is a record of an import that can't become an event. Reading
+// can go on with the next record.
+type RecordError struct {
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Reader decodes the events of an import one at a time. Read returns io.EOF
+// after the last event, and a *RecordError for a record that is skipped.
+type Reader interface {
+	Read() (*models.ExportedEvent, error)
+}
+
+// NewReader returns a reader for "csv" or "ndjson". A CSV import starts with
+// a header row and its columns are mapped to events by mapping, which must
+// be valid. NDJSON is in the format of eventdb export; only the mapping's
+// tags and source apply to it.
+func NewReader(format string, r io.Reader, mapping Mapping) (Reader, error) {
+	switch format {
+	case "csv":
+		cr := newCSV(r)
+		if _, err := cr.Read(); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		return &csvReader{r: cr, mapping: mapping}, nil
+	case "ndjson":
+		return &ndjsonReader{r: bufio.NewReader(r), mapping: mapping}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// CSVHeader reads the header row of a CSV import and up to samples records
+// after it, for choosing the mapping
+func CSVHeader(r io.Reader, samples int) (header []string, rows [][]string, err error) {
+	cr := newCSV(r)
+	header, err = cr.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("the file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for len(rows) < samples {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	return header, rows, nil
+}
+
+// newCSV reads CSV whose rows may differ in length, dropping a leading
+// byte order mark as spreadsheets write
+func newCSV(r io.Reader) *csv.Reader {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	return cr
+}
+
+// csvReader maps the rows of a CSV import to events
+type csvReader struct {
+	r       *csv.Reader
+	mapping Mapping
+}
+
+func (c *csvReader) Read() (*models.ExportedEvent, error) {
+	record, err := c.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &RecordError{Line: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	line, _ := c.r.FieldPos(0)
+	event, err := c.mapping.event(record, time.Now())
+	if err != nil {
+		return nil, &RecordError{Line: line, Err: err}
+	}
+	return event, nil
+}
+
+// event converts a CSV record to an event created at now unless the record
+// says otherwise
+func (m *Mapping) event(record []string, now time.Time) (*models.ExportedEvent, error) {
+	event := &models.ExportedEvent{Event: models.Event{ProjectID: m.ProjectID, CreatedAt: now.UTC()}}
+	for i, target := range m.Columns {
+		if target == "" || i >= len(record) {
+			continue
+		}
+		value := strings.TrimSpace(record[i])
+		if value == "" {
+			continue
+		}
+		if name, ok := strings.CutPrefix(target, FieldColumnPrefix); ok {
+			field := models.FieldByName(m.Fields, name)
+			parsed, err := field.Parse(value)
+			if err != nil {
+				return nil, err
+			}
+			if event.Metadata == nil {
+				event.Metadata = models.Metadata{}
+			}
+			event.Metadata[name] = parsed
+			continue
+		}
+
+		switch target {
+		case "data":
+			event.Data = value
+		case "tags":
+			event.Tags = splitList(value)
+		case "source":
+			event.Source = value
+		case "created_at":
+			t, err := m.parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("created_at: %w", err)
+			}
+			event.CreatedAt = t
+		case "status":
+			status := strings.ToLower(value)
+			if !slices.Contains(models.EventStatuses, status) {
+				return nil, fmt.Errorf("status must be one of %s", strings.Join(models.EventStatuses, ", "))
+			}
+			event.Status = status
+		case "due_at":
+			t, err := m.parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("due_at: %w", err)
+			}
+			event.DueAt = &t
+		case "labels":
+			event.Labels = splitList(value)
+		case "location":
+			event.Location = value
+		case "latitude", "longitude":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a number", target)
+			}
+			if target == "latitude" {
+				event.Latitude = &n
+			} else {
+				event.Longitude = &n
+			}
+		}
+	}
+
+	if event.Data == "" {
+		return nil, fmt.Errorf("data is empty")
+	}
+	if err := models.ValidateCoordinates(event.Latitude, event.Longitude); err != nil {
+		return nil, err
+	}
+	m.complete(event)
+	return event, nil
+}
+
+// complete adds the mapping's tags to an event and gives it a source if it
+// has none
+func (m *Mapping) complete(event *models.ExportedEvent) {
+	for _, tag := range m.Tags {
+		if !slices.Contains(event.Tags, tag) {
+			event.Tags = append(event.Tags, tag)
+		}
+	}
+	if event.Source == "" {
+		event.Source = m.Source
+	}
+	if event.Source == "" {
+		event.Source = defaultImportSource
+	}
+}
+
+// parseTime reads a time in one of timeLayouts
+func (m *Mapping) parseTime(value string) (time.Time, error) {
+	location := m.Location
+	if location == nil {
+		location = time.UTC
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a time such as 2006-01-02 15:04:05 or RFC 3339", value)
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ndjsonReader decodes an NDJSON export one line at a time, so a malformed
+// line only loses that event
+type ndjsonReader struct {
+	r       *bufio.Reader
+	mapping Mapping
+	line    int
+}
+
+func (n *ndjsonReader) Read() (*models.ExportedEvent, error) {
+	for {
+		data, err := n.r.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return nil, err
+		}
+		n.line++
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		var event models.ExportedEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, &RecordError{Line: n.line, Err: fmt.Errorf("invalid JSON: %w", err)}
+		}
+		if event.Data == "" {
+			return nil, &RecordError{Line: n.line, Err: fmt.Errorf("data is empty")}
+		}
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now().UTC()
+		}
+		n.mapping.complete(&event)
+		return &event, nil
+	}
+}
@@ -0,0 +1,146 @@
+package export
+
+import (
+	"errors"
+	"example-api/internal/models"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFields = []models.CustomField{
+	{Name: "severity", Label: "Severity", Type: models.FieldEnum, Options: []string{"low", "high"}},
+	{Name: "cost", Label: "Cost", Type: models.FieldNumber},
+}
+
+func TestGuessColumn(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"data", "data"},
+		{" Created At ", "created_at"},
+		{"due-at", "due_at"},
+		{"Message", "data"},
+		{"Timestamp", "created_at"},
+		{"lng", "longitude"},
+		{"Severity", "field.severity"},
+		{"field.cost", "field.cost"},
+		{"field.unknown", ""},
+		{"notes", ""},
+	}
+	for _, tt := range tests {
+		if got := GuessColumn(tt.header, testFields); got != tt.want {
+			t.Errorf("GuessColumn(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMappingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		err     bool
+	}{
+		{name: "data only", columns: []string{"data"}},
+		{name: "skipped columns", columns: []string{"", "data", "", "field.severity"}},
+		{name: "no data", columns: []string{"tags", "source"}, err: true},
+		{name: "unknown target", columns: []string{"data", "priority"}, err: true},
+		{name: "unknown field", columns: []string{"data", "field.priority"}, err: true},
+		{name: "target mapped twice", columns: []string{"data", "tags", "tags"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Mapping{Columns: tt.columns, Fields: testFields}
+			if err := m.Validate(); (err != nil) != tt.err {
+				t.Errorf("Validate() = %v, want error %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCSVMapping(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	mapping := Mapping{
+		Columns:   []string{"data", "tags", "created_at", "status", "field.severity", "field.cost", "latitude", "longitude", "", "source"},
+		Fields:    testFields,
+		Tags:      []string{"imported", "ops"},
+		Source:    "spreadsheet",
+		ProjectID: 7,
+		Location:  berlin,
+	}
+	if err := mapping.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	lat, lon := 52.5, 13.4
+
+	tests := []struct {
+		name string
+		row  string
+		want *models.Event // nil when the row is rejected
+	}{
+		{
+			name: "every column",
+			row:  `"Disk full, db1","ops, disk,",2024-03-01 12:00,Resolved,high,12.5,52.5,13.4,ignored,nagios`,
+			want: &models.Event{
+				Data: "Disk full, db1", Tags: []string{"ops", "disk", "imported"}, Source: "nagios", Status: "resolved",
+				CreatedAt: time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), ProjectID: 7,
+				Metadata: models.Metadata{"severity": "high", "cost": 12.5}, Latitude: &lat, Longitude: &lon,
+			},
+		},
+		{
+			name: "offset overrides the location",
+			row:  `Backup ok,,2024-03-01T12:00:00Z`,
+			want: &models.Event{
+				Data: "Backup ok", Tags: []string{"imported", "ops"}, Source: "spreadsheet",
+				CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), ProjectID: 7,
+			},
+		},
+		{
+			name: "date only",
+			row:  `Backup ok,,2024-07-01`,
+			want: &models.Event{
+				Data: "Backup ok", Tags: []string{"imported", "ops"}, Source: "spreadsheet",
+				CreatedAt: time.Date(2024, 6, 30, 22, 0, 0, 0, time.UTC), ProjectID: 7,
+			},
+		},
+		{name: "empty data", row: `  ,ops,2024-03-01`},
+		{name: "bad time", row: `Backup ok,,yesterday`},
+		{name: "bad status", row: `Backup ok,,,pending`},
+		{name: "bad enum", row: `Backup ok,,,,urgent`},
+		{name: "bad number", row: `Backup ok,,,,,cheap`},
+		{name: "latitude out of range", row: `Backup ok,,,,,,91,0`},
+		{name: "unterminated quote", row: `"Backup ok`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := "\xef\xbb\xbfdata,tags,created_at,status,severity,cost,lat,lon,notes,source\n"
+			r, err := NewReader("csv", strings.NewReader(header+tt.row+"\n"), mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.Read()
+			if tt.want == nil {
+				var recordErr *RecordError
+				if !errors.As(err, &recordErr) || recordErr.Line != 2 {
+					t.Fatalf("Read() = %+v, %v, want a record error on line 2", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read(): %v", err)
+			}
+			if !reflect.DeepEqual(got.Event, *tt.want) {
+				t.Errorf("Read() = %+v, want %+v", got.Event, *tt.want)
+			}
+			if _, err := r.Read(); err != io.EOF {
+				t.Errorf("Read() after the last row = %v, want io.EOF", err)
+			}
+		})
+	}
+}
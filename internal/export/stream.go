@@ -0,0 +1,78 @@
+package export
+
+import (
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// chunkSize is how much of a streamed export is written before it is
+	// flushed to the client
+	chunkSize = 32 << 10
+	// flushInterval flushes what is written at least this often, so slow
+	// queries still show progress
+	flushInterval = time.Second
+)
+
+// Stream sends the events produced by iterate to the client as a download
+// in format, one event at a time. Whatever has been written is flushed every
+// chunkSize bytes or flushInterval, so neither the server nor a proxy holds
+// the whole export in memory. It returns the number of events written.
+//
+// Nothing is sent before the first event is written, so the caller can still
+// answer with an error when none was; after that, an error can only cut the
+// download short.
+func Stream(w http.ResponseWriter, format string, iterate func(fn func(models.Event) error) error) (int, error) {
+	counter := &countingWriter{w: w}
+	writer, err := NewWriter(format, counter)
+	if err != nil {
+		return 0, err
+	}
+
+	w.Header().Set("Content-Type", ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", Filename(format, time.Now())))
+
+	flusher, _ := w.(http.Flusher)
+	flushed, lastFlush := 0, time.Now()
+	flush := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		flushed, lastFlush = counter.n, time.Now()
+		return nil
+	}
+
+	count := 0
+	err = iterate(func(event models.Event) error {
+		if err := writer.WriteEvent(event); err != nil {
+			return err
+		}
+		count++
+		if counter.n-flushed >= chunkSize || time.Since(lastFlush) >= flushInterval {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, writer.Close()
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
@@ -0,0 +1,130 @@
+package feed
+
+import (
+	"encoding/xml"
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// titleLength is the longest entry title taken from an event's data
+const titleLength = 80
+
+// Feed describes a feed of events
+type Feed struct {
+	ID           string // Permanent feed identifier
+	Title        string
+	SelfURL      string // Where the feed itself is served
+	AlternateURL string // Web page listing the same events
+	BaseURL      string // Scheme and host used to build event links
+}
+
+// EventURL returns the web page of an event
+func (f Feed) EventURL(id int64) string {
+	return fmt.Sprintf("%s/events/%d", f.BaseURL, id)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+// WriteAtom writes the events as an Atom 1.0 feed. Events should be newest
+// first; the feed's updated time is taken from the first one.
+func (f Feed) WriteAtom(w io.Writer, events []models.Event) error {
+	updated := time.Now()
+	if len(events) > 0 {
+		updated = events[0].CreatedAt
+	}
+
+	feed := atomFeed{
+		ID:      f.ID,
+		Title:   f.Title,
+		Updated: atomTime(updated),
+		Author:  atomAuthor{Name: "Event Database"},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.SelfURL},
+			{Rel: "alternate", Type: "text/html", Href: f.AlternateURL},
+		},
+	}
+
+	for _, event := range events {
+		link := f.EventURL(event.ID)
+		entry := atomEntry{
+			ID:      link,
+			Title:   EventTitle(event),
+			Updated: atomTime(event.CreatedAt),
+			Links:   []atomLink{{Rel: "alternate", Type: "text/html", Href: link}},
+			Content: atomContent{Type: "text", Body: event.Data},
+		}
+		for _, tag := range event.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return fmt.Errorf("failed to encode atom feed: %w", err)
+	}
+	return enc.Flush()
+}
+
+// EventTitle returns a one-line title for an event: the first non-empty line
+// of its data, shortened if needed
+func EventTitle(event models.Event) string {
+	for _, line := range strings.Split(event.Data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if runes := []rune(line); len(runes) > titleLength {
+			line = string(runes[:titleLength-3]) + "..."
+		}
+		return line
+	}
+	return fmt.Sprintf("Event #%d", event.ID)
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,94 @@
+package feed
+
+import (
+	"bufio"
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CalendarEntryLength is how long each event appears in calendars. Events are
+// points in time, but zero-length entries are hidden by some calendar apps.
+const CalendarEntryLength = 15 * time.Minute
+
+// icalLineLength is the longest line, in octets, allowed by RFC 5545
+const icalLineLength = 75
+
+const icalTimeFormat = "20060102T150405Z"
+
+// WriteICal writes the events as an iCalendar (RFC 5545) feed with one entry
+// per event, starting at the event's creation time
+func (f Feed) WriteICal(w io.Writer, events []models.Event) error {
+	bw := bufio.NewWriter(w)
+	host := f.BaseURL
+	if u, err := url.Parse(f.BaseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	now := time.Now().UTC().Format(icalTimeFormat)
+
+	writeICalLine(bw, "BEGIN:VCALENDAR")
+	writeICalLine(bw, "VERSION:2.0")
+	writeICalLine(bw, "PRODID:-//Event Database//Events//EN")
+	writeICalLine(bw, "CALSCALE:GREGORIAN")
+	writeICalLine(bw, "X-WR-CALNAME:"+icalEscape(f.Title))
+
+	for _, event := range events {
+		start := event.CreatedAt.UTC()
+		writeICalLine(bw, "BEGIN:VEVENT")
+		writeICalLine(bw, fmt.Sprintf("UID:event-%d@%s", event.ID, host))
+		writeICalLine(bw, "DTSTAMP:"+now)
+		writeICalLine(bw, "DTSTART:"+start.Format(icalTimeFormat))
+		writeICalLine(bw, "DTEND:"+start.Add(CalendarEntryLength).Format(icalTimeFormat))
+		writeICalLine(bw, "SUMMARY:"+icalEscape(EventTitle(event)))
+		writeICalLine(bw, "DESCRIPTION:"+icalEscape(event.Data))
+		if len(event.Tags) > 0 {
+			escaped := make([]string, len(event.Tags))
+			for i, tag := range event.Tags {
+				escaped[i] = icalEscape(tag)
+			}
+			writeICalLine(bw, "CATEGORIES:"+strings.Join(escaped, ","))
+		}
+		writeICalLine(bw, "URL:"+f.EventURL(event.ID))
+		writeICalLine(bw, "END:VEVENT")
+	}
+
+	writeICalLine(bw, "END:VCALENDAR")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write calendar: %w", err)
+	}
+	return nil
+}
+
+// icalEscape escapes text property values
+func icalEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// writeICalLine writes a content line, folding it so no line exceeds 75
+// octets without splitting a UTF-8 character
+func writeICalLine(w *bufio.Writer, line string) {
+	limit := icalLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts toward the limit
+		limit = icalLineLength - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
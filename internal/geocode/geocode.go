@@ -0,0 +1,229 @@
+// Package geocode finds the coordinates of event locations, from a
+// configured list of places or a Nominatim geocoding service
+package geocode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lookupTimeout bounds each request to a geocoding service
+	lookupTimeout = 10 * time.Second
+	// cacheSize is how many locations are remembered; the cache starts over
+	// when it fills up
+	cacheSize = 1000
+)
+
+// Geocoder finds the coordinates of a location, such as a site name or an
+// address. found is false when the location is unknown.
+type Geocoder interface {
+	Geocode(location string) (latitude, longitude float64, found bool, err error)
+}
+
+// New creates a geocoder that looks locations up in places, keyed by name
+// with "latitude,longitude" values, and then with the Nominatim service at
+// serviceURL, if set. It returns nil when neither is configured.
+func New(places map[string]string, serviceURL, userAgent string) (Geocoder, error) {
+	var chain Chain
+	if len(places) > 0 {
+		parsed, err := ParsePlaces(places)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, parsed)
+	}
+	if serviceURL != "" {
+		if _, err := url.Parse(serviceURL); err != nil {
+			return nil, fmt.Errorf("invalid geocoding URL: %w", err)
+		}
+		chain = append(chain, Cache(NewNominatim(serviceURL, userAgent)))
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// Locate returns the coordinates geocoder finds for location, or nil ones
+// when it doesn't know it or geocoder is nil
+func Locate(geocoder Geocoder, location string) (latitude, longitude *float64, err error) {
+	if geocoder == nil || strings.TrimSpace(location) == "" {
+		return nil, nil, nil
+	}
+	lat, lon, found, err := geocoder.Geocode(location)
+	if err != nil || !found {
+		return nil, nil, err
+	}
+	return &lat, &lon, nil
+}
+
+// Places geocodes known locations, such as data center codes, by name.
+// Names are lowercase.
+type Places map[string][2]float64
+
+// ParsePlaces reads places keyed by name with "latitude,longitude" values
+func ParsePlaces(places map[string]string) (Places, error) {
+	parsed := make(Places, len(places))
+	for name, value := range places {
+		latitude, longitude, err := ParseCoordinates(value)
+		if err != nil {
+			return nil, fmt.Errorf("place %q: %w", name, err)
+		}
+		parsed[normalize(name)] = [2]float64{latitude, longitude}
+	}
+	return parsed, nil
+}
+
+// Geocode returns the coordinates of the place named location, ignoring case
+func (p Places) Geocode(location string) (float64, float64, bool, error) {
+	point, ok := p[normalize(location)]
+	return point[0], point[1], ok, nil
+}
+
+// ParseCoordinates reads "latitude,longitude" in degrees
+func ParseCoordinates(s string) (latitude, longitude float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("coordinates must be latitude,longitude")
+	}
+	if latitude, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil || latitude < -90 || latitude > 90 {
+		return 0, 0, fmt.Errorf("latitude must be a number between -90 and 90")
+	}
+	if longitude, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil || longitude < -180 || longitude > 180 {
+		return 0, 0, fmt.Errorf("longitude must be a number between -180 and 180")
+	}
+	return latitude, longitude, nil
+}
+
+// Chain tries geocoders in order, returning the first match. An error stops
+// the search.
+type Chain []Geocoder
+
+// Geocode asks each geocoder in turn
+func (c Chain) Geocode(location string) (float64, float64, bool, error) {
+	for _, geocoder := range c {
+		latitude, longitude, found, err := geocoder.Geocode(location)
+		if err != nil || found {
+			return latitude, longitude, found, err
+		}
+	}
+	return 0, 0, false, nil
+}
+
+// Nominatim geocodes with the search API of a Nominatim server, such as
+// OpenStreetMap's at https://nominatim.openstreetmap.org. Requests are sent
+// one at a time, at most one a second, as the public server's usage policy
+// asks.
+type Nominatim struct {
+	URL       string // Base URL of the server
+	UserAgent string // Identifies the application, which the public server requires
+	client    *http.Client
+
+	mu   sync.Mutex // Serializes requests
+	last time.Time  // When the last request was sent
+}
+
+// NewNominatim creates a geocoder for the Nominatim server at url
+func NewNominatim(url, userAgent string) *Nominatim {
+	return &Nominatim{URL: strings.TrimRight(url, "/"), UserAgent: userAgent, client: &http.Client{Timeout: lookupTimeout}}
+}
+
+// Geocode returns the coordinates of the server's best match for location
+func (n *Nominatim) Geocode(location string) (float64, float64, bool, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if wait := time.Second - time.Since(n.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	n.last = time.Now()
+
+	query := url.Values{"q": {location}, "format": {"jsonv2"}, "limit": {"1"}}
+	req, err := http.NewRequest(http.MethodGet, n.URL+"/search?"+query.Encode(), nil)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if n.UserAgent != "" {
+		req.Header.Set("User-Agent", n.UserAgent)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("geocoding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, 0, false, fmt.Errorf("geocoding service returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	// Nominatim returns coordinates as strings
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return 0, 0, false, fmt.Errorf("invalid geocoding response: %w", err)
+	}
+	if len(results) == 0 {
+		return 0, 0, false, nil
+	}
+	latitude, longitude, err := ParseCoordinates(results[0].Lat + "," + results[0].Lon)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("invalid geocoding response: %w", err)
+	}
+	return latitude, longitude, true, nil
+}
+
+// Cache remembers what g found, and didn't find, for each location, so
+// events from the same place don't each wait on a geocoding service. Errors
+// aren't remembered.
+func Cache(g Geocoder) Geocoder {
+	return &cache{geocoder: g, results: make(map[string]result)}
+}
+
+type cache struct {
+	geocoder Geocoder
+
+	mu      sync.Mutex
+	results map[string]result
+}
+
+type result struct {
+	latitude, longitude float64
+	found               bool
+}
+
+// Geocode returns the remembered result for location, or asks the geocoder
+func (c *cache) Geocode(location string) (float64, float64, bool, error) {
+	key := normalize(location)
+	c.mu.Lock()
+	r, ok := c.results[key]
+	c.mu.Unlock()
+	if ok {
+		return r.latitude, r.longitude, r.found, nil
+	}
+
+	latitude, longitude, found, err := c.geocoder.Geocode(location)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	c.mu.Lock()
+	if len(c.results) >= cacheSize {
+		c.results = make(map[string]result)
+	}
+	c.results[key] = result{latitude: latitude, longitude: longitude, found: found}
+	c.mu.Unlock()
+	return latitude, longitude, found, nil
+}
+
+// normalize makes lookups ignore case and surrounding space
+func normalize(location string) string {
+	return strings.ToLower(strings.TrimSpace(location))
+}
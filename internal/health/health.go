@@ -0,0 +1,131 @@
+// Package health serves the liveness and readiness endpoints orchestrators
+// probe: /livez answers while the process is up, and /readyz only when its
+// dependencies work too
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"example-api/internal/database"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds all readiness checks together
+const checkTimeout = 5 * time.Second
+
+// Check reports why a dependency isn't usable, or nil when it is
+type Check func(ctx context.Context) error
+
+// Checker runs the named readiness checks of a binary
+type Checker struct {
+	names  []string
+	checks map[string]Check
+}
+
+// New creates a checker without checks
+func New() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Add registers a readiness check
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks[name] = check
+}
+
+// status is the body of both endpoints
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Livez answers 200 as long as the process can serve requests
+func (c *Checker) Livez(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, status{Status: "ok"})
+}
+
+// Readyz runs every check and answers 200 if all pass, or 503 with the
+// failures
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	results := make(map[string]string, len(c.names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range c.names {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			result := "ok"
+			if err := check(ctx); err != nil {
+				result = err.Error()
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, c.checks[name])
+	}
+	wg.Wait()
+
+	code, body := http.StatusOK, status{Status: "ok", Checks: results}
+	for _, result := range results {
+		if result != "ok" {
+			code, body.Status = http.StatusServiceUnavailable, "unavailable"
+		}
+	}
+	writeStatus(w, code, body)
+}
+
+func writeStatus(w http.ResponseWriter, code int, body status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Database checks that the database answers
+func Database(db *database.Database) Check {
+	return db.Ping
+}
+
+// Migrations checks that every migration in dir has been applied. The files
+// are listed once, so a deployment shipping new migrations stays unready
+// until they are run.
+func Migrations(db *database.Database, dir string) (Check, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no migrations found in %s", dir)
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = filepath.Base(file)
+	}
+	sort.Strings(names)
+
+	return func(ctx context.Context) error {
+		applied, err := db.AppliedMigrations(ctx)
+		if err != nil {
+			return err
+		}
+		var pending []string
+		for _, name := range names {
+			if !applied[name] {
+				pending = append(pending, name)
+			}
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s): %s", len(pending), strings.Join(pending, ", "))
+		}
+		return nil
+	}, nil
+}
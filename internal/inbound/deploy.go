@@ -0,0 +1,339 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Headers of deploy webhooks
+const (
+	DeploySignatureHeader = "X-Signature-256" // "sha256=" and the hex HMAC-SHA256 of the body, as GitHub signs
+	GitLabTokenHeader     = "X-Gitlab-Token"
+	GitLabEventHeader     = "X-Gitlab-Event"
+	JenkinsTokenHeader    = "X-Jenkins-Token"
+)
+
+// Providers of deploy webhooks, which become the source of their events
+const (
+	DeployGitHub  = "github-actions"
+	DeployGitLab  = "gitlab-ci"
+	DeployJenkins = "jenkins"
+	DeployGeneric = "deploy"
+)
+
+// Statuses of a deploy, one of which tags its event
+const (
+	DeployStarted   = "started"
+	DeploySucceeded = "succeeded"
+	DeployFailed    = "failed"
+	DeployCancelled = "cancelled"
+)
+
+// DeployTag tags every deploy event
+const DeployTag = "deploy"
+
+// Deploy is a deployment reported by a CI system, in one shape whatever the
+// provider
+type Deploy struct {
+	Service     string `json:"service"`
+	Version     string `json:"version"`
+	Environment string `json:"environment"`
+	Status      string `json:"status"`
+	URL         string `json:"url"`    // Of the pipeline, build or deployed app
+	Actor       string `json:"actor"`  // Who started the deploy
+	Commit      string `json:"commit"` // Revision deployed
+	Description string `json:"description"`
+}
+
+// VerifyToken checks a shared secret sent as is, such as GitLab's
+// X-Gitlab-Token, in constant time
+func VerifyToken(expected, token string) error {
+	if expected == "" || !hmac.Equal([]byte(expected), []byte(token)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ParseDeploy reads the simple deploy schema:
+//
+//	{"service": "api", "version": "1.4.2", "environment": "production", "status": "succeeded"}
+//
+// A service is required; the status defaults to succeeded.
+func ParseDeploy(body []byte) (*Deploy, error) {
+	var d Deploy
+	if err := json.Unmarshal(body, &d); err != nil {
+		return nil, fmt.Errorf("invalid deploy payload: %w", err)
+	}
+	if strings.TrimSpace(d.Service) == "" {
+		return nil, fmt.Errorf("service is required")
+	}
+	status, ok := deployStatus(d.Status)
+	if !ok {
+		return nil, fmt.Errorf("unknown status %q", d.Status)
+	}
+	d.Status = status
+	return &d, nil
+}
+
+// deployStatus maps the status names CI systems use to a deploy status
+func deployStatus(status string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "started", "start", "running", "in_progress":
+		return DeployStarted, true
+	case "", "succeeded", "success", "successful", "deployed", "ok":
+		return DeploySucceeded, true
+	case "failed", "failure", "error", "unstable":
+		return DeployFailed, true
+	case "cancelled", "canceled", "aborted":
+		return DeployCancelled, true
+	}
+	return "", false
+}
+
+// githubDeployPayload holds the fields used from a deployment_status delivery
+type githubDeployPayload struct {
+	Deployment struct {
+		Environment string     `json:"environment"`
+		Ref         string     `json:"ref"`
+		SHA         string     `json:"sha"`
+		Description string     `json:"description"`
+		Creator     githubUser `json:"creator"`
+	} `json:"deployment"`
+	DeploymentStatus struct {
+		State          string `json:"state"`
+		Description    string `json:"description"`
+		TargetURL      string `json:"target_url"`
+		EnvironmentURL string `json:"environment_url"`
+	} `json:"deployment_status"`
+	Repository struct {
+		Name string `json:"name"`
+	} `json:"repository"`
+}
+
+// ParseGitHubDeploy reads a deployment_status delivery, which GitHub Actions
+// sends for jobs that deploy to an environment. It returns nil for other
+// event types, and for states that neither start nor end a deploy.
+func ParseGitHubDeploy(eventType string, body []byte) (*Deploy, error) {
+	if eventType != "deployment_status" {
+		return nil, nil
+	}
+	var p githubDeployPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid GitHub payload: %w", err)
+	}
+
+	var status string
+	switch p.DeploymentStatus.State {
+	case "in_progress":
+		status = DeployStarted
+	case "success":
+		status = DeploySucceeded
+	case "failure", "error":
+		status = DeployFailed
+	default:
+		// queued, pending and inactive
+		return nil, nil
+	}
+	d := &Deploy{
+		Service:     p.Repository.Name,
+		Version:     p.Deployment.Ref,
+		Environment: p.Deployment.Environment,
+		Status:      status,
+		URL:         p.DeploymentStatus.TargetURL,
+		Actor:       p.Deployment.Creator.Login,
+		Commit:      p.Deployment.SHA,
+		Description: p.DeploymentStatus.Description,
+	}
+	if d.URL == "" {
+		d.URL = p.DeploymentStatus.EnvironmentURL
+	}
+	if d.Description == "" {
+		d.Description = p.Deployment.Description
+	}
+	return d, nil
+}
+
+// gitlabDeployPayload holds the fields used from a deployment hook
+type gitlabDeployPayload struct {
+	ObjectKind    string `json:"object_kind"`
+	Status        string `json:"status"`
+	DeployableURL string `json:"deployable_url"`
+	Environment   string `json:"environment"`
+	Ref           string `json:"ref"`
+	ShortSHA      string `json:"short_sha"`
+	CommitTitle   string `json:"commit_title"`
+	Project       struct {
+		Name string `json:"name"`
+	} `json:"project"`
+	User struct {
+		Username string `json:"username"`
+	} `json:"user"`
+}
+
+// ParseGitLabDeploy reads a GitLab deployment hook. It returns nil for other
+// hooks, and for deployments that were only created.
+func ParseGitLabDeploy(body []byte) (*Deploy, error) {
+	var p gitlabDeployPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid GitLab payload: %w", err)
+	}
+	if p.ObjectKind != "deployment" {
+		return nil, nil
+	}
+
+	var status string
+	switch p.Status {
+	case "running":
+		status = DeployStarted
+	case "success":
+		status = DeploySucceeded
+	case "failed":
+		status = DeployFailed
+	case "canceled":
+		status = DeployCancelled
+	default:
+		return nil, nil
+	}
+	return &Deploy{
+		Service:     p.Project.Name,
+		Version:     p.Ref,
+		Environment: p.Environment,
+		Status:      status,
+		URL:         p.DeployableURL,
+		Actor:       p.User.Username,
+		Commit:      p.ShortSHA,
+		Description: p.CommitTitle,
+	}, nil
+}
+
+// jenkinsPayload holds the fields used from a Notification plugin message
+type jenkinsPayload struct {
+	Name  string `json:"name"`
+	Build *struct {
+		FullURL    string            `json:"full_url"`
+		Number     int               `json:"number"`
+		Phase      string            `json:"phase"`
+		Status     string            `json:"status"`
+		Parameters map[string]string `json:"parameters"`
+		SCM        struct {
+			Commit string `json:"commit"`
+			Branch string `json:"branch"`
+		} `json:"scm"`
+	} `json:"build"`
+}
+
+// IsJenkins reports whether a body looks like a message of the Jenkins
+// Notification plugin
+func IsJenkins(body []byte) bool {
+	var p jenkinsPayload
+	return json.Unmarshal(body, &p) == nil && p.Build != nil && p.Build.Phase != ""
+}
+
+// ParseJenkinsDeploy reads a message of the Jenkins Notification plugin for
+// a deploy job. The job name is the service. The environment and version
+// come from the build parameters ENVIRONMENT (or ENV) and VERSION; the
+// version defaults to the build number. It returns nil for the phases that
+// neither start nor end a build.
+func ParseJenkinsDeploy(body []byte) (*Deploy, error) {
+	var p jenkinsPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid Jenkins payload: %w", err)
+	}
+	if p.Build == nil {
+		return nil, fmt.Errorf("invalid Jenkins payload: no build")
+	}
+	b := p.Build
+
+	var status string
+	switch b.Phase {
+	case "STARTED":
+		status = DeployStarted
+	case "COMPLETED":
+		switch b.Status {
+		case "SUCCESS":
+			status = DeploySucceeded
+		case "ABORTED", "NOT_BUILT":
+			status = DeployCancelled
+		default:
+			status = DeployFailed
+		}
+	default:
+		// QUEUED and FINALIZED, which follows COMPLETED
+		return nil, nil
+	}
+
+	param := func(names ...string) string {
+		for _, name := range names {
+			for key, value := range b.Parameters {
+				if strings.EqualFold(key, name) && value != "" {
+					return value
+				}
+			}
+		}
+		return ""
+	}
+	d := &Deploy{
+		Service:     p.Name,
+		Version:     param("VERSION"),
+		Environment: param("ENVIRONMENT", "ENV"),
+		Status:      status,
+		URL:         b.FullURL,
+		Commit:      b.SCM.Commit,
+	}
+	if d.Version == "" {
+		d.Version = "#" + strconv.Itoa(b.Number)
+	}
+	return d, nil
+}
+
+// DeployEventRequest turns a deploy into an event tagged "deploy", the
+// service, the environment and the status, with the provider as its source
+func DeployEventRequest(provider string, d *Deploy) *models.EventRequest {
+	var data strings.Builder
+	what := d.Service
+	if d.Version != "" {
+		what += " " + d.Version
+	}
+	if d.Environment != "" {
+		what += " to " + d.Environment
+	}
+	switch d.Status {
+	case DeployStarted:
+		fmt.Fprintf(&data, "Deploying %s", what)
+	case DeployFailed:
+		fmt.Fprintf(&data, "Deploy of %s failed", what)
+	case DeployCancelled:
+		fmt.Fprintf(&data, "Deploy of %s cancelled", what)
+	default:
+		fmt.Fprintf(&data, "Deployed %s", what)
+	}
+	var details []string
+	if d.Actor != "" {
+		details = append(details, "by "+d.Actor)
+	}
+	if d.Commit != "" {
+		details = append(details, fmt.Sprintf("commit %.12s", d.Commit))
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&data, " (%s)", strings.Join(details, ", "))
+	}
+	if d.Description != "" {
+		fmt.Fprintf(&data, "\n\n%s", d.Description)
+	}
+	if d.URL != "" {
+		fmt.Fprintf(&data, "\n\n%s", d.URL)
+	}
+
+	var tags []string
+	for _, tag := range []string{DeployTag, d.Service, d.Environment, d.Status} {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return &models.EventRequest{Tags: tags, Data: data.String(), Source: provider}
+}
@@ -0,0 +1,93 @@
+package inbound
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDeploy(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want *Deploy
+		err  bool
+	}{
+		{
+			name: "full",
+			body: `{"service": "api", "version": "1.4.2", "environment": "production", "status": "failed", "actor": "ci"}`,
+			want: &Deploy{Service: "api", Version: "1.4.2", Environment: "production", Status: DeployFailed, Actor: "ci"},
+		},
+		{name: "status defaults to succeeded", body: `{"service": "api"}`, want: &Deploy{Service: "api", Status: DeploySucceeded}},
+		{name: "status alias", body: `{"service": "api", "status": " In_Progress "}`, want: &Deploy{Service: "api", Status: DeployStarted}},
+		{name: "canceled spelling", body: `{"service": "api", "status": "canceled"}`, want: &Deploy{Service: "api", Status: DeployCancelled}},
+		{name: "missing service", body: `{"version": "1.4.2"}`, err: true},
+		{name: "blank service", body: `{"service": "  "}`, err: true},
+		{name: "unknown status", body: `{"service": "api", "status": "pending"}`, err: true},
+		{name: "not JSON", body: `service=api`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDeploy([]byte(tt.body))
+			if tt.err {
+				if err == nil {
+					t.Fatalf("ParseDeploy(%s) = %+v, want an error", tt.body, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDeploy(%s): %v", tt.body, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDeploy(%s) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+// Simple deploy payloads are signed in DeploySignatureHeader as GitHub signs
+// its webhooks. Like GitHub's, the signature covers no timestamp, so there is
+// no stale case to reject.
+func TestVerifyDeploySignature(t *testing.T) {
+	const secret = "deploy-secret"
+	body := []byte(`{"service": "api", "status": "succeeded"}`)
+
+	tests := []struct {
+		name      string
+		signature string
+		body      []byte
+		want      error
+	}{
+		{name: "valid", signature: githubSignature(secret, body), body: body},
+		{name: "tampered body", signature: githubSignature(secret, body), body: []byte(`{"service": "api", "status": "failed"}`), want: ErrInvalidSignature},
+		{name: "missing header", body: body, want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyGitHub(secret, tt.signature, tt.body); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyGitHub() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		token    string
+		want     error
+	}{
+		{name: "valid", expected: "gitlab-token", token: "gitlab-token"},
+		{name: "wrong token", expected: "gitlab-token", token: "gitlab-tokem", want: ErrInvalidSignature},
+		{name: "missing header", expected: "gitlab-token", want: ErrInvalidSignature},
+		{name: "not configured", want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyToken(tt.expected, tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyToken() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"strings"
+)
+
+// Headers of a GitHub webhook delivery
+const (
+	GitHubSignatureHeader = "X-Hub-Signature-256"
+	GitHubEventHeader     = "X-GitHub-Event"
+)
+
+// VerifyGitHub checks a GitHub webhook signature: "sha256=" and the hex
+// HMAC-SHA256 of the body, keyed with the webhook secret
+func VerifyGitHub(secret, signature string, body []byte) error {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return ErrInvalidSignature
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// githubUser is the account fields used from any GitHub payload
+type githubUser struct {
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+// githubPayload holds the fields used from the supported event types
+type githubPayload struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Sender githubUser `json:"sender"`
+
+	// push
+	Ref     string     `json:"ref"`
+	Compare string     `json:"compare"`
+	Pusher  githubUser `json:"pusher"`
+	Deleted bool       `json:"deleted"`
+	Commits []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	} `json:"commits"`
+
+	// release
+	Release struct {
+		TagName    string `json:"tag_name"`
+		Name       string `json:"name"`
+		Body       string `json:"body"`
+		HTMLURL    string `json:"html_url"`
+		Prerelease bool   `json:"prerelease"`
+	} `json:"release"`
+
+	// deployment and deployment_status
+	Deployment struct {
+		Environment string     `json:"environment"`
+		Ref         string     `json:"ref"`
+		SHA         string     `json:"sha"`
+		Description string     `json:"description"`
+		Creator     githubUser `json:"creator"`
+	} `json:"deployment"`
+	DeploymentStatus struct {
+		State       string `json:"state"`
+		Description string `json:"description"`
+		TargetURL   string `json:"target_url"`
+	} `json:"deployment_status"`
+
+	// issues
+	Issue struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+		Labels  []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	} `json:"issue"`
+}
+
+// GitHubEventRequest converts a push, release, deployment, deployment_status
+// or issues delivery into an event, or returns nil for other event types. The
+// repository becomes the source; tags are "github", the event type and
+// details such as the branch, action, environment or issue labels.
+func GitHubEventRequest(eventType string, body []byte) (*models.EventRequest, error) {
+	var p githubPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid GitHub payload: %w", err)
+	}
+
+	tags := []string{"github", eventType}
+	var data strings.Builder
+	switch eventType {
+	case "push":
+		branch := strings.TrimPrefix(strings.TrimPrefix(p.Ref, "refs/heads/"), "refs/tags/")
+		tags = append(tags, branch)
+		if p.Deleted {
+			fmt.Fprintf(&data, "%s deleted %s", p.Pusher.Name, branch)
+			break
+		}
+		fmt.Fprintf(&data, "%s pushed %d commit(s) to %s\n", p.Pusher.Name, len(p.Commits), branch)
+		for _, commit := range p.Commits {
+			subject, _, _ := strings.Cut(commit.Message, "\n")
+			fmt.Fprintf(&data, "\n%.7s %s", commit.ID, subject)
+		}
+		if p.Compare != "" {
+			fmt.Fprintf(&data, "\n\n%s", p.Compare)
+		}
+
+	case "release":
+		tags = append(tags, p.Action, p.Release.TagName)
+		if p.Release.Prerelease {
+			tags = append(tags, "prerelease")
+		}
+		name := p.Release.Name
+		if name == "" {
+			name = p.Release.TagName
+		}
+		fmt.Fprintf(&data, "%s %s release %s\n\n%s", p.Sender.Login, p.Action, name, p.Release.HTMLURL)
+		if p.Release.Body != "" {
+			fmt.Fprintf(&data, "\n\n%s", p.Release.Body)
+		}
+
+	case "deployment", "deployment_status":
+		d := p.Deployment
+		tags = append(tags, d.Environment)
+		if eventType == "deployment" {
+			fmt.Fprintf(&data, "%s deployed %s (%.7s) to %s", d.Creator.Login, d.Ref, d.SHA, d.Environment)
+			if d.Description != "" {
+				fmt.Fprintf(&data, "\n\n%s", d.Description)
+			}
+			break
+		}
+		s := p.DeploymentStatus
+		tags = append(tags, s.State)
+		fmt.Fprintf(&data, "Deployment of %s (%.7s) to %s: %s", d.Ref, d.SHA, d.Environment, s.State)
+		if s.Description != "" {
+			fmt.Fprintf(&data, "\n\n%s", s.Description)
+		}
+		if s.TargetURL != "" {
+			fmt.Fprintf(&data, "\n\n%s", s.TargetURL)
+		}
+
+	case "issues":
+		tags = append(tags, p.Action)
+		for _, label := range p.Issue.Labels {
+			tags = append(tags, label.Name)
+		}
+		fmt.Fprintf(&data, "%s %s issue #%d: %s\n\n%s", p.Sender.Login, p.Action, p.Issue.Number, p.Issue.Title, p.Issue.HTMLURL)
+		if p.Issue.Body != "" && p.Action == "opened" {
+			fmt.Fprintf(&data, "\n\n%s", p.Issue.Body)
+		}
+
+	default:
+		return nil, nil
+	}
+
+	var cleaned []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !containsString(cleaned, tag) {
+			cleaned = append(cleaned, tag)
+		}
+	}
+	source := p.Repository.FullName
+	if source == "" {
+		source = "github"
+	}
+	return &models.EventRequest{Tags: cleaned, Data: data.String(), Source: source}, nil
+}
@@ -0,0 +1,42 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func githubSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GitHub doesn't sign a timestamp, so there is no stale case
+func TestVerifyGitHub(t *testing.T) {
+	const secret = "webhook-secret"
+	body := []byte(`{"action":"opened"}`)
+
+	tests := []struct {
+		name      string
+		signature string
+		body      []byte
+		want      error
+	}{
+		{name: "valid", signature: githubSignature(secret, body), body: body},
+		{name: "tampered body", signature: githubSignature(secret, body), body: []byte(`{"action":"closed"}`), want: ErrInvalidSignature},
+		{name: "other secret", signature: githubSignature("other", body), body: body, want: ErrInvalidSignature},
+		{name: "missing signature", body: body, want: ErrInvalidSignature},
+		{name: "sha1 signature", signature: "sha1=" + githubSignature(secret, body)[7:], body: body, want: ErrInvalidSignature},
+		{name: "not hex", signature: "sha256=zz", body: body, want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyGitHub(secret, tt.signature, tt.body); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyGitHub() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
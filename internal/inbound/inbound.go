@@ -0,0 +1,82 @@
+// Package inbound verifies and parses webhooks from inbound email providers
+// and other services that post events to the API
+package inbound
+
+import (
+	"errors"
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strings"
+	"time"
+)
+
+// maxTimestampSkew is how far a signed webhook's timestamp may be from now
+// before it is rejected as a replay
+const maxTimestampSkew = 15 * time.Minute
+
+var (
+	// ErrInvalidSignature is returned when a webhook's signature doesn't verify
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrStaleTimestamp is returned when a signed webhook is too old or too far
+	// in the future
+	ErrStaleTimestamp = errors.New("webhook timestamp outside the allowed window")
+)
+
+// checkTimestamp rejects signed timestamps outside maxTimestampSkew of now
+func checkTimestamp(t time.Time) error {
+	if d := time.Since(t); d > maxTimestampSkew || d < -maxTimestampSkew {
+		return ErrStaleTimestamp
+	}
+	return nil
+}
+
+// readAttachment reads an uploaded file into an attachment
+func readAttachment(fh *multipart.FileHeader, contentType string) (models.Attachment, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("failed to open attachment %s: %w", fh.Filename, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("failed to read attachment %s: %w", fh.Filename, err)
+	}
+	if contentType == "" {
+		contentType = fh.Header.Get("Content-Type")
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return models.Attachment{
+		Filename:    fh.Filename,
+		ContentType: contentType,
+		Size:        len(data),
+		Data:        data,
+	}, nil
+}
+
+// providerAuth records the DKIM and SPF verdicts an inbound provider reports,
+// or returns nil when it reports neither
+func providerAuth(provider, dkim, dkimDomain, spf, spfDomain string) *models.EmailAuth {
+	if dkim == "" && spf == "" {
+		return nil
+	}
+	return &models.EmailAuth{
+		DKIM:       strings.ToLower(dkim),
+		DKIMDomain: dkimDomain,
+		SPF:        strings.ToLower(spf),
+		SPFDomain:  spfDomain,
+		CheckedBy:  provider,
+	}
+}
+
+// addressDomain returns the lowercased domain of an email address
+func addressDomain(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return strings.ToLower(strings.Trim(address[at+1:], "<> "))
+	}
+	return ""
+}
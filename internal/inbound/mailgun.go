@@ -0,0 +1,118 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"example-api/internal/ingest"
+	"example-api/internal/models"
+	"fmt"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// VerifyMailgun checks a Mailgun webhook signature: the hex HMAC-SHA256 of
+// timestamp and token, keyed with the account's webhook signing key
+func VerifyMailgun(signingKey, timestamp, token, signature string) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte(token))
+	expected := mac.Sum(nil)
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, expected) {
+		return ErrInvalidSignature
+	}
+	return checkTimestamp(time.Unix(unix, 0))
+}
+
+// ParseMailgun converts a Mailgun inbound route post into an email. Routes
+// forwarding to a URL ending in "mime" send the raw message as body-mime;
+// otherwise the parsed fields and attachment-N files are used.
+func ParseMailgun(form *multipart.Form) (*ingest.Email, error) {
+	if raw := formValue(form, "body-mime"); raw != "" {
+		email, err := ingest.ParseMessage([]byte(raw))
+		if err != nil {
+			return nil, err
+		}
+		if recipient := formValue(form, "recipient"); recipient != "" {
+			email.Recipients = []string{recipient}
+		}
+		email.Auth = mailgunAuth(form, email)
+		return email, nil
+	}
+
+	// Message-Headers is a JSON list of [name, value] pairs
+	header := mail.Header{}
+	if raw := formValue(form, "message-headers"); raw != "" {
+		var pairs [][2]string
+		if err := json.Unmarshal([]byte(raw), &pairs); err != nil {
+			return nil, fmt.Errorf("invalid message-headers: %w", err)
+		}
+		for _, pair := range pairs {
+			name := textproto.CanonicalMIMEHeaderKey(pair[0])
+			header[name] = append(header[name], pair[1])
+		}
+	}
+
+	email := ingest.FromHeader(header)
+	if email.From == "" {
+		email.From = formValue(form, "from")
+	}
+	if email.Subject == "" {
+		email.Subject = formValue(form, "subject")
+	}
+	email.PlainBody = formValue(form, "body-plain")
+	email.HTMLBody = formValue(form, "body-html")
+	if recipient := formValue(form, "recipient"); recipient != "" {
+		email.Recipients = []string{recipient}
+	}
+
+	count, _ := strconv.Atoi(formValue(form, "attachment-count"))
+	for i := 1; i <= count; i++ {
+		files := form.File[fmt.Sprintf("attachment-%d", i)]
+		if len(files) == 0 {
+			continue
+		}
+		attachment, err := readAttachment(files[0], "")
+		if err != nil {
+			return nil, err
+		}
+		email.Attachments = append(email.Attachments, attachment)
+	}
+	email.Auth = mailgunAuth(form, email)
+
+	return email, nil
+}
+
+// mailgunAuth returns the verdicts Mailgun adds as the X-Mailgun-Spf and
+// X-Mailgun-Dkim-Check-Result fields and headers
+func mailgunAuth(form *multipart.Form, email *ingest.Email) *models.EmailAuth {
+	verdict := func(name string) string {
+		if value := formValue(form, name); value != "" {
+			return value
+		}
+		if values := email.Headers[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	return providerAuth("mailgun", verdict("X-Mailgun-Dkim-Check-Result"), "", verdict("X-Mailgun-Spf"), addressDomain(formValue(form, "sender")))
+}
+
+// formValue returns the first value of a form field
+func formValue(form *multipart.Form, name string) string {
+	if values := form.Value[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func mailgunSignature(key, timestamp, token string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Mailgun signs the timestamp and a random token rather than the body, so
+// tampering means changing the token
+func TestVerifyMailgun(t *testing.T) {
+	const key = "signing-key"
+	const token = "0123456789abcdef"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		token     string
+		signature string
+		want      error
+	}{
+		{name: "valid", timestamp: now, token: token, signature: mailgunSignature(key, now, token)},
+		{name: "tampered token", timestamp: now, token: "fedcba9876543210", signature: mailgunSignature(key, now, token), want: ErrInvalidSignature},
+		{name: "other key", timestamp: now, token: token, signature: mailgunSignature("other", now, token), want: ErrInvalidSignature},
+		{name: "timestamp changed after signing", timestamp: now, token: token, signature: mailgunSignature(key, stale, token), want: ErrInvalidSignature},
+		{name: "stale timestamp", timestamp: stale, token: token, signature: mailgunSignature(key, stale, token), want: ErrStaleTimestamp},
+		{name: "missing signature", timestamp: now, token: token, want: ErrInvalidSignature},
+		{name: "missing timestamp", token: token, signature: mailgunSignature(key, "", token), want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyMailgun(key, tt.timestamp, tt.token, tt.signature); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyMailgun() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
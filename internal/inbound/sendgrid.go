@@ -0,0 +1,171 @@
+package inbound
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"example-api/internal/ingest"
+	"example-api/internal/models"
+	"example-api/internal/utils"
+	"fmt"
+	"mime/multipart"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers carrying SendGrid's signature of a signed webhook
+const (
+	SendGridSignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	SendGridTimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// ParseSendGridKey parses the base64 DER public key SendGrid shows for a
+// signed webhook
+func ParseSendGridKey(key string) (*ecdsa.PublicKey, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SendGrid public key: %w", err)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SendGrid public key: %w", err)
+	}
+	pub, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("SendGrid public key is not an ECDSA key")
+	}
+	return pub, nil
+}
+
+// VerifySendGrid checks a SendGrid webhook signature: a base64 ECDSA
+// signature of the SHA-256 of the timestamp header followed by the raw body
+func VerifySendGrid(key *ecdsa.PublicKey, signature, timestamp string, body []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	digest := sha256.Sum256(append([]byte(timestamp), body...))
+	if !ecdsa.VerifyASN1(key, digest[:], sig) {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	return checkTimestamp(time.Unix(unix, 0))
+}
+
+// ParseSendGrid converts a SendGrid Inbound Parse post into an email. With
+// "POST the raw, full MIME message" enabled the message arrives in the email
+// field; otherwise the parsed headers, text, html and attachmentN files are
+// used.
+func ParseSendGrid(form *multipart.Form) (*ingest.Email, error) {
+	var envelope struct {
+		To   []string `json:"to"`
+		From string   `json:"from"`
+	}
+	if raw := formValue(form, "envelope"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &envelope); err != nil {
+			return nil, fmt.Errorf("invalid envelope: %w", err)
+		}
+	}
+
+	if raw := formValue(form, "email"); raw != "" {
+		email, err := ingest.ParseMessage([]byte(raw))
+		if err != nil {
+			return nil, err
+		}
+		email.Recipients = envelope.To
+		email.Auth = sendGridAuth(form, envelope.From)
+		return email, nil
+	}
+
+	// headers is the raw header block of the message
+	header := mail.Header{}
+	if raw := formValue(form, "headers"); raw != "" {
+		msg, err := mail.ReadMessage(strings.NewReader(strings.TrimRight(raw, "\r\n") + "\r\n\r\n"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid headers: %w", err)
+		}
+		header = msg.Header
+	}
+
+	// charsets names the charset of each field, e.g. {"text": "iso-8859-1"}
+	charsets := map[string]string{}
+	if raw := formValue(form, "charsets"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &charsets); err != nil {
+			return nil, fmt.Errorf("invalid charsets: %w", err)
+		}
+	}
+	field := func(name string) string {
+		return utils.ToUTF8([]byte(formValue(form, name)), charsets[name])
+	}
+
+	email := ingest.FromHeader(header)
+	if email.From == "" {
+		email.From = field("from")
+	}
+	if email.To == "" {
+		email.To = field("to")
+	}
+	if email.Subject == "" {
+		email.Subject = field("subject")
+	}
+	email.PlainBody = field("text")
+	email.HTMLBody = field("html")
+	email.Recipients = envelope.To
+
+	// attachment-info maps attachmentN to its file name and type
+	var info map[string]struct {
+		Filename string `json:"filename"`
+		Type     string `json:"type"`
+	}
+	if raw := formValue(form, "attachment-info"); raw != "" {
+		if err := json.Unmarshal(bytes.TrimSpace([]byte(raw)), &info); err != nil {
+			return nil, fmt.Errorf("invalid attachment-info: %w", err)
+		}
+	}
+	count, _ := strconv.Atoi(formValue(form, "attachments"))
+	for i := 1; i <= count; i++ {
+		name := fmt.Sprintf("attachment%d", i)
+		files := form.File[name]
+		if len(files) == 0 {
+			continue
+		}
+		attachment, err := readAttachment(files[0], info[name].Type)
+		if err != nil {
+			return nil, err
+		}
+		if info[name].Filename != "" {
+			attachment.Filename = info[name].Filename
+		}
+		email.Attachments = append(email.Attachments, attachment)
+	}
+	email.Auth = sendGridAuth(form, envelope.From)
+
+	return email, nil
+}
+
+// sendGridAuth returns the verdicts in the SPF and dkim fields. dkim lists
+// each signature's domain and result, e.g. "{@example.com : pass}"; it passes
+// if any signature passed.
+func sendGridAuth(form *multipart.Form, envelopeFrom string) *models.EmailAuth {
+	var dkim, dkimDomain string
+	for _, signature := range strings.Split(strings.Trim(formValue(form, "dkim"), "{} "), ",") {
+		domain, result, ok := strings.Cut(signature, ":")
+		if !ok {
+			continue
+		}
+		domain, result = strings.TrimPrefix(strings.TrimSpace(domain), "@"), strings.ToLower(strings.TrimSpace(result))
+		if dkim != "pass" {
+			dkim, dkimDomain = result, domain
+		}
+	}
+	return providerAuth("sendgrid", dkim, dkimDomain, formValue(form, "SPF"), addressDomain(envelopeFrom))
+}
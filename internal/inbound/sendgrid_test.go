@@ -0,0 +1,78 @@
+package inbound
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySendGrid(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The key is configured as SendGrid shows it
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseSendGridKey(base64.StdEncoding.EncodeToString(der))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(signer *ecdsa.PrivateKey, timestamp string, body []byte) string {
+		digest := sha256.Sum256(append([]byte(timestamp), body...))
+		sig, err := ecdsa.SignASN1(rand.Reader, signer, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+	body := []byte(`[{"event":"delivered"}]`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		signature string
+		timestamp string
+		body      []byte
+		want      error
+	}{
+		{name: "valid", signature: sign(private, now, body), timestamp: now, body: body},
+		{name: "tampered body", signature: sign(private, now, body), timestamp: now, body: []byte(`[{"event":"bounce"}]`), want: ErrInvalidSignature},
+		{name: "other key", signature: sign(other, now, body), timestamp: now, body: body, want: ErrInvalidSignature},
+		{name: "timestamp changed after signing", signature: sign(private, stale, body), timestamp: now, body: body, want: ErrInvalidSignature},
+		{name: "stale timestamp", signature: sign(private, stale, body), timestamp: stale, body: body, want: ErrStaleTimestamp},
+		{name: "missing signature", timestamp: now, body: body, want: ErrInvalidSignature},
+		{name: "missing timestamp", signature: sign(private, "", body), body: body, want: ErrInvalidSignature},
+		{name: "not base64", signature: "%%%", timestamp: now, body: body, want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifySendGrid(key, tt.signature, tt.timestamp, tt.body); !errors.Is(err, tt.want) {
+				t.Errorf("VerifySendGrid() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSendGridKeyRejectsInvalidKeys(t *testing.T) {
+	for _, key := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("not a key"))} {
+		if _, err := ParseSendGridKey(key); err == nil {
+			t.Errorf("ParseSendGridKey(%q) succeeded", key)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package inbound
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"example-api/internal/ingest"
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SNS message types
+const (
+	SNSSubscriptionConfirmation = "SubscriptionConfirmation"
+	SNSNotification             = "Notification"
+	SNSUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+)
+
+// snsHost matches the hosts SNS signing certificates and subscription
+// confirmations are served from
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// SNSMessage is an Amazon SNS HTTP(S) delivery
+type SNSMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	Token            string `json:"Token"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+	SubscribeURL     string `json:"SubscribeURL"`
+}
+
+// SNSVerifier checks SNS message signatures, caching signing certificates
+type SNSVerifier struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+// NewSNSVerifier creates an SNSVerifier
+func NewSNSVerifier() *SNSVerifier {
+	return &SNSVerifier{
+		client: &http.Client{Timeout: 10 * time.Second},
+		certs:  make(map[string]*x509.Certificate),
+	}
+}
+
+// Verify checks the message's signature against the AWS certificate it names
+func (v *SNSVerifier) Verify(msg *SNSMessage) error {
+	var hash crypto.Hash
+	switch msg.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return ErrInvalidSignature
+	}
+
+	cert, err := v.certificate(msg.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("SNS signing certificate does not hold an RSA key")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(msg.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	var digest []byte
+	if hash == crypto.SHA1 {
+		sum := sha1.Sum([]byte(msg.stringToSign()))
+		digest = sum[:]
+	} else {
+		sum := sha256.Sum256([]byte(msg.stringToSign()))
+		digest = sum[:]
+	}
+	if err := rsa.VerifyPKCS1v15(pub, hash, digest, sig); err != nil {
+		return ErrInvalidSignature
+	}
+
+	sent, err := time.Parse(time.RFC3339, msg.Timestamp)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	// Confirmations are only valid for a few days; notifications are checked
+	// like other signed webhooks
+	if msg.Type == SNSNotification {
+		return checkTimestamp(sent)
+	}
+	return nil
+}
+
+// Confirm visits the SubscribeURL of a verified subscription confirmation
+func (v *SNSVerifier) Confirm(msg *SNSMessage) error {
+	u, err := url.Parse(msg.SubscribeURL)
+	if err != nil || u.Scheme != "https" || !snsHost.MatchString(u.Hostname()) {
+		return fmt.Errorf("refusing to confirm subscription at %q", msg.SubscribeURL)
+	}
+	resp, err := v.client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("failed to confirm subscription: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to confirm subscription: %s", resp.Status)
+	}
+	return nil
+}
+
+// certificate fetches, or returns the cached, signing certificate at certURL
+// after checking it is served by SNS
+func (v *SNSVerifier) certificate(certURL string) (*x509.Certificate, error) {
+	u, err := url.Parse(certURL)
+	if err != nil || u.Scheme != "https" || !snsHost.MatchString(u.Hostname()) || !strings.HasSuffix(u.Path, ".pem") {
+		return nil, fmt.Errorf("untrusted SNS signing certificate URL %q", certURL)
+	}
+
+	v.mu.Lock()
+	cert, ok := v.certs[certURL]
+	v.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	resp, err := v.client.Get(certURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SNS signing certificate: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch SNS signing certificate: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SNS signing certificate: %w", err)
+	}
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, fmt.Errorf("SNS signing certificate is not PEM encoded")
+	}
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNS signing certificate: %w", err)
+	}
+
+	v.mu.Lock()
+	v.certs[certURL] = cert
+	v.mu.Unlock()
+	return cert, nil
+}
+
+// stringToSign builds the canonical "Name\nvalue\n" form SNS signs
+func (msg *SNSMessage) stringToSign() string {
+	var fields [][2]string
+	if msg.Type == SNSNotification {
+		fields = [][2]string{
+			{"Message", msg.Message},
+			{"MessageId", msg.MessageID},
+			{"Subject", msg.Subject},
+			{"Timestamp", msg.Timestamp},
+			{"TopicArn", msg.TopicArn},
+			{"Type", msg.Type},
+		}
+	} else {
+		fields = [][2]string{
+			{"Message", msg.Message},
+			{"MessageId", msg.MessageID},
+			{"SubscribeURL", msg.SubscribeURL},
+			{"Timestamp", msg.Timestamp},
+			{"Token", msg.Token},
+			{"TopicArn", msg.TopicArn},
+			{"Type", msg.Type},
+		}
+	}
+
+	var b strings.Builder
+	for _, f := range fields {
+		// Subject is only signed when present
+		if f[0] == "Subject" && f[1] == "" {
+			continue
+		}
+		b.WriteString(f[0])
+		b.WriteByte('\n')
+		b.WriteString(f[1])
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// sesNotification is the part of an SES receipt notification that is used
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	Mail             struct {
+		Source        string   `json:"source"`
+		Destination   []string `json:"destination"`
+		MessageID     string   `json:"messageId"`
+		CommonHeaders struct {
+			From      []string `json:"from"`
+			To        []string `json:"to"`
+			Cc        []string `json:"cc"`
+			Subject   string   `json:"subject"`
+			MessageID string   `json:"messageId"`
+			Date      string   `json:"date"`
+		} `json:"commonHeaders"`
+	} `json:"mail"`
+	Receipt struct {
+		SPFVerdict  sesVerdict `json:"spfVerdict"`
+		DKIMVerdict sesVerdict `json:"dkimVerdict"`
+		Action      struct {
+			Type     string `json:"type"`
+			Encoding string `json:"encoding"`
+		} `json:"action"`
+	} `json:"receipt"`
+	Content string `json:"content"`
+}
+
+// sesVerdict is one of SES's spam and authentication verdicts
+type sesVerdict struct {
+	Status string `json:"status"` // PASS, FAIL, GRAY or PROCESSING_FAILED
+}
+
+// result returns the verdict as an RFC 8601 result name
+func (v sesVerdict) result() string {
+	switch v.Status {
+	case "PASS":
+		return "pass"
+	case "FAIL":
+		return "fail"
+	case "GRAY":
+		return "none"
+	case "PROCESSING_FAILED":
+		return "temperror"
+	}
+	return ""
+}
+
+// sesAuth returns the receipt's DKIM and SPF verdicts
+func sesAuth(n *sesNotification) *models.EmailAuth {
+	return providerAuth("ses", n.Receipt.DKIMVerdict.result(), "", n.Receipt.SPFVerdict.result(), addressDomain(n.Mail.Source))
+}
+
+// ParseSES converts the SES receipt notification carried by an SNS message
+// into an email. The SNS receipt action includes the raw message (up to
+// 150 KB); other actions, such as S3, only carry the common headers, so the
+// event has no body.
+func ParseSES(message string) (*ingest.Email, error) {
+	var n sesNotification
+	if err := json.Unmarshal([]byte(message), &n); err != nil {
+		return nil, fmt.Errorf("invalid SES notification: %w", err)
+	}
+	if n.NotificationType != "Received" {
+		return nil, fmt.Errorf("unsupported SES notification type %q", n.NotificationType)
+	}
+
+	if n.Content != "" {
+		raw := []byte(n.Content)
+		if strings.EqualFold(n.Receipt.Action.Encoding, "BASE64") {
+			decoded, err := base64.StdEncoding.DecodeString(n.Content)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SES content: %w", err)
+			}
+			raw = decoded
+		}
+		email, err := ingest.ParseMessage(raw)
+		if err != nil {
+			return nil, err
+		}
+		email.Recipients = n.Mail.Destination
+		email.Auth = sesAuth(&n)
+		return email, nil
+	}
+
+	headers := n.Mail.CommonHeaders
+	email := &ingest.Email{
+		From:       strings.Join(headers.From, ", "),
+		To:         strings.Join(headers.To, ", "),
+		Cc:         headers.Cc,
+		Subject:    headers.Subject,
+		MessageID:  headers.MessageID,
+		Recipients: n.Mail.Destination,
+		Auth:       sesAuth(&n),
+	}
+	if date, err := time.Parse(time.RFC1123Z, headers.Date); err == nil {
+		email.Date = date
+	}
+	return email, nil
+}
@@ -0,0 +1,165 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers carrying Slack's request signature
+const (
+	SlackSignatureHeader = "X-Slack-Signature"
+	SlackTimestampHeader = "X-Slack-Request-Timestamp"
+)
+
+// slackHashtag matches #words in message text. Channel links are sent as
+// <#C123|name>, so they don't match.
+var slackHashtag = regexp.MustCompile(`(?:^|\s)#([\w-]+)`)
+
+// slackLink matches Slack's <target|label> and <target> markup
+var slackLink = regexp.MustCompile(`<([^<>|]*)(?:\|([^<>]*))?>`)
+
+// VerifySlack checks a Slack request signature: "v0=" and the hex
+// HMAC-SHA256 of "v0:timestamp:body", keyed with the app's signing secret
+func VerifySlack(signingSecret, signature, timestamp string, body []byte) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "v0="))
+	if err != nil || !strings.HasPrefix(signature, "v0=") || !hmac.Equal(sig, expected) {
+		return ErrInvalidSignature
+	}
+	return checkTimestamp(time.Unix(unix, 0))
+}
+
+// SlackPayload is a request from the Slack Events API or a workflow webhook
+// step. Events API requests have a type; workflow steps post their variables
+// as a flat object.
+type SlackPayload struct {
+	Type      string      `json:"type"`
+	Challenge string      `json:"challenge"`
+	EventID   string      `json:"event_id"`
+	Event     *SlackEvent `json:"event"`
+
+	// Workflow variables
+	Channel  string `json:"channel"`
+	Text     string `json:"text"`
+	User     string `json:"user"`
+	Keywords string `json:"keywords"`
+}
+
+// SlackEvent is the inner event of an Events API callback
+type SlackEvent struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype"`
+	Channel string `json:"channel"`
+	User    string `json:"user"`
+	BotID   string `json:"bot_id"`
+	Text    string `json:"text"`
+	TS      string `json:"ts"`
+}
+
+// ParseSlack decodes a Slack request body
+func ParseSlack(body []byte) (*SlackPayload, error) {
+	var p SlackPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("invalid Slack payload: %w", err)
+	}
+	return &p, nil
+}
+
+// SlackEventRequest converts a message into an event, or returns nil for
+// payloads that aren't new messages (edits, deletions, joins and other event
+// types). The channel becomes the source. Tags are "slack", the configured
+// keywords found in the text, #hashtags and, for workflows, the keywords
+// variable.
+func SlackEventRequest(p *SlackPayload, keywords []string) *models.EventRequest {
+	var channel, user, text string
+	var extra []string
+	switch {
+	case p.Type == "event_callback" && p.Event != nil:
+		e := p.Event
+		if (e.Type != "message" && e.Type != "app_mention") || (e.Subtype != "" && e.Subtype != "bot_message") {
+			return nil
+		}
+		channel, user, text = e.Channel, e.User, e.Text
+		if user == "" {
+			user = e.BotID
+		}
+	case p.Type == "" && p.Text != "":
+		channel, user, text = p.Channel, p.User, p.Text
+		extra = strings.FieldsFunc(p.Keywords, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n'
+		})
+	default:
+		return nil
+	}
+
+	text = slackText(text)
+	tags := []string{"slack"}
+	addTag := func(tag string) {
+		tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+		if tag != "" && !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	lower := strings.ToLower(text)
+	for _, keyword := range keywords {
+		if strings.Contains(lower, strings.ToLower(keyword)) {
+			addTag(keyword)
+		}
+	}
+	for _, m := range slackHashtag.FindAllStringSubmatch(text, -1) {
+		addTag(m[1])
+	}
+	for _, keyword := range extra {
+		addTag(keyword)
+	}
+
+	data := text
+	if user != "" {
+		data = fmt.Sprintf("%s: %s", user, text)
+	}
+	source := strings.TrimPrefix(channel, "#")
+	if source == "" {
+		source = "slack"
+	}
+	return &models.EventRequest{Tags: tags, Data: data, Source: source}
+}
+
+// slackText turns Slack message markup into plain text: links show their
+// label (or target) and the &, < and > escapes are undone
+func slackText(text string) string {
+	text = slackLink.ReplaceAllStringFunc(text, func(s string) string {
+		m := slackLink.FindStringSubmatch(s)
+		if m[2] != "" {
+			return m[2]
+		}
+		return strings.TrimPrefix(m[1], "!")
+	})
+	return html.UnescapeString(text)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
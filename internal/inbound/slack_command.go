@@ -0,0 +1,249 @@
+package inbound
+
+import (
+	"example-api/internal/models"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SlackCommand is a slash command invocation, which Slack posts form-encoded
+type SlackCommand struct {
+	Command     string // Such as "/events"
+	Text        string // What follows the command
+	UserName    string
+	ChannelName string
+}
+
+// ParseSlackCommand decodes the body of a slash command request
+func ParseSlackCommand(body []byte) (*SlackCommand, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Slack command: %w", err)
+	}
+	return &SlackCommand{
+		Command:     values.Get("command"),
+		Text:        strings.TrimSpace(values.Get("text")),
+		UserName:    values.Get("user_name"),
+		ChannelName: values.Get("channel_name"),
+	}, nil
+}
+
+// SlackQuery is the event search a slash command asks for, such as
+// "tag:outage source:nagios last:24h disk full". Words without a known
+// prefix are searched for in the events' data.
+type SlackQuery struct {
+	Tags   []string // Events with any of these
+	Source string
+	Status string
+	Last   time.Duration // Only events this recent, unless zero
+	Text   string
+}
+
+// ParseSlackQuery reads the text of a slash command
+func ParseSlackQuery(text string) (*SlackQuery, error) {
+	q := &SlackQuery{}
+	var words []string
+	for _, word := range strings.Fields(slackText(text)) {
+		key, value, found := strings.Cut(word, ":")
+		if !found || value == "" {
+			words = append(words, word)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "tag":
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#")); tag != "" {
+					q.Tags = append(q.Tags, tag)
+				}
+			}
+		case "source":
+			q.Source = value
+		case "status":
+			q.Status = strings.ToLower(value)
+			if !slices.Contains(models.EventStatuses, q.Status) {
+				return nil, fmt.Errorf("unknown status %q, expected open, acknowledged, resolved or ignored", value)
+			}
+		case "last":
+			last, err := parseSlackDuration(value)
+			if err != nil {
+				return nil, err
+			}
+			q.Last = last
+		default:
+			words = append(words, word)
+		}
+	}
+	q.Text = strings.Join(words, " ")
+	return q, nil
+}
+
+// parseSlackDuration reads a duration such as 30m, 24h, 7d or 2w
+func parseSlackDuration(s string) (time.Duration, error) {
+	units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	s = strings.ToLower(s)
+	unit, ok := units[s[len(s)-1]]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if !ok || err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid last:%s, expected a duration such as 30m, 24h or 7d", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// formatSlackDuration writes a duration in the units parseSlackDuration
+// reads, keeping a single day in hours as people usually ask for it
+func formatSlackDuration(d time.Duration) string {
+	switch day := 24 * time.Hour; {
+	case d%day == 0 && d > day:
+		return fmt.Sprintf("%dd", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// Filter returns the event filter of the query, for events created up to now
+func (q *SlackQuery) Filter(now time.Time) models.EventFilter {
+	filter := models.EventFilter{
+		Tags:     q.Tags,
+		Source:   q.Source,
+		Status:   q.Status,
+		Query:    q.Text,
+		SortBy:   "created_at",
+		SortDesc: true,
+	}
+	if q.Last > 0 {
+		filter.Since = now.Add(-q.Last)
+	}
+	return filter
+}
+
+// describe says what the query selects, such as "tagged `outage` in the
+// last 24h"
+func (q *SlackQuery) describe() string {
+	var parts []string
+	if len(q.Tags) > 0 {
+		tags := make([]string, len(q.Tags))
+		for i, tag := range q.Tags {
+			tags[i] = "`" + slackEscape(tag) + "`"
+		}
+		parts = append(parts, "tagged "+strings.Join(tags, " or "))
+	}
+	if q.Source != "" {
+		parts = append(parts, "from `"+slackEscape(q.Source)+"`")
+	}
+	if q.Status != "" {
+		parts = append(parts, "with status "+q.Status)
+	}
+	if q.Text != "" {
+		parts = append(parts, "matching \""+slackEscape(q.Text)+"\"")
+	}
+	if q.Last > 0 {
+		parts = append(parts, "in the last "+formatSlackDuration(q.Last))
+	}
+	return strings.Join(parts, " ")
+}
+
+// webURL links to the query's events in the web interface at baseURL. The
+// web interface filters whole days, so it may list a few older events.
+func (q *SlackQuery) webURL(baseURL string, now time.Time) string {
+	query := url.Values{}
+	for _, tag := range q.Tags {
+		query.Add("tag", tag)
+	}
+	for name, value := range map[string]string{"source": q.Source, "status": q.Status, "q": q.Text} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if q.Last > 0 {
+		query.Set("from", now.Add(-q.Last).UTC().Format("2006-01-02"))
+	}
+	return baseURL + "/?" + query.Encode()
+}
+
+// SlackReply is the response to a slash command. Ephemeral replies are only
+// shown to the user who ran the command.
+type SlackReply struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// SlackMessage replies with a message only the user sees
+func SlackMessage(text string) *SlackReply {
+	return &SlackReply{ResponseType: "ephemeral", Text: text}
+}
+
+// SlackUsage explains the slash command
+func SlackUsage(command string) *SlackReply {
+	if command == "" {
+		command = "/events"
+	}
+	return SlackMessage(fmt.Sprintf("Search events with `%s [tag:name] [source:name] [status:open] [last:24h] [words]`, such as `%s tag:outage last:24h`. "+
+		"Tags can be separated by commas to match any of them, and `last` takes minutes, hours, days or weeks (30m, 24h, 7d, 2w).", command, command))
+}
+
+// SlackQueryReply summarizes the events of a query: how many match and the
+// newest of them, linked to the web interface at baseURL when it is set
+func SlackQueryReply(q *SlackQuery, events []models.Event, total int, baseURL string, now time.Time) *SlackReply {
+	baseURL = strings.TrimRight(baseURL, "/")
+	what := q.describe()
+	if what != "" {
+		what = " " + what
+	}
+	if total == 0 {
+		return SlackMessage("No events" + what + ".")
+	}
+
+	var text strings.Builder
+	noun := "events"
+	if total == 1 {
+		noun = "event"
+	}
+	fmt.Fprintf(&text, "*%d %s*%s", total, noun, what)
+	if len(events) < total {
+		fmt.Fprintf(&text, ", newest %d", len(events))
+	}
+	text.WriteString(":\n")
+	for _, event := range events {
+		id := fmt.Sprintf("#%d", event.ID)
+		if baseURL != "" {
+			id = fmt.Sprintf("<%s/events/%d|#%d>", baseURL, event.ID, event.ID)
+		}
+		// Slack shows the date in the reader's time zone
+		created := fmt.Sprintf("<!date^%d^{date_short} {time}|%s>",
+			event.CreatedAt.Unix(), event.CreatedAt.UTC().Format("Jan 02 15:04 UTC"))
+		fmt.Fprintf(&text, "• %s %s · %s", id, created, slackEscape(event.Source))
+		if event.Status != "" && event.Status != models.StatusOpen {
+			fmt.Fprintf(&text, " · _%s_", event.Status)
+		}
+		fmt.Fprintf(&text, " · %s\n", slackEscape(summaryLine(event.Data, 100)))
+	}
+	if baseURL != "" {
+		fmt.Fprintf(&text, "<%s|View all in eventdb>", slackEscape(q.webURL(baseURL, now)))
+	}
+	return SlackMessage(strings.TrimRight(text.String(), "\n"))
+}
+
+// summaryLine returns the first non-empty line of data, cut to max runes
+func summaryLine(data string, max int) string {
+	line := ""
+	for _, l := range strings.Split(data, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			line = l
+			break
+		}
+	}
+	if runes := []rune(line); len(runes) > max {
+		line = string(runes[:max-3]) + "..."
+	}
+	return line
+}
+
+// slackEscape escapes the characters Slack reads as markup
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,81 @@
+package inbound
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSlackQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want *SlackQuery
+		err  bool
+	}{
+		{name: "empty", text: "", want: &SlackQuery{}},
+		{
+			name: "all prefixes",
+			text: "tag:outage source:nagios status:Open last:24h disk full",
+			want: &SlackQuery{Tags: []string{"outage"}, Source: "nagios", Status: "open", Last: 24 * time.Hour, Text: "disk full"},
+		},
+		{name: "tag list", text: "tag:a,#b", want: &SlackQuery{Tags: []string{"a", "b"}}},
+		{name: "tag list with empty entries", text: "tag:A,,#", want: &SlackQuery{Tags: []string{"a"}}},
+		{name: "last in weeks", text: "last:2w", want: &SlackQuery{Last: 14 * 24 * time.Hour}},
+		{name: "last without unit", text: "last:1", err: true},
+		{name: "last without number", text: "last:h", err: true},
+		{name: "last of zero", text: "last:0d", err: true},
+		{name: "last in seconds", text: "last:30s", err: true},
+		{name: "unknown status", text: "status:bogus", err: true},
+		{name: "unknown prefix is text", text: "error:timeout", want: &SlackQuery{Text: "error:timeout"}},
+		{name: "text with a colon", text: "db: connection refused", want: &SlackQuery{Text: "db: connection refused"}},
+		{name: "empty value is text", text: "tag: outage", want: &SlackQuery{Text: "tag: outage"}},
+		{name: "link shows its label", text: "<https://status.example.com|status page> down", want: &SlackQuery{Text: "status page down"}},
+		{name: "escapes are undone", text: "a &lt;b&gt; &amp; c", want: &SlackQuery{Text: "a <b> & c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSlackQuery(tt.text)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("ParseSlackQuery(%q) = %+v, want an error", tt.text, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSlackQuery(%q): %v", tt.text, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSlackQuery(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSlackDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Minute, "30m"},
+		{90 * time.Minute, "90m"},
+		{time.Hour, "1h"},
+		{24 * time.Hour, "24h"},
+		{48 * time.Hour, "2d"},
+		{14 * 24 * time.Hour, "14d"},
+	}
+	for _, tt := range tests {
+		if got := formatSlackDuration(tt.d); got != tt.want {
+			t.Errorf("formatSlackDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestSlackQueryReplyEscapes(t *testing.T) {
+	q := &SlackQuery{Source: "a<b", Text: "x & <y>"}
+	reply := SlackQueryReply(q, nil, 0, "", time.Now())
+	want := "No events from `a&lt;b` matching \"x &amp; &lt;y&gt;\"."
+	if reply.Text != want {
+		t.Errorf("reply = %q, want %q", reply.Text, want)
+	}
+}
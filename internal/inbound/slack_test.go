@@ -0,0 +1,49 @@
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func slackSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySlack(t *testing.T) {
+	const secret = "signing-secret"
+	body := []byte(`{"type":"event_callback"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		signature string
+		timestamp string
+		body      []byte
+		want      error
+	}{
+		{name: "valid", signature: slackSignature(secret, now, body), timestamp: now, body: body},
+		{name: "tampered body", signature: slackSignature(secret, now, body), timestamp: now, body: []byte(`{"type":"url_verification"}`), want: ErrInvalidSignature},
+		{name: "other secret", signature: slackSignature("other", now, body), timestamp: now, body: body, want: ErrInvalidSignature},
+		{name: "timestamp changed after signing", signature: slackSignature(secret, stale, body), timestamp: now, body: body, want: ErrInvalidSignature},
+		{name: "stale timestamp", signature: slackSignature(secret, stale, body), timestamp: stale, body: body, want: ErrStaleTimestamp},
+		{name: "missing signature", timestamp: now, body: body, want: ErrInvalidSignature},
+		{name: "missing timestamp", signature: slackSignature(secret, "", body), body: body, want: ErrInvalidSignature},
+		{name: "missing prefix", signature: slackSignature(secret, now, body)[3:], timestamp: now, body: body, want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifySlack(secret, tt.signature, tt.timestamp, tt.body); !errors.Is(err, tt.want) {
+				t.Errorf("VerifySlack() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package ingest
+
+import (
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrRejected is returned by Ingest when a rule or the spam score rejects
+	// an email; nothing is stored
+	ErrRejected = errors.New("email rejected")
+	// ErrQuarantined is returned by Ingest when an email is held in the
+	// quarantine for review instead of being stored as an event
+	ErrQuarantined = errors.New("email quarantined")
+)
+
+// Actions for emails that match no allow rule
+const (
+	ActionAccept     = "accept"
+	ActionQuarantine = "quarantine"
+	ActionReject     = "reject"
+)
+
+// FilterRules decide which senders and sources are accepted. Patterns are
+// shell globs matched case-insensitively, such as "*@example.com".
+type FilterRules struct {
+	AllowSenders  []string
+	DenySenders   []string
+	AllowSources  []string
+	DenySources   []string
+	UnknownAction string // For senders and sources not allowed when allow rules are set; defaults to quarantine
+}
+
+// ConfigureFilters replaces the "filter" and "spam" processors with ones using
+// the given rules and spam checker. A nil checker disables spam scoring. It
+// must be called before pipelines are set.
+func ConfigureFilters(rules FilterRules, checker SpamChecker, quarantineScore, rejectScore float64) error {
+	switch rules.UnknownAction {
+	case "", ActionAccept, ActionQuarantine, ActionReject:
+	default:
+		return fmt.Errorf("unknown action %q, want accept, quarantine or reject", rules.UnknownAction)
+	}
+	RegisterProcessor("filter", NewFilter(rules))
+	RegisterProcessor("spam", NewSpamCheck(checker, quarantineScore, rejectScore))
+	return nil
+}
+
+// NewFilter creates the "filter" processor for rules. Denied senders and
+// sources are rejected. When allow rules are set, emails matching none of
+// them get rules.UnknownAction.
+func NewFilter(rules FilterRules) ProcessorFactory {
+	if rules.UnknownAction == "" {
+		rules.UnknownAction = ActionQuarantine
+	}
+	return func(db *database.Database) Processor {
+		return &filter{db: db, rules: rules}
+	}
+}
+
+type filter struct {
+	db    *database.Database
+	rules FilterRules
+}
+
+// Process applies the rules to the email's sender and the event's source
+func (f *filter) Process(item *Item) error {
+	sender := senderAddress(item.Email)
+	source := item.Event.Source
+
+	if matchAny(f.rules.DenySenders, sender) {
+		return reject(item, fmt.Sprintf("sender %s is denied", sender))
+	}
+	if matchAny(f.rules.DenySources, source) {
+		return reject(item, fmt.Sprintf("source %s is denied", source))
+	}
+
+	if len(f.rules.AllowSenders) == 0 && len(f.rules.AllowSources) == 0 {
+		return nil
+	}
+	if matchAny(f.rules.AllowSenders, sender) || matchAny(f.rules.AllowSources, source) {
+		return nil
+	}
+
+	reason := fmt.Sprintf("unknown sender %q from source %q", sender, source)
+	switch f.rules.UnknownAction {
+	case ActionAccept:
+		return nil
+	case ActionReject:
+		return reject(item, reason)
+	default:
+		return quarantine(f.db, item, reason, nil)
+	}
+}
+
+// senderAddress returns the bare, lowercased address of the email's sender
+func senderAddress(email *Email) string {
+	if addr, err := mail.ParseAddress(email.From); err == nil {
+		return strings.ToLower(addr.Address)
+	}
+	return strings.ToLower(strings.Trim(strings.TrimSpace(email.From), "<>"))
+}
+
+// matchAny reports whether value matches one of the glob patterns
+func matchAny(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	value = strings.ToLower(value)
+	for _, pattern := range patterns {
+		if ok, err := path.Match(strings.ToLower(pattern), value); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reject stops the pipeline without storing anything
+func reject(item *Item, reason string) error {
+	slog.Info("Rejected email", "from", item.Email.From, "reason", reason)
+	return fmt.Errorf("%w: %s", ErrRejected, reason)
+}
+
+// quarantine holds the event for review and stops the pipeline
+func quarantine(db *database.Database, item *Item, reason string, score *float64) error {
+	q := &models.QuarantinedEvent{
+		Sender:  senderAddress(item.Email),
+		Source:  item.Event.Source,
+		Subject: item.Email.Subject,
+		Reason:  reason,
+		Score:   score,
+		Event:   *item.Event,
+	}
+	if err := db.QuarantineEvent(q); err != nil {
+		return err
+	}
+	slog.Info("Quarantined email", "from", item.Email.From, "quarantine_id", q.ID, "reason", reason)
+	return fmt.Errorf("%w: %s", ErrQuarantined, reason)
+}
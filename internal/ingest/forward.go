@@ -0,0 +1,126 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// forwardAttempts is how many times delivery to an endpoint is tried
+	forwardAttempts = 4
+	// forwardBackoff is the wait before the first retry; it doubles after each
+	forwardBackoff = 2 * time.Second
+	// forwardTimeout bounds each delivery attempt
+	forwardTimeout = 10 * time.Second
+)
+
+// forwardClient delivers events to mapping endpoints
+var forwardClient = &http.Client{Timeout: forwardTimeout}
+
+var (
+	// forwards tracks deliveries running in the background
+	forwards        sync.WaitGroup
+	pendingForwards atomic.Int64
+)
+
+// WaitForwards waits for events being forwarded to mapping endpoints to be
+// delivered or given up on. It returns ctx's error if ctx is done first.
+func WaitForwards(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		forwards.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		slog.Warn("Stopped waiting for forwarded events", "pending", pendingForwards.Load())
+		return ctx.Err()
+	}
+}
+
+// startForward delivers the event in the background
+func startForward(db *database.Database, event *models.Event, mapping *models.EmailMapping) {
+	forwards.Add(1)
+	pendingForwards.Add(1)
+	go func() {
+		defer forwards.Done()
+		defer pendingForwards.Add(-1)
+		forward(db, event, mapping)
+	}()
+}
+
+// forward POSTs a stored event to its mapping's endpoint, retrying failed
+// attempts, and records the outcome in the event's ingestion log
+func forward(db *database.Database, event *models.Event, mapping *models.EmailMapping) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Failed to encode event for forwarding", "event_id", event.ID, "error", err)
+		return
+	}
+
+	backoff := forwardBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := postEvent(mapping.EndpointURL, event.ID, body)
+		if err == nil {
+			slog.Info("Forwarded event", "event_id", event.ID, "url", mapping.EndpointURL)
+			if err := db.LogEventStatus(event.ID, "forwarded", fmt.Sprintf("delivered to %s (attempt %d)", mapping.EndpointURL, attempt)); err != nil {
+				slog.Warn("Failed to log forwarding", "event_id", event.ID, "error", err)
+			}
+			if err := db.TouchEmailMapping(mapping.ID); err != nil {
+				slog.Warn("Failed to update mapping", "mapping_id", mapping.ID, "error", err)
+			}
+			return
+		}
+
+		if !retry || attempt == forwardAttempts {
+			slog.Error("Giving up forwarding event", "event_id", event.ID, "url", mapping.EndpointURL, "error", err)
+			message := fmt.Sprintf("delivery to %s failed after %d attempt(s): %v", mapping.EndpointURL, attempt, err)
+			if err := db.LogEventStatus(event.ID, "forward_failed", message); err != nil {
+				slog.Warn("Failed to log forwarding", "event_id", event.ID, "error", err)
+			}
+			return
+		}
+
+		slog.Warn("Forwarding event failed, retrying", "event_id", event.ID, "url", mapping.EndpointURL, "attempt", attempt, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postEvent makes one delivery attempt, reporting whether a failure is worth
+// retrying: network errors, 429 and 5xx responses are, other statuses aren't
+func postEvent(endpoint string, eventID int64, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "event-db-forwarder/1.0")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(eventID, 10))
+
+	resp, err := forwardClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("endpoint responded %s", resp.Status)
+}
@@ -0,0 +1,59 @@
+package ingest
+
+import (
+	"example-api/internal/database"
+	"example-api/internal/geocode"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// locationHeader names the event's location in emails that don't carry one
+// otherwise, such as those received over SMTP
+const locationHeader = "X-Event-Location"
+
+// ConfigureGeocoder replaces the "geocode" processor with one using
+// geocoder; nil stops geocoding. It must be called before pipelines are set.
+func ConfigureGeocoder(geocoder geocode.Geocoder) {
+	RegisterProcessor("geocode", NewGeocode(geocoder))
+}
+
+// NewGeocode creates the "geocode" processor. Events with a location but no
+// coordinates get the coordinates geocoder finds for it. If it fails or
+// doesn't know the location, the event is stored without coordinates and a
+// warning is recorded.
+func NewGeocode(geocoder geocode.Geocoder) ProcessorFactory {
+	return func(*database.Database) Processor {
+		return &geocodeLocation{geocoder: geocoder}
+	}
+}
+
+type geocodeLocation struct {
+	geocoder geocode.Geocoder
+}
+
+// Process takes the location from the email's X-Event-Location header when
+// none was given, and geocodes it
+func (g *geocodeLocation) Process(item *Item) error {
+	event := item.Event
+	if event.Location == "" {
+		if values := item.Email.Headers[locationHeader]; len(values) > 0 {
+			event.Location = strings.TrimSpace(values[0])
+		}
+	}
+	if event.Location == "" || event.Latitude != nil || g.geocoder == nil {
+		return nil
+	}
+
+	latitude, longitude, err := geocode.Locate(g.geocoder, event.Location)
+	switch {
+	case err != nil:
+		slog.Warn("Failed to geocode location", "location", event.Location, "error", err)
+		item.Warnings = append(item.Warnings, fmt.Sprintf("geocoding %q failed: %v", event.Location, err))
+	case latitude == nil:
+		item.Warnings = append(item.Warnings, fmt.Sprintf("location %q not found by geocoding", event.Location))
+	default:
+		event.Latitude, event.Longitude = latitude, longitude
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"example-api/internal/reporting"
+	"log/slog"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Email is an email to be stored as an event, however it arrived: posted to
+// the API by a forwarder or received directly over SMTP
+type Email struct {
+	From            string
+	To              string
+	Cc              []string
+	Subject         string
+	MessageID       string
+	InReplyTo       string
+	References      []string
+	Date            time.Time
+	Body            string // Raw body, possibly MIME multipart
+	PlainBody       string // Text alternative, used when Body is empty
+	HTMLBody        string // HTML alternative, kept for the rendered view
+	ReceivedFrom    string
+	AuthenticatedAs string
+	Headers         map[string][]string
+	Recipients      []string            // Envelope recipients, when known; To and Cc are used otherwise
+	Attachments     []models.Attachment // Files sent alongside the body rather than inside it
+	Raw             []byte              // Complete message as received, when available
+	ClientIP        net.IP              // Address of the server that delivered the message over SMTP
+	Helo            string              // HELO/EHLO name given by that server
+	MailFrom        string              // Envelope sender (MAIL FROM)
+	Auth            *models.EmailAuth   // DKIM and SPF verdicts reported by an inbound provider
+	Project         int64               // Project to store the event in; the mapping's, or the default one, when zero
+	Tags            []string            // Tags the sender gives the event, besides those the pipeline adds
+	Location        string              // Where the event happened, geocoded unless Latitude and Longitude are set
+	Latitude        *float64
+	Longitude       *float64
+}
+
+// Ingester turns emails into stored events by running them through the
+// pipeline configured for their source
+type Ingester struct {
+	db *database.Database
+
+	mu              sync.RWMutex // Guards the pipelines, which can be replaced while ingesting
+	defaultPipeline []Processor
+	pipelines       map[string][]Processor
+}
+
+// New creates an Ingester storing events in db with DefaultPipeline
+func New(db *database.Database) *Ingester {
+	i := &Ingester{db: db}
+	if err := i.SetPipelines(nil, nil); err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// Ingest runs the email through its source's pipeline. With the default
+// pipeline, when an email with the same Message-ID was stored before, that
+// event is returned instead and created is false.
+//
+// Mail sent to an active mapping's generated address gets the mapping's tags
+// and, when it has one, its source. It is stored in the mapping's project
+// unless the email names one. New events are then forwarded to the
+// mapping's endpoint URL, if it has one.
+func (i *Ingester) Ingest(email *Email, source string) (event *models.Event, created bool, err error) {
+	item := newItem(email, source)
+	if err := run(item, i.pipelineFor(source)); err != nil {
+		return nil, false, err
+	}
+	return item.Stored, item.Created, nil
+}
+
+// IngestEvent runs an event built by a webhook adapter, such as GitHub's,
+// through its source's pipeline. The event's data is kept as it is rather
+// than extracted from a body; filtering, spam checks, tagging, geocoding and
+// storing apply as they do to emails.
+func (i *Ingester) IngestEvent(req *models.EventRequest) (event *models.Event, created bool, err error) {
+	item := &Item{
+		Email: &Email{
+			Body:      req.Data,
+			Project:   req.ProjectID,
+			Tags:      req.Tags,
+			Location:  req.Location,
+			Latitude:  req.Latitude,
+			Longitude: req.Longitude,
+		},
+		Source:     req.Source,
+		Event:      req,
+		Structured: true,
+	}
+	if err := run(item, i.pipelineFor(req.Source)); err != nil {
+		return nil, false, err
+	}
+	return item.Stored, item.Created, nil
+}
+
+// newItem starts an item for an email received with source
+func newItem(email *Email, source string) *Item {
+	return &Item{
+		Email:  email,
+		Source: source,
+		Event: &models.EventRequest{
+			Source:    source,
+			Tags:      email.Tags,
+			ProjectID: email.Project,
+			Location:  strings.TrimSpace(email.Location),
+			Latitude:  email.Latitude,
+			Longitude: email.Longitude,
+		},
+	}
+}
+
+// run passes the item through processors, stopping at the first error.
+// Errors other than rejection and quarantine are reported.
+func run(item *Item, processors []Processor) error {
+	for _, processor := range processors {
+		if err := processor.Process(item); err != nil {
+			if !errors.Is(err, ErrRejected) && !errors.Is(err, ErrQuarantined) {
+				reporting.CaptureError(context.Background(), err, map[string]string{"source": item.Source})
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// findMapping returns the active mapping for the first recipient that has one
+func findMapping(db *database.Database, email *Email) (*models.EmailMapping, error) {
+	for _, address := range recipientAddresses(email) {
+		mapping, err := db.GetEmailMappingByAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		if mapping == nil {
+			continue
+		}
+		if !mapping.IsActive {
+			slog.Info("Ignoring disabled mapping", "mapping_id", mapping.ID, "address", address)
+			continue
+		}
+		return mapping, nil
+	}
+	return nil, nil
+}
+
+// recipientAddresses returns the bare addresses mail was sent to: the
+// envelope recipients if known, otherwise those in the To and Cc headers
+func recipientAddresses(email *Email) []string {
+	if len(email.Recipients) > 0 {
+		return email.Recipients
+	}
+
+	var addresses []string
+	for _, list := range append([]string{email.To}, email.Cc...) {
+		parsed, err := mail.ParseAddressList(list)
+		if err != nil {
+			// Keep anything that looks like a bare address
+			for _, part := range strings.Split(list, ",") {
+				if part = strings.Trim(strings.TrimSpace(part), "<>"); strings.Contains(part, "@") {
+					addresses = append(addresses, part)
+				}
+			}
+			continue
+		}
+		for _, addr := range parsed {
+			addresses = append(addresses, addr.Address)
+		}
+	}
+	return addresses
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// extractSimpleContent tries to extract content from MIME messages by looking for content after headers.
+// Text that doesn't start with headers is returned whole.
+func extractSimpleContent(content string) string {
+	// Split by lines
+	lines := strings.Split(content, "\n")
+	if !startsWithHeaders(lines) {
+		return strings.TrimSpace(content)
+	}
+
+	// Find a blank line (which typically separates headers from content)
+	inContent := false
+	var contentLines []string
+
+	for _, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+
+		// When we find a blank line, we're transitioning into content
+		if trimmedLine == "" && !inContent {
+			inContent = true
+			continue
+		}
+
+		// If we're in the content section and hit a boundary line, we're done
+		if inContent && strings.HasPrefix(trimmedLine, "--") {
+			break
+		}
+
+		// If we're in content, collect the line
+		if inContent {
+			contentLines = append(contentLines, line)
+		}
+	}
+
+	// Join the content lines
+	if len(contentLines) > 0 {
+		return strings.TrimSpace(strings.Join(contentLines, "\n"))
+	}
+
+	return ""
+}
+
+// startsWithHeaders reports whether the lines before the first blank line
+// are all "Name: value" headers or their continuations
+func startsWithHeaders(lines []string) bool {
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			return i > 0
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			if i == 0 {
+				return false
+			}
+			continue
+		}
+		colon := strings.IndexByte(line, ':')
+		if colon <= 0 || strings.ContainsAny(line[:colon], " \t") {
+			return false
+		}
+	}
+	return false
+}
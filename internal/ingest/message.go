@@ -0,0 +1,106 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"example-api/internal/utils"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+)
+
+// ParseMessage reads a raw RFC 5322 message, such as one received over SMTP
+func ParseMessage(raw []byte) (*Email, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message: %w", err)
+	}
+	header := msg.Header
+	email := FromHeader(header)
+	email.Raw = raw
+
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		// MIME extraction expects the body to start at the first boundary,
+		// so drop the preamble ("This is a multi-part message...")
+		email.Body = string(body)
+		if boundary := params["boundary"]; boundary != "" {
+			if i := bytes.Index(body, []byte("--"+boundary)); i >= 0 {
+				email.Body = string(body[i:])
+			}
+		}
+	case mediaType == "text/html":
+		email.HTMLBody = utils.ToUTF8(decodeTransfer(body, header.Get("Content-Transfer-Encoding")), params["charset"])
+	default:
+		text := utils.ToUTF8(decodeTransfer(body, header.Get("Content-Transfer-Encoding")), params["charset"])
+		email.Body = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+
+	return email, nil
+}
+
+// FromHeader fills in an Email's envelope and threading fields from message
+// headers. Providers that post headers separately from the body use it too.
+func FromHeader(header mail.Header) *Email {
+	email := &Email{
+		From:       header.Get("From"),
+		To:         header.Get("To"),
+		Subject:    header.Get("Subject"),
+		MessageID:  header.Get("Message-Id"),
+		InReplyTo:  firstField(header.Get("In-Reply-To")),
+		References: strings.Fields(header.Get("References")),
+		Headers:    map[string][]string(header),
+	}
+	if date, err := header.Date(); err == nil {
+		email.Date = date
+	}
+	if cc := header.Get("Cc"); cc != "" {
+		if list, err := header.AddressList("Cc"); err == nil {
+			for _, addr := range list {
+				email.Cc = append(email.Cc, addr.String())
+			}
+		} else {
+			email.Cc = strings.Split(cc, ",")
+		}
+	}
+	return email
+}
+
+// decodeTransfer undoes the Content-Transfer-Encoding of a single-part body
+func decodeTransfer(body []byte, encoding string) []byte {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, bytes.NewReader(body))
+	case "quoted-printable":
+		r = quotedprintable.NewReader(bytes.NewReader(body))
+	default:
+		return body
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// firstField returns the first whitespace-separated field of s
+func firstField(s string) string {
+	if fields := strings.Fields(s); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
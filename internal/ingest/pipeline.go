@@ -0,0 +1,136 @@
+package ingest
+
+import (
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultPipeline is the processors run for sources without their own pipeline
+var DefaultPipeline = []string{"decode", "extract", "clean", "enrich", "verify", "tag", "filter", "spam", "geocode", "store"}
+
+// Item is an email on its way through a pipeline, together with the event
+// being built from it
+type Item struct {
+	Email    *Email
+	Source   string               // Source the email was received with
+	Event    *models.EventRequest // Event being built; its source may change
+	Mapping  *models.EmailMapping // Mapping the email was sent to, if any
+	Warnings []string             // Recorded in the ingestion log once stored
+
+	// Structured is set when the event was built by a webhook adapter rather
+	// than from an email; the email then only carries the event's text
+	Structured bool
+
+	Stored  *models.Event // Set by the store processor
+	Created bool          // False when the email had been stored before
+}
+
+// Processor is one step of an ingestion pipeline. Returning an error stops
+// the pipeline and fails the ingestion.
+type Processor interface {
+	Process(item *Item) error
+}
+
+// ProcessorFunc adapts a function to a Processor
+type ProcessorFunc func(item *Item) error
+
+// Process calls f(item)
+func (f ProcessorFunc) Process(item *Item) error {
+	return f(item)
+}
+
+// ProcessorFactory creates a processor using the ingester's database
+type ProcessorFactory func(db *database.Database) Processor
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]ProcessorFactory)
+)
+
+// RegisterProcessor makes a processor available to pipelines under name,
+// replacing any processor already registered with that name. Deployments
+// register their own processors before configuring pipelines.
+func RegisterProcessor(name string, factory ProcessorFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Processors lists the registered processor names
+func Processors() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registeredNames()
+}
+
+// registeredNames lists the registry's names; registryMu must be held
+func registeredNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetPipelines sets the default pipeline and the pipelines of particular
+// sources. An empty default keeps DefaultPipeline. Every pipeline must end
+// with "store". Ingestions in progress finish with the pipelines they
+// started with.
+func (i *Ingester) SetPipelines(defaultNames []string, sources map[string][]string) error {
+	if len(defaultNames) == 0 {
+		defaultNames = DefaultPipeline
+	}
+	defaultPipeline, err := i.buildPipeline(defaultNames)
+	if err != nil {
+		return fmt.Errorf("default pipeline: %w", err)
+	}
+
+	bySource := make(map[string][]Processor, len(sources))
+	for source, names := range sources {
+		pipeline, err := i.buildPipeline(names)
+		if err != nil {
+			return fmt.Errorf("pipeline for source %q: %w", source, err)
+		}
+		bySource[strings.ToLower(source)] = pipeline
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.defaultPipeline = defaultPipeline
+	i.pipelines = bySource
+	return nil
+}
+
+// buildPipeline creates the named processors
+func (i *Ingester) buildPipeline(names []string) ([]Processor, error) {
+	if len(names) == 0 || names[len(names)-1] != "store" {
+		return nil, fmt.Errorf("must end with \"store\"")
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	pipeline := make([]Processor, 0, len(names))
+	for _, name := range names {
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown processor %q (available: %s)", name, strings.Join(registeredNames(), ", "))
+		}
+		pipeline = append(pipeline, factory(i.db))
+	}
+	return pipeline, nil
+}
+
+// pipelineFor returns the pipeline configured for source
+func (i *Ingester) pipelineFor(source string) []Processor {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if pipeline, ok := i.pipelines[strings.ToLower(source)]; ok {
+		return pipeline
+	}
+	return i.defaultPipeline
+}
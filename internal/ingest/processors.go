@@ -0,0 +1,318 @@
+package ingest
+
+import (
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/logging"
+	"example-api/internal/models"
+	"example-api/internal/utils"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	RegisterProcessor("decode", func(*database.Database) Processor { return ProcessorFunc(decodeHeaders) })
+	RegisterProcessor("extract", func(*database.Database) Processor { return ProcessorFunc(extractContent) })
+	RegisterProcessor("clean", func(*database.Database) Processor { return ProcessorFunc(cleanText) })
+	RegisterProcessor("strip_quotes", func(*database.Database) Processor { return ProcessorFunc(stripQuotes) })
+	RegisterProcessor("strip_signature", func(*database.Database) Processor { return ProcessorFunc(stripSignature) })
+	RegisterProcessor("enrich", func(db *database.Database) Processor { return &enricher{db: db} })
+	RegisterProcessor("verify", func(*database.Database) Processor { return ProcessorFunc(verifySender) })
+	RegisterProcessor("tag", func(*database.Database) Processor { return ProcessorFunc(tagFromSubject) })
+	RegisterProcessor("filter", NewFilter(FilterRules{}))
+	RegisterProcessor("spam", NewSpamCheck(nil, 0, 0))
+	RegisterProcessor("geocode", NewGeocode(nil))
+	RegisterProcessor("store", func(db *database.Database) Processor { return &storer{db: db} })
+}
+
+// decodeHeaders decodes RFC 2047 encoded-words so non-ASCII subjects become
+// readable tags
+func decodeHeaders(item *Item) error {
+	email := item.Email
+	email.Subject = utils.DecodeHeader(email.Subject)
+	email.From = utils.DecodeHeader(email.From)
+	email.To = utils.DecodeHeader(email.To)
+	return nil
+}
+
+// extractContent sets the event's text, HTML and attachments from the body.
+// If the MIME body can't be parsed the raw content is used and a warning is
+// recorded.
+func extractContent(item *Item) error {
+	if item.Structured {
+		return nil
+	}
+	email, event := item.Email, item.Event
+
+	var contentToProcess string
+	// Check if Body is empty, use PlainBody as fallback
+	if email.Body == "" && email.PlainBody != "" {
+		slog.Debug("Body is empty, using the plain text body", "body", logging.Payload(email.PlainBody))
+		contentToProcess = email.PlainBody
+	} else if email.Body == "" && email.HTMLBody != "" {
+		// HTML-only email: store the text version and keep the HTML for the rendered view
+		slog.Debug("Body and plain text body are empty, converting the HTML body to text")
+		text, err := utils.HTMLToText(email.HTMLBody)
+		if err != nil {
+			slog.Warn("Failed to convert HTML body to text", "error", err)
+			text = email.HTMLBody
+		}
+		contentToProcess = text
+	} else {
+		slog.Debug("Using body", "body", logging.Payload(email.Body))
+		contentToProcess = email.Body
+	}
+	event.HTMLBody = email.HTMLBody
+
+	// Non-text parts are kept as attachments whichever way the text is extracted
+	event.Attachments = append(email.Attachments, utils.ExtractAttachments([]byte(contentToProcess))...)
+	if len(event.Attachments) > 0 {
+		slog.Debug("Extracted attachments", "count", len(event.Attachments))
+	}
+
+	// Try a simple content extraction first - look for content after blank line
+	// This works for simple MIME messages that follow the standard format.
+	// Multipart bodies go straight to MIME parsing, which handles nested parts,
+	// transfer encodings and charsets.
+	if !strings.HasPrefix(strings.TrimSpace(contentToProcess), "--") {
+		if actualContent := extractSimpleContent(contentToProcess); actualContent != "" {
+			slog.Debug("Extracted simple content", "data", logging.Payload(actualContent))
+			event.Data = actualContent
+			return nil
+		}
+	}
+
+	// If simple extraction didn't work, try the more complex MIME parsing
+	slog.Debug("Simple extraction failed, trying MIME parsing", "body", logging.Payload(contentToProcess))
+	plainData, err := utils.ExtractPlain([]byte(contentToProcess))
+	if err != nil {
+		slog.Warn("Failed to extract plain text, storing raw content", "error", err)
+		plainData = contentToProcess // fallback to original
+		// The raw content is stored; record why so it shows up in the ingestion logs
+		item.Warnings = append(item.Warnings, fmt.Sprintf("MIME extraction failed, stored raw content: %v", err))
+	}
+	slog.Debug("Extracted MIME content", "data", logging.Payload(plainData))
+	event.Data = plainData
+	return nil
+}
+
+// blankLines matches runs of more than one empty line
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// cleanText normalizes line endings, drops trailing spaces and collapses runs
+// of blank lines
+func cleanText(item *Item) error {
+	text := strings.ReplaceAll(item.Event.Data, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	item.Event.Data = strings.TrimSpace(text)
+	return nil
+}
+
+// replyHeader matches the line mail clients put above a quoted reply
+var replyHeader = regexp.MustCompile(`^On .+ wrote:$`)
+
+// stripQuotes removes quoted lines ("> ...") and the "On ... wrote:" line
+// introducing them, leaving only the new text of a reply
+func stripQuotes(item *Item) error {
+	lines := strings.Split(item.Event.Data, "\n")
+	kept := lines[:0]
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		if replyHeader.MatchString(trimmed) && i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	item.Event.Data = strings.TrimSpace(strings.Join(kept, "\n"))
+	return nil
+}
+
+// stripSignature cuts the text at the "-- " signature separator line, which
+// is just "--" once clean has dropped trailing spaces
+func stripSignature(item *Item) error {
+	lines := strings.Split(item.Event.Data, "\n")
+	for i, line := range lines {
+		if strings.TrimRight(line, " \r") == "--" {
+			item.Event.Data = strings.TrimSpace(strings.Join(lines[:i], "\n"))
+			break
+		}
+	}
+	return nil
+}
+
+// enricher attaches the email's envelope and headers, and the mapping it was
+// sent to
+type enricher struct {
+	db *database.Database
+}
+
+// Process keeps the envelope and headers alongside the extracted content and
+// routes mail sent to an active mapping's address: the mapping's source, when
+// it has one, replaces the event's
+func (e *enricher) Process(item *Item) error {
+	email := item.Email
+	if email.From != "" || email.MessageID != "" {
+		item.Event.Email = &models.EmailMetadata{
+			From:            email.From,
+			To:              email.To,
+			Cc:              email.Cc,
+			Subject:         email.Subject,
+			MessageID:       strings.TrimSpace(email.MessageID),
+			InReplyTo:       strings.TrimSpace(email.InReplyTo),
+			References:      email.References,
+			Date:            email.Date,
+			ReceivedFrom:    email.ReceivedFrom,
+			AuthenticatedAs: email.AuthenticatedAs,
+			Headers:         email.Headers,
+		}
+	}
+
+	mapping, err := findMapping(e.db, email)
+	if err != nil {
+		return err
+	}
+	if mapping == nil {
+		return nil
+	}
+	slog.Info("Routing mail through mapping", "address", mapping.GeneratedEmail, "mapping_id", mapping.ID)
+	item.Mapping = mapping
+	if mapping.Source != "" {
+		item.Event.Source = mapping.Source
+	}
+	if item.Event.ProjectID == 0 {
+		item.Event.ProjectID = mapping.ProjectID
+	}
+	if err := e.db.TouchEmailMapping(mapping.ID); err != nil {
+		slog.Warn("Failed to update mapping", "mapping_id", mapping.ID, "error", err)
+	}
+	return nil
+}
+
+// tagFromSubject tags the event with the lowercased words of the subject and
+// the tags of the mapping the email was sent to. Events with neither are
+// tagged "untagged".
+func tagFromSubject(item *Item) error {
+	var tags []string
+	for _, word := range strings.Fields(item.Email.Subject) {
+		tags = append(tags, strings.ToLower(word))
+	}
+	if item.Mapping != nil {
+		for _, tag := range item.Mapping.Tags {
+			tag = strings.ToLower(tag)
+			if !containsTag(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	for _, tag := range item.Event.Tags {
+		if !containsTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		tags = []string{"untagged"}
+	}
+	slog.Debug("Extracted tags", "tags", tags)
+	item.Event.Tags = tags
+	return nil
+}
+
+// storer stores the event. When an email with the same Message-ID was stored
+// before, that event is returned instead. New events routed through a mapping
+// with an endpoint URL are forwarded there.
+type storer struct {
+	db *database.Database
+}
+
+// Process stores the event with its warnings and records the outcome on the
+// item
+func (s *storer) Process(item *Item) error {
+	req := item.Event
+	slog.Debug("Storing event", "tags", req.Tags, "source", req.Source, "data", logging.Payload(req.Data))
+	event, err := s.db.StoreEvent(req, item.Warnings...)
+	if errors.Is(err, database.ErrDuplicateMessageID) {
+		return s.duplicate(item)
+	}
+	if err != nil {
+		return err
+	}
+	s.stored(item, event)
+	return nil
+}
+
+// storeBatch stores the events of several items in one transaction. If that
+// fails, they are stored one at a time so one bad event doesn't hold up the
+// others; the error of each item is returned in errs.
+func (s *storer) storeBatch(items []*Item) (errs []error) {
+	errs = make([]error, len(items))
+	requests := make([]*models.EventRequest, len(items))
+	for i, item := range items {
+		requests[i] = item.Event
+	}
+	events, err := s.db.InsertEvents(requests)
+	if err != nil {
+		slog.Warn("Failed to store batch, storing events one at a time", "events", len(items), "error", err)
+		for i, item := range items {
+			errs[i] = s.Process(item)
+		}
+		return errs
+	}
+	for i, item := range items {
+		if events[i] == nil {
+			errs[i] = s.duplicate(item)
+		} else {
+			s.logWarnings(events[i], item.Warnings)
+			s.stored(item, events[i])
+		}
+	}
+	return errs
+}
+
+// duplicate records on the item the event its email was stored as before in
+// the same project
+func (s *storer) duplicate(item *Item) error {
+	messageID := item.Event.Email.MessageID
+	existing, err := s.db.GetEventByMessageID(item.Event.ProjectID, messageID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("event for message %s not found", messageID)
+	}
+	slog.Info("Message was already stored", "message_id", messageID, "event_id", existing.ID)
+	if err := s.db.LogEventStatus(existing.ID, "duplicate", "message delivered again, existing event returned"); err != nil {
+		slog.Warn("Failed to log duplicate delivery", "event_id", existing.ID, "error", err)
+	}
+	item.Stored, item.Created = existing, false
+	return nil
+}
+
+// logWarnings logs the warnings of an event stored in a batch; StoreEvent
+// logs them with the event
+func (s *storer) logWarnings(event *models.Event, warnings []string) {
+	for _, warning := range warnings {
+		if err := s.db.LogEventStatus(event.ID, "warning", warning); err != nil {
+			slog.Warn("Failed to log warning", "event_id", event.ID, "error", err)
+		}
+	}
+}
+
+// stored records a newly stored event on the item and starts forwarding it
+func (s *storer) stored(item *Item, event *models.Event) {
+	slog.Info("Stored event", "event_id", event.ID, "source", item.Event.Source, "warnings", len(item.Warnings))
+	item.Stored, item.Created = event, true
+
+	// Relay the event without holding up the sender while the endpoint is retried
+	if item.Mapping != nil && item.Mapping.EndpointURL != "" {
+		startForward(s.db, event, item.Mapping)
+	}
+}
@@ -0,0 +1,199 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"example-api/internal/reporting"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned by Queue.Enqueue when the queue has no room left
+	ErrQueueFull = errors.New("ingestion queue is full")
+	// ErrQueueClosed is returned by Queue.Enqueue once the server is stopping
+	ErrQueueClosed = errors.New("ingestion queue is closed")
+)
+
+// Queue ingests emails in the background. Workers take them off a bounded
+// in-memory queue, run them through their source's pipeline and store the
+// events of several emails in one transaction. Emails still queued when the
+// process stops without closing the queue are lost.
+type Queue struct {
+	ingester  *Ingester
+	store     *storer
+	batchSize int
+	batchWait time.Duration
+	workers   sync.WaitGroup
+
+	mu     sync.RWMutex // Guards sending to jobs against closing it
+	jobs   chan job
+	closed bool
+}
+
+// job is an email waiting in the queue
+type job struct {
+	email  *Email
+	source string
+}
+
+var (
+	queuesMu sync.Mutex
+	queues   []*Queue // Open queues, closed by CloseQueues
+)
+
+// NewQueue starts workers ingesting emails queued for i. Each worker stores
+// up to batchSize events at a time, waiting at most batchWait for a batch to
+// fill up.
+func (i *Ingester) NewQueue(size, workers, batchSize int, batchWait time.Duration) *Queue {
+	q := &Queue{
+		ingester:  i,
+		store:     &storer{db: i.db},
+		jobs:      make(chan job, size),
+		batchSize: max(batchSize, 1),
+		batchWait: batchWait,
+	}
+	for n := 0; n < max(workers, 1); n++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+	queuesMu.Lock()
+	queues = append(queues, q)
+	queuesMu.Unlock()
+	return q
+}
+
+// Enqueue queues an email received with source, or returns ErrQueueFull
+// without waiting if the queue has no room
+func (q *Queue) Enqueue(email *Email, source string) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- job{email: email, source: source}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Len returns the number of emails waiting in the queue
+func (q *Queue) Len() int {
+	return len(q.jobs)
+}
+
+// CloseQueues stops every queue accepting emails and waits for the queued
+// ones to be stored. It returns ctx's error if ctx is done first.
+func CloseQueues(ctx context.Context) error {
+	queuesMu.Lock()
+	closing := queues
+	queues = nil
+	queuesMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for _, q := range closing {
+			q.mu.Lock()
+			q.closed = true
+			close(q.jobs)
+			q.mu.Unlock()
+		}
+		for _, q := range closing {
+			q.workers.Wait()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		pending := 0
+		for _, q := range closing {
+			pending += q.Len()
+		}
+		slog.Warn("Stopped waiting for queued emails", "pending", pending)
+		return ctx.Err()
+	}
+}
+
+// work ingests queued emails until the queue is closed and empty
+func (q *Queue) work() {
+	defer q.workers.Done()
+	var batch []*Item
+	for {
+		j, ok := <-q.jobs
+		if !ok {
+			return
+		}
+		batch = q.process(j, batch)
+
+		// Collect more emails until the batch is full or has waited long enough
+		timeout := time.NewTimer(q.batchWait)
+	collect:
+		for len(batch) < q.batchSize {
+			select {
+			case j, ok := <-q.jobs:
+				if !ok {
+					break collect
+				}
+				batch = q.process(j, batch)
+			case <-timeout.C:
+				break collect
+			}
+		}
+		timeout.Stop()
+
+		if len(batch) > 0 {
+			for i, err := range q.store.storeBatch(batch) {
+				if err != nil {
+					failed(batch[i], err)
+				}
+			}
+			batch = batch[:0]
+		}
+	}
+}
+
+// process runs a queued email through its pipeline. When the pipeline ends
+// with the standard store processor, the email is added to the batch to be
+// stored instead of being stored on its own.
+func (q *Queue) process(j job, batch []*Item) []*Item {
+	item := newItem(j.email, j.source)
+	processors := q.ingester.pipelineFor(j.source)
+	batched := false
+	if len(processors) > 0 {
+		_, batched = processors[len(processors)-1].(*storer)
+	}
+	if !batched {
+		if err := run(item, processors); err != nil {
+			logFailure(item, err)
+		}
+		return batch
+	}
+	if err := run(item, processors[:len(processors)-1]); err != nil {
+		logFailure(item, err)
+		return batch
+	}
+	return append(batch, item)
+}
+
+// failed reports an error storing a batched item; errors from the other
+// processors are reported by run
+func failed(item *Item, err error) {
+	reporting.CaptureError(context.Background(), err, map[string]string{"source": item.Source})
+	logFailure(item, err)
+}
+
+// logFailure records why a queued email wasn't stored, since there is no
+// sender waiting for the answer
+func logFailure(item *Item, err error) {
+	level := slog.LevelError
+	if errors.Is(err, ErrRejected) || errors.Is(err, ErrQuarantined) {
+		level = slog.LevelInfo
+	}
+	slog.Log(context.Background(), level, "Queued email not stored",
+		"source", item.Source, "message_id", item.Email.MessageID, "error", err)
+}
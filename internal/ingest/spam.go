@@ -0,0 +1,182 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"example-api/internal/database"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// spamCheckTimeout bounds each call to a spam checker
+const spamCheckTimeout = 10 * time.Second
+
+// SpamChecker scores a raw message; higher scores are more likely spam
+type SpamChecker interface {
+	Score(raw []byte) (float64, error)
+}
+
+// NewSpamCheck creates the "spam" processor. Emails scoring at least
+// rejectScore are rejected, and those scoring at least quarantineScore are
+// quarantined; a zero threshold is not applied. If the checker fails the
+// email is accepted with a warning.
+func NewSpamCheck(checker SpamChecker, quarantineScore, rejectScore float64) ProcessorFactory {
+	return func(db *database.Database) Processor {
+		return &spamCheck{db: db, checker: checker, quarantineScore: quarantineScore, rejectScore: rejectScore}
+	}
+}
+
+type spamCheck struct {
+	db              *database.Database
+	checker         SpamChecker
+	quarantineScore float64
+	rejectScore     float64
+}
+
+// Process scores the email and rejects or quarantines spam
+func (s *spamCheck) Process(item *Item) error {
+	if s.checker == nil {
+		return nil
+	}
+	score, err := s.checker.Score(rawMessage(item.Email))
+	if err != nil {
+		slog.Warn("Spam check failed, accepting email", "error", err)
+		item.Warnings = append(item.Warnings, fmt.Sprintf("spam check failed: %v", err))
+		return nil
+	}
+
+	reason := fmt.Sprintf("spam score %.1f", score)
+	if s.rejectScore > 0 && score >= s.rejectScore {
+		return reject(item, reason)
+	}
+	if s.quarantineScore > 0 && score >= s.quarantineScore {
+		return quarantine(s.db, item, reason, &score)
+	}
+	return nil
+}
+
+// rawMessage returns the message as received or, when it arrived already
+// parsed, rebuilds one from its headers and body
+func rawMessage(email *Email) []byte {
+	if len(email.Raw) > 0 {
+		return email.Raw
+	}
+
+	var b bytes.Buffer
+	if len(email.Headers) > 0 {
+		for name, values := range email.Headers {
+			for _, value := range values {
+				fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+			}
+		}
+	} else {
+		fmt.Fprintf(&b, "From: %s\r\nTo: %s\r\nSubject: %s\r\n", email.From, email.To, email.Subject)
+		if email.MessageID != "" {
+			fmt.Fprintf(&b, "Message-ID: %s\r\n", email.MessageID)
+		}
+	}
+	b.WriteString("\r\n")
+	switch {
+	case email.Body != "":
+		b.WriteString(email.Body)
+	case email.PlainBody != "":
+		b.WriteString(email.PlainBody)
+	default:
+		b.WriteString(email.HTMLBody)
+	}
+	return b.Bytes()
+}
+
+// Rspamd checks messages with rspamd's HTTP protocol (/checkv2)
+type Rspamd struct {
+	URL      string // Base URL of the normal worker, e.g. http://localhost:11333
+	Password string // Sent as the Password header when set
+	client   *http.Client
+}
+
+// NewRspamd creates an rspamd checker for the worker at url
+func NewRspamd(url, password string) *Rspamd {
+	return &Rspamd{URL: strings.TrimRight(url, "/"), Password: password, client: &http.Client{Timeout: spamCheckTimeout}}
+}
+
+// Score returns rspamd's score for the message
+func (r *Rspamd) Score(raw []byte) (float64, error) {
+	req, err := http.NewRequest(http.MethodPost, r.URL+"/checkv2", bytes.NewReader(raw))
+	if err != nil {
+		return 0, err
+	}
+	if r.Password != "" {
+		req.Header.Set("Password", r.Password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("rspamd request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return 0, fmt.Errorf("rspamd returned %s", resp.Status)
+	}
+
+	var result struct {
+		Score float64 `json:"score"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("invalid rspamd response: %w", err)
+	}
+	return result.Score, nil
+}
+
+// SpamAssassin checks messages with spamd's SPAMC protocol
+type SpamAssassin struct {
+	Addr string // host:port of spamd, e.g. localhost:783
+}
+
+// Score returns SpamAssassin's score for the message
+func (s *SpamAssassin) Score(raw []byte) (float64, error) {
+	conn, err := net.DialTimeout("tcp", s.Addr, spamCheckTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to spamd: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(spamCheckTimeout))
+
+	if _, err := fmt.Fprintf(conn, "CHECK SPAMC/1.5\r\nContent-length: %d\r\n\r\n", len(raw)); err != nil {
+		return 0, fmt.Errorf("failed to send to spamd: %w", err)
+	}
+	if _, err := conn.Write(raw); err != nil {
+		return 0, fmt.Errorf("failed to send to spamd: %w", err)
+	}
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.CloseWrite()
+	}
+
+	// SPAMD/1.1 0 EX_OK, then headers including "Spam: True ; 15.3 / 5.0"
+	r := bufio.NewReader(conn)
+	status, err := r.ReadString('\n')
+	if err != nil {
+		return 0, fmt.Errorf("failed to read spamd response: %w", err)
+	}
+	if fields := strings.Fields(status); len(fields) < 2 || fields[1] != "0" {
+		return 0, fmt.Errorf("spamd error: %s", strings.TrimSpace(status))
+	}
+	for {
+		line, err := r.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Spam") {
+			_, scores, _ := strings.Cut(value, ";")
+			score, _, _ := strings.Cut(scores, "/")
+			return strconv.ParseFloat(strings.TrimSpace(score), 64)
+		}
+		if err != nil || line == "" {
+			return 0, fmt.Errorf("spamd response has no Spam header")
+		}
+	}
+}
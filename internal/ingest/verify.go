@@ -0,0 +1,111 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"example-api/internal/models"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"blitiri.com.ar/go/spf"
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+// verifyTimeout bounds the DNS lookups of each DKIM and SPF check
+const verifyTimeout = 10 * time.Second
+
+// maxDKIMSignatures caps the signatures verified per message
+const maxDKIMSignatures = 5
+
+// verifySender records the email's DKIM and SPF verdicts with the event.
+// Verdicts reported by an inbound provider are kept as they are. Otherwise
+// DKIM signatures are verified when the raw message is available, and SPF is
+// checked when the message was received over SMTP.
+func verifySender(item *Item) error {
+	email := item.Email
+	if item.Event.Email == nil {
+		return nil
+	}
+	if email.Auth != nil {
+		item.Event.Email.Auth = email.Auth
+		return nil
+	}
+
+	auth := &models.EmailAuth{}
+	if len(email.Raw) > 0 && hasHeader(email, "Dkim-Signature") {
+		auth.DKIM, auth.DKIMDomain = checkDKIM(email.Raw, senderAddress(email))
+	}
+	if email.ClientIP != nil {
+		auth.SPF, auth.SPFDomain = checkSPF(email.ClientIP, email.Helo, email.MailFrom)
+		auth.CheckedBy = "smtp"
+	}
+	if auth.DKIM == "" && auth.SPF == "" {
+		return nil
+	}
+	slog.Info("Verified sender", "from", email.From, "dkim", auth.DKIM, "spf", auth.SPF)
+	item.Event.Email.Auth = auth
+	return nil
+}
+
+// hasHeader reports whether the email has a header named name, in canonical
+// form
+func hasHeader(email *Email, name string) bool {
+	return len(email.Headers[name]) > 0
+}
+
+// checkDKIM verifies the message's DKIM signatures. It passes if any
+// signature is valid, preferring one from the sender's domain.
+func checkDKIM(raw []byte, sender string) (result, domain string) {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+	options := &dkim.VerifyOptions{
+		LookupTXT: func(name string) ([]string, error) {
+			return net.DefaultResolver.LookupTXT(ctx, name)
+		},
+		MaxVerifications: maxDKIMSignatures,
+	}
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(raw), options)
+	if err != nil && len(verifications) == 0 {
+		slog.Warn("DKIM verification failed", "error", err)
+		return "permerror", ""
+	}
+
+	_, senderDomain, _ := strings.Cut(sender, "@")
+	result = "none"
+	for _, v := range verifications {
+		switch {
+		case v.Err == nil:
+			if result != "pass" || strings.EqualFold(v.Domain, senderDomain) {
+				result, domain = "pass", v.Domain
+			}
+		case result == "pass":
+			// A valid signature outweighs invalid ones
+		case dkim.IsTempFail(v.Err):
+			result, domain = "temperror", v.Domain
+		case result != "temperror":
+			result, domain = "fail", v.Domain
+		}
+	}
+	return result, domain
+}
+
+// checkSPF checks whether ip may send mail for the envelope sender's domain,
+// or the HELO name's when the sender is empty (bounces)
+func checkSPF(ip net.IP, helo, mailFrom string) (result, domain string) {
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	sender := strings.Trim(mailFrom, "<>")
+	if at := strings.LastIndex(sender, "@"); at >= 0 {
+		domain = strings.ToLower(sender[at+1:])
+	} else {
+		domain = strings.ToLower(helo)
+	}
+	res, err := spf.CheckHostWithSender(ip, helo, sender, spf.WithContext(ctx))
+	if err != nil && res != spf.Pass {
+		slog.Debug("SPF check did not pass", "domain", domain, "ip", ip.String(), "result", string(res), "error", err)
+	}
+	return string(res), domain
+}
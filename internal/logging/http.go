@@ -0,0 +1,142 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader carries the request ID, taken from the client or a proxy
+// when it sends one and echoed in the response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength caps request IDs accepted from clients
+const maxRequestIDLength = 128
+
+type requestInfoKey struct{}
+
+// requestInfo is filled in while a request is handled and logged once it is
+// done
+type requestInfo struct {
+	id   string
+	user string
+}
+
+// Middleware logs one record per request with its method, path, status,
+// latency, response size, client address, request ID and the identity the
+// request authenticated as. Server errors are logged at error level, and
+// successful health probes at debug level.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{id: requestID(r.Header.Get(RequestIDHeader))}
+		w.Header().Set(RequestIDHeader, info.id)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)))
+
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case r.URL.Path == "/livez" || r.URL.Path == "/readyz" || r.URL.Path == "/metrics":
+			// Probes and scrapes arrive every few seconds; only failures are worth seeing
+			if rec.status == http.StatusOK {
+				level = slog.LevelDebug
+			}
+		}
+		slog.LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int64("bytes", rec.bytes),
+			slog.String("remote_ip", remoteIP(r)),
+			slog.String("user", info.user),
+			slog.String("request_id", info.id),
+		)
+	})
+}
+
+// SetUser records the identity a request authenticated as: a username, or
+// "api-token" for the API token
+func SetUser(ctx context.Context, user string) {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.user = user
+	}
+}
+
+// RequestID returns the ID of the request being handled, if any
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// requestID keeps a client-supplied ID made of printable ASCII, or generates
+// one
+func requestID(supplied string) string {
+	if supplied != "" && len(supplied) <= maxRequestIDLength {
+		valid := true
+		for _, c := range supplied {
+			if c < '!' || c > '~' {
+				valid = false
+				break
+			}
+		}
+		if valid {
+			return supplied
+		}
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// statusRecorder captures the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status, s.wroteHeader = status, true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// Flush lets streamed responses, such as exports, reach the client as they
+// are written
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,95 @@
+// Package logging sets up structured logging for the binaries
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Formats of log output
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// level is the level of the handler installed by Setup, changed by SetLevel
+var level slog.LevelVar
+
+// Setup makes the default slog logger write to stderr at level ("debug",
+// "info", "warn" or "error"; default info) in format ("text" or "json";
+// default text). Every record carries the service name. Output of the
+// standard log package goes through the same handler at info level, so its
+// prefix is cleared.
+func Setup(service, lvl, format string) error {
+	l, err := parseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	handler, err := newHandler(os.Stderr, &level, format)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	slog.SetDefault(slog.New(handler).With("service", service))
+	log.SetPrefix("")
+	return nil
+}
+
+// SetLevel changes the level of the logger installed by Setup
+func SetLevel(lvl string) error {
+	l, err := parseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// NewHandler creates a handler writing to w at level in format
+func NewHandler(w io.Writer, lvl, format string) (slog.Handler, error) {
+	l, err := parseLevel(lvl)
+	if err != nil {
+		return nil, err
+	}
+	return newHandler(w, l, format)
+}
+
+func parseLevel(lvl string) (slog.Level, error) {
+	var l slog.Level
+	if lvl == "" {
+		lvl = "info"
+	}
+	if err := l.UnmarshalText([]byte(lvl)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q, want debug, info, warn or error", lvl)
+	}
+	return l, nil
+}
+
+func newHandler(w io.Writer, l slog.Leveler, format string) (slog.Handler, error) {
+	options := &slog.HandlerOptions{Level: l}
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return slog.NewTextHandler(w, options), nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, options), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, want text or json", format)
+	}
+}
+
+// Payload is message content, such as an email body or a request body. It is
+// only logged when debug logging is enabled; otherwise just its size is.
+type Payload string
+
+// LogValue implements slog.LogValuer
+func (p Payload) LogValue() slog.Value {
+	if !slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		return slog.StringValue(fmt.Sprintf("[redacted %d bytes]", len(p)))
+	}
+	return slog.StringValue(string(p))
+}
@@ -0,0 +1,40 @@
+// Package metrics serves /metrics in the Prometheus text format
+package metrics
+
+import (
+	"example-api/internal/database"
+	"fmt"
+	"net/http"
+)
+
+// metric is one value in the exposition
+type metric struct {
+	name  string
+	kind  string // gauge or counter
+	help  string
+	value float64
+}
+
+// Handler serves the database connection pool statistics
+func Handler(db *database.Database) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := db.Stats()
+		metrics := []metric{
+			{"eventdb_db_connections_max", "gauge", "Maximum number of open connections to the database; 0 is unlimited.", float64(stats.MaxOpenConnections)},
+			{"eventdb_db_connections_open", "gauge", "Open connections to the database, in use or idle.", float64(stats.OpenConnections)},
+			{"eventdb_db_connections_in_use", "gauge", "Connections running a query or transaction.", float64(stats.InUse)},
+			{"eventdb_db_connections_idle", "gauge", "Idle connections kept for reuse.", float64(stats.Idle)},
+			{"eventdb_db_connections_wait_total", "counter", "Times a query waited for a free connection.", float64(stats.WaitCount)},
+			{"eventdb_db_connections_wait_seconds_total", "counter", "Time spent waiting for a free connection.", stats.WaitDuration.Seconds()},
+			{"eventdb_db_connections_closed_max_idle_total", "counter", "Connections closed because too many were idle.", float64(stats.MaxIdleClosed)},
+			{"eventdb_db_connections_closed_max_idle_time_total", "counter", "Connections closed because they were idle too long.", float64(stats.MaxIdleTimeClosed)},
+			{"eventdb_db_connections_closed_max_lifetime_total", "counter", "Connections closed because they reached their maximum lifetime.", float64(stats.MaxLifetimeClosed)},
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		for _, m := range metrics {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AlertRule notifies a channel when a received event matches it. Every
+// condition that is set must match.
+type AlertRule struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Tags        []string  `json:"tags"`    // Any of them
+	Source      string    `json:"source"`  // Whole, ignoring case
+	Keyword     string    `json:"keyword"` // Anywhere in the data, ignoring case
+	Channel     string    `json:"channel"` // "webhook", "email" or "slack"
+	Target      string    `json:"target"`  // URL, or email address
+	Enabled     bool      `json:"enabled"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastFiredAt time.Time `json:"last_fired_at,omitempty"` // Zero if it never fired
+}
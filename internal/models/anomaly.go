@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Anomaly directions
+const (
+	AnomalySpike = "spike" // Far more events than usual
+	AnomalyDrop  = "drop"  // Far fewer events than usual, or none
+)
+
+// AnomalyTag and AnomalySource mark the events stored to report anomalies.
+// They are left out of anomaly detection.
+const (
+	AnomalyTag    = "anomaly"
+	AnomalySource = "anomaly-detector"
+)
+
+// HourlySeries is the number of events per hour from a source, with a tag,
+// or of all events together, oldest hour first
+type HourlySeries struct {
+	Kind   string // "events", "source" or "tag"
+	Name   string // The source or tag; empty for "events"
+	Counts []int
+}
+
+// Anomaly is an hour in which a source, a tag or all events together saw
+// far more or far fewer events than in the hours before
+type Anomaly struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"` // "events", "source" or "tag"
+	Name      string    `json:"name,omitempty"`
+	Hour      time.Time `json:"hour"`      // Start of the hour, in UTC
+	Direction string    `json:"direction"` // One of the Anomaly directions
+	Count     int       `json:"count"`
+	Expected  float64   `json:"expected"` // Mean events per hour over the baseline
+	StdDev    float64   `json:"stddev"`
+	EventID   int64     `json:"event_id,omitempty"` // Event stored to report the anomaly
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AnomalyResponse lists anomalies, most recent hour first
+type AnomalyResponse struct {
+	Anomalies []Anomaly `json:"anomalies"`
+	Total     int       `json:"total"`
+}
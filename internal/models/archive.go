@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Archive is an object of compressed NDJSON events uploaded to S3-compatible
+// storage, in the export format
+type Archive struct {
+	ID           int64     `json:"id"`
+	Bucket       string    `json:"bucket"`
+	Key          string    `json:"key"`
+	FirstEventID int64     `json:"first_event_id"`
+	LastEventID  int64     `json:"last_event_id"`
+	Events       int       `json:"events"`
+	Bytes        int64     `json:"bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
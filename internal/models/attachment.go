@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Attachment is a file that arrived as part of an email event
+type Attachment struct {
+	ID          int64     `json:"id"`
+	EventID     int64     `json:"event_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	Data        []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
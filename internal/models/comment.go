@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// MaxCommentLength limits the length of a comment's body, in characters
+const MaxCommentLength = 10000
+
+// EventComment is a note someone attached to an event
+type EventComment struct {
+	ID        int64     `json:"id"`
+	EventID   int64     `json:"event_id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateCommentRequest adds a comment to an event
+type CreateCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// EventCommentsResponse is the comments on an event, oldest first
+type EventCommentsResponse struct {
+	Comments []EventComment `json:"comments"`
+	Total    int            `json:"total"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// DebugPayload is a raw request body captured while debugging a source
+type DebugPayload struct {
+	ID          int64     `json:"id"`
+	Source      string    `json:"source"`
+	ContentType string    `json:"content_type"`
+	RemoteAddr  string    `json:"remote_addr"`
+	RequestID   string    `json:"request_id"`
+	Status      int       `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Payload     string    `json:"payload"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DebugPayloadsResponse represents a list of captured payloads
+type DebugPayloadsResponse struct {
+	Items []DebugPayload `json:"items"`
+	Total int            `json:"total"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// DigestSubscriber is a user who chose to receive email digests
+type DigestSubscriber struct {
+	Username string
+	Role     string    // Decides which projects' events the digest covers
+	Email    string    // The digest address, or the account's email
+	Timezone string    // Periods start at midnight in this zone
+	SentAt   time.Time // End of the period of the last digest sent; zero if none was
+}
+
+// Digest summarizes the events created in a period
+type Digest struct {
+	From    time.Time   `json:"from"`
+	To      time.Time   `json:"to"`
+	Total   int         `json:"total"`
+	Tags    []NameCount `json:"tags"`    // Most used first
+	Sources []NameCount `json:"sources"` // Busiest first
+	Latest  []Event     `json:"latest"`  // Newest first
+}
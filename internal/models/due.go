@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// DueSoonWindow is how long before its due date an unresolved event counts
+// as approaching its SLA
+const DueSoonWindow = 24 * time.Hour
+
+// Due date filters and reminders
+const (
+	DueSoon    = "soon"    // Unresolved and due within DueSoonWindow
+	DueOverdue = "overdue" // Unresolved and past due
+)
+
+// DueFilters lists the ways the events list can be filtered by due date
+var DueFilters = []string{DueSoon, DueOverdue}
+
+// SetDueRequest sets when an event is due, or clears it when DueAt is nil
+type SetDueRequest struct {
+	DueAt *time.Time `json:"due_at"`
+}
+
+// Unresolved reports whether the event still needs attention: it is open or
+// acknowledged
+func (e Event) Unresolved() bool {
+	return e.Status == StatusOpen || e.Status == StatusAcknowledged || e.Status == ""
+}
+
+// Overdue reports whether the event is unresolved past its due date
+func (e Event) Overdue() bool {
+	return e.DueAt != nil && e.Unresolved() && e.DueAt.Before(time.Now())
+}
+
+// DueSoon reports whether the event is unresolved and due within
+// DueSoonWindow
+func (e Event) DueSoon() bool {
+	return e.DueAt != nil && e.Unresolved() && !e.Overdue() && time.Until(*e.DueAt) < DueSoonWindow
+}
+
+// DueReminder is an unresolved event that is due soon or overdue and whose
+// owners haven't been reminded of it at that stage yet
+type DueReminder struct {
+	Event Event
+	Stage string // DueSoon or DueOverdue
+}
@@ -1,19 +1,104 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Event struct {
-	ID        int64     `json:"id"`
-	Tags      []string  `json:"tags"`
-	Data      string    `json:"data"`
-	Source    string    `json:"source"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              int64          `json:"id"`
+	Tags            []string       `json:"tags"`
+	Labels          []string       `json:"labels,omitempty"` // Names of the event's labels
+	Data            string         `json:"data"`
+	Source          string         `json:"source"`
+	HTMLBody        string         `json:"html_body,omitempty"` // Original HTML of email events
+	Email           *EmailMetadata `json:"email,omitempty"`     // Set for events ingested from email
+	AttachmentCount int            `json:"attachment_count"`
+	Metadata        Metadata       `json:"metadata,omitempty"`      // Custom field values
+	Starred         bool           `json:"starred,omitempty"`       // Starred by the user asking, where known
+	IncidentID      int64          `json:"incident_id,omitempty"`   // Incident the event is grouped in, where known
+	IncidentSize    int            `json:"incident_size,omitempty"` // Events in that incident, where known
+	Links           []EventLink    `json:"links,omitempty"`         // Links from and to other events, where asked for
+	ProjectID       int64          `json:"project_id"`
+	Status          string         `json:"status"`              // One of EventStatuses
+	DueAt           *time.Time     `json:"due_at,omitempty"`    // When the event should be resolved by, if set
+	Location        string         `json:"location,omitempty"`  // Where the event happened, such as a site or an address
+	Latitude        *float64       `json:"latitude,omitempty"`  // Degrees, set together with Longitude
+	Longitude       *float64       `json:"longitude,omitempty"` // Degrees, set together with Latitude
+	CreatedAt       time.Time      `json:"created_at"`
 }
 
 type EventRequest struct {
-	Tags   []string `json:"tags"`
-	Data   string   `json:"data"`
-	Source string   `json:"source"`
+	Tags        []string       `json:"tags"`
+	Data        string         `json:"data"`
+	Source      string         `json:"source"`
+	HTMLBody    string         `json:"html_body,omitempty"`
+	Email       *EmailMetadata `json:"email,omitempty"`
+	Metadata    Metadata       `json:"metadata,omitempty"`   // Custom field values
+	ProjectID   int64          `json:"project_id,omitempty"` // The default project when zero
+	Location    string         `json:"location,omitempty"`   // Geocoded by the "geocode" processor when there are no coordinates
+	Latitude    *float64       `json:"latitude,omitempty"`   // Degrees, set together with Longitude
+	Longitude   *float64       `json:"longitude,omitempty"`  // Degrees, set together with Latitude
+	Attachments []Attachment   `json:"-"`                    // Stored with the event; IDs are assigned on insert
+}
+
+// EmailMetadata is the envelope and headers of an email that became an event
+type EmailMetadata struct {
+	From            string              `json:"from,omitempty"`
+	To              string              `json:"to,omitempty"`
+	Cc              []string            `json:"cc,omitempty"`
+	Subject         string              `json:"subject,omitempty"`
+	MessageID       string              `json:"message_id,omitempty"`
+	InReplyTo       string              `json:"in_reply_to,omitempty"`
+	References      []string            `json:"references,omitempty"`
+	Date            time.Time           `json:"date,omitempty"`
+	ReceivedFrom    string              `json:"received_from,omitempty"`
+	AuthenticatedAs string              `json:"authenticated_as,omitempty"`
+	Headers         map[string][]string `json:"headers,omitempty"`
+	Auth            *EmailAuth          `json:"auth,omitempty"` // DKIM and SPF verdicts, when checked
+}
+
+// Trust levels of an email event, from its DKIM and SPF verdicts
+const (
+	TrustVerified   = "verified"   // DKIM or SPF passed for the sender's domain
+	TrustFailed     = "failed"     // DKIM or SPF failed; the sender may be spoofed
+	TrustUnverified = "unverified" // Nothing could be checked
+)
+
+// EmailAuth records the DKIM and SPF verdicts of an email. Verdicts are the
+// RFC 8601 result names: pass, fail, softfail, neutral, none, temperror or
+// permerror. An empty verdict was not checked.
+type EmailAuth struct {
+	DKIM       string `json:"dkim,omitempty"`
+	DKIMDomain string `json:"dkim_domain,omitempty"` // Signing domain (d=) of the passing or first signature
+	SPF        string `json:"spf,omitempty"`
+	SPFDomain  string `json:"spf_domain,omitempty"` // Domain of the envelope sender
+	CheckedBy  string `json:"checked_by,omitempty"` // "smtp" or the inbound provider that reported the verdicts
+}
+
+// Trust summarizes the verdicts for the From address. A pass counts only when
+// its domain is the From domain or a parent of it; verdicts reported without
+// a domain count as is.
+func (a *EmailAuth) Trust(from string) string {
+	if a == nil {
+		return TrustUnverified
+	}
+	fromDomain := ""
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		fromDomain = strings.ToLower(strings.Trim(from[at+1:], "> "))
+	}
+	aligned := func(domain string) bool {
+		domain = strings.ToLower(domain)
+		return domain == "" || fromDomain == "" || fromDomain == domain || strings.HasSuffix(fromDomain, "."+domain)
+	}
+
+	if (a.DKIM == "pass" && aligned(a.DKIMDomain)) || (a.SPF == "pass" && aligned(a.SPFDomain)) {
+		return TrustVerified
+	}
+	if a.DKIM == "fail" || a.SPF == "fail" || a.SPF == "softfail" {
+		return TrustFailed
+	}
+	return TrustUnverified
 }
 
 // EventResponse represents a list of events
@@ -21,3 +106,29 @@ type EventResponse struct {
 	Events []Event `json:"events"`
 	Total  int     `json:"total"`
 }
+
+// EventFilter describes a filtered, paginated query over events
+type EventFilter struct {
+	Tags      []string
+	MatchAll  bool           // Require every tag in Tags rather than any of them
+	DateFrom  string         // YYYY-MM-DD, inclusive
+	DateTo    string         // YYYY-MM-DD, inclusive
+	Location  *time.Location // Time zone the dates are days in; UTC when nil
+	Since     time.Time      // Only events created at or after this time, unless zero
+	Source    string
+	Query     string       // Free-text search over event data and source
+	StarredBy string       // Only events this user starred
+	Incident  int64        // Only events grouped in this incident
+	Collapse  bool         // Only the newest matching event of each incident
+	Status    string       // Only events with this status, one of EventStatuses
+	Due       string       // Only unresolved events due soon or overdue, one of DueFilters
+	Labels    []string     // Only events with all of these labels
+	BBox      *BoundingBox // Only events located inside this box
+	Located   bool         // Only events with coordinates
+	Metadata  Metadata     // Custom field values events must have
+	Projects  []int64      // Only events in these projects, unless nil
+	SortBy    string       // "created_at" (default), "source" or "id"
+	SortDesc  bool
+	Limit     int
+	Offset    int
+}
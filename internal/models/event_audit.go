@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Actions recorded in the event audit trail
+const (
+	EventCreated       = "create"
+	EventEdited        = "edit"
+	EventDeleted       = "delete"
+	EventRestored      = "restore"
+	EventStatusChanged = "status"
+)
+
+// EventAuditEntry records a change someone made to an event. Old is nil for
+// created and restored events and New is nil for deleted ones.
+type EventAuditEntry struct {
+	ID        int64     `json:"id"`
+	EventID   int64     `json:"event_id"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Old       *Event    `json:"old,omitempty"`
+	New       *Event    `json:"new,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FieldChange is an edited field of an event with its values before and after
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// Changes lists the fields that differ between the old and new event. It is
+// empty unless both are set.
+func (e *EventAuditEntry) Changes() []FieldChange {
+	if e.Old == nil || e.New == nil {
+		return nil
+	}
+	var changes []FieldChange
+	if oldTags, newTags := strings.Join(e.Old.Tags, ", "), strings.Join(e.New.Tags, ", "); oldTags != newTags {
+		changes = append(changes, FieldChange{Field: "tags", Old: oldTags, New: newTags})
+	}
+	if oldLabels, newLabels := strings.Join(e.Old.Labels, ", "), strings.Join(e.New.Labels, ", "); oldLabels != newLabels {
+		changes = append(changes, FieldChange{Field: "labels", Old: oldLabels, New: newLabels})
+	}
+	if e.Old.Status != e.New.Status {
+		changes = append(changes, FieldChange{Field: "status", Old: e.Old.Status, New: e.New.Status})
+	}
+	if oldDue, newDue := formatDue(e.Old.DueAt), formatDue(e.New.DueAt); oldDue != newDue {
+		changes = append(changes, FieldChange{Field: "due", Old: oldDue, New: newDue})
+	}
+	if oldPlace, newPlace := e.Old.Place(), e.New.Place(); oldPlace != newPlace {
+		changes = append(changes, FieldChange{Field: "location", Old: oldPlace, New: newPlace})
+	}
+	if e.Old.Source != e.New.Source {
+		changes = append(changes, FieldChange{Field: "source", Old: e.Old.Source, New: e.New.Source})
+	}
+	if e.Old.Data != e.New.Data {
+		changes = append(changes, FieldChange{Field: "data", Old: e.Old.Data, New: e.New.Data})
+	}
+
+	// Custom field values, by name
+	var names []string
+	for name := range e.Old.Metadata {
+		names = append(names, name)
+	}
+	for name := range e.New.Metadata {
+		if _, ok := e.Old.Metadata[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		oldValue, newValue := metadataValue(e.Old.Metadata, name), metadataValue(e.New.Metadata, name)
+		if oldValue != newValue {
+			changes = append(changes, FieldChange{Field: name, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+// metadataValue formats a custom field value for the audit trail, empty
+// when it isn't set
+func metadataValue(metadata Metadata, name string) string {
+	value, ok := metadata[name]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// EventAuditResponse is the audit trail of an event
+type EventAuditResponse struct {
+	Entries []EventAuditEntry `json:"entries"`
+	Total   int               `json:"total"`
+}
+
+// formatDue formats a due date for the audit trail, empty when it isn't set
+func formatDue(due *time.Time) string {
+	if due == nil {
+		return ""
+	}
+	return due.UTC().Format("2006-01-02 15:04 MST")
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// EventLog records the outcome of storing or changing an event
+type EventLog struct {
+	ID           int64     `json:"id"`
+	EventID      int64     `json:"event_id,omitempty"` // 0 when the event was never stored
+	Status       string    `json:"status"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expectation is activity that must keep arriving: at least MinEvents events
+// from Source, or with any of Tags, in every Window. Every condition that is
+// set must match.
+type Expectation struct {
+	ID          int64         `json:"id"`
+	Name        string        `json:"name"`
+	Source      string        `json:"source"` // Whole, ignoring case
+	Tags        []string      `json:"tags"`   // Any of them
+	MinEvents   int           `json:"min_events"`
+	Window      time.Duration `json:"window"`
+	Channel     string        `json:"channel"` // "webhook", "email" or "slack", as for alert rules
+	Target      string        `json:"target"`  // URL, or email address
+	Enabled     bool          `json:"enabled"`
+	CreatedBy   string        `json:"created_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+	CheckedAt   *time.Time    `json:"checked_at,omitempty"`
+	MissedSince *time.Time    `json:"missed_since,omitempty"` // Set while the expectation is missed
+}
+
+// WindowText shows the window as it is typed into forms, such as "30m",
+// "36h" or "7d"
+func (e Expectation) WindowText() string {
+	if e.Window > 0 && e.Window%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(e.Window/(24*time.Hour))) + "d"
+	}
+	s := e.Window.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Expectation check outcomes
+const (
+	ExpectationMet       = "met"       // Enough events arrived in the last window
+	ExpectationPending   = "pending"   // Too few events arrived, but the expectation is younger than its window
+	ExpectationMissed    = "missed"    // Too few events arrived in the last window
+	ExpectationRecovered = "recovered" // The expectation is met again after it was missed
+)
+
+// ExpectationCheck is how an expectation fared when it was checked
+type ExpectationCheck struct {
+	Expectation Expectation `json:"expectation"`
+	State       string      `json:"state"`                // One of the Expectation outcomes
+	Events      int         `json:"events"`               // Matching events in the last window, counted up to MinEvents
+	LastEvent   *time.Time  `json:"last_event,omitempty"` // Newest matching event ever stored
+	CheckedAt   time.Time   `json:"checked_at"`
+}
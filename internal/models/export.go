@@ -0,0 +1,33 @@
+package models
+
+// ExportedEvent is one line of an NDJSON export: an event with its
+// ingestion logs and attachments
+type ExportedEvent struct {
+	Event
+	Logs        []EventLog           `json:"logs,omitempty"`
+	Attachments []ExportedAttachment `json:"attachments,omitempty"`
+}
+
+// ExportedAttachment is an attachment of an exported event. Data is only
+// set when the export includes attachment contents.
+type ExportedAttachment struct {
+	Attachment
+	Data []byte `json:"data,omitempty"`
+}
+
+// Conflict strategies for importing an event whose ID or email Message-ID
+// is already stored
+const (
+	ImportSkip      = "skip"      // keep the stored event
+	ImportOverwrite = "overwrite" // replace the stored event with the same ID
+	ImportNewID     = "new-id"    // store the event under a new ID
+)
+
+// ImportResult counts what an import did with the events it was given
+type ImportResult struct {
+	Imported    int `json:"imported"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+	// Attachments whose contents were not in the export and were left out
+	MissingAttachmentData int `json:"missing_attachment_data"`
+}
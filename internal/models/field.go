@@ -0,0 +1,187 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of custom fields
+const (
+	FieldString = "string"
+	FieldNumber = "number"
+	FieldEnum   = "enum"
+	FieldDate   = "date"
+)
+
+// FieldTypes lists the types a custom field can have
+var FieldTypes = []string{FieldString, FieldNumber, FieldEnum, FieldDate}
+
+// maxFieldStringLength limits the values of string fields, in characters
+const maxFieldStringLength = 500
+
+// fieldNamePattern is what custom field names look like; they are used as
+// keys in event metadata and in query parameters
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// Metadata holds an event's custom field values by field name
+type Metadata map[string]interface{}
+
+// CustomField is a typed attribute admins define for events, such as
+// "severity". Values are kept in the event's metadata under the field's name.
+type CustomField struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Label     string    `json:"label"`
+	Type      string    `json:"type"`
+	Options   []string  `json:"options,omitempty"` // Allowed values of enum fields
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateFieldRequest defines a new custom field
+type CreateFieldRequest struct {
+	Name    string   `json:"name" binding:"required"`
+	Label   string   `json:"label"`
+	Type    string   `json:"type" binding:"required"`
+	Options []string `json:"options"`
+}
+
+// ListFieldsResponse is the defined custom fields
+type ListFieldsResponse struct {
+	Fields []CustomField `json:"fields"`
+	Total  int           `json:"total"`
+}
+
+// Validate checks the field's name, type and options, trimming the options
+// and defaulting the label to the name
+func (f *CustomField) Validate() error {
+	if !fieldNamePattern.MatchString(f.Name) {
+		return fmt.Errorf("field names must start with a lowercase letter and contain only lowercase letters, digits and underscores, up to 40 characters")
+	}
+	if f.Label = strings.TrimSpace(f.Label); f.Label == "" {
+		f.Label = f.Name
+	}
+	if !slices.Contains(FieldTypes, f.Type) {
+		return fmt.Errorf("unknown field type %q, want one of %s", f.Type, strings.Join(FieldTypes, ", "))
+	}
+
+	var options []string
+	for _, option := range f.Options {
+		if option = strings.TrimSpace(option); option != "" && !slices.Contains(options, option) {
+			options = append(options, option)
+		}
+	}
+	f.Options = options
+	if f.Type == FieldEnum && len(f.Options) == 0 {
+		return fmt.Errorf("enum fields need at least one option")
+	}
+	if f.Type != FieldEnum {
+		f.Options = nil
+	}
+	return nil
+}
+
+// Parse converts a value typed into a form or query string to the field's
+// type. Numbers become float64 and dates stay YYYY-MM-DD strings.
+func (f *CustomField) Parse(value string) (interface{}, error) {
+	value = strings.TrimSpace(value)
+	switch f.Type {
+	case FieldNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+			return nil, fmt.Errorf("%s must be a number", f.Label)
+		}
+		return n, nil
+	default:
+		return f.Check(value)
+	}
+}
+
+// Check validates a value given in an event's metadata, such as through the
+// API, returning it in the field's type
+func (f *CustomField) Check(value interface{}) (interface{}, error) {
+	switch f.Type {
+	case FieldNumber:
+		n, ok := value.(float64)
+		if !ok || math.IsInf(n, 0) || math.IsNaN(n) {
+			return nil, fmt.Errorf("%s must be a number", f.Label)
+		}
+		return n, nil
+	case FieldString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", f.Label)
+		}
+		if len([]rune(s)) > maxFieldStringLength {
+			return nil, fmt.Errorf("%s must be at most %d characters", f.Label, maxFieldStringLength)
+		}
+		return s, nil
+	case FieldEnum:
+		s, ok := value.(string)
+		if !ok || !slices.Contains(f.Options, s) {
+			return nil, fmt.Errorf("%s must be one of %s", f.Label, strings.Join(f.Options, ", "))
+		}
+		return s, nil
+	case FieldDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD)", f.Label)
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD)", f.Label)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s has unknown type %q", f.Label, f.Type)
+	}
+}
+
+// Format writes a stored value the way it is typed into forms
+func (f *CustomField) Format(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// FieldByName returns the field with the name, or nil if there is none
+func FieldByName(fields []CustomField, name string) *CustomField {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// CheckMetadata validates an event's metadata against the defined fields.
+// Every key must be a field's name; null values are dropped.
+func CheckMetadata(fields []CustomField, metadata Metadata) (Metadata, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	checked := make(Metadata, len(metadata))
+	for name, value := range metadata {
+		field := FieldByName(fields, name)
+		if field == nil {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		if value == nil {
+			continue
+		}
+		v, err := field.Check(value)
+		if err != nil {
+			return nil, err
+		}
+		checked[name] = v
+	}
+	return checked, nil
+}
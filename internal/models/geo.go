@@ -0,0 +1,133 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLocationLength limits event locations, in characters
+const maxLocationLength = 200
+
+// SetLocationRequest sets where an event happened. When only the location is
+// given it is geocoded; an empty request clears both.
+type SetLocationRequest struct {
+	Location  string   `json:"location"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// Validate trims the location and checks it and the coordinates
+func (r *SetLocationRequest) Validate() error {
+	r.Location = strings.TrimSpace(r.Location)
+	if utf8.RuneCountInString(r.Location) > maxLocationLength {
+		return fmt.Errorf("location must be at most %d characters", maxLocationLength)
+	}
+	return ValidateCoordinates(r.Latitude, r.Longitude)
+}
+
+// ValidateCoordinates checks that latitude and longitude are both set or
+// both nil, and are degrees within range
+func ValidateCoordinates(latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return fmt.Errorf("latitude and longitude must be given together")
+	}
+	if latitude == nil {
+		return nil
+	}
+	if math.IsNaN(*latitude) || *latitude < -90 || *latitude > 90 {
+		return fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if math.IsNaN(*longitude) || *longitude < -180 || *longitude > 180 {
+		return fmt.Errorf("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// Located reports whether the event has coordinates
+func (e Event) Located() bool {
+	return e.Latitude != nil && e.Longitude != nil
+}
+
+// Coordinates formats the event's coordinates as "latitude, longitude", or
+// returns "" when it has none
+func (e Event) Coordinates() string {
+	if !e.Located() {
+		return ""
+	}
+	return strconv.FormatFloat(*e.Latitude, 'f', 5, 64) + ", " + strconv.FormatFloat(*e.Longitude, 'f', 5, 64)
+}
+
+// Place describes where the event happened: its location, its coordinates
+// or both, such as "Amsterdam DC (52.37000, 4.89000)"
+func (e Event) Place() string {
+	switch coordinates := e.Coordinates(); {
+	case coordinates == "":
+		return e.Location
+	case e.Location == "":
+		return coordinates
+	default:
+		return e.Location + " (" + coordinates + ")"
+	}
+}
+
+// BoundingBox is an area between two meridians and two parallels, in
+// degrees. West is greater than East for boxes crossing the antimeridian.
+type BoundingBox struct {
+	West, South, East, North float64
+}
+
+// ParseBoundingBox reads a box written "west,south,east,north", the order
+// used by GeoJSON and OpenStreetMap
+func ParseBoundingBox(s string) (*BoundingBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bounding box must be west,south,east,north")
+	}
+	var values [4]float64
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(value) {
+			return nil, fmt.Errorf("bounding box must be four numbers, west,south,east,north")
+		}
+		values[i] = value
+	}
+	box := &BoundingBox{West: values[0], South: values[1], East: values[2], North: values[3]}
+	if box.South < -90 || box.North > 90 || box.South > box.North {
+		return nil, fmt.Errorf("bounding box latitudes must be between -90 and 90, south first")
+	}
+	if box.West < -180 || box.West > 180 || box.East < -180 || box.East > 180 {
+		return nil, fmt.Errorf("bounding box longitudes must be between -180 and 180")
+	}
+	return box, nil
+}
+
+// String writes the box as ParseBoundingBox reads it
+func (b BoundingBox) String() string {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return format(b.West) + "," + format(b.South) + "," + format(b.East) + "," + format(b.North)
+}
+
+// Contains reports whether the event is located inside the box
+func (b BoundingBox) Contains(e Event) bool {
+	if !e.Located() || *e.Latitude < b.South || *e.Latitude > b.North {
+		return false
+	}
+	if b.West > b.East {
+		return *e.Longitude >= b.West || *e.Longitude <= b.East
+	}
+	return *e.Longitude >= b.West && *e.Longitude <= b.East
+}
+
+// Around returns the box reaching margin degrees from a point, clamped to
+// valid coordinates
+func Around(latitude, longitude, margin float64) BoundingBox {
+	return BoundingBox{
+		West:  math.Max(longitude-margin, -180),
+		South: math.Max(latitude-margin, -90),
+		East:  math.Min(longitude+margin, 180),
+		North: math.Min(latitude+margin, 90),
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Incident is a burst of similar events: events of one project with the same
+// source and tags, each stored within the correlation window of the previous
+// one
+type Incident struct {
+	ID         int64     `json:"id"`
+	ProjectID  int64     `json:"project_id"`
+	Source     string    `json:"source"`
+	Tags       []string  `json:"tags"`
+	Summary    string    `json:"summary"`     // First line of the first event
+	EventCount int       `json:"event_count"` // Events still grouped in it
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// IncidentResponse lists incidents, most recently active first
+type IncidentResponse struct {
+	Incidents []Incident `json:"incidents"`
+	Total     int        `json:"total"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// InviteCode grants a single self-service registration
+type InviteCode struct {
+	ID        int64     `json:"id"`
+	Code      string    `json:"code"`
+	CreatedBy string    `json:"created_by"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UsedAt    time.Time `json:"used_at,omitempty"`
+	UsedBy    string    `json:"used_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsUsable reports whether the invite code can still be redeemed
+func (i *InviteCode) IsUsable() bool {
+	return i.UsedAt.IsZero() && time.Now().Before(i.ExpiresAt)
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultLabelColor is the color of labels created without one
+const DefaultLabelColor = "#3498db"
+
+// maxLabelDescriptionLength limits label descriptions, in characters
+const maxLabelDescriptionLength = 200
+
+var (
+	// labelNamePattern is what label names look like; they are used in
+	// query parameters such as label=needs-triage
+	labelNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]{0,39}$`)
+	// labelColorPattern is a CSS hex color such as #e74c3c
+	labelColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+)
+
+// Label is a category admins define for events, such as "customer-impact".
+// Unlike tags, which are free-form and extracted from events, labels come
+// from a managed list and have a color and description. Events keep the
+// names of their labels.
+type Label struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// LabelRequest defines a label, or changes its color and description; names
+// can't be changed
+type LabelRequest struct {
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+}
+
+// ListLabelsResponse is the defined labels
+type ListLabelsResponse struct {
+	Labels []Label `json:"labels"`
+	Total  int     `json:"total"`
+}
+
+// SetLabelsRequest replaces the labels of an event
+type SetLabelsRequest struct {
+	Labels []string `json:"labels"`
+}
+
+// Validate checks the label's name, color and description, lowercasing the
+// name and color and defaulting the color
+func (l *Label) Validate() error {
+	l.Name = strings.ToLower(strings.TrimSpace(l.Name))
+	if !labelNamePattern.MatchString(l.Name) {
+		return fmt.Errorf("label names must start with a lowercase letter or digit and contain only lowercase letters, digits and _ . : -, up to 40 characters")
+	}
+	return l.ValidateStyle()
+}
+
+// ValidateStyle checks the label's color and description, the parts of a
+// label that can be changed, lowercasing and defaulting the color
+func (l *Label) ValidateStyle() error {
+	if l.Color = strings.ToLower(strings.TrimSpace(l.Color)); l.Color == "" {
+		l.Color = DefaultLabelColor
+	}
+	if !labelColorPattern.MatchString(l.Color) {
+		return fmt.Errorf("colors must look like #e74c3c")
+	}
+	if l.Description = strings.TrimSpace(l.Description); len([]rune(l.Description)) > maxLabelDescriptionLength {
+		return fmt.Errorf("descriptions are limited to %d characters", maxLabelDescriptionLength)
+	}
+	return nil
+}
+
+// CheckLabels returns the defined labels' names among names, lowercased,
+// sorted in the order labels are listed and without duplicates, or an error
+// naming the first undefined label
+func CheckLabels(labels []Label, names []string) ([]string, error) {
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			wanted[name] = true
+		}
+	}
+	var checked []string
+	for _, label := range labels {
+		if wanted[label.Name] {
+			checked = append(checked, label.Name)
+			delete(wanted, label.Name)
+		}
+	}
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); wanted[name] {
+			return nil, fmt.Errorf("unknown label %q", name)
+		}
+	}
+	return checked, nil
+}
+
+// LabelsByName indexes labels by their names
+func LabelsByName(labels []Label) map[string]Label {
+	byName := make(map[string]Label, len(labels))
+	for _, label := range labels {
+		byName[label.Name] = label
+	}
+	return byName
+}
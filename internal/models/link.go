@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Link types. A link reads "event <type> target".
+const (
+	LinkCausedBy    = "caused-by"    // The event was caused by the target, such as an incident by a deploy
+	LinkDuplicateOf = "duplicate-of" // The event reports the same thing as the target
+	LinkFollowUp    = "follow-up"    // The event follows up on the target
+)
+
+// LinkTypes lists the types of links between events
+var LinkTypes = []string{LinkCausedBy, LinkDuplicateOf, LinkFollowUp}
+
+// EventLink relates two events
+type EventLink struct {
+	ID        int64     `json:"id"`
+	EventID   int64     `json:"event_id"`
+	TargetID  int64     `json:"target_id"`
+	Type      string    `json:"type"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Incoming  bool      `json:"incoming"`        // The other event links to the one asked about
+	Event     *Event    `json:"event,omitempty"` // The other event
+}
+
+// Label describes the link from the side of the event asked about, such as
+// "caused by" or, for an incoming link, "caused"
+func (l EventLink) Label() string {
+	switch {
+	case l.Type == LinkCausedBy && l.Incoming:
+		return "caused"
+	case l.Type == LinkDuplicateOf && l.Incoming:
+		return "duplicated by"
+	case l.Type == LinkFollowUp && l.Incoming:
+		return "followed up by"
+	case l.Type == LinkCausedBy:
+		return "caused by"
+	case l.Type == LinkDuplicateOf:
+		return "duplicate of"
+	case l.Type == LinkFollowUp:
+		return "follow-up to"
+	}
+	return l.Type
+}
+
+// CreateLinkRequest links an event to another
+type CreateLinkRequest struct {
+	Type     string `json:"type" binding:"required,oneof=caused-by duplicate-of follow-up"`
+	TargetID int64  `json:"target_id" binding:"required"`
+}
+
+// EventLinksResponse is the links of an event, in both directions
+type EventLinksResponse struct {
+	Links []EventLink `json:"links"`
+	Total int         `json:"total"`
+}
@@ -20,13 +20,18 @@ type RegistrationToken struct {
 	UsedAt    time.Time `json:"used_at,omitempty"`
 }
 
+// EmailMapping routes mail sent to a generated address: events get the
+// mapping's tags and source, and are forwarded to its endpoint URL if set
 type EmailMapping struct {
 	ID             int64     `json:"id"`
-	UserID         int64     `json:"user_id"`
+	Owner          string    `json:"owner"`
 	GeneratedEmail string    `json:"generated_email"`
-	EndpointURL    string    `json:"endpoint_url"`
+	Tags           []string  `json:"tags"`
+	Source         string    `json:"source,omitempty"`
+	EndpointURL    string    `json:"endpoint_url,omitempty"`
 	Description    string    `json:"description,omitempty"`
 	IsActive       bool      `json:"is_active"`
+	ProjectID      int64     `json:"project_id"` // Project events routed by the mapping are stored in
 	CreatedAt      time.Time `json:"created_at"`
 	LastUsedAt     time.Time `json:"last_used_at,omitempty"`
 }
@@ -50,8 +55,20 @@ type CreateUserRequest struct {
 }
 
 type CreateMappingRequest struct {
-	EndpointURL string `json:"endpoint_url" binding:"required,url"`
-	Description string `json:"description"`
+	Tags        []string `json:"tags"`
+	Source      string   `json:"source"`
+	EndpointURL string   `json:"endpoint_url" binding:"omitempty,url"`
+	Description string   `json:"description"`
+	ProjectID   int64    `json:"project_id"` // The default project, or a project token's, when zero
+}
+
+type UpdateMappingRequest struct {
+	Tags        []string `json:"tags"`
+	Source      string   `json:"source"`
+	EndpointURL string   `json:"endpoint_url" binding:"omitempty,url"`
+	Description string   `json:"description"`
+	IsActive    *bool    `json:"is_active"`
+	ProjectID   int64    `json:"project_id"` // Unchanged when zero
 }
 
 type LoginRequest struct {
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// HeartbeatTag is the tag of the synthetic events monitors send
+const HeartbeatTag = "heartbeat"
+
+// Monitor states
+const (
+	MonitorOK      = "ok"      // Heartbeats arrive and so do other events from the source
+	MonitorQuiet   = "quiet"   // Heartbeats arrive, but no other events came from the source in the last interval
+	MonitorLate    = "late"    // The last heartbeat is older than two intervals: events from the source aren't getting stored
+	MonitorPending = "pending" // No heartbeat has been stored yet
+)
+
+// MonitorActivity is when a monitor's source last had a heartbeat and another
+// event stored
+type MonitorActivity struct {
+	LastHeartbeat *time.Time
+	HeartbeatID   int64 // Event of the last heartbeat
+	LastEvent     *time.Time
+}
+
+// MonitorStatus tells whether a monitor's heartbeats, and other events from
+// its source, are arriving
+type MonitorStatus struct {
+	Name          string     `json:"name"`
+	Source        string     `json:"source"`
+	Interval      string     `json:"interval"`
+	State         string     `json:"state"` // One of the Monitor states
+	LastHeartbeat *time.Time `json:"last_heartbeat,omitempty"`
+	HeartbeatID   int64      `json:"heartbeat_event_id,omitempty"`
+	LastEvent     *time.Time `json:"last_event,omitempty"` // Newest event from the source besides heartbeats
+}
+
+// MonitorStatusResponse lists the configured monitors
+type MonitorStatusResponse struct {
+	Monitors []MonitorStatus `json:"monitors"`
+	Total    int             `json:"total"`
+}
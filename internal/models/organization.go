@@ -0,0 +1,94 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultOrganizationID is the organization projects belong to unless they
+// are put in another one
+const DefaultOrganizationID = 1
+
+// Organization roles
+const (
+	OrganizationRoleMember = "member" // Reads the events of every project of the organization
+	OrganizationRoleAdmin  = "admin"  // Also edits them, and manages the organization's members, projects and API tokens
+)
+
+// OrganizationRoles lists the roles a user can have in an organization
+var OrganizationRoles = []string{OrganizationRoleMember, OrganizationRoleAdmin}
+
+// Organization groups the projects of one tenant
+type Organization struct {
+	ID        int64     `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks the slug and fills in a missing name
+func (o *Organization) Validate() error {
+	if err := validateSlug("organization", &o.Slug); err != nil {
+		return err
+	}
+	if o.Name = strings.TrimSpace(o.Name); o.Name == "" {
+		o.Name = o.Slug
+	}
+	return nil
+}
+
+// OrganizationMember gives a user a role in an organization
+type OrganizationMember struct {
+	OrganizationID int64     `json:"organization_id"`
+	Username       string    `json:"username"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ProjectUsage is how much a project stores
+type ProjectUsage struct {
+	ProjectID   int64      `json:"project_id"`
+	Slug        string     `json:"slug"`
+	Name        string     `json:"name"`
+	Events      int        `json:"events"`
+	LastDay     int        `json:"events_last_24h"`
+	Size        int        `json:"bytes"`        // Stored size of the events, not counting attachments
+	NewestEvent *time.Time `json:"newest_event"` // nil when there are no events
+}
+
+// OrganizationUsage describes how much an organization stores and how it
+// uses the API
+type OrganizationUsage struct {
+	OrganizationID int64          `json:"organization_id"`
+	Events         int            `json:"events"`
+	LastDay        int            `json:"events_last_24h"`
+	Size           int            `json:"bytes"`
+	Projects       []ProjectUsage `json:"projects"`
+	Members        int            `json:"members"`
+	Tokens         int            `json:"api_tokens"`
+	TokensUsed     int            `json:"api_tokens_used_last_24h"`
+	EventsPerDay   []DailyCount   `json:"events_per_day"`
+}
+
+// CreateOrganizationRequest creates an organization
+type CreateOrganizationRequest struct {
+	Slug string `json:"slug" binding:"required"`
+	Name string `json:"name"`
+}
+
+// SetOrganizationMemberRequest gives a user a role in an organization
+type SetOrganizationMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=member admin"`
+}
+
+// ListOrganizationsResponse is the organizations the caller belongs to
+type ListOrganizationsResponse struct {
+	Organizations []Organization `json:"organizations"`
+	Total         int            `json:"total"`
+}
+
+// OrganizationMembersResponse is the members of an organization
+type OrganizationMembersResponse struct {
+	Members []OrganizationMember `json:"members"`
+	Total   int                  `json:"total"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// UserPreferences holds a user's web interface settings
+type UserPreferences struct {
+	Username      string    `json:"username"`
+	PageSize      int       `json:"page_size"`
+	DefaultFilter string    `json:"default_filter"` // Query string applied to the events list
+	Timezone      string    `json:"timezone"`
+	Theme         string    `json:"theme"`        // "light" or "dark"
+	Digest        string    `json:"digest"`       // "daily", "weekly", or empty for none
+	DigestEmail   string    `json:"digest_email"` // Where digests go; the account's email when empty
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DefaultPreferences returns the settings used until a user saves their own
+func DefaultPreferences(username string) *UserPreferences {
+	return &UserPreferences{
+		Username: username,
+		PageSize: 20,
+		Timezone: "UTC",
+		Theme:    "light",
+	}
+}
+
+// LocalDay returns when date, YYYY-MM-DD, starts and ends in loc, as UTC
+// times. A nil loc is UTC. Days are 23 or 25 hours long when clocks change.
+func LocalDay(date string, loc *time.Location) (start, end time.Time, err error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return day.UTC(), day.AddDate(0, 0, 1).UTC(), nil
+}
+
+// Location returns the preferred time zone, falling back to UTC if it is unknown
+func (p *UserPreferences) Location() *time.Location {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultProjectID is the project events belong to unless they are put in
+// another one. Every user can see and edit its events.
+const DefaultProjectID = 1
+
+// Project roles
+const (
+	ProjectViewer = "viewer" // Reads the project's events and comments on them
+	ProjectEditor = "editor" // Also creates, edits and deletes them
+)
+
+// ProjectRoles lists the roles a user or API token can have in a project
+var ProjectRoles = []string{ProjectViewer, ProjectEditor}
+
+// slugPattern is what project and organization slugs may look like
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,39}$`)
+
+// validateSlug normalizes a project or organization slug and checks it
+func validateSlug(kind string, slug *string) error {
+	*slug = strings.ToLower(strings.TrimSpace(*slug))
+	if !slugPattern.MatchString(*slug) {
+		return fmt.Errorf("%s slugs must start with a lowercase letter or digit and contain only lowercase letters, digits and dashes, up to 40 characters", kind)
+	}
+	return nil
+}
+
+// Project groups the events of a team. Users only see the events of the
+// projects they are members of, plus the default project.
+type Project struct {
+	ID             int64     `json:"id"`
+	Slug           string    `json:"slug"` // Identifies the project in URLs and API requests
+	Name           string    `json:"name"`
+	OrganizationID int64     `json:"organization_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Validate checks the slug and fills in a missing name
+func (p *Project) Validate() error {
+	if err := validateSlug("project", &p.Slug); err != nil {
+		return err
+	}
+	if p.Name = strings.TrimSpace(p.Name); p.Name == "" {
+		p.Name = p.Slug
+	}
+	return nil
+}
+
+// ProjectMember gives a user a role in a project
+type ProjectMember struct {
+	ProjectID int64     `json:"project_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIToken is a token for the API limited to one project, or to the
+// projects of one organization when ProjectID is zero. The token itself is
+// only known when it is created; TokenHash is kept instead.
+type APIToken struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`
+	TokenHash      string    `json:"-"`
+	OrganizationID int64     `json:"organization_id"`
+	ProjectID      int64     `json:"project_id,omitempty"`
+	Role           string    `json:"role"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastUsedAt     time.Time `json:"last_used_at,omitempty"`
+}
+
+// ProjectAccess is what a user or API token can do in each project
+type ProjectAccess struct {
+	All           bool             // Admins and the configured API token can edit every project
+	Roles         map[int64]string // Otherwise, their role in each project they can see
+	Organizations map[int64]string // and their role in each organization they belong to
+}
+
+// CanManage reports whether the organization's members, projects and API
+// tokens can be managed
+func (a *ProjectAccess) CanManage(organizationID int64) bool {
+	return a.All || a.Organizations[organizationID] == OrganizationRoleAdmin
+}
+
+// CanView reports whether the events of the project can be read
+func (a *ProjectAccess) CanView(projectID int64) bool {
+	if projectID == 0 {
+		projectID = DefaultProjectID
+	}
+	return a.All || a.Roles[projectID] != ""
+}
+
+// CanEdit reports whether events can be created, edited and deleted in the
+// project
+func (a *ProjectAccess) CanEdit(projectID int64) bool {
+	if projectID == 0 {
+		projectID = DefaultProjectID
+	}
+	return a.All || a.Roles[projectID] == ProjectEditor
+}
+
+// Viewable returns the IDs of the projects that can be read, for
+// EventFilter.Projects, or nil when every project can be
+func (a *ProjectAccess) Viewable() []int64 {
+	if a.All {
+		return nil
+	}
+	ids := make([]int64, 0, len(a.Roles))
+	for id := range a.Roles {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Visible returns the projects among projects that can be read
+func (a *ProjectAccess) Visible(projects []Project) []Project {
+	var visible []Project
+	for _, project := range projects {
+		if a.CanView(project.ID) {
+			visible = append(visible, project)
+		}
+	}
+	return visible
+}
+
+// CreateProjectRequest creates a project
+type CreateProjectRequest struct {
+	Slug           string `json:"slug" binding:"required"`
+	Name           string `json:"name"`
+	OrganizationID int64  `json:"organization_id"` // The default organization unless set
+}
+
+// SetProjectMemberRequest gives a user a role in a project
+type SetProjectMemberRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor"`
+}
+
+// CreateAPITokenRequest creates a project API token
+type CreateAPITokenRequest struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role" binding:"required,oneof=viewer editor"`
+}
+
+// CreateAPITokenResponse is a new token, the only time it is shown
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// ListProjectsResponse is the projects the caller can see
+type ListProjectsResponse struct {
+	Projects []Project `json:"projects"`
+	Total    int       `json:"total"`
+}
+
+// ProjectMembersResponse is the members of a project
+type ProjectMembersResponse struct {
+	Members []ProjectMember `json:"members"`
+	Total   int             `json:"total"`
+}
+
+// ListAPITokensResponse is the API tokens of a project
+type ListAPITokensResponse struct {
+	Tokens []APIToken `json:"tokens"`
+	Total  int        `json:"total"`
+}
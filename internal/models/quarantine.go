@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// QuarantinedEvent is an event held back for review instead of being stored
+type QuarantinedEvent struct {
+	ID        int64        `json:"id"`
+	Sender    string       `json:"sender"`
+	Source    string       `json:"source"`
+	Subject   string       `json:"subject"`
+	Reason    string       `json:"reason"`
+	Score     *float64     `json:"score,omitempty"`
+	Event     EventRequest `json:"event"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// QuarantineResponse represents a list of quarantined events
+type QuarantineResponse struct {
+	Items []QuarantinedEvent `json:"items"`
+	Total int                `json:"total"`
+}
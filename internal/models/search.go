@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// SavedSearch is a named events list filter saved by a web user
+type SavedSearch struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"` // Query string applied to the events list
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// URL returns the events list filtered by the search
+func (s SavedSearch) URL() string {
+	return "/?" + s.Query
+}
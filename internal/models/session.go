@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// WebSession is a persisted web interface session
+type WebSession struct {
+	TokenHash string    `json:"-"`
+	Handle    string    `json:"handle"`
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EmailVerification is a pending verification of a self-registered user's
+// email address
+type EmailVerification struct {
+	TokenHash string
+	UserID    int
+	Username  string
+	ExpiresAt time.Time
+}
+
+// WebUser is a persisted web interface account
+type WebUser struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
+	Email        string    `json:"email,omitempty"`
+	Pending      bool      `json:"pending"`
+	Provider     string    `json:"provider,omitempty"` // Identity provider that created the user, empty for local users
+	CreatedAt    time.Time `json:"created_at"`
+}
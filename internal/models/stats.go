@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// DailyCount is the number of events created on one day
+type DailyCount struct {
+	Day   time.Time `json:"day"`
+	Count int       `json:"count"`
+}
+
+// NameCount is the number of events carrying a tag or coming from a source
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TableStats is the size of one database table
+type TableStats struct {
+	Name string `json:"name"`
+	Rows int    `json:"rows"`  // estimated from the planner statistics
+	Size int    `json:"bytes"` // including indexes and TOAST data
+}
+
+// DatabaseStats describes the size and growth of the database
+type DatabaseStats struct {
+	Size         int          `json:"database_bytes"`
+	Tables       []TableStats `json:"tables"`
+	TotalEvents  int          `json:"total_events"`
+	OldestEvent  *time.Time   `json:"oldest_event"` // nil when there are no events
+	NewestEvent  *time.Time   `json:"newest_event"`
+	LastHour     int          `json:"events_last_hour"`
+	LastDay      int          `json:"events_last_24h"`
+	EventsPerDay []DailyCount `json:"events_per_day"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// Event statuses, for tracking events like incidents
+const (
+	StatusOpen         = "open" // New events start open
+	StatusAcknowledged = "acknowledged"
+	StatusResolved     = "resolved"
+	StatusIgnored      = "ignored"
+)
+
+// EventStatuses lists the statuses an event can have, in workflow order
+var EventStatuses = []string{StatusOpen, StatusAcknowledged, StatusResolved, StatusIgnored}
+
+// SetStatusRequest changes the status of an event
+type SetStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=open acknowledged resolved ignored"`
+}
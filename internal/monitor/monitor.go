@@ -0,0 +1,187 @@
+// Package monitor sends synthetic heartbeat events through the ingestion
+// pipeline on a schedule, so a source that has gone quiet can be told apart
+// from one whose events are no longer being stored
+package monitor
+
+import (
+	"context"
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/ingest"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultInterval is how often a heartbeat is sent when no interval is set
+	defaultInterval = time.Hour
+	// minInterval keeps heartbeats from crowding out real events
+	minInterval = time.Minute
+	// lateAfter is how many intervals may pass without a heartbeat before the
+	// monitor is late, leaving room for a slow or restarted sender
+	lateAfter = 2
+)
+
+// Sender is the address heartbeats are sent from, for sender rules
+const Sender = "heartbeat@eventdb.invalid"
+
+// namePattern is what monitor names look like; they tag the heartbeats
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]{0,39}$`)
+
+// Monitor sends a heartbeat from one source on an interval
+type Monitor struct {
+	Name     string
+	Source   string // Source of the heartbeats, whose pipeline they run through; defaults to the name
+	Interval time.Duration
+	Tags     []string // Added to every heartbeat, after "heartbeat" and the name
+}
+
+// Validate checks the monitor's configuration and fills in defaults
+func (m *Monitor) Validate() error {
+	m.Name = strings.ToLower(strings.TrimSpace(m.Name))
+	if !namePattern.MatchString(m.Name) {
+		return fmt.Errorf("monitor %q: name must be lowercase letters, digits and _ . : -", m.Name)
+	}
+	if m.Interval == 0 {
+		m.Interval = defaultInterval
+	}
+	if m.Interval < minInterval {
+		return fmt.Errorf("monitor %s: interval must be at least %s", m.Name, minInterval)
+	}
+	if m.Source == "" {
+		m.Source = m.Name
+	}
+	return nil
+}
+
+// Runner sends the heartbeats of a set of monitors
+type Runner struct {
+	ingester *ingest.Ingester
+	monitors []*Monitor
+}
+
+// NewRunner creates a runner ingesting heartbeats with ingester. Monitors
+// must be valid.
+func NewRunner(ingester *ingest.Ingester, monitors []*Monitor) *Runner {
+	return &Runner{ingester: ingester, monitors: monitors}
+}
+
+// Run sends every monitor's heartbeat immediately and then on its interval
+// until ctx is done
+func (r *Runner) Run(ctx context.Context) {
+	done := make(chan struct{})
+	for _, m := range r.monitors {
+		go func(m *Monitor) {
+			defer func() { done <- struct{}{} }()
+			r.loop(ctx, m)
+		}(m)
+	}
+	for range r.monitors {
+		<-done
+	}
+}
+
+func (r *Runner) loop(ctx context.Context, m *Monitor) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Beat(m, time.Now()); err != nil {
+			log.Printf("Monitor %s: %v", m.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Beat sends the monitor's heartbeat through the pipeline of its source and
+// returns the stored event. A heartbeat rejected or quarantined by the
+// pipeline is an error, since it means the monitor's checks can't pass.
+func (r *Runner) Beat(m *Monitor, now time.Time) (*models.Event, error) {
+	event, _, err := r.ingester.Ingest(Heartbeat(m, now), m.Source)
+	switch {
+	case errors.Is(err, ingest.ErrRejected), errors.Is(err, ingest.ErrQuarantined):
+		return nil, fmt.Errorf("heartbeat was not stored (%w); allow the sender %s or the source %s", err, Sender, m.Source)
+	case err != nil:
+		return nil, fmt.Errorf("failed to store heartbeat: %w", err)
+	}
+	log.Printf("Monitor %s: stored heartbeat from %s as event %d", m.Name, m.Source, event.ID)
+	return event, nil
+}
+
+// Heartbeat builds the monitor's heartbeat email sent at now. It is tagged
+// "heartbeat", the monitor's name and its tags, and has a unique Message-ID
+// so it isn't taken for a duplicate.
+func Heartbeat(m *Monitor, now time.Time) *ingest.Email {
+	tags := []string{models.HeartbeatTag, m.Name}
+	for _, tag := range m.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return &ingest.Email{
+		From:      Sender,
+		Subject:   "Heartbeat " + m.Name,
+		MessageID: fmt.Sprintf("<heartbeat.%s.%d@eventdb.invalid>", m.Name, now.UnixNano()),
+		Date:      now,
+		PlainBody: fmt.Sprintf("Heartbeat of the monitor %s at %s.\n\nSent every %s to show that events from %s are being stored.",
+			m.Name, now.UTC().Format(time.RFC3339), m.Interval, m.Source),
+		Headers: map[string][]string{"X-Eventdb-Monitor": {m.Name}},
+		Tags:    tags,
+	}
+}
+
+// Statuses tells, for each monitor, whether its heartbeats and other events
+// from its source are arriving as of now
+func Statuses(db *database.Database, monitors []*Monitor, now time.Time) ([]models.MonitorStatus, error) {
+	statuses := make([]models.MonitorStatus, 0, len(monitors))
+	for _, m := range monitors {
+		activity, err := db.MonitorActivity(m.Name, m.Source)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, models.MonitorStatus{
+			Name:          m.Name,
+			Source:        m.Source,
+			Interval:      m.Interval.String(),
+			State:         State(activity, m.Interval, now),
+			LastHeartbeat: activity.LastHeartbeat,
+			HeartbeatID:   activity.HeartbeatID,
+			LastEvent:     activity.LastEvent,
+		})
+	}
+	return statuses, nil
+}
+
+// State is one of the models.Monitor states: pending before the first
+// heartbeat, late when none arrived for lateAfter intervals, and quiet when
+// heartbeats arrive but no other event came from the source in the last
+// interval
+func State(activity *models.MonitorActivity, interval time.Duration, now time.Time) string {
+	switch {
+	case activity.LastHeartbeat == nil:
+		return models.MonitorPending
+	case now.Sub(*activity.LastHeartbeat) > lateAfter*interval:
+		return models.MonitorLate
+	case activity.LastEvent == nil || now.Sub(*activity.LastEvent) > interval:
+		return models.MonitorQuiet
+	default:
+		return models.MonitorOK
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
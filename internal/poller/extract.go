@@ -0,0 +1,207 @@
+package poller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"example-api/internal/utils"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Item is one entry or value found in a polled response
+type Item struct {
+	Key   string // Identifies the item across polls
+	Title string
+	Text  string
+	Link  string
+}
+
+// extract finds the items in a response body
+func extract(format, selector string, body []byte) ([]Item, error) {
+	switch format {
+	case FormatFeed:
+		return extractFeed(body)
+	case FormatJSON:
+		return extractJSON(selector, body)
+	case FormatHTML:
+		return extractHTML(selector, body)
+	default:
+		text := strings.TrimSpace(string(body))
+		if text == "" {
+			return nil, nil
+		}
+		return []Item{{Key: hash(text), Text: text}}, nil
+	}
+}
+
+// feed covers RSS 2.0 (items in a channel), RSS 1.0 (items beside the
+// channel) and Atom (entries)
+type feed struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+}
+
+type atomEntry struct {
+	ID      string `xml:"id"`
+	Title   string `xml:"title"`
+	Updated string `xml:"updated"`
+	Summary string `xml:"summary"`
+	Content string `xml:"content"`
+	Links   []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+}
+
+// extractFeed returns a feed's entries. An Atom entry's key includes its
+// updated time, so an updated incident on a status page feed is a new item.
+func extractFeed(body []byte) ([]Item, error) {
+	var f feed
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(utils.ToUTF8(data, charset)), nil
+	}
+	if err := decoder.Decode(&f); err != nil {
+		return nil, fmt.Errorf("invalid feed: %w", err)
+	}
+
+	var items []Item
+	for _, entry := range append(f.Channel.Items, f.Items...) {
+		item := Item{Title: strings.TrimSpace(entry.Title), Text: htmlText(entry.Description), Link: strings.TrimSpace(entry.Link)}
+		item.Key = firstNonEmpty(strings.TrimSpace(entry.GUID), item.Link, hash(item.Title+"\n"+item.Text))
+		items = append(items, item)
+	}
+	for _, entry := range f.Entries {
+		item := Item{Title: strings.TrimSpace(entry.Title), Text: htmlText(firstNonEmpty(entry.Content, entry.Summary))}
+		for _, link := range entry.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				item.Link = link.Href
+				break
+			}
+		}
+		item.Key = firstNonEmpty(strings.TrimSpace(entry.ID), item.Link, hash(item.Title+"\n"+item.Text))
+		if updated := strings.TrimSpace(entry.Updated); updated != "" {
+			item.Key += "@" + updated
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// extractJSON returns the values selected by a JSONPath expression. When the
+// result is an array, as with wildcards, each element is an item. Objects
+// with a title or name use it as the title, and a url or link as the link.
+func extractJSON(selector string, body []byte) ([]Item, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	result, err := jsonpath.Get(selector, doc)
+	if err != nil {
+		return nil, fmt.Errorf("selector %q: %w", selector, err)
+	}
+	values, ok := result.([]interface{})
+	if !ok {
+		values = []interface{}{result}
+	}
+
+	var items []Item
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		var item Item
+		if s, ok := value.(string); ok {
+			item.Text = s
+		} else {
+			// Map keys are sorted, so an unchanged value gives the same key
+			encoded, err := json.MarshalIndent(value, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			item.Text = string(encoded)
+		}
+		if m, ok := value.(map[string]interface{}); ok {
+			item.Title = firstNonEmpty(stringField(m, "title"), stringField(m, "name"))
+			item.Link = firstNonEmpty(stringField(m, "url"), stringField(m, "link"))
+		}
+		item.Key = hash(item.Text)
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// extractHTML returns the text of each element matching a CSS selector
+func extractHTML(selector string, body []byte) ([]Item, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTML: %w", err)
+	}
+	var items []Item
+	doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			return
+		}
+		item := Item{Key: hash(text), Text: text}
+		if href, ok := s.Attr("href"); ok {
+			item.Link = href
+		}
+		items = append(items, item)
+	})
+	return items, nil
+}
+
+// htmlText converts feed HTML to plain text
+func htmlText(html string) string {
+	html = strings.TrimSpace(html)
+	if !strings.Contains(html, "<") {
+		return html
+	}
+	text, err := utils.HTMLToText(html)
+	if err != nil {
+		return html
+	}
+	return strings.TrimSpace(text)
+}
+
+func stringField(m map[string]interface{}, name string) string {
+	s, _ := m[name].(string)
+	return s
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// hash returns a short, stable key for content
+func hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,260 @@
+// Package poller periodically fetches URLs, such as RSS feeds and status
+// pages, and stores new items and changes as events
+package poller
+
+import (
+	"context"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"example-api/internal/reporting"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultInterval is how often a URL is fetched when no interval is set
+	defaultInterval = 5 * time.Minute
+	// minInterval keeps a misconfigured poller from hammering a site
+	minInterval = 30 * time.Second
+	// maxBodySize caps the response read from a polled URL
+	maxBodySize = 10 << 20
+	// retention is how long an item no longer in the response is remembered,
+	// so an entry dropping off a feed and reappearing isn't stored again
+	retention = 30 * 24 * time.Hour
+)
+
+// Formats of polled responses
+const (
+	FormatFeed = "feed" // RSS 2.0 or Atom; each entry is an item
+	FormatJSON = "json" // Items are the values selected by a JSONPath expression
+	FormatHTML = "html" // Items are the text of the elements selected by a CSS selector
+	FormatText = "text" // The whole response is one item
+)
+
+// Poller fetches one URL on an interval
+type Poller struct {
+	Name     string
+	URL      string
+	Interval time.Duration
+	Format   string
+	Selector string            // JSONPath for json, CSS selector for html
+	Headers  map[string]string // Sent with each request, e.g. Authorization
+	Tags     []string          // Added to every event, after "poller" and the name
+	Source   string            // Event source; defaults to the name
+	Backfill bool              // Store the items found on the first poll instead of only later ones
+
+	etag         string
+	lastModified string
+	lastKeys     []string // Items of the last response, still current while it is unmodified
+}
+
+// Validate checks the poller's configuration and fills in defaults
+func (p *Poller) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("poller has no name")
+	}
+	if !strings.HasPrefix(p.URL, "http://") && !strings.HasPrefix(p.URL, "https://") {
+		return fmt.Errorf("poller %s: url must be http or https", p.Name)
+	}
+	if p.Interval == 0 {
+		p.Interval = defaultInterval
+	}
+	if p.Interval < minInterval {
+		return fmt.Errorf("poller %s: interval must be at least %s", p.Name, minInterval)
+	}
+	switch p.Format {
+	case "":
+		p.Format = FormatFeed
+	case FormatFeed, FormatText:
+	case FormatJSON, FormatHTML:
+		if p.Selector == "" {
+			return fmt.Errorf("poller %s: %s format needs a selector", p.Name, p.Format)
+		}
+	default:
+		return fmt.Errorf("poller %s: unknown format %q, want feed, json, html or text", p.Name, p.Format)
+	}
+	if p.Source == "" {
+		p.Source = p.Name
+	}
+	return nil
+}
+
+// Runner polls a set of URLs and stores what changed
+type Runner struct {
+	db      *database.Database
+	client  *http.Client
+	pollers []*Poller
+}
+
+// NewRunner creates a runner for pollers, which must be valid
+func NewRunner(db *database.Database, pollers []*Poller) *Runner {
+	return &Runner{db: db, client: &http.Client{Timeout: 30 * time.Second}, pollers: pollers}
+}
+
+// Run polls every URL immediately and then on its interval until ctx is done
+func (r *Runner) Run(ctx context.Context) {
+	done := make(chan struct{})
+	for _, p := range r.pollers {
+		go func(p *Poller) {
+			defer func() { done <- struct{}{} }()
+			r.loop(ctx, p)
+		}(p)
+	}
+	for range r.pollers {
+		<-done
+	}
+}
+
+func (r *Runner) loop(ctx context.Context, p *Poller) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Poll(ctx, p); err != nil {
+			log.Printf("Poller %s: %v", p.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the poller's URL once and stores an event for each new item.
+// Feed entries are new the first time they are seen. For the other formats an
+// item is new when it wasn't in the previous response, so a status page going
+// back to an earlier state is reported again.
+func (r *Runner) Poll(ctx context.Context, p *Poller) error {
+	resp, err := r.fetch(ctx, p)
+	if err != nil {
+		return err
+	}
+	seenAt := time.Now().UTC().Truncate(time.Microsecond)
+	if resp == nil {
+		return r.db.TouchPollerItems(p.Name, p.lastKeys, seenAt)
+	}
+
+	items, err := extract(p.Format, p.Selector, resp.body)
+	if err != nil {
+		return err
+	}
+	seen, err := r.db.PollerItems(p.Name)
+	if err != nil {
+		return err
+	}
+	var lastPoll time.Time
+	for _, lastSeen := range seen {
+		if lastSeen.After(lastPoll) {
+			lastPoll = lastSeen
+		}
+	}
+
+	var keys []string
+	var events []*models.EventRequest
+	for _, item := range items {
+		if containsKey(keys, item.Key) {
+			continue
+		}
+		keys = append(keys, item.Key)
+		lastSeen, ok := seen[item.Key]
+		isNew := !ok || (p.Format != FormatFeed && lastSeen.Before(lastPoll))
+		if isNew && (len(seen) > 0 || p.Backfill) {
+			events = append(events, p.event(item))
+		}
+	}
+
+	if len(events) > 0 {
+		stored, err := r.db.StoreEvents(events)
+		if err != nil {
+			reporting.CaptureError(ctx, err, map[string]string{"poller": p.Name})
+			// Leave the items unrecorded so they are stored on the next poll
+			return err
+		}
+		log.Printf("Poller %s: stored %d new item(s)", p.Name, stored)
+	}
+	if err := r.db.TouchPollerItems(p.Name, keys, seenAt); err != nil {
+		return err
+	}
+	// Only now can the server be asked for changes since this response
+	p.etag, p.lastModified, p.lastKeys = resp.etag, resp.lastModified, keys
+	if _, err := r.db.PrunePollerItems(p.Name, seenAt.Add(-retention)); err != nil {
+		log.Printf("Poller %s: %v", p.Name, err)
+	}
+	return nil
+}
+
+// response is a fetched body and its cache validators
+type response struct {
+	body         []byte
+	etag         string
+	lastModified string
+}
+
+// fetch gets the URL. It returns nil when the server answers 304 Not Modified
+// to a conditional request.
+func (r *Runner) fetch(ctx context.Context, p *Poller) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "event-db-poller")
+	for name, value := range p.Headers {
+		req.Header.Set(name, value)
+	}
+	if p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+	if p.lastModified != "" {
+		req.Header.Set("If-Modified-Since", p.lastModified)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", p.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s returned %s", p.URL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", p.URL, err)
+	}
+	return &response{body: body, etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}, nil
+}
+
+// event builds the event for a new item
+func (p *Poller) event(item Item) *models.EventRequest {
+	tags := []string{"poller", strings.ToLower(p.Name)}
+	for _, tag := range p.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !containsKey(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	var parts []string
+	for _, part := range []string{item.Title, item.Text, item.Link} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return &models.EventRequest{Tags: tags, Data: strings.Join(parts, "\n\n"), Source: p.Source}
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
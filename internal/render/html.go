@@ -0,0 +1,30 @@
+package render
+
+import (
+	"html/template"
+	"net/url"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// EmailHTML sanitizes an HTML email body for display. Scripts, styles, forms
+// and event handlers are always removed. Unless allowRemoteImages is set,
+// images loaded over the network are blocked so opening an event cannot
+// trigger tracking pixels; the number of blocked images is returned.
+func EmailHTML(body string, allowRemoteImages bool) (template.HTML, int) {
+	blocked := 0
+
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("bgcolor", "align", "valign", "width", "height").OnElements("table", "tr", "td", "th", "img")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	if !allowRemoteImages {
+		p.RewriteSrc(func(u *url.URL) {
+			if u.Scheme == "http" || u.Scheme == "https" || (u.Scheme == "" && u.Host != "") {
+				blocked++
+				*u = url.URL{}
+			}
+		})
+	}
+
+	return template.HTML(p.Sanitize(body)), blocked
+}
@@ -0,0 +1,116 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+)
+
+// LooksLikeJSON reports whether data is a JSON object or array
+func LooksLikeJSON(data string) bool {
+	trimmed := strings.TrimSpace(data)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+	return json.Valid([]byte(trimmed))
+}
+
+// PrettyJSON returns data indented for display
+func PrettyJSON(data string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(strings.TrimSpace(data)), "", "  "); err != nil {
+		return "", fmt.Errorf("failed to indent json: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// JSONTree renders data as a collapsible, syntax-highlighted HTML tree.
+// Objects and arrays become <details> elements; keys keep their original order.
+func JSONTree(data string) (template.HTML, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+
+	var buf bytes.Buffer
+	buf.WriteString(`<div class="json-tree">`)
+	if err := writeJSONValue(&buf, dec, 0); err != nil {
+		return "", fmt.Errorf("failed to render json: %w", err)
+	}
+	buf.WriteString(`</div>`)
+	return template.HTML(buf.String()), nil
+}
+
+// writeJSONValue writes the next value from dec
+func writeJSONValue(buf *bytes.Buffer, dec *json.Decoder, depth int) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch v := token.(type) {
+	case json.Delim:
+		return writeJSONContainer(buf, dec, v, depth)
+	case string:
+		fmt.Fprintf(buf, `<span class="json-string">"%s"</span>`, html.EscapeString(v))
+	case json.Number:
+		fmt.Fprintf(buf, `<span class="json-number">%s</span>`, html.EscapeString(v.String()))
+	case bool:
+		fmt.Fprintf(buf, `<span class="json-bool">%t</span>`, v)
+	case nil:
+		buf.WriteString(`<span class="json-null">null</span>`)
+	}
+	return nil
+}
+
+// writeJSONContainer writes the members of an object or array whose opening
+// delimiter has already been read
+func writeJSONContainer(buf *bytes.Buffer, dec *json.Decoder, open json.Delim, depth int) error {
+	closing := "]"
+	if open == '{' {
+		closing = "}"
+	}
+
+	var body bytes.Buffer
+	count := 0
+	for dec.More() {
+		body.WriteString(`<div class="json-member">`)
+		if open == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&body, `<span class="json-key">"%s"</span>: `, html.EscapeString(fmt.Sprint(key)))
+		}
+		if err := writeJSONValue(&body, dec, depth+1); err != nil {
+			return err
+		}
+		body.WriteString(`</div>`)
+		count++
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+
+	if count == 0 {
+		fmt.Fprintf(buf, "%s%s", open, closing)
+		return nil
+	}
+
+	unit := "item"
+	if open == '{' {
+		unit = "key"
+	}
+	if count != 1 {
+		unit += "s"
+	}
+	openAttr := ""
+	if depth < 2 {
+		openAttr = " open"
+	}
+	fmt.Fprintf(buf, `<details%s><summary>%s <span class="json-count">%d %s</span></summary>`, openAttr, open, count, unit)
+	buf.Write(body.Bytes())
+	fmt.Fprintf(buf, `</details>%s`, closing)
+	return nil
+}
@@ -0,0 +1,55 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	// policy allows the formatting Markdown produces but no scripts, styles or event handlers
+	policy = bluemonday.UGCPolicy()
+
+	// markdownHints match constructs that rarely appear in plain text
+	markdownHints = []*regexp.Regexp{
+		regexp.MustCompile(`(?m)^#{1,6} \S`),                  // ATX headings
+		regexp.MustCompile("(?m)^```"),                        // fenced code
+		regexp.MustCompile(`(?m)^\s*[-*+] \S.*\n\s*[-*+] \S`), // consecutive list items
+		regexp.MustCompile(`(?m)^\s*\d+\. \S.*\n\s*\d+\. \S`), // consecutive numbered items
+		regexp.MustCompile(`\[[^\]\n]+\]\([^)\s]+\)`),         // inline links
+		regexp.MustCompile(`(?m)^\|.*\|\s*\n\|[\s:|-]+\|`),    // tables
+		regexp.MustCompile(`\*\*[^*\n]+\*\*`),                 // bold
+	}
+)
+
+// LooksLikeMarkdown reports whether data appears to be Markdown. It requires
+// two distinct Markdown constructs so ordinary text with a stray "#" or "*"
+// is left alone.
+func LooksLikeMarkdown(data string) bool {
+	hits := 0
+	for _, hint := range markdownHints {
+		if hint.MatchString(data) {
+			hits++
+			if hits >= 2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Markdown renders Markdown to sanitized HTML
+func Markdown(data string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(data), &buf); err != nil {
+		return "", fmt.Errorf("failed to render markdown: %w", err)
+	}
+	return template.HTML(policy.SanitizeBytes(buf.Bytes())), nil
+}
@@ -0,0 +1,106 @@
+// Package reporting sends panics and unexpected errors to Sentry when a DSN
+// is configured. Without one every function is a no-op.
+package reporting
+
+import (
+	"context"
+	"errors"
+	"example-api/internal/logging"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Setup enables reporting to the Sentry project at dsn, sending sampleRate
+// (0 to 1) of the errors. An empty dsn leaves reporting disabled.
+func Setup(service, dsn string, sampleRate float64, environment string) error {
+	if dsn == "" {
+		return nil
+	}
+	if sampleRate < 0 || sampleRate > 1 {
+		return fmt.Errorf("sample rate %v is not between 0 and 1", sampleRate)
+	}
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:         dsn,
+		SampleRate:  sampleRate,
+		Environment: environment,
+		ServerName:  service,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up Sentry: %w", err)
+	}
+	sentry.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("service", service)
+	})
+	slog.Info("Reporting errors to Sentry", "environment", environment, "sample_rate", sampleRate)
+	return nil
+}
+
+// Enabled reports whether errors are being sent anywhere
+func Enabled() bool {
+	return sentry.CurrentHub().Client() != nil
+}
+
+// Flush waits up to timeout for reports still being sent. Call it before
+// the process exits.
+func Flush(timeout time.Duration) {
+	if Enabled() {
+		sentry.Flush(timeout)
+	}
+}
+
+// CaptureError reports an unexpected error with tags describing where it
+// happened. ctx supplies the request ID when the error happened while
+// handling a request.
+func CaptureError(ctx context.Context, err error, tags map[string]string) {
+	if !Enabled() || err == nil {
+		return
+	}
+	hub := hubFor(ctx, tags)
+	hub.CaptureException(err)
+}
+
+// CapturePanic reports a value recovered from a panic while handling r
+func CapturePanic(r *http.Request, recovered interface{}) {
+	if !Enabled() {
+		return
+	}
+	hub := hubFor(r.Context(), nil)
+	hub.Scope().SetRequest(r)
+	hub.RecoverWithContext(r.Context(), recovered)
+}
+
+// hubFor returns a hub whose scope carries the tags and the request ID
+func hubFor(ctx context.Context, tags map[string]string) *sentry.Hub {
+	hub := sentry.CurrentHub().Clone()
+	if id := logging.RequestID(ctx); id != "" {
+		hub.Scope().SetTag("request_id", id)
+	}
+	hub.Scope().SetTags(tags)
+	return hub
+}
+
+// Middleware reports panics in next and answers them with a 500
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// The server aborts the response without logging this one
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+			slog.Error("Panic while handling request", "path", r.URL.Path, "panic", recovered,
+				"request_id", logging.RequestID(r.Context()), "stack", string(debug.Stack()))
+			CapturePanic(r, recovered)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,184 @@
+// Package s3 uploads objects to Amazon S3 and S3-compatible storage such as
+// MinIO, signing requests with AWS Signature Version 4
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// uploadTimeout bounds one upload, leaving room for large archives on slow
+// links
+const uploadTimeout = 10 * time.Minute
+
+// Client uploads objects to one bucket
+type Client struct {
+	Endpoint     string // Such as https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // For temporary credentials; optional
+	PathStyle    bool   // Address the bucket in the path rather than the host name, as MinIO needs
+
+	client *http.Client
+}
+
+// Validate checks that the client can address its bucket and fills in the
+// AWS endpoint of its region when no endpoint is set
+func (c *Client) Validate() error {
+	if c.Bucket == "" {
+		return fmt.Errorf("a bucket is required")
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = "https://s3." + c.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("endpoint %q must be an http or https URL", c.Endpoint)
+	}
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return fmt.Errorf("an access key and a secret key are required")
+	}
+	return nil
+}
+
+// Put uploads size bytes of body under key. The body's SHA-256, given as
+// hex, is part of the signature, so S3 rejects a corrupted upload.
+func (c *Client) Put(ctx context.Context, key string, body io.Reader, size int64, sha256Hex, contentType string) error {
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+
+	target := c.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	c.sign(req, sha256Hex, time.Now())
+
+	if c.client == nil {
+		c.client = &http.Client{}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("uploading %s returned status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// URL returns the s3:// address of an object, for logs
+func (c *Client) URL(key string) string {
+	return "s3://" + c.Bucket + "/" + key
+}
+
+func (c *Client) objectURL(key string) *url.URL {
+	endpoint, _ := url.Parse(c.Endpoint)
+	target := *endpoint
+	if c.PathStyle {
+		target.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + c.Bucket + "/" + key
+	} else {
+		target.Host = c.Bucket + "." + endpoint.Host
+		target.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + key
+	}
+	target.RawPath = escapePath(target.Path)
+	return &target
+}
+
+// sign adds the headers of an AWS Signature Version 4 for req, made at now
+func (c *Client) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if name = strings.ToLower(name); strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(values[0])
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + c.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKey, scope, signedHeaders, signature))
+}
+
+// escapePath percent-encodes every byte of a path but the unreserved
+// characters and slashes, as the signature's canonical request requires
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		ch := path[i]
+		if ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' ||
+			ch == '-' || ch == '.' || ch == '_' || ch == '~' || ch == '/' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,79 @@
+package signing
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	signer := New([]byte("secret"))
+	const path = "/attachments/42/report.pdf"
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		path  string
+		query func() url.Values
+		want  error
+	}{
+		{name: "round trip", path: path, query: func() url.Values { return signer.Query(path, future) }},
+		{name: "never expires", path: path, query: func() url.Values { return signer.Query(path, time.Time{}) }},
+		{name: "other path", path: "/attachments/43/report.pdf", query: func() url.Values { return signer.Query(path, future) }, want: ErrInvalidSignature},
+		{
+			name: "expiry extended",
+			path: path,
+			query: func() url.Values {
+				q := signer.Query(path, future)
+				q.Set(ExpiresParam, strconv.FormatInt(future.Add(24*time.Hour).Unix(), 10))
+				return q
+			},
+			want: ErrInvalidSignature,
+		},
+		{
+			name: "expiry removed",
+			path: path,
+			query: func() url.Values {
+				q := signer.Query(path, future)
+				q.Del(ExpiresParam)
+				return q
+			},
+			want: ErrInvalidSignature,
+		},
+		{name: "expired", path: path, query: func() url.Values { return signer.Query(path, past) }, want: ErrExpired},
+		{name: "other key", path: path, query: func() url.Values { return New([]byte("other")).Query(path, future) }, want: ErrInvalidSignature},
+		{name: "missing signature", path: path, query: func() url.Values { return url.Values{ExpiresParam: {strconv.FormatInt(future.Unix(), 10)}} }, want: ErrInvalidSignature},
+		{name: "signature not hex", path: path, query: func() url.Values { return url.Values{SignatureParam: {"zz"}} }, want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := signer.Verify(tt.path, tt.query()); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignAndExpires(t *testing.T) {
+	signer, err := NewRandom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	signed, err := url.Parse(signer.Sign("/exports/7", expires))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Verify(signed.Path, signed.Query()); err != nil {
+		t.Fatalf("Verify(%s) = %v", signed, err)
+	}
+	if got := Expires(signed.Query()); !got.Equal(expires) {
+		t.Errorf("Expires() = %v, want %v", got, expires)
+	}
+	if got := Expires(url.Values{}); !got.IsZero() {
+		t.Errorf("Expires() of an unsigned URL = %v, want the zero time", got)
+	}
+}
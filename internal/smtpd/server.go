@@ -0,0 +1,533 @@
+package smtpd
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// commandTimeout is how long a client may take to send each command
+	commandTimeout = 5 * time.Minute
+	// dataTimeout is how long a client may take to send a message
+	dataTimeout = 10 * time.Minute
+	// maxRecipients caps the recipients of one message
+	maxRecipients = 100
+	// shutdownPollInterval is how often Shutdown checks for finished sessions
+	shutdownPollInterval = 100 * time.Millisecond
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Shutdown
+var ErrServerClosed = errors.New("smtpd: server closed")
+
+// Envelope is a message received by the server
+type Envelope struct {
+	RemoteAddr      string
+	Helo            string
+	From            string
+	To              []string
+	AuthenticatedAs string
+	Data            []byte
+}
+
+// Handler is called with each received message. Returning an error makes the
+// server answer with a temporary failure so the sender retries, unless it is
+// an *Error.
+type Handler func(env *Envelope) error
+
+// Error is a Handler error answered with its own reply code, such as a 550
+// permanent rejection
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// Server accepts mail over SMTP (RFC 5321) and hands each message to a Handler
+type Server struct {
+	Hostname       string      // Name given in the greeting and EHLO reply
+	MaxMessageSize int64       // Largest accepted message in bytes
+	AllowedDomains []string    // Recipient domains to accept; empty accepts any
+	TLSConfig      *tls.Config // Enables STARTTLS when set
+	AuthPassword   string      // Requires AUTH PLAIN with this password when set
+	Handler        Handler
+
+	mu        sync.Mutex
+	closing   bool
+	listeners map[net.Listener]struct{}
+	sessions  map[*session]bool // Whether each session is running a command
+}
+
+// ListenAndServe listens on addr and serves connections until the listener fails
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l, serving each in its own goroutine, until
+// the listener fails or Shutdown is called
+func (s *Server) Serve(l net.Listener) error {
+	defer l.Close()
+	if !s.trackListener(l, true) {
+		return ErrServerClosed
+	}
+	defer s.trackListener(l, false)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(time.Second)
+				continue
+			}
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// session is the state of one client connection
+type session struct {
+	server *Server
+	conn   net.Conn
+	text   *textproto.Conn
+
+	helo      string
+	tls       bool
+	authUser  string
+	from      string
+	to        []string
+	inMessage bool
+}
+
+// Shutdown stops accepting connections and closes idle ones, then waits for
+// the commands in progress, such as a message being handled, to finish. Once
+// ctx is done the remaining connections are closed and ctx's error returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		if s.closeIdle() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			for sess := range s.sessions {
+				sess.conn.Close()
+			}
+			s.mu.Unlock()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeIdle tells sessions waiting for a command that the service is closing
+// and disconnects them, reporting whether no sessions are left
+func (s *Server) closeIdle() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sess, busy := range s.sessions {
+		if !busy {
+			sess.closingReply()
+			sess.conn.Close()
+			delete(s.sessions, sess)
+		}
+	}
+	return len(s.sessions) == 0
+}
+
+func (s *Server) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// trackListener adds or removes a listener, reporting false when adding one
+// after Shutdown
+func (s *Server) trackListener(l net.Listener, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.listeners, l)
+		return true
+	}
+	if s.closing {
+		return false
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	return true
+}
+
+// setBusy records whether a session is running a command, reporting false
+// once the server is shutting down
+func (s *Server) setBusy(sess *session, busy bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	if s.sessions == nil {
+		s.sessions = make(map[*session]bool)
+	}
+	s.sessions[sess] = busy
+	return true
+}
+
+func (s *Server) removeSession(sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sess)
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	sess := &session{server: s, conn: conn, text: textproto.NewConn(conn)}
+	_, sess.tls = conn.(*tls.Conn)
+	defer s.removeSession(sess)
+
+	if !s.setBusy(sess, true) {
+		sess.closingReply()
+		return
+	}
+	sess.reply(220, "%s ESMTP Event Database ready", s.Hostname)
+	for {
+		if !s.setBusy(sess, false) {
+			sess.closingReply()
+			return
+		}
+		conn.SetDeadline(time.Now().Add(commandTimeout))
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			if err != io.EOF && !s.shuttingDown() {
+				log.Printf("SMTP %s: read failed: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if !s.setBusy(sess, true) {
+			// The client retries the command later
+			sess.closingReply()
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		if !sess.handle(strings.ToUpper(verb), strings.TrimSpace(arg)) {
+			return
+		}
+	}
+}
+
+// handle runs one command, reporting false when the connection should close
+func (sess *session) handle(verb, arg string) bool {
+	switch verb {
+	case "HELO", "EHLO":
+		if arg == "" {
+			sess.reply(501, "Domain name required")
+			return true
+		}
+		sess.helo = arg
+		sess.reset()
+		if verb == "HELO" {
+			sess.reply(250, "%s", sess.server.Hostname)
+			return true
+		}
+		lines := []string{sess.server.Hostname, "PIPELINING", "8BITMIME"}
+		if sess.server.MaxMessageSize > 0 {
+			lines = append(lines, fmt.Sprintf("SIZE %d", sess.server.MaxMessageSize))
+		}
+		if sess.server.TLSConfig != nil && !sess.tls {
+			lines = append(lines, "STARTTLS")
+		}
+		if sess.server.AuthPassword != "" && sess.authAllowed() {
+			lines = append(lines, "AUTH PLAIN")
+		}
+		sess.replyLines(250, lines)
+
+	case "STARTTLS":
+		if sess.server.TLSConfig == nil || sess.tls {
+			sess.reply(502, "STARTTLS not available")
+			return true
+		}
+		sess.reply(220, "Ready to start TLS")
+		tlsConn := tls.Server(sess.conn, sess.server.TLSConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			log.Printf("SMTP %s: TLS handshake failed: %v", sess.conn.RemoteAddr(), err)
+			return false
+		}
+		// Start over as if the connection had just been opened (RFC 3207)
+		sess.conn = tlsConn
+		sess.text = textproto.NewConn(tlsConn)
+		sess.tls = true
+		sess.helo = ""
+		sess.authUser = ""
+		sess.reset()
+
+	case "AUTH":
+		sess.auth(arg)
+
+	case "MAIL":
+		if sess.helo == "" {
+			sess.reply(503, "Send HELO/EHLO first")
+			return true
+		}
+		if sess.server.AuthPassword != "" && sess.authUser == "" {
+			sess.reply(530, "Authentication required")
+			return true
+		}
+		if sess.inMessage {
+			sess.reply(503, "Nested MAIL command")
+			return true
+		}
+		addr, params, ok := parsePath(arg, "FROM:")
+		if !ok {
+			sess.reply(501, "Syntax: MAIL FROM:<address>")
+			return true
+		}
+		if size, err := strconv.ParseInt(params["SIZE"], 10, 64); err == nil && sess.server.MaxMessageSize > 0 && size > sess.server.MaxMessageSize {
+			sess.reply(552, "Message exceeds maximum size")
+			return true
+		}
+		sess.from = addr
+		sess.inMessage = true
+		sess.reply(250, "OK")
+
+	case "RCPT":
+		if !sess.inMessage {
+			sess.reply(503, "Send MAIL first")
+			return true
+		}
+		addr, _, ok := parsePath(arg, "TO:")
+		if !ok || addr == "" {
+			sess.reply(501, "Syntax: RCPT TO:<address>")
+			return true
+		}
+		if len(sess.to) >= maxRecipients {
+			sess.reply(452, "Too many recipients")
+			return true
+		}
+		if !sess.server.acceptsRecipient(addr) {
+			sess.reply(550, "Recipient domain not accepted here")
+			return true
+		}
+		sess.to = append(sess.to, addr)
+		sess.reply(250, "OK")
+
+	case "DATA":
+		if !sess.inMessage || len(sess.to) == 0 {
+			sess.reply(503, "Send MAIL and RCPT first")
+			return true
+		}
+		sess.data()
+
+	case "RSET":
+		sess.reset()
+		sess.reply(250, "OK")
+
+	case "NOOP":
+		sess.reply(250, "OK")
+
+	case "VRFY":
+		sess.reply(252, "Cannot verify user")
+
+	case "QUIT":
+		sess.reply(221, "Bye")
+		return false
+
+	default:
+		sess.reply(502, "Command not implemented")
+	}
+	return true
+}
+
+// auth handles AUTH PLAIN (RFC 4616), with the initial response either on the
+// command line or sent after a 334 prompt
+func (sess *session) auth(arg string) {
+	if sess.server.AuthPassword == "" || !sess.authAllowed() {
+		sess.reply(502, "AUTH not available")
+		return
+	}
+	if sess.authUser != "" {
+		sess.reply(503, "Already authenticated")
+		return
+	}
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") {
+		sess.reply(504, "Unsupported authentication mechanism")
+		return
+	}
+	if initial == "" {
+		sess.reply(334, "")
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return
+		}
+		initial = line
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(initial))
+	parts := strings.Split(string(decoded), "\x00")
+	if err != nil || len(parts) != 3 {
+		sess.reply(501, "Malformed AUTH PLAIN response")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(parts[2]), []byte(sess.server.AuthPassword)) != 1 {
+		log.Printf("SMTP %s: authentication failed for %q", sess.conn.RemoteAddr(), parts[1])
+		sess.reply(535, "Authentication credentials invalid")
+		return
+	}
+	sess.authUser = parts[1]
+	sess.reply(235, "Authentication successful")
+}
+
+// authAllowed reports whether credentials may be sent: only over TLS, unless
+// TLS isn't configured at all
+func (sess *session) authAllowed() bool {
+	return sess.tls || sess.server.TLSConfig == nil
+}
+
+// data reads the message and passes it to the handler
+func (sess *session) data() {
+	sess.reply(354, "End data with <CR><LF>.<CR><LF>")
+	sess.conn.SetDeadline(time.Now().Add(dataTimeout))
+
+	dot := sess.text.DotReader()
+	var r io.Reader = dot
+	if max := sess.server.MaxMessageSize; max > 0 {
+		r = io.LimitReader(dot, max+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		log.Printf("SMTP %s: failed to read message: %v", sess.conn.RemoteAddr(), err)
+		return
+	}
+	if max := sess.server.MaxMessageSize; max > 0 && int64(len(data)) > max {
+		// Read and drop the rest so the connection stays usable
+		io.Copy(io.Discard, dot)
+		sess.reset()
+		sess.reply(552, "Message exceeds maximum size")
+		return
+	}
+
+	env := &Envelope{
+		RemoteAddr:      sess.conn.RemoteAddr().String(),
+		Helo:            sess.helo,
+		From:            sess.from,
+		To:              sess.to,
+		AuthenticatedAs: sess.authUser,
+		Data:            data,
+	}
+	sess.reset()
+
+	if err := sess.server.Handler(env); err != nil {
+		var smtpErr *Error
+		if errors.As(err, &smtpErr) {
+			sess.reply(smtpErr.Code, "%s", smtpErr.Message)
+			return
+		}
+		log.Printf("SMTP %s: failed to handle message: %v", env.RemoteAddr, err)
+		sess.reply(451, "Requested action aborted: local error in processing")
+		return
+	}
+	sess.reply(250, "OK: message accepted")
+}
+
+// closingReply tells the client the server is shutting down
+func (sess *session) closingReply() {
+	sess.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	sess.reply(421, "%s Service closing transmission channel", sess.server.Hostname)
+}
+
+// reset clears the current transaction
+func (sess *session) reset() {
+	sess.from = ""
+	sess.to = nil
+	sess.inMessage = false
+}
+
+func (sess *session) reply(code int, format string, args ...interface{}) {
+	sess.text.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+}
+
+func (sess *session) replyLines(code int, lines []string) {
+	for i, line := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		sess.text.PrintfLine("%d%s%s", code, sep, line)
+	}
+}
+
+// acceptsRecipient reports whether mail for addr is accepted
+func (s *Server) acceptsRecipient(addr string) bool {
+	if len(s.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return false
+	}
+	domain := addr[at+1:]
+	for _, allowed := range s.AllowedDomains {
+		if strings.EqualFold(domain, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePath parses "FROM:<address> PARAM=value ..." and its parameters. The
+// null reverse-path "<>" gives an empty address.
+func parsePath(arg, prefix string) (string, map[string]string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", nil, false
+	}
+	end := strings.IndexByte(rest, '>')
+	if end < 0 {
+		return "", nil, false
+	}
+	addr := rest[1:end]
+
+	params := make(map[string]string)
+	for _, field := range strings.Fields(rest[end+1:]) {
+		key, value, _ := strings.Cut(field, "=")
+		params[strings.ToUpper(key)] = value
+	}
+	return addr, params, true
+}
@@ -0,0 +1,240 @@
+package sso
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+)
+
+// requestCookie holds the ID of the outstanding AuthnRequest between redirect and ACS
+const requestCookie = "saml_request"
+
+// SAMLConfig configures the SAML service provider
+type SAMLConfig struct {
+	RootURL           string   // Public base URL of the web interface, e.g. https://events.example.com
+	EntityID          string   // Defaults to the metadata URL
+	CertFile          string   // PEM certificate used to sign requests
+	KeyFile           string   // PEM RSA private key matching CertFile
+	IDPMetadataURL    string   // Fetched at startup if set
+	IDPMetadataFile   string   // Read from disk if IDPMetadataURL is empty
+	UsernameAttribute string   // Attribute used as username, defaults to the NameID
+	RoleAttribute     string   // Attribute whose values are matched against AdminValues
+	AdminValues       []string // Attribute values that grant the admin role
+}
+
+// Identity is the user identity extracted from a validated SAML assertion
+type Identity struct {
+	Username string
+	Email    string
+	Role     string
+}
+
+// SAMLProvider is a SAML 2.0 service provider
+type SAMLProvider struct {
+	sp     saml.ServiceProvider
+	config SAMLConfig
+	secure bool
+}
+
+// NewSAMLProvider loads the SP key pair and IdP metadata
+func NewSAMLProvider(cfg SAMLConfig) (*SAMLProvider, error) {
+	rootURL, err := url.Parse(strings.TrimRight(cfg.RootURL, "/"))
+	if err != nil || rootURL.Host == "" {
+		return nil, fmt.Errorf("invalid SAML root URL %q", cfg.RootURL)
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SAML key pair: %w", err)
+	}
+	key, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("SAML private key must be an RSA key")
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SAML certificate: %w", err)
+	}
+
+	idpMetadata, err := loadIDPMetadata(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	metadataURL := rootURL.ResolveReference(&url.URL{Path: "/saml/metadata"})
+	acsURL := rootURL.ResolveReference(&url.URL{Path: "/saml/acs"})
+
+	p := &SAMLProvider{
+		config: cfg,
+		secure: rootURL.Scheme == "https",
+		sp: saml.ServiceProvider{
+			EntityID:          cfg.EntityID,
+			Key:               key,
+			Certificate:       cert,
+			MetadataURL:       *metadataURL,
+			AcsURL:            *acsURL,
+			IDPMetadata:       idpMetadata,
+			AllowIDPInitiated: true,
+		},
+	}
+	if p.config.RoleAttribute == "" {
+		p.config.RoleAttribute = "role"
+	}
+
+	return p, nil
+}
+
+func loadIDPMetadata(cfg SAMLConfig) (*saml.EntityDescriptor, error) {
+	if cfg.IDPMetadataURL != "" {
+		metadataURL, err := url.Parse(cfg.IDPMetadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IdP metadata URL: %w", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		metadata, err := samlsp.FetchMetadata(ctx, http.DefaultClient, *metadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch IdP metadata: %w", err)
+		}
+		return metadata, nil
+	}
+
+	if cfg.IDPMetadataFile == "" {
+		return nil, errors.New("either an IdP metadata URL or file is required")
+	}
+	data, err := os.ReadFile(cfg.IDPMetadataFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IdP metadata: %w", err)
+	}
+	metadata, err := samlsp.ParseMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse IdP metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// ServeMetadata writes the SP metadata document
+func (p *SAMLProvider) ServeMetadata(w http.ResponseWriter, r *http.Request) {
+	buf, err := xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to generate metadata", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write(buf)
+}
+
+// StartLogin redirects the browser to the IdP with a new AuthnRequest
+func (p *SAMLProvider) StartLogin(w http.ResponseWriter, r *http.Request) {
+	req, err := p.sp.MakeAuthenticationRequest(
+		p.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding),
+		saml.HTTPRedirectBinding,
+		saml.HTTPPostBinding,
+	)
+	if err != nil {
+		http.Error(w, "Failed to create SAML request", http.StatusInternalServerError)
+		return
+	}
+
+	redirectURL, err := req.Redirect("", &p.sp)
+	if err != nil {
+		http.Error(w, "Failed to create SAML request", http.StatusInternalServerError)
+		return
+	}
+
+	// The ACS POST is cross-site, so the cookie only survives it with SameSite=None over HTTPS
+	sameSite := http.SameSiteLaxMode
+	if p.secure {
+		sameSite = http.SameSiteNoneMode
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     requestCookie,
+		Value:    req.ID,
+		Path:     "/saml/acs",
+		HttpOnly: true,
+		Secure:   p.secure,
+		SameSite: sameSite,
+		MaxAge:   300,
+	})
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+// ParseACS validates the IdP response posted to the ACS endpoint and maps it to an identity
+func (p *SAMLProvider) ParseACS(w http.ResponseWriter, r *http.Request) (*Identity, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("failed to parse form: %w", err)
+	}
+
+	var requestIDs []string
+	if cookie, err := r.Cookie(requestCookie); err == nil {
+		requestIDs = append(requestIDs, cookie.Value)
+		http.SetCookie(w, &http.Cookie{Name: requestCookie, Path: "/saml/acs", MaxAge: -1})
+	}
+
+	assertion, err := p.sp.ParseResponse(r, requestIDs)
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			return nil, fmt.Errorf("invalid SAML response: %w", invalid.PrivateErr)
+		}
+		return nil, fmt.Errorf("invalid SAML response: %w", err)
+	}
+
+	return p.identityFromAssertion(assertion)
+}
+
+// identityFromAssertion applies the attribute→role mapping
+func (p *SAMLProvider) identityFromAssertion(assertion *saml.Assertion) (*Identity, error) {
+	attributes := make(map[string][]string)
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			for _, value := range attr.Values {
+				attributes[attr.Name] = append(attributes[attr.Name], value.Value)
+				if attr.FriendlyName != "" {
+					attributes[attr.FriendlyName] = append(attributes[attr.FriendlyName], value.Value)
+				}
+			}
+		}
+	}
+
+	identity := &Identity{Role: "user"}
+	if p.config.UsernameAttribute != "" {
+		if values := attributes[p.config.UsernameAttribute]; len(values) > 0 {
+			identity.Username = values[0]
+		}
+	} else if assertion.Subject != nil && assertion.Subject.NameID != nil {
+		identity.Username = assertion.Subject.NameID.Value
+	}
+	if identity.Username == "" {
+		return nil, errors.New("SAML assertion does not contain a username")
+	}
+
+	for _, name := range []string{"email", "mail", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress"} {
+		if values := attributes[name]; len(values) > 0 {
+			identity.Email = values[0]
+			break
+		}
+	}
+
+	for _, value := range attributes[p.config.RoleAttribute] {
+		for _, adminValue := range p.config.AdminValues {
+			if strings.EqualFold(value, adminValue) {
+				identity.Role = "admin"
+			}
+		}
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,102 @@
+// Package stream turns messages consumed from message brokers into events
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"example-api/internal/reporting"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryDelay caps the wait between attempts to store a batch
+const maxRetryDelay = 30 * time.Second
+
+// ErrEmptyEvent is returned for payloads with neither data nor tags
+var ErrEmptyEvent = errors.New("event has no data or tags")
+
+// DecodeEvent decodes a JSON event payload, the same object POST /api/events
+// returns: {"tags": [...], "data": "...", "source": "..."}. Events without a
+// source get defaultSource; tags are lowercased and extraTags are added.
+func DecodeEvent(payload []byte, defaultSource string, extraTags ...string) (*models.EventRequest, error) {
+	var event models.EventRequest
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid event payload: %w", err)
+	}
+	if event.Data == "" && len(event.Tags) == 0 {
+		return nil, ErrEmptyEvent
+	}
+
+	var tags []string
+	for _, tag := range append(event.Tags, extraTags...) {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		tags = []string{"untagged"}
+	}
+	event.Tags = tags
+
+	if event.Source == "" {
+		event.Source = defaultSource
+	}
+	return &event, nil
+}
+
+// SubjectTags returns the tokens of a NATS subject matched by the wildcards
+// of the subscription pattern: "events.deploy.api" received on "events.>"
+// gives [deploy api]. Literal tokens of the pattern aren't tags.
+func SubjectTags(pattern, subject string) []string {
+	patternTokens := strings.Split(pattern, ".")
+	var tags []string
+	for i, token := range strings.Split(subject, ".") {
+		if i < len(patternTokens) && patternTokens[i] != "*" && patternTokens[i] != ">" {
+			continue
+		}
+		tags = append(tags, token)
+	}
+	return tags
+}
+
+// StoreBatch stores events in one transaction, retrying with backoff until
+// they are stored. It returns ctx's error if ctx is cancelled first, so the
+// caller can leave the messages unacknowledged for redelivery.
+func StoreBatch(ctx context.Context, db *database.Database, events []*models.EventRequest) (int, error) {
+	delay := time.Second
+	for {
+		stored, err := db.StoreEvents(events)
+		if err == nil {
+			return stored, nil
+		}
+		log.Printf("Failed to store batch, retrying in %s: %v", delay, err)
+		if delay == time.Second {
+			// Report once per batch rather than on every retry
+			reporting.CaptureError(ctx, err, map[string]string{"batch_size": strconv.Itoa(len(events))})
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// headerDecoder decodes RFC 2047 encoded-words in any charset known to the
+// WHATWG encoding index, not just UTF-8 and ISO-8859-1
+var headerDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported charset %q: %w", charset, err)
+		}
+		return enc.NewDecoder().Reader(input), nil
+	},
+}
+
+// DecodeHeader decodes the RFC 2047 encoded-words ("=?ISO-8859-1?Q?...?=") in
+// an email header such as a subject. Headers that can't be decoded are
+// returned unchanged.
+func DecodeHeader(header string) string {
+	decoded, err := headerDecoder.DecodeHeader(header)
+	if err != nil {
+		log.Printf("Failed to decode header %q: %v", header, err)
+		return header
+	}
+	return decoded
+}
+
+// ToUTF8 converts text in the named charset to UTF-8. Text that is already
+// UTF-8, or in a charset that isn't recognised, is returned unchanged.
+func ToUTF8(text []byte, charset string) string {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if charset == "" || charset == "utf-8" || charset == "utf8" || charset == "us-ascii" {
+		return string(text)
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		log.Printf("Unknown charset %q, keeping text as-is", charset)
+		return string(text)
+	}
+	decoded, err := enc.NewDecoder().Bytes(text)
+	if err != nil {
+		log.Printf("Failed to decode %s text: %v", charset, err)
+		return string(text)
+	}
+	return string(decoded)
+}
+
+// readTextPart reads a text part as UTF-8, undoing base64 transfer encoding
+// (quoted-printable is already undone by the multipart reader) and then the
+// part's charset
+func readTextPart(p *multipart.Part, charset string) string {
+	var body io.Reader = p
+	if strings.EqualFold(p.Header.Get("Content-Transfer-Encoding"), "base64") {
+		body = base64.NewDecoder(base64.StdEncoding, p)
+	}
+	text, err := io.ReadAll(body)
+	if err != nil {
+		log.Printf("Failed to read text part: %v", err)
+	}
+	return ToUTF8(text, charset)
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// multipartBody builds a multipart body from parts, each its headers and
+// content separated by a blank line
+func multipartBody(boundary string, parts ...string) string {
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString("--" + boundary + "\r\n" + part + "\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return b.String()
+}
+
+func TestExtractPlainCharsets(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "plain text",
+			body: "  Disk full on db1  \n",
+			want: "Disk full on db1",
+		},
+		{
+			name: "utf-8",
+			body: multipartBody("b", "Content-Type: text/plain; charset=utf-8\r\n\r\ncafé"),
+			want: "café",
+		},
+		{
+			name: "no charset",
+			body: multipartBody("b", "Content-Type: text/plain\r\n\r\nplain ascii"),
+			want: "plain ascii",
+		},
+		{
+			name: "latin-1",
+			body: multipartBody("b", "Content-Type: text/plain; charset=ISO-8859-1\r\n\r\ncaf\xe9"),
+			want: "café",
+		},
+		{
+			name: "latin-1 quoted-printable",
+			body: multipartBody("b", "Content-Type: text/plain; charset=\"iso-8859-1\"\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\ncaf=E9 cr=E8me"),
+			want: "café crème",
+		},
+		{
+			name: "windows-1252 base64",
+			body: multipartBody("b", "Content-Type: text/plain; charset=windows-1252\r\nContent-Transfer-Encoding: base64\r\n\r\ngDUg"),
+			want: "€5",
+		},
+		{
+			name: "iso-2022-jp",
+			body: multipartBody("b", "Content-Type: text/plain; charset=ISO-2022-JP\r\n\r\n\x1b$BF|K\\\x1b(B"),
+			want: "日本",
+		},
+		{
+			name: "unknown charset kept as is",
+			body: multipartBody("b", "Content-Type: text/plain; charset=x-unknown\r\n\r\nas sent"),
+			want: "as sent",
+		},
+		{
+			name: "html only",
+			body: multipartBody("b", "Content-Type: text/html; charset=iso-8859-1\r\n\r\n<p>caf\xe9</p>"),
+			want: "café",
+		},
+		{
+			name: "nested alternative prefers plain",
+			body: multipartBody("outer",
+				"Content-Type: multipart/alternative; boundary=inner\r\n\r\n"+multipartBody("inner",
+					"Content-Type: text/html; charset=utf-8\r\n\r\n<p>html</p>",
+					"Content-Type: text/plain; charset=iso-8859-15\r\n\r\n\xa4 plain")),
+			want: "€ plain",
+		},
+		{
+			name: "text attachment skipped",
+			body: multipartBody("b",
+				"Content-Type: text/plain; charset=utf-8\r\nContent-Disposition: attachment; filename=log.txt\r\n\r\nattached",
+				"Content-Type: text/plain; charset=utf-8\r\n\r\nbody"),
+			want: "body",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractPlain([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ExtractPlain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"Backup failed", "Backup failed"},
+		{"=?UTF-8?B?Y2Fmw6k=?=", "café"},
+		{"=?ISO-8859-1?Q?caf=E9?= down", "café down"},
+		{"=?windows-1252?Q?=80100?=", "€100"},
+		{"=?x-unknown?Q?abc?=", "=?x-unknown?Q?abc?="},
+	}
+	for _, tt := range tests {
+		if got := DecodeHeader(tt.header); got != tt.want {
+			t.Errorf("DecodeHeader(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package utils
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"example-api/internal/logging"
+	"example-api/internal/models"
 	"fmt"
 	"strings"
 	"time"
@@ -10,8 +12,11 @@ import (
 	"bytes"
 	"io"
 	"log"
+	"log/slog"
+	"mime"
 	"mime/multipart"
 	"mime/quotedprintable"
+	"path/filepath"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/jaytaylor/html2text"
@@ -84,7 +89,7 @@ func IsValidRole(role string) bool {
 
 // cleanMessageContent removes unwanted signatures and normalizes content
 func cleanMessageContent(content string) string {
-	log.Printf("Cleaning content (before): %q", content)
+	slog.Debug("Cleaning content", "content", logging.Payload(content))
 
 	// First decode quoted-printable if needed
 	if strings.Contains(strings.ToLower(content), "=\r\n") || strings.Contains(strings.ToLower(content), "=\n") {
@@ -113,7 +118,7 @@ func cleanMessageContent(content string) string {
 	// Final cleanup of any trailing whitespace or newlines
 	content = strings.TrimRight(content, "\r\n \t=")
 
-	log.Printf("Cleaning content (after): %q", content)
+	slog.Debug("Cleaned content", "content", logging.Payload(content))
 	return content
 }
 
@@ -122,7 +127,11 @@ func cleanMessageContent(content string) string {
 //
 //   - multipart body that starts with "--boundary" lines ➜ first text/plain part
 //   - multipart with only text/html           ➜ html→text conversion
+//   - nested multipart parts are searched at any depth
 //   - anything else (already plain text)      ➜ returned as-is
+//
+// Text parts are converted to UTF-8 from the charset named in their
+// Content-Type.
 func ExtractPlain(data []byte) (string, error) {
 	trimmed := bytes.TrimSpace(data)
 
@@ -132,47 +141,168 @@ func ExtractPlain(data []byte) (string, error) {
 	}
 
 	// ── try multipart ───────────────────────────────────────────────
-	// find the first line   -->  boundary string
+	boundary, ok := leadingBoundary(trimmed)
+	if !ok {
+		return string(trimmed), nil
+	}
+
+	plain, html, err := findText(bytes.NewReader(trimmed), boundary)
+	if err != nil {
+		// Not multipart after all → fall back
+		return string(trimmed), nil
+	}
+
+	if plain != "" {
+		return strings.TrimSpace(plain), nil
+	}
+	if html != "" {
+		return HTMLToText(html)
+	}
+	// multipart but neither plain nor html found
+	return "", nil
+}
+
+// findText returns the first text/plain and text/html parts of a multipart
+// body, descending into nested multipart parts (multipart/alternative inside
+// multipart/mixed, and deeper). Text sent as an attachment is skipped.
+func findText(r io.Reader, boundary string) (plain, html string, err error) {
+	mr := multipart.NewReader(r, boundary)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return plain, html, nil
+		}
+		if err != nil {
+			return plain, html, err
+		}
+
+		ct, params, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if disposition, _, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition")); disposition == "attachment" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(ct, "multipart/") && params["boundary"] != "":
+			nestedPlain, nestedHTML, err := findText(p, params["boundary"])
+			if err != nil {
+				log.Printf("Failed to read nested %s part: %v", ct, err)
+			}
+			if plain == "" {
+				plain = nestedPlain
+			}
+			if html == "" {
+				html = nestedHTML
+			}
+		case ct == "text/plain" && plain == "":
+			plain = readTextPart(p, params["charset"])
+		case ct == "text/html" && html == "":
+			html = readTextPart(p, params["charset"])
+		}
+	}
+}
+
+// leadingBoundary returns the multipart boundary taken from the first line of
+// a body that starts with "--boundary"
+func leadingBoundary(trimmed []byte) (string, bool) {
 	nl := bytes.IndexByte(trimmed, '\n')
 	if nl == -1 {
-		return string(trimmed), nil
+		return "", false
 	}
 	boundary := strings.TrimPrefix(
 		strings.TrimSpace(string(trimmed[:nl])),
 		"--")
+	return boundary, boundary != ""
+}
+
+// MaxAttachmentSize is the largest attachment kept from an email; bigger
+// parts are skipped
+const MaxAttachmentSize = 25 << 20
 
-	mr := multipart.NewReader(bytes.NewReader(trimmed), boundary)
+// ExtractAttachments walks every part of a multipart body, descending into
+// nested multipart parts, and returns the non-text parts and any part marked
+// Content-Disposition: attachment. Bodies that are not multipart have none.
+func ExtractAttachments(data []byte) []models.Attachment {
+	trimmed := bytes.TrimSpace(data)
+	if !bytes.HasPrefix(trimmed, []byte("--")) {
+		return nil
+	}
+	boundary, ok := leadingBoundary(trimmed)
+	if !ok {
+		return nil
+	}
 
-	var plain, html string
+	var attachments []models.Attachment
+	walkParts(bytes.NewReader(trimmed), boundary, &attachments)
+	return attachments
+}
+
+// walkParts collects attachments from one multipart level
+func walkParts(r io.Reader, boundary string, attachments *[]models.Attachment) {
+	mr := multipart.NewReader(r, boundary)
 	for {
 		p, err := mr.NextPart()
 		if err == io.EOF {
-			break
+			return
 		}
 		if err != nil {
-			// Not multipart after all → fall back
-			return string(trimmed), nil
+			log.Printf("Stopped reading MIME parts: %v", err)
+			return
 		}
-		ct := p.Header.Get("Content-Type")
-		body, _ := io.ReadAll(p)
-		switch {
-		case strings.HasPrefix(ct, "text/plain"):
-			plain = string(body)
-		case strings.HasPrefix(ct, "text/html"):
-			html = string(body)
+
+		mediaType, params, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if err != nil {
+			mediaType = "text/plain"
+		}
+		if strings.HasPrefix(mediaType, "multipart/") {
+			if params["boundary"] != "" {
+				walkParts(p, params["boundary"], attachments)
+			}
+			continue
 		}
-	}
 
-	if plain != "" {
-		return strings.TrimSpace(plain), nil
-	}
-	if html != "" {
-		txt, err := html2text.FromString(html, html2text.Options{})
+		disposition, _, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+		if disposition != "attachment" && strings.HasPrefix(mediaType, "text/") {
+			continue
+		}
+
+		var body io.Reader = p
+		if strings.EqualFold(p.Header.Get("Content-Transfer-Encoding"), "base64") {
+			body = base64.NewDecoder(base64.StdEncoding, p)
+		}
+		content, err := io.ReadAll(io.LimitReader(body, MaxAttachmentSize+1))
 		if err != nil {
-			return "", err
+			log.Printf("Failed to read attachment part: %v", err)
+			continue
+		}
+		if len(content) > MaxAttachmentSize {
+			log.Printf("Skipping attachment larger than %d bytes", MaxAttachmentSize)
+			continue
+		}
+
+		filename := p.FileName()
+		if filename == "" {
+			filename = params["name"]
+		}
+		if filename == "" {
+			filename = fmt.Sprintf("attachment-%d", len(*attachments)+1)
+			if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+				filename += exts[0]
+			}
 		}
-		return strings.TrimSpace(txt), nil
+
+		*attachments = append(*attachments, models.Attachment{
+			Filename:    filepath.Base(DecodeHeader(filename)),
+			ContentType: mediaType,
+			Size:        len(content),
+			Data:        content,
+		})
 	}
-	// multipart but neither plain nor html found
-	return "", nil
+}
+
+// HTMLToText converts an HTML body to plain text
+func HTMLToText(html string) (string, error) {
+	txt, err := html2text.FromString(html, html2text.Options{})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(txt), nil
 }
@@ -0,0 +1,54 @@
+package utils
+
+import "testing"
+
+func TestExtractPlainNestedParts(t *testing.T) {
+	plain := "Content-Type: text/plain; charset=utf-8\r\n\r\nplain text"
+	html := "Content-Type: text/html; charset=utf-8\r\n\r\n<p>html text</p>"
+	nested := func(boundary string, parts ...string) string {
+		return "Content-Type: multipart/alternative; boundary=" + boundary + "\r\n\r\n" + multipartBody(boundary, parts...)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "top level", body: multipartBody("b", html, plain), want: "plain text"},
+		{name: "alternative inside mixed", body: multipartBody("mixed", nested("alt", plain, html)), want: "plain text"},
+		{
+			name: "three levels deep",
+			body: multipartBody("mixed", "Content-Type: multipart/related; boundary=rel\r\n\r\n"+
+				multipartBody("rel", nested("alt", html, plain))),
+			want: "plain text",
+		},
+		{name: "html when there is no plain part", body: multipartBody("mixed", nested("alt", html)), want: "html text"},
+		{
+			name: "plain at any depth beats html above it",
+			body: multipartBody("mixed", html, nested("alt", plain)),
+			want: "plain text",
+		},
+		{
+			name: "first plain part wins",
+			body: multipartBody("mixed", nested("alt", plain), "Content-Type: text/plain\r\n\r\nsignature"),
+			want: "plain text",
+		},
+		{
+			name: "attachments only",
+			body: multipartBody("mixed", "Content-Type: application/pdf\r\n\r\n%PDF-1.4"),
+			want: "",
+		},
+		{name: "not multipart after all", body: "-- \nSent from my phone", want: "-- \nSent from my phone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractPlain([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ExtractPlain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
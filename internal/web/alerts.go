@@ -0,0 +1,139 @@
+package web
+
+import (
+	"example-api/internal/alert"
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// SetAlertNotifier sets what sends test notifications for alert rules
+func (h *WebHandler) SetAlertNotifier(n *alert.Notifier) {
+	h.alerts = n
+}
+
+// HandleAdminAlerts lists the alert rules, with a form for adding one
+func (h *WebHandler) HandleAdminAlerts(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.db.ListAlertRules()
+	if err != nil {
+		log.Printf("Error fetching alert rules: %v", err)
+		http.Error(w, "Error fetching alert rules", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:          auth.GetUserFromContext(r.Context()),
+		AlertRules:    rules,
+		AlertChannels: alert.Channels,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "alerts.html", data)
+}
+
+// HandleCreateAlertPost adds an alert rule
+func (h *WebHandler) HandleCreateAlertPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	rule := &models.AlertRule{
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		Tags:      splitTags(r.FormValue("tags")),
+		Source:    strings.TrimSpace(r.FormValue("source")),
+		Keyword:   strings.TrimSpace(r.FormValue("keyword")),
+		Channel:   r.FormValue("channel"),
+		Target:    strings.TrimSpace(r.FormValue("target")),
+		Enabled:   true,
+		CreatedBy: user.Username,
+	}
+	if err := alert.Validate(rule); err != nil {
+		h.setFlash(w, "Invalid rule: "+err.Error(), "error")
+		http.Redirect(w, r, "/admin/alerts", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.db.CreateAlertRule(rule); err != nil {
+		log.Printf("Error creating alert rule: %v", err)
+		h.setFlash(w, "Error creating alert rule", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Created alert rule %q", rule.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/alerts", http.StatusSeeOther)
+}
+
+// HandleToggleAlertPost enables or disables an alert rule
+func (h *WebHandler) HandleToggleAlertPost(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.alertRule(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.SetAlertRuleEnabled(rule.ID, !rule.Enabled); err != nil {
+		log.Printf("Error updating alert rule %d: %v", rule.ID, err)
+		h.setFlash(w, "Error updating alert rule", "error")
+	} else if rule.Enabled {
+		h.setFlash(w, fmt.Sprintf("Disabled %q", rule.Name), "success")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Enabled %q", rule.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/alerts", http.StatusSeeOther)
+}
+
+// HandleTestAlertPost sends a rule's notification for a made-up event
+func (h *WebHandler) HandleTestAlertPost(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.alertRule(w, r)
+	if !ok {
+		return
+	}
+
+	if h.alerts == nil {
+		h.setFlash(w, "Alert notifications aren't set up", "error")
+	} else if err := h.alerts.Test(rule); err != nil {
+		log.Printf("Test notification for alert rule %d failed: %v", rule.ID, err)
+		h.setFlash(w, fmt.Sprintf("Test notification for %q failed: %v", rule.Name, err), "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Sent a test notification for %q", rule.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/alerts", http.StatusSeeOther)
+}
+
+// HandleDeleteAlertPost removes an alert rule
+func (h *WebHandler) HandleDeleteAlertPost(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.alertRule(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteAlertRule(rule.ID); err != nil {
+		log.Printf("Error deleting alert rule %d: %v", rule.ID, err)
+		h.setFlash(w, "Error deleting alert rule", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Deleted %q", rule.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/alerts", http.StatusSeeOther)
+}
+
+// alertRule loads the alert rule named in the URL
+func (h *WebHandler) alertRule(w http.ResponseWriter, r *http.Request) (*models.AlertRule, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid alert rule ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	rule, err := h.db.GetAlertRule(id)
+	if err != nil {
+		log.Printf("Error fetching alert rule %d: %v", id, err)
+		http.Error(w, "Error retrieving alert rule", http.StatusInternalServerError)
+		return nil, false
+	}
+	if rule == nil {
+		http.Error(w, "Alert rule not found", http.StatusNotFound)
+		return nil, false
+	}
+	return rule, true
+}
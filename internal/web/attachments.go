@@ -0,0 +1,74 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleDownloadAttachment serves an attachment stored with an email event.
+// Attachments are always downloaded rather than shown inline so that HTML or
+// SVG files sent by email can't run in the application's origin.
+func (h *WebHandler) HandleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	eventID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(vars["attachmentID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.db.GetEventByID(eventID)
+	if err != nil {
+		http.Error(w, "Error retrieving event", http.StatusInternalServerError)
+		return
+	}
+	if event == nil || !h.projectAccess(auth.GetUserFromContext(r.Context())).CanView(event.ProjectID) {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	attachment, err := h.db.GetAttachment(eventID, id)
+	if err != nil {
+		log.Printf("Error retrieving attachment %d of event %d: %v", id, eventID, err)
+		http.Error(w, "Error retrieving attachment", http.StatusInternalServerError)
+		return
+	}
+	if attachment == nil {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(attachment.Data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(attachment.Data)
+}
+
+// formatFileSize formats a byte count for display
+func formatFileSize(size int) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := unit, 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMG"[exp])
+}
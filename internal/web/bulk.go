@@ -0,0 +1,168 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// bulkActions lists the supported bulk actions and how they are described
+var bulkActions = map[string]string{
+	"delete":      "Delete",
+	"add_tags":    "Add tags to",
+	"remove_tags": "Remove tags from",
+}
+
+// HandleBulkEvents applies a bulk action to the events selected in the list.
+// The first submission renders a confirmation page; the action only runs once
+// the form is re-posted with confirm=yes.
+func (h *WebHandler) HandleBulkEvents(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.setFlash(w, "Error processing form data", "error")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	returnURL := localURL(r.FormValue("return"))
+	action := r.FormValue("action")
+	if _, ok := bulkActions[action]; !ok {
+		h.setFlash(w, "Choose a bulk action", "error")
+		http.Redirect(w, r, returnURL, http.StatusSeeOther)
+		return
+	}
+
+	ids := parseEventIDs(r.Form["ids"])
+	if len(ids) == 0 {
+		h.setFlash(w, "No events selected", "error")
+		http.Redirect(w, r, returnURL, http.StatusSeeOther)
+		return
+	}
+
+	tags := splitTags(r.FormValue("tags"))
+	if action != "delete" && len(tags) == 0 {
+		h.setFlash(w, "Enter at least one tag", "error")
+		http.Redirect(w, r, returnURL, http.StatusSeeOther)
+		return
+	}
+
+	if r.FormValue("confirm") != "yes" {
+		h.renderBulkConfirm(w, r, action, ids, tags, returnURL)
+		return
+	}
+
+	// Events in projects the user can't edit are left alone
+	access := h.projectAccess(auth.GetUserFromContext(r.Context()))
+	before := h.snapshotEvents(ids)
+	ids = ids[:0]
+	for id, event := range before {
+		if access.CanEdit(event.ProjectID) {
+			ids = append(ids, id)
+		} else {
+			delete(before, id)
+		}
+	}
+	if len(ids) == 0 {
+		h.setFlash(w, "None of the selected events can be changed", "error")
+		http.Redirect(w, r, returnURL, http.StatusSeeOther)
+		return
+	}
+
+	var affected int64
+	var err error
+	switch action {
+	case "delete":
+		affected, err = h.db.DeleteEvents(ids)
+	case "add_tags":
+		affected, err = h.db.AddTagsToEvents(ids, tags)
+	case "remove_tags":
+		affected, err = h.db.RemoveTagsFromEvents(ids, tags)
+	}
+	if err != nil {
+		log.Printf("Error applying bulk %s to %d events: %v", action, len(ids), err)
+		h.setFlash(w, fmt.Sprintf("Error applying bulk action: %v", err), "error")
+		http.Redirect(w, r, returnURL, http.StatusSeeOther)
+		return
+	}
+	h.recordBulkAudit(r, before)
+
+	switch action {
+	case "delete":
+		h.setFlash(w, fmt.Sprintf("Deleted %d events", affected), "success")
+	default:
+		h.setFlash(w, fmt.Sprintf("Updated tags on %d events", affected), "success")
+	}
+	http.Redirect(w, r, returnURL, http.StatusSeeOther)
+}
+
+// renderBulkConfirm shows the selected events and asks the user to confirm the action
+func (h *WebHandler) renderBulkConfirm(w http.ResponseWriter, r *http.Request, action string, ids []int64, tags []string, returnURL string) {
+	user := auth.GetUserFromContext(r.Context())
+	access := h.projectAccess(user)
+	var events []models.Event
+	for _, id := range ids {
+		event, err := h.db.GetEventByID(id)
+		if err != nil {
+			log.Printf("Error fetching event %d for bulk confirmation: %v", id, err)
+			continue
+		}
+		if event != nil && access.CanEdit(event.ProjectID) {
+			events = append(events, *event)
+		}
+	}
+	if len(events) == 0 {
+		h.setFlash(w, "None of the selected events can be changed", "error")
+		http.Redirect(w, r, returnURL, http.StatusSeeOther)
+		return
+	}
+
+	data := TemplateData{
+		User:   user,
+		Events: events,
+	}
+	data.Bulk.Action = action
+	data.Bulk.Description = bulkActions[action]
+	data.Bulk.Tags = strings.Join(tags, ", ")
+	data.Bulk.ReturnURL = returnURL
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "bulk_confirm.html", data)
+}
+
+// parseEventIDs converts form values to event IDs, ignoring invalid and duplicate entries
+func parseEventIDs(values []string) []int64 {
+	var ids []int64
+	seen := make(map[int64]bool)
+	for _, value := range values {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// splitTags parses a comma-separated tag list, dropping empty entries
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// localURL returns u if it is a path on this site, otherwise the events list
+func localURL(u string) string {
+	if !strings.HasPrefix(u, "/") || strings.HasPrefix(u, "//") || strings.HasPrefix(u, "/\\") {
+		return "/"
+	}
+	return u
+}
@@ -0,0 +1,108 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleCommentPost adds the user's comment to an event
+func (h *WebHandler) HandleCommentPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.db.GetEventByID(id)
+	if err != nil {
+		http.Error(w, "Error retrieving event", http.StatusInternalServerError)
+		return
+	}
+	if event == nil || !h.projectAccess(user).CanView(event.ProjectID) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	eventURL := fmt.Sprintf("/events/%d", event.ID)
+	body := strings.TrimSpace(r.FormValue("body"))
+	switch {
+	case body == "":
+		h.setFlash(w, "Write something before adding a comment", "error")
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	case len([]rune(body)) > models.MaxCommentLength:
+		h.setFlash(w, fmt.Sprintf("Comments can be at most %d characters", models.MaxCommentLength), "error")
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+
+	comment := &models.EventComment{EventID: event.ID, Author: user.Username, Body: body}
+	if err := h.db.AddComment(comment); err != nil {
+		log.Printf("Error adding comment to event %d: %v", event.ID, err)
+		h.setFlash(w, "Error adding comment", "error")
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s#comment-%d", eventURL, comment.ID), http.StatusSeeOther)
+}
+
+// HandleDeleteCommentPost removes a comment. Only its author and admins can,
+// and only while they can see the event's project.
+func (h *WebHandler) HandleDeleteCommentPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+
+	vars := mux.Vars(r)
+	eventID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(vars["commentID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.db.GetEventByID(eventID)
+	if err != nil {
+		http.Error(w, "Error retrieving event", http.StatusInternalServerError)
+		return
+	}
+	if event == nil || !h.projectAccess(user).CanView(event.ProjectID) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	comment, err := h.db.GetComment(eventID, id)
+	if err != nil {
+		log.Printf("Error fetching comment %d: %v", id, err)
+		http.Error(w, "Error retrieving comment", http.StatusInternalServerError)
+		return
+	}
+	if comment == nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if comment.Author != user.Username && user.Role != "admin" {
+		http.Error(w, "Only the comment's author or an admin can delete it", http.StatusForbidden)
+		return
+	}
+
+	if err := h.db.DeleteComment(id); err != nil {
+		log.Printf("Error deleting comment %d: %v", id, err)
+		h.setFlash(w, "Error deleting comment", "error")
+	} else {
+		h.setFlash(w, "Comment deleted", "success")
+	}
+	http.Redirect(w, r, fmt.Sprintf("/events/%d#comments", eventID), http.StatusSeeOther)
+}
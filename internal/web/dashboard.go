@@ -0,0 +1,159 @@
+package web
+
+import (
+	"context"
+	"example-api/internal/auth"
+	"example-api/internal/cache"
+	"example-api/internal/models"
+	"example-api/internal/monitor"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	dashboardDays       = 30 // days shown in the events-per-day chart
+	dashboardRecentDays = 7  // window counted as "recent" events
+	dashboardTopN       = 10 // tags and sources shown in the top charts
+	dashboardAnomalies  = 20 // anomalies of the last day shown
+)
+
+// dashboardStats are the aggregates shown on the dashboard
+type dashboardStats struct {
+	TotalEvents  int                 `json:"total_events"`
+	RecentEvents int                 `json:"recent_events"`
+	UniqueTags   int                 `json:"unique_tags"`
+	EventsPerDay []models.DailyCount `json:"events_per_day"`
+	TopTags      []models.NameCount  `json:"top_tags"`
+	TopSources   []models.NameCount  `json:"top_sources"`
+}
+
+// SetStatsCache caches the dashboard's aggregates in c for ttl, so every
+// page view doesn't query them again. Zero ttl turns caching off.
+func (h *WebHandler) SetStatsCache(c cache.Cache, ttl time.Duration) {
+	h.statsCache, h.statsTTL = c, ttl
+}
+
+// SetMonitors sets the heartbeat monitors whose status the dashboard shows
+func (h *WebHandler) SetMonitors(monitors []*monitor.Monitor) {
+	h.monitors = monitors
+}
+
+// cachedStats fills v from the cache under key, or with load. The result is
+// cached when load reports it complete.
+func (h *WebHandler) cachedStats(key string, v interface{}, load func() (complete bool, err error)) error {
+	ctx := context.Background()
+	if h.statsCache == nil || h.statsTTL <= 0 {
+		_, err := load()
+		return err
+	}
+	if ok, err := cache.GetJSON(ctx, h.statsCache, key, v); err != nil {
+		log.Printf("Warning: failed to read %s from the cache: %v", key, err)
+	} else if ok {
+		return nil
+	}
+	complete, err := load()
+	if err != nil || !complete {
+		return err
+	}
+	if err := cache.SetJSON(ctx, h.statsCache, key, v, h.statsTTL); err != nil {
+		log.Printf("Warning: failed to cache %s: %v", key, err)
+	}
+	return nil
+}
+
+// loadDashboardStats queries the dashboard's aggregates. Only counting the
+// events is required; the charts are left empty when their queries fail,
+// which makes the result incomplete.
+func (h *WebHandler) loadDashboardStats(stats *dashboardStats) (complete bool, err error) {
+	total, recent, err := h.db.CountEvents(time.Now().AddDate(0, 0, -dashboardRecentDays))
+	if err != nil {
+		return false, err
+	}
+	stats.TotalEvents = total
+	stats.RecentEvents = recent
+
+	complete = true
+	if stats.UniqueTags, err = h.db.CountUniqueTags(); err != nil {
+		log.Printf("Error counting tags: %v", err)
+		complete = false
+	}
+	if stats.EventsPerDay, err = h.db.GetEventsPerDay(dashboardDays); err != nil {
+		log.Printf("Error fetching events per day: %v", err)
+		complete = false
+	}
+	if stats.TopTags, err = h.db.GetTopTags(dashboardTopN); err != nil {
+		log.Printf("Error fetching top tags: %v", err)
+		complete = false
+	}
+	if stats.TopSources, err = h.db.GetTopSources(dashboardTopN); err != nil {
+		log.Printf("Error fetching top sources: %v", err)
+		complete = false
+	}
+	return complete, nil
+}
+
+// HandleDashboard shows event statistics and charts
+func (h *WebHandler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
+	data := TemplateData{
+		User: auth.GetUserFromContext(r.Context()),
+	}
+
+	var stats dashboardStats
+	err := h.cachedStats("dashboard:stats", &stats, func() (bool, error) { return h.loadDashboardStats(&stats) })
+	if err != nil {
+		log.Printf("Error counting events: %v", err)
+		http.Error(w, "Error fetching statistics", http.StatusInternalServerError)
+		return
+	}
+	data.Stats.TotalEvents = stats.TotalEvents
+	data.Stats.RecentEvents = stats.RecentEvents
+	data.Stats.UniqueTags = stats.UniqueTags
+	data.Stats.EventsPerDay = stats.EventsPerDay
+	data.Stats.TopTags = stats.TopTags
+	data.Stats.TopSources = stats.TopSources
+
+	if data.User != nil && data.User.Role == "admin" {
+		var dbStats models.DatabaseStats
+		err := h.cachedStats("dashboard:database", &dbStats, func() (bool, error) {
+			loaded, err := h.db.GetDatabaseStats(dashboardDays)
+			if err != nil {
+				return false, err
+			}
+			dbStats = *loaded
+			return true, nil
+		})
+		if err != nil {
+			log.Printf("Error fetching database statistics: %v", err)
+		} else {
+			data.Stats.Database = &dbStats
+		}
+	}
+
+	if len(h.monitors) > 0 {
+		monitors, err := monitor.Statuses(h.db, h.monitors, time.Now())
+		if err != nil {
+			log.Printf("Error fetching monitor statuses: %v", err)
+		}
+		data.Monitors = monitors
+	}
+
+	// Anomalies count the events of every project, so only admins see them
+	if data.User != nil && data.User.Role == "admin" {
+		anomalies, err := h.db.RecentAnomalies(time.Now().Add(-24*time.Hour), dashboardAnomalies)
+		if err != nil {
+			log.Printf("Error fetching anomalies: %v", err)
+		}
+		data.Anomalies = anomalies
+	}
+
+	recentFilter := models.EventFilter{SortBy: "created_at", SortDesc: true, Limit: 10, Projects: h.projectAccess(data.User).Viewable()}
+	recentEvents, _, err := h.db.ListEvents(recentFilter)
+	if err != nil {
+		log.Printf("Error fetching recent events: %v", err)
+	}
+	data.RecentEvents = recentEvents
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "dashboard.html", data)
+}
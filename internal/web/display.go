@@ -0,0 +1,77 @@
+package web
+
+import (
+	"example-api/internal/models"
+	"example-api/internal/render"
+	"log"
+)
+
+// markdownTag marks an individual event as Markdown regardless of its source
+const markdownTag = "markdown"
+
+// SetMarkdownSources makes events from the given sources always render as Markdown
+func (h *WebHandler) SetMarkdownSources(sources []string) {
+	markdownSources := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		markdownSources[source] = true
+	}
+	h.displayMu.Lock()
+	defer h.displayMu.Unlock()
+	h.markdownSources = markdownSources
+}
+
+// isMarkdown reports whether an event's data should be rendered as Markdown:
+// when it is tagged "markdown", comes from a Markdown source, or looks like Markdown
+func (h *WebHandler) isMarkdown(event *models.Event) bool {
+	for _, tag := range event.Tags {
+		if tag == markdownTag {
+			return true
+		}
+	}
+	h.displayMu.RLock()
+	markdownSource := h.markdownSources[event.Source]
+	h.displayMu.RUnlock()
+	if markdownSource {
+		return true
+	}
+	return render.LooksLikeMarkdown(event.Data)
+}
+
+// setRenderedData fills in the rendered form of the event data, if any.
+// The original HTML of email events takes precedence, then JSON, then Markdown.
+// Remote images in HTML email are only loaded when showImages is set.
+func (h *WebHandler) setRenderedData(data *TemplateData, event *models.Event, showImages bool) {
+	data.RawData = event.Data
+
+	if event.HTMLBody != "" {
+		data.DataFormat = "html"
+		data.RenderedData, data.BlockedImages = render.EmailHTML(event.HTMLBody, showImages)
+		return
+	}
+
+	if render.LooksLikeJSON(event.Data) {
+		tree, err := render.JSONTree(event.Data)
+		if err != nil {
+			log.Printf("Error rendering event %d as json: %v", event.ID, err)
+			return
+		}
+		if pretty, err := render.PrettyJSON(event.Data); err == nil {
+			data.RawData = pretty
+		}
+		data.DataFormat = "json"
+		data.RenderedData = tree
+		return
+	}
+
+	if !h.isMarkdown(event) {
+		return
+	}
+
+	html, err := render.Markdown(event.Data)
+	if err != nil {
+		log.Printf("Error rendering event %d as markdown: %v", event.ID, err)
+		return
+	}
+	data.DataFormat = "markdown"
+	data.RenderedData = html
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// dueFormat is how the event page's due date input sends its value, in the
+// user's time zone
+const dueFormat = "2006-01-02T15:04"
+
+// HandleEventDuePost sets or clears when an event is due and records the
+// change in its audit trail
+func (h *WebHandler) HandleEventDuePost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	event, ok := h.editableEvent(w, r, user)
+	if !ok {
+		return
+	}
+	eventURL := fmt.Sprintf("/events/%d", event.ID)
+
+	var due *time.Time
+	if r.FormValue("clear") == "" {
+		parsed, err := time.ParseInLocation(dueFormat, r.FormValue("due"), h.preferences(user).Location())
+		if err != nil {
+			h.setFlash(w, "Enter the date and time the event is due", "error")
+			http.Redirect(w, r, eventURL, http.StatusSeeOther)
+			return
+		}
+		due = &parsed
+	}
+
+	found, err := h.db.SetEventDue(event.ID, due)
+	switch {
+	case err != nil:
+		log.Printf("Error setting due date of event %d: %v", event.ID, err)
+		h.setFlash(w, "Error changing due date", "error")
+	case !found:
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	default:
+		updated := *event
+		updated.DueAt = due
+		h.recordEventAudit(r, models.EventEdited, event.ID, event, &updated)
+		if due == nil {
+			h.setFlash(w, "Due date cleared", "success")
+		} else {
+			h.setFlash(w, "Due "+due.Format("January 2, 2006 at 3:04 PM"), "success")
+		}
+	}
+	http.Redirect(w, r, eventURL, http.StatusSeeOther)
+}
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// auditActor names the signed-in user for the event audit trail
+func auditActor(r *http.Request) string {
+	if user := auth.GetUserFromContext(r.Context()); user != nil {
+		return user.Username
+	}
+	return "unknown"
+}
+
+// recordEventAudit adds a change to an event's audit trail. A failure is only
+// logged; the change itself has already been made.
+func (h *WebHandler) recordEventAudit(r *http.Request, action string, id int64, before, after *models.Event) {
+	entry := &models.EventAuditEntry{EventID: id, Action: action, Actor: auditActor(r), Old: before, New: after}
+	if err := h.db.RecordEventAudit(entry); err != nil {
+		log.Printf("Error recording %s of event %d in the audit trail: %v", action, id, err)
+	}
+}
+
+// snapshotEvents fetches the events about to be changed by a bulk operation
+func (h *WebHandler) snapshotEvents(ids []int64) map[int64]*models.Event {
+	events := make(map[int64]*models.Event, len(ids))
+	for _, id := range ids {
+		event, err := h.db.GetEventByID(id)
+		if err != nil {
+			log.Printf("Error fetching event %d for the audit trail: %v", id, err)
+			continue
+		}
+		if event != nil {
+			events[id] = event
+		}
+	}
+	return events
+}
+
+// recordBulkAudit compares the snapshots taken before a bulk operation with
+// the events now stored, recording a delete for each event that is gone and
+// an edit for each one that changed
+func (h *WebHandler) recordBulkAudit(r *http.Request, before map[int64]*models.Event) {
+	for id, old := range before {
+		current, err := h.db.GetEventByID(id)
+		if err != nil {
+			log.Printf("Error fetching event %d for the audit trail: %v", id, err)
+			continue
+		}
+		if current == nil {
+			h.recordEventAudit(r, models.EventDeleted, id, old, nil)
+			continue
+		}
+		entry := models.EventAuditEntry{Old: old, New: current}
+		if len(entry.Changes()) > 0 {
+			h.recordEventAudit(r, models.EventEdited, id, old, current)
+		}
+	}
+}
+
+// HandleRestoreEventPost brings back a deleted event from its audit trail
+func (h *WebHandler) HandleRestoreEventPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.db.RestoreEvent(id, auditActor(r))
+	switch {
+	case errors.Is(err, database.ErrEventExists):
+		h.setFlash(w, "The event has not been deleted", "error")
+	case errors.Is(err, database.ErrDuplicateMessageID):
+		h.setFlash(w, "The event's email has been stored again since it was deleted", "error")
+	case err != nil:
+		log.Printf("Error restoring event %d: %v", id, err)
+		h.setFlash(w, fmt.Sprintf("Error restoring event: %v", err), "error")
+	case event == nil:
+		h.setFlash(w, "No deleted event with that ID", "error")
+	default:
+		h.setFlash(w, "Event restored", "success")
+		http.Redirect(w, r, fmt.Sprintf("/events/%d", id), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/admin/activity", http.StatusSeeOther)
+}
+
+// HandleEventActivity lists recent changes to events, such as deletions that
+// can be restored
+func (h *WebHandler) HandleEventActivity(w http.ResponseWriter, r *http.Request) {
+	action := r.URL.Query().Get("action")
+	entries, err := h.db.ListEventAudit(action, 200)
+	if err != nil {
+		log.Printf("Error fetching event audit trail: %v", err)
+		http.Error(w, "Error fetching event activity", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:          auth.GetUserFromContext(r.Context()),
+		EventActivity: entries,
+	}
+	data.Filter.Action = action
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "activity.html", data)
+}
+
+// snapshotTagged fetches the events carrying any of the tags, before a tag
+// is renamed, merged or deleted
+func (h *WebHandler) snapshotTagged(tags []string) map[int64]*models.Event {
+	ids, err := h.db.EventIDsWithTags(tags)
+	if err != nil {
+		log.Printf("Error finding events tagged %v for the audit trail: %v", tags, err)
+		return nil
+	}
+	return h.snapshotEvents(ids)
+}
@@ -0,0 +1,160 @@
+package web
+
+import (
+	"example-api/internal/alert"
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleAdminExpectations lists the expectations, with a form for adding one
+func (h *WebHandler) HandleAdminExpectations(w http.ResponseWriter, r *http.Request) {
+	expectations, err := h.db.ListExpectations()
+	if err != nil {
+		log.Printf("Error fetching expectations: %v", err)
+		http.Error(w, "Error fetching expectations", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:          auth.GetUserFromContext(r.Context()),
+		Expectations:  expectations,
+		AlertChannels: alert.Channels,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "expectations.html", data)
+}
+
+// HandleCreateExpectationPost adds an expectation
+func (h *WebHandler) HandleCreateExpectationPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	exp := &models.Expectation{
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		Source:    strings.TrimSpace(r.FormValue("source")),
+		Tags:      splitTags(r.FormValue("tags")),
+		Channel:   r.FormValue("channel"),
+		Target:    strings.TrimSpace(r.FormValue("target")),
+		Enabled:   true,
+		CreatedBy: user.Username,
+	}
+	minEvents, err := strconv.Atoi(strings.TrimSpace(r.FormValue("min_events")))
+	if err == nil {
+		exp.MinEvents = minEvents
+		exp.Window, err = parseWindow(strings.TrimSpace(r.FormValue("window")))
+	}
+	if err == nil {
+		err = alert.ValidateExpectation(exp)
+	}
+	if err != nil {
+		h.setFlash(w, "Invalid expectation: "+err.Error(), "error")
+		http.Redirect(w, r, "/admin/expectations", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.db.CreateExpectation(exp); err != nil {
+		log.Printf("Error creating expectation: %v", err)
+		h.setFlash(w, "Error creating expectation", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Created expectation %q", exp.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/expectations", http.StatusSeeOther)
+}
+
+// HandleToggleExpectationPost enables or disables an expectation
+func (h *WebHandler) HandleToggleExpectationPost(w http.ResponseWriter, r *http.Request) {
+	exp, ok := h.expectation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.SetExpectationEnabled(exp.ID, !exp.Enabled); err != nil {
+		log.Printf("Error updating expectation %d: %v", exp.ID, err)
+		h.setFlash(w, "Error updating expectation", "error")
+	} else if exp.Enabled {
+		h.setFlash(w, fmt.Sprintf("Disabled %q", exp.Name), "success")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Enabled %q", exp.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/expectations", http.StatusSeeOther)
+}
+
+// HandleTestExpectationPost sends an expectation's notification as if it
+// was missed
+func (h *WebHandler) HandleTestExpectationPost(w http.ResponseWriter, r *http.Request) {
+	exp, ok := h.expectation(w, r)
+	if !ok {
+		return
+	}
+
+	if h.alerts == nil {
+		h.setFlash(w, "Alert notifications aren't set up", "error")
+	} else if err := h.alerts.TestExpectation(exp); err != nil {
+		log.Printf("Test notification for expectation %d failed: %v", exp.ID, err)
+		h.setFlash(w, fmt.Sprintf("Test notification for %q failed: %v", exp.Name, err), "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Sent a test notification for %q", exp.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/expectations", http.StatusSeeOther)
+}
+
+// HandleDeleteExpectationPost removes an expectation
+func (h *WebHandler) HandleDeleteExpectationPost(w http.ResponseWriter, r *http.Request) {
+	exp, ok := h.expectation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteExpectation(exp.ID); err != nil {
+		log.Printf("Error deleting expectation %d: %v", exp.ID, err)
+		h.setFlash(w, "Error deleting expectation", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Deleted %q", exp.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/expectations", http.StatusSeeOther)
+}
+
+// expectation loads the expectation named in the URL
+func (h *WebHandler) expectation(w http.ResponseWriter, r *http.Request) (*models.Expectation, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid expectation ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	exp, err := h.db.GetExpectation(id)
+	if err != nil {
+		log.Printf("Error fetching expectation %d: %v", id, err)
+		http.Error(w, "Error retrieving expectation", http.StatusInternalServerError)
+		return nil, false
+	}
+	if exp == nil {
+		http.Error(w, "Expectation not found", http.StatusNotFound)
+		return nil, false
+	}
+	return exp, true
+}
+
+// parseWindow parses an expectation's window, a duration such as "30m" or
+// whole days such as "7d"
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	window, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window %q, use a duration such as 30m, 24h or 7d", s)
+	}
+	return window, nil
+}
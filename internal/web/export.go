@@ -0,0 +1,47 @@
+package web
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/export"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+)
+
+// HandleExportEvents streams the currently filtered events as CSV or JSON
+func (h *WebHandler) HandleExportEvents(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	filter := parseEventFilter(r, user, h.preferences(user).Location())
+	filter.Metadata, _ = fieldFilter(r, h.customFields())
+	access := h.projectAccess(user)
+	scopeFilter(r, &filter, access, access.Visible(h.projects()))
+	filter.Located = format == "geojson"
+	log.Printf("Exporting events as %s - Tags: %v, From: '%s', To: '%s', Source: '%s', Query: '%s'",
+		format, filter.Tags, filter.DateFrom, filter.DateTo, filter.Source, filter.Query)
+
+	count, err := export.Stream(w, format, func(fn func(models.Event) error) error {
+		return h.db.StreamEvents(filter, fn)
+	})
+	if errors.Is(err, export.ErrUnsupportedFormat) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if count == 0 && err != nil {
+		log.Printf("Error exporting events: %v", err)
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Error exporting events", http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		// Headers are already sent, so the best we can do is log and truncate
+		log.Printf("Error exporting events after %d rows: %v", count, err)
+		return
+	}
+	log.Printf("Exported %d events as %s", count, format)
+}
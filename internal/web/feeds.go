@@ -0,0 +1,172 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/feed"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// feedEventsLimit is the number of recent events included in an Atom feed
+const feedEventsLimit = 50
+
+// calendarEventsLimit is the number of recent events included in a calendar
+// feed, which is browsed by date rather than read like a feed
+const calendarEventsLimit = 500
+
+// FeedLink is a signed feed URL offered on the events list
+type FeedLink struct {
+	Title string
+	Type  string
+	URL   string
+}
+
+// tagFeedPath and sourceFeedPath return the unescaped paths that feed links
+// are signed over
+func tagFeedPath(tag string) string {
+	return "/feeds/tag/" + tag + ".atom"
+}
+
+func sourceFeedPath(source string) string {
+	return "/feeds/source/" + source + ".atom"
+}
+
+// signedFeedURL signs a feed path and returns the absolute URL of its escaped
+// form. Feed links do not expire; changing the link secret revokes them.
+func (h *WebHandler) signedFeedURL(r *http.Request, path, escapedPath string) string {
+	query := h.linkSigner.Query(path, time.Time{})
+	return requestBaseURL(r) + escapedPath + "?" + query.Encode()
+}
+
+// feedLinks returns the feeds matching the list's tag and source filters
+func (h *WebHandler) feedLinks(r *http.Request, filter models.EventFilter) []FeedLink {
+	var links []FeedLink
+	for _, tag := range filter.Tags {
+		links = append(links, FeedLink{
+			Title: "Tag " + tag,
+			Type:  "application/atom+xml",
+			URL:   h.signedFeedURL(r, tagFeedPath(tag), tagFeedPath(url.PathEscape(tag))),
+		})
+	}
+	if filter.Source != "" {
+		links = append(links, FeedLink{
+			Title: "Source " + filter.Source,
+			Type:  "application/atom+xml",
+			URL:   h.signedFeedURL(r, sourceFeedPath(filter.Source), sourceFeedPath(url.PathEscape(filter.Source))),
+		})
+	}
+
+	calendar := FeedLink{Title: "Calendar", Type: "text/calendar", URL: h.calendarFeedURL(r, filter.Tags)}
+	if len(filter.Tags) > 0 {
+		calendar.Title = "Calendar of tags " + strings.Join(filter.Tags, ", ")
+	}
+	return append(links, calendar)
+}
+
+// HandleTagFeed serves an Atom feed of recent events with a tag
+func (h *WebHandler) HandleTagFeed(w http.ResponseWriter, r *http.Request) {
+	tag := mux.Vars(r)["tag"]
+	h.serveFeed(w, r, tagFeedPath(tag), models.EventFilter{Tags: []string{tag}}, feed.Feed{
+		Title:        "Events tagged " + tag,
+		AlternateURL: requestBaseURL(r) + "/?" + url.Values{"tag": {tag}}.Encode(),
+	})
+}
+
+// HandleSourceFeed serves an Atom feed of recent events from a source
+func (h *WebHandler) HandleSourceFeed(w http.ResponseWriter, r *http.Request) {
+	source := mux.Vars(r)["source"]
+	h.serveFeed(w, r, sourceFeedPath(source), models.EventFilter{Source: source}, feed.Feed{
+		Title:        "Events from " + source,
+		AlternateURL: requestBaseURL(r) + "/?" + url.Values{"source": {source}}.Encode(),
+	})
+}
+
+// serveFeed writes the newest events matching a verified feed link as Atom
+func (h *WebHandler) serveFeed(w http.ResponseWriter, r *http.Request, path string, filter models.EventFilter, f feed.Feed) {
+	events, ok := h.feedEvents(w, r, path, filter, feedEventsLimit)
+	if !ok {
+		return
+	}
+
+	f.BaseURL = requestBaseURL(r)
+	f.ID = f.BaseURL + r.URL.EscapedPath()
+	f.SelfURL = f.BaseURL + r.URL.RequestURI()
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if err := f.WriteAtom(w, events); err != nil {
+		log.Printf("Error writing feed %s: %v", path, err)
+	}
+}
+
+// calendarFeedPath returns the string calendar links are signed over. The
+// tags are part of the query rather than the path, so they are signed too.
+func calendarFeedPath(tags []string) string {
+	if len(tags) == 0 {
+		return "/feeds/events.ics"
+	}
+	sorted := append([]string(nil), tags...)
+	sort.Strings(sorted)
+	return "/feeds/events.ics?" + url.Values{"tag": sorted}.Encode()
+}
+
+// calendarFeedURL returns the signed calendar URL for events with any of the tags
+func (h *WebHandler) calendarFeedURL(r *http.Request, tags []string) string {
+	query := h.linkSigner.Query(calendarFeedPath(tags), time.Time{})
+	if len(tags) > 0 {
+		query["tag"] = tags
+	}
+	return requestBaseURL(r) + "/feeds/events.ics?" + query.Encode()
+}
+
+// HandleCalendarFeed serves recent events, optionally limited to any of the
+// tag parameters, as an iCalendar feed
+func (h *WebHandler) HandleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	tags := r.URL.Query()["tag"]
+	path := calendarFeedPath(tags)
+	events, ok := h.feedEvents(w, r, path, models.EventFilter{Tags: tags}, calendarEventsLimit)
+	if !ok {
+		return
+	}
+
+	f := feed.Feed{Title: "Events", BaseURL: requestBaseURL(r)}
+	if len(tags) > 0 {
+		f.Title = "Events tagged " + strings.Join(tags, ", ")
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+	if err := f.WriteICal(w, events); err != nil {
+		log.Printf("Error writing calendar %s: %v", path, err)
+	}
+}
+
+// feedEvents checks a feed link's signature and returns the newest events
+// matching the filter, writing an error response when it fails
+func (h *WebHandler) feedEvents(w http.ResponseWriter, r *http.Request, path string, filter models.EventFilter, limit int) ([]models.Event, bool) {
+	if err := h.linkSigner.Verify(path, r.URL.Query()); err != nil {
+		log.Printf("Rejected feed request for %s from %s: %v", path, auth.ClientIP(r), err)
+		http.Error(w, "Invalid feed link", http.StatusForbidden)
+		return nil, false
+	}
+
+	// Anyone holding a feed link can read it, so it only has events everyone
+	// can see
+	filter.Projects = []int64{models.DefaultProjectID}
+	filter.SortBy = "created_at"
+	filter.SortDesc = true
+	filter.Limit = limit
+	events, _, err := h.db.ListEvents(filter)
+	if err != nil {
+		log.Printf("Error fetching events for feed %s: %v", path, err)
+		http.Error(w, "Error retrieving events", http.StatusInternalServerError)
+		return nil, false
+	}
+	return events, true
+}
@@ -0,0 +1,155 @@
+package web
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// fieldParamPrefix starts the names of custom field inputs and filters, such
+// as field.severity=high
+const fieldParamPrefix = "field."
+
+// customFields returns the defined custom fields, or none if they can't be
+// loaded
+func (h *WebHandler) customFields() []models.CustomField {
+	fields, err := h.db.ListCustomFields()
+	if err != nil {
+		log.Printf("Error loading custom fields: %v", err)
+	}
+	return fields
+}
+
+// fieldFilter reads the custom field filters of the events list, returning
+// the values events must have and the values as entered. Unknown fields and
+// invalid values are ignored.
+func fieldFilter(r *http.Request, fields []models.CustomField) (models.Metadata, map[string]string) {
+	var filter models.Metadata
+	entered := make(map[string]string)
+	for _, field := range fields {
+		raw := strings.TrimSpace(r.URL.Query().Get(fieldParamPrefix + field.Name))
+		if raw == "" {
+			continue
+		}
+		entered[field.Name] = raw
+		value, err := field.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if filter == nil {
+			filter = models.Metadata{}
+		}
+		filter[field.Name] = value
+	}
+	return filter, entered
+}
+
+// fieldValues formats an event's custom field values the way they are typed
+// into forms
+func fieldValues(fields []models.CustomField, metadata models.Metadata) map[string]string {
+	values := make(map[string]string)
+	for _, field := range fields {
+		if value, ok := metadata[field.Name]; ok {
+			values[field.Name] = field.Format(value)
+		}
+	}
+	return values
+}
+
+// parseFields reads and validates the custom field inputs of the event form.
+// Empty inputs leave the field unset.
+func (f *eventForm) parseFields(r *http.Request, fields []models.CustomField) {
+	f.Fields = make(map[string]string)
+	for _, field := range fields {
+		raw := strings.TrimSpace(r.FormValue(fieldParamPrefix + field.Name))
+		if raw == "" {
+			continue
+		}
+		f.Fields[field.Name] = raw
+		value, err := field.Parse(raw)
+		if err != nil {
+			f.Errors[fieldParamPrefix+field.Name] = err.Error()
+			continue
+		}
+		if f.Metadata == nil {
+			f.Metadata = models.Metadata{}
+		}
+		f.Metadata[field.Name] = value
+	}
+}
+
+// HandleAdminFields lists the custom fields, with a form for defining one
+func (h *WebHandler) HandleAdminFields(w http.ResponseWriter, r *http.Request) {
+	fields, err := h.db.ListCustomFields()
+	if err != nil {
+		log.Printf("Error fetching custom fields: %v", err)
+		http.Error(w, "Error fetching custom fields", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:         auth.GetUserFromContext(r.Context()),
+		CustomFields: fields,
+		FieldTypes:   models.FieldTypes,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "fields.html", data)
+}
+
+// HandleCreateFieldPost defines a custom field
+func (h *WebHandler) HandleCreateFieldPost(w http.ResponseWriter, r *http.Request) {
+	field := &models.CustomField{
+		Name:    strings.TrimSpace(r.FormValue("name")),
+		Label:   r.FormValue("label"),
+		Type:    r.FormValue("type"),
+		Options: strings.Split(r.FormValue("options"), ","),
+	}
+	if err := field.Validate(); err != nil {
+		h.setFlash(w, "Invalid field: "+err.Error(), "error")
+		http.Redirect(w, r, "/admin/fields", http.StatusSeeOther)
+		return
+	}
+
+	err := h.db.CreateCustomField(field)
+	switch {
+	case errors.Is(err, database.ErrFieldExists):
+		h.setFlash(w, fmt.Sprintf("A field named %q already exists", field.Name), "error")
+	case err != nil:
+		log.Printf("Error creating custom field: %v", err)
+		h.setFlash(w, "Error creating field", "error")
+	default:
+		h.setFlash(w, fmt.Sprintf("Created field %q", field.Label), "success")
+	}
+	http.Redirect(w, r, "/admin/fields", http.StatusSeeOther)
+}
+
+// HandleDeleteFieldPost removes a custom field and its values from every event
+func (h *WebHandler) HandleDeleteFieldPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid field ID", http.StatusBadRequest)
+		return
+	}
+
+	field, err := h.db.DeleteCustomField(id)
+	switch {
+	case err != nil:
+		log.Printf("Error deleting custom field %d: %v", id, err)
+		h.setFlash(w, "Error deleting field", "error")
+	case field == nil:
+		http.Error(w, "Field not found", http.StatusNotFound)
+		return
+	default:
+		h.setFlash(w, fmt.Sprintf("Deleted field %q", field.Label), "success")
+	}
+	http.Redirect(w, r, "/admin/fields", http.StatusSeeOther)
+}
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"encoding/json"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Limits enforced on events submitted through the web forms
+const (
+	maxEventDataSize = 1 << 20 // 1 MiB
+	maxEventTags     = 20
+	maxTagLength     = 64
+	maxSourceLength  = 255
+)
+
+// tagSuggestionLimit is the number of tags returned by the autocomplete endpoint
+const tagSuggestionLimit = 10
+
+// eventForm holds submitted event form values and any validation errors
+type eventForm struct {
+	Data      string
+	Tags      []string
+	Source    string
+	ProjectID int64
+	Fields    map[string]string // Custom field values as entered
+	Metadata  models.Metadata   // Custom field values that passed validation
+	Errors    map[string]string // Field name to error message
+}
+
+// parseEventForm reads and validates the event form. Tag values from every
+// "tags" field are combined, split on commas and de-duplicated.
+func parseEventForm(r *http.Request) *eventForm {
+	form := &eventForm{
+		Data:   r.FormValue("data"),
+		Source: strings.TrimSpace(r.FormValue("source")),
+		Errors: make(map[string]string),
+	}
+
+	seen := make(map[string]bool)
+	for _, value := range r.Form["tags"] {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				form.Tags = append(form.Tags, tag)
+			}
+		}
+	}
+
+	if strings.TrimSpace(form.Data) == "" {
+		form.Errors["data"] = "Event data is required"
+	} else if len(form.Data) > maxEventDataSize {
+		form.Errors["data"] = fmt.Sprintf("Event data is %d KiB; the limit is %d KiB", len(form.Data)/1024, maxEventDataSize/1024)
+	}
+
+	if len(form.Tags) > maxEventTags {
+		form.Errors["tags"] = fmt.Sprintf("At most %d tags are allowed, got %d", maxEventTags, len(form.Tags))
+	} else {
+		for _, tag := range form.Tags {
+			if len(tag) > maxTagLength {
+				form.Errors["tags"] = fmt.Sprintf("Tag %q is longer than %d characters", tag[:20]+"...", maxTagLength)
+				break
+			}
+		}
+	}
+
+	if len(form.Source) > maxSourceLength {
+		form.Errors["source"] = fmt.Sprintf("Source must be at most %d characters", maxSourceLength)
+	}
+
+	return form
+}
+
+// Valid reports whether the form passed validation
+func (f *eventForm) Valid() bool {
+	return len(f.Errors) == 0
+}
+
+// TagList returns the tags as a comma-separated list for re-filling the form
+func (f *eventForm) TagList() string {
+	return strings.Join(f.Tags, ", ")
+}
+
+// HandleTagSuggestions returns existing tags matching the q parameter as JSON,
+// prefix matches first
+func (h *WebHandler) HandleTagSuggestions(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+
+	tags, err := h.db.GetAllTags()
+	if err != nil {
+		log.Printf("Error fetching tags for suggestions: %v", err)
+		http.Error(w, "Error fetching tags", http.StatusInternalServerError)
+		return
+	}
+
+	var prefix, contains []string
+	for _, tag := range tags {
+		lower := strings.ToLower(tag)
+		switch {
+		case strings.HasPrefix(lower, q):
+			prefix = append(prefix, tag)
+		case strings.Contains(lower, q):
+			contains = append(contains, tag)
+		}
+	}
+	sort.Strings(prefix)
+	sort.Strings(contains)
+
+	suggestions := append(prefix, contains...)
+	if len(suggestions) > tagSuggestionLimit {
+		suggestions = suggestions[:tagSuggestionLimit]
+	}
+	if suggestions == nil {
+		suggestions = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(suggestions); err != nil {
+		log.Printf("Error encoding tag suggestions: %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/feed"
+	"example-api/internal/geocode"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// mapEventLimit is how many events the map shows at most, newest first
+	mapEventLimit = 1000
+	// mapMargin is how far, in degrees, the map of an event's surroundings
+	// reaches from it
+	mapMargin = 0.5
+)
+
+// mapPoint is an event placed on the map
+type mapPoint struct {
+	ID        int64   `json:"id"`
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lon"`
+	Title     string  `json:"title"`
+	Location  string  `json:"location"`
+	Status    string  `json:"status"`
+	Overdue   bool    `json:"overdue"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// mapView is the event map's settings and the events placed on it
+type mapView struct {
+	URL         string // Map of the events listed, or on the map page, of them anywhere
+	EventURL    string // Map around the event shown
+	ListURL     string // Events list of the events mapped
+	Tiles       string
+	Attribution string
+	Points      []mapPoint
+	Total       int // Located events matching the filters, of which Points are the newest
+}
+
+// SetGeocoder sets the geocoder for locations entered without coordinates;
+// nil leaves them without
+func (h *WebHandler) SetGeocoder(geocoder geocode.Geocoder) {
+	h.geocoder = geocoder
+}
+
+// SetMap sets the tiles of the event map, a URL with {z}, {x} and {y}
+// placeholders, and the attribution their provider asks for
+func (h *WebHandler) SetMap(tiles, attribution string) {
+	h.mapTiles, h.mapAttribution = tiles, attribution
+}
+
+// HandleMap shows the located events matching the events list filters on a
+// map. A bbox parameter keeps those inside an area.
+func (h *WebHandler) HandleMap(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	prefs := h.preferences(user)
+
+	filter := parseEventFilter(r, user, prefs.Location())
+	filter.Metadata, _ = fieldFilter(r, h.customFields())
+	access := h.projectAccess(user)
+	scopeFilter(r, &filter, access, access.Visible(h.projects()))
+	filter.Located = true
+	filter.SortBy, filter.SortDesc = "created_at", true
+	filter.Limit = mapEventLimit
+
+	events, total, err := h.db.ListEvents(filter)
+	if err != nil {
+		log.Printf("Error fetching events for the map: %v", err)
+		http.Error(w, "Error fetching events", http.StatusInternalServerError)
+		return
+	}
+	localizeEvents(events, prefs.Location())
+
+	data := TemplateData{
+		User:        user,
+		Events:      events,
+		Preferences: prefs,
+		Theme:       prefs.Theme,
+	}
+	data.Filter.Encoded = cleanFilterQuery(r.URL.RawQuery)
+	data.Map.ListURL = "/?" + data.Filter.Encoded
+	if filter.BBox != nil {
+		data.Filter.BBox = filter.BBox.String()
+		everywhere := r.URL.Query()
+		everywhere.Del("bbox")
+		data.Map.URL = "/map?" + everywhere.Encode()
+	}
+	data.Map.Tiles, data.Map.Attribution = h.mapTiles, h.mapAttribution
+	data.Map.Total = total
+	data.Map.Points = make([]mapPoint, 0, len(events))
+	for _, event := range events {
+		data.Map.Points = append(data.Map.Points, mapPoint{
+			ID:        event.ID,
+			Latitude:  *event.Latitude,
+			Longitude: *event.Longitude,
+			Title:     feed.EventTitle(event),
+			Location:  event.Location,
+			Status:    event.Status,
+			Overdue:   event.Overdue(),
+			CreatedAt: event.CreatedAt.Format("2006-01-02 15:04"),
+		})
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "map.html", data)
+}
+
+// mapURL returns the map of the events matching the current filters
+func mapURL(r *http.Request) string {
+	query := r.URL.Query()
+	query.Del("page")
+	return "/map?" + query.Encode()
+}
+
+// eventMapURL returns the map of the events around an event's coordinates
+func eventMapURL(event *models.Event) string {
+	if !event.Located() {
+		return ""
+	}
+	return "/map?bbox=" + models.Around(*event.Latitude, *event.Longitude, mapMargin).String()
+}
+
+// HandleEventLocationPost sets or clears where an event happened and records
+// the change in its audit trail. A location entered without coordinates is
+// geocoded.
+func (h *WebHandler) HandleEventLocationPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	event, ok := h.editableEvent(w, r, user)
+	if !ok {
+		return
+	}
+	eventURL := fmt.Sprintf("/events/%d", event.ID)
+
+	var req models.SetLocationRequest
+	if r.FormValue("clear") == "" {
+		req.Location = r.FormValue("location")
+		var err error
+		req.Latitude, err = parseDegrees(r.FormValue("latitude"))
+		if err == nil {
+			req.Longitude, err = parseDegrees(r.FormValue("longitude"))
+		}
+		if err == nil {
+			err = req.Validate()
+		}
+		if err != nil {
+			h.setFlash(w, "Invalid location: "+err.Error(), "error")
+			http.Redirect(w, r, eventURL, http.StatusSeeOther)
+			return
+		}
+	}
+
+	geocoded := true
+	if req.Latitude == nil && req.Location != "" {
+		var err error
+		if req.Latitude, req.Longitude, err = geocode.Locate(h.geocoder, req.Location); err != nil {
+			log.Printf("Error geocoding %q for event %d: %v", req.Location, event.ID, err)
+		}
+		geocoded = req.Latitude != nil
+	}
+
+	found, err := h.db.SetEventLocation(event.ID, req.Location, req.Latitude, req.Longitude)
+	switch {
+	case err != nil:
+		log.Printf("Error setting location of event %d: %v", event.ID, err)
+		h.setFlash(w, "Error changing location", "error")
+	case !found:
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	default:
+		updated := *event
+		updated.Location, updated.Latitude, updated.Longitude = req.Location, req.Latitude, req.Longitude
+		h.recordEventAudit(r, models.EventEdited, event.ID, event, &updated)
+		switch {
+		case updated.Place() == "":
+			h.setFlash(w, "Location cleared", "success")
+		case !geocoded:
+			h.setFlash(w, "Location set, but it couldn't be found on the map; enter its coordinates to place it", "success")
+		default:
+			h.setFlash(w, "Location set to "+updated.Place(), "success")
+		}
+	}
+	http.Redirect(w, r, eventURL, http.StatusSeeOther)
+}
+
+// parseDegrees reads a latitude or longitude typed into a form, nil when
+// left empty
+func parseDegrees(value string) (*float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	degrees, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("coordinates must be numbers of degrees, such as 52.37")
+	}
+	return &degrees, nil
+}
@@ -1,9 +1,17 @@
 package web
 
 import (
+	"bytes"
+	"example-api/internal/alert"
 	"example-api/internal/auth"
+	"example-api/internal/cache"
 	"example-api/internal/database"
+	"example-api/internal/geocode"
+	"example-api/internal/mailer"
 	"example-api/internal/models"
+	"example-api/internal/monitor"
+	"example-api/internal/signing"
+	"example-api/internal/sso"
 	"fmt"
 	"html/template"
 	"log"
@@ -13,6 +21,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -22,9 +31,44 @@ import (
 type WebHandler struct {
 	db         *database.Database
 	auth       *auth.Auth
-	templates  *template.Template
+	pages      map[string]*template.Template // Page templates by file name, each with the base layout
 	apiToken   string
 	sessionMap map[string]string // Used to store flash messages between requests
+
+	// Where the page templates were loaded from, to parse them again for
+	// every page when reloadTemplates is set
+	templatesDir    string
+	funcMap         template.FuncMap
+	reloadTemplates bool
+
+	// Self-service registration
+	allowRegistration bool
+	inviteExpiry      time.Duration
+	mailer            *mailer.Mailer
+
+	adminAllowlist *auth.IPAllowlist
+	saml           *sso.SAMLProvider
+	alerts         *alert.Notifier // Sends test notifications for alert rules
+
+	displayMu       sync.RWMutex // Guards markdownSources, which can be reloaded
+	markdownSources map[string]bool
+	linkSigner      *signing.Signer
+
+	// Where the dashboard's aggregates are cached, and for how long
+	statsCache cache.Cache
+	statsTTL   time.Duration
+
+	// Generated addresses of email mappings
+	mappingDomain string
+	mappingLength int
+
+	geocoder       geocode.Geocoder // Locates events given a location without coordinates
+	mapTiles       string           // Tile URL of the event map
+	mapAttribution string
+
+	monitors []*monitor.Monitor // Heartbeat monitors shown on the dashboard
+
+	imports importJobs // Files uploaded for import
 }
 
 // TemplateData contains data passed to templates
@@ -32,27 +76,131 @@ type TemplateData struct {
 	User         *auth.User
 	Events       []models.Event
 	Event        *models.Event
+	DataFormat   string
+	RenderedData template.HTML
+	RawData      string
+	BlockedImages int
+	ImagesURL    string
 	RelatedEvents []models.Event
+	Attachments  []models.Attachment
+	Thread       []models.Event
+	Incident     *models.Incident  // Incident of the event shown, or the one the list is filtered by
+	Incidents    []models.Incident
 	RecentEvents []models.Event
 	Tags         []string
 	Sources      []string
+	AuditEntries []models.AuditEntry
+	EventLogs    []models.EventLog
+	EventActivity []models.EventAuditEntry
+	Comments     []models.EventComment
+	Links        []models.EventLink // Links from and to the event shown
+	LinkTypes    []string
+	EventStatuses []string
+	LogStatuses  []models.NameCount
+	Sessions     []auth.Session
+	Users        []auth.User
+	CurrentSession string
+	Invites      []models.InviteCode
+	Mappings     []models.EmailMapping
+	MailDomain   string
+	TagCounts    []models.NameCount
+	Quarantine   []models.QuarantinedEvent
+	AlertRules   []models.AlertRule
+	AlertChannels []string
+	Expectations []models.Expectation
+	CustomFields []models.CustomField
+	FieldTypes   []string
+	FieldValues  map[string]string // Custom field values of the event shown, as typed into forms
+	Labels       []models.Label
+	LabelsByName map[string]models.Label // For showing the labels of events
+	Projects     []models.Project
+	ProjectNames map[int64]string // Names of the projects events are in, when there is more than one
+	Project      *models.Project
+	ProjectMembers []models.ProjectMember
+	ProjectRoles []string
+	APITokens    []models.APIToken
+	NewToken     string // API token just created, shown once
+	Organizations []models.Organization
+	Organization *models.Organization
+	OrganizationMembers []models.OrganizationMember
+	OrganizationRoles []string
+	MemberRoles  map[int64]string // User's role in each organization they belong to
+	Usage        *models.OrganizationUsage
+	Monitors     []models.MonitorStatus
+	Anomalies    []models.Anomaly // Of the last day, on the dashboard
+	Import       *importPage
+	RegistrationEnabled bool
+	SSOEnabled   bool
 	Stats        struct {
 		TotalEvents  int
 		UniqueTags   int
 		RecentEvents int
+		EventsPerDay []models.DailyCount
+		TopTags      []models.NameCount
+		TopSources   []models.NameCount
+		Database     *models.DatabaseStats // admins only
 	}
 	Filter     struct {
-		Tag    string
-		Date   string
-		Source string
+		Tags     []string
+		MatchAll bool
+		DateFrom string
+		DateTo   string
+		Source   string
+		Query    string
+		Starred  bool
+		Fields   map[string]string // Custom field filters as entered
+		Project  string
+		Action   string
+		Status   string
+		Due      string
+		Label    string
+		BBox     string // Area as west,south,east,north
+		Incident int64
+		Expanded bool   // Every event is listed rather than one per incident
+		GroupURL string // The list with incidents expanded, or collapsed when they are
+		Encoded  string // Current filters as a query string
+	}
+	Export struct {
+		CSVURL  string
+		JSONURL string
+	}
+	Map   mapView
+	Feeds []FeedLink
+	Share struct {
+		ReadOnly bool      // Page is being viewed through a share link
+		URL      string    // Newly created share link
+		Expires  time.Time
+	}
+	Bulk struct {
+		Action      string
+		Description string
+		Tags        string
+		ReturnURL   string
+	}
+	Sort struct {
+		Field string
+		Desc  bool
+		Links map[string]string
 	}
 	Pagination struct {
 		CurrentPage  int
 		TotalPages   int
 		TotalItems   int
 		ItemsPerPage int
+		FirstItem    int
+		LastItem     int
+		PrevURL      string
+		NextURL      string
+		Pages        []PageLink
 	}
 	FlashMessage string
+	CSRFToken    string
+	Preferences  *models.UserPreferences
+	Location     *time.Location // User's time zone, for the local template function
+	SavedSearches []models.SavedSearch
+	Form         *eventForm
+	PageSizes    []int
+	Theme        string
 	FlashType    string
 }
 
@@ -80,54 +228,46 @@ func NewWebHandler(db *database.Database, auth *auth.Auth, apiToken string) (*We
 			return s[start:end]
 		},
 		"now": time.Now,
+		"contains": func(list []string, s string) bool {
+			for _, item := range list {
+				if strings.EqualFold(item, s) {
+					return true
+				}
+			}
+			return false
+		},
+		"filesize": formatFileSize,
+		"local": func(t time.Time, loc *time.Location) time.Time {
+			if loc == nil {
+				return t
+			}
+			return t.In(loc)
+		},
 	}
 	
-	// Initialize templates with simple approach
-	tmpl := template.New("").Funcs(funcMap)
-	
-	// Define template directories to scan
-	templateRootDir := filepath.Join(workingDir, "templates")
-	log.Printf("Loading templates from: %s", templateRootDir)
-	
-	// Find all template files recursively
-	var templateFiles []string
-	
-	// Helper function to walk through directories and find template files
-	err = filepath.Walk(templateRootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && strings.HasSuffix(path, ".html") {
-			log.Printf("Found template: %s", path)
-			templateFiles = append(templateFiles, path)
-		}
-		return nil
-	})
-	
-	if err != nil {
-		return nil, fmt.Errorf("error scanning templates: %w", err)
-	}
-	
-	// Parse all templates at once
-	tmpl, err = tmpl.ParseFiles(templateFiles...)
+	templatesDir := filepath.Join(workingDir, "templates")
+	log.Printf("Loading templates from: %s", templatesDir)
+	pages, err := loadTemplates(templatesDir, funcMap)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing templates: %w", err)
-	}
-	
-	// List all templates that were loaded
-	for _, t := range tmpl.Templates() {
-		log.Printf("Template loaded: %s", t.Name())
+		return nil, err
 	}
 
 	return &WebHandler{
-		db:         db,
-		auth:       auth,
-		templates:  tmpl,
+		db:           db,
+		auth:         auth,
+		pages:        pages,
+		templatesDir: templatesDir,
+		funcMap:      funcMap,
 		apiToken:   apiToken,
 		sessionMap: make(map[string]string),
 	}, nil
 }
 
+// SetAdminAllowlist restricts /admin/* routes to the given address ranges
+func (h *WebHandler) SetAdminAllowlist(list *auth.IPAllowlist) {
+	h.adminAllowlist = list
+}
+
 // SetupRoutes configures the routes for the web interface
 func (h *WebHandler) SetupRoutes(r *mux.Router) {
 	// Serve static files
@@ -138,6 +278,20 @@ func (h *WebHandler) SetupRoutes(r *mux.Router) {
 	r.HandleFunc("/login", h.HandleLogin).Methods("GET")
 	r.HandleFunc("/login", h.HandleLoginPost).Methods("POST")
 	r.HandleFunc("/logout", h.HandleLogout).Methods("GET", "POST")
+	r.HandleFunc("/register", h.HandleRegister).Methods("GET")
+	r.HandleFunc("/register", h.HandleRegisterPost).Methods("POST")
+	r.HandleFunc("/verify", h.HandleVerifyEmail).Methods("GET")
+	r.HandleFunc("/share/events/{id}", h.HandleSharedEvent).Methods("GET")
+	r.HandleFunc("/feeds/tag/{tag}.atom", h.HandleTagFeed).Methods("GET")
+	r.HandleFunc("/feeds/source/{source}.atom", h.HandleSourceFeed).Methods("GET")
+	r.HandleFunc("/feeds/events.ics", h.HandleCalendarFeed).Methods("GET")
+
+	// SAML single sign-on routes
+	if h.saml != nil {
+		r.HandleFunc("/saml/metadata", h.saml.ServeMetadata).Methods("GET")
+		r.HandleFunc("/saml/login", h.saml.StartLogin).Methods("GET")
+		r.HandleFunc("/saml/acs", h.HandleSAMLACS).Methods("POST")
+	}
 
 	// Root route handler - will show welcome page when logged out, events when logged in
 	r.HandleFunc("/", h.HandleRoot).Methods("GET")
@@ -148,29 +302,144 @@ func (h *WebHandler) SetupRoutes(r *mux.Router) {
 	// Debug routes
 	r.HandleFunc("/debug/auth", h.HandleAuthDebug).Methods("GET")
 	
+	// Import uploads get their own chain so their size is limited before
+	// RequireCSRF reads the form
+	upload := r.Path("/events/import").Methods("POST").Subrouter()
+	upload.Use(h.limitImportUpload, h.auth.RequireAuth, auth.RequireCSRF, h.adminAllowlist.Middleware, h.auth.RequireAdmin)
+	upload.HandleFunc("", h.HandleImportUploadPost)
+
 	// Protected routes
 	protected := r.NewRoute().Subrouter()
 	protected.Use(h.auth.RequireAuth)
+	protected.Use(auth.RequireCSRF)
+	protected.HandleFunc("/dashboard", h.HandleDashboard).Methods("GET")
+	protected.HandleFunc("/events/export", h.HandleExportEvents).Methods("GET")
+	protected.HandleFunc("/map", h.HandleMap).Methods("GET")
+	protected.HandleFunc("/events/bulk", h.HandleBulkEvents).Methods("POST")
+	protected.HandleFunc("/events/tags", h.HandleTagSuggestions).Methods("GET")
 	protected.HandleFunc("/events/new", h.HandleCreateEvent).Methods("GET")
 	protected.HandleFunc("/events/new", h.HandleCreateEventPost).Methods("POST")
+	// Imports are for admins, and registered before /events/{id} would take them
+	imports := protected.PathPrefix("/events/import").Subrouter()
+	imports.Use(h.adminAllowlist.Middleware)
+	imports.Use(h.auth.RequireAdmin)
+	imports.HandleFunc("", h.HandleImport).Methods("GET")
+	imports.HandleFunc("/{token}", h.HandleImportJob).Methods("GET")
+	imports.HandleFunc("/{token}/preview", h.HandleImportPreviewPost).Methods("POST")
+	imports.HandleFunc("/{token}/start", h.HandleImportStartPost).Methods("POST")
 	protected.HandleFunc("/events/{id}", h.HandleViewEvent).Methods("GET")
 	protected.HandleFunc("/events/{id}/edit", h.HandleEditEvent).Methods("GET")
 	protected.HandleFunc("/events/{id}/edit", h.HandleEditEventPost).Methods("POST")
-	protected.HandleFunc("/events/{id}/delete", h.HandleDeleteEvent).Methods("GET")
+	protected.HandleFunc("/events/{id}/delete", h.HandleDeleteEvent).Methods("POST")
+	protected.HandleFunc("/events/{id}/share", h.HandleShareEventPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/star", h.HandleStarEventPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/thread", h.HandleViewThread).Methods("GET")
+	protected.HandleFunc("/incidents", h.HandleIncidents).Methods("GET")
+	protected.HandleFunc("/events/{id}/comments", h.HandleCommentPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/comments/{commentID}/delete", h.HandleDeleteCommentPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/status", h.HandleEventStatusPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/due", h.HandleEventDuePost).Methods("POST")
+	protected.HandleFunc("/events/{id}/labels", h.HandleEventLabelPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/location", h.HandleEventLocationPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/labels/{label}/delete", h.HandleRemoveEventLabelPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/links", h.HandleLinkPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/links/{linkID}/delete", h.HandleDeleteLinkPost).Methods("POST")
+	protected.HandleFunc("/events/{id}/attachments/{attachmentID}", h.HandleDownloadAttachment).Methods("GET")
+	protected.HandleFunc("/mappings", h.HandleMappings).Methods("GET")
+	protected.HandleFunc("/mappings", h.HandleCreateMappingPost).Methods("POST")
+	protected.HandleFunc("/mappings/{id}/toggle", h.HandleToggleMappingPost).Methods("POST")
+	protected.HandleFunc("/mappings/{id}/delete", h.HandleDeleteMappingPost).Methods("POST")
+	protected.HandleFunc("/profile", h.HandleProfile).Methods("GET")
+	protected.HandleFunc("/settings", h.HandleSettings).Methods("GET")
+	protected.HandleFunc("/settings", h.HandleSettingsPost).Methods("POST")
+	protected.HandleFunc("/settings/default-filter", h.HandleSaveDefaultFilterPost).Methods("POST")
+	protected.HandleFunc("/searches", h.HandleSaveSearchPost).Methods("POST")
+	protected.HandleFunc("/searches/{id}/default", h.HandleDefaultSearchPost).Methods("POST")
+	protected.HandleFunc("/searches/{id}/delete", h.HandleDeleteSearchPost).Methods("POST")
+	protected.HandleFunc("/profile/sessions/revoke", h.HandleRevokeSession).Methods("POST")
+	protected.HandleFunc("/profile/sessions/revoke-all", h.HandleRevokeAllSessions).Methods("POST")
+
+	// Projects and organizations, managed by admins and organization admins
+	protected.HandleFunc("/projects/{id}", h.HandleProject).Methods("GET")
+	protected.HandleFunc("/projects/{id}/delete", h.HandleDeleteProjectPost).Methods("POST")
+	protected.HandleFunc("/projects/{id}/members", h.HandleSetProjectMemberPost).Methods("POST")
+	protected.HandleFunc("/projects/{id}/members/{username}/delete", h.HandleRemoveProjectMemberPost).Methods("POST")
+	protected.HandleFunc("/projects/{id}/tokens", h.HandleCreateAPITokenPost).Methods("POST")
+	protected.HandleFunc("/projects/{id}/tokens/{tokenID}/delete", h.HandleDeleteAPITokenPost).Methods("POST")
+	protected.HandleFunc("/organizations/{id}", h.HandleOrganization).Methods("GET")
+	protected.HandleFunc("/organizations/{id}/projects", h.HandleCreateOrganizationProjectPost).Methods("POST")
+	protected.HandleFunc("/organizations/{id}/members", h.HandleSetOrganizationMemberPost).Methods("POST")
+	protected.HandleFunc("/organizations/{id}/members/{username}/delete", h.HandleRemoveOrganizationMemberPost).Methods("POST")
+	protected.HandleFunc("/organizations/{id}/tokens", h.HandleCreateOrganizationTokenPost).Methods("POST")
+	protected.HandleFunc("/organizations/{id}/tokens/{tokenID}/delete", h.HandleDeleteOrganizationTokenPost).Methods("POST")
+
+	// Admin routes
+	admin := protected.PathPrefix("/admin").Subrouter()
+	admin.Use(h.adminAllowlist.Middleware)
+	admin.Use(h.auth.RequireAdmin)
+	admin.HandleFunc("/audit", h.HandleAuditLog).Methods("GET")
+	admin.HandleFunc("/logs", h.HandleEventLogs).Methods("GET")
+	admin.HandleFunc("/activity", h.HandleEventActivity).Methods("GET")
+	admin.HandleFunc("/events/{id}/restore", h.HandleRestoreEventPost).Methods("POST")
+	admin.HandleFunc("/users", h.HandleAdminUsers).Methods("GET")
+	admin.HandleFunc("/users/{id}/logout", h.HandleForceLogout).Methods("POST")
+	admin.HandleFunc("/invites", h.HandleAdminInvites).Methods("GET")
+	admin.HandleFunc("/invites", h.HandleCreateInvitePost).Methods("POST")
+	admin.HandleFunc("/tags", h.HandleAdminTags).Methods("GET")
+	admin.HandleFunc("/tags/rename", h.HandleRenameTagPost).Methods("POST")
+	admin.HandleFunc("/tags/merge", h.HandleMergeTagsPost).Methods("POST")
+	admin.HandleFunc("/tags/delete", h.HandleDeleteTagPost).Methods("POST")
+	admin.HandleFunc("/quarantine", h.HandleAdminQuarantine).Methods("GET")
+	admin.HandleFunc("/quarantine/{id}/release", h.HandleReleaseQuarantinedPost).Methods("POST")
+	admin.HandleFunc("/quarantine/{id}/delete", h.HandleDeleteQuarantinedPost).Methods("POST")
+	admin.HandleFunc("/alerts", h.HandleAdminAlerts).Methods("GET")
+	admin.HandleFunc("/alerts", h.HandleCreateAlertPost).Methods("POST")
+	admin.HandleFunc("/alerts/{id}/toggle", h.HandleToggleAlertPost).Methods("POST")
+	admin.HandleFunc("/alerts/{id}/test", h.HandleTestAlertPost).Methods("POST")
+	admin.HandleFunc("/alerts/{id}/delete", h.HandleDeleteAlertPost).Methods("POST")
+	admin.HandleFunc("/expectations", h.HandleAdminExpectations).Methods("GET")
+	admin.HandleFunc("/expectations", h.HandleCreateExpectationPost).Methods("POST")
+	admin.HandleFunc("/expectations/{id}/toggle", h.HandleToggleExpectationPost).Methods("POST")
+	admin.HandleFunc("/expectations/{id}/test", h.HandleTestExpectationPost).Methods("POST")
+	admin.HandleFunc("/expectations/{id}/delete", h.HandleDeleteExpectationPost).Methods("POST")
+	admin.HandleFunc("/fields", h.HandleAdminFields).Methods("GET")
+	admin.HandleFunc("/fields", h.HandleCreateFieldPost).Methods("POST")
+	admin.HandleFunc("/fields/{id}/delete", h.HandleDeleteFieldPost).Methods("POST")
+	admin.HandleFunc("/labels", h.HandleAdminLabels).Methods("GET")
+	admin.HandleFunc("/labels", h.HandleCreateLabelPost).Methods("POST")
+	admin.HandleFunc("/labels/{id}", h.HandleUpdateLabelPost).Methods("POST")
+	admin.HandleFunc("/labels/{id}/delete", h.HandleDeleteLabelPost).Methods("POST")
+	admin.HandleFunc("/projects", h.HandleAdminProjects).Methods("GET")
+	admin.HandleFunc("/projects", h.HandleCreateProjectPost).Methods("POST")
+	admin.HandleFunc("/organizations", h.HandleAdminOrganizations).Methods("GET")
+	admin.HandleFunc("/organizations", h.HandleCreateOrganizationPost).Methods("POST")
+	admin.HandleFunc("/organizations/{id}/delete", h.HandleDeleteOrganizationPost).Methods("POST")
 }
 
 // renderTemplate is a helper function to render templates with proper content
 func (h *WebHandler) renderTemplate(w http.ResponseWriter, name string, data TemplateData) {
-	// Set content type for all templates
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	
-	// Execute the named template
-	err := h.templates.ExecuteTemplate(w, name, data)
-	
+	h.renderTemplateStatus(w, http.StatusOK, name, data)
+}
+
+// renderTemplateStatus renders a page inside the base layout with the given status code
+func (h *WebHandler) renderTemplateStatus(w http.ResponseWriter, status int, name string, data TemplateData) {
+	page, err := h.page(name)
 	if err != nil {
-		log.Printf("Error rendering template %s: %v", name, err)
-		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+		h.templateError(w, name, err)
+		return
 	}
+	
+	// Render into a buffer so a failed template doesn't send half a page
+	var buf bytes.Buffer
+	if err := page.ExecuteTemplate(&buf, "base", data); err != nil {
+		h.templateError(w, name, err)
+		return
+	}
+	
+	// Set content type for all templates
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
 }
 
 // HandleLogin handles the login page
@@ -195,23 +464,23 @@ func (h *WebHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		log.Printf("No session cookie found: %v", err)
 	}
 	
-	data := TemplateData{}
+	data := TemplateData{
+		RegistrationEnabled: h.allowRegistration,
+		SSOEnabled:          h.saml != nil,
+	}
 	
 	// Set data properties if needed
 	if msg, ok := r.URL.Query()["error"]; ok && len(msg) > 0 {
 		data.FlashMessage = msg[0]
 		data.FlashType = "error"
+	} else if msg, ok := r.URL.Query()["message"]; ok && len(msg) > 0 {
+		data.FlashMessage = msg[0]
+		data.FlashType = "info"
 	}
 	
-	// Set content type
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	h.preparePage(w, r, &data)
 	
-	// Execute template directly
-	err := h.templates.ExecuteTemplate(w, "login.html", data)
-	if err != nil {
-		log.Printf("Error rendering login template: %v", err)
-		http.Error(w, "Error rendering template", http.StatusInternalServerError)
-	}
+	h.renderTemplate(w, "login.html", data)
 }
 
 // HandleLoginPost handles the login form submission
@@ -225,13 +494,14 @@ func (h *WebHandler) HandleLoginPost(w http.ResponseWriter, r *http.Request) {
 	user, err := h.auth.Authenticate(username, password)
 	if err != nil {
 		log.Printf("Authentication failed for user '%s': %v", username, err)
+		h.auth.RecordAudit(auth.AuditLoginFailed, username, username, auth.ClientIP(r), err.Error())
 		// Redirect back to login with error
 		http.Redirect(w, r, "/login?error=Invalid+username+or+password.+Please+try+again.", http.StatusSeeOther)
 		return
 	}
 
 	log.Printf("Authentication successful for user: %s (ID: %d)", user.Username, user.ID)
-	session, err := h.auth.CreateSession(user.ID)
+	session, err := h.auth.CreateSession(user.ID, auth.ClientIP(r), r.UserAgent())
 	if err != nil {
 		log.Printf("Failed to create session for user %s: %v", user.Username, err)
 		http.Redirect(w, r, "/login?error=Failed+to+create+session.+Please+try+again+later.", http.StatusSeeOther)
@@ -239,7 +509,8 @@ func (h *WebHandler) HandleLoginPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Created session ID: %s for user: %s (expires: %v)", session.ID, user.Username, session.ExpiresAt)
-	auth.SetSessionCookie(w, session)
+	h.auth.RecordAudit(auth.AuditLogin, user.Username, user.Username, auth.ClientIP(r), "")
+	auth.SetSessionCookie(w, r, session)
 	log.Printf("Set session cookie and redirecting to home page")
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -251,6 +522,11 @@ func (h *WebHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	// Handle both GET and POST requests for logout
 	if cookie, err := r.Cookie("session"); err == nil {
 		log.Printf("Found session cookie to delete: %s", cookie.Value)
+		if session, err := h.auth.GetSession(cookie.Value); err == nil {
+			if user, err := h.auth.GetUserByID(session.UserID); err == nil {
+				h.auth.RecordAudit(auth.AuditLogout, user.Username, user.Username, auth.ClientIP(r), "")
+			}
+		}
 		h.auth.DeleteSession(cookie.Value)
 		log.Printf("Session deleted: %s", cookie.Value)
 	} else {
@@ -297,15 +573,9 @@ func (h *WebHandler) HandleRoot(w http.ResponseWriter, r *http.Request) {
 	// User is not logged in, show welcome page
 	data := TemplateData{}
 	
-	// Set content type
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	h.preparePage(w, r, &data)
 	
-	// Execute template
-	err := h.templates.ExecuteTemplate(w, "index.html", data)
-	if err != nil {
-		log.Printf("Error rendering index template: %v", err)
-		http.Error(w, "Error rendering template", http.StatusInternalServerError)
-	}
+	h.renderTemplate(w, "index.html", data)
 }
 
 // HandleEventsRedirect redirects /events to root while preserving query parameters
@@ -313,6 +583,9 @@ func (h *WebHandler) HandleEventsRedirect(w http.ResponseWriter, r *http.Request
 	http.Redirect(w, r, "/?"+r.URL.RawQuery, http.StatusSeeOther)
 }
 
+// relatedEventsLimit is the number of related events shown on the event page
+const relatedEventsLimit = 10
+
 // HandleViewEvent displays a single event
 func (h *WebHandler) HandleViewEvent(w http.ResponseWriter, r *http.Request) {
 	// Get the event ID from the URL
@@ -333,34 +606,176 @@ func (h *WebHandler) HandleViewEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	if event == nil {
+	// Events of projects the user isn't in don't exist as far as they know
+	if event == nil || !h.projectAccess(auth.GetUserFromContext(r.Context())).CanView(event.ProjectID) {
 		http.Error(w, "Event not found", http.StatusNotFound)
 		return
 	}
 	
-	// Prepare template data
-	data := TemplateData{
-		Event: event,
+	h.renderEventView(w, r, event, TemplateData{})
+}
+
+// renderEventView renders the event detail page. Pages opened through a share
+// link are read-only and leave out anything that needs a login.
+func (h *WebHandler) renderEventView(w http.ResponseWriter, r *http.Request, event *models.Event, data TemplateData) {
+	user := auth.GetUserFromContext(r.Context())
+	
+	// Find events sharing tags with this one
+	var related []models.Event
+	if !data.Share.ReadOnly {
+		var err error
+		related, err = h.db.GetRelatedEvents(event, relatedEventsLimit)
+		if err != nil {
+			log.Printf("Error fetching related events for %d: %v", event.ID, err)
+		}
 	}
 	
-	// Set content type
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Attachments need a login to download, so shared pages don't list them
+	var attachments []models.Attachment
+	if !data.Share.ReadOnly && event.AttachmentCount > 0 {
+		var err error
+		attachments, err = h.db.GetAttachments(event.ID)
+		if err != nil {
+			log.Printf("Error fetching attachments for %d: %v", event.ID, err)
+		}
+	}
 	
-	// Execute template
-	templateName := "view.html"
-	err = h.templates.ExecuteTemplate(w, templateName, data)
-	if err != nil {
-		log.Printf("Error rendering event view template %s: %v", templateName, err)
-		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	// Link to the conversation when other emails belong to the same thread
+	var thread []models.Event
+	if !data.Share.ReadOnly && event.Email != nil {
+		var err error
+		thread, err = h.db.GetThread(event.ID)
+		if err != nil {
+			log.Printf("Error fetching thread for %d: %v", event.ID, err)
+		}
+		thread = visibleEvents(h.projectAccess(user), thread)
 	}
+	
+	// The burst of similar events it belongs to, which shared pages don't reveal
+	var incident *models.Incident
+	if !data.Share.ReadOnly {
+		var err error
+		incident, err = h.db.EventIncident(event.ID)
+		if err != nil {
+			log.Printf("Error fetching incident of %d: %v", event.ID, err)
+		}
+	}
+	
+	// Changes people made to the event, which shared pages don't reveal
+	var activity []models.EventAuditEntry
+	if !data.Share.ReadOnly {
+		var err error
+		activity, err = h.db.GetEventAudit(event.ID)
+		if err != nil {
+			log.Printf("Error fetching activity for %d: %v", event.ID, err)
+		}
+	}
+	
+	// Comments are for the people working on the event, not share links
+	var comments []models.EventComment
+	if !data.Share.ReadOnly {
+		var err error
+		comments, err = h.db.GetComments(event.ID)
+		if err != nil {
+			log.Printf("Error fetching comments for %d: %v", event.ID, err)
+		}
+	}
+	
+	if !data.Share.ReadOnly && user != nil {
+		var err error
+		event.Starred, err = h.db.IsStarred(user.Username, event.ID)
+		if err != nil {
+			log.Printf("Error fetching star for %d: %v", event.ID, err)
+		}
+	}
+	
+	// Links to other events, which shared pages don't reveal either
+	var links []models.EventLink
+	if !data.Share.ReadOnly {
+		var err error
+		links, err = h.db.GetEventLinks(event.ID)
+		if err != nil {
+			log.Printf("Error fetching links for %d: %v", event.ID, err)
+		}
+		links = visibleLinks(h.projectAccess(user), links)
+	}
+	
+	// Show times in the user's time zone
+	prefs := h.preferences(user)
+	event.CreatedAt = event.CreatedAt.In(prefs.Location())
+	if event.DueAt != nil {
+		due := event.DueAt.In(prefs.Location())
+		event.DueAt = &due
+	}
+	for i := range links {
+		links[i].Event.CreatedAt = links[i].Event.CreatedAt.In(prefs.Location())
+	}
+	for i := range activity {
+		activity[i].CreatedAt = activity[i].CreatedAt.In(prefs.Location())
+	}
+	for i := range comments {
+		comments[i].CreatedAt = comments[i].CreatedAt.In(prefs.Location())
+	}
+	localizeEvents(related, prefs.Location())
+	localizeEvents(thread, prefs.Location())
+	
+	// Prepare template data
+	data.User = user
+	data.Event = event
+	data.RelatedEvents = related
+	data.Attachments = attachments
+	data.Thread = thread
+	data.Incident = incident
+	data.EventActivity = activity
+	data.Comments = comments
+	data.Links = links
+	data.LinkTypes = models.LinkTypes
+	data.EventStatuses = models.EventStatuses
+	data.Labels = h.labels()
+	data.LabelsByName = models.LabelsByName(data.Labels)
+	if !data.Share.ReadOnly {
+		data.Map.EventURL = eventMapURL(event)
+	}
+	data.CustomFields = h.customFields()
+	data.FieldValues = fieldValues(data.CustomFields, event.Metadata)
+	if projects := h.projects(); !data.Share.ReadOnly && len(projects) > 1 {
+		data.ProjectNames = projectNames(projects)
+	}
+	data.Preferences = prefs
+	data.Theme = prefs.Theme
+	h.setRenderedData(&data, event, r.URL.Query().Get("images") == "show")
+	data.ImagesURL = fmt.Sprintf("/events/%d?images=show", event.ID)
+	if data.Share.ReadOnly {
+		data.ImagesURL = sharedImagesURL(r)
+	}
+	
+	h.preparePage(w, r, &data)
+	
+	h.renderTemplate(w, "view.html", data)
 }
 
 // displayEventsList is a helper function to show the events list
 func (h *WebHandler) displayEventsList(w http.ResponseWriter, r *http.Request, user *auth.User) {
+	prefs := h.preferences(user)
+	
+	// Apply the user's default filter when no filter was requested
+	if r.URL.RawQuery == "" && prefs.DefaultFilter != "" {
+		http.Redirect(w, r, "/?"+prefs.DefaultFilter, http.StatusSeeOther)
+		return
+	}
+	
 	// Get query parameters for filtering
-	tag := r.URL.Query().Get("tag")
-	date := r.URL.Query().Get("date")
-	source := r.URL.Query().Get("source")
+	filter := parseEventFilter(r, user, prefs.Location())
+	fields := h.customFields()
+	var enteredFields map[string]string
+	filter.Metadata, enteredFields = fieldFilter(r, fields)
+	access := h.projectAccess(user)
+	projects := access.Visible(h.projects())
+	projectSlug := scopeFilter(r, &filter, access, projects)
+	
+	// One row per incident, unless every event was asked for or one incident is shown
+	expanded := r.URL.Query().Get("expand") != ""
+	filter.Collapse = !expanded && filter.Incident == 0
 	
 	// Get all unique tags from the database
 	allTags, err := h.db.GetAllTags()
@@ -376,108 +791,100 @@ func (h *WebHandler) displayEventsList(w http.ResponseWriter, r *http.Request, u
 		allSources = []string{} // Use empty list if there's an error
 	}
 	
-	// Fetch events based on filters
-	var events []models.Event
-	var fetchErr error
-	
-	log.Printf("Filtering events - Tag: '%s', Date: '%s', Source: '%s'", tag, date, source)
-	
-	if date != "" {
-		// If date filter is provided
-		log.Printf("Fetching events by date: %s", date)
-		events, fetchErr = h.db.GetEventsByDate(date)
-		
-		// Apply additional source filter if provided
-		if source != "" && fetchErr == nil {
-			log.Printf("Additionally filtering %d events by source: %s", len(events), source)
-			var filteredEvents []models.Event
-			for _, event := range events {
-				if event.Source != "" && strings.EqualFold(event.Source, source) {
-					filteredEvents = append(filteredEvents, event)
-				}
-			}
-			events = filteredEvents
-			log.Printf("After source filtering: %d events remain", len(events))
+	// Determine the requested page
+	page := 1
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
 		}
-		
-		// Apply additional tag filter if provided
-		if tag != "" && fetchErr == nil {
-			log.Printf("Additionally filtering %d events by tag: %s", len(events), tag)
-			var filteredEvents []models.Event
-			for _, event := range events {
-				for _, eventTag := range event.Tags {
-					if strings.EqualFold(eventTag, tag) {
-						filteredEvents = append(filteredEvents, event)
-						break
-					}
-				}
-			}
-			events = filteredEvents
-			log.Printf("After tag filtering: %d events remain", len(events))
-		}
-	} else if source != "" {
-		// If source filter is provided
-		log.Printf("Fetching events by source: %s", source)
-		events, fetchErr = h.db.GetEventsBySource(source)
-		
-		// Apply additional tag filter if provided
-		if tag != "" && fetchErr == nil {
-			log.Printf("Additionally filtering %d events by tag: %s", len(events), tag)
-			var filteredEvents []models.Event
-			for _, event := range events {
-				for _, eventTag := range event.Tags {
-					if strings.EqualFold(eventTag, tag) {
-						filteredEvents = append(filteredEvents, event)
-						break
-					}
-				}
-			}
-			events = filteredEvents
-			log.Printf("After tag filtering: %d events remain", len(events))
-		}
-	} else {
-		// Default: filter by tag (or get all if tag is empty)
-		log.Printf("Fetching events by tag: %s", tag)
-		events, fetchErr = h.db.GetEventsByTag(tag)
 	}
 	
-	if fetchErr != nil {
-		log.Printf("Error fetching events: %v", fetchErr)
+	log.Printf("Filtering events - Tags: %v (all: %t), From: '%s', To: '%s', Source: '%s', Query: '%s', Page: %d",
+		filter.Tags, filter.MatchAll, filter.DateFrom, filter.DateTo, filter.Source, filter.Query, page)
+	
+	pageSize := prefs.PageSize
+	if !validPageSize(pageSize) {
+		pageSize = eventsPerPage
+	}
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
+	events, total, err := h.db.ListEvents(filter)
+	if err != nil {
+		log.Printf("Error fetching events: %v", err)
 		http.Error(w, "Error fetching events", http.StatusInternalServerError)
 		return
 	}
 	
-	log.Printf("Total events found: %d", len(events))
+	log.Printf("Total events found: %d (showing %d)", total, len(events))
+	if err := h.db.MarkStarred(user.Username, events); err != nil {
+		log.Printf("Error fetching stars for %s: %v", user.Username, err)
+	}
+	if err := h.db.MarkIncidents(events); err != nil {
+		log.Printf("Error fetching incidents of events: %v", err)
+	}
+	localizeEvents(events, prefs.Location())
 	
 	// Prepare template data
 	data := TemplateData{
-		User:    user,
-		Events:  events,
-		Tags:    allTags,
-		Sources: allSources,
+		User:         user,
+		Events:       events,
+		Tags:         allTags,
+		Sources:      allSources,
+		CustomFields: fields,
+		Projects:     projects,
+		Preferences:  prefs,
+		Theme:        prefs.Theme,
+	}
+	if len(projects) > 1 {
+		data.ProjectNames = projectNames(projects)
 	}
 	
 	// Set filter info
-	data.Filter.Tag = tag
-	data.Filter.Date = date
-	data.Filter.Source = source
+	data.Filter.Tags = filter.Tags
+	data.Filter.MatchAll = filter.MatchAll
+	data.Filter.DateFrom = filter.DateFrom
+	data.Filter.DateTo = filter.DateTo
+	data.Filter.Source = filter.Source
+	data.Filter.Query = filter.Query
+	data.Filter.Starred = filter.StarredBy != ""
+	data.Filter.Status = filter.Status
+	data.Filter.Due = filter.Due
+	if filter.BBox != nil {
+		data.Filter.BBox = filter.BBox.String()
+	}
+	if len(filter.Labels) > 0 {
+		data.Filter.Label = filter.Labels[0]
+	}
+	data.Labels = h.labels()
+	data.LabelsByName = models.LabelsByName(data.Labels)
+	data.EventStatuses = models.EventStatuses
+	data.Filter.Fields = enteredFields
+	data.Filter.Project = projectSlug
+	data.Filter.Incident = filter.Incident
+	data.Filter.Expanded = expanded
+	data.Filter.GroupURL = groupURL(r, expanded)
+	if filter.Incident != 0 {
+		incident, err := h.db.GetIncident(filter.Incident)
+		if err != nil {
+			log.Printf("Error fetching incident %d: %v", filter.Incident, err)
+		} else if incident != nil && access.CanView(incident.ProjectID) {
+			data.Incident = incident
+		}
+	}
+	data.Filter.Encoded = cleanFilterQuery(r.URL.RawQuery)
+	data.Export.CSVURL = exportURL(r, "csv")
+	data.Export.JSONURL = exportURL(r, "json")
+	data.Map.URL = mapURL(r)
+	data.Feeds = h.feedLinks(r, filter)
+	data.Bulk.ReturnURL = r.URL.RequestURI()
 	
-	// Set pagination info
-	data.Pagination.CurrentPage = 1
-	data.Pagination.TotalPages = 1
-	data.Pagination.TotalItems = len(events)
-	data.Pagination.ItemsPerPage = 20
+	// Set sort and pagination info
+	setSort(&data, r, filter.SortBy, filter.SortDesc)
+	setPagination(&data, r, page, total, pageSize)
 	
-	// Set content type
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	h.preparePage(w, r, &data)
 	
-	// Execute the template
-	templateName := "list.html"
-	err = h.templates.ExecuteTemplate(w, templateName, data)
-	if err != nil {
-		log.Printf("Error rendering events template %s: %v", templateName, err)
-		http.Error(w, "Error rendering template", http.StatusInternalServerError)
-	}
+	h.renderTemplate(w, "list.html", data)
 }
 
 // HandleCreateEvent displays the event creation form
@@ -490,20 +897,28 @@ func (h *WebHandler) HandleCreateEvent(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	
+	access := h.projectAccess(user)
 	data := TemplateData{
-		User: user,
+		User:         user,
+		CustomFields: h.customFields(),
+		Projects:     editableProjects(access, h.projects()),
 	}
 	
-	// Set content type
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	
-	// Execute template
-	templateName := "new.html"
-	err := h.templates.ExecuteTemplate(w, templateName, data)
-	if err != nil {
-		log.Printf("Error rendering event creation template %s: %v", templateName, err)
-		http.Error(w, "Error rendering template", http.StatusInternalServerError)
+	// Pre-fill the form when duplicating an existing event
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		if fromID, err := strconv.ParseInt(fromStr, 10, 64); err == nil {
+			if source, err := h.db.GetEventByID(fromID); err != nil {
+				log.Printf("Error loading event %d to duplicate: %v", fromID, err)
+			} else if source != nil && access.CanView(source.ProjectID) {
+				data.Form = &eventForm{Data: source.Data, Tags: source.Tags, Source: source.Source, ProjectID: source.ProjectID}
+				data.FieldValues = fieldValues(data.CustomFields, source.Metadata)
+			}
+		}
 	}
+	
+	h.preparePage(w, r, &data)
+	
+	h.renderTemplate(w, "new.html", data)
 }
 
 // HandleCreateEventPost handles the event creation form submission
@@ -515,34 +930,32 @@ func (h *WebHandler) HandleCreateEventPost(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	
-	// Get form data
-	data := r.FormValue("data")
-	tagsStr := r.FormValue("tags")
-	source := r.FormValue("source")
-	
-	// Validate required fields
-	if data == "" {
-		h.setFlash(w, "Event data is required", "error")
-		http.Redirect(w, r, "/events/new", http.StatusSeeOther)
-		return
-	}
-	
-	// Process tags
-	var tags []string
-	if tagsStr != "" {
-		for _, tag := range strings.Split(tagsStr, ",") {
-			tag = strings.TrimSpace(tag)
-			if tag != "" {
-				tags = append(tags, tag)
-			}
+	// Validate the submission, re-rendering the form with errors if needed
+	user := auth.GetUserFromContext(r.Context())
+	access := h.projectAccess(user)
+	form := parseEventForm(r)
+	fields := h.customFields()
+	form.parseFields(r, fields)
+	form.parseProject(r, access)
+	if !form.Valid() {
+		data := TemplateData{
+			User:         user,
+			Form:         form,
+			CustomFields: fields,
+			FieldValues:  form.Fields,
+			Projects:     editableProjects(access, h.projects()),
 		}
+		h.renderEventForm(w, r, "new.html", data)
+		return
 	}
 	
 	// Create event
 	event := models.Event{
-		Data:      data,
-		Tags:      tags,
-		Source:    source,
+		Data:      form.Data,
+		Tags:      form.Tags,
+		Source:    form.Source,
+		Metadata:  form.Metadata,
+		ProjectID: form.ProjectID,
 		CreatedAt: time.Now(),
 	}
 	
@@ -553,6 +966,7 @@ func (h *WebHandler) HandleCreateEventPost(w http.ResponseWriter, r *http.Reques
 		http.Redirect(w, r, "/events/new", http.StatusSeeOther)
 		return
 	}
+	h.recordEventAudit(r, models.EventCreated, event.ID, nil, &event)
 	
 	// Set success flash message
 	h.setFlash(w, "Event created successfully", "success")
@@ -561,6 +975,15 @@ func (h *WebHandler) HandleCreateEventPost(w http.ResponseWriter, r *http.Reques
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// renderEventForm re-renders an event form with the submitted values and validation errors
+func (h *WebHandler) renderEventForm(w http.ResponseWriter, r *http.Request, name string, data TemplateData) {
+	data.CSRFToken = auth.RequestCSRFToken(r)
+	data.FlashMessage = "Please correct the errors below"
+	data.FlashType = "error"
+	
+	h.renderTemplateStatus(w, http.StatusUnprocessableEntity, name, data)
+}
+
 // HandleEditEvent displays the event edit form
 func (h *WebHandler) HandleEditEvent(w http.ResponseWriter, r *http.Request) {
 	// Get the event ID from the URL
@@ -581,26 +1004,27 @@ func (h *WebHandler) HandleEditEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	if event == nil {
+	access := h.projectAccess(auth.GetUserFromContext(r.Context()))
+	if event == nil || !access.CanView(event.ProjectID) {
 		http.Error(w, "Event not found", http.StatusNotFound)
 		return
 	}
+	if !access.CanEdit(event.ProjectID) {
+		http.Error(w, "You can't edit events in this project", http.StatusForbidden)
+		return
+	}
 	
 	// Prepare template data
 	data := TemplateData{
-		Event: event,
+		Event:        event,
+		CustomFields: h.customFields(),
+		Projects:     editableProjects(access, h.projects()),
 	}
+	data.FieldValues = fieldValues(data.CustomFields, event.Metadata)
 	
-	// Set content type
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	h.preparePage(w, r, &data)
 	
-	// Execute template
-	templateName := "edit.html"
-	err = h.templates.ExecuteTemplate(w, templateName, data)
-	if err != nil {
-		log.Printf("Error rendering event edit template %s: %v", templateName, err)
-		http.Error(w, "Error rendering template", http.StatusInternalServerError)
-	}
+	h.renderTemplate(w, "edit.html", data)
 }
 
 // HandleEditEventPost processes the event edit form submission
@@ -623,10 +1047,16 @@ func (h *WebHandler) HandleEditEventPost(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	
-	if event == nil {
+	user := auth.GetUserFromContext(r.Context())
+	access := h.projectAccess(user)
+	if event == nil || !access.CanView(event.ProjectID) {
 		http.Error(w, "Event not found", http.StatusNotFound)
 		return
 	}
+	if !access.CanEdit(event.ProjectID) {
+		http.Error(w, "You can't edit events in this project", http.StatusForbidden)
+		return
+	}
 	
 	// Parse form data
 	if err := r.ParseForm(); err != nil {
@@ -635,51 +1065,49 @@ func (h *WebHandler) HandleEditEventPost(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	
-	// Get form data
-	data := r.FormValue("data")
-	tagsStr := r.FormValue("tags")
-	source := r.FormValue("source")
-	
-	// Log received form data for debugging
-	log.Printf("Edit event form data - ID: %d, Data length: %d, Tags: %s, Source: %s", 
-		id, len(data), tagsStr, source)
-	
-	// Validate required fields
-	if data == "" {
-		h.setFlash(w, "Event data is required", "error")
+	// Validate the submission, re-rendering the form with errors if needed
+	form := parseEventForm(r)
+	// The form replaces every custom field value, so it can't be saved
+	// without knowing the fields
+	fields, err := h.db.ListCustomFields()
+	if err != nil {
+		log.Printf("Error loading custom fields: %v", err)
+		h.setFlash(w, "Error loading custom fields", "error")
 		http.Redirect(w, r, fmt.Sprintf("/events/%d/edit", id), http.StatusSeeOther)
 		return
 	}
-	
-	// Process tags
-	var tags []string
-	if tagsStr != "" {
-		for _, tag := range strings.Split(tagsStr, ",") {
-			tag = strings.TrimSpace(tag)
-			if tag != "" {
-				tags = append(tags, tag)
-				log.Printf("Added tag: %s", tag)
-			}
+	form.parseFields(r, fields)
+	form.parseProject(r, access)
+	log.Printf("Edit event form data - ID: %d, Data length: %d, Tags: %v, Source: %s", 
+		id, len(form.Data), form.Tags, form.Source)
+	
+	if !form.Valid() {
+		event.Data = form.Data
+		event.Tags = form.Tags
+		event.Source = form.Source
+		data := TemplateData{
+			User:         user,
+			Event:        event,
+			Form:         form,
+			CustomFields: fields,
+			FieldValues:  form.Fields,
+			Projects:     editableProjects(access, h.projects()),
 		}
+		h.renderEventForm(w, r, "edit.html", data)
+		return
 	}
-	log.Printf("Final processed tags: %v", tags)
-	
-	// Update event fields
-	event.Data = data
-	if len(tags) > 0 {
-		event.Tags = tags
-	}
-	event.Source = source
 	
-	// Ensure we're not saving empty tags array if we had tags before
-	if len(event.Tags) == 0 && len(tagsStr) == 0 {
+	// Update event fields, keeping the existing tags if none were submitted
+	old := *event
+	event.Data = form.Data
+	if len(form.Tags) > 0 {
+		event.Tags = form.Tags
+	} else if len(event.Tags) > 0 {
 		log.Printf("Warning: No tags provided but event previously had tags. Keeping existing tags.")
-		// Get fresh copy of event to ensure we have original tags
-		originalEvent, _ := h.db.GetEventByID(id)
-		if originalEvent != nil && len(originalEvent.Tags) > 0 {
-			event.Tags = originalEvent.Tags
-		}
 	}
+	event.Source = form.Source
+	event.Metadata = form.Metadata
+	event.ProjectID = form.ProjectID
 	
 	// Save updated event to database
 	err = h.db.UpdateEvent(event)
@@ -689,6 +1117,7 @@ func (h *WebHandler) HandleEditEventPost(w http.ResponseWriter, r *http.Request)
 		http.Redirect(w, r, fmt.Sprintf("/events/%d/edit", id), http.StatusSeeOther)
 		return
 	}
+	h.recordEventAudit(r, models.EventEdited, id, &old, event)
 	
 	// Set success flash message
 	h.setFlash(w, "Event updated successfully", "success")
@@ -710,12 +1139,26 @@ func (h *WebHandler) HandleDeleteEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
+	// Keep the event in the audit trail so it can be restored
+	old, err := h.db.GetEventByID(id)
+	if err != nil {
+		log.Printf("Error fetching event %d before deleting it: %v", id, err)
+		http.Error(w, "Error retrieving event", http.StatusInternalServerError)
+		return
+	}
+	access := h.projectAccess(auth.GetUserFromContext(r.Context()))
+	if old != nil && !access.CanEdit(old.ProjectID) {
+		http.Error(w, "You can't delete events in this project", http.StatusForbidden)
+		return
+	}
+	
 	// Delete the event
 	err = h.db.DeleteEvent(id)
 	if err != nil {
 		log.Printf("Error deleting event: %v", err)
 		h.setFlash(w, fmt.Sprintf("Error deleting event: %v", err), "error")
 	} else {
+		h.recordEventAudit(r, models.EventDeleted, id, old, nil)
 		h.setFlash(w, "Event deleted successfully", "success")
 	}
 	
@@ -723,6 +1166,27 @@ func (h *WebHandler) HandleDeleteEvent(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// HandleAuditLog displays the audit log to admins
+func (h *WebHandler) HandleAuditLog(w http.ResponseWriter, r *http.Request) {
+	action := r.URL.Query().Get("action")
+
+	entries, err := h.db.GetAuditLog(action, 200)
+	if err != nil {
+		log.Printf("Error fetching audit log: %v", err)
+		http.Error(w, "Error fetching audit log", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:         auth.GetUserFromContext(r.Context()),
+		AuditEntries: entries,
+	}
+	data.Filter.Action = action
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "audit.html", data)
+}
+
 // HandleDebug displays template debugging information
 func (h *WebHandler) HandleDebug(w http.ResponseWriter, r *http.Request) {
 	log.Printf("HandleDebug called with URL: %s", r.URL.String())
@@ -731,15 +1195,14 @@ func (h *WebHandler) HandleDebug(w http.ResponseWriter, r *http.Request) {
 	output := "Template Debug Information\n\n"
 	output += "Available Templates:\n"
 	
-	for _, t := range h.templates.Templates() {
-		output += fmt.Sprintf("- %s\n", t.Name())
+	for name := range h.pages {
+		output += fmt.Sprintf("- %s\n", name)
 	}
 	
 	// Try to identify which template would be used
 	output += "\nTemplate Lookup Test:\n"
-	for _, name := range []string{"base.html", "login.html", "dashboard.html", "index.html"} {
-		tmpl := h.templates.Lookup(name)
-		if tmpl != nil {
+	for _, name := range []string{"login.html", "dashboard.html", "index.html"} {
+		if _, ok := h.pages[name]; ok {
 			output += fmt.Sprintf("- %s: FOUND\n", name)
 		} else {
 			output += fmt.Sprintf("- %s: NOT FOUND\n", name)
@@ -814,22 +1277,54 @@ func (h *WebHandler) setFlash(w http.ResponseWriter, message string, messageType
 	http.SetCookie(w, flashCookie)
 }
 
-func (h *WebHandler) getFlash(r *http.Request) (string, string) {
+func (h *WebHandler) getFlash(w http.ResponseWriter, r *http.Request) (string, string) {
 	flashCookie, err := r.Cookie("flash")
 	if err != nil {
 		return "", ""
 	}
 	
-	// Delete the cookie
-	flashCookie.Value = ""
-	flashCookie.MaxAge = -1
+	// Delete the cookie so the message is only shown once
+	http.SetCookie(w, &http.Cookie{
+		Name:     "flash",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
 	
-	// Parse the value
-	value, _ := url.QueryUnescape(flashCookie.Value)
-	parts := strings.Split(value, "|")
-	if len(parts) != 2 {
+	// Parse the value; the message itself may contain "|"
+	value, err := url.QueryUnescape(flashCookie.Value)
+	if err != nil {
+		return "", ""
+	}
+	sep := strings.LastIndex(value, "|")
+	if sep < 0 {
 		return "", ""
 	}
 	
-	return parts[0], parts[1]
-}
\ No newline at end of file
+	return value[:sep], value[sep+1:]
+}
+
+// preparePage fills in the per-request template data: the CSRF token for
+// forms, the user's saved searches for the navigation and any pending flash
+// message, unless the page has already set its own
+func (h *WebHandler) preparePage(w http.ResponseWriter, r *http.Request, data *TemplateData) {
+	data.CSRFToken = auth.RequestCSRFToken(r)
+	if data.Location == nil {
+		prefs := data.Preferences
+		if prefs == nil {
+			prefs = h.preferences(data.User)
+		}
+		data.Location = prefs.Location()
+	}
+	if data.SavedSearches == nil && !data.Share.ReadOnly {
+		data.SavedSearches = h.savedSearches(data.User)
+	}
+
+	message, messageType := h.getFlash(w, r)
+	if message == "" || data.FlashMessage != "" {
+		return
+	}
+	data.FlashMessage = message
+	data.FlashType = messageType
+}
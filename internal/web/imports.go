@@ -0,0 +1,509 @@
+package web
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/export"
+	"example-api/internal/models"
+	"example-api/internal/utils"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxImportSize     = 64 << 20 // Largest file that can be uploaded
+	importFormSize    = 64 << 10 // Room for the other fields and the multipart framing of an upload
+	importBatchSize   = 500      // Events per transaction
+	importSampleRows  = 5        // Rows shown under the columns when mapping them
+	importPreviewRows = 20       // Events shown in a preview
+	importErrorsShown = 20       // Invalid records listed in a preview or a report
+	// importTTL is how long an upload and the report of its import are kept
+	importTTL = 24 * time.Hour
+)
+
+// importJobs holds the uploaded files being imported, by token. Jobs only
+// live in memory; a restart loses the uploads not imported yet.
+type importJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*importJob
+}
+
+// importJob is an uploaded file, from mapping its columns to the report of
+// its import. Only its progress changes after the upload, guarded by mu.
+type importJob struct {
+	Token    string
+	Owner    string
+	Filename string
+	Format   string // "csv" or "ndjson"
+	Size     int64
+	Uploaded time.Time
+	path     string // Temporary copy of the file, removed once imported
+
+	Header  []string   // CSV header row
+	Samples [][]string // First rows of a CSV file
+
+	mu       sync.Mutex
+	progress importProgress
+}
+
+// importProgress is how far the import of a job got
+type importProgress struct {
+	Started  time.Time
+	Finished time.Time
+	Read     int // Records read so far
+	Invalid  int // Records that couldn't become events
+	Result   models.ImportResult
+	Errors   []string // First invalid records
+	Failure  string   // Why the import stopped early, if it did
+}
+
+// importPage is what the import page shows of a job
+type importPage struct {
+	Job      *importJob
+	Progress importProgress
+	Error    string   // Why the mapping can't be used
+	Targets  []string // What a CSV column can be mapped to
+	Columns  []string // Target chosen for each CSV column
+	Tags     string
+	Source   string
+	Project  int64
+	Conflict string // Conflict strategy of NDJSON imports
+
+	Previewed bool
+	Preview   []models.ExportedEvent
+	Valid     int
+	Invalid   int
+	Errors    []string
+}
+
+func (j *importJobs) add(job *importJob) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.jobs == nil {
+		j.jobs = make(map[string]*importJob)
+	}
+	j.jobs[job.Token] = job
+}
+
+func (j *importJobs) get(token string) *importJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jobs[token]
+}
+
+// expire forgets the jobs uploaded more than importTTL ago, removing their
+// files unless they are being imported
+func (j *importJobs) expire(now time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for token, job := range j.jobs {
+		if now.Sub(job.Uploaded) < importTTL {
+			continue
+		}
+		if job.snapshot().Running() {
+			continue
+		}
+		os.Remove(job.path)
+		delete(j.jobs, token)
+	}
+}
+
+// snapshot copies the job's progress, so a page can show it while the
+// import goes on
+func (job *importJob) snapshot() importProgress {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	progress := job.progress
+	progress.Errors = append([]string(nil), job.progress.Errors...)
+	return progress
+}
+
+// Running reports whether the import started and hasn't finished
+func (p importProgress) Running() bool {
+	return !p.Started.IsZero() && p.Finished.IsZero()
+}
+
+// HandleImport shows the form for uploading a file to import
+func (h *WebHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	data := TemplateData{
+		User: auth.GetUserFromContext(r.Context()),
+	}
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "import.html", data)
+}
+
+// limitImportUpload caps the body of an upload before the CSRF check parses
+// the form, so a file over the limit is refused before it is spooled to disk
+func (h *WebHandler) limitImportUpload(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxImportSize+importFormSize {
+			h.setFlash(w, fmt.Sprintf("The file is larger than the limit of %d MB", maxImportSize>>20), "error")
+			http.Redirect(w, r, "/events/import", http.StatusSeeOther)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+importFormSize)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleImportUploadPost keeps an uploaded CSV or NDJSON file for mapping
+// and importing
+func (h *WebHandler) HandleImportUploadPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.setFlash(w, "Choose a file to import", "error")
+		http.Redirect(w, r, "/events/import", http.StatusSeeOther)
+		return
+	}
+	defer file.Close()
+	if header.Size > maxImportSize {
+		h.setFlash(w, fmt.Sprintf("The file is larger than the limit of %d MB", maxImportSize>>20), "error")
+		http.Redirect(w, r, "/events/import", http.StatusSeeOther)
+		return
+	}
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = "csv"
+		if ext := strings.ToLower(filepath.Ext(header.Filename)); ext == ".ndjson" || ext == ".jsonl" {
+			format = "ndjson"
+		}
+	}
+	if format != "csv" && format != "ndjson" {
+		h.setFlash(w, "Files can be imported as CSV or NDJSON", "error")
+		http.Redirect(w, r, "/events/import", http.StatusSeeOther)
+		return
+	}
+
+	token, err := utils.GenerateRandomString(24)
+	if err != nil {
+		log.Printf("Error generating import token: %v", err)
+		http.Error(w, "Error uploading file", http.StatusInternalServerError)
+		return
+	}
+	job := &importJob{
+		Token:    token,
+		Owner:    user.Username,
+		Filename: filepath.Base(header.Filename),
+		Format:   format,
+		Uploaded: time.Now(),
+	}
+	if err := job.save(file); err != nil {
+		log.Printf("Error saving import upload: %v", err)
+		http.Error(w, "Error uploading file", http.StatusInternalServerError)
+		return
+	}
+	if format == "csv" {
+		err = job.readHeader()
+	}
+	if err != nil {
+		os.Remove(job.path)
+		h.setFlash(w, "Can't read "+job.Filename+": "+err.Error(), "error")
+		http.Redirect(w, r, "/events/import", http.StatusSeeOther)
+		return
+	}
+
+	h.imports.expire(time.Now())
+	h.imports.add(job)
+	log.Printf("User %s uploaded %s (%d bytes) for import as %s", user.Username, job.Filename, job.Size, format)
+	http.Redirect(w, r, "/events/import/"+token, http.StatusSeeOther)
+}
+
+// save copies the upload to a temporary file
+func (job *importJob) save(upload io.Reader) error {
+	file, err := os.CreateTemp("", "eventdb-import-*."+job.Format)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer file.Close()
+	job.path = file.Name()
+	if job.Size, err = io.Copy(file, upload); err != nil {
+		os.Remove(job.path)
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	return nil
+}
+
+// readHeader reads the header and first rows of a CSV upload
+func (job *importJob) readHeader() error {
+	file, err := os.Open(job.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	job.Header, job.Samples, err = export.CSVHeader(file, importSampleRows)
+	return err
+}
+
+// HandleImportJob shows the mapping form of an upload, or the progress and
+// report of its import
+func (h *WebHandler) HandleImportJob(w http.ResponseWriter, r *http.Request) {
+	job := h.imports.get(mux.Vars(r)["token"])
+	if job == nil {
+		http.Error(w, "Import not found; uploads are kept for 24 hours", http.StatusNotFound)
+		return
+	}
+
+	fields := h.customFields()
+	page := &importPage{Job: job, Progress: job.snapshot(), Conflict: models.ImportNewID}
+	page.Targets = importTargets(fields)
+	for _, column := range job.Header {
+		page.Columns = append(page.Columns, export.GuessColumn(column, fields))
+	}
+	h.renderImport(w, r, page)
+}
+
+// HandleImportPreviewPost converts the first records of an upload with the
+// mapping chosen, and checks the rest, without importing anything
+func (h *WebHandler) HandleImportPreviewPost(w http.ResponseWriter, r *http.Request) {
+	job := h.imports.get(mux.Vars(r)["token"])
+	if job == nil {
+		http.Error(w, "Import not found; uploads are kept for 24 hours", http.StatusNotFound)
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	fields := h.customFields()
+	page := h.importForm(r, job, fields)
+	mapping, err := h.importMapping(page, user, fields)
+	if err != nil {
+		page.Error = err.Error()
+		h.renderImport(w, r, page)
+		return
+	}
+
+	file, err := os.Open(job.path)
+	if err != nil {
+		log.Printf("Error opening import upload: %v", err)
+		http.Error(w, "The upload is no longer available", http.StatusGone)
+		return
+	}
+	defer file.Close()
+	reader, err := export.NewReader(job.Format, file, mapping)
+	if err == nil {
+		err = readImport(reader, func(event *models.ExportedEvent) error {
+			if len(page.Preview) < importPreviewRows {
+				page.Preview = append(page.Preview, *event)
+			}
+			page.Valid++
+			return nil
+		}, func(invalid *export.RecordError) {
+			if page.Invalid < importErrorsShown {
+				page.Errors = append(page.Errors, invalid.Error())
+			}
+			page.Invalid++
+		})
+	}
+	if err != nil {
+		page.Error = "Can't read " + job.Filename + ": " + err.Error()
+	}
+	page.Previewed = true
+	h.renderImport(w, r, page)
+}
+
+// HandleImportStartPost starts importing an upload in the background and
+// shows its progress
+func (h *WebHandler) HandleImportStartPost(w http.ResponseWriter, r *http.Request) {
+	job := h.imports.get(mux.Vars(r)["token"])
+	if job == nil {
+		http.Error(w, "Import not found; uploads are kept for 24 hours", http.StatusNotFound)
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	fields := h.customFields()
+	page := h.importForm(r, job, fields)
+	mapping, err := h.importMapping(page, user, fields)
+	if err != nil {
+		page.Error = err.Error()
+		h.renderImport(w, r, page)
+		return
+	}
+	strategy := models.ImportNewID
+	if job.Format == "ndjson" {
+		strategy = page.Conflict
+	}
+
+	job.mu.Lock()
+	started := !job.progress.Started.IsZero()
+	if !started {
+		job.progress.Started = time.Now()
+	}
+	job.mu.Unlock()
+	if started {
+		h.setFlash(w, "This file is already being imported", "error")
+		http.Redirect(w, r, "/events/import/"+job.Token, http.StatusSeeOther)
+		return
+	}
+
+	log.Printf("User %s started importing %s", user.Username, job.Filename)
+	go h.runImport(job, mapping, strategy)
+	http.Redirect(w, r, "/events/import/"+job.Token, http.StatusSeeOther)
+}
+
+// runImport imports a job's file importBatchSize events per transaction,
+// recording its progress. A failing batch stops the import; the batches
+// before it stay imported.
+func (h *WebHandler) runImport(job *importJob, mapping export.Mapping, strategy string) {
+	fail := func(err error) {
+		log.Printf("Import of %s failed: %v", job.Filename, err)
+		job.mu.Lock()
+		job.progress.Failure = err.Error()
+		job.mu.Unlock()
+	}
+	defer func() {
+		job.mu.Lock()
+		progress := &job.progress
+		progress.Finished = time.Now()
+		log.Printf("Import of %s finished: %d records read, %d imported, %d overwritten, %d skipped, %d invalid",
+			job.Filename, progress.Read, progress.Result.Imported, progress.Result.Overwritten, progress.Result.Skipped, progress.Invalid)
+		job.mu.Unlock()
+		os.Remove(job.path)
+	}()
+
+	file, err := os.Open(job.path)
+	if err != nil {
+		fail(err)
+		return
+	}
+	defer file.Close()
+	reader, err := export.NewReader(job.Format, file, mapping)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	batch := make([]models.ExportedEvent, 0, importBatchSize)
+	flush := func() error {
+		result, err := h.db.ImportEvents(batch, strategy)
+		if err != nil {
+			return err
+		}
+		job.mu.Lock()
+		progress := &job.progress
+		progress.Read += len(batch)
+		progress.Result.Imported += result.Imported
+		progress.Result.Overwritten += result.Overwritten
+		progress.Result.Skipped += result.Skipped
+		progress.Result.MissingAttachmentData += result.MissingAttachmentData
+		job.mu.Unlock()
+		batch = batch[:0]
+		return nil
+	}
+	err = readImport(reader, func(event *models.ExportedEvent) error {
+		batch = append(batch, *event)
+		if len(batch) < importBatchSize {
+			return nil
+		}
+		return flush()
+	}, func(invalid *export.RecordError) {
+		job.mu.Lock()
+		progress := &job.progress
+		if progress.Invalid < importErrorsShown {
+			progress.Errors = append(progress.Errors, invalid.Error())
+		}
+		progress.Invalid++
+		progress.Read++
+		job.mu.Unlock()
+	})
+	if err == nil && len(batch) > 0 {
+		err = flush()
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+// readImport passes every event of reader to fn and every invalid record to
+// invalid, stopping at the first other error
+func readImport(reader export.Reader, fn func(*models.ExportedEvent) error, invalid func(*export.RecordError)) error {
+	for {
+		event, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var recordErr *export.RecordError
+		if errors.As(err, &recordErr) {
+			invalid(recordErr)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+// importForm reads the mapping form of a job
+func (h *WebHandler) importForm(r *http.Request, job *importJob, fields []models.CustomField) *importPage {
+	page := &importPage{
+		Job:      job,
+		Progress: job.snapshot(),
+		Targets:  importTargets(fields),
+		Tags:     r.FormValue("tags"),
+		Source:   strings.TrimSpace(r.FormValue("source")),
+		Conflict: r.FormValue("on_conflict"),
+	}
+	if page.Conflict == "" {
+		page.Conflict = models.ImportNewID
+	}
+	page.Project, _ = strconv.ParseInt(r.FormValue("project"), 10, 64)
+	for i := range job.Header {
+		page.Columns = append(page.Columns, r.FormValue("column."+strconv.Itoa(i)))
+	}
+	return page
+}
+
+// importMapping checks the mapping form and returns the mapping it chose
+func (h *WebHandler) importMapping(page *importPage, user *auth.User, fields []models.CustomField) (export.Mapping, error) {
+	mapping := export.Mapping{
+		Fields:    fields,
+		Tags:      splitTags(page.Tags),
+		Source:    page.Source,
+		ProjectID: page.Project,
+		Location:  h.preferences(user).Location(),
+	}
+	switch page.Conflict {
+	case models.ImportSkip, models.ImportOverwrite, models.ImportNewID:
+	default:
+		return mapping, fmt.Errorf("unknown conflict strategy %q", page.Conflict)
+	}
+	if _, ok := projectNames(h.projects())[page.Project]; page.Project != 0 && !ok {
+		return mapping, fmt.Errorf("unknown project")
+	}
+	if page.Job.Format != "csv" {
+		return mapping, nil
+	}
+	mapping.Columns = page.Columns
+	return mapping, mapping.Validate()
+}
+
+// importTargets lists what a CSV column can be mapped to
+func importTargets(fields []models.CustomField) []string {
+	targets := append([]string(nil), export.ImportColumns...)
+	for _, field := range fields {
+		targets = append(targets, export.FieldColumnPrefix+field.Name)
+	}
+	return targets
+}
+
+func (h *WebHandler) renderImport(w http.ResponseWriter, r *http.Request, page *importPage) {
+	data := TemplateData{
+		User:     auth.GetUserFromContext(r.Context()),
+		Import:   page,
+		Projects: h.projects(),
+	}
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "import.html", data)
+}
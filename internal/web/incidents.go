@@ -0,0 +1,53 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// incidentsPerPage is how many incidents the incidents page lists at a time
+const incidentsPerPage = 50
+
+// HandleIncidents lists the incidents the user can see, most recently active
+// first
+func (h *WebHandler) HandleIncidents(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+
+	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	incidents, total, err := h.db.ListIncidents(h.projectAccess(user).Viewable(), incidentsPerPage, (page-1)*incidentsPerPage)
+	if err != nil {
+		log.Printf("Error fetching incidents: %v", err)
+		http.Error(w, "Error fetching incidents", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:      user,
+		Incidents: incidents,
+	}
+	setPagination(&data, r, page, total, incidentsPerPage)
+	if total > 0 {
+		data.Pagination.LastItem = data.Pagination.FirstItem + len(incidents) - 1
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "incidents.html", data)
+}
+
+// groupURL links to the events list with incidents expanded into their
+// events, or collapsed again when they are expanded
+func groupURL(r *http.Request, expanded bool) string {
+	query := r.URL.Query()
+	query.Del("page")
+	if expanded {
+		query.Del("expand")
+	} else {
+		query.Set("expand", "1")
+	}
+	return r.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,174 @@
+package web
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// labels returns the defined labels, or none if they can't be loaded
+func (h *WebHandler) labels() []models.Label {
+	labels, err := h.db.ListLabels()
+	if err != nil {
+		log.Printf("Error loading labels: %v", err)
+	}
+	return labels
+}
+
+// HandleAdminLabels lists the labels, with forms for defining and restyling
+// them
+func (h *WebHandler) HandleAdminLabels(w http.ResponseWriter, r *http.Request) {
+	labels, err := h.db.ListLabels()
+	if err != nil {
+		log.Printf("Error fetching labels: %v", err)
+		http.Error(w, "Error fetching labels", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:   auth.GetUserFromContext(r.Context()),
+		Labels: labels,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "labels.html", data)
+}
+
+// HandleCreateLabelPost defines a label
+func (h *WebHandler) HandleCreateLabelPost(w http.ResponseWriter, r *http.Request) {
+	label := &models.Label{
+		Name:        r.FormValue("name"),
+		Color:       r.FormValue("color"),
+		Description: r.FormValue("description"),
+	}
+	if err := label.Validate(); err != nil {
+		h.setFlash(w, "Invalid label: "+err.Error(), "error")
+		http.Redirect(w, r, "/admin/labels", http.StatusSeeOther)
+		return
+	}
+
+	err := h.db.CreateLabel(label)
+	switch {
+	case errors.Is(err, database.ErrLabelExists):
+		h.setFlash(w, fmt.Sprintf("A label named %q already exists", label.Name), "error")
+	case err != nil:
+		log.Printf("Error creating label: %v", err)
+		h.setFlash(w, "Error creating label", "error")
+	default:
+		h.setFlash(w, fmt.Sprintf("Created label %q", label.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/labels", http.StatusSeeOther)
+}
+
+// HandleUpdateLabelPost changes the color and description of a label
+func (h *WebHandler) HandleUpdateLabelPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid label ID", http.StatusBadRequest)
+		return
+	}
+
+	label := &models.Label{ID: id, Color: r.FormValue("color"), Description: r.FormValue("description")}
+	if err := label.ValidateStyle(); err != nil {
+		h.setFlash(w, "Invalid label: "+err.Error(), "error")
+		http.Redirect(w, r, "/admin/labels", http.StatusSeeOther)
+		return
+	}
+
+	found, err := h.db.UpdateLabel(label)
+	switch {
+	case err != nil:
+		log.Printf("Error updating label %d: %v", id, err)
+		h.setFlash(w, "Error updating label", "error")
+	case !found:
+		http.Error(w, "Label not found", http.StatusNotFound)
+		return
+	default:
+		h.setFlash(w, fmt.Sprintf("Updated label %q", label.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/labels", http.StatusSeeOther)
+}
+
+// HandleDeleteLabelPost removes a label and takes it off every event
+func (h *WebHandler) HandleDeleteLabelPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid label ID", http.StatusBadRequest)
+		return
+	}
+
+	label, err := h.db.DeleteLabel(id)
+	switch {
+	case err != nil:
+		log.Printf("Error deleting label %d: %v", id, err)
+		h.setFlash(w, "Error deleting label", "error")
+	case label == nil:
+		http.Error(w, "Label not found", http.StatusNotFound)
+		return
+	default:
+		h.setFlash(w, fmt.Sprintf("Deleted label %q", label.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/labels", http.StatusSeeOther)
+}
+
+// HandleEventLabelPost adds the chosen label to an event
+func (h *WebHandler) HandleEventLabelPost(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.editableEvent(w, r, auth.GetUserFromContext(r.Context()))
+	if !ok {
+		return
+	}
+	eventURL := fmt.Sprintf("/events/%d", event.ID)
+
+	name := r.FormValue("label")
+	if slices.Contains(event.Labels, name) {
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+	labels, err := models.CheckLabels(h.labels(), append(slices.Clone(event.Labels), name))
+	if err != nil || name == "" {
+		h.setFlash(w, "Choose a label", "error")
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+	h.setEventLabels(w, r, event, labels, "Added label "+strconv.Quote(name))
+}
+
+// HandleRemoveEventLabelPost takes a label off an event
+func (h *WebHandler) HandleRemoveEventLabelPost(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.editableEvent(w, r, auth.GetUserFromContext(r.Context()))
+	if !ok {
+		return
+	}
+
+	name := mux.Vars(r)["label"]
+	labels := slices.DeleteFunc(slices.Clone(event.Labels), func(label string) bool { return label == name })
+	h.setEventLabels(w, r, event, labels, "Removed label "+strconv.Quote(name))
+}
+
+// setEventLabels replaces an event's labels, records the change in its
+// audit trail and returns to the event page
+func (h *WebHandler) setEventLabels(w http.ResponseWriter, r *http.Request, event *models.Event, labels []string, message string) {
+	found, err := h.db.SetEventLabels(event.ID, labels)
+	switch {
+	case err != nil:
+		log.Printf("Error setting labels of event %d: %v", event.ID, err)
+		h.setFlash(w, "Error changing labels", "error")
+	case !found:
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	default:
+		updated := *event
+		updated.Labels = labels
+		h.recordEventAudit(r, models.EventEdited, event.ID, event, &updated)
+		h.setFlash(w, message, "success")
+	}
+	http.Redirect(w, r, fmt.Sprintf("/events/%d", event.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,127 @@
+package web
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleLinkPost links an event to the one whose ID was entered
+func (h *WebHandler) HandleLinkPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	event, ok := h.editableEvent(w, r, user)
+	if !ok {
+		return
+	}
+	eventURL := fmt.Sprintf("/events/%d#links", event.ID)
+
+	linkType := r.FormValue("type")
+	if !slices.Contains(models.LinkTypes, linkType) {
+		h.setFlash(w, "Choose how the events are linked", "error")
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+	// Accept "12" as well as "#12"
+	targetID, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(r.FormValue("target")), "#"), 10, 64)
+	if err != nil || targetID == event.ID {
+		h.setFlash(w, "Enter the ID of another event", "error")
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+	target, err := h.db.GetEventByID(targetID)
+	if err != nil {
+		log.Printf("Error fetching event %d: %v", targetID, err)
+		h.setFlash(w, "Error retrieving event", "error")
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+	if target == nil || !h.projectAccess(user).CanView(target.ProjectID) {
+		h.setFlash(w, fmt.Sprintf("There is no event #%d", targetID), "error")
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+
+	link := &models.EventLink{EventID: event.ID, TargetID: target.ID, Type: linkType, CreatedBy: user.Username}
+	err = h.db.AddEventLink(link)
+	switch {
+	case errors.Is(err, database.ErrLinkExists):
+		h.setFlash(w, "The events are already linked this way", "error")
+	case err != nil:
+		log.Printf("Error linking event %d to %d: %v", event.ID, target.ID, err)
+		h.setFlash(w, "Error linking events", "error")
+	default:
+		h.setFlash(w, fmt.Sprintf("Linked to #%d", target.ID), "success")
+	}
+	http.Redirect(w, r, eventURL, http.StatusSeeOther)
+}
+
+// HandleDeleteLinkPost removes a link from or to an event
+func (h *WebHandler) HandleDeleteLinkPost(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.editableEvent(w, r, auth.GetUserFromContext(r.Context()))
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(mux.Vars(r)["linkID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.db.DeleteEventLink(event.ID, id)
+	switch {
+	case err != nil:
+		log.Printf("Error deleting link %d: %v", id, err)
+		h.setFlash(w, "Error removing link", "error")
+	case !deleted:
+		h.setFlash(w, "Link not found", "error")
+	default:
+		h.setFlash(w, "Link removed", "success")
+	}
+	http.Redirect(w, r, fmt.Sprintf("/events/%d#links", event.ID), http.StatusSeeOther)
+}
+
+// editableEvent loads the event named in the URL, responding with an error
+// unless the user can edit it
+func (h *WebHandler) editableEvent(w http.ResponseWriter, r *http.Request, user *auth.User) (*models.Event, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	event, err := h.db.GetEventByID(id)
+	if err != nil {
+		http.Error(w, "Error retrieving event", http.StatusInternalServerError)
+		return nil, false
+	}
+	access := h.projectAccess(user)
+	if event == nil || !access.CanView(event.ProjectID) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !access.CanEdit(event.ProjectID) {
+		http.Error(w, "You can't edit events in this project", http.StatusForbidden)
+		return nil, false
+	}
+	return event, true
+}
+
+// visibleLinks drops the links to events of projects the user can't see
+func visibleLinks(access *models.ProjectAccess, links []models.EventLink) []models.EventLink {
+	var visible []models.EventLink
+	for _, link := range links {
+		if access.CanView(link.Event.ProjectID) {
+			visible = append(visible, link)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,37 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"log"
+	"net/http"
+)
+
+// eventLogLimit is the number of ingestion log entries shown on the admin page
+const eventLogLimit = 200
+
+// HandleEventLogs displays recent ingestion log entries to admins
+func (h *WebHandler) HandleEventLogs(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	entries, err := h.db.GetEventLogs(status, eventLogLimit)
+	if err != nil {
+		log.Printf("Error fetching event logs: %v", err)
+		http.Error(w, "Error fetching event logs", http.StatusInternalServerError)
+		return
+	}
+
+	statuses, err := h.db.GetEventLogStatusCounts()
+	if err != nil {
+		log.Printf("Error fetching event log statuses: %v", err)
+	}
+
+	data := TemplateData{
+		User:        auth.GetUserFromContext(r.Context()),
+		EventLogs:   entries,
+		LogStatuses: statuses,
+	}
+	data.Filter.Status = status
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "logs.html", data)
+}
@@ -0,0 +1,173 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"example-api/internal/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// SetMappingAddresses sets the domain and random part length of generated
+// mapping addresses
+func (h *WebHandler) SetMappingAddresses(domain string, length int) {
+	h.mappingDomain = domain
+	h.mappingLength = length
+}
+
+// HandleMappings lists the user's email mappings; admins see all of them
+func (h *WebHandler) HandleMappings(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	owner := user.Username
+	if user.Role == "admin" {
+		owner = ""
+	}
+
+	mappings, err := h.db.ListEmailMappings(owner)
+	if err != nil {
+		log.Printf("Error fetching email mappings: %v", err)
+		http.Error(w, "Error fetching email mappings", http.StatusInternalServerError)
+		return
+	}
+
+	projects := h.projects()
+	data := TemplateData{
+		User:       user,
+		Mappings:   mappings,
+		MailDomain: h.mappingDomain,
+		Projects:   editableProjects(h.projectAccess(user), projects),
+	}
+	if len(projects) > 1 {
+		data.ProjectNames = projectNames(projects)
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "mappings.html", data)
+}
+
+// HandleCreateMappingPost generates a new address with the submitted tags,
+// source and endpoint
+func (h *WebHandler) HandleCreateMappingPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if h.mappingDomain == "" {
+		h.setFlash(w, "No mail domain is configured (server.domain)", "error")
+		http.Redirect(w, r, "/mappings", http.StatusSeeOther)
+		return
+	}
+
+	endpoint := strings.TrimSpace(r.FormValue("endpoint_url"))
+	if endpoint != "" && !utils.ValidateEndpointURL(endpoint) {
+		h.setFlash(w, "Endpoint URL must start with http:// or https://", "error")
+		http.Redirect(w, r, "/mappings", http.StatusSeeOther)
+		return
+	}
+
+	// Events of the mapping go to the project chosen, which the user must
+	// be able to add events to
+	projectID := int64(models.DefaultProjectID)
+	if value := r.FormValue("project"); value != "" {
+		projectID, _ = strconv.ParseInt(value, 10, 64)
+	}
+	if !h.projectAccess(user).CanEdit(projectID) {
+		h.setFlash(w, "You can't add events to this project", "error")
+		http.Redirect(w, r, "/mappings", http.StatusSeeOther)
+		return
+	}
+
+	length := h.mappingLength
+	if length <= 0 {
+		length = 12
+	}
+	address, err := utils.GenerateEmailAddress(length, h.mappingDomain)
+	if err != nil {
+		log.Printf("Failed to generate address: %v", err)
+		http.Error(w, "Failed to generate address", http.StatusInternalServerError)
+		return
+	}
+
+	var tags []string
+	for _, tag := range splitTags(r.FormValue("tags")) {
+		tags = append(tags, strings.ToLower(tag))
+	}
+	mapping := &models.EmailMapping{
+		Owner:          user.Username,
+		GeneratedEmail: address,
+		Tags:           tags,
+		Source:         strings.TrimSpace(r.FormValue("source")),
+		EndpointURL:    endpoint,
+		Description:    strings.TrimSpace(r.FormValue("description")),
+		ProjectID:      projectID,
+		IsActive:       true,
+	}
+	if err := h.db.CreateEmailMapping(mapping); err != nil {
+		log.Printf("Failed to create mapping: %v", err)
+		h.setFlash(w, "Failed to create mapping", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Mail sent to %s will now be routed", address), "success")
+	}
+	http.Redirect(w, r, "/mappings", http.StatusSeeOther)
+}
+
+// HandleToggleMappingPost enables or disables a mapping
+func (h *WebHandler) HandleToggleMappingPost(w http.ResponseWriter, r *http.Request) {
+	mapping, ok := h.ownedMapping(w, r)
+	if !ok {
+		return
+	}
+
+	mapping.IsActive = !mapping.IsActive
+	if err := h.db.UpdateEmailMapping(mapping); err != nil {
+		log.Printf("Failed to update mapping %d: %v", mapping.ID, err)
+		h.setFlash(w, "Failed to update mapping", "error")
+	} else if mapping.IsActive {
+		h.setFlash(w, fmt.Sprintf("Enabled %s", mapping.GeneratedEmail), "success")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Disabled %s", mapping.GeneratedEmail), "success")
+	}
+	http.Redirect(w, r, "/mappings", http.StatusSeeOther)
+}
+
+// HandleDeleteMappingPost removes a mapping
+func (h *WebHandler) HandleDeleteMappingPost(w http.ResponseWriter, r *http.Request) {
+	mapping, ok := h.ownedMapping(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteEmailMapping(mapping.ID); err != nil {
+		log.Printf("Failed to delete mapping %d: %v", mapping.ID, err)
+		h.setFlash(w, "Failed to delete mapping", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Deleted %s", mapping.GeneratedEmail), "success")
+	}
+	http.Redirect(w, r, "/mappings", http.StatusSeeOther)
+}
+
+// ownedMapping loads the mapping named in the URL if the user owns it or is
+// an admin
+func (h *WebHandler) ownedMapping(w http.ResponseWriter, r *http.Request) (*models.EmailMapping, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid mapping ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	mapping, err := h.db.GetEmailMapping(id)
+	if err != nil {
+		log.Printf("Error fetching mapping %d: %v", id, err)
+		http.Error(w, "Error retrieving mapping", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	if mapping == nil || (mapping.Owner != user.Username && user.Role != "admin") {
+		http.Error(w, "Mapping not found", http.StatusNotFound)
+		return nil, false
+	}
+	return mapping, true
+}
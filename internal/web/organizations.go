@@ -0,0 +1,320 @@
+package web
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleAdminOrganizations lists the organizations, with a form for adding
+// one
+func (h *WebHandler) HandleAdminOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.db.ListOrganizations()
+	if err != nil {
+		log.Printf("Error fetching organizations: %v", err)
+		http.Error(w, "Error fetching organizations", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:          auth.GetUserFromContext(r.Context()),
+		Organizations: orgs,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "organizations.html", data)
+}
+
+// HandleCreateOrganizationPost adds an organization
+func (h *WebHandler) HandleCreateOrganizationPost(w http.ResponseWriter, r *http.Request) {
+	org := &models.Organization{
+		Slug: r.FormValue("slug"),
+		Name: r.FormValue("name"),
+	}
+	if err := org.Validate(); err != nil {
+		h.setFlash(w, "Invalid organization: "+err.Error(), "error")
+		http.Redirect(w, r, "/admin/organizations", http.StatusSeeOther)
+		return
+	}
+
+	err := h.db.CreateOrganization(org)
+	switch {
+	case errors.Is(err, database.ErrOrganizationExists):
+		h.setFlash(w, fmt.Sprintf("An organization with the slug %q already exists", org.Slug), "error")
+	case err != nil:
+		log.Printf("Error creating organization: %v", err)
+		h.setFlash(w, "Error creating organization", "error")
+	default:
+		h.setFlash(w, fmt.Sprintf("Created organization %q", org.Name), "success")
+		http.Redirect(w, r, fmt.Sprintf("/organizations/%d", org.ID), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/admin/organizations", http.StatusSeeOther)
+}
+
+// HandleDeleteOrganizationPost removes an organization without projects
+func (h *WebHandler) HandleDeleteOrganizationPost(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.managedOrganization(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := h.db.DeleteOrganization(org.ID)
+	switch {
+	case errors.Is(err, database.ErrOrganizationInUse):
+		h.setFlash(w, fmt.Sprintf("%q still has projects, or is the default organization", org.Name), "error")
+	case err != nil:
+		log.Printf("Error deleting organization %d: %v", org.ID, err)
+		h.setFlash(w, "Error deleting organization", "error")
+	default:
+		h.setFlash(w, fmt.Sprintf("Deleted %q", org.Name), "success")
+	}
+	http.Redirect(w, r, "/admin/organizations", http.StatusSeeOther)
+}
+
+// HandleOrganization shows an organization's projects, members, API tokens
+// and usage to those who can manage it
+func (h *WebHandler) HandleOrganization(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.managedOrganization(w, r)
+	if !ok {
+		return
+	}
+	h.renderOrganization(w, r, org, TemplateData{})
+}
+
+// renderOrganization renders an organization's management page
+func (h *WebHandler) renderOrganization(w http.ResponseWriter, r *http.Request, org *models.Organization, data TemplateData) {
+	members, err := h.db.OrganizationMembers(org.ID)
+	if err != nil {
+		log.Printf("Error fetching members of organization %d: %v", org.ID, err)
+		http.Error(w, "Error fetching organization members", http.StatusInternalServerError)
+		return
+	}
+	tokens, err := h.db.ListOrganizationTokens(org.ID)
+	if err != nil {
+		log.Printf("Error fetching API tokens of organization %d: %v", org.ID, err)
+		http.Error(w, "Error fetching API tokens", http.StatusInternalServerError)
+		return
+	}
+	usage, err := h.db.OrganizationUsage(org.ID, 30)
+	if err != nil {
+		log.Printf("Error fetching usage of organization %d: %v", org.ID, err)
+		http.Error(w, "Error fetching usage", http.StatusInternalServerError)
+		return
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	prefs := h.preferences(user)
+	for i := range tokens {
+		tokens[i].CreatedAt = tokens[i].CreatedAt.In(prefs.Location())
+		if !tokens[i].LastUsedAt.IsZero() {
+			tokens[i].LastUsedAt = tokens[i].LastUsedAt.In(prefs.Location())
+		}
+	}
+	for i := range usage.Projects {
+		if newest := usage.Projects[i].NewestEvent; newest != nil {
+			local := newest.In(prefs.Location())
+			usage.Projects[i].NewestEvent = &local
+		}
+	}
+
+	data.User = user
+	data.Organization = org
+	data.OrganizationMembers = members
+	data.OrganizationRoles = models.OrganizationRoles
+	data.Usage = usage
+	data.APITokens = tokens
+	data.ProjectRoles = models.ProjectRoles
+	data.Preferences = prefs
+	data.Stats.EventsPerDay = usage.EventsPerDay
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "organization.html", data)
+}
+
+// HandleCreateOrganizationProjectPost adds a project to an organization
+func (h *WebHandler) HandleCreateOrganizationProjectPost(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.managedOrganization(w, r)
+	if !ok {
+		return
+	}
+	h.createProject(w, r, org.ID, fmt.Sprintf("/organizations/%d", org.ID))
+}
+
+// HandleSetOrganizationMemberPost gives a user a role in an organization
+func (h *WebHandler) HandleSetOrganizationMemberPost(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.managedOrganization(w, r)
+	if !ok {
+		return
+	}
+	orgURL := fmt.Sprintf("/organizations/%d", org.ID)
+
+	role := r.FormValue("role")
+	if !slices.Contains(models.OrganizationRoles, role) {
+		h.setFlash(w, "Choose a role", "error")
+		http.Redirect(w, r, orgURL, http.StatusSeeOther)
+		return
+	}
+	username := strings.TrimSpace(r.FormValue("username"))
+	user, err := h.db.GetUser(username)
+	if err != nil {
+		log.Printf("Error fetching user %s: %v", username, err)
+		h.setFlash(w, "Error retrieving user", "error")
+		http.Redirect(w, r, orgURL, http.StatusSeeOther)
+		return
+	}
+	if user == nil {
+		h.setFlash(w, fmt.Sprintf("There is no user %q", username), "error")
+		http.Redirect(w, r, orgURL, http.StatusSeeOther)
+		return
+	}
+
+	member := &models.OrganizationMember{OrganizationID: org.ID, Username: user.Username, Role: role}
+	if err := h.db.SetOrganizationMember(member); err != nil {
+		log.Printf("Error setting member of organization %d: %v", org.ID, err)
+		h.setFlash(w, "Error setting organization member", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("%s is now %s of %q", user.Username, role, org.Name), "success")
+	}
+	http.Redirect(w, r, orgURL, http.StatusSeeOther)
+}
+
+// HandleRemoveOrganizationMemberPost takes a user's role in an organization
+// away
+func (h *WebHandler) HandleRemoveOrganizationMemberPost(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.managedOrganization(w, r)
+	if !ok {
+		return
+	}
+
+	username := mux.Vars(r)["username"]
+	if err := h.db.RemoveOrganizationMember(org.ID, username); err != nil {
+		log.Printf("Error removing member of organization %d: %v", org.ID, err)
+		h.setFlash(w, "Error removing organization member", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Removed %s from %q", username, org.Name), "success")
+	}
+	http.Redirect(w, r, fmt.Sprintf("/organizations/%d", org.ID), http.StatusSeeOther)
+}
+
+// HandleCreateOrganizationTokenPost creates an API token for every project
+// of an organization and shows it, the only time it can be seen
+func (h *WebHandler) HandleCreateOrganizationTokenPost(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.managedOrganization(w, r)
+	if !ok {
+		return
+	}
+	orgURL := fmt.Sprintf("/organizations/%d", org.ID)
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	role := r.FormValue("role")
+	if name == "" || !slices.Contains(models.ProjectRoles, role) {
+		h.setFlash(w, "Enter a name and choose a role for the token", "error")
+		http.Redirect(w, r, orgURL, http.StatusSeeOther)
+		return
+	}
+
+	secret, hash, err := auth.NewAPIToken()
+	if err != nil {
+		log.Printf("Error generating API token: %v", err)
+		h.setFlash(w, "Error generating API token", "error")
+		http.Redirect(w, r, orgURL, http.StatusSeeOther)
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	token := &models.APIToken{Name: name, TokenHash: hash, OrganizationID: org.ID, Role: role, CreatedBy: user.Username}
+	if err := h.db.CreateAPIToken(token); err != nil {
+		log.Printf("Error creating API token for organization %d: %v", org.ID, err)
+		h.setFlash(w, "Error creating API token", "error")
+		http.Redirect(w, r, orgURL, http.StatusSeeOther)
+		return
+	}
+	h.auth.RecordAudit(auth.AuditTokenIssued, user.Username, fmt.Sprintf("organization:%d:token:%d", org.ID, token.ID), auth.ClientIP(r), role+" token "+strconv.Quote(name))
+
+	var data TemplateData
+	data.NewToken = secret
+	h.renderOrganization(w, r, org, data)
+}
+
+// HandleDeleteOrganizationTokenPost revokes an API token of an organization
+func (h *WebHandler) HandleDeleteOrganizationTokenPost(w http.ResponseWriter, r *http.Request) {
+	org, ok := h.managedOrganization(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(mux.Vars(r)["tokenID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.db.DeleteOrganizationToken(org.ID, id)
+	switch {
+	case err != nil:
+		log.Printf("Error deleting API token %d: %v", id, err)
+		h.setFlash(w, "Error revoking API token", "error")
+	case !deleted:
+		h.setFlash(w, "API token not found", "error")
+	default:
+		h.setFlash(w, "API token revoked", "success")
+	}
+	http.Redirect(w, r, fmt.Sprintf("/organizations/%d", org.ID), http.StatusSeeOther)
+}
+
+// managedOrganization loads the organization named in the URL, responding
+// with an error unless the user can manage it
+func (h *WebHandler) managedOrganization(w http.ResponseWriter, r *http.Request) (*models.Organization, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return nil, false
+	}
+	if !h.projectAccess(auth.GetUserFromContext(r.Context())).CanManage(id) {
+		http.Error(w, "Only admins of the organization can manage it", http.StatusForbidden)
+		return nil, false
+	}
+
+	org, err := h.db.GetOrganization(id)
+	if err != nil {
+		log.Printf("Error fetching organization %d: %v", id, err)
+		http.Error(w, "Error retrieving organization", http.StatusInternalServerError)
+		return nil, false
+	}
+	if org == nil {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return nil, false
+	}
+	return org, true
+}
+
+// memberOrganizations returns the organizations the user belongs to, with
+// their role in each
+func (h *WebHandler) memberOrganizations(user *auth.User) ([]models.Organization, map[int64]string) {
+	access := h.projectAccess(user)
+	if len(access.Organizations) == 0 {
+		return nil, nil
+	}
+	orgs, err := h.db.ListOrganizations()
+	if err != nil {
+		log.Printf("Error loading organizations: %v", err)
+		return nil, nil
+	}
+
+	var member []models.Organization
+	for _, org := range orgs {
+		if access.Organizations[org.ID] != "" {
+			member = append(member, org)
+		}
+	}
+	return member, access.Organizations
+}
@@ -0,0 +1,194 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eventsPerPage is the number of events shown on each page of the events list
+const eventsPerPage = 20
+
+// pageWindow is the number of page links shown on each side of the current page
+const pageWindow = 3
+
+// PageLink is a numbered link in the pagination controls
+type PageLink struct {
+	Number int
+	URL    string
+	Active bool
+}
+
+// setPagination fills in the pagination fields of the template data, building
+// links that preserve the request's other query parameters
+func setPagination(data *TemplateData, r *http.Request, page, totalItems, perPage int) {
+	totalPages := (totalItems + perPage - 1) / perPage
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	data.Pagination.CurrentPage = page
+	data.Pagination.TotalPages = totalPages
+	data.Pagination.TotalItems = totalItems
+	data.Pagination.ItemsPerPage = perPage
+
+	if totalItems > 0 {
+		data.Pagination.FirstItem = (page-1)*perPage + 1
+		data.Pagination.LastItem = data.Pagination.FirstItem + len(data.Events) - 1
+	}
+
+	if page > 1 {
+		data.Pagination.PrevURL = pageURL(r, page-1)
+	}
+	if page < totalPages {
+		data.Pagination.NextURL = pageURL(r, page+1)
+	}
+
+	first := page - pageWindow
+	if first < 1 {
+		first = 1
+	}
+	last := page + pageWindow
+	if last > totalPages {
+		last = totalPages
+	}
+	for n := first; n <= last; n++ {
+		data.Pagination.Pages = append(data.Pagination.Pages, PageLink{
+			Number: n,
+			URL:    pageURL(r, n),
+			Active: n == page,
+		})
+	}
+}
+
+// pageURL returns the current URL with its page parameter replaced
+func pageURL(r *http.Request, page int) string {
+	query := r.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// sortDefaults lists the sortable columns and whether each sorts descending by default
+var sortDefaults = map[string]bool{
+	"created_at": true,
+	"id":         true,
+	"source":     false,
+}
+
+// parseSort reads the sort and order query parameters, falling back to newest first
+func parseSort(r *http.Request) (string, bool) {
+	sortBy := r.URL.Query().Get("sort")
+	defaultDesc, ok := sortDefaults[sortBy]
+	if !ok {
+		return "created_at", true
+	}
+
+	switch r.URL.Query().Get("order") {
+	case "asc":
+		return sortBy, false
+	case "desc":
+		return sortBy, true
+	}
+	return sortBy, defaultDesc
+}
+
+// setSort fills in the sort state and the header links that toggle it
+func setSort(data *TemplateData, r *http.Request, sortBy string, desc bool) {
+	data.Sort.Field = sortBy
+	data.Sort.Desc = desc
+	data.Sort.Links = make(map[string]string)
+
+	for field, defaultDesc := range sortDefaults {
+		nextDesc := defaultDesc
+		if field == sortBy {
+			nextDesc = !desc
+		}
+
+		order := "asc"
+		if nextDesc {
+			order = "desc"
+		}
+
+		query := r.URL.Query()
+		query.Set("sort", field)
+		query.Set("order", order)
+		query.Del("page")
+		data.Sort.Links[field] = r.URL.Path + "?" + query.Encode()
+	}
+}
+
+// parseEventFilter reads the events list filter and sort query parameters.
+// starred=1 keeps the events user starred, status one of
+// models.EventStatuses the events with that status, and due=soon or
+// due=overdue the unresolved events approaching or past their due date,
+// label (repeatable) the events with all of those labels, bbox
+// (west,south,east,north) the events located inside that area, and incident
+// the events grouped in that incident. The from and to dates are days in
+// loc, the user's time zone.
+func parseEventFilter(r *http.Request, user *auth.User, loc *time.Location) models.EventFilter {
+	query := r.URL.Query()
+
+	var tags []string
+	for _, tag := range query["tag"] {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	filter := models.EventFilter{
+		Tags:     tags,
+		MatchAll: query.Get("match") == "all",
+		DateFrom: query.Get("from"),
+		DateTo:   query.Get("to"),
+		Location: loc,
+		Source:   query.Get("source"),
+		Query:    strings.TrimSpace(query.Get("q")),
+	}
+	if status := query.Get("status"); slices.Contains(models.EventStatuses, status) {
+		filter.Status = status
+	}
+	if due := query.Get("due"); slices.Contains(models.DueFilters, due) {
+		filter.Due = due
+	}
+	for _, label := range query["label"] {
+		if label = strings.ToLower(strings.TrimSpace(label)); label != "" {
+			filter.Labels = append(filter.Labels, label)
+		}
+	}
+	if bbox := query.Get("bbox"); bbox != "" {
+		if box, err := models.ParseBoundingBox(bbox); err == nil {
+			filter.BBox = box
+		}
+	}
+	if id, err := strconv.ParseInt(query.Get("incident"), 10, 64); err == nil && id > 0 {
+		filter.Incident = id
+	}
+	if query.Get("starred") != "" && user != nil {
+		filter.StarredBy = user.Username
+	}
+	filter.SortBy, filter.SortDesc = parseSort(r)
+
+	// Support the old single-day filter in existing links
+	if date := query.Get("date"); date != "" && filter.DateFrom == "" && filter.DateTo == "" {
+		filter.DateFrom, filter.DateTo = date, date
+	}
+
+	// Swap a reversed range rather than returning nothing
+	if filter.DateFrom != "" && filter.DateTo != "" && filter.DateFrom > filter.DateTo {
+		filter.DateFrom, filter.DateTo = filter.DateTo, filter.DateFrom
+	}
+
+	return filter
+}
+
+// exportURL returns the export endpoint URL for the current filters in the given format
+func exportURL(r *http.Request, format string) string {
+	query := r.URL.Query()
+	query.Del("page")
+	query.Set("format", format)
+	return "/events/export?" + query.Encode()
+}
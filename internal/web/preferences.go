@@ -0,0 +1,171 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/digest"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pageSizes lists the events-per-page choices offered on the settings page
+var pageSizes = []int{10, 20, 50, 100}
+
+// filterParams lists the events list parameters that can be saved as a default filter
+var filterParams = []string{"tag", "match", "from", "to", "source", "project", "q", "starred", "status", "due", "label", "bbox", "expand", "sort", "order"}
+
+// preferences returns the user's saved preferences, or the defaults
+func (h *WebHandler) preferences(user *auth.User) *models.UserPreferences {
+	if user == nil {
+		return models.DefaultPreferences("")
+	}
+	prefs, err := h.db.GetPreferences(user.Username)
+	if err != nil {
+		log.Printf("Error loading preferences for %s: %v", user.Username, err)
+	}
+	if prefs == nil {
+		return models.DefaultPreferences(user.Username)
+	}
+	return prefs
+}
+
+// localizeEvents converts event timestamps to the given time zone for display
+func localizeEvents(events []models.Event, loc *time.Location) {
+	for i := range events {
+		events[i].CreatedAt = events[i].CreatedAt.In(loc)
+		if events[i].DueAt != nil {
+			due := events[i].DueAt.In(loc)
+			events[i].DueAt = &due
+		}
+	}
+}
+
+// cleanFilterQuery keeps only the events list filter parameters of a query
+// string, including custom field filters
+func cleanFilterQuery(raw string) string {
+	parsed, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(raw), "?"))
+	if err != nil {
+		return ""
+	}
+	kept := url.Values{}
+	for key, values := range parsed {
+		if !slices.Contains(filterParams, key) && !strings.HasPrefix(key, fieldParamPrefix) {
+			continue
+		}
+		for _, value := range values {
+			if value != "" {
+				kept.Add(key, value)
+			}
+		}
+	}
+	return kept.Encode()
+}
+
+// HandleSettings displays the current user's preferences
+func (h *WebHandler) HandleSettings(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+
+	data := TemplateData{
+		User:        user,
+		Preferences: h.preferences(user),
+		PageSizes:   pageSizes,
+	}
+	data.Theme = data.Preferences.Theme
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "settings.html", data)
+}
+
+// HandleSettingsPost saves the current user's preferences
+func (h *WebHandler) HandleSettingsPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	prefs := h.preferences(user)
+
+	pageSize, err := strconv.Atoi(r.FormValue("page_size"))
+	if err != nil || !validPageSize(pageSize) {
+		h.setFlash(w, "Invalid page size", "error")
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+
+	timezone := strings.TrimSpace(r.FormValue("timezone"))
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		h.setFlash(w, fmt.Sprintf("Unknown time zone %q", timezone), "error")
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+
+	theme := r.FormValue("theme")
+	if theme != "dark" {
+		theme = "light"
+	}
+
+	period := r.FormValue("digest")
+	if period != "" && !slices.Contains(digest.Periods, period) {
+		h.setFlash(w, "Invalid digest frequency", "error")
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+	digestEmail := strings.TrimSpace(r.FormValue("digest_email"))
+	if digestEmail != "" {
+		if _, err := mail.ParseAddress(digestEmail); err != nil {
+			h.setFlash(w, fmt.Sprintf("Invalid email address %q", digestEmail), "error")
+			http.Redirect(w, r, "/settings", http.StatusSeeOther)
+			return
+		}
+	}
+
+	prefs.PageSize = pageSize
+	prefs.Timezone = timezone
+	prefs.Theme = theme
+	prefs.Digest = period
+	prefs.DigestEmail = digestEmail
+	prefs.DefaultFilter = cleanFilterQuery(r.FormValue("default_filter"))
+
+	if err := h.db.SavePreferences(prefs); err != nil {
+		log.Printf("Error saving preferences for %s: %v", user.Username, err)
+		h.setFlash(w, "Error saving preferences", "error")
+	} else {
+		h.setFlash(w, "Preferences saved", "success")
+	}
+
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+// HandleSaveDefaultFilterPost stores the events list's current filters as the user's default
+func (h *WebHandler) HandleSaveDefaultFilterPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	prefs := h.preferences(user)
+	prefs.DefaultFilter = cleanFilterQuery(r.FormValue("filter"))
+
+	if err := h.db.SavePreferences(prefs); err != nil {
+		log.Printf("Error saving default filter for %s: %v", user.Username, err)
+		h.setFlash(w, "Error saving default filter", "error")
+	} else if prefs.DefaultFilter == "" {
+		h.setFlash(w, "Default filter cleared", "success")
+	} else {
+		h.setFlash(w, "Default filter saved", "success")
+	}
+
+	http.Redirect(w, r, "/?"+prefs.DefaultFilter, http.StatusSeeOther)
+}
+
+// validPageSize reports whether n is one of the offered page sizes
+func validPageSize(n int) bool {
+	for _, size := range pageSizes {
+		if n == size {
+			return true
+		}
+	}
+	return false
+}
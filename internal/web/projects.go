@@ -0,0 +1,394 @@
+package web
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/database"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// projectAccess returns what the user can do in each project. If it can't be
+// loaded, they can only read the default project.
+func (h *WebHandler) projectAccess(user *auth.User) *models.ProjectAccess {
+	readOnly := &models.ProjectAccess{Roles: map[int64]string{models.DefaultProjectID: models.ProjectViewer}}
+	if user == nil {
+		return readOnly
+	}
+	access, err := h.db.ProjectAccess(user.Username, user.Role)
+	if err != nil {
+		log.Printf("Error loading project access of %s: %v", user.Username, err)
+		return readOnly
+	}
+	return access
+}
+
+// projects returns every project, or none if they can't be loaded
+func (h *WebHandler) projects() []models.Project {
+	projects, err := h.db.ListProjects()
+	if err != nil {
+		log.Printf("Error loading projects: %v", err)
+	}
+	return projects
+}
+
+// editableProjects returns the projects the user can add events to
+func editableProjects(access *models.ProjectAccess, projects []models.Project) []models.Project {
+	var editable []models.Project
+	for _, project := range projects {
+		if access.CanEdit(project.ID) {
+			editable = append(editable, project)
+		}
+	}
+	return editable
+}
+
+// visibleEvents drops the events of projects the user can't see
+func visibleEvents(access *models.ProjectAccess, events []models.Event) []models.Event {
+	var visible []models.Event
+	for _, event := range events {
+		if access.CanView(event.ProjectID) {
+			visible = append(visible, event)
+		}
+	}
+	return visible
+}
+
+// projectNames maps project IDs to names for showing them next to events
+func projectNames(projects []models.Project) map[int64]string {
+	names := make(map[int64]string, len(projects))
+	for _, project := range projects {
+		names[project.ID] = project.Name
+	}
+	return names
+}
+
+// scopeFilter limits a filter to the projects the user can see, or to the
+// one whose slug is in the project parameter. It returns the slug of the
+// project filtered on, if it is known and visible.
+func scopeFilter(r *http.Request, filter *models.EventFilter, access *models.ProjectAccess, projects []models.Project) string {
+	filter.Projects = access.Viewable()
+	slug := r.URL.Query().Get("project")
+	if slug == "" {
+		return ""
+	}
+	for _, project := range projects {
+		if project.Slug == slug && access.CanView(project.ID) {
+			filter.Projects = []int64{project.ID}
+			return slug
+		}
+	}
+	return ""
+}
+
+// parseProject reads the project chosen in the event form, or the default
+// project if there is no choice to make. It records an error on the form
+// unless the user can add events to it.
+func (f *eventForm) parseProject(r *http.Request, access *models.ProjectAccess) {
+	f.ProjectID = models.DefaultProjectID
+	if value := r.FormValue("project"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			f.Errors["project"] = "Choose a project"
+			return
+		}
+		f.ProjectID = id
+	}
+	if !access.CanEdit(f.ProjectID) {
+		f.Errors["project"] = "You can't add events to this project"
+	}
+}
+
+// HandleAdminProjects lists the projects, with a form for adding one
+func (h *WebHandler) HandleAdminProjects(w http.ResponseWriter, r *http.Request) {
+	projects, err := h.db.ListProjects()
+	if err != nil {
+		log.Printf("Error fetching projects: %v", err)
+		http.Error(w, "Error fetching projects", http.StatusInternalServerError)
+		return
+	}
+	orgs, err := h.db.ListOrganizations()
+	if err != nil {
+		log.Printf("Error fetching organizations: %v", err)
+		http.Error(w, "Error fetching organizations", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:          auth.GetUserFromContext(r.Context()),
+		Projects:      projects,
+		Organizations: orgs,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "projects.html", data)
+}
+
+// HandleCreateProjectPost adds a project to the organization chosen in the
+// form
+func (h *WebHandler) HandleCreateProjectPost(w http.ResponseWriter, r *http.Request) {
+	orgID, err := strconv.ParseInt(r.FormValue("organization"), 10, 64)
+	if err != nil {
+		orgID = models.DefaultOrganizationID
+	}
+	h.createProject(w, r, orgID, "/admin/projects")
+}
+
+// createProject adds a project to an organization from the submitted form,
+// going back to backURL if it can't
+func (h *WebHandler) createProject(w http.ResponseWriter, r *http.Request, orgID int64, backURL string) {
+	project := &models.Project{
+		OrganizationID: orgID,
+		Slug:           r.FormValue("slug"),
+		Name:           r.FormValue("name"),
+	}
+	if err := project.Validate(); err != nil {
+		h.setFlash(w, "Invalid project: "+err.Error(), "error")
+		http.Redirect(w, r, backURL, http.StatusSeeOther)
+		return
+	}
+
+	err := h.db.CreateProject(project)
+	switch {
+	case errors.Is(err, database.ErrProjectExists):
+		h.setFlash(w, fmt.Sprintf("A project with the slug %q already exists", project.Slug), "error")
+	case errors.Is(err, database.ErrOrganizationNotFound):
+		h.setFlash(w, "Organization not found", "error")
+	case err != nil:
+		log.Printf("Error creating project: %v", err)
+		h.setFlash(w, "Error creating project", "error")
+	default:
+		h.setFlash(w, fmt.Sprintf("Created project %q", project.Name), "success")
+		http.Redirect(w, r, fmt.Sprintf("/projects/%d", project.ID), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, backURL, http.StatusSeeOther)
+}
+
+// HandleProject shows a project's members and API tokens to those who can
+// manage it
+func (h *WebHandler) HandleProject(w http.ResponseWriter, r *http.Request) {
+	project, ok := h.managedProject(w, r)
+	if !ok {
+		return
+	}
+	h.renderProject(w, r, project, TemplateData{})
+}
+
+// renderProject renders a project's management page
+func (h *WebHandler) renderProject(w http.ResponseWriter, r *http.Request, project *models.Project, data TemplateData) {
+	members, err := h.db.ProjectMembers(project.ID)
+	if err != nil {
+		log.Printf("Error fetching members of project %d: %v", project.ID, err)
+		http.Error(w, "Error fetching project members", http.StatusInternalServerError)
+		return
+	}
+	tokens, err := h.db.ListAPITokens(project.ID)
+	if err != nil {
+		log.Printf("Error fetching API tokens of project %d: %v", project.ID, err)
+		http.Error(w, "Error fetching API tokens", http.StatusInternalServerError)
+		return
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	prefs := h.preferences(user)
+	for i := range tokens {
+		tokens[i].CreatedAt = tokens[i].CreatedAt.In(prefs.Location())
+		if !tokens[i].LastUsedAt.IsZero() {
+			tokens[i].LastUsedAt = tokens[i].LastUsedAt.In(prefs.Location())
+		}
+	}
+
+	data.User = user
+	data.Project = project
+	data.ProjectMembers = members
+	data.APITokens = tokens
+	data.ProjectRoles = models.ProjectRoles
+	data.Preferences = prefs
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "project.html", data)
+}
+
+// HandleDeleteProjectPost removes an empty project
+func (h *WebHandler) HandleDeleteProjectPost(w http.ResponseWriter, r *http.Request) {
+	project, ok := h.managedProject(w, r)
+	if !ok {
+		return
+	}
+
+	_, err := h.db.DeleteProject(project.ID)
+	switch {
+	case errors.Is(err, database.ErrProjectInUse):
+		h.setFlash(w, fmt.Sprintf("%q still has events or email mappings, or is the default project", project.Name), "error")
+		http.Redirect(w, r, fmt.Sprintf("/projects/%d", project.ID), http.StatusSeeOther)
+		return
+	case err != nil:
+		log.Printf("Error deleting project %d: %v", project.ID, err)
+		h.setFlash(w, "Error deleting project", "error")
+	default:
+		h.setFlash(w, fmt.Sprintf("Deleted %q", project.Name), "success")
+	}
+	http.Redirect(w, r, fmt.Sprintf("/organizations/%d", project.OrganizationID), http.StatusSeeOther)
+}
+
+// HandleSetProjectMemberPost gives a user a role in a project
+func (h *WebHandler) HandleSetProjectMemberPost(w http.ResponseWriter, r *http.Request) {
+	project, ok := h.managedProject(w, r)
+	if !ok {
+		return
+	}
+	projectURL := fmt.Sprintf("/projects/%d", project.ID)
+
+	role := r.FormValue("role")
+	if !slices.Contains(models.ProjectRoles, role) {
+		h.setFlash(w, "Choose a role", "error")
+		http.Redirect(w, r, projectURL, http.StatusSeeOther)
+		return
+	}
+	username := strings.TrimSpace(r.FormValue("username"))
+	user, err := h.db.GetUser(username)
+	if err != nil {
+		log.Printf("Error fetching user %s: %v", username, err)
+		h.setFlash(w, "Error retrieving user", "error")
+		http.Redirect(w, r, projectURL, http.StatusSeeOther)
+		return
+	}
+	if user == nil {
+		h.setFlash(w, fmt.Sprintf("There is no user %q", username), "error")
+		http.Redirect(w, r, projectURL, http.StatusSeeOther)
+		return
+	}
+
+	member := &models.ProjectMember{ProjectID: project.ID, Username: user.Username, Role: role}
+	if err := h.db.SetProjectMember(member); err != nil {
+		log.Printf("Error setting member of project %d: %v", project.ID, err)
+		h.setFlash(w, "Error setting project member", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("%s is now %s of %q", user.Username, role, project.Name), "success")
+	}
+	http.Redirect(w, r, projectURL, http.StatusSeeOther)
+}
+
+// HandleRemoveProjectMemberPost takes a user's role in a project away
+func (h *WebHandler) HandleRemoveProjectMemberPost(w http.ResponseWriter, r *http.Request) {
+	project, ok := h.managedProject(w, r)
+	if !ok {
+		return
+	}
+
+	username := mux.Vars(r)["username"]
+	if err := h.db.RemoveProjectMember(project.ID, username); err != nil {
+		log.Printf("Error removing member of project %d: %v", project.ID, err)
+		h.setFlash(w, "Error removing project member", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Removed %s from %q", username, project.Name), "success")
+	}
+	http.Redirect(w, r, fmt.Sprintf("/projects/%d", project.ID), http.StatusSeeOther)
+}
+
+// HandleCreateAPITokenPost creates an API token for a project and shows it,
+// the only time it can be seen
+func (h *WebHandler) HandleCreateAPITokenPost(w http.ResponseWriter, r *http.Request) {
+	project, ok := h.managedProject(w, r)
+	if !ok {
+		return
+	}
+	projectURL := fmt.Sprintf("/projects/%d", project.ID)
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	role := r.FormValue("role")
+	if name == "" || !slices.Contains(models.ProjectRoles, role) {
+		h.setFlash(w, "Enter a name and choose a role for the token", "error")
+		http.Redirect(w, r, projectURL, http.StatusSeeOther)
+		return
+	}
+
+	secret, hash, err := auth.NewAPIToken()
+	if err != nil {
+		log.Printf("Error generating API token: %v", err)
+		h.setFlash(w, "Error generating API token", "error")
+		http.Redirect(w, r, projectURL, http.StatusSeeOther)
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	token := &models.APIToken{
+		Name:           name,
+		TokenHash:      hash,
+		OrganizationID: project.OrganizationID,
+		ProjectID:      project.ID,
+		Role:           role,
+		CreatedBy:      user.Username,
+	}
+	if err := h.db.CreateAPIToken(token); err != nil {
+		log.Printf("Error creating API token for project %d: %v", project.ID, err)
+		h.setFlash(w, "Error creating API token", "error")
+		http.Redirect(w, r, projectURL, http.StatusSeeOther)
+		return
+	}
+	h.auth.RecordAudit(auth.AuditTokenIssued, user.Username, fmt.Sprintf("project:%d:token:%d", project.ID, token.ID), auth.ClientIP(r), role+" token "+strconv.Quote(name))
+
+	var data TemplateData
+	data.NewToken = secret
+	h.renderProject(w, r, project, data)
+}
+
+// HandleDeleteAPITokenPost revokes an API token of a project
+func (h *WebHandler) HandleDeleteAPITokenPost(w http.ResponseWriter, r *http.Request) {
+	project, ok := h.managedProject(w, r)
+	if !ok {
+		return
+	}
+	projectURL := fmt.Sprintf("/projects/%d", project.ID)
+
+	id, err := strconv.ParseInt(mux.Vars(r)["tokenID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.db.DeleteAPIToken(project.ID, id)
+	switch {
+	case err != nil:
+		log.Printf("Error deleting API token %d: %v", id, err)
+		h.setFlash(w, "Error revoking API token", "error")
+	case !deleted:
+		h.setFlash(w, "API token not found", "error")
+	default:
+		h.setFlash(w, "API token revoked", "success")
+	}
+	http.Redirect(w, r, projectURL, http.StatusSeeOther)
+}
+
+// managedProject loads the project named in the URL, responding with an
+// error unless the user can manage its organization
+func (h *WebHandler) managedProject(w http.ResponseWriter, r *http.Request) (*models.Project, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	project, err := h.db.GetProject(id)
+	if err != nil {
+		log.Printf("Error fetching project %d: %v", id, err)
+		http.Error(w, "Error retrieving project", http.StatusInternalServerError)
+		return nil, false
+	}
+	if project == nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !h.projectAccess(auth.GetUserFromContext(r.Context())).CanManage(project.OrganizationID) {
+		http.Error(w, "Only admins of the project's organization can manage it", http.StatusForbidden)
+		return nil, false
+	}
+	return project, true
+}
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// quarantinePageSize is how many quarantined events the review queue shows
+const quarantinePageSize = 200
+
+// HandleAdminQuarantine lists the events held back for review
+func (h *WebHandler) HandleAdminQuarantine(w http.ResponseWriter, r *http.Request) {
+	items, err := h.db.ListQuarantine(quarantinePageSize)
+	if err != nil {
+		log.Printf("Error fetching quarantine: %v", err)
+		http.Error(w, "Error fetching quarantine", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:       auth.GetUserFromContext(r.Context()),
+		Quarantine: items,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "quarantine.html", data)
+}
+
+// HandleReleaseQuarantinedPost stores a quarantined event
+func (h *WebHandler) HandleReleaseQuarantinedPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid quarantine ID", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.db.ReleaseQuarantined(id)
+	switch {
+	case err != nil:
+		log.Printf("Error releasing quarantined event %d: %v", id, err)
+		h.setFlash(w, fmt.Sprintf("Error releasing event: %v", err), "error")
+	case event == nil:
+		h.setFlash(w, "That event is no longer in the quarantine", "error")
+	default:
+		h.setFlash(w, fmt.Sprintf("Released as event #%d", event.ID), "success")
+	}
+	http.Redirect(w, r, "/admin/quarantine", http.StatusSeeOther)
+}
+
+// HandleDeleteQuarantinedPost discards a quarantined event
+func (h *WebHandler) HandleDeleteQuarantinedPost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid quarantine ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.db.DeleteQuarantined(id); err != nil {
+		log.Printf("Error deleting quarantined event %d: %v", id, err)
+		h.setFlash(w, fmt.Sprintf("Error deleting event: %v", err), "error")
+	} else {
+		h.setFlash(w, "Deleted quarantined event", "success")
+	}
+	http.Redirect(w, r, "/admin/quarantine", http.StatusSeeOther)
+}
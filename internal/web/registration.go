@@ -0,0 +1,172 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/mailer"
+	"example-api/internal/models"
+	"example-api/internal/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EnableRegistration turns on the invite-gated /register page
+func (h *WebHandler) EnableRegistration(m *mailer.Mailer, inviteExpiry time.Duration) {
+	h.allowRegistration = true
+	h.mailer = m
+	h.inviteExpiry = inviteExpiry
+}
+
+// HandleRegister displays the registration form
+func (h *WebHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	if !h.allowRegistration {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := TemplateData{}
+	if msg := r.URL.Query().Get("error"); msg != "" {
+		data.FlashMessage = msg
+		data.FlashType = "error"
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "register.html", data)
+}
+
+// HandleRegisterPost redeems an invite code and creates an unverified user
+func (h *WebHandler) HandleRegisterPost(w http.ResponseWriter, r *http.Request) {
+	if !h.allowRegistration {
+		http.NotFound(w, r)
+		return
+	}
+
+	code := strings.TrimSpace(r.FormValue("invite_code"))
+	username := strings.TrimSpace(r.FormValue("username"))
+	email := utils.SanitizeEmail(r.FormValue("email"))
+	password := r.FormValue("password")
+
+	fail := func(msg string) {
+		http.Redirect(w, r, "/register?error="+url.QueryEscape(msg), http.StatusSeeOther)
+	}
+
+	if username == "" || email == "" || !strings.Contains(email, "@") {
+		fail("Username and a valid email address are required.")
+		return
+	}
+
+	invite, err := h.db.GetInviteCode(code)
+	if err != nil {
+		log.Printf("Error looking up invite code: %v", err)
+		fail("Unable to validate invite code. Please try again later.")
+		return
+	}
+	if invite == nil || !invite.IsUsable() {
+		log.Printf("Registration attempt with invalid invite code from %s", auth.ClientIP(r))
+		fail("Invalid or expired invite code.")
+		return
+	}
+
+	_, token, err := h.auth.RegisterUser(username, email, password, invite.Role)
+	if err != nil {
+		log.Printf("Registration failed for %s: %v", username, err)
+		fail(fmt.Sprintf("Registration failed: %v", err))
+		return
+	}
+
+	if err := h.db.RedeemInviteCode(code, username); err != nil {
+		log.Printf("Failed to redeem invite code for %s: %v", username, err)
+		_ = h.auth.DeleteUser(username)
+		fail("Invalid or expired invite code.")
+		return
+	}
+
+	verifyURL := fmt.Sprintf("%s/verify?token=%s", requestBaseURL(r), token)
+	body := fmt.Sprintf("Welcome to Event Database, %s!\n\nConfirm your email address to activate your account:\n\n%s\n\nThis link expires in 24 hours.\n", username, verifyURL)
+	if err := h.mailer.Send(email, "Verify your Event Database account", body); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", email, err)
+	}
+
+	http.Redirect(w, r, "/login?message="+url.QueryEscape("Account created. Check your email to verify your address before logging in."), http.StatusSeeOther)
+}
+
+// HandleVerifyEmail activates an account from the emailed verification link
+func (h *WebHandler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	user, err := h.auth.VerifyEmail(r.URL.Query().Get("token"))
+	if err != nil {
+		log.Printf("Email verification failed: %v", err)
+		http.Redirect(w, r, "/login?error="+url.QueryEscape("Verification link is invalid or has expired."), http.StatusSeeOther)
+		return
+	}
+
+	log.Printf("Email verified for user %s", user.Username)
+	http.Redirect(w, r, "/login?message="+url.QueryEscape("Email verified. You can now log in."), http.StatusSeeOther)
+}
+
+// HandleAdminInvites lists invite codes
+func (h *WebHandler) HandleAdminInvites(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.db.ListInviteCodes()
+	if err != nil {
+		log.Printf("Error fetching invite codes: %v", err)
+		http.Error(w, "Error fetching invite codes", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:                auth.GetUserFromContext(r.Context()),
+		Invites:             invites,
+		RegistrationEnabled: h.allowRegistration,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "invites.html", data)
+}
+
+// HandleCreateInvitePost generates a new invite code
+func (h *WebHandler) HandleCreateInvitePost(w http.ResponseWriter, r *http.Request) {
+	admin := auth.GetUserFromContext(r.Context())
+
+	role := r.FormValue("role")
+	if !utils.IsValidRole(role) {
+		role = "user"
+	}
+
+	code, err := utils.GenerateRandomString(16)
+	if err != nil {
+		log.Printf("Failed to generate invite code: %v", err)
+		http.Error(w, "Failed to generate invite code", http.StatusInternalServerError)
+		return
+	}
+
+	expiry := h.inviteExpiry
+	if expiry <= 0 {
+		expiry = 7 * 24 * time.Hour
+	}
+
+	invite := &models.InviteCode{
+		Code:      code,
+		CreatedBy: admin.Username,
+		Role:      role,
+		ExpiresAt: time.Now().Add(expiry),
+	}
+	if err := h.db.CreateInviteCode(invite); err != nil {
+		log.Printf("Failed to store invite code: %v", err)
+		http.Error(w, "Failed to store invite code", http.StatusInternalServerError)
+		return
+	}
+
+	h.auth.RecordAudit(auth.AuditTokenIssued, admin.Username, "invite:"+role, auth.ClientIP(r), "invite code generated")
+	http.Redirect(w, r, "/admin/invites", http.StatusSeeOther)
+}
+
+// requestBaseURL returns the scheme and host the request was made to
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
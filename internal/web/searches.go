@@ -0,0 +1,147 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxSearchNameLength limits the names of saved searches so they fit in the navigation
+const maxSearchNameLength = 60
+
+// savedSearches returns the user's saved searches for the navigation
+func (h *WebHandler) savedSearches(user *auth.User) []models.SavedSearch {
+	if user == nil {
+		return nil
+	}
+	searches, err := h.db.ListSavedSearches(user.Username)
+	if err != nil {
+		log.Printf("Error loading saved searches for %s: %v", user.Username, err)
+	}
+	return searches
+}
+
+// HandleSaveSearchPost saves the events list's current filters under a name,
+// optionally making them the user's default view
+func (h *WebHandler) HandleSaveSearchPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	search := &models.SavedSearch{
+		Username: user.Username,
+		Name:     strings.TrimSpace(r.FormValue("name")),
+		Query:    cleanFilterQuery(r.FormValue("filter")),
+	}
+
+	switch {
+	case search.Query == "":
+		h.setFlash(w, "Choose some filters before saving a search", "error")
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	case search.Name == "":
+		h.setFlash(w, "Give the search a name", "error")
+		http.Redirect(w, r, search.URL(), http.StatusSeeOther)
+		return
+	case len([]rune(search.Name)) > maxSearchNameLength:
+		h.setFlash(w, fmt.Sprintf("Search names can be at most %d characters", maxSearchNameLength), "error")
+		http.Redirect(w, r, search.URL(), http.StatusSeeOther)
+		return
+	}
+
+	if err := h.db.SaveSearch(search); err != nil {
+		log.Printf("Error saving search for %s: %v", user.Username, err)
+		h.setFlash(w, "Error saving search", "error")
+		http.Redirect(w, r, search.URL(), http.StatusSeeOther)
+		return
+	}
+
+	if r.FormValue("default") == "" {
+		h.setFlash(w, fmt.Sprintf("Saved search %q", search.Name), "success")
+	} else if err := h.setDefaultSearch(user, search); err != nil {
+		h.setFlash(w, fmt.Sprintf("Saved search %q, but couldn't make it your default view", search.Name), "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Saved search %q as your default view", search.Name), "success")
+	}
+	http.Redirect(w, r, search.URL(), http.StatusSeeOther)
+}
+
+// HandleDefaultSearchPost makes a saved search the user's default view
+func (h *WebHandler) HandleDefaultSearchPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	search, ok := h.ownedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.setDefaultSearch(user, search); err != nil {
+		h.setFlash(w, "Error saving default view", "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("%q is now your default view", search.Name), "success")
+	}
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+// HandleDeleteSearchPost removes a saved search. If it was the user's default
+// view, the events list goes back to showing every event.
+func (h *WebHandler) HandleDeleteSearchPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	search, ok := h.ownedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteSavedSearch(user.Username, search.ID); err != nil {
+		log.Printf("Error deleting saved search %d: %v", search.ID, err)
+		h.setFlash(w, "Error deleting search", "error")
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+
+	prefs := h.preferences(user)
+	if prefs.DefaultFilter == search.Query {
+		prefs.DefaultFilter = ""
+		if err := h.db.SavePreferences(prefs); err != nil {
+			log.Printf("Error clearing default filter for %s: %v", user.Username, err)
+		}
+	}
+
+	h.setFlash(w, fmt.Sprintf("Deleted search %q", search.Name), "success")
+	http.Redirect(w, r, "/settings", http.StatusSeeOther)
+}
+
+// setDefaultSearch makes the search's filters the user's default filter
+func (h *WebHandler) setDefaultSearch(user *auth.User, search *models.SavedSearch) error {
+	prefs := h.preferences(user)
+	prefs.DefaultFilter = search.Query
+	if err := h.db.SavePreferences(prefs); err != nil {
+		log.Printf("Error saving default filter for %s: %v", user.Username, err)
+		return err
+	}
+	return nil
+}
+
+// ownedSearch loads the current user's saved search named in the URL
+func (h *WebHandler) ownedSearch(w http.ResponseWriter, r *http.Request) (*models.SavedSearch, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid search ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	user := auth.GetUserFromContext(r.Context())
+	search, err := h.db.GetSavedSearch(user.Username, id)
+	if err != nil {
+		log.Printf("Error fetching saved search %d: %v", id, err)
+		http.Error(w, "Error retrieving search", http.StatusInternalServerError)
+		return nil, false
+	}
+	if search == nil {
+		http.Error(w, "Search not found", http.StatusNotFound)
+		return nil, false
+	}
+	return search, true
+}
@@ -0,0 +1,101 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleProfile displays the current user's profile and active sessions
+func (h *WebHandler) HandleProfile(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+
+	data := TemplateData{
+		User:     user,
+		Sessions: h.auth.ListUserSessions(user.ID),
+	}
+	data.Organizations, data.MemberRoles = h.memberOrganizations(user)
+	if cookie, err := r.Cookie("session"); err == nil {
+		if session, err := h.auth.GetSession(cookie.Value); err == nil {
+			data.CurrentSession = session.Handle
+		}
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "profile.html", data)
+}
+
+// HandleRevokeSession revokes a single session belonging to the current user
+func (h *WebHandler) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	handle := r.FormValue("session")
+
+	if err := h.auth.RevokeSession(user.ID, handle); err != nil {
+		log.Printf("Failed to revoke session %s for user %s: %v", handle, user.Username, err)
+		h.setFlash(w, "Session not found", "error")
+	} else {
+		h.auth.RecordAudit(auth.AuditSessionsRevoked, user.Username, user.Username, auth.ClientIP(r), "revoked session "+handle)
+		h.setFlash(w, "Session revoked", "success")
+	}
+
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// HandleRevokeAllSessions logs the current user out everywhere
+func (h *WebHandler) HandleRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+
+	revoked := h.auth.RevokeUserSessions(user.ID)
+	log.Printf("Revoked %d sessions for user %s", revoked, user.Username)
+	h.auth.RecordAudit(auth.AuditSessionsRevoked, user.Username, user.Username, auth.ClientIP(r), fmt.Sprintf("revoked all %d sessions", revoked))
+
+	auth.ClearSessionCookie(w)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// HandleAdminUsers lists all users and their active session counts
+func (h *WebHandler) HandleAdminUsers(w http.ResponseWriter, r *http.Request) {
+	users := h.auth.ListUsers()
+
+	var sessions []auth.Session
+	for _, user := range users {
+		sessions = append(sessions, h.auth.ListUserSessions(user.ID)...)
+	}
+
+	data := TemplateData{
+		User:     auth.GetUserFromContext(r.Context()),
+		Users:    users,
+		Sessions: sessions,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "users.html", data)
+}
+
+// HandleForceLogout revokes all sessions of the given user
+func (h *WebHandler) HandleForceLogout(w http.ResponseWriter, r *http.Request) {
+	admin := auth.GetUserFromContext(r.Context())
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	target, err := h.auth.GetUserByID(id)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	revoked := h.auth.RevokeUserSessions(target.ID)
+	log.Printf("Admin %s force-logged out user %s (%d sessions)", admin.Username, target.Username, revoked)
+	h.auth.RecordAudit(auth.AuditSessionsRevoked, admin.Username, target.Username, auth.ClientIP(r), fmt.Sprintf("force logout of %d sessions", revoked))
+
+	h.setFlash(w, fmt.Sprintf("Logged out %s from %d sessions", target.Username, revoked), "success")
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"errors"
+	"example-api/internal/auth"
+	"example-api/internal/signing"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// shareDurations lists how long a share link may stay valid, keyed by the
+// value of the share form's expires field
+var shareDurations = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// defaultShareDuration is used when the share form does not pick a duration
+const defaultShareDuration = "24h"
+
+// SetLinkSigner configures the key used to sign share links
+func (h *WebHandler) SetLinkSigner(signer *signing.Signer) {
+	h.linkSigner = signer
+}
+
+// sharePath returns the public path of an event's share link
+func sharePath(id int64) string {
+	return fmt.Sprintf("/share/events/%d", id)
+}
+
+// HandleShareEventPost creates a time-limited share link for an event
+func (h *WebHandler) HandleShareEventPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	event, err := h.db.GetEventByID(id)
+	if err != nil {
+		http.Error(w, "Error retrieving event", http.StatusInternalServerError)
+		return
+	}
+	if event == nil || !h.projectAccess(user).CanView(event.ProjectID) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	choice := r.FormValue("expires")
+	if choice == "" {
+		choice = defaultShareDuration
+	}
+	duration, ok := shareDurations[choice]
+	if !ok {
+		http.Error(w, "Invalid share link duration", http.StatusBadRequest)
+		return
+	}
+
+	expires := time.Now().Add(duration)
+	link := requestBaseURL(r) + h.linkSigner.Sign(sharePath(event.ID), expires)
+	h.auth.RecordAudit(auth.AuditTokenIssued, user.Username, fmt.Sprintf("share:event:%d", event.ID), auth.ClientIP(r), "share link valid for "+choice)
+
+	var data TemplateData
+	data.Share.URL = link
+	data.Share.Expires = expires.In(h.preferences(user).Location())
+	h.renderEventView(w, r, event, data)
+}
+
+// HandleSharedEvent shows a read-only event page to anyone holding a valid share link
+func (h *WebHandler) HandleSharedEvent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.linkSigner.Verify(sharePath(id), r.URL.Query()); err != nil {
+		if errors.Is(err, signing.ErrExpired) {
+			http.Error(w, "This share link has expired", http.StatusGone)
+			return
+		}
+		log.Printf("Rejected share link for event %d from %s: %v", id, auth.ClientIP(r), err)
+		http.Error(w, "Invalid share link", http.StatusForbidden)
+		return
+	}
+
+	event, err := h.db.GetEventByID(id)
+	if err != nil {
+		http.Error(w, "Error retrieving event", http.StatusInternalServerError)
+		return
+	}
+	if event == nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	var data TemplateData
+	data.Share.ReadOnly = true
+	data.Share.Expires = signing.Expires(r.URL.Query())
+	h.renderEventView(w, r, event, data)
+}
+
+// sharedImagesURL returns the share link being viewed with remote images
+// switched on, keeping its signature intact
+func sharedImagesURL(r *http.Request) string {
+	query := r.URL.Query()
+	query.Set("images", "show")
+	return r.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,43 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/sso"
+	"log"
+	"net/http"
+)
+
+// SetSAML enables SAML single sign-on through the given provider
+func (h *WebHandler) SetSAML(provider *sso.SAMLProvider) {
+	h.saml = provider
+}
+
+// HandleSAMLACS completes a SAML login and creates a session for the asserted user
+func (h *WebHandler) HandleSAMLACS(w http.ResponseWriter, r *http.Request) {
+	identity, err := h.saml.ParseACS(w, r)
+	if err != nil {
+		log.Printf("SAML login failed from %s: %v", auth.ClientIP(r), err)
+		h.auth.RecordAudit(auth.AuditLoginFailed, "saml", "", auth.ClientIP(r), err.Error())
+		http.Redirect(w, r, "/login?error=Single+sign-on+failed.+Please+try+again.", http.StatusSeeOther)
+		return
+	}
+
+	user, err := h.auth.EnsureExternalUser(identity.Username, identity.Email, identity.Role, "saml")
+	if err != nil {
+		log.Printf("Failed to provision SAML user %s: %v", identity.Username, err)
+		http.Redirect(w, r, "/login?error=Single+sign-on+failed.+Please+try+again.", http.StatusSeeOther)
+		return
+	}
+
+	session, err := h.auth.CreateSession(user.ID, auth.ClientIP(r), r.UserAgent())
+	if err != nil {
+		log.Printf("Failed to create session for SAML user %s: %v", user.Username, err)
+		http.Redirect(w, r, "/login?error=Failed+to+create+session.+Please+try+again+later.", http.StatusSeeOther)
+		return
+	}
+
+	log.Printf("SAML login successful for user: %s (role: %s)", user.Username, user.Role)
+	h.auth.RecordAudit(auth.AuditLogin, user.Username, user.Username, auth.ClientIP(r), "saml")
+	auth.SetSessionCookie(w, r, session)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,48 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleStarEventPost stars or unstars an event for the user, then returns to
+// the page the toggle was on
+func (h *WebHandler) HandleStarEventPost(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	returnURL := fmt.Sprintf("/events/%d", id)
+	if r.FormValue("return") != "" {
+		returnURL = localURL(r.FormValue("return"))
+	}
+
+	if r.FormValue("starred") == "true" {
+		err = h.db.UnstarEvent(user.Username, id)
+	} else {
+		event, getErr := h.db.GetEventByID(id)
+		switch {
+		case getErr != nil:
+			err = getErr
+		case event == nil || !h.projectAccess(user).CanView(event.ProjectID):
+			http.Error(w, "Event not found", http.StatusNotFound)
+			return
+		default:
+			err = h.db.StarEvent(user.Username, id)
+		}
+	}
+	if err != nil {
+		log.Printf("Error toggling star on event %d for %s: %v", id, user.Username, err)
+		h.setFlash(w, "Error updating star", "error")
+	}
+	http.Redirect(w, r, returnURL, http.StatusSeeOther)
+}
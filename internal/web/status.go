@@ -0,0 +1,47 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+)
+
+// HandleEventStatusPost moves an event to the chosen status and records the
+// change in its audit trail
+func (h *WebHandler) HandleEventStatusPost(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.editableEvent(w, r, auth.GetUserFromContext(r.Context()))
+	if !ok {
+		return
+	}
+	eventURL := fmt.Sprintf("/events/%d", event.ID)
+
+	status := r.FormValue("status")
+	if !slices.Contains(models.EventStatuses, status) {
+		h.setFlash(w, "Choose a status", "error")
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+	if status == event.Status {
+		http.Redirect(w, r, eventURL, http.StatusSeeOther)
+		return
+	}
+
+	found, err := h.db.SetEventStatus(event.ID, status)
+	switch {
+	case err != nil:
+		log.Printf("Error setting status of event %d: %v", event.ID, err)
+		h.setFlash(w, "Error changing status", "error")
+	case !found:
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	default:
+		updated := *event
+		updated.Status = status
+		h.recordEventAudit(r, models.EventStatusChanged, event.ID, event, &updated)
+		h.setFlash(w, "Event marked "+status, "success")
+	}
+	http.Redirect(w, r, eventURL, http.StatusSeeOther)
+}
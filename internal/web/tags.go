@@ -0,0 +1,100 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// HandleAdminTags lists all tags with their usage counts
+func (h *WebHandler) HandleAdminTags(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.db.GetTagCounts()
+	if err != nil {
+		log.Printf("Error fetching tag counts: %v", err)
+		http.Error(w, "Error fetching tags", http.StatusInternalServerError)
+		return
+	}
+
+	data := TemplateData{
+		User:      auth.GetUserFromContext(r.Context()),
+		TagCounts: counts,
+	}
+
+	h.preparePage(w, r, &data)
+	h.renderTemplate(w, "tags.html", data)
+}
+
+// HandleRenameTagPost renames a tag across all events
+func (h *WebHandler) HandleRenameTagPost(w http.ResponseWriter, r *http.Request) {
+	oldTag := strings.TrimSpace(r.FormValue("tag"))
+	newTag := strings.TrimSpace(r.FormValue("new_name"))
+	if oldTag == "" || newTag == "" || strings.Contains(newTag, ",") {
+		h.setFlash(w, "Enter a new tag name without commas", "error")
+		http.Redirect(w, r, "/admin/tags", http.StatusSeeOther)
+		return
+	}
+
+	before := h.snapshotTagged([]string{oldTag})
+	changed, err := h.db.RenameTag(oldTag, newTag)
+	h.recordBulkAudit(r, before)
+	if err != nil {
+		log.Printf("Error renaming tag %q to %q: %v", oldTag, newTag, err)
+		h.setFlash(w, fmt.Sprintf("Error renaming tag: %v", err), "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Renamed %q to %q on %d events", oldTag, newTag, changed), "success")
+	}
+
+	http.Redirect(w, r, "/admin/tags", http.StatusSeeOther)
+}
+
+// HandleMergeTagsPost merges the selected tags into a single target tag
+func (h *WebHandler) HandleMergeTagsPost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.setFlash(w, "Error processing form data", "error")
+		http.Redirect(w, r, "/admin/tags", http.StatusSeeOther)
+		return
+	}
+
+	sources := r.Form["tags"]
+	target := strings.TrimSpace(r.FormValue("target"))
+	if len(sources) == 0 || target == "" || strings.Contains(target, ",") {
+		h.setFlash(w, "Select tags to merge and enter a target tag without commas", "error")
+		http.Redirect(w, r, "/admin/tags", http.StatusSeeOther)
+		return
+	}
+
+	before := h.snapshotTagged(sources)
+	changed, err := h.db.MergeTags(sources, target)
+	h.recordBulkAudit(r, before)
+	if err != nil {
+		log.Printf("Error merging tags %v into %q: %v", sources, target, err)
+		h.setFlash(w, fmt.Sprintf("Error merging tags: %v", err), "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Merged %d tags into %q on %d events", len(sources), target, changed), "success")
+	}
+
+	http.Redirect(w, r, "/admin/tags", http.StatusSeeOther)
+}
+
+// HandleDeleteTagPost removes a tag from all events
+func (h *WebHandler) HandleDeleteTagPost(w http.ResponseWriter, r *http.Request) {
+	tag := strings.TrimSpace(r.FormValue("tag"))
+	if tag == "" {
+		http.Redirect(w, r, "/admin/tags", http.StatusSeeOther)
+		return
+	}
+
+	before := h.snapshotTagged([]string{tag})
+	changed, err := h.db.DeleteTag(tag)
+	h.recordBulkAudit(r, before)
+	if err != nil {
+		log.Printf("Error deleting tag %q: %v", tag, err)
+		h.setFlash(w, fmt.Sprintf("Error deleting tag: %v", err), "error")
+	} else {
+		h.setFlash(w, fmt.Sprintf("Removed %q from %d events", tag, changed), "success")
+	}
+
+	http.Redirect(w, r, "/admin/tags", http.StatusSeeOther)
+}
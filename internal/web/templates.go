@@ -0,0 +1,115 @@
+package web
+
+import (
+	"context"
+	"example-api/internal/reporting"
+	"fmt"
+	"html/template"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// layoutsDir holds the shared layout and partials, relative to the templates root
+const layoutsDir = "layouts"
+
+// loadTemplates parses the layouts under root and then every other .html file
+// as a page. Each page gets its own copy of the layouts so the blocks it
+// defines (title, styles, nav, flash, content, scripts) only replace the
+// layout's defaults for that page. Pages are keyed by file name.
+func loadTemplates(root string, funcMap template.FuncMap) (map[string]*template.Template, error) {
+	layout, err := template.New("").Funcs(funcMap).ParseGlob(filepath.Join(root, layoutsDir, "*.html"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing layouts: %w", err)
+	}
+
+	pages := make(map[string]*template.Template)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == filepath.Join(root, layoutsDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".html") {
+			return nil
+		}
+
+		name := filepath.Base(path)
+		if _, exists := pages[name]; exists {
+			return fmt.Errorf("duplicate page template name %s", name)
+		}
+
+		page, err := layout.Clone()
+		if err != nil {
+			return err
+		}
+		if _, err := page.ParseFiles(path); err != nil {
+			return fmt.Errorf("error parsing %s: %w", path, err)
+		}
+		pages[name] = page
+		slog.Debug("Template loaded", "name", name)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading templates: %w", err)
+	}
+
+	return pages, nil
+}
+
+// SetTemplateReload makes every page parse the templates again, so changes to
+// them show up without a restart. Parse and execution errors are then shown
+// in the browser. Parsing once at startup is much faster.
+func (h *WebHandler) SetTemplateReload(reload bool) {
+	h.reloadTemplates = reload
+}
+
+// page returns the template of the named page
+func (h *WebHandler) page(name string) (*template.Template, error) {
+	pages := h.pages
+	if h.reloadTemplates {
+		var err error
+		if pages, err = loadTemplates(h.templatesDir, h.funcMap); err != nil {
+			return nil, err
+		}
+	}
+	page, ok := pages[name]
+	if !ok {
+		return nil, fmt.Errorf("no such page")
+	}
+	return page, nil
+}
+
+// templateError answers 500 for a page that failed to parse or render. The
+// error names the template file and line; it is logged and reported, and
+// shown in the browser when templates are reloaded for development.
+func (h *WebHandler) templateError(w http.ResponseWriter, name string, err error) {
+	log.Printf("Error rendering template %s: %v", name, err)
+	reporting.CaptureError(context.Background(), err, map[string]string{"page": name})
+	if h.reloadTemplates {
+		http.Error(w, fmt.Sprintf("Error rendering template %s:\n\n%v", name, err), http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, "Error rendering template", http.StatusInternalServerError)
+}
+
+// CheckTemplates reports why the page templates aren't usable, for the
+// readiness endpoint
+func (h *WebHandler) CheckTemplates(ctx context.Context) error {
+	if len(h.pages) == 0 {
+		return fmt.Errorf("no page templates loaded")
+	}
+	for name, page := range h.pages {
+		if page.Lookup("base") == nil {
+			return fmt.Errorf("page template %s has no base layout", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"example-api/internal/auth"
+	"example-api/internal/models"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// HandleViewThread shows every email in an event's conversation, oldest first
+func (h *WebHandler) HandleViewThread(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	thread, err := h.db.GetThread(id)
+	if err != nil {
+		log.Printf("Error fetching thread for %d: %v", id, err)
+		http.Error(w, "Error retrieving thread", http.StatusInternalServerError)
+		return
+	}
+	user := auth.GetUserFromContext(r.Context())
+	thread = visibleEvents(h.projectAccess(user), thread)
+	if !slices.ContainsFunc(thread, func(event models.Event) bool { return event.ID == id }) {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+
+	prefs := h.preferences(user)
+	localizeEvents(thread, prefs.Location())
+
+	data := TemplateData{
+		User:        user,
+		Thread:      thread,
+		Preferences: prefs,
+		Theme:       prefs.Theme,
+	}
+	for i := range thread {
+		if thread[i].ID == id {
+			data.Event = &thread[i]
+		}
+	}
+
+	h.preparePage(w, r, &data)
+
+	h.renderTemplate(w, "thread.html", data)
+}
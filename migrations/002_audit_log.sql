@@ -0,0 +1,14 @@
+-- Create audit_log table
+CREATE TABLE IF NOT EXISTS audit_log (
+    id SERIAL PRIMARY KEY,
+    action TEXT NOT NULL,  -- 'login', 'login_failed', 'logout', 'user_created', 'role_changed', 'token_issued'
+    actor TEXT NOT NULL,
+    target TEXT,
+    ip_address TEXT,
+    details TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
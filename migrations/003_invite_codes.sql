@@ -0,0 +1,14 @@
+-- Create invite_codes table
+CREATE TABLE IF NOT EXISTS invite_codes (
+    id SERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE,
+    created_by TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'user',
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    used_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_invite_codes_expires_at ON invite_codes(expires_at);
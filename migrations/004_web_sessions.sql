@@ -0,0 +1,17 @@
+-- Create web_sessions table, shared between the web and API servers
+CREATE TABLE IF NOT EXISTS web_sessions (
+    token_hash TEXT PRIMARY KEY,  -- SHA-256 of the session cookie value
+    handle TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    username TEXT NOT NULL,
+    role TEXT NOT NULL,
+    ip_address TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_web_sessions_user_id ON web_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_web_sessions_expires_at ON web_sessions(expires_at);
@@ -0,0 +1,2 @@
+-- Full-text search index over event data
+CREATE INDEX IF NOT EXISTS idx_events_data_fts ON events USING GIN (to_tsvector('english', data));
@@ -0,0 +1,2 @@
+-- Original HTML body of events ingested from HTML email
+ALTER TABLE events ADD COLUMN IF NOT EXISTS html_body TEXT NOT NULL DEFAULT '';
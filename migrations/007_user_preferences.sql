@@ -0,0 +1,9 @@
+-- Per-user web interface preferences
+CREATE TABLE IF NOT EXISTS user_preferences (
+    username TEXT PRIMARY KEY,
+    page_size INTEGER NOT NULL DEFAULT 20,
+    default_filter TEXT NOT NULL DEFAULT '',  -- query string applied to the events list
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    theme TEXT NOT NULL DEFAULT 'light',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- Allow logging ingestion failures that happen before an event row exists
+ALTER TABLE event_logs ALTER COLUMN event_id DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_event_logs_status_created_at ON event_logs(status, created_at DESC);
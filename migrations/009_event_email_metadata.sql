@@ -0,0 +1,5 @@
+-- Envelope and headers of events ingested from email. The Message-ID gets its
+-- own column so events can be looked up by it.
+ALTER TABLE events ADD COLUMN IF NOT EXISTS message_id TEXT;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS email JSONB;
+CREATE INDEX IF NOT EXISTS idx_events_message_id ON events(message_id);
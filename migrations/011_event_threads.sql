@@ -0,0 +1,3 @@
+-- Lookups used to group email events into threads
+CREATE INDEX IF NOT EXISTS idx_events_in_reply_to ON events ((email->>'in_reply_to'));
+CREATE INDEX IF NOT EXISTS idx_events_references ON events USING GIN ((email->'references'));
@@ -0,0 +1,9 @@
+-- Each email is stored once; forwarders that retry a delivery get the
+-- existing event back. Earlier duplicates keep their data but lose the
+-- Message-ID so the constraint can be created.
+UPDATE events SET message_id = NULL
+WHERE message_id IS NOT NULL
+  AND id NOT IN (SELECT MIN(id) FROM events WHERE message_id IS NOT NULL GROUP BY message_id);
+
+DROP INDEX IF EXISTS idx_events_message_id;
+CREATE UNIQUE INDEX IF NOT EXISTS events_message_id_key ON events(message_id);
@@ -0,0 +1,16 @@
+-- Generated addresses that route incoming mail to tags, a source and an
+-- optional forwarding endpoint
+CREATE TABLE IF NOT EXISTS email_mappings (
+    id SERIAL PRIMARY KEY,
+    owner TEXT NOT NULL,
+    generated_email TEXT NOT NULL UNIQUE,
+    tags TEXT NOT NULL DEFAULT '[]',  -- JSON array of tags added to events
+    source TEXT NOT NULL DEFAULT '',  -- replaces the event source when set
+    endpoint_url TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_mappings_owner ON email_mappings(owner);
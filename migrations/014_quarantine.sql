@@ -0,0 +1,14 @@
+-- Events held back by sender rules or spam scoring until an admin releases
+-- or deletes them
+CREATE TABLE IF NOT EXISTS quarantine (
+    id SERIAL PRIMARY KEY,
+    sender TEXT NOT NULL DEFAULT '',
+    source TEXT NOT NULL DEFAULT '',
+    subject TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    score DOUBLE PRECISION,           -- spam score, when the event was scored
+    event JSONB NOT NULL,             -- the event as it would have been stored
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_quarantine_created_at ON quarantine(created_at DESC);
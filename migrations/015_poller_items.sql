@@ -0,0 +1,11 @@
+-- Items seen by URL pollers, so each feed entry or page change becomes one
+-- event
+CREATE TABLE IF NOT EXISTS poller_items (
+    poller TEXT NOT NULL,
+    item_key TEXT NOT NULL,  -- feed entry ID or hash of the extracted content
+    first_seen TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (poller, item_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_poller_items_last_seen ON poller_items(poller, last_seen);
@@ -0,0 +1,15 @@
+-- Who created, edited, deleted or restored each event, with the event's
+-- values before and after. There is no foreign key so entries outlive the
+-- events they describe.
+CREATE TABLE IF NOT EXISTS event_audit (
+    id SERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL,
+    action TEXT NOT NULL,             -- 'create', 'edit', 'delete', 'restore'
+    actor TEXT NOT NULL,
+    old_values JSONB,                 -- the event before the change; NULL for create and restore
+    new_values JSONB,                 -- the event after the change; NULL for delete
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_audit_event_id ON event_audit(event_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_event_audit_action ON event_audit(action, created_at DESC);
@@ -0,0 +1,11 @@
+-- Create web_users table so accounts survive restarts and can be created
+-- from the command line with "eventdb create-user"
+CREATE TABLE IF NOT EXISTS web_users (
+    id SERIAL PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    pending BOOLEAN NOT NULL DEFAULT FALSE,  -- true until a self-registered user verifies their email
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,38 @@
+-- Store tags as JSONB so tag filters match whole tags with the ?| and ?&
+-- operators instead of LIKE on the JSON text, which also matched
+-- "deployment" when searching "deploy". Tags are now written lowercased and
+-- trimmed; existing ones are normalized once, when the column is converted.
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'events' AND column_name = 'tags') = 'text' THEN
+        ALTER TABLE events ALTER COLUMN tags TYPE JSONB USING tags::jsonb;
+
+        UPDATE events e SET tags = n.tags
+        FROM (
+            SELECT ev.id, COALESCE((
+                SELECT jsonb_agg(tag ORDER BY first)
+                FROM (
+                    SELECT lower(btrim(t)) AS tag, MIN(i) AS first
+                    FROM jsonb_array_elements_text(
+                        CASE WHEN jsonb_typeof(ev.tags) = 'array' THEN ev.tags ELSE '[]'::jsonb END
+                    ) WITH ORDINALITY AS x(t, i)
+                    WHERE btrim(t) != ''
+                    GROUP BY 1
+                ) d
+            ), '[]'::jsonb) AS tags
+            FROM events ev
+        ) n
+        WHERE e.id = n.id AND e.tags != n.tags;
+    END IF;
+END $$;
+
+-- Replace the btree index from 001 with a GIN index the JSONB operators can use
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_indexes
+        WHERE indexname = 'idx_events_tags' AND indexdef NOT LIKE '%USING gin%') THEN
+        DROP INDEX idx_events_tags;
+    END IF;
+END $$;
+CREATE INDEX IF NOT EXISTS idx_events_tags ON events USING GIN (tags);
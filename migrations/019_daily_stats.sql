@@ -0,0 +1,74 @@
+-- Events per day, per tag and per source, kept up to date by a trigger on
+-- events so the dashboard and statistics don't aggregate the whole events
+-- table on every request
+CREATE TABLE IF NOT EXISTS daily_stats (
+    day DATE NOT NULL,
+    kind TEXT NOT NULL,  -- 'events', 'tag' or 'source'
+    name TEXT NOT NULL,  -- the tag or source; '' for 'events'
+    count INTEGER NOT NULL,
+    PRIMARY KEY (day, kind, name)
+);
+CREATE INDEX IF NOT EXISTS idx_daily_stats_kind_name ON daily_stats(kind, name);
+
+-- Adds delta to the counts an event contributes to
+CREATE OR REPLACE FUNCTION daily_stats_add(ev events, delta INTEGER) RETURNS void AS $$
+BEGIN
+    IF ev.created_at IS NULL THEN
+        RETURN;
+    END IF;
+    INSERT INTO daily_stats (day, kind, name, count)
+    SELECT ev.created_at::date, s.kind, s.name, delta
+    FROM (
+        SELECT 'events' AS kind, '' AS name
+        UNION ALL
+        SELECT 'source', ev.source WHERE ev.source != ''
+        UNION ALL
+        SELECT DISTINCT 'tag', tag
+        FROM jsonb_array_elements_text(CASE WHEN jsonb_typeof(ev.tags) = 'array' THEN ev.tags ELSE '[]'::jsonb END) AS tag
+        WHERE tag != ''
+    ) s
+    ON CONFLICT (day, kind, name) DO UPDATE SET count = daily_stats.count + EXCLUDED.count;
+    IF delta < 0 THEN
+        DELETE FROM daily_stats WHERE day = ev.created_at::date AND count <= 0;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION events_daily_stats() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM daily_stats_add(OLD, -1);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM daily_stats_add(NEW, 1);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS events_daily_stats ON events;
+CREATE TRIGGER events_daily_stats
+    AFTER INSERT OR DELETE OR UPDATE OF tags, source, created_at ON events
+    FOR EACH ROW EXECUTE FUNCTION events_daily_stats();
+
+-- Fill the table from existing events the first time
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM daily_stats) THEN
+        INSERT INTO daily_stats (day, kind, name, count)
+        SELECT created_at::date, 'events', '', COUNT(*)
+        FROM events WHERE created_at IS NOT NULL
+        GROUP BY 1;
+
+        INSERT INTO daily_stats (day, kind, name, count)
+        SELECT created_at::date, 'source', source, COUNT(*)
+        FROM events WHERE created_at IS NOT NULL AND source != ''
+        GROUP BY 1, 3;
+
+        INSERT INTO daily_stats (day, kind, name, count)
+        SELECT created_at::date, 'tag', tag, COUNT(DISTINCT id)
+        FROM events, jsonb_array_elements_text(tags) AS tag
+        WHERE created_at IS NOT NULL AND tag != ''
+        GROUP BY 1, 3;
+    END IF;
+END $$;
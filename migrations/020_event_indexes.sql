@@ -0,0 +1,7 @@
+-- Indexes for the common event queries: listing by date, by source (matched
+-- case-insensitively, newest first) and by tag. The created_at and tags
+-- indexes come from 001 and 018 and are repeated here so this file lists the
+-- whole set; the server warns at startup when one is missing.
+CREATE INDEX IF NOT EXISTS idx_events_created_at ON events(created_at);
+CREATE INDEX IF NOT EXISTS idx_events_source_created_at ON events (lower(source), created_at);
+CREATE INDEX IF NOT EXISTS idx_events_tags ON events USING GIN (tags);
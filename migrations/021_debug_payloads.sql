@@ -0,0 +1,15 @@
+-- Raw POST /api/events request bodies captured for the sources listed in
+-- pipeline.debug.sources, for troubleshooting what a sender actually sends
+CREATE TABLE IF NOT EXISTS debug_payloads (
+    id BIGSERIAL PRIMARY KEY,
+    source TEXT NOT NULL DEFAULT '',
+    content_type TEXT NOT NULL DEFAULT '',
+    remote_addr TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    status INTEGER NOT NULL,        -- the status the request was answered with
+    error TEXT NOT NULL DEFAULT '', -- why the event wasn't stored, if it wasn't
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_debug_payloads_source ON debug_payloads(source, id DESC);
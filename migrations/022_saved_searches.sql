@@ -0,0 +1,9 @@
+-- Named event list filters saved by web users, listed in the navigation
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id BIGSERIAL PRIMARY KEY,
+    username TEXT NOT NULL,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL,  -- query string applied to the events list
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (username, name)
+);
@@ -0,0 +1,15 @@
+-- Rules notifying a webhook, email address or Slack channel when a received
+-- event matches. Every condition that is set must match.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    tags JSONB NOT NULL DEFAULT '[]',    -- lowercased; any of them matches
+    source TEXT NOT NULL DEFAULT '',     -- matched whole, ignoring case
+    keyword TEXT NOT NULL DEFAULT '',    -- found anywhere in the data, ignoring case
+    channel TEXT NOT NULL,               -- webhook, email or slack
+    target TEXT NOT NULL,                -- URL, or email address
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_fired_at TIMESTAMP
+);
@@ -0,0 +1,4 @@
+-- Daily or weekly email digests of new events, chosen on the settings page
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS digest TEXT NOT NULL DEFAULT '';        -- '', daily or weekly
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS digest_email TEXT NOT NULL DEFAULT '';  -- the account's email when empty
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS digest_sent_at TIMESTAMP;              -- end of the period of the last digest sent
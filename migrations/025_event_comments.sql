@@ -0,0 +1,10 @@
+-- Follow-up notes people attach to events, such as what the root cause was
+CREATE TABLE IF NOT EXISTS event_comments (
+    id BIGSERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_comments_event_id ON event_comments(event_id, created_at);
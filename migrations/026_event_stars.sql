@@ -0,0 +1,9 @@
+-- Events each web user starred to find them again from the events list
+CREATE TABLE IF NOT EXISTS event_stars (
+    username TEXT NOT NULL,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (username, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_stars_event_id ON event_stars(event_id);
@@ -0,0 +1,15 @@
+-- Typed attributes admins define for events, such as severity. Events keep
+-- their values in the metadata column, keyed by field name.
+CREATE TABLE IF NOT EXISTS custom_fields (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    label TEXT NOT NULL,
+    type TEXT NOT NULL,      -- 'string', 'number', 'enum' or 'date'
+    options JSONB,           -- allowed values of enum fields
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+
+-- Serves the containment (@>) filters on field values
+CREATE INDEX IF NOT EXISTS idx_events_metadata ON events USING GIN (metadata jsonb_path_ops);
@@ -0,0 +1,43 @@
+-- Projects let one instance host several teams. Every event belongs to a
+-- project; existing events go to the default project, which every user can
+-- see. Other projects are only visible to their members and admins.
+CREATE TABLE IF NOT EXISTS projects (
+    id SERIAL PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO projects (id, slug, name) VALUES (1, 'default', 'Default') ON CONFLICT (id) DO NOTHING;
+SELECT setval('projects_id_seq', GREATEST((SELECT MAX(id) FROM projects), 1));
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS project_id INTEGER NOT NULL DEFAULT 1 REFERENCES projects(id);
+CREATE INDEX IF NOT EXISTS idx_events_project_created_at ON events(project_id, created_at);
+
+-- Mail sent to a mapping's address is stored in the mapping's project
+ALTER TABLE email_mappings ADD COLUMN IF NOT EXISTS project_id INTEGER NOT NULL DEFAULT 1 REFERENCES projects(id);
+
+-- Per-project roles: viewers read the project's events, editors also create,
+-- edit and delete them
+CREATE TABLE IF NOT EXISTS project_members (
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    username TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, username)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_members_username ON project_members(username);
+
+-- API tokens limited to one project, alongside the configured API token
+-- that can reach every project. Only a hash of each token is kept.
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP
+);
@@ -0,0 +1,34 @@
+-- Organizations group projects for one tenant, with their own admins and
+-- API tokens. Existing projects go to the default organization.
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO organizations (id, slug, name) VALUES (1, 'default', 'Default') ON CONFLICT (id) DO NOTHING;
+SELECT setval('organizations_id_seq', GREATEST((SELECT MAX(id) FROM organizations), 1));
+
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS organization_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations(id);
+CREATE INDEX IF NOT EXISTS idx_projects_organization ON projects(organization_id);
+
+-- Organization roles: members read the events of every project of the
+-- organization, admins also edit them and manage the organization
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    username TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, username)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_username ON organization_members(username);
+
+-- API tokens belong to an organization. Those without a project reach every
+-- project of the organization.
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE api_tokens t SET organization_id = p.organization_id FROM projects p WHERE p.id = t.project_id AND t.organization_id IS NULL;
+ALTER TABLE api_tokens ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE api_tokens ALTER COLUMN project_id DROP NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_api_tokens_organization ON api_tokens(organization_id);
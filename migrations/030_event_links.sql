@@ -0,0 +1,14 @@
+-- Typed links between events, such as an incident caused by a deploy. A
+-- link goes from event_id to target_id and reads "event_id <type> target_id".
+CREATE TABLE IF NOT EXISTS event_links (
+    id BIGSERIAL PRIMARY KEY,
+    event_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    target_id INTEGER NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (event_id, target_id, type),
+    CHECK (event_id <> target_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_links_target_id ON event_links(target_id);
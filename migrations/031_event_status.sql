@@ -0,0 +1,5 @@
+-- Events can be tracked like incidents: open until someone acknowledges,
+-- resolves or ignores them. Changes are recorded in the event audit trail.
+ALTER TABLE events ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open';
+
+CREATE INDEX IF NOT EXISTS idx_events_status_created_at ON events(status, created_at);
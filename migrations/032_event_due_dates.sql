@@ -0,0 +1,7 @@
+-- Events can be given a due date, such as an incident's SLA. due_reminder
+-- is the last reminder sent about it ('soon' or 'overdue'), so each is sent
+-- once; it is cleared when the due date changes.
+ALTER TABLE events ADD COLUMN IF NOT EXISTS due_at TIMESTAMP;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS due_reminder TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_events_due_at ON events(due_at) WHERE due_at IS NOT NULL;
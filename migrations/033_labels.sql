@@ -0,0 +1,15 @@
+-- Labels are a managed list of categories for events, with a color and a
+-- description, kept apart from free-form tags. Events keep the names of
+-- their labels.
+CREATE TABLE IF NOT EXISTS labels (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    color TEXT NOT NULL,            -- such as '#e74c3c'
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '[]';
+
+-- Serves the containment (@>) filters on labels
+CREATE INDEX IF NOT EXISTS idx_events_labels ON events USING GIN (labels jsonb_path_ops);
@@ -0,0 +1,9 @@
+-- Events can carry where they happened: a location such as a site or an
+-- address, and its coordinates in degrees (WGS 84), given on ingestion or
+-- geocoded from the location. Coordinates are set together or not at all.
+ALTER TABLE events ADD COLUMN IF NOT EXISTS location TEXT NOT NULL DEFAULT '';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+-- Serves bounding box queries and the map of located events
+CREATE INDEX IF NOT EXISTS idx_events_coordinates ON events(latitude, longitude) WHERE latitude IS NOT NULL;
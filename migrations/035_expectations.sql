@@ -0,0 +1,19 @@
+-- Expected activity: a source, or events with any of a set of tags, must
+-- produce at least min_events events every window_seconds. eventdb expect
+-- notifies channel/target once when an expectation is missed, setting
+-- missed_since, and again when events arrive, clearing it.
+CREATE TABLE IF NOT EXISTS expectations (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT '',     -- matched whole, ignoring case
+    tags JSONB NOT NULL DEFAULT '[]',    -- lowercased; any of them matches
+    min_events INTEGER NOT NULL DEFAULT 1,
+    window_seconds INTEGER NOT NULL,
+    channel TEXT NOT NULL,               -- webhook, email or slack
+    target TEXT NOT NULL,                -- URL, or email address
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    checked_at TIMESTAMP,
+    missed_since TIMESTAMP
+);
@@ -0,0 +1,19 @@
+-- Bursts of similar events (same project, source and tags, each within the
+-- correlation window of the previous one) are grouped into incidents, so the
+-- events list can show a flapping alert as one row.
+CREATE TABLE IF NOT EXISTS incidents (
+    id BIGSERIAL PRIMARY KEY,
+    fingerprint TEXT NOT NULL,           -- project, lowercased source and sorted tags
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    tags JSONB NOT NULL DEFAULT '[]',
+    summary TEXT NOT NULL,               -- first line of the first event
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_fingerprint ON incidents(fingerprint, last_seen);
+CREATE INDEX IF NOT EXISTS idx_incidents_last_seen ON incidents(last_seen);
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS incident_id BIGINT REFERENCES incidents(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_events_incident_id ON events(incident_id) WHERE incident_id IS NOT NULL;
@@ -0,0 +1,91 @@
+-- Events per hour, per tag and per source, kept up to date by a trigger on
+-- events like daily_stats. eventdb anomalies compares each hour with the
+-- ones before it and prunes the hours it no longer needs.
+CREATE TABLE IF NOT EXISTS hourly_stats (
+    hour TIMESTAMP NOT NULL,  -- start of the hour, in UTC
+    kind TEXT NOT NULL,       -- 'events', 'tag' or 'source'
+    name TEXT NOT NULL,       -- the tag or source; '' for 'events'
+    count INTEGER NOT NULL,
+    PRIMARY KEY (hour, kind, name)
+);
+
+-- Adds delta to the counts an event contributes to
+CREATE OR REPLACE FUNCTION hourly_stats_add(ev events, delta INTEGER) RETURNS void AS $$
+BEGIN
+    IF ev.created_at IS NULL THEN
+        RETURN;
+    END IF;
+    INSERT INTO hourly_stats (hour, kind, name, count)
+    SELECT date_trunc('hour', ev.created_at), s.kind, s.name, delta
+    FROM (
+        SELECT 'events' AS kind, '' AS name
+        UNION ALL
+        SELECT 'source', ev.source WHERE ev.source != ''
+        UNION ALL
+        SELECT DISTINCT 'tag', tag
+        FROM jsonb_array_elements_text(CASE WHEN jsonb_typeof(ev.tags) = 'array' THEN ev.tags ELSE '[]'::jsonb END) AS tag
+        WHERE tag != ''
+    ) s
+    ON CONFLICT (hour, kind, name) DO UPDATE SET count = hourly_stats.count + EXCLUDED.count;
+    IF delta < 0 THEN
+        DELETE FROM hourly_stats WHERE hour = date_trunc('hour', ev.created_at) AND count <= 0;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION events_hourly_stats() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        PERFORM hourly_stats_add(OLD, -1);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM hourly_stats_add(NEW, 1);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS events_hourly_stats ON events;
+CREATE TRIGGER events_hourly_stats
+    AFTER INSERT OR DELETE OR UPDATE OF tags, source, created_at ON events
+    FOR EACH ROW EXECUTE FUNCTION events_hourly_stats();
+
+-- Fill the table with the last four weeks of events the first time
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM hourly_stats) THEN
+        INSERT INTO hourly_stats (hour, kind, name, count)
+        SELECT date_trunc('hour', created_at), 'events', '', COUNT(*)
+        FROM events WHERE created_at >= now() AT TIME ZONE 'UTC' - interval '28 days'
+        GROUP BY 1;
+
+        INSERT INTO hourly_stats (hour, kind, name, count)
+        SELECT date_trunc('hour', created_at), 'source', source, COUNT(*)
+        FROM events WHERE created_at >= now() AT TIME ZONE 'UTC' - interval '28 days' AND source != ''
+        GROUP BY 1, 3;
+
+        INSERT INTO hourly_stats (hour, kind, name, count)
+        SELECT date_trunc('hour', created_at), 'tag', tag, COUNT(DISTINCT id)
+        FROM events, jsonb_array_elements_text(tags) AS tag
+        WHERE created_at >= now() AT TIME ZONE 'UTC' - interval '28 days' AND tag != ''
+        GROUP BY 1, 3;
+    END IF;
+END $$;
+
+-- Hours in which a source, a tag or all events together saw far more
+-- (spike) or far fewer (drop) events than in the hours before. event_id is
+-- the event stored to report it, if any.
+CREATE TABLE IF NOT EXISTS anomalies (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    name TEXT NOT NULL,
+    hour TIMESTAMP NOT NULL,
+    direction TEXT NOT NULL,          -- spike or drop
+    count INTEGER NOT NULL,
+    expected DOUBLE PRECISION NOT NULL,  -- mean events per hour over the baseline
+    stddev DOUBLE PRECISION NOT NULL,
+    event_id INTEGER REFERENCES events(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (kind, name, hour)
+);
+CREATE INDEX IF NOT EXISTS idx_anomalies_hour ON anomalies(hour);
@@ -0,0 +1,14 @@
+-- Objects eventdb archive uploaded to S3-compatible storage, each holding
+-- the events with IDs from first_event_id to last_event_id. The next upload
+-- starts after the highest last_event_id.
+CREATE TABLE IF NOT EXISTS archives (
+    id BIGSERIAL PRIMARY KEY,
+    bucket TEXT NOT NULL,
+    object_key TEXT NOT NULL,
+    first_event_id INTEGER NOT NULL,
+    last_event_id INTEGER NOT NULL,
+    events INTEGER NOT NULL,
+    bytes BIGINT NOT NULL,  -- compressed size
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_archives_bucket_last_event ON archives(bucket, last_event_id);
@@ -0,0 +1,5 @@
+-- Message-IDs are unique within a project rather than across the database,
+-- so delivering an email to one project can't return another project's
+-- event as the duplicate.
+DROP INDEX IF EXISTS events_message_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS events_project_message_id_key ON events(project_id, message_id);
@@ -0,0 +1,3 @@
+-- Record which identity provider created a user, so single sign-on only
+-- logs in and updates the accounts it provisioned. Local accounts have none.
+ALTER TABLE web_users ADD COLUMN IF NOT EXISTS provider TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- Keep pending email verifications next to the users they activate, so a
+-- verification link works on every server and survives restarts
+CREATE TABLE IF NOT EXISTS email_verifications (
+    token_hash TEXT PRIMARY KEY,  -- SHA-256 of the emailed token
+    user_id INTEGER NOT NULL REFERENCES web_users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications(user_id);
@@ -0,0 +1,211 @@
+// Package client is a Go client for the eventdb API. It posts events, reads
+// them back and streams exports with the payload shapes the server expects,
+// so services don't have to build the HTTP calls themselves:
+//
+//	c := client.New("https://events.example.com", os.Getenv("EVENTDB_TOKEN"))
+//	event, err := c.CreateEvent(ctx, &client.NewEvent{
+//		Subject: "Deploy finished",
+//		Body:    "api v1.4.2 is live",
+//		Tags:    []string{"deploy"},
+//		Source:  "ci",
+//	})
+//
+// The package only depends on the standard library.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetries  = 3
+	defaultTimeout  = 30 * time.Second
+	initialBackoff  = 500 * time.Millisecond
+	maxBackoff      = 10 * time.Second
+	maxErrorMessage = 4 << 10
+)
+
+// Client calls the API of one eventdb server. Its fields can be changed
+// before it is first used; it is safe for concurrent use after that.
+type Client struct {
+	BaseURL string // Such as https://events.example.com, without /api
+	Token   string // The server's API token, or a project or organization token
+	// HTTPClient sends the requests. New gives it a timeout, which also
+	// bounds reading a stream; set one without a timeout for long exports.
+	HTTPClient *http.Client
+	// Retries is how many more times a request is tried after it failed in
+	// a way that may pass, such as a 503 or a refused connection
+	Retries   int
+	UserAgent string
+}
+
+// New creates a client for the server at baseURL authenticating with token
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: defaultTimeout},
+		Retries:    defaultRetries,
+		UserAgent:  "eventdb-go-client",
+	}
+}
+
+// Error is an error response of the API
+type Error struct {
+	StatusCode int
+	Message    string // The server's "error" message, or the response body
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("eventdb: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is a 404 response
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// request is one API call
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{} // Sent as JSON when not nil
+	// retryAll retries failures that may have reached the server, such as a
+	// timeout or a 500. Requests that aren't safe to repeat only retry the
+	// answers that say nothing was done: 429 and 503.
+	retryAll bool
+}
+
+// do sends req, retrying as the request allows, and returns the response
+// of the first attempt that succeeded. Error responses are returned as
+// *Error. The caller closes the body.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, fmt.Errorf("eventdb: failed to encode request: %w", err)
+		}
+	}
+	target := c.BaseURL + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req.method, target, body)
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || !req.retryAll || attempt >= c.Retries {
+				return nil, err
+			}
+		case resp.StatusCode < 400:
+			return resp, nil
+		default:
+			apiErr := readError(resp)
+			if !retryable(resp.StatusCode, req.retryAll) || attempt >= c.Retries {
+				return nil, apiErr
+			}
+			delay = retryAfter(resp)
+		}
+		if delay == 0 {
+			delay = min(initialBackoff<<attempt, maxBackoff)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// send makes one attempt of a request
+func (c *Client) send(ctx context.Context, method, target string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("eventdb: failed to create request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.UserAgent != "" {
+		httpReq.Header.Set("User-Agent", c.UserAgent)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("eventdb: %s %s: %w", method, target, err)
+	}
+	return resp, nil
+}
+
+// retryable reports whether a response with status may succeed when the
+// request is sent again
+func retryable(status int, retryAll bool) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return retryAll
+	}
+	return false
+}
+
+// retryAfter reads the seconds a server asks to wait before trying again
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, maxBackoff)
+}
+
+// readError turns an error response into an *Error and closes its body
+func readError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorMessage))
+	apiErr := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	var decoded struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &decoded) == nil && decoded.Error != "" {
+		apiErr.Message = decoded.Error
+	}
+	return apiErr
+}
+
+// getJSON decodes the response of a GET request into v
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: path, query: query, retryAll: true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("eventdb: failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrAccepted is returned by CreateEvent when the server accepted the event
+// without storing it yet: it was queued for background ingestion or
+// quarantined. The error's message says which.
+var ErrAccepted = errors.New("eventdb: event accepted but not stored yet")
+
+// Event is a stored event, as the API returns it
+type Event struct {
+	ID              int64                  `json:"id"`
+	Tags            []string               `json:"tags"`
+	Labels          []string               `json:"labels,omitempty"`
+	Data            string                 `json:"data"`
+	Source          string                 `json:"source"`
+	HTMLBody        string                 `json:"html_body,omitempty"`
+	Email           *Email                 `json:"email,omitempty"`
+	AttachmentCount int                    `json:"attachment_count"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"` // Custom field values
+	Starred         bool                   `json:"starred,omitempty"`
+	IncidentID      int64                  `json:"incident_id,omitempty"`
+	ProjectID       int64                  `json:"project_id"`
+	Status          string                 `json:"status"`
+	DueAt           *time.Time             `json:"due_at,omitempty"`
+	Location        string                 `json:"location,omitempty"`
+	Latitude        *float64               `json:"latitude,omitempty"`
+	Longitude       *float64               `json:"longitude,omitempty"`
+	CreatedAt       time.Time              `json:"created_at"`
+}
+
+// Email is the envelope and headers of an event received as an email
+type Email struct {
+	From       string    `json:"from,omitempty"`
+	To         string    `json:"to,omitempty"`
+	Cc         []string  `json:"cc,omitempty"`
+	Subject    string    `json:"subject,omitempty"`
+	MessageID  string    `json:"message_id,omitempty"`
+	InReplyTo  string    `json:"in_reply_to,omitempty"`
+	References []string  `json:"references,omitempty"`
+	Date       time.Time `json:"date,omitempty"`
+}
+
+// NewEvent is an event to create. The server tags it with the words of its
+// subject and with Tags, and runs it through the ingestion pipeline of its
+// source.
+type NewEvent struct {
+	Subject string
+	Body    string
+	Tags    []string
+	Source  string
+	Project string // ID or slug; the token's project, or the default one, when empty
+	// MessageID identifies the event: sending the same MessageID again
+	// returns the stored event instead of creating another. CreateEvent
+	// only retries failures that may have stored the event when it is set.
+	MessageID string
+	From      string
+	To        string
+	Date      time.Time // Date header of the email; the event is created when it is stored
+	Location  string    // Geocoded by the server unless Latitude and Longitude are set
+	Latitude  *float64
+	Longitude *float64
+}
+
+// eventPayload is the body of POST /api/events, which takes events in the
+// shape of the emails forwarders send
+type eventPayload struct {
+	Data struct {
+		From      string     `json:"from,omitempty"`
+		To        string     `json:"to,omitempty"`
+		Subject   string     `json:"subject"`
+		Body      string     `json:"body"`
+		MessageID string     `json:"message_id,omitempty"`
+		Date      *time.Time `json:"date,omitempty"`
+	} `json:"data"`
+	Tags      []string `json:"tags,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	Project   string   `json:"project,omitempty"`
+	Location  string   `json:"location,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+// CreateEvent stores an event and returns it. An event whose MessageID was
+// stored before is returned as it is. When the server queues or quarantines
+// the event instead, the error is ErrAccepted.
+func (c *Client) CreateEvent(ctx context.Context, event *NewEvent) (*Event, error) {
+	var payload eventPayload
+	payload.Data.From = event.From
+	payload.Data.To = event.To
+	payload.Data.Subject = event.Subject
+	payload.Data.Body = event.Body
+	payload.Data.MessageID = event.MessageID
+	if !event.Date.IsZero() {
+		payload.Data.Date = &event.Date
+	}
+	payload.Tags = event.Tags
+	payload.Source = event.Source
+	payload.Project = event.Project
+	payload.Location = event.Location
+	payload.Latitude = event.Latitude
+	payload.Longitude = event.Longitude
+
+	resp, err := c.do(ctx, request{
+		method:   http.MethodPost,
+		path:     "/api/events",
+		body:     payload,
+		retryAll: event.MessageID != "",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		var accepted struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&accepted)
+		return nil, fmt.Errorf("%w: %s", ErrAccepted, accepted.Message)
+	}
+	var stored Event
+	if err := json.NewDecoder(resp.Body).Decode(&stored); err != nil {
+		return nil, fmt.Errorf("eventdb: failed to decode response: %w", err)
+	}
+	return &stored, nil
+}
+
+// GetEvent returns the event with the ID. A missing event is an *Error for
+// which IsNotFound is true.
+func (c *Client) GetEvent(ctx context.Context, id int64) (*Event, error) {
+	var event Event
+	if err := c.getJSON(ctx, "/api/events/"+strconv.FormatInt(id, 10), nil, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Query selects events, as the filters of GET /api/events/export do. The
+// zero Query selects every event the token can see, newest first.
+type Query struct {
+	Tags      []string
+	MatchAll  bool   // Events must have every tag rather than any
+	From      string // First day, YYYY-MM-DD
+	To        string // Last day, YYYY-MM-DD
+	Timezone  string // IANA name the days are in; UTC when empty
+	Source    string
+	Text      string // Full-text search
+	Status    string // open, acknowledged, resolved or ignored
+	Due       string // soon (unresolved and due within a day) or overdue
+	Labels    []string
+	BBox      string            // west,south,east,north
+	Fields    map[string]string // Custom field values
+	Project   string            // ID or slug
+	Starred   bool              // Only events starred by the token's user
+	Ascending bool              // Oldest first
+}
+
+// values encodes the query as URL parameters
+func (q *Query) values() url.Values {
+	values := url.Values{}
+	if q == nil {
+		return values
+	}
+	for _, tag := range q.Tags {
+		values.Add("tag", tag)
+	}
+	if q.MatchAll {
+		values.Set("match", "all")
+	}
+	for _, label := range q.Labels {
+		values.Add("label", label)
+	}
+	for name, value := range q.Fields {
+		values.Set("field."+name, value)
+	}
+	if q.Starred {
+		values.Set("starred", "true")
+	}
+	if q.Ascending {
+		values.Set("order", "asc")
+	}
+	for name, value := range map[string]string{
+		"from": q.From, "to": q.To, "tz": q.Timezone, "source": q.Source, "q": q.Text,
+		"status": q.Status, "due": q.Due, "bbox": q.BBox, "project": q.Project,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	return values
+}
+
+// Query returns up to limit events matching q, or all of them when limit is
+// zero
+func (c *Client) Query(ctx context.Context, q *Query, limit int) ([]Event, error) {
+	var events []Event
+	errLimit := errors.New("limit reached")
+	err := c.Stream(ctx, q, func(event Event) error {
+		events = append(events, event)
+		if limit > 0 && len(events) >= limit {
+			return errLimit
+		}
+		return nil
+	})
+	if err != nil && err != errLimit {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Stream calls fn with each event matching q, decoding the export as it
+// arrives, so any number of events can be read without holding them all.
+// It stops at the first error fn returns, and returns it.
+func (c *Client) Stream(ctx context.Context, q *Query, fn func(Event) error) error {
+	body, err := c.Export(ctx, q, "ndjson")
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var event Event
+			if err := json.Unmarshal(line, &event); err != nil {
+				return fmt.Errorf("eventdb: failed to decode event: %w", err)
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("eventdb: failed to read export: %w", err)
+		}
+	}
+}
+
+// Export downloads the events matching q in format: "ndjson", "json", "csv"
+// or "geojson". The caller reads the export from the returned body and
+// closes it.
+func (c *Client) Export(ctx context.Context, q *Query, format string) (io.ReadCloser, error) {
+	values := q.values()
+	values.Set("format", format)
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "/api/events/export", query: values, retryAll: true})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,104 @@
+// Minimal SVG bar charts for the dashboard.
+//
+//   Charts.bar(container, labels, values, { color: '#3498db', horizontal: false, href: fn })
+//
+// Vertical charts suit time series; horizontal charts suit ranked lists.
+(function() {
+    'use strict';
+
+    var SVG_NS = 'http://www.w3.org/2000/svg';
+
+    function el(name, attrs, text) {
+        var node = document.createElementNS(SVG_NS, name);
+        Object.keys(attrs || {}).forEach(function(key) {
+            node.setAttribute(key, attrs[key]);
+        });
+        if (text !== undefined) {
+            node.textContent = text;
+        }
+        return node;
+    }
+
+    function wrapLink(node, href) {
+        if (!href) {
+            return node;
+        }
+        var link = el('a', { href: href });
+        link.appendChild(node);
+        return link;
+    }
+
+    function vertical(svg, labels, values, opts) {
+        var width = opts.width, height = opts.height;
+        var padBottom = 20, padTop = 14;
+        var max = Math.max.apply(null, values.concat([1]));
+        var slot = width / values.length;
+        var barWidth = Math.max(1, slot * 0.8);
+        var labelEvery = Math.ceil(values.length / 10);
+
+        values.forEach(function(value, i) {
+            var barHeight = (height - padBottom - padTop) * value / max;
+            var x = i * slot + (slot - barWidth) / 2;
+            var y = height - padBottom - barHeight;
+            var bar = el('rect', { x: x, y: y, width: barWidth, height: barHeight, fill: opts.color });
+            bar.appendChild(el('title', {}, labels[i] + ': ' + value));
+            svg.appendChild(wrapLink(bar, opts.href && opts.href(labels[i])));
+
+            if (i % labelEvery === 0) {
+                svg.appendChild(el('text', {
+                    x: x + barWidth / 2, y: height - 5, 'font-size': 10, 'text-anchor': 'middle', fill: '#666'
+                }, opts.format ? opts.format(labels[i]) : labels[i]));
+            }
+        });
+
+        svg.appendChild(el('text', { x: 0, y: 10, 'font-size': 10, fill: '#666' }, 'max ' + max));
+    }
+
+    function horizontal(svg, labels, values, opts) {
+        var width = opts.width;
+        var rowHeight = 22, labelWidth = Math.min(160, width * 0.35), countWidth = 40;
+        var max = Math.max.apply(null, values.concat([1]));
+
+        svg.setAttribute('viewBox', '0 0 ' + width + ' ' + rowHeight * values.length);
+        svg.setAttribute('height', rowHeight * values.length);
+
+        values.forEach(function(value, i) {
+            var y = i * rowHeight;
+            var barWidth = (width - labelWidth - countWidth) * value / max;
+            svg.appendChild(wrapLink(el('text', {
+                x: labelWidth - 6, y: y + 15, 'font-size': 12, 'text-anchor': 'end', fill: '#333'
+            }, labels[i]), opts.href && opts.href(labels[i])));
+            var bar = el('rect', { x: labelWidth, y: y + 3, width: barWidth, height: rowHeight - 6, fill: opts.color });
+            bar.appendChild(el('title', {}, labels[i] + ': ' + value));
+            svg.appendChild(bar);
+            svg.appendChild(el('text', {
+                x: labelWidth + barWidth + 4, y: y + 15, 'font-size': 12, fill: '#666'
+            }, String(value)));
+        });
+    }
+
+    function bar(container, labels, values, opts) {
+        opts = opts || {};
+        opts.color = opts.color || '#3498db';
+        opts.width = container.clientWidth || 600;
+        opts.height = opts.height || 200;
+
+        container.innerHTML = '';
+        if (!values.length) {
+            container.textContent = opts.empty || 'No data';
+            return;
+        }
+
+        var svg = el('svg', {
+            width: '100%', height: opts.height, viewBox: '0 0 ' + opts.width + ' ' + opts.height
+        });
+        if (opts.horizontal) {
+            horizontal(svg, labels, values, opts);
+        } else {
+            vertical(svg, labels, values, opts);
+        }
+        container.appendChild(svg);
+    }
+
+    window.Charts = { bar: bar };
+})();
@@ -0,0 +1,241 @@
+// Minimal tile map of located events for the map page.
+//
+//   var map = EventMap.render(container, points, { tiles: 'https://tile.openstreetmap.org/{z}/{x}/{y}.png',
+//                                                  attribution: '© OpenStreetMap contributors', bbox: 'w,s,e,n' })
+//   map.getBBox() // "west,south,east,north" of the area in view
+//
+// Points are { id, lat, lon, title, location, status, overdue, created_at }. The map fits the bbox when
+// given, otherwise the points, and can be dragged and zoomed.
+(function() {
+    'use strict';
+
+    var TILE = 256, MIN_ZOOM = 1, MAX_ZOOM = 18, POINTS_ZOOM = 12, MAX_LAT = 85.05112878;
+
+    function clamp(value, min, max) {
+        return Math.min(max, Math.max(min, value));
+    }
+
+    // Web Mercator pixel coordinates of a point at a zoom level
+    function project(lat, lon, zoom) {
+        var size = TILE * Math.pow(2, zoom);
+        var sin = Math.sin(clamp(lat, -MAX_LAT, MAX_LAT) * Math.PI / 180);
+        return {
+            x: (lon + 180) / 360 * size,
+            y: (0.5 - Math.log((1 + sin) / (1 - sin)) / (4 * Math.PI)) * size
+        };
+    }
+
+    function unproject(x, y, zoom) {
+        var size = TILE * Math.pow(2, zoom);
+        var n = Math.PI - 2 * Math.PI * y / size;
+        return {
+            lat: 180 / Math.PI * Math.atan(Math.sinh(n)),
+            lon: x / size * 360 - 180
+        };
+    }
+
+    function el(name, style, attrs) {
+        var node = document.createElement(name);
+        node.style.cssText = style || '';
+        Object.keys(attrs || {}).forEach(function(key) {
+            node.setAttribute(key, attrs[key]);
+        });
+        return node;
+    }
+
+    function round(value) {
+        return String(Math.round(value * 100000) / 100000);
+    }
+
+    function render(container, points, opts) {
+        opts = opts || {};
+        container.innerHTML = '';
+
+        var tilePane = el('div', 'position:absolute;left:0;top:0;');
+        var markerPane = el('div', 'position:absolute;left:0;top:0;');
+        container.appendChild(tilePane);
+        container.appendChild(markerPane);
+
+        var tiles = {};
+        var markers = points.map(function(point) {
+            var marker = el('a', 'position:absolute;width:12px;height:12px;margin:-8px 0 0 -8px;border-radius:50%;' +
+                'border:2px solid #fff;box-shadow:0 0 2px rgba(0,0,0,0.6);background-color:' +
+                (point.overdue ? '#e74c3c' : '#3498db') + ';', {
+                href: '/events/' + point.id,
+                title: point.title + (point.location ? '\n' + point.location : '') + '\n' + point.created_at +
+                    (point.status ? ' · ' + point.status : '')
+            });
+            markerPane.appendChild(marker);
+            return { point: point, node: marker };
+        });
+
+        var controls = el('div', 'position:absolute;top:10px;left:10px;display:flex;flex-direction:column;');
+        [['+', 1], ['−', -1]].forEach(function(button) {
+            var node = el('button', 'width:28px;height:28px;margin-bottom:4px;font-size:16px;cursor:pointer;', {
+                type: 'button', 'aria-label': button[1] > 0 ? 'Zoom in' : 'Zoom out'
+            });
+            node.textContent = button[0];
+            node.addEventListener('click', function() {
+                zoomAround(zoom + button[1], width() / 2, height() / 2);
+            });
+            controls.appendChild(node);
+        });
+        container.appendChild(controls);
+
+        if (opts.attribution) {
+            var attribution = el('div', 'position:absolute;right:0;bottom:0;padding:1px 5px;font-size:11px;' +
+                'background-color:rgba(255,255,255,0.8);color:#333;');
+            attribution.textContent = opts.attribution;
+            container.appendChild(attribution);
+        }
+
+        if (!points.length) {
+            var empty = el('div', 'position:absolute;top:10px;left:50%;transform:translateX(-50%);padding:4px 10px;' +
+                'background-color:rgba(255,255,255,0.9);border-radius:4px;');
+            empty.textContent = 'No located events here';
+            container.appendChild(empty);
+        }
+
+        // The view is a zoom level and the pixel coordinates of the map's center at it
+        var zoom = MIN_ZOOM, center = { x: TILE, y: TILE };
+
+        function width() {
+            return container.clientWidth || 600;
+        }
+
+        function height() {
+            return container.clientHeight || 400;
+        }
+
+        function draw() {
+            var worldSize = TILE * Math.pow(2, zoom);
+            var left = center.x - width() / 2, top = center.y - height() / 2;
+            var used = {};
+
+            for (var tx = Math.floor(left / TILE); tx * TILE < left + width(); tx++) {
+                for (var ty = Math.max(0, Math.floor(top / TILE)); ty * TILE < Math.min(top + height(), worldSize); ty++) {
+                    var wrapped = ((tx % Math.pow(2, zoom)) + Math.pow(2, zoom)) % Math.pow(2, zoom);
+                    var key = zoom + '/' + tx + '/' + ty;
+                    var tile = tiles[key];
+                    if (!tile) {
+                        tile = el('img', 'position:absolute;width:' + TILE + 'px;height:' + TILE + 'px;', {
+                            src: opts.tiles.replace('{z}', zoom).replace('{x}', wrapped).replace('{y}', ty),
+                            alt: '', draggable: 'false'
+                        });
+                        tiles[key] = tile;
+                        tilePane.appendChild(tile);
+                    }
+                    tile.style.left = (tx * TILE - left) + 'px';
+                    tile.style.top = (ty * TILE - top) + 'px';
+                    used[key] = true;
+                }
+            }
+            Object.keys(tiles).forEach(function(key) {
+                if (!used[key]) {
+                    tilePane.removeChild(tiles[key]);
+                    delete tiles[key];
+                }
+            });
+
+            markers.forEach(function(marker) {
+                var p = project(marker.point.lat, marker.point.lon, zoom);
+                // Show the copy of the world nearest the center
+                var x = p.x + Math.round((center.x - p.x) / worldSize) * worldSize;
+                marker.node.style.left = (x - left) + 'px';
+                marker.node.style.top = (p.y - top) + 'px';
+            });
+        }
+
+        function zoomAround(level, x, y) {
+            level = clamp(level, MIN_ZOOM, MAX_ZOOM);
+            if (level === zoom) {
+                return;
+            }
+            var scale = Math.pow(2, level - zoom);
+            var focus = { x: center.x - width() / 2 + x, y: center.y - height() / 2 + y };
+            center = { x: focus.x * scale - x + width() / 2, y: focus.y * scale - y + height() / 2 };
+            zoom = level;
+            draw();
+        }
+
+        // Shows the area between two meridians and parallels, zoomed in as far as it fits
+        function fit(west, south, east, north) {
+            if (west > east) {
+                east += 360;
+            }
+            zoom = MAX_ZOOM;
+            while (zoom > MIN_ZOOM) {
+                var sw = project(south, west, zoom), ne = project(north, east, zoom);
+                if (ne.x - sw.x <= width() - 40 && sw.y - ne.y <= height() - 40) {
+                    break;
+                }
+                zoom--;
+            }
+            var a = project(south, west, zoom), b = project(north, east, zoom);
+            center = { x: (a.x + b.x) / 2, y: (a.y + b.y) / 2 };
+        }
+
+        if (opts.bbox) {
+            var box = opts.bbox.split(',').map(Number);
+            fit(box[0], box[1], box[2], box[3]);
+        } else if (points.length) {
+            var lats = points.map(function(p) { return p.lat; });
+            var lons = points.map(function(p) { return p.lon; });
+            fit(Math.min.apply(null, lons), Math.min.apply(null, lats), Math.max.apply(null, lons), Math.max.apply(null, lats));
+            // Don't zoom into the street of a single event
+            if (zoom > POINTS_ZOOM) {
+                var scale = Math.pow(2, POINTS_ZOOM - zoom);
+                center = { x: center.x * scale, y: center.y * scale };
+                zoom = POINTS_ZOOM;
+            }
+        }
+        draw();
+
+        var drag = null;
+        container.addEventListener('pointerdown', function(e) {
+            if (e.target.closest('a, button')) {
+                return;
+            }
+            drag = { x: e.clientX, y: e.clientY };
+            container.classList.add('dragging');
+            container.setPointerCapture(e.pointerId);
+        });
+        container.addEventListener('pointermove', function(e) {
+            if (!drag) {
+                return;
+            }
+            center = { x: center.x - (e.clientX - drag.x), y: center.y - (e.clientY - drag.y) };
+            drag = { x: e.clientX, y: e.clientY };
+            draw();
+        });
+        ['pointerup', 'pointercancel'].forEach(function(type) {
+            container.addEventListener(type, function() {
+                drag = null;
+                container.classList.remove('dragging');
+            });
+        });
+        container.addEventListener('wheel', function(e) {
+            e.preventDefault();
+            var rect = container.getBoundingClientRect();
+            zoomAround(zoom + (e.deltaY < 0 ? 1 : -1), e.clientX - rect.left, e.clientY - rect.top);
+        }, { passive: false });
+        window.addEventListener('resize', draw);
+
+        return {
+            // getBBox returns the area in view as "west,south,east,north"
+            getBBox: function() {
+                var left = center.x - width() / 2, top = center.y - height() / 2;
+                var nw = unproject(left, Math.max(0, top), zoom);
+                var se = unproject(left + width(), Math.min(top + height(), TILE * Math.pow(2, zoom)), zoom);
+                var west = -180, east = 180;
+                if (se.lon - nw.lon < 360) {
+                    west = ((nw.lon + 540) % 360) - 180;
+                    east = ((se.lon + 540) % 360) - 180;
+                }
+                return [west, clamp(se.lat, -90, 90), east, clamp(nw.lat, -90, 90)].map(round).join(',');
+            }
+        };
+    }
+
+    window.EventMap = { render: render };
+})();
@@ -0,0 +1,102 @@
+// Tag autocomplete for the event forms.
+//
+// Suggests existing tags from /events/tags as the user types into #tags.
+// Choosing a suggestion fills the input and fires the same Enter keydown the
+// form's tag input already handles, so the tag is added as a badge.
+(function() {
+    'use strict';
+
+    document.addEventListener('DOMContentLoaded', function() {
+        const input = document.getElementById('tags');
+        if (!input) {
+            return;
+        }
+
+        const list = document.createElement('ul');
+        list.className = 'tag-suggestions';
+        list.hidden = true;
+        input.insertAdjacentElement('afterend', list);
+
+        let timer = null;
+        let active = -1;
+
+        function hide() {
+            list.hidden = true;
+            list.innerHTML = '';
+            active = -1;
+        }
+
+        function choose(tag) {
+            input.value = tag;
+            hide();
+            input.dispatchEvent(new KeyboardEvent('keydown', { key: 'Enter', bubbles: true, cancelable: true }));
+            input.focus();
+        }
+
+        function highlight(index) {
+            const items = list.querySelectorAll('li');
+            items.forEach(function(item, i) {
+                item.classList.toggle('active', i === index);
+            });
+            active = index;
+        }
+
+        function show(tags) {
+            list.innerHTML = '';
+            active = -1;
+            if (!tags.length) {
+                list.hidden = true;
+                return;
+            }
+            tags.forEach(function(tag) {
+                const item = document.createElement('li');
+                item.textContent = tag;
+                // mousedown fires before the input's blur handler
+                item.addEventListener('mousedown', function(e) {
+                    e.preventDefault();
+                    choose(tag);
+                });
+                list.appendChild(item);
+            });
+            list.hidden = false;
+        }
+
+        input.addEventListener('input', function() {
+            clearTimeout(timer);
+            const q = input.value.split(',').pop().trim();
+            if (!q) {
+                hide();
+                return;
+            }
+            timer = setTimeout(function() {
+                fetch('/events/tags?q=' + encodeURIComponent(q), { credentials: 'same-origin' })
+                    .then(function(resp) { return resp.ok ? resp.json() : []; })
+                    .then(show)
+                    .catch(hide);
+            }, 150);
+        });
+
+        // Runs in the capture phase so a highlighted suggestion wins over the form's Enter handler
+        input.addEventListener('keydown', function(e) {
+            if (list.hidden) {
+                return;
+            }
+            const items = list.querySelectorAll('li');
+            if (e.key === 'ArrowDown') {
+                e.preventDefault();
+                highlight(Math.min(active + 1, items.length - 1));
+            } else if (e.key === 'ArrowUp') {
+                e.preventDefault();
+                highlight(Math.max(active - 1, 0));
+            } else if (e.key === 'Enter' && active >= 0) {
+                e.preventDefault();
+                e.stopImmediatePropagation();
+                choose(items[active].textContent);
+            } else if (e.key === 'Escape') {
+                hide();
+            }
+        }, true);
+
+        input.addEventListener('blur', hide);
+    });
+})();